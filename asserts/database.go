@@ -102,6 +102,8 @@ type KeypairManager interface {
 	Put(privKey PrivateKey) error
 	// Get returns the private/public key pair with the given key id.
 	Get(keyID string) (PrivateKey, error)
+	// DeleteByID deletes the private/public key pair with the given key id.
+	DeleteByID(keyID string) error
 }
 
 // DatabaseConfig for an assertion database.
@@ -119,3 +119,57 @@ func (f *fetcher) fetchAccountKey(keyID string) error {
 func (f *fetcher) Save(a Assertion) error {
 	return f.chase(a.Ref(), a)
 }
+
+// A SequenceFormingFetcher is a Fetcher with special support for fetching
+// sequence-forming assertions through FetchSequence.
+type SequenceFormingFetcher interface {
+	Fetcher
+	// FetchSequence retrieves the sequence-forming assertion indicated
+	// by seq, at the latest sequence point if seq.Sequence is not set,
+	// then its prerequisites recursively, along the way saving
+	// prerequisites before dependent assertions.
+	FetchSequence(seq *AtSequence) error
+}
+
+type seqFormingFetcher struct {
+	*fetcher
+	retrieveSeq func(*AtSequence) (Assertion, error)
+}
+
+// NewSequenceFormingFetcher creates a SequenceFormingFetcher which will use trustedDB to determine trusted assertions, will fetch assertions following prerequisites using retrieve and sequence-forming assertions using retrieveSeq, and then will pass them to save, saving prerequisites before dependent assertions.
+func NewSequenceFormingFetcher(trustedDB RODatabase, retrieve func(*Ref) (Assertion, error), retrieveSeq func(*AtSequence) (Assertion, error), save func(Assertion) error) SequenceFormingFetcher {
+	return &seqFormingFetcher{
+		fetcher:     NewFetcher(trustedDB, retrieve, save).(*fetcher),
+		retrieveSeq: retrieveSeq,
+	}
+}
+
+// FetchSequence retrieves the sequence-forming assertion indicated by
+// seq then its prerequisites recursively, along the way saving
+// prerequisites before dependent assertions.
+func (f *seqFormingFetcher) FetchSequence(seq *AtSequence) error {
+	if !seq.Type.SequenceForming() {
+		return fmt.Errorf("internal error: %q assertions are not sequence-forming", seq.Type.Name)
+	}
+	if seq.Pinned && seq.Sequence <= 0 {
+		return fmt.Errorf("cannot fetch %s pinned without a sequence point", seq)
+	}
+	a, err := f.retrieveSeq(seq)
+	if err != nil {
+		return err
+	}
+	if a.Type() != seq.Type {
+		return fmt.Errorf("internal error: retrieved %q assertion instead of %q", a.Type().Name, seq.Type.Name)
+	}
+	ref := a.Ref()
+	retrieved := &AtSequence{
+		Type:        ref.Type,
+		SequenceKey: ref.PrimaryKey[:len(ref.PrimaryKey)-1],
+		Sequence:    a.(SequenceMember).Sequence(),
+		Revision:    RevisionNotKnown,
+	}
+	if retrieved.Unique() != seq.Unique() || (seq.Sequence > 0 && retrieved.Sequence != seq.Sequence) {
+		return fmt.Errorf("internal error: retrieved %s instead of %s", retrieved, seq)
+	}
+	return f.chase(ref, a)
+}
@@ -165,3 +165,171 @@ func (s *fetcherSuite) TestSave(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(snapDecl.(*asserts.SnapDeclaration).SnapName(), Equals, "foo")
 }
+
+func (s *fetcherSuite) prereqValidationSets(c *C, sequences ...int) {
+	for _, seq := range sequences {
+		headers := map[string]interface{}{
+			"series":       "16",
+			"account-id":   s.storeSigning.AuthorityID,
+			"authority-id": s.storeSigning.AuthorityID,
+			"name":         "base-set",
+			"sequence":     fmt.Sprintf("%d", seq),
+			"snaps": []interface{}{map[string]interface{}{
+				"name":     "foo",
+				"id":       "qOqKhntON3vR7kwEbVPsILm7bUViPDzz",
+				"presence": "required",
+			}},
+			"timestamp": time.Now().Format(time.RFC3339),
+		}
+		vs, err := s.storeSigning.Sign(asserts.ValidationSetType, headers, nil, "")
+		c.Assert(err, IsNil)
+		c.Assert(s.storeSigning.Add(vs), IsNil)
+	}
+}
+
+func (s *fetcherSuite) TestFetchSequence(c *C) {
+	s.prereqValidationSets(c, 1, 2, 3)
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return ref.Resolve(s.storeSigning.Find)
+	}
+	retrieveSeq := func(seq *asserts.AtSequence) (asserts.Assertion, error) {
+		if seq.Sequence > 0 {
+			return seq.Resolve(s.storeSigning.Find)
+		}
+		hdrs, err := asserts.HeadersFromSequenceKey(seq.Type, seq.SequenceKey)
+		c.Assert(err, IsNil)
+		return s.storeSigning.FindSequence(seq.Type, hdrs, -1, -1)
+	}
+
+	for _, t := range []struct {
+		sequence int
+		pinned   bool
+		expected int
+	}{
+		{0, false, 3},
+		{2, true, 2},
+	} {
+		db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+			Backstore: asserts.NewMemoryBackstore(),
+			Trusted:   s.storeSigning.Trusted,
+		})
+		c.Assert(err, IsNil)
+
+		f := asserts.NewSequenceFormingFetcher(db, retrieve, retrieveSeq, db.Add)
+		seq := &asserts.AtSequence{
+			Type:        asserts.ValidationSetType,
+			SequenceKey: []string{"16", s.storeSigning.AuthorityID, "base-set"},
+			Sequence:    t.sequence,
+			Pinned:      t.pinned,
+			Revision:    asserts.RevisionNotKnown,
+		}
+		c.Assert(f.FetchSequence(seq), IsNil)
+
+		hdrs, err := asserts.HeadersFromSequenceKey(seq.Type, seq.SequenceKey)
+		c.Assert(err, IsNil)
+		vs, err := db.FindSequence(asserts.ValidationSetType, hdrs, -1, -1)
+		c.Assert(err, IsNil)
+		c.Check(vs.Sequence(), Equals, t.expected)
+		// only the requested sequence point was fetched
+		_, err = db.Find(asserts.ValidationSetType, map[string]string{
+			"series":     "16",
+			"account-id": s.storeSigning.AuthorityID,
+			"name":       "base-set",
+			"sequence":   fmt.Sprintf("%d", t.expected-1),
+		})
+		c.Check(asserts.IsNotFound(err), Equals, true)
+		// together with its prerequisites
+		_, err = db.Find(asserts.AccountKeyType, map[string]string{
+			"public-key-sha3-384": vs.SignKeyID(),
+		})
+		c.Check(err, IsNil)
+	}
+}
+
+func (s *fetcherSuite) TestFetchSequenceErrors(c *C) {
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return ref.Resolve(s.storeSigning.Find)
+	}
+	retrieveSeq := func(seq *asserts.AtSequence) (asserts.Assertion, error) {
+		return nil, fmt.Errorf("boom")
+	}
+	f := asserts.NewSequenceFormingFetcher(db, retrieve, retrieveSeq, db.Add)
+
+	err = f.FetchSequence(&asserts.AtSequence{
+		Type:        asserts.SnapDeclarationType,
+		SequenceKey: []string{"16", "snap-id-1"},
+	})
+	c.Check(err, ErrorMatches, `internal error: "snap-declaration" assertions are not sequence-forming`)
+
+	err = f.FetchSequence(&asserts.AtSequence{
+		Type:        asserts.ValidationSetType,
+		SequenceKey: []string{"16", "account-id", "base-set"},
+		Pinned:      true,
+		Revision:    asserts.RevisionNotKnown,
+	})
+	c.Check(err, ErrorMatches, `cannot fetch validation-set account-id/base-set pinned without a sequence point`)
+
+	err = f.FetchSequence(&asserts.AtSequence{
+		Type:        asserts.ValidationSetType,
+		SequenceKey: []string{"16", "account-id", "base-set"},
+		Sequence:    1,
+		Revision:    asserts.RevisionNotKnown,
+	})
+	c.Check(err, ErrorMatches, "boom")
+}
+
+func (s *fetcherSuite) TestFetchSequenceMismatch(c *C) {
+	s.prereqValidationSets(c, 1, 2)
+
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore: asserts.NewMemoryBackstore(),
+		Trusted:   s.storeSigning.Trusted,
+	})
+	c.Assert(err, IsNil)
+
+	retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+		return ref.Resolve(s.storeSigning.Find)
+	}
+	// always hands out base-set at sequence 2
+	retrieveSeq := func(seq *asserts.AtSequence) (asserts.Assertion, error) {
+		return s.storeSigning.Find(asserts.ValidationSetType, map[string]string{
+			"series":     "16",
+			"account-id": s.storeSigning.AuthorityID,
+			"name":       "base-set",
+			"sequence":   "2",
+		})
+	}
+	f := asserts.NewSequenceFormingFetcher(db, retrieve, retrieveSeq, db.Add)
+
+	err = f.FetchSequence(&asserts.AtSequence{
+		Type:        asserts.ValidationSetType,
+		SequenceKey: []string{"16", s.storeSigning.AuthorityID, "other-set"},
+		Revision:    asserts.RevisionNotKnown,
+	})
+	c.Check(err, ErrorMatches, `internal error: retrieved validation-set .*/base-set/2 instead of validation-set .*/other-set`)
+
+	err = f.FetchSequence(&asserts.AtSequence{
+		Type:        asserts.ValidationSetType,
+		SequenceKey: []string{"16", s.storeSigning.AuthorityID, "base-set"},
+		Sequence:    1,
+		Pinned:      true,
+		Revision:    asserts.RevisionNotKnown,
+	})
+	c.Check(err, ErrorMatches, `internal error: retrieved validation-set .*/base-set/2 instead of validation-set .*/base-set=1`)
+
+	// nothing was saved
+	_, err = db.Find(asserts.ValidationSetType, map[string]string{
+		"series":     "16",
+		"account-id": s.storeSigning.AuthorityID,
+		"name":       "base-set",
+		"sequence":   "2",
+	})
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
@@ -90,3 +90,17 @@ func (fskm *filesystemKeypairManager) Get(keyID string) (PrivateKey, error) {
 	}
 	return privKey, nil
 }
+
+func (fskm *filesystemKeypairManager) DeleteByID(keyID string) error {
+	fskm.mu.Lock()
+	defer fskm.mu.Unlock()
+
+	err := removeEntry(fskm.top, keyID)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errKeypairNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type fsKeypairMgrSuite struct{}
@@ -63,3 +64,24 @@ func (fsbss *fsKeypairMgrSuite) TestOpenWorldWritableFail(c *C) {
 	c.Assert(err, ErrorMatches, "assert storage root unexpectedly world-writable: .*")
 	c.Check(bs, IsNil)
 }
+
+func (fsbss *fsKeypairMgrSuite) TestDeleteByID(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	keypairMgr, err := asserts.OpenFSKeypairManager(topDir)
+	c.Assert(err, IsNil)
+
+	pk1 := testPrivKey1
+	keyID := pk1.PublicKey().ID()
+	err = keypairMgr.Put(pk1)
+	c.Assert(err, IsNil)
+
+	err = keypairMgr.DeleteByID(keyID)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(topDir, "private-keys-v1", keyID), testutil.FileAbsent)
+
+	_, err = keypairMgr.Get(keyID)
+	c.Check(err, ErrorMatches, "cannot find key pair")
+
+	err = keypairMgr.DeleteByID(keyID)
+	c.Check(err, ErrorMatches, "cannot find key pair")
+}
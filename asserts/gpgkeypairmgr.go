@@ -339,15 +339,37 @@ func (gkm *GPGKeypairManager) Export(name string) ([]byte, error) {
 	return EncodePublicKey(keyInfo.privKey.PublicKey())
 }
 
+// DeleteByID removes the key pair with the given key id from GnuPG's storage.
+func (gkm *GPGKeypairManager) DeleteByID(keyID string) error {
+	stop := errors.New("stop marker")
+	var fingerprint string
+	match := func(privk PrivateKey, fpr string, uid string) error {
+		if privk.PublicKey().ID() == keyID {
+			fingerprint = fpr
+			return stop
+		}
+		return nil
+	}
+	err := gkm.Walk(match)
+	if err == nil {
+		return fmt.Errorf("cannot find key %q in GPG keyring", keyID)
+	}
+	if err != stop {
+		return err
+	}
+	return gkm.deleteByFingerprint(fingerprint)
+}
+
 // Delete removes the named key pair from GnuPG's storage.
 func (gkm *GPGKeypairManager) Delete(name string) error {
 	keyInfo, err := gkm.findByName(name)
 	if err != nil {
 		return err
 	}
-	_, err = gkm.gpg(nil, "--batch", "--delete-secret-and-public-key", "0x"+keyInfo.fingerprint)
-	if err != nil {
-		return err
-	}
-	return nil
+	return gkm.deleteByFingerprint(keyInfo.fingerprint)
+}
+
+func (gkm *GPGKeypairManager) deleteByFingerprint(fingerprint string) error {
+	_, err := gkm.gpg(nil, "--batch", "--delete-secret-and-public-key", "0x"+fingerprint)
+	return err
 }
@@ -57,3 +57,15 @@ func (mkm *memoryKeypairManager) Get(keyID string) (PrivateKey, error) {
 	}
 	return privKey, nil
 }
+
+func (mkm *memoryKeypairManager) DeleteByID(keyID string) error {
+	mkm.mu.Lock()
+	defer mkm.mu.Unlock()
+
+	_, ok := mkm.pairs[keyID]
+	if !ok {
+		return errKeypairNotFound
+	}
+	delete(mkm.pairs, keyID)
+	return nil
+}
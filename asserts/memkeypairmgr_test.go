@@ -71,3 +71,19 @@ func (mkms *memKeypairMgtSuite) TestGetNotFound(c *C) {
 	c.Check(got, IsNil)
 	c.Check(err, ErrorMatches, "cannot find key pair")
 }
+
+func (mkms *memKeypairMgtSuite) TestDeleteByID(c *C) {
+	pk1 := testPrivKey1
+	keyID := pk1.PublicKey().ID()
+	err := mkms.keypairMgr.Put(pk1)
+	c.Assert(err, IsNil)
+
+	err = mkms.keypairMgr.DeleteByID(keyID)
+	c.Assert(err, IsNil)
+
+	_, err = mkms.keypairMgr.Get(keyID)
+	c.Check(err, ErrorMatches, "cannot find key pair")
+
+	err = mkms.keypairMgr.DeleteByID(keyID)
+	c.Check(err, ErrorMatches, "cannot find key pair")
+}
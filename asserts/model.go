@@ -436,6 +436,12 @@ func (mod *Model) Classic() bool {
 	return mod.classic
 }
 
+// Distribution returns the distribution of a classic model with
+// modes, it is empty otherwise.
+func (mod *Model) Distribution() string {
+	return mod.HeaderString("distribution")
+}
+
 // Architecture returns the architecture the model is based on.
 func (mod *Model) Architecture() string {
 	return mod.HeaderString("architecture")
@@ -636,6 +642,9 @@ var (
 	extendedCoreMandatory    = []string{"architecture", "base"}
 	extendedSnapsConflicting = []string{"gadget", "kernel", "required-snaps"}
 	classicModelOptional     = []string{"architecture", "gadget"}
+
+	// distribution ids follow the ID field of os-release(5)
+	validDistribution = regexp.MustCompile("^[a-z0-9._-]+$")
 )
 
 func assembleModel(assert assertionBase) (Assertion, error) {
@@ -657,10 +666,6 @@ func assembleModel(assert assertionBase) (Assertion, error) {
 	// Core 20 extended snaps header
 	extendedSnaps, extended := assert.headers["snaps"]
 	if extended {
-		if classic {
-			return nil, fmt.Errorf("cannot use extended snaps header for a classic model (yet)")
-		}
-
 		for _, conflicting := range extendedSnapsConflicting {
 			if _, ok := assert.headers[conflicting]; ok {
 				return nil, fmt.Errorf("cannot specify separate %q header once using the extended snaps header", conflicting)
@@ -675,7 +680,17 @@ func assembleModel(assert assertionBase) (Assertion, error) {
 		}
 	}
 
-	if classic {
+	// classic models with an extended snaps header are hybrid
+	// systems with modes, they need to name their distribution
+	if classic && extended {
+		if _, err := checkStringMatches(assert.headers, "distribution", validDistribution); err != nil {
+			return nil, err
+		}
+	} else if _, ok := assert.headers["distribution"]; ok {
+		return nil, fmt.Errorf("cannot specify distribution for a model that is not classic with an extended snaps header")
+	}
+
+	if classic && !extended {
 		if _, ok := assert.headers["kernel"]; ok {
 			return nil, fmt.Errorf("cannot specify a kernel with a classic model")
 		}
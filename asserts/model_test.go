@@ -892,6 +892,20 @@ func (mods *modelSuite) TestCore20DefaultStorageSafetySignedDangerous(c *C) {
 	}
 }
 
+func (mods *modelSuite) TestClassicWithModesDecodeOK(c *C) {
+	encoded := strings.Replace(core20ModelExample, "TSLINE", mods.tsLine, 1)
+	encoded = strings.Replace(encoded, "OTHER", "classic: true\ndistribution: ubuntu\n", 1)
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	model := a.(*asserts.Model)
+	c.Check(model.Classic(), Equals, true)
+	c.Check(model.Distribution(), Equals, "ubuntu")
+	c.Check(model.Grade(), Equals, asserts.ModelSecured)
+	c.Check(model.Kernel(), Equals, "baz-linux")
+	c.Check(model.Gadget(), Equals, "brand-gadget")
+	c.Check(model.Base(), Equals, "core20")
+}
+
 func (mods *modelSuite) TestCore20DecodeInvalid(c *C) {
 	encoded := strings.Replace(core20ModelExample, "TSLINE", mods.tsLine, 1)
 
@@ -900,7 +914,9 @@ func (mods *modelSuite) TestCore20DecodeInvalid(c *C) {
 	invalidTests := []struct{ original, invalid, expectedErr string }{
 		{"base: core20\n", "", `"base" header is mandatory`},
 		{"base: core20\n", "base: alt-base\n", `cannot specify not well-known base "alt-base" without a corresponding "snaps" header entry`},
-		{"OTHER", "classic: true\n", `cannot use extended snaps header for a classic model \(yet\)`},
+		{"OTHER", "classic: true\n", `"distribution" header is mandatory`},
+		{"OTHER", "classic: true\ndistribution: Ubuntu\n", `"distribution" header contains invalid characters: "Ubuntu"`},
+		{"OTHER", "distribution: ubuntu\n", `cannot specify distribution for a model that is not classic with an extended snaps header`},
 		{snapsStanza, "snaps: snap\n", `"snaps" header must be a list of maps`},
 		{snapsStanza, "snaps:\n  - snap\n", `"snaps" header must be a list of maps`},
 		{"name: myapp\n", "other: 1\n", `"name" of snap is mandatory`},
@@ -0,0 +1,205 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"crypto"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/snapcore/snapd/snap/naming"
+)
+
+// PreseedSnap holds the details about a snap that was set up when
+// preseeding an image, as listed by a preseed assertion.
+type PreseedSnap struct {
+	Name     string
+	SnapID   string
+	Revision int
+}
+
+// SnapName implements naming.SnapRef.
+func (s *PreseedSnap) SnapName() string {
+	return s.Name
+}
+
+// ID implements naming.SnapRef.
+func (s *PreseedSnap) ID() string {
+	return s.SnapID
+}
+
+// Preseed holds a preseed assertion, which is a statement by the brand
+// about the digest of the artifacts produced when preseeding an image
+// for a given system label of a model. First boot uses it to verify
+// the preseeded data before making use of it.
+type Preseed struct {
+	assertionBase
+
+	snaps     []*PreseedSnap
+	timestamp time.Time
+}
+
+// Series returns the series that this assertion is valid for.
+func (p *Preseed) Series() string {
+	return p.HeaderString("series")
+}
+
+// BrandID returns the brand identifier that signed this assertion.
+func (p *Preseed) BrandID() string {
+	return p.HeaderString("brand-id")
+}
+
+// Model returns the model name identifier of the device.
+func (p *Preseed) Model() string {
+	return p.HeaderString("model")
+}
+
+// SystemLabel returns the label of the seed system the image was
+// preseeded for.
+func (p *Preseed) SystemLabel() string {
+	return p.HeaderString("system-label")
+}
+
+// ArtifactSHA3_384 returns the digest of the preseeding artifacts.
+func (p *Preseed) ArtifactSHA3_384() string {
+	return p.HeaderString("artifact-sha3-384")
+}
+
+// Snaps returns the snaps that were set up when preseeding.
+func (p *Preseed) Snaps() []*PreseedSnap {
+	return p.snaps
+}
+
+// Timestamp returns the time when the preseed assertion was issued.
+func (p *Preseed) Timestamp() time.Time {
+	return p.timestamp
+}
+
+func checkPreseedSnap(snap map[string]interface{}) (*PreseedSnap, error) {
+	name, err := checkNotEmptyStringWhat(snap, "name", "of snap")
+	if err != nil {
+		return nil, err
+	}
+	if err := naming.ValidateSnap(name); err != nil {
+		return nil, fmt.Errorf("invalid snap name %q", name)
+	}
+
+	what := fmt.Sprintf("of snap %q", name)
+
+	// snap id and revision are either both set, or neither for
+	// snaps that are not from the store
+	snapID, err := checkOptionalStringWhat(snap, "id", what)
+	if err != nil {
+		return nil, err
+	}
+	var snapRevision int
+	if snapID != "" {
+		if !naming.ValidSnapID.MatchString(snapID) {
+			return nil, fmt.Errorf("invalid snap id %q", snapID)
+		}
+		snapRevision, err = checkSnapRevisionWhat(snap, "revision", what)
+		if err != nil {
+			return nil, err
+		}
+	} else if _, ok := snap["revision"]; ok {
+		return nil, fmt.Errorf(`cannot specify revision %s without its snap id`, what)
+	}
+
+	return &PreseedSnap{
+		Name:     name,
+		SnapID:   snapID,
+		Revision: snapRevision,
+	}, nil
+}
+
+func checkPreseedSnaps(snapList interface{}) ([]*PreseedSnap, error) {
+	const wrongHeaderType = `"snaps" header must be a list of maps`
+
+	entries, ok := snapList.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(wrongHeaderType)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	snaps := make([]*PreseedSnap, 0, len(entries))
+	for _, entry := range entries {
+		snap, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(wrongHeaderType)
+		}
+		preseedSnap, err := checkPreseedSnap(snap)
+		if err != nil {
+			return nil, err
+		}
+		if seen[preseedSnap.Name] {
+			return nil, fmt.Errorf("cannot list the same snap %q multiple times", preseedSnap.Name)
+		}
+		seen[preseedSnap.Name] = true
+		snaps = append(snaps, preseedSnap)
+	}
+
+	return snaps, nil
+}
+
+// same rules as for the system labels of seeds
+var validPreseedSystemLabel = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
+
+func assemblePreseed(assert assertionBase) (Assertion, error) {
+	err := checkAuthorityMatchesBrand(&assert)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = checkModel(assert.headers)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = checkStringMatches(assert.headers, "system-label", validPreseedSystemLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = checkDigest(assert.headers, "artifact-sha3-384", crypto.SHA3_384)
+	if err != nil {
+		return nil, err
+	}
+
+	snapList, ok := assert.headers["snaps"]
+	if !ok {
+		return nil, fmt.Errorf(`"snaps" header is mandatory`)
+	}
+	snaps, err := checkPreseedSnaps(snapList)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Preseed{
+		assertionBase: assert,
+		snaps:         snaps,
+		timestamp:     timestamp,
+	}, nil
+}
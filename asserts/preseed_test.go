@@ -0,0 +1,125 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+var _ = Suite(&preseedSuite{})
+
+type preseedSuite struct {
+	ts     time.Time
+	tsLine string
+}
+
+func (s *preseedSuite) SetUpSuite(c *C) {
+	s.ts = time.Now().Truncate(time.Second).UTC()
+	s.tsLine = "timestamp: " + s.ts.Format(time.RFC3339) + "\n"
+}
+
+const preseedExample = "type: preseed\n" +
+	"authority-id: brand-id1\n" +
+	"series: 16\n" +
+	"brand-id: brand-id1\n" +
+	"model: baz-3000\n" +
+	"system-label: 20210302\n" +
+	"artifact-sha3-384: KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj\n" +
+	"snaps:\n" +
+	"  -\n" +
+	"    name: baz-linux\n" +
+	"    id: bazlinuxidididididididididididid\n" +
+	"    revision: 99\n" +
+	"  -\n" +
+	"    name: local-snap\n" +
+	"TSLINE" +
+	"body-length: 0\n" +
+	"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
+	"\n\n" +
+	"AXNpZw=="
+
+func (s *preseedSuite) TestDecodeOK(c *C) {
+	encoded := strings.Replace(preseedExample, "TSLINE", s.tsLine, 1)
+
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.PreseedType)
+	preseed := a.(*asserts.Preseed)
+	c.Check(preseed.AuthorityID(), Equals, "brand-id1")
+	c.Check(preseed.Timestamp(), Equals, s.ts)
+	c.Check(preseed.Series(), Equals, "16")
+	c.Check(preseed.BrandID(), Equals, "brand-id1")
+	c.Check(preseed.Model(), Equals, "baz-3000")
+	c.Check(preseed.SystemLabel(), Equals, "20210302")
+	c.Check(preseed.ArtifactSHA3_384(), Equals, "KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj")
+	c.Check(preseed.Snaps(), DeepEquals, []*asserts.PreseedSnap{
+		{
+			Name:     "baz-linux",
+			SnapID:   "bazlinuxidididididididididididid",
+			Revision: 99,
+		}, {
+			Name: "local-snap",
+		},
+	})
+	c.Check(preseed.Snaps()[0].SnapName(), Equals, "baz-linux")
+	c.Check(preseed.Snaps()[0].ID(), Equals, "bazlinuxidididididididididididid")
+}
+
+const preseedErrPrefix = "assertion preseed: "
+
+func (s *preseedSuite) TestDecodeInvalid(c *C) {
+	encoded := strings.Replace(preseedExample, "TSLINE", s.tsLine, 1)
+
+	snapsStanza := encoded[strings.Index(encoded, "snaps:"):strings.Index(encoded, "timestamp:")]
+
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"brand-id: brand-id1\n", "brand-id: other\n", `authority-id and brand-id must match, preseed assertions are expected to be signed by the brand: "brand-id1" != "other"`},
+		{"model: baz-3000\n", "", `"model" header is mandatory`},
+		{"model: baz-3000\n", "model: Baz-3000\n", `"model" header cannot contain uppercase letters`},
+		{"system-label: 20210302\n", "", `"system-label" header is mandatory`},
+		{"system-label: 20210302\n", "system-label: -x\n", `"system-label" header contains invalid characters: "-x"`},
+		{"artifact-sha3-384: KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj\n", "", `"artifact-sha3-384" header is mandatory`},
+		{"artifact-sha3-384: KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj\n", "artifact-sha3-384: #\n", `"artifact-sha3-384" header cannot be decoded: .*`},
+		{"artifact-sha3-384: KPIl7M4vQ9d4AUjkoU41TGAwtOMLc_bWUCeW8AvdRWD4_xcP60Oo4ABsFNo6BtXj\n", "artifact-sha3-384: eHl6\n", `"artifact-sha3-384" header does not have the expected bit length: 24`},
+		{snapsStanza, "", `"snaps" header is mandatory`},
+		{snapsStanza, "snaps: foo\n", `"snaps" header must be a list of maps`},
+		{snapsStanza, "snaps:\n  - foo\n", `"snaps" header must be a list of maps`},
+		{"    name: baz-linux\n", "    other: 1\n", `"name" of snap is mandatory`},
+		{"    name: baz-linux\n", "    name: baz-linux_instance\n", `invalid snap name "baz-linux_instance"`},
+		{"    name: local-snap\n", "    name: baz-linux\n", `cannot list the same snap "baz-linux" multiple times`},
+		{"    id: bazlinuxidididididididididididid\n", "    id: 2\n", `invalid snap id "2"`},
+		{"    id: bazlinuxidididididididididididid\n", "", `cannot specify revision of snap "baz-linux" without its snap id`},
+		{"    revision: 99\n", "", `"revision" of snap "baz-linux" is mandatory`},
+		{"    revision: 99\n", "    revision: 0\n", `"revision" of snap "baz-linux" must be >=1: 0`},
+		{s.tsLine, "", `"timestamp" header is mandatory`},
+		{s.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, preseedErrPrefix+test.expectedErr)
+	}
+}
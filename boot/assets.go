@@ -25,8 +25,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "golang.org/x/crypto/sha3"
 
@@ -166,6 +168,71 @@ func CopyBootAssetsCacheToRoot(dstRoot string) error {
 	return err
 }
 
+// unused returns the assets in the cache which are tracked in neither the run
+// mode nor the recovery trusted boot assets of the given modeenv.
+func (c *trustedAssetsCache) unused(m *Modeenv) ([]*trackedAsset, error) {
+	blDirs, err := ioutil.ReadDir(c.cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var unused []*trackedAsset
+	for _, blDir := range blDirs {
+		if !blDir.IsDir() {
+			continue
+		}
+		entries, err := ioutil.ReadDir(c.pathInCache(blDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.Mode().IsRegular() || strings.HasSuffix(entry.Name(), "~") {
+				// only committed cache entries are of interest
+				continue
+			}
+			idx := strings.LastIndex(entry.Name(), "-")
+			if idx <= 0 {
+				continue
+			}
+			name, hash := entry.Name()[:idx], entry.Name()[idx+1:]
+			if isAssetHashTrackedInMap(m.CurrentTrustedBootAssets, name, hash) ||
+				isAssetHashTrackedInMap(m.CurrentTrustedRecoveryBootAssets, name, hash) {
+				continue
+			}
+			unused = append(unused, &trackedAsset{
+				blName: blDir.Name(),
+				name:   name,
+				hash:   hash,
+			})
+		}
+	}
+	return unused, nil
+}
+
+// removeUnusedTrustedBootAssets garbage collects the trusted boot assets
+// cache, removing the assets which are no longer tracked in the given modeenv,
+// such as those left behind when an update of the gadget was interrupted before
+// the cache could be cleaned up. It is called once a boot with updated assets
+// was marked as successful. The assets of an update are added to the cache
+// before they are tracked in the modeenv, thus the call must be excluded with
+// updates of the assets, which is the case as both happen with the state
+// locked by the device manager.
+func removeUnusedTrustedBootAssets(m *Modeenv) error {
+	cache := newTrustedAssetsCache(dirs.SnapBootAssetsDir)
+	unused, err := cache.unused(m)
+	if err != nil {
+		return fmt.Errorf("cannot list cached boot assets: %v", err)
+	}
+	for _, ta := range unused {
+		if err := cache.Remove(ta.blName, ta.name, ta.hash); err != nil {
+			return fmt.Errorf("cannot remove unused boot asset %v:%v: %v", ta.name, ta.hash, err)
+		}
+	}
+	return nil
+}
+
 // ErrObserverNotApplicable indicates that observer is not applicable for use
 // with the model.
 var ErrObserverNotApplicable = errors.New("observer not applicable")
@@ -236,6 +303,42 @@ func isAssetAlreadyTracked(bam bootAssetsMap, newAsset *trackedAsset) bool {
 	return isAssetHashTrackedInMap(bam, newAsset.name, newAsset.hash)
 }
 
+// trackAssetHash adds the hash of an asset to the map, unless it is tracked
+// already. At most 2 hashes are tracked for an asset, the current one and the
+// one of an update.
+func trackAssetHash(bam *bootAssetsMap, ta *trackedAsset) error {
+	if isAssetAlreadyTracked(*bam, ta) {
+		return nil
+	}
+	if len((*bam)[ta.name]) > 1 {
+		// more entries indicate that the same asset name is used
+		// multiple times with different content
+		return fmt.Errorf("cannot reuse asset name %q", ta.name)
+	}
+	if *bam == nil {
+		*bam = bootAssetsMap{}
+	}
+	(*bam)[ta.name] = append((*bam)[ta.name], ta.hash)
+	return nil
+}
+
+// dropAssetHash stops tracking the hash of an asset in the map, the asset is
+// dropped once none of its hashes is tracked.
+func dropAssetHash(bam bootAssetsMap, assetName, assetHash string) {
+	hashes := bam[assetName]
+	for idx, hash := range hashes {
+		if hash == assetHash {
+			hashes = append(hashes[:idx:idx], hashes[idx+1:]...)
+			break
+		}
+	}
+	if len(hashes) == 0 {
+		delete(bam, assetName)
+	} else {
+		bam[assetName] = hashes
+	}
+}
+
 func isAssetHashTrackedInMap(bam bootAssetsMap, assetName, assetHash string) bool {
 	if bam == nil {
 		return false
@@ -573,16 +676,10 @@ func (o *TrustedAssetsUpdateObserver) observeUpdate(bl bootloader.Bootloader, re
 		(*trustedAssets)[taBefore.name] = append([]string{taBefore.hash}, (*trustedAssets)[taBefore.name]...)
 	}
 
-	if !isAssetAlreadyTracked(*trustedAssets, ta) {
-		if len((*trustedAssets)[ta.name]) > 1 {
-			// we expect at most 2 different blobs for a given asset
-			// name, the current one and one that will be installed
-			// during an update; more entries indicates that the
-			// same asset name is used multiple times with different
-			// content
-			return gadget.ChangeAbort, fmt.Errorf("cannot reuse asset name %q", ta.name)
-		}
-		(*trustedAssets)[ta.name] = append((*trustedAssets)[ta.name], ta.hash)
+	// we expect at most 2 different blobs for a given asset name, the
+	// current one and one that will be installed during an update
+	if err := trackAssetHash(trustedAssets, ta); err != nil {
+		return gadget.ChangeAbort, err
 	}
 
 	if o.modeenv.deepEqual(modeenvBefore) {
@@ -651,10 +748,8 @@ func (o *TrustedAssetsUpdateObserver) observeRollback(bl bootloader.Bootloader,
 	}
 
 	// update modeenv content
-	if !newlyAdded {
-		(*trustedAssets)[assetName] = hashList[:1]
-	} else {
-		delete(*trustedAssets, assetName)
+	if newHash != "" {
+		dropAssetHash(*trustedAssets, assetName, newHash)
 	}
 
 	if err := o.modeenv.Write(); err != nil {
@@ -2785,3 +2785,76 @@ func (s *assetsSuite) TestUpdateObserverUpdateMockedNonEncryption(c *C) {
 	c.Assert(err, IsNil)
 	c.Check(resealCalls, Equals, 0)
 }
+
+func (s *assetsSuite) TestRemoveUnusedTrustedBootAssets(c *C) {
+	m := &boot.Modeenv{
+		Mode: "run",
+		CurrentTrustedBootAssets: boot.BootAssetsMap{
+			"asset": {"assethash", "newassethash"},
+		},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"asset":      {"recoveryassethash"},
+			"shim-asset": {"shimhash"},
+		},
+	}
+
+	// does not fail when the cache does not exist
+	err := boot.RemoveUnusedTrustedBootAssets(m)
+	c.Assert(err, IsNil)
+
+	mockAssetsCache(c, s.rootdir, "trusted", []string{
+		"asset-assethash",
+		"asset-newassethash",
+		"asset-recoveryassethash",
+		// left behind by an interrupted update
+		"asset-oldassethash",
+		"shim-asset-shimhash",
+		"shim-asset-oldshimhash",
+		// not committed yet
+		"asset.temp.1234~",
+	})
+	mockAssetsCache(c, s.rootdir, "other", []string{
+		"asset-assethash",
+		"other-asset-hash",
+	})
+
+	err = boot.RemoveUnusedTrustedBootAssets(m)
+	c.Assert(err, IsNil)
+	checkContentGlob(c, filepath.Join(dirs.SnapBootAssetsDir, "*", "*"), []string{
+		filepath.Join(dirs.SnapBootAssetsDir, "other", "asset-assethash"),
+		filepath.Join(dirs.SnapBootAssetsDir, "trusted", "asset-assethash"),
+		filepath.Join(dirs.SnapBootAssetsDir, "trusted", "asset-newassethash"),
+		filepath.Join(dirs.SnapBootAssetsDir, "trusted", "asset-recoveryassethash"),
+		filepath.Join(dirs.SnapBootAssetsDir, "trusted", "asset.temp.1234~"),
+		filepath.Join(dirs.SnapBootAssetsDir, "trusted", "shim-asset-shimhash"),
+	})
+}
+
+func (s *assetsSuite) TestTrackAndDropAssetHash(c *C) {
+	var bam boot.BootAssetsMap
+	c.Assert(boot.TrackAssetHash(&bam, "trusted", "asset", "hash-1"), IsNil)
+	c.Assert(boot.TrackAssetHash(&bam, "trusted", "asset", "hash-2"), IsNil)
+	// already tracked
+	c.Assert(boot.TrackAssetHash(&bam, "trusted", "asset", "hash-2"), IsNil)
+	c.Check(bam, DeepEquals, boot.BootAssetsMap{
+		"asset": {"hash-1", "hash-2"},
+	})
+	err := boot.TrackAssetHash(&bam, "trusted", "asset", "hash-3")
+	c.Assert(err, ErrorMatches, `cannot reuse asset name "asset"`)
+
+	tracked := bam["asset"]
+	boot.DropAssetHash(bam, "asset", "hash-1")
+	c.Check(bam, DeepEquals, boot.BootAssetsMap{
+		"asset": {"hash-2"},
+	})
+	// the original list is not modified
+	c.Check(tracked, DeepEquals, []string{"hash-1", "hash-2"})
+	// not tracked
+	boot.DropAssetHash(bam, "asset", "hash-3")
+	boot.DropAssetHash(bam, "other-asset", "hash-3")
+	c.Check(bam, DeepEquals, boot.BootAssetsMap{
+		"asset": {"hash-2"},
+	})
+	boot.DropAssetHash(bam, "asset", "hash-2")
+	c.Check(bam, HasLen, 0)
+}
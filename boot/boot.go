@@ -22,9 +22,13 @@ package boot
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -100,7 +104,8 @@ type Device interface {
 // to call anything on it always.
 //
 // Currently, on classic, nothing is a boot participant (returned will
-// always be NOP).
+// always be NOP), except for the kernel and gadget on classic systems with
+// modes.
 func Participant(s snap.PlaceInfo, t snap.Type, dev Device) BootParticipant {
 	if applicable(s, t, dev) {
 		bs, err := bootStateFor(t, dev)
@@ -130,13 +135,13 @@ func bootloaderOptionsForDeviceKernel(dev Device) *bootloader.Options {
 // BootKernel, or a trivial implementation otherwise.
 func Kernel(s snap.PlaceInfo, t snap.Type, dev Device) BootKernel {
 	if t == snap.TypeKernel && applicable(s, t, dev) {
-		return &coreKernel{s: s, bopts: bootloaderOptionsForDeviceKernel(dev)}
+		return &coreKernel{s: s, bopts: bootloaderOptionsForDeviceKernel(dev), dev: dev}
 	}
 	return trivial{}
 }
 
 func applicable(s snap.PlaceInfo, t snap.Type, dev Device) bool {
-	if dev.Classic() {
+	if dev.Classic() && !dev.HasModeenv() {
 		return false
 	}
 	// In ephemeral modes we never need to care about updating the boot
@@ -145,8 +150,20 @@ func applicable(s snap.PlaceInfo, t snap.Type, dev Device) bool {
 		return false
 	}
 
-	if t != snap.TypeOS && t != snap.TypeKernel && t != snap.TypeBase {
-		// note we don't currently have anything useful to do with gadgets
+	switch t {
+	case snap.TypeKernel:
+	case snap.TypeOS, snap.TypeBase:
+		// classic systems with modes do not boot a base
+		if dev.Classic() {
+			return false
+		}
+	case snap.TypeGadget:
+		// the gadget is only tracked in the boot state on UC20, where it
+		// may carry trusted boot assets
+		if !dev.HasModeenv() {
+			return false
+		}
+	default:
 		return false
 	}
 
@@ -164,6 +181,11 @@ func applicable(s snap.PlaceInfo, t snap.Type, dev Device) bool {
 		if s.InstanceName() != base {
 			return false
 		}
+	case snap.TypeGadget:
+		if s.InstanceName() != dev.Model().Gadget() {
+			// a remodel might leave you in this state
+			return false
+		}
 	}
 
 	return true
@@ -216,6 +238,12 @@ func bootStateFor(typ snap.Type, dev Device) (s bootState, err error) {
 		return newBootState(snap.TypeBase, dev), nil
 	case snap.TypeKernel:
 		return newBootState(snap.TypeKernel, dev), nil
+	case snap.TypeGadget:
+		// only UC20 tracks the gadget
+		if dev.HasModeenv() {
+			return newBootState(snap.TypeGadget, dev), nil
+		}
+		return nil, fmt.Errorf("internal error: no boot state handling for snap type %q", typ)
 	default:
 		return nil, fmt.Errorf("internal error: no boot state handling for snap type %q", typ)
 	}
@@ -233,8 +261,9 @@ func fixedInUse(inUse bool) InUseFunc {
 // InUse returns a checker for whether a given name/revision is used in the
 // boot environment for snaps of the relevant snap type.
 func InUse(typ snap.Type, dev Device) (InUseFunc, error) {
-	if dev.Classic() {
-		// no boot state on classic
+	if dev.Classic() && (!dev.HasModeenv() || typ != snap.TypeKernel) {
+		// no boot state on classic, besides the kernel on classic
+		// systems with modes
 		return fixedInUse(false), nil
 	}
 	if !dev.RunMode() {
@@ -298,10 +327,226 @@ func GetCurrentBoot(t snap.Type, dev Device) (snap.PlaceInfo, error) {
 	return snap, nil
 }
 
+// SnapBootStatus carries the boot state of the boot snap of a given type.
+type SnapBootStatus struct {
+	// Current is the snap the device boots by default.
+	Current snap.PlaceInfo
+	// Try is the snap set up to be tried on the next boot, or being tried
+	// right now, if any.
+	Try snap.PlaceInfo
+	// Status is the status of trying the try snap, one of DefaultStatus,
+	// TryStatus or TryingStatus.
+	Status string
+}
+
+// BootStatus carries the boot state of the kernel and base snaps of a device
+// in run mode.
+type BootStatus struct {
+	Kernel SnapBootStatus
+	Base   SnapBootStatus
+}
+
+// RebootPending returns whether a try snap was set up and the device needs to
+// be rebooted to try it.
+func (bs *BootStatus) RebootPending() bool {
+	return bs.Kernel.Status == TryStatus || bs.Base.Status == TryStatus
+}
+
+// Status returns the boot status of the kernel and base snaps of the device.
+func Status(dev Device) (*BootStatus, error) {
+	if dev.Classic() {
+		return nil, fmt.Errorf("cannot get boot status on classic")
+	}
+	if !dev.RunMode() {
+		return nil, fmt.Errorf("cannot get boot status outside of run mode")
+	}
+
+	var status BootStatus
+	for _, x := range []struct {
+		typ    snap.Type
+		status *SnapBootStatus
+	}{
+		{snap.TypeKernel, &status.Kernel},
+		{snap.TypeBase, &status.Base},
+	} {
+		st, err := snapBootStatus(x.typ, dev)
+		if err != nil {
+			return nil, err
+		}
+		*x.status = *st
+	}
+	return &status, nil
+}
+
+func snapBootStatus(typ snap.Type, dev Device) (*SnapBootStatus, error) {
+	s, err := bootStateFor(typ, dev)
+	if err != nil {
+		return nil, err
+	}
+	current, try, tryStatus, err := s.revisions()
+	if err != nil && !isTrySnapError(err) {
+		return nil, err
+	}
+	// a broken try snap is reported as no try snap at all
+	return &SnapBootStatus{
+		Current: current,
+		Try:     try,
+		Status:  tryStatus,
+	}, nil
+}
+
+// BootStateReport carries the boot state of a device as tracked by snapd.
+type BootStateReport struct {
+	// Status is the boot status of the kernel and base snaps, as returned
+	// by Status, it is only set in run mode.
+	Status *BootStatus
+	// Mode is the mode recorded in the modeenv, it is unset on devices
+	// without a modeenv.
+	Mode string
+	// RecoverySystem is the recovery system recorded in the modeenv, it
+	// is unset on devices without a modeenv.
+	RecoverySystem string
+}
+
+// QueryState returns a report of the boot state of the device. The kernel
+// and base snaps are only reported in run mode.
+func QueryState(dev Device) (*BootStateReport, error) {
+	if dev.Classic() {
+		return nil, fmt.Errorf("cannot query boot state on classic")
+	}
+
+	var report BootStateReport
+	if dev.HasModeenv() {
+		m, err := ReadModeenv("")
+		if err != nil {
+			return nil, fmt.Errorf("cannot query boot state: %v", err)
+		}
+		report.Mode = m.Mode
+		report.RecoverySystem = m.RecoverySystem
+	}
+	if !dev.RunMode() {
+		return &report, nil
+	}
+
+	status, err := Status(dev)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query boot state: %v", err)
+	}
+	report.Status = status
+	return &report, nil
+}
+
+// LastBootIssues returns the descriptions of the issues the initramfs worked
+// around during the last boot, such as falling back from a try snap or
+// recovering a broken modeenv, which leave the system in a degraded state.
+// There are none on devices without a modeenv.
+func LastBootIssues() ([]string, error) {
+	m, err := ReadModeenv("")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the last boot issues: %v", err)
+	}
+	return m.BootIssues, nil
+}
+
+// CancelTry drops the try kernel and base snaps that were set up to be tried
+// on the next boot, so that the device boots the current snaps instead. Snaps
+// which are already being tried are left alone. The caller must make sure
+// that no change setting up the kernel or base snaps is in progress, as such
+// changes expect the device to boot into the try snaps.
+func CancelTry(dev Device) error {
+	if dev.Classic() {
+		return fmt.Errorf("cannot cancel try snaps on classic")
+	}
+	if !dev.RunMode() {
+		return fmt.Errorf("cannot cancel try snaps outside of run mode")
+	}
+
+	for _, typ := range []snap.Type{snap.TypeKernel, snap.TypeBase} {
+		s, err := bootStateFor(typ, dev)
+		if err != nil {
+			return err
+		}
+		current, _, tryStatus, err := s.revisions()
+		if err != nil && !isTrySnapError(err) {
+			return err
+		}
+		if tryStatus != TryStatus {
+			continue
+		}
+		// setting the current snap as the next one drops the pending
+		// try snap
+		_, u, err := s.setNext(current)
+		if err != nil {
+			return fmt.Errorf("cannot cancel try %s: %v", typ, err)
+		}
+		if u == nil {
+			continue
+		}
+		if err := u.commit(); err != nil {
+			return fmt.Errorf("cannot cancel try %s: %v", typ, err)
+		}
+	}
+	return nil
+}
+
+// revertableBootState is implemented by the boot states of the snaps that
+// can be reverted to a previous revision.
+type revertableBootState interface {
+	// revertTo lazily implements pointing the boot configuration of the
+	// type's boot snap back to the given previous snap, dropping any try
+	// snap. The actual committing of the update is done via the returned
+	// bootStateUpdate's commit method.
+	revertTo(prev snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error)
+}
+
+// RevertTo points the boot configuration of the kernel or base snap of the
+// device back to the given previous revision, which must still be installed,
+// dropping any try snap. The previous revision is trusted before the
+// bootloader is pointed to it, and the other revisions are distrusted only
+// once the bootloader cannot boot them anymore. The revert takes effect on
+// the next boot, so a reboot is required unless the previous revision is the
+// one that was booted.
+func RevertTo(dev Device, typ snap.Type, prev snap.PlaceInfo) (rebootRequired bool, err error) {
+	if dev.Classic() {
+		return false, fmt.Errorf("cannot revert boot snaps on classic")
+	}
+	if !dev.RunMode() {
+		return false, fmt.Errorf("cannot revert boot snaps outside of run mode")
+	}
+	if !osutil.FileExists(filepath.Join(dirs.SnapBlobDir, prev.Filename())) {
+		return false, fmt.Errorf("cannot revert %s to %q: snap file does not exist", typ, prev.Filename())
+	}
+
+	s, err := bootStateFor(typ, dev)
+	if err != nil {
+		return false, err
+	}
+	rs, ok := s.(revertableBootState)
+	if !ok {
+		return false, fmt.Errorf("internal error: cannot revert snap type %q", typ)
+	}
+	rebootRequired, u, err := rs.revertTo(prev)
+	if err != nil {
+		return false, fmt.Errorf("cannot revert %s to %q: %v", typ, prev.Filename(), err)
+	}
+	if u != nil {
+		if err := u.commit(); err != nil {
+			return false, fmt.Errorf("cannot revert %s to %q: %v", typ, prev.Filename(), err)
+		}
+	}
+	return rebootRequired, nil
+}
+
 // bootStateUpdate carries the state for an on-going boot state update.
 // At the end it can be used to commit it.
 type bootStateUpdate interface {
 	commit() error
+	// describe returns descriptions of the changes commit would
+	// perform, in order, without performing them
+	describe() ([]string, error)
 }
 
 // MarkBootSuccessful marks the current boot as successful. This means
@@ -325,22 +570,42 @@ type bootStateUpdate interface {
 //   will set snap_mode="" and the system will boot with the known good
 //   values from snap_{core,kernel}
 func MarkBootSuccessful(dev Device) error {
+	_, err := MarkBootSuccessfulWithOptions(dev, nil)
+	return err
+}
+
+// MarkSuccessfulOptions carries options for MarkBootSuccessfulWithOptions.
+type MarkSuccessfulOptions struct {
+	// DryRun when set computes the changes needed to mark the boot
+	// successful without performing them.
+	DryRun bool
+}
+
+// MarkBootSuccessfulWithOptions is like MarkBootSuccessful but with
+// options, in particular it can just report the planned changes without
+// performing them.
+func MarkBootSuccessfulWithOptions(dev Device, opts *MarkSuccessfulOptions) (*BootChanges, error) {
 	const errPrefix = "cannot mark boot successful: %s"
 
+	if opts == nil {
+		opts = &MarkSuccessfulOptions{}
+	}
+
 	var u bootStateUpdate
 	for _, t := range []snap.Type{snap.TypeBase, snap.TypeKernel} {
 		s, err := bootStateFor(t, dev)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		u, err = s.markSuccessful(u)
 		if err != nil {
-			return fmt.Errorf(errPrefix, err)
+			return nil, fmt.Errorf(errPrefix, err)
 		}
 	}
 
 	if dev.HasModeenv() {
 		for _, bs := range []successfulBootState{
+			newBootState20(snap.TypeGadget, dev),
 			trustedAssetsBootState(dev),
 			trustedCommandLineBootState(dev),
 			recoverySystemsBootState(dev),
@@ -348,17 +613,27 @@ func MarkBootSuccessful(dev Device) error {
 			var err error
 			u, err = bs.markSuccessful(u)
 			if err != nil {
-				return fmt.Errorf(errPrefix, err)
+				return nil, fmt.Errorf(errPrefix, err)
 			}
 		}
 	}
 
-	if u != nil {
-		if err := u.commit(); err != nil {
-			return fmt.Errorf(errPrefix, err)
+	changes := &BootChanges{}
+	if u == nil {
+		return changes, nil
+	}
+	if opts.DryRun {
+		var err error
+		changes.Changes, err = u.describe()
+		if err != nil {
+			return nil, fmt.Errorf(errPrefix, err)
 		}
+		return changes, nil
 	}
-	return nil
+	if err := u.commit(); err != nil {
+		return nil, fmt.Errorf(errPrefix, err)
+	}
+	return changes, nil
 }
 
 var ErrUnsupportedSystemMode = errors.New("system mode is unsupported")
@@ -397,6 +672,28 @@ func SetRecoveryBootSystemAndMode(dev Device, systemLabel, mode string) error {
 	return bl.SetBootVars(m)
 }
 
+// SetKernelRetention sets the number of kernels that are kept trusted in the
+// modeenv under the given root directory once a kernel booted successfully,
+// including that kernel, such that it is possible to roll back to the
+// previous kernels. Systems without a modeenv are left alone.
+func SetKernelRetention(rootdir string, kernels int) error {
+	if kernels < 0 {
+		return fmt.Errorf("cannot retain a negative number of kernels")
+	}
+	m, err := ReadModeenv(rootdir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.KernelRetention == kernels {
+		return nil
+	}
+	m.KernelRetention = kernels
+	return m.Write()
+}
+
 // UpdateManagedBootConfigs updates managed boot config assets if those are
 // present for the ubuntu-boot bootloader. Returns true when an update was
 // carried out.
@@ -464,6 +464,33 @@ func (s *bootenv20EnvRefKernelSuite) TestCurrentBoot20NameAndRevision(c *C) {
 	c.Assert(current.SnapRevision(), Equals, snap.R(1))
 }
 
+func (s *bootenv20EnvRefKernelSuite) TestCurrentBoot20NameAndRevisionUnhappy(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				CurrentKernels: []string{s.kern1.Filename()},
+			},
+			// no kernel set in the bootenv
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	_, err := boot.GetCurrentBoot(snap.TypeKernel, coreDev)
+	c.Check(err, ErrorMatches, `cannot identify kernel snap with bootloader mock: snap_kernel is unset`)
+
+	s.bootloader.SetBootVars(map[string]string{"snap_kernel": "not-a-snap"})
+	_, err = boot.GetCurrentBoot(snap.TypeKernel, coreDev)
+	c.Check(err, ErrorMatches, `cannot identify kernel snap with bootloader mock: .*`)
+}
+
 func (s *bootenvSuite) TestCurrentBootNameAndRevisionUnhappy(c *C) {
 	coreDev := boottest.MockDevice("some-snap")
 
@@ -498,6 +525,260 @@ func (s *bootenvSuite) TestCurrentBootNameAndRevisionUnhappy(c *C) {
 	c.Check(err, ErrorMatches, "cannot get boot settings: broken bootloader")
 }
 
+func (s *bootenvSuite) TestStatus(c *C) {
+	coreDev := boottest.MockDevice("some-snap")
+
+	s.bootloader.BootVars["snap_core"] = "core_2.snap"
+	s.bootloader.BootVars["snap_kernel"] = "canonical-pc-linux_2.snap"
+	s.bootloader.BootVars["snap_try_kernel"] = "canonical-pc-linux_3.snap"
+	s.bootloader.BootVars["snap_mode"] = boot.TryStatus
+
+	status, err := boot.Status(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(status.Kernel.Current, DeepEquals, snap.MinimalPlaceInfo("canonical-pc-linux", snap.R(2)))
+	c.Check(status.Kernel.Try, DeepEquals, snap.MinimalPlaceInfo("canonical-pc-linux", snap.R(3)))
+	c.Check(status.Kernel.Status, Equals, boot.TryStatus)
+	c.Check(status.Base.Current, DeepEquals, snap.MinimalPlaceInfo("core", snap.R(2)))
+	c.Check(status.Base.Try, IsNil)
+	c.Check(status.RebootPending(), Equals, true)
+
+	s.bootloader.BootVars["snap_mode"] = boot.TryingStatus
+	status, err = boot.Status(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(status.Kernel.Status, Equals, boot.TryingStatus)
+	c.Check(status.RebootPending(), Equals, false)
+}
+
+func (s *bootenvSuite) TestStatusUnhappy(c *C) {
+	_, err := boot.Status(boottest.MockDevice(""))
+	c.Check(err, ErrorMatches, "cannot get boot status on classic")
+
+	_, err = boot.Status(boottest.MockDevice("some-snap@recover"))
+	c.Check(err, ErrorMatches, "cannot get boot status outside of run mode")
+}
+
+func (s *bootenvSuite) TestQueryState(c *C) {
+	coreDev := boottest.MockDevice("some-snap")
+
+	s.bootloader.BootVars["snap_core"] = "core_2.snap"
+	s.bootloader.BootVars["snap_try_core"] = "core_3.snap"
+	s.bootloader.BootVars["snap_kernel"] = "canonical-pc-linux_2.snap"
+	s.bootloader.BootVars["snap_mode"] = boot.TryingStatus
+
+	report, err := boot.QueryState(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(report, DeepEquals, &boot.BootStateReport{
+		Status: &boot.BootStatus{
+			Kernel: boot.SnapBootStatus{
+				Current: snap.MinimalPlaceInfo("canonical-pc-linux", snap.R(2)),
+				Status:  boot.TryingStatus,
+			},
+			Base: boot.SnapBootStatus{
+				Current: snap.MinimalPlaceInfo("core", snap.R(2)),
+				Try:     snap.MinimalPlaceInfo("core", snap.R(3)),
+				Status:  boot.TryingStatus,
+			},
+		},
+	})
+}
+
+func (s *bootenvSuite) TestQueryStateUnhappy(c *C) {
+	_, err := boot.QueryState(boottest.MockDevice(""))
+	c.Check(err, ErrorMatches, "cannot query boot state on classic")
+
+	s.bootloader.GetErr = errors.New("zap")
+	_, err = boot.QueryState(boottest.MockDevice("some-snap"))
+	c.Check(err, ErrorMatches, "cannot query boot state: cannot get boot variables: zap")
+}
+
+func (s *bootenvSuite) TestCancelTry(c *C) {
+	coreDev := boottest.MockDevice("some-snap")
+
+	s.bootloader.BootVars["snap_core"] = "core_2.snap"
+	s.bootloader.BootVars["snap_kernel"] = "canonical-pc-linux_2.snap"
+	s.bootloader.BootVars["snap_try_kernel"] = "canonical-pc-linux_3.snap"
+	s.bootloader.BootVars["snap_mode"] = boot.TryStatus
+
+	err := boot.CancelTry(coreDev)
+	c.Assert(err, IsNil)
+
+	m, err := s.bootloader.GetBootVars("snap_mode", "snap_kernel", "snap_try_kernel", "snap_core", "snap_try_core")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snap_mode":       boot.DefaultStatus,
+		"snap_kernel":     "canonical-pc-linux_2.snap",
+		"snap_try_kernel": "",
+		"snap_core":       "core_2.snap",
+		"snap_try_core":   "",
+	})
+}
+
+func (s *bootenvSuite) TestCancelTryLeavesTryingAlone(c *C) {
+	coreDev := boottest.MockDevice("some-snap")
+
+	s.bootloader.BootVars["snap_core"] = "core_2.snap"
+	s.bootloader.BootVars["snap_kernel"] = "canonical-pc-linux_2.snap"
+	s.bootloader.BootVars["snap_try_kernel"] = "canonical-pc-linux_3.snap"
+	s.bootloader.BootVars["snap_mode"] = boot.TryingStatus
+
+	err := boot.CancelTry(coreDev)
+	c.Assert(err, IsNil)
+
+	m, err := s.bootloader.GetBootVars("snap_mode", "snap_try_kernel")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snap_mode":       boot.TryingStatus,
+		"snap_try_kernel": "canonical-pc-linux_3.snap",
+	})
+}
+
+func (s *bootenv20Suite) TestStatus20(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				TryBase:        s.base2.Filename(),
+				BaseStatus:     boot.TryStatus,
+				CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+			},
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryStatus,
+		},
+	)
+	defer r()
+
+	status, err := boot.Status(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, &boot.BootStatus{
+		Kernel: boot.SnapBootStatus{
+			Current: s.kern1,
+			Try:     s.kern2,
+			Status:  boot.TryStatus,
+		},
+		Base: boot.SnapBootStatus{
+			Current: s.base1,
+			Try:     s.base2,
+			Status:  boot.TryStatus,
+		},
+	})
+	c.Check(status.RebootPending(), Equals, true)
+}
+
+func (s *bootenv20Suite) TestQueryState20(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				RecoverySystem: "20210101",
+				Base:           s.base1.Filename(),
+				CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+			},
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryStatus,
+		},
+	)
+	defer r()
+
+	report, err := boot.QueryState(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(report, DeepEquals, &boot.BootStateReport{
+		Status: &boot.BootStatus{
+			Kernel: boot.SnapBootStatus{
+				Current: s.kern1,
+				Try:     s.kern2,
+				Status:  boot.TryStatus,
+			},
+			Base: boot.SnapBootStatus{
+				Current: s.base1,
+				Status:  boot.DefaultStatus,
+			},
+		},
+		Mode:           "run",
+		RecoverySystem: "20210101",
+	})
+}
+
+func (s *bootenv20Suite) TestQueryState20RecoverMode(c *C) {
+	coreDev := boottest.MockUC20Device("recover", nil)
+
+	m := &boot.Modeenv{
+		Mode:           "recover",
+		RecoverySystem: "20210101",
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	// the boot snaps are not reported outside of run mode
+	report, err := boot.QueryState(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(report, DeepEquals, &boot.BootStateReport{
+		Mode:           "recover",
+		RecoverySystem: "20210101",
+	})
+}
+
+func (s *bootenv20Suite) TestQueryState20NoModeenv(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+
+	_, err := boot.QueryState(coreDev)
+	c.Check(err, ErrorMatches, "cannot query boot state: open .*/modeenv: no such file or directory")
+}
+
+func (s *bootenv20Suite) TestCancelTry20(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				TryBase:        s.base2.Filename(),
+				BaseStatus:     boot.TryStatus,
+				CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+			},
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryStatus,
+		},
+	)
+	defer r()
+
+	err := boot.CancelTry(coreDev)
+	c.Assert(err, IsNil)
+
+	// the try kernel is gone
+	c.Check(s.bootloader.BootVars["kernel_status"], Equals, boot.DefaultStatus)
+	_, err = s.bootloader.TryKernel()
+	c.Check(err, Equals, bootloader.ErrNoTryKernelRef)
+
+	// and so is the try base
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+	c.Check(m.Base, Equals, s.base1.Filename())
+	c.Check(m.TryBase, Equals, "")
+	c.Check(m.BaseStatus, Equals, boot.DefaultStatus)
+
+	status, err := boot.Status(coreDev)
+	c.Assert(err, IsNil)
+	c.Check(status.RebootPending(), Equals, false)
+}
+
 func (s *bootenvSuite) TestParticipant(c *C) {
 	info := &snap.Info{}
 	info.RealName = "some-snap"
@@ -508,6 +789,10 @@ func (s *bootenvSuite) TestParticipant(c *C) {
 	bp := boot.Participant(info, snap.TypeApp, coreDev)
 	c.Check(bp.IsTrivial(), Equals, true)
 
+	// the gadget only participates on UC20
+	bp = boot.Participant(info, snap.TypeGadget, coreDev)
+	c.Check(bp.IsTrivial(), Equals, true)
+
 	for _, typ := range []snap.Type{
 		snap.TypeKernel,
 		snap.TypeOS,
@@ -808,6 +1093,94 @@ func (s *bootenv20EnvRefKernelSuite) TestCoreParticipant20SetNextSameKernelSnap(
 	c.Assert(s.bootloader.SetBootVarsCalls, Equals, 0)
 }
 
+func (s *bootenv20Suite) TestCoreParticipant20SetNextSameKernelSnapUndoesTry(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	// kern2 was setup as try kernel but never booted
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+			},
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryStatus,
+		},
+	)
+	defer r()
+
+	// going back to kern1 as part of undo
+	bootKern := boot.Participant(s.kern1, snap.TypeKernel, coreDev)
+	rebootRequired, err := bootKern.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Assert(rebootRequired, Equals, false)
+
+	// kernel_status is reset
+	c.Assert(s.bootloader.BootVars["kernel_status"], Equals, boot.DefaultStatus)
+
+	// the try kernel was disabled
+	_, nDisableTryCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
+	c.Assert(nDisableTryCalls, Equals, 1)
+	_, err = s.bootloader.TryKernel()
+	c.Assert(err, Equals, bootloader.ErrNoTryKernelRef)
+
+	// and kern1 is still the enabled kernel
+	_, enableKernelCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Assert(enableKernelCalls, Equals, 0)
+
+	// the modeenv no longer lists kern2
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+}
+
+func (s *bootenv20EnvRefKernelSuite) TestCoreParticipant20SetNextSameKernelSnapUndoesTry(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	// kern2 was setup as try kernel but never booted
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+			},
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryStatus,
+		},
+	)
+	defer r()
+
+	// going back to kern1 as part of undo
+	bootKern := boot.Participant(s.kern1, snap.TypeKernel, coreDev)
+	rebootRequired, err := bootKern.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Assert(rebootRequired, Equals, false)
+
+	// the bootenv no longer references kern2
+	m, err := s.bootloader.GetBootVars("kernel_status", "snap_kernel", "snap_try_kernel")
+	c.Assert(err, IsNil)
+	c.Assert(m, DeepEquals, map[string]string{
+		"kernel_status":   boot.DefaultStatus,
+		"snap_kernel":     s.kern1.Filename(),
+		"snap_try_kernel": "",
+	})
+
+	// and neither does the modeenv
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+}
+
 func (s *bootenv20Suite) TestCoreParticipant20SetNextNewKernelSnap(c *C) {
 	coreDev := boottest.MockUC20Device("", nil)
 	c.Assert(coreDev.HasModeenv(), Equals, true)
@@ -846,17 +1219,50 @@ func (s *bootenv20Suite) TestCoreParticipant20SetNextNewKernelSnap(c *C) {
 	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename(), s.kern2.Filename()})
 }
 
-func (s *bootenv20Suite) TestCoreParticipant20SetNextNewKernelSnapWithReseal(c *C) {
-	// checked by resealKeyToModeenv
-	s.stampSealedKeys(c, dirs.GlobalRootDir)
-
-	tab := s.bootloaderWithTrustedAssets(c, []string{"asset"})
+func (s *bootenv20Suite) TestSetNextBootWithOptions20DryRun(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
 
-	data := []byte("foobar")
-	// SHA3-384
-	dataHash := "0fa8abfbdaf924ad307b74dd2ed183b9a4a398891a2f6bac8fd2db7041b77f068580f9c6c66f699b496c2da1cbcc7ed8"
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		s.normalDefaultState,
+	)
+	defer r()
 
-	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuBootDir), 0755), IsNil)
+	changes, err := boot.SetNextBootWithOptions(boot.Participant(s.kern2, snap.TypeKernel, coreDev),
+		&boot.SetNextOptions{DryRun: true})
+	c.Assert(err, IsNil)
+	c.Check(changes, DeepEquals, &boot.BootChanges{
+		RebootRequired: true,
+		Changes: []string{
+			"set modeenv current_kernels=" + s.kern1.Filename() + "," + s.kern2.Filename(),
+			"reseal the encryption keys if needed",
+			fmt.Sprintf(`set kernel %s with status "try" as next in the bootloader`, s.kern2.Filename()),
+		},
+	})
+
+	// no try kernel was enabled
+	c.Check(s.bootloader.BootVars["kernel_status"], Equals, boot.DefaultStatus)
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableTryKernel")
+	c.Check(actual, HasLen, 0)
+
+	// and the modeenv is unchanged
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+}
+
+func (s *bootenv20Suite) TestCoreParticipant20SetNextNewKernelSnapWithReseal(c *C) {
+	// checked by resealKeyToModeenv
+	s.stampSealedKeys(c, dirs.GlobalRootDir)
+
+	tab := s.bootloaderWithTrustedAssets(c, []string{"asset"})
+
+	data := []byte("foobar")
+	// SHA3-384
+	dataHash := "0fa8abfbdaf924ad307b74dd2ed183b9a4a398891a2f6bac8fd2db7041b77f068580f9c6c66f699b496c2da1cbcc7ed8"
+
+	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuBootDir), 0755), IsNil)
 	c.Assert(os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir), 0755), IsNil)
 	c.Assert(ioutil.WriteFile(filepath.Join(boot.InitramfsUbuntuBootDir, "asset"), data, 0644), IsNil)
 	c.Assert(ioutil.WriteFile(filepath.Join(boot.InitramfsUbuntuSeedDir, "asset"), data, 0644), IsNil)
@@ -1507,6 +1913,41 @@ func (s *bootenv20Suite) TestCoreParticipant20SetNextSameBaseSnap(c *C) {
 	c.Assert(m2.TryBase, Equals, m.TryBase)
 }
 
+func (s *bootenv20Suite) TestCoreParticipant20SetNextSameBaseSnapUndoesTry(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	// base2 was setup as try base but never booted
+	m := &boot.Modeenv{
+		Mode:       "run",
+		Base:       s.base1.Filename(),
+		TryBase:    s.base2.Filename(),
+		BaseStatus: boot.TryStatus,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: m,
+			// no kernel setup necessary
+		},
+	)
+	defer r()
+
+	// going back to base1 as part of undo
+	bootBase := boot.Participant(s.base1, snap.TypeBase, coreDev)
+	rebootRequired, err := bootBase.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Assert(rebootRequired, Equals, false)
+
+	// the pending try base is gone
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.Base, Equals, s.base1.Filename())
+	c.Assert(m2.BaseStatus, Equals, boot.DefaultStatus)
+	c.Assert(m2.TryBase, Equals, "")
+}
+
 func (s *bootenv20Suite) TestCoreParticipant20SetNextNewBaseSnap(c *C) {
 	coreDev := boottest.MockUC20Device("", nil)
 	c.Assert(coreDev.HasModeenv(), Equals, true)
@@ -1544,6 +1985,52 @@ func (s *bootenv20Suite) TestCoreParticipant20SetNextNewBaseSnap(c *C) {
 	c.Assert(m2.TryBase, Equals, s.base2.Filename())
 }
 
+func (s *bootenv20Suite) TestCoreParticipant20SetNextNewCoreSnap(c *C) {
+	// a system migrated from core, where the core snap is the base
+	model := boottest.MakeMockUC20Model(map[string]interface{}{
+		"base": "core",
+	})
+	coreDev := boottest.MockUC20Device("", model)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	core1, err := snap.ParsePlaceInfoFromSnapFileName("core_1.snap")
+	c.Assert(err, IsNil)
+	core2, err := snap.ParsePlaceInfoFromSnapFileName("core_2.snap")
+	c.Assert(err, IsNil)
+
+	// default state
+	m := &boot.Modeenv{
+		Mode: "run",
+		Base: core1.Filename(),
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: m,
+			// no kernel setup necessary
+		},
+	)
+	defer r()
+
+	// get the boot participant from the new core snap
+	bootCore := boot.Participant(core2, snap.TypeOS, coreDev)
+	// make sure it's not a trivial boot participant
+	c.Assert(bootCore.IsTrivial(), Equals, false)
+
+	// make the core snap used on next boot
+	rebootRequired, err := bootCore.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Assert(rebootRequired, Equals, true)
+
+	// make sure the modeenv was updated
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.Base, Equals, m.Base)
+	c.Assert(m2.BaseStatus, Equals, boot.TryStatus)
+	c.Assert(m2.TryBase, Equals, core2.Filename())
+}
+
 func (s *bootenv20Suite) TestCoreParticipant20SetNextNewBaseSnapNoReseal(c *C) {
 	// checked by resealKeyToModeenv
 	s.stampSealedKeys(c, dirs.GlobalRootDir)
@@ -1625,6 +2112,106 @@ func (s *bootenvSuite) TestMarkBootSuccessfulAllSnap(c *C) {
 	c.Assert(s.bootloader.BootVars, DeepEquals, expected)
 }
 
+func (s *bootenv20Suite) TestCoreParticipant20SetNextGadgetSnap(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	// default state
+	m := &boot.Modeenv{
+		Mode:   "run",
+		Base:   s.base1.Filename(),
+		Gadget: "pc_1.snap",
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: m,
+			// no kernel setup necessary
+		},
+	)
+	defer r()
+
+	gadget2, err := snap.ParsePlaceInfoFromSnapFileName("pc_2.snap")
+	c.Assert(err, IsNil)
+
+	// the gadget participates in the boot on UC20
+	bootGadget := boot.Participant(gadget2, snap.TypeGadget, coreDev)
+	c.Assert(bootGadget.IsTrivial(), Equals, false)
+
+	// no boot relevant content changed, so no reboot is needed
+	rebootRequired, err := bootGadget.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Assert(rebootRequired, Equals, false)
+
+	// make sure the modeenv was updated
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.Gadget, Equals, "pc_2.snap")
+	c.Assert(m2.Base, Equals, m.Base)
+}
+
+func (s *bootenv20Suite) TestCoreParticipant20SetNextGadgetSnapBootContentPending(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	gadget2, err := snap.ParsePlaceInfoFromSnapFileName("pc_2.snap")
+	c.Assert(err, IsNil)
+
+	for _, m := range []*boot.Modeenv{
+		{
+			// modeenv written by an older snapd, with a new
+			// trusted boot asset observed during the update
+			Mode: "run",
+			Base: s.base1.Filename(),
+			CurrentTrustedBootAssets: boot.BootAssetsMap{
+				"asset": []string{"oldhash", "newhash"},
+			},
+		}, {
+			Mode:   "run",
+			Base:   s.base1.Filename(),
+			Gadget: "pc_1.snap",
+			CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+				"asset": []string{"oldhash", "newhash"},
+			},
+		}, {
+			// new kernel command line from the gadget
+			Mode:   "run",
+			Base:   s.base1.Filename(),
+			Gadget: "pc_1.snap",
+			CurrentKernelCommandLines: boot.BootCommandLines{
+				"snapd_recovery_mode=run",
+				"snapd_recovery_mode=run extra=1",
+			},
+		},
+	} {
+		r := setupUC20Bootenv(c, s.bootloader, &bootenv20Setup{modeenv: m})
+
+		rebootRequired, err := boot.Participant(gadget2, snap.TypeGadget, coreDev).SetNextBoot()
+		c.Assert(err, IsNil)
+		c.Check(rebootRequired, Equals, true)
+
+		m2, err := boot.ReadModeenv("")
+		c.Assert(err, IsNil)
+		c.Check(m2.Gadget, Equals, "pc_2.snap")
+		c.Check(m2.CurrentTrustedBootAssets, DeepEquals, m.CurrentTrustedBootAssets)
+		c.Check(m2.CurrentKernelCommandLines, DeepEquals, m.CurrentKernelCommandLines)
+
+		r()
+	}
+}
+
+func (s *bootenv20Suite) TestCoreParticipant20GadgetNotFromModel(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+
+	otherGadget, err := snap.ParsePlaceInfoFromSnapFileName("other-gadget_1.snap")
+	c.Assert(err, IsNil)
+
+	// a remodel might leave you in this state
+	bootGadget := boot.Participant(otherGadget, snap.TypeGadget, coreDev)
+	c.Check(bootGadget.IsTrivial(), Equals, true)
+}
+
 func (s *bootenv20Suite) TestMarkBootSuccessful20AllSnap(c *C) {
 	coreDev := boottest.MockUC20Device("", nil)
 	c.Assert(coreDev.HasModeenv(), Equals, true)
@@ -1796,50 +2383,461 @@ func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdate(c *C) {
 		s.bootloader,
 		&bootenv20Setup{
 			modeenv:    m,
-			kern:       s.kern1,
-			tryKern:    s.kern2,
-			kernStatus: boot.TryingStatus,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	// mark successful
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	// check the bootloader variables
+	expected := map[string]string{"kernel_status": boot.DefaultStatus}
+	c.Assert(s.bootloader.BootVars, DeepEquals, expected)
+
+	// check that MarkBootSuccessful enabled the try kernel
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Assert(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+
+	// and that we disabled a try kernel
+	_, nDisableTryCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
+	c.Assert(nDisableTryCalls, Equals, 1)
+
+	// check that the new kernel is the only one in modeenv
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename()})
+
+	// do it again, verify its still valid
+	err = boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+	c.Assert(s.bootloader.BootVars, DeepEquals, expected)
+
+	// no new bootloader calls
+	actual, _ = s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Assert(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+
+	// we did disable the kernel again because we always do this to cleanup in
+	// case there were leftovers
+	_, nDisableTryCalls = s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
+	c.Assert(nDisableTryCalls, Equals, 2)
+}
+
+func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdateKeepsUnknownModeenvKeys(c *C) {
+	// trying a kernel snap
+	m := &boot.Modeenv{
+		Mode:           "run",
+		Base:           s.base1.Filename(),
+		CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+
+	// the modeenv was written by a newer snap-bootstrap
+	f, err := os.OpenFile(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir), os.O_APPEND|os.O_WRONLY, 0644)
+	c.Assert(err, IsNil)
+	_, err = f.WriteString("newer_key=newer value\n")
+	c.Assert(err, IsNil)
+	c.Assert(f.Close(), IsNil)
+	// without a backup copy to validate the modeenv against
+	c.Assert(os.Remove(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir)+".backup"), IsNil)
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err = boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	// the modeenv was updated, and the key is kept
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename()})
+	c.Check(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir), testutil.FileContains, "\nnewer_key=newer value\n")
+}
+
+func (s *bootenvSuite) TestLastBootIssuesNoModeenv(c *C) {
+	issues, err := boot.LastBootIssues()
+	c.Assert(err, IsNil)
+	c.Check(issues, HasLen, 0)
+}
+
+func (s *bootenv20Suite) TestLastBootIssues(c *C) {
+	m := &boot.Modeenv{
+		Mode:       "run",
+		Base:       s.base1.Filename(),
+		BootIssues: []string{"something went wrong, but not too wrong"},
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	issues, err := boot.LastBootIssues()
+	c.Assert(err, IsNil)
+	c.Check(issues, DeepEquals, []string{"something went wrong, but not too wrong"})
+
+	m.BootIssues = nil
+	c.Assert(m.WriteTo(""), IsNil)
+	issues, err = boot.LastBootIssues()
+	c.Assert(err, IsNil)
+	c.Check(issues, HasLen, 0)
+}
+
+func (s *bootenv20Suite) TestClassicWithModesKernelTryCycle(c *C) {
+	m := &boot.Modeenv{
+		Mode:           "run",
+		Classic:        true,
+		CurrentKernels: []string{s.kern1.Filename()},
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	dev := boottest.MockClassicWithModesDevice("", nil)
+
+	// the base does not participate
+	c.Check(boot.Participant(s.base2, snap.TypeBase, dev).IsTrivial(), Equals, true)
+	bootKern := boot.Participant(s.kern2, snap.TypeKernel, dev)
+	c.Assert(bootKern.IsTrivial(), Equals, false)
+
+	rebootRequired, err := bootKern.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
+	c.Check(s.bootloader.BootVars["kernel_status"], Equals, boot.TryStatus)
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableTryKernel")
+	c.Check(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename(), s.kern2.Filename()})
+	c.Check(m2.Base, Equals, "")
+
+	// both kernels are in use, unlike any base
+	inUse, err := boot.InUse(snap.TypeKernel, dev)
+	c.Assert(err, IsNil)
+	c.Check(inUse(s.kern2.SnapName(), s.kern2.SnapRevision()), Equals, true)
+	inUse, err = boot.InUse(snap.TypeBase, dev)
+	c.Assert(err, IsNil)
+	c.Check(inUse(s.base1.SnapName(), s.base1.SnapRevision()), Equals, false)
+
+	// the boot script tries the kernel
+	s.bootloader.BootVars["kernel_status"] = boot.TryingStatus
+
+	c.Assert(boot.MarkBootSuccessful(dev), IsNil)
+	c.Check(s.bootloader.BootVars["kernel_status"], Equals, boot.DefaultStatus)
+	actual, _ = s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Check(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+
+	m2, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename()})
+	c.Check(m2.Base, Equals, "")
+	c.Check(m2.BaseStatus, Equals, boot.DefaultStatus)
+}
+
+func (s *bootenv20Suite) TestClassicWithModesNoBaseRevert(c *C) {
+	m := &boot.Modeenv{
+		Mode:           "run",
+		Classic:        true,
+		CurrentKernels: []string{s.kern1.Filename()},
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	mockSnapBlobs(c, s.base1)
+
+	dev := boottest.MockUC20Device("", nil)
+	_, err := boot.RevertTo(dev, snap.TypeBase, s.base1)
+	c.Assert(err, ErrorMatches, `cannot revert base to "core20_1.snap": no base on classic systems with modes`)
+}
+
+func (s *bootenv20Suite) TestMarkBootSuccessfulWithOptions20DryRun(c *C) {
+	// trying a kernel snap
+	m := &boot.Modeenv{
+		Mode:           "run",
+		Base:           s.base1.Filename(),
+		CurrentKernels: []string{s.kern1.Filename(), s.kern2.Filename()},
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+
+	coreDev := boottest.MockUC20Device("", nil)
+
+	changes, err := boot.MarkBootSuccessfulWithOptions(coreDev, &boot.MarkSuccessfulOptions{DryRun: true})
+	c.Assert(err, IsNil)
+	c.Check(changes, DeepEquals, &boot.BootChanges{
+		Changes: []string{
+			fmt.Sprintf("mark kernel %s successful in the bootloader", s.kern2.Filename()),
+			"reset the kernel boot attempts in the bootloader",
+			"set modeenv current_kernels=" + s.kern2.Filename(),
+			"reseal the encryption keys if needed",
+		},
+	})
+
+	// nothing was changed in the bootloader
+	c.Check(s.bootloader.BootVars["kernel_status"], Equals, boot.TryingStatus)
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Check(actual, HasLen, 0)
+	_, nDisableTryCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
+	c.Check(nDisableTryCalls, Equals, 0)
+
+	// nor in the modeenv
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename(), s.kern2.Filename()})
+}
+
+func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdateResetsBootAttempts(c *C) {
+	// trying a kernel snap for the second time
+	m := &boot.Modeenv{
+		Mode:                  "run",
+		Base:                  s.base1.Filename(),
+		CurrentKernels:        []string{s.kern1.Filename(), s.kern2.Filename()},
+		MaxKernelBootAttempts: 3,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+	s.bootloader.BootVars["kernel_boot_attempts"] = "2"
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"kernel_status":        boot.DefaultStatus,
+		"kernel_boot_attempts": "",
+	})
+}
+
+func (s *bootenv20Suite) TestCoreParticipant20SetNextNewKernelSnapResetsBootAttempts(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+
+	m := &boot.Modeenv{
+		Mode:                  "run",
+		Base:                  s.base1.Filename(),
+		CurrentKernels:        []string{s.kern1.Filename()},
+		MaxKernelBootAttempts: 3,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+	// left over from an earlier try kernel
+	s.bootloader.BootVars["kernel_boot_attempts"] = "1"
+
+	bootKern := boot.Participant(s.kern2, snap.TypeKernel, coreDev)
+	rebootRequired, err := bootKern.SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Assert(rebootRequired, Equals, true)
+
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"kernel_status":        boot.TryStatus,
+		"kernel_boot_attempts": "",
+	})
+}
+
+func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdateRetainsPreviousKernels(c *C) {
+	kern3, err := snap.ParsePlaceInfoFromSnapFileName("pc-kernel_3.snap")
+	c.Assert(err, IsNil)
+
+	// trying a kernel snap, with one previous kernel retained
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename(), s.kern2.Filename(), kern3.Filename()},
+		KernelRetention: 2,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern2,
+			tryKern:    kern3,
+			kernStatus: boot.TryingStatus,
+		},
+	)
+	defer r()
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err = boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	// the oldest kernel is not retained anymore
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename(), kern3.Filename()})
+}
+
+func (s *bootenv20Suite) TestMarkBootSuccessful20KernelFailedDoesNotRetainTryKernel(c *C) {
+	// the try kernel failed to boot and the bootloader fell back to the
+	// current kernel
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename(), s.kern2.Filename()},
+		KernelRetention: 2,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			tryKern:    s.kern2,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+}
+
+func (s *bootenv20Suite) TestCoreParticipant20SetNextRetainedKernel(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename(), s.kern2.Filename()},
+		KernelRetention: 2,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern2,
+			kernStatus: boot.DefaultStatus,
 		},
 	)
 	defer r()
 
-	coreDev := boottest.MockUC20Device("", nil)
-	c.Assert(coreDev.HasModeenv(), Equals, true)
+	// going back to the retained kernel
+	rebootRequired, err := boot.Participant(s.kern1, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
 
-	// mark successful
-	err := boot.MarkBootSuccessful(coreDev)
+	// which becomes the most recent kernel
+	m2, err := boot.ReadModeenv("")
 	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename(), s.kern1.Filename()})
 
-	// check the bootloader variables
-	expected := map[string]string{"kernel_status": boot.DefaultStatus}
-	c.Assert(s.bootloader.BootVars, DeepEquals, expected)
+	// and once it booted, the other kernel is retained
+	s.bootloader.BootVars["kernel_status"] = boot.TryingStatus
+	err = boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+	m2, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename(), s.kern1.Filename()})
+}
 
-	// check that MarkBootSuccessful enabled the try kernel
-	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
-	c.Assert(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+func (s *bootenv20Suite) TestRemoveKernelAssets20UntrustsRetainedKernel(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
 
-	// and that we disabled a try kernel
-	_, nDisableTryCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
-	c.Assert(nDisableTryCalls, Equals, 1)
+	m := &boot.Modeenv{
+		Mode:            "run",
+		Base:            s.base1.Filename(),
+		CurrentKernels:  []string{s.kern1.Filename(), s.kern2.Filename()},
+		KernelRetention: 2,
+	}
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern2,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
 
-	// check that the new kernel is the only one in modeenv
+	// the current kernel stays trusted
+	err := boot.NewCoreKernel(s.kern2, coreDev).RemoveKernelAssets()
+	c.Assert(err, IsNil)
 	m2, err := boot.ReadModeenv("")
 	c.Assert(err, IsNil)
-	c.Assert(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename()})
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern1.Filename(), s.kern2.Filename()})
 
-	// do it again, verify its still valid
-	err = boot.MarkBootSuccessful(coreDev)
+	// the retained kernel does not
+	err = boot.NewCoreKernel(s.kern1, coreDev).RemoveKernelAssets()
 	c.Assert(err, IsNil)
-	c.Assert(s.bootloader.BootVars, DeepEquals, expected)
+	m2, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernels, DeepEquals, []string{s.kern2.Filename()})
+}
 
-	// no new bootloader calls
-	actual, _ = s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
-	c.Assert(actual, DeepEquals, []snap.PlaceInfo{s.kern2})
+func (s *bootenv20Suite) TestSetKernelRetention(c *C) {
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(m.WriteTo(""), IsNil)
 
-	// we did disable the kernel again because we always do this to cleanup in
-	// case there were leftovers
-	_, nDisableTryCalls = s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
-	c.Assert(nDisableTryCalls, Equals, 2)
+	err := boot.SetKernelRetention("", 3)
+	c.Assert(err, IsNil)
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.KernelRetention, Equals, 3)
+
+	err = boot.SetKernelRetention("", -1)
+	c.Check(err, ErrorMatches, "cannot retain a negative number of kernels")
+
+	// no modeenv, nothing to do
+	err = boot.SetKernelRetention(c.MkDir(), 3)
+	c.Check(err, IsNil)
 }
 
 func (s *bootenv20Suite) TestMarkBootSuccessful20KernelUpdateWithReseal(c *C) {
@@ -2062,6 +3060,8 @@ func (s *bootenv20Suite) TestMarkBootSuccessful20BootAssetsUpdateHappy(c *C) {
 		"asset-assethash",
 		"asset-recoveryassethash",
 		"asset-" + dataHash,
+		// left behind by an earlier interrupted update
+		"asset-interruptedassethash",
 	})
 
 	shimBf := bootloader.NewBootFile("", filepath.Join(dirs.SnapBootAssetsDir, "trusted", fmt.Sprintf("shim-%s", shimHash)), bootloader.RoleRecovery)
@@ -2616,6 +3616,88 @@ func (s *bootenv20Suite) TestMarkBootSuccessful20CommandLineUpdatedHappy(c *C) {
 	})
 }
 
+func (s *bootenv20Suite) TestMarkBootSuccessful20CommandLineCommitsTriedExtraArgs(c *C) {
+	s.mockCmdline(c, "snapd_recovery_mode=run panic=-1 args from gadget")
+	tab := s.bootloaderWithTrustedAssets(c, []string{"asset"})
+	m := s.setupMarkBootSuccessful20CommandLine(c, "run", boot.BootCommandLines{
+		"snapd_recovery_mode=run panic=-1",
+		"snapd_recovery_mode=run panic=-1 args from gadget",
+	})
+	r := setupUC20Bootenv(
+		c,
+		tab.MockBootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+	// the bootloader booted with the tried arguments
+	c.Assert(tab.SetBootVars(map[string]string{
+		"snapd_try_extra_cmdline_args":    "args from gadget",
+		"snapd_extra_cmdline_args_status": boot.TryingStatus,
+	}), IsNil)
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	vars, err := tab.GetBootVars("snapd_extra_cmdline_args", "snapd_try_extra_cmdline_args", "snapd_extra_cmdline_args_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args":        "args from gadget",
+		"snapd_try_extra_cmdline_args":    "",
+		"snapd_extra_cmdline_args_status": boot.DefaultStatus,
+	})
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernelCommandLines, DeepEquals, boot.BootCommandLines{
+		"snapd_recovery_mode=run panic=-1 args from gadget",
+	})
+}
+
+func (s *bootenv20Suite) TestMarkBootSuccessful20CommandLineDropsFailedExtraArgs(c *C) {
+	s.mockCmdline(c, "snapd_recovery_mode=run panic=-1")
+	tab := s.bootloaderWithTrustedAssets(c, []string{"asset"})
+	m := s.setupMarkBootSuccessful20CommandLine(c, "run", boot.BootCommandLines{
+		"snapd_recovery_mode=run panic=-1",
+		"snapd_recovery_mode=run panic=-1 args from gadget",
+	})
+	r := setupUC20Bootenv(
+		c,
+		tab.MockBootloader,
+		&bootenv20Setup{
+			modeenv:    m,
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+	// the bootloader fell back to the current arguments
+	c.Assert(tab.SetBootVars(map[string]string{
+		"snapd_try_extra_cmdline_args":    "args from gadget",
+		"snapd_extra_cmdline_args_status": boot.DefaultStatus,
+	}), IsNil)
+
+	coreDev := boottest.MockUC20Device("", nil)
+	err := boot.MarkBootSuccessful(coreDev)
+	c.Assert(err, IsNil)
+
+	vars, err := tab.GetBootVars("snapd_extra_cmdline_args", "snapd_try_extra_cmdline_args", "snapd_extra_cmdline_args_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args":        "",
+		"snapd_try_extra_cmdline_args":    "",
+		"snapd_extra_cmdline_args_status": boot.DefaultStatus,
+	})
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernelCommandLines, DeepEquals, boot.BootCommandLines{
+		"snapd_recovery_mode=run panic=-1",
+	})
+}
+
 func (s *bootenv20Suite) TestMarkBootSuccessful20CommandLineUpdatedOld(c *C) {
 	s.mockCmdline(c, "snapd_recovery_mode=run panic=-1")
 	tab := s.bootloaderWithTrustedAssets(c, []string{"asset"})
@@ -3020,6 +4102,213 @@ func (s *bootConfigSuite) TestBootConfigUpdateHappyWithReseal(c *C) {
 	})
 }
 
+func (s *bootConfigSuite) mockGadgetCmdline(c *C, cmdline string) string {
+	gadgetDir := c.MkDir()
+	if cmdline != "" {
+		c.Assert(ioutil.WriteFile(filepath.Join(gadgetDir, "cmdline.extra"), []byte(cmdline), 0644), IsNil)
+	}
+	return gadgetDir
+}
+
+func (s *bootConfigSuite) TestUpdateCommandLineForGadgetComponentHappyWithReseal(c *C) {
+	s.stampSealedKeys(c, dirs.GlobalRootDir)
+
+	coreDev := boottest.MockUC20Device("", nil)
+	c.Assert(coreDev.HasModeenv(), Equals, true)
+
+	runKernelBf := bootloader.NewBootFile("/var/lib/snapd/snap/pc-kernel_600.snap", "kernel.efi", bootloader.RoleRunMode)
+	recoveryKernelBf := bootloader.NewBootFile("/var/lib/snapd/seed/snaps/pc-kernel_1.snap", "kernel.efi", bootloader.RoleRecovery)
+	mockAssetsCache(c, dirs.GlobalRootDir, "trusted", []string{
+		"asset-hash-1",
+	})
+
+	s.bootloader.TrustedAssetsList = []string{"asset"}
+	s.bootloader.BootChainList = []bootloader.BootFile{
+		bootloader.NewBootFile("", "asset", bootloader.RoleRunMode),
+		runKernelBf,
+	}
+	s.bootloader.RecoveryBootChainList = []bootloader.BootFile{
+		bootloader.NewBootFile("", "asset", bootloader.RoleRecovery),
+		recoveryKernelBf,
+	}
+	m := &boot.Modeenv{
+		Mode:           "run",
+		CurrentKernels: []string{"pc-kernel_500.snap"},
+		CurrentKernelCommandLines: boot.BootCommandLines{
+			"snapd_recovery_mode=run this is mocked panic=-1",
+		},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"asset": []string{"hash-1"},
+		},
+		CurrentTrustedBootAssets: boot.BootAssetsMap{
+			"asset": []string{"hash-1"},
+		},
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	resealCalls := 0
+	restore := boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		resealCalls++
+		c.Assert(params, NotNil)
+		c.Assert(params.ModelParams, HasLen, 1)
+		c.Check(params.ModelParams[0].KernelCmdlines, DeepEquals, []string{
+			"snapd_recovery_mode=run this is mocked panic=-1",
+			"snapd_recovery_mode=run this is mocked panic=-1 args from gadget",
+		})
+		return nil
+	})
+	defer restore()
+
+	gadgetDir := s.mockGadgetCmdline(c, "args from gadget\n")
+	needsReboot, err := boot.UpdateCommandLineForGadgetComponent(coreDev, gadgetDir)
+	c.Assert(err, IsNil)
+	c.Check(needsReboot, Equals, true)
+	c.Check(resealCalls, Equals, 1)
+	// the new arguments are tried on the next boot
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_try_extra_cmdline_args":    "args from gadget",
+		"snapd_extra_cmdline_args_status": "try",
+	})
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Assert(m2.CurrentKernelCommandLines, DeepEquals, boot.BootCommandLines{
+		"snapd_recovery_mode=run this is mocked panic=-1",
+		"snapd_recovery_mode=run this is mocked panic=-1 args from gadget",
+	})
+
+	// the candidate is not committed yet, another update has to wait
+	_, err = boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, "other args"))
+	c.Assert(err, ErrorMatches, "cannot update the kernel command line while another update is pending")
+	c.Check(s.bootloader.BootVars["snapd_try_extra_cmdline_args"], Equals, "args from gadget")
+
+	// the extra arguments are not committed until a successful boot
+	cmdline, err := boot.ComposeCommandLine(boottest.MakeMockUC20Model())
+	c.Assert(err, IsNil)
+	c.Check(cmdline, Equals, "snapd_recovery_mode=run this is mocked panic=-1")
+}
+
+func (s *bootConfigSuite) TestUpdateCommandLineForGadgetComponentNoChange(c *C) {
+	s.bootloader.BootVars["snapd_extra_cmdline_args"] = "args from gadget"
+	coreDev := boottest.MockUC20Device("", nil)
+	m := &boot.Modeenv{
+		Mode: "run",
+		CurrentKernelCommandLines: boot.BootCommandLines{
+			"snapd_recovery_mode=run this is mocked panic=-1 args from gadget",
+		},
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	restore := boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})
+	defer restore()
+
+	needsReboot, err := boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, "args from gadget"))
+	c.Assert(err, IsNil)
+	c.Check(needsReboot, Equals, false)
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernelCommandLines, DeepEquals, m.CurrentKernelCommandLines)
+}
+
+func (s *bootConfigSuite) TestUpdateCommandLineForGadgetComponentDropArgs(c *C) {
+	s.bootloader.BootVars["snapd_extra_cmdline_args"] = "args from gadget"
+	coreDev := boottest.MockUC20Device("", nil)
+	m := &boot.Modeenv{
+		Mode: "run",
+		CurrentKernelCommandLines: boot.BootCommandLines{
+			"snapd_recovery_mode=run this is mocked panic=-1 args from gadget",
+		},
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	// the new gadget provides no extra arguments
+	needsReboot, err := boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, ""))
+	c.Assert(err, IsNil)
+	c.Check(needsReboot, Equals, true)
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args":        "args from gadget",
+		"snapd_try_extra_cmdline_args":    "",
+		"snapd_extra_cmdline_args_status": "try",
+	})
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernelCommandLines, DeepEquals, boot.BootCommandLines{
+		"snapd_recovery_mode=run this is mocked panic=-1 args from gadget",
+		"snapd_recovery_mode=run this is mocked panic=-1",
+	})
+}
+
+func (s *bootConfigSuite) TestUpdateCommandLineForGadgetComponentErrors(c *C) {
+	// not UC20
+	needsReboot, err := boot.UpdateCommandLineForGadgetComponent(boottest.MockDevice("some-snap"), c.MkDir())
+	c.Assert(err, IsNil)
+	c.Check(needsReboot, Equals, false)
+
+	// not run mode
+	_, err = boot.UpdateCommandLineForGadgetComponent(boottest.MockUC20Device("recover", nil), c.MkDir())
+	c.Assert(err, ErrorMatches, "internal error: kernel command line can only be updated in run mode")
+
+	coreDev := boottest.MockUC20Device("", nil)
+	// invalid command line in the gadget
+	_, err = boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, "snapd_recovery_mode=recover"))
+	c.Assert(err, ErrorMatches, `invalid kernel command line in cmdline.extra: disallowed argument "snapd_recovery_mode=recover"`)
+
+	// a previous update was not committed yet
+	m := &boot.Modeenv{
+		Mode: "run",
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+	s.bootloader.BootVars["snapd_extra_cmdline_args_status"] = "trying"
+	_, err = boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, "args"))
+	c.Assert(err, ErrorMatches, "cannot update the kernel command line while another update is pending")
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args_status": "trying",
+	})
+}
+
+func (s *bootConfigSuite) TestUpdateCommandLineForGadgetComponentUnencrypted(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	// no command lines are tracked in the modeenv without encryption
+	m := &boot.Modeenv{
+		Mode: "run",
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	restore := boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})
+	defer restore()
+
+	needsReboot, err := boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, "args"))
+	c.Assert(err, IsNil)
+	c.Check(needsReboot, Equals, true)
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_try_extra_cmdline_args":    "args",
+		"snapd_extra_cmdline_args_status": "try",
+	})
+
+	m2, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m2.CurrentKernelCommandLines, HasLen, 0)
+
+	// same arguments as the current ones
+	s.bootloader.BootVars = map[string]string{
+		"snapd_extra_cmdline_args": "args",
+	}
+	needsReboot, err = boot.UpdateCommandLineForGadgetComponent(coreDev, s.mockGadgetCmdline(c, "args"))
+	c.Assert(err, IsNil)
+	c.Check(needsReboot, Equals, false)
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_extra_cmdline_args": "args",
+	})
+}
+
 func (s *bootConfigSuite) TestBootConfigUpdateHappyNoChange(c *C) {
 	s.stampSealedKeys(c, dirs.GlobalRootDir)
 
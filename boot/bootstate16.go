@@ -21,12 +21,14 @@ package boot
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/snap"
 )
 
 type bootState16 struct {
+	typ       snap.Type
 	varSuffix string
 	errName   string
 }
@@ -43,7 +45,7 @@ func newBootState16(typ snap.Type, dev Device) bootState {
 	default:
 		panic(fmt.Sprintf("cannot make a bootState16 for snap type %q", typ))
 	}
-	return &bootState16{varSuffix: varSuffix, errName: errName}
+	return &bootState16{typ: typ, varSuffix: varSuffix, errName: errName}
 }
 
 func (s16 *bootState16) revisions() (s, tryS snap.PlaceInfo, status string, err error) {
@@ -93,6 +95,8 @@ type bootStateUpdate16 struct {
 	bl       bootloader.Bootloader
 	env      map[string]string
 	toCommit map[string]string
+
+	history bootHistory
 }
 
 func newBootStateUpdate16(u bootStateUpdate, names ...string) (*bootStateUpdate16, error) {
@@ -114,7 +118,18 @@ func newBootStateUpdate16(u bootStateUpdate, names ...string) (*bootStateUpdate1
 	return &bootStateUpdate16{bl: bl, env: m, toCommit: make(map[string]string)}, nil
 }
 
-func (u16 *bootStateUpdate16) commit() error {
+func (u16 *bootStateUpdate16) commit() (err error) {
+	defer func() {
+		if err == nil {
+			u16.history.afterCommit()
+		}
+		u16.history.flush(err)
+	}()
+
+	if err := u16.history.beforeCommit(); err != nil {
+		return err
+	}
+
 	if len(u16.toCommit) == 0 {
 		// nothing to do
 		return nil
@@ -128,6 +143,22 @@ func (u16 *bootStateUpdate16) commit() error {
 	return u16.bl.SetBootVars(env)
 }
 
+// describe returns the boot variables commit would change.
+func (u16 *bootStateUpdate16) describe() ([]string, error) {
+	names := make([]string, 0, len(u16.toCommit))
+	for k, v := range u16.toCommit {
+		if u16.env[k] != v {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	changes := make([]string, 0, len(names))
+	for _, k := range names {
+		changes = append(changes, fmt.Sprintf("set boot variable %s=%q", k, u16.toCommit[k]))
+	}
+	return changes, nil
+}
+
 func (s16 *bootState16) markSuccessful(update bootStateUpdate) (bootStateUpdate, error) {
 	u16, err := newBootStateUpdate16(update, "snap_mode", "snap_try_core", "snap_try_kernel")
 	if err != nil {
@@ -143,6 +174,15 @@ func (s16 *bootState16) markSuccessful(update bootStateUpdate) (bootStateUpdate,
 	// snap_mode goes from "" -> "try" -> "trying" -> ""
 	// so if we are not in "trying" mode, nothing to do here
 	if env["snap_mode"] != TryingStatus {
+		if env[tryBootVar] != "" {
+			u16.history.record(&BootHistoryEntry{
+				Action:    "mark-successful",
+				SnapType:  s16.typ,
+				Try:       env[tryBootVar],
+				OldStatus: env["snap_mode"],
+				NewStatus: env["snap_mode"],
+			})
+		}
 		// clean the try var anyways in case it was leftover from a rollback,
 		// etc.
 		toCommit[tryBootVar] = ""
@@ -153,6 +193,16 @@ func (s16 *bootState16) markSuccessful(update bootStateUpdate) (bootStateUpdate,
 	if env[tryBootVar] != "" {
 		toCommit[bootVar] = env[tryBootVar]
 		toCommit[tryBootVar] = ""
+		// snap_mode is shared, only the snap that was tried is
+		// recorded
+		u16.history.record(&BootHistoryEntry{
+			Action:    "mark-successful",
+			SnapType:  s16.typ,
+			Snap:      env[tryBootVar],
+			Try:       env[tryBootVar],
+			OldStatus: env["snap_mode"],
+			NewStatus: DefaultStatus,
+		})
 	}
 	toCommit["snap_mode"] = DefaultStatus
 
@@ -182,7 +232,7 @@ func (s16 *bootState16) setNext(s snap.PlaceInfo) (rebootRequired bool, u bootSt
 		// mitigates https://forum.snapcraft.io/t/5253
 		if env["snap_mode"] == DefaultStatus {
 			// already clean
-			return false, nil, nil
+			return false, u16, nil
 		}
 		// clean
 		snapMode = DefaultStatus
@@ -192,6 +242,60 @@ func (s16 *bootState16) setNext(s snap.PlaceInfo) (rebootRequired bool, u bootSt
 
 	toCommit["snap_mode"] = snapMode
 	toCommit[nextBootVar] = nextBoot
+	u16.history.record(&BootHistoryEntry{
+		Action:    "set-next",
+		SnapType:  s16.typ,
+		Snap:      s.Filename(),
+		Current:   env[goodBootVar],
+		OldStatus: env["snap_mode"],
+		NewStatus: toCommit["snap_mode"],
+	})
 
 	return rebootRequired, u16, nil
 }
+
+func (s16 *bootState16) revertTo(prev snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error) {
+	tryBootVar := fmt.Sprintf("snap_try_%s", s16.varSuffix)
+	goodBootVar := fmt.Sprintf("snap_%s", s16.varSuffix)
+
+	u16, err := newBootStateUpdate16(nil, "snap_mode", goodBootVar, "snap_try_core", "snap_try_kernel")
+	if err != nil {
+		return false, nil, err
+	}
+
+	env := u16.env
+	toCommit := u16.toCommit
+
+	if env[goodBootVar] == prev.Filename() && env[tryBootVar] == "" {
+		// nothing to revert
+		return false, nil, nil
+	}
+
+	booted := env[goodBootVar]
+	if env["snap_mode"] == TryingStatus && env[tryBootVar] != "" {
+		booted = env[tryBootVar]
+	}
+
+	toCommit[goodBootVar] = prev.Filename()
+	toCommit[tryBootVar] = ""
+	// snap_mode is shared by the kernel and the core, leave it alone
+	// when the other one is being tried
+	otherTryBootVar := "snap_try_core"
+	if tryBootVar == otherTryBootVar {
+		otherTryBootVar = "snap_try_kernel"
+	}
+	if env[otherTryBootVar] == "" {
+		toCommit["snap_mode"] = DefaultStatus
+	}
+	u16.history.record(&BootHistoryEntry{
+		Action:    "revert",
+		SnapType:  s16.typ,
+		Snap:      prev.Filename(),
+		Current:   env[goodBootVar],
+		Try:       env[tryBootVar],
+		OldStatus: env["snap_mode"],
+		NewStatus: toCommit["snap_mode"],
+	})
+
+	return booted != prev.Filename(), u16, nil
+}
@@ -22,6 +22,7 @@ package boot
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
@@ -34,12 +35,16 @@ import (
 
 func newBootState20(typ snap.Type, dev Device) bootState {
 	switch typ {
-	case snap.TypeBase:
+	case snap.TypeBase, snap.TypeOS:
+		// on systems migrated from core the modeenv base may be the
+		// core snap, which is handled just like any other base
 		return &bootState20Base{}
 	case snap.TypeKernel:
 		return &bootState20Kernel{
 			dev: dev,
 		}
+	case snap.TypeGadget:
+		return &bootState20Gadget{}
 	default:
 		panic(fmt.Sprintf("cannot make a bootState20 for snap type %q", typ))
 	}
@@ -82,11 +87,18 @@ type bootloaderKernelState20 interface {
 
 type bootCommitTask func() error
 
+// bootCommitStep is a task run on commit along with a description of what
+// it does.
+type bootCommitStep struct {
+	description string
+	run         bootCommitTask
+}
+
 // bootStateUpdate20 implements the bootStateUpdate interface for both kernel
 // and base snaps on UC20.
 type bootStateUpdate20 struct {
 	// tasks to run before the modeenv has been written
-	preModeenvTasks []bootCommitTask
+	preModeenvTasks []bootCommitStep
 
 	// the modeenv that was read from disk
 	modeenv *Modeenv
@@ -95,18 +107,21 @@ type bootStateUpdate20 struct {
 	writeModeenv *Modeenv
 
 	// tasks to run after the modeenv has been written
-	postModeenvTasks []bootCommitTask
+	postModeenvTasks []bootCommitStep
 
 	// model set if a reseal might be necessary
 	resealModel *asserts.Model
+
+	// the boot state transitions recorded on commit
+	history bootHistory
 }
 
-func (u20 *bootStateUpdate20) preModeenv(task bootCommitTask) {
-	u20.preModeenvTasks = append(u20.preModeenvTasks, task)
+func (u20 *bootStateUpdate20) preModeenv(description string, task bootCommitTask) {
+	u20.preModeenvTasks = append(u20.preModeenvTasks, bootCommitStep{description, task})
 }
 
-func (u20 *bootStateUpdate20) postModeenv(task bootCommitTask) {
-	u20.postModeenvTasks = append(u20.postModeenvTasks, task)
+func (u20 *bootStateUpdate20) postModeenv(description string, task bootCommitTask) {
+	u20.postModeenvTasks = append(u20.postModeenvTasks, bootCommitStep{description, task})
 }
 
 func (u20 *bootStateUpdate20) resealForModel(model *asserts.Model) {
@@ -133,7 +148,18 @@ func newBootStateUpdate20(m *Modeenv) (*bootStateUpdate20, error) {
 }
 
 // commit will write out boot state persistently to disk.
-func (u20 *bootStateUpdate20) commit() error {
+func (u20 *bootStateUpdate20) commit() (err error) {
+	defer func() {
+		if err == nil {
+			u20.history.afterCommit()
+		}
+		u20.history.flush(err)
+	}()
+
+	if err := u20.history.beforeCommit(); err != nil {
+		return err
+	}
+
 	// The actual actions taken here will depend on what things were called
 	// before commit(), either setNextBoot for a single type of kernel snap, or
 	// markSuccessful for kernel and/or base snaps.
@@ -148,7 +174,7 @@ func (u20 *bootStateUpdate20) commit() error {
 
 	// first handle any pre-modeenv writing tasks
 	for _, t := range u20.preModeenvTasks {
-		if err := t(); err != nil {
+		if err := t.run(); err != nil {
 			return err
 		}
 	}
@@ -179,7 +205,7 @@ func (u20 *bootStateUpdate20) commit() error {
 
 	// finally handle any post-modeenv writing tasks
 	for _, t := range u20.postModeenvTasks {
-		if err := t(); err != nil {
+		if err := t.run(); err != nil {
 			return err
 		}
 	}
@@ -187,6 +213,26 @@ func (u20 *bootStateUpdate20) commit() error {
 	return nil
 }
 
+// describe returns the changes commit would perform, in the same order.
+func (u20 *bootStateUpdate20) describe() ([]string, error) {
+	var changes []string
+	for _, t := range u20.preModeenvTasks {
+		changes = append(changes, t.description)
+	}
+	modeenvChanges, err := u20.modeenv.changesTo(u20.writeModeenv)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, modeenvChanges...)
+	if u20.resealModel != nil {
+		changes = append(changes, "reseal the encryption keys if needed")
+	}
+	for _, t := range u20.postModeenvTasks {
+		changes = append(changes, t.description)
+	}
+	return changes, nil
+}
+
 //
 // kernel snap methods
 //
@@ -196,6 +242,9 @@ func (u20 *bootStateUpdate20) commit() error {
 // methods returning bootStateUpdate20 to be used with bootStateUpdate.
 type bootState20Kernel struct {
 	bks bootloaderKernelState20
+	// the run-mode bootloader, used directly to manage the boot attempts
+	// of try kernels
+	bl bootloader.Bootloader
 
 	// used to find the bootloader to manipulate the enabled kernel, etc.
 	blOpts *bootloader.Options
@@ -223,6 +272,7 @@ func (ks20 *bootState20Kernel) loadBootenv() error {
 	if err != nil {
 		return err
 	}
+	ks20.bl = bl
 	ebl, ok := bl.(bootloader.ExtractedRunKernelImageBootloader)
 	if ok {
 		// use the new 20-style ExtractedRunKernelImage implementation
@@ -270,7 +320,7 @@ func (ks20 *bootState20Kernel) revisionsFromModeenv(*Modeenv) (curSnap, trySnap
 
 func (ks20 *bootState20Kernel) markSuccessful(update bootStateUpdate) (bootStateUpdate, error) {
 	// call the generic method with this object to do most of the legwork
-	u20, sn, err := selectSuccessfulBootSnap(ks20, update)
+	u20, sn, err := selectSuccessfulBootSnap(ks20, snap.TypeKernel, update)
 	if err != nil {
 		return nil, err
 	}
@@ -285,11 +335,18 @@ func (ks20 *bootState20Kernel) markSuccessful(update bootStateUpdate) (bootState
 		// failed to mark it successful and then fall back to the original
 		// kernel, but that kernel would no longer be in the modeenv, so we
 		// would die in the initramfs
-		u20.preModeenv(func() error { return ks20.bks.markSuccessfulKernel(sn) })
+		u20.preModeenv(fmt.Sprintf("mark kernel %s successful in the bootloader", sn.Filename()),
+			func() error { return ks20.bks.markSuccessfulKernel(sn) })
+
+		// the try kernel, if any, needs no more boot attempts, the
+		// counter is reset even if the limit was dropped meanwhile
+		u20.preModeenv("reset the kernel boot attempts in the bootloader",
+			func() error { return ks20.resetBootAttempts() })
 
 		// On commit, set CurrentKernels as just this kernel because that is the
-		// successful kernel we booted
-		u20.writeModeenv.CurrentKernels = []string{sn.Filename()}
+		// successful kernel we booted, along with the previous kernels
+		// retained as per the retention policy
+		u20.writeModeenv.CurrentKernels = retainedKernels(u20.writeModeenv, sn)
 
 		// keep track of the model for resealing
 		u20.resealForModel(ks20.dev.Model())
@@ -299,7 +356,7 @@ func (ks20 *bootState20Kernel) markSuccessful(update bootStateUpdate) (bootState
 }
 
 func (ks20 *bootState20Kernel) setNext(next snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error) {
-	u20, nextStatus, err := genericSetNext(ks20, next)
+	u20, nextStatus, err := genericSetNext(ks20, next, nil)
 	if err != nil {
 		return false, nil, err
 	}
@@ -311,10 +368,34 @@ func (ks20 *bootState20Kernel) setNext(next snap.PlaceInfo) (rebootRequired bool
 	}
 
 	currentKernel := ks20.bks.kernel()
+	u20.history.record(&BootHistoryEntry{
+		Action:    "set-next",
+		SnapType:  snap.TypeKernel,
+		Snap:      next.Filename(),
+		Current:   currentKernel.Filename(),
+		OldStatus: ks20.bks.kernelStatus(),
+		NewStatus: nextStatus,
+	})
+	if nextStatus == DefaultStatus && ks20.bks.kernelStatus() == TryStatus {
+		// we are going back to the current kernel before the try
+		// kernel was ever booted, i.e. the change that set it up is
+		// being undone, so cleanup the pending try kernel the same way
+		// as marking the current kernel successful would, first the
+		// boot vars and try-kernel and only then the modeenv
+		u20.preModeenv(fmt.Sprintf("mark kernel %s successful in the bootloader", next.Filename()),
+			func() error { return ks20.bks.markSuccessfulKernel(next) })
+		u20.preModeenv("reset the kernel boot attempts in the bootloader",
+			func() error { return ks20.resetBootAttempts() })
+		u20.writeModeenv.CurrentKernels = retainedKernels(u20.writeModeenv, next)
+		u20.resealForModel(ks20.dev.Model())
+		return false, u20, nil
+	}
+
 	if next.Filename() != currentKernel.Filename() {
-		// on commit, add this kernel to the modeenv
+		// on commit, add this kernel to the modeenv, as the most recent
+		// one if it was retained already
 		u20.writeModeenv.CurrentKernels = append(
-			u20.writeModeenv.CurrentKernels,
+			removeKernel(u20.writeModeenv.CurrentKernels, next),
 			next.Filename(),
 		)
 	}
@@ -325,7 +406,13 @@ func (ks20 *bootState20Kernel) setNext(next snap.PlaceInfo) (rebootRequired bool
 	// kernel and updating the modeenv, the initramfs would fail the boot
 	// because the modeenv doesn't "trust" or expect the new kernel that booted.
 	// As such, set the next kernel as a post modeenv task.
-	u20.postModeenv(func() error { return ks20.bks.setNextKernel(next, nextStatus) })
+	if nextStatus == TryStatus && u20.writeModeenv.MaxKernelBootAttempts > 0 {
+		// the new try kernel has not been booted yet
+		u20.postModeenv("reset the kernel boot attempts in the bootloader",
+			func() error { return ks20.resetBootAttempts() })
+	}
+	u20.postModeenv(fmt.Sprintf("set kernel %s with status %q as next in the bootloader", next.Filename(), nextStatus),
+		func() error { return ks20.bks.setNextKernel(next, nextStatus) })
 
 	// keep track of the model for resealing
 	u20.resealForModel(ks20.dev.Model())
@@ -333,6 +420,142 @@ func (ks20 *bootState20Kernel) setNext(next snap.PlaceInfo) (rebootRequired bool
 	return rebootRequired, u20, nil
 }
 
+func (ks20 *bootState20Kernel) revertTo(prev snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error) {
+	current, try, status, err := ks20.revisions()
+	if err != nil && !isTrySnapError(err) {
+		return false, nil, err
+	}
+	// a broken try kernel is cleaned up all the same
+	hasTry := try != nil || err != nil
+	if current.Filename() == prev.Filename() && status == DefaultStatus && !hasTry {
+		// nothing to revert
+		return false, nil, nil
+	}
+
+	u20, err := newBootStateUpdate20(nil)
+	if err != nil {
+		return false, nil, err
+	}
+
+	booted := current
+	if status == TryingStatus && try != nil {
+		booted = try
+	}
+
+	u20.history.record(&BootHistoryEntry{
+		Action:    "revert",
+		SnapType:  snap.TypeKernel,
+		Snap:      prev.Filename(),
+		Current:   current.Filename(),
+		Try:       snapFilename(try),
+		OldStatus: status,
+		NewStatus: DefaultStatus,
+	})
+
+	// On commit, the previous kernel must be trusted in the modeenv before
+	// the bootloader is pointed to it, otherwise if we got rebooted in
+	// between the initramfs would refuse to boot it.
+	if !strutil.ListContains(u20.writeModeenv.CurrentKernels, prev.Filename()) {
+		u20.writeModeenv.CurrentKernels = append(u20.writeModeenv.CurrentKernels, prev.Filename())
+	}
+	u20.resealForModel(ks20.dev.Model())
+
+	// Then point the bootloader to the previous kernel, the same way as
+	// marking it successful would, clearing the kernel status first and
+	// dropping the try kernel last.
+	u20.postModeenv(fmt.Sprintf("mark kernel %s successful in the bootloader", prev.Filename()),
+		func() error { return ks20.bks.markSuccessfulKernel(prev) })
+	// no kernel is being tried anymore
+	u20.postModeenv("reset the kernel boot attempts in the bootloader",
+		func() error { return ks20.resetBootAttempts() })
+
+	// Finally stop trusting the other kernels, now that the bootloader
+	// cannot boot them anymore.
+	u20.postModeenv(fmt.Sprintf("stop trusting kernels other than %s in the modeenv", prev.Filename()), func() error {
+		m, err := u20.writeModeenv.Copy()
+		if err != nil {
+			return err
+		}
+		m.CurrentKernels = []string{prev.Filename()}
+		if m.deepEqual(u20.writeModeenv) {
+			return nil
+		}
+		if err := m.Write(); err != nil {
+			return err
+		}
+		const expectReseal = true
+		return resealKeyToModeenv(dirs.GlobalRootDir, ks20.dev.Model(), m, expectReseal)
+	})
+
+	return booted.Filename() != prev.Filename(), u20, nil
+}
+
+// retainedKernels returns the kernels to keep trusted in the modeenv once the
+// given kernel booted successfully. Those are the kernel itself and, as
+// allowed by the kernel retention policy, the most recent of the kernels that
+// were added to the modeenv before it. Kernels that were added after it, like
+// a try kernel that failed to boot, are dropped.
+func retainedKernels(m *Modeenv, booted snap.PlaceInfo) []string {
+	var previous []string
+	for _, k := range m.CurrentKernels {
+		if k == booted.Filename() {
+			break
+		}
+		previous = append(previous, k)
+	}
+	keep := m.KernelRetention - 1
+	if keep < 0 {
+		keep = 0
+	}
+	if len(previous) > keep {
+		previous = previous[len(previous)-keep:]
+	}
+	return append(previous, booted.Filename())
+}
+
+func removeKernel(kernels []string, kernel snap.PlaceInfo) []string {
+	filtered := make([]string, 0, len(kernels))
+	for _, k := range kernels {
+		if k != kernel.Filename() {
+			filtered = append(filtered, k)
+		}
+	}
+	return filtered
+}
+
+// untrustRemovedKernel drops the given kernel, which is being removed from
+// the system, from the kernels trusted in the modeenv, unless the bootloader
+// may still boot it.
+func untrustRemovedKernel(kernel snap.PlaceInfo, dev Device) error {
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	if !strutil.ListContains(m.CurrentKernels, kernel.Filename()) {
+		return nil
+	}
+
+	ks20 := &bootState20Kernel{dev: dev}
+	current, try, _, err := ks20.revisions()
+	if err != nil && !isTrySnapError(err) {
+		return err
+	}
+	if current.Filename() == kernel.Filename() || (try != nil && try.Filename() == kernel.Filename()) {
+		return nil
+	}
+
+	newM, err := m.Copy()
+	if err != nil {
+		return err
+	}
+	newM.CurrentKernels = removeKernel(m.CurrentKernels, kernel)
+	if err := newM.Write(); err != nil {
+		return err
+	}
+	const expectReseal = true
+	return resealKeyToModeenv(dirs.GlobalRootDir, dev.Model(), newM, expectReseal)
+}
+
 // selectAndCommitSnapInitramfsMount chooses which snap should be mounted
 // during the initramfs, and commits that choice if it needs state updated.
 // Choosing to boot/mount the base snap needs to be committed to the
@@ -346,13 +569,33 @@ func (ks20 *bootState20Kernel) selectAndCommitSnapInitramfsMount(modeenv *Modeen
 	}
 
 	if err == errTrySnapFallback {
-		// this should not actually return, it should immediately reboot
-		return nil, initramfsReboot()
+		_, tryErr := ks20.bks.tryKernel()
+		if ks20.bks.kernelStatus() != TryStatus || tryErr != bootloader.ErrNoTryKernelRef {
+			// this should not actually return, it should immediately reboot
+			return nil, initramfsReboot()
+		}
+		// without a try kernel the bootloader could only boot the
+		// current kernel, there is nothing to reboot into
+		modeenv.issues = append(modeenv.issues, fmt.Sprintf("try kernel missing while \"kernel_status\" is %q, booted kernel %q",
+			TryStatus, first.Filename()))
 	}
 
 	// now validate the chosen kernel snap against the modeenv CurrentKernel's
 	// setting
 	if strutil.ListContains(modeenv.CurrentKernels, first.Filename()) {
+		if modeenv.MaxKernelBootAttempts > 0 {
+			if err := ks20.countBootAttempt(modeenv, second != nil); err != nil {
+				return nil, err
+			}
+		}
+		if second == nil && ks20.bks.kernelStatus() == DefaultStatus {
+			// a try kernel that is still around when booting the
+			// current kernel failed to boot, the bootloader fell back
+			if tryKernel, err := ks20.bks.tryKernel(); err == nil {
+				modeenv.issues = append(modeenv.issues, fmt.Sprintf("try kernel %q failed to boot, booted kernel %q",
+					tryKernel.Filename(), first.Filename()))
+			}
+		}
 		return first, nil
 	}
 
@@ -375,6 +618,95 @@ func (ks20 *bootState20Kernel) selectAndCommitSnapInitramfsMount(modeenv *Modeen
 	return nil, fmt.Errorf("fallback kernel snap %q is not trusted in the modeenv", first.Filename())
 }
 
+// kernelBootAttemptsVar is the bootenv variable counting the boots of the try
+// kernel. It is managed by the initramfs and snapd, bootloader scripts may
+// only read it.
+const kernelBootAttemptsVar = "kernel_boot_attempts"
+
+func (ks20 *bootState20Kernel) bootAttempts() (int, error) {
+	m, err := ks20.bl.GetBootVars(kernelBootAttemptsVar)
+	if err != nil {
+		return 0, err
+	}
+	if m[kernelBootAttemptsVar] == "" {
+		return 0, nil
+	}
+	attempts, err := strconv.Atoi(m[kernelBootAttemptsVar])
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %v", kernelBootAttemptsVar, err)
+	}
+	return attempts, nil
+}
+
+func (ks20 *bootState20Kernel) setBootAttempts(attempts int) error {
+	value := ""
+	if attempts > 0 {
+		value = strconv.Itoa(attempts)
+	}
+	return ks20.bl.SetBootVars(map[string]string{kernelBootAttemptsVar: value})
+}
+
+func (ks20 *bootState20Kernel) resetBootAttempts() error {
+	attempts, err := ks20.bootAttempts()
+	if err != nil {
+		// a broken counter is reset all the same
+		logger.Noticef("cannot get the boot attempts of the try kernel: %v", err)
+	} else if attempts == 0 {
+		return nil
+	}
+	return ks20.setBootAttempts(0)
+}
+
+// countBootAttempt keeps track of the boot attempts of the try kernel in the
+// initramfs. When booting the try kernel the attempt is counted, when the
+// bootloader fell back to the current kernel because the try kernel failed to
+// boot, the try kernel is set up to be tried again, unless it was attempted
+// MaxKernelBootAttempts times already, in which case it is given up on and
+// recorded in the modeenv as a failed kernel.
+func (ks20 *bootState20Kernel) countBootAttempt(modeenv *Modeenv, tryingKernel bool) error {
+	attempts, err := ks20.bootAttempts()
+	if err != nil {
+		// the boot must go on, the counter starts over
+		logger.Noticef("cannot get the boot attempts of the try kernel: %v", err)
+		attempts = 0
+	}
+
+	if tryingKernel {
+		return ks20.setBootAttempts(attempts + 1)
+	}
+	if attempts == 0 {
+		return nil
+	}
+
+	tryKernel, err := ks20.bks.tryKernel()
+	if err != nil || ks20.bks.kernelStatus() != DefaultStatus {
+		// the try kernel is gone, the counter is stale
+		return ks20.setBootAttempts(0)
+	}
+
+	if attempts < modeenv.MaxKernelBootAttempts {
+		logger.Noticef("try kernel %q failed to boot, attempting to boot it again (attempt %d of %d)",
+			tryKernel.Filename(), attempts+1, modeenv.MaxKernelBootAttempts)
+		if err := ks20.bks.setNextKernel(tryKernel, TryStatus); err != nil {
+			return err
+		}
+		// this should not actually return, it should immediately reboot
+		return initramfsReboot()
+	}
+
+	// give up on the try kernel, snapd will clean it up when marking the
+	// current kernel successful
+	logger.Noticef("try kernel %q failed to boot %d times, reverting to kernel %q",
+		tryKernel.Filename(), attempts, ks20.bks.kernel().Filename())
+	if !strutil.ListContains(modeenv.FailedKernels, tryKernel.Filename()) {
+		modeenv.FailedKernels = append(modeenv.FailedKernels, tryKernel.Filename())
+		if err := modeenv.Write(); err != nil {
+			return err
+		}
+	}
+	return ks20.setBootAttempts(0)
+}
+
 //
 // base snap methods
 //
@@ -382,6 +714,8 @@ func (ks20 *bootState20Kernel) selectAndCommitSnapInitramfsMount(modeenv *Modeen
 // bootState20Base implements the bootState interface for base snaps on UC20.
 // It is used for both setNext() and markSuccessful(), with both of those
 // methods returning bootStateUpdate20 to be used with bootStateUpdate.
+// On classic systems with modes there is no base to track, so its methods
+// leave the boot state alone.
 type bootState20Base struct{}
 
 // revisions returns the current boot snap and optional try boot snap for the
@@ -397,6 +731,9 @@ func (bs20 *bootState20Base) revisions() (curSnap, trySnap snap.PlaceInfo, tryin
 func (bs20 *bootState20Base) revisionsFromModeenv(modeenv *Modeenv) (curSnap, trySnap snap.PlaceInfo, tryingStatus string, err error) {
 	var bootSn, tryBootSn snap.PlaceInfo
 
+	if modeenv.Classic {
+		return nil, nil, "", fmt.Errorf("cannot get snap revision: no base on classic systems with modes")
+	}
 	if modeenv.Base == "" {
 		return nil, nil, "", fmt.Errorf("cannot get snap revision: modeenv base boot variable is empty")
 	}
@@ -417,8 +754,17 @@ func (bs20 *bootState20Base) revisionsFromModeenv(modeenv *Modeenv) (curSnap, tr
 }
 
 func (bs20 *bootState20Base) markSuccessful(update bootStateUpdate) (bootStateUpdate, error) {
+	u20, err := toBootStateUpdate20(update)
+	if err != nil {
+		return nil, err
+	}
+	if u20.modeenv.Classic {
+		// the root filesystem is not a base snap, nothing to mark
+		return u20, nil
+	}
+
 	// call the generic method with this object to do most of the legwork
-	u20, sn, err := selectSuccessfulBootSnap(bs20, update)
+	u20, sn, err := selectSuccessfulBootSnap(bs20, snap.TypeBase, u20)
 	if err != nil {
 		return nil, err
 	}
@@ -437,10 +783,28 @@ func (bs20 *bootState20Base) markSuccessful(update bootStateUpdate) (bootStateUp
 }
 
 func (bs20 *bootState20Base) setNext(next snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error) {
-	u20, nextStatus, err := genericSetNext(bs20, next)
+	u20, err := newBootStateUpdate20(nil)
 	if err != nil {
 		return false, nil, err
 	}
+	if u20.modeenv.Classic {
+		// the base is not booted, there is nothing to set up
+		return false, u20, nil
+	}
+
+	u20, nextStatus, err := genericSetNext(bs20, next, u20)
+	if err != nil {
+		return false, nil, err
+	}
+	u20.history.record(&BootHistoryEntry{
+		Action:    "set-next",
+		SnapType:  snap.TypeBase,
+		Snap:      next.Filename(),
+		Current:   u20.modeenv.Base,
+		Try:       u20.modeenv.TryBase,
+		OldStatus: u20.modeenv.BaseStatus,
+		NewStatus: nextStatus,
+	})
 
 	// if we are setting a snap as a try snap, then we need to reboot
 	rebootRequired = false
@@ -448,6 +812,10 @@ func (bs20 *bootState20Base) setNext(next snap.PlaceInfo) (rebootRequired bool,
 		// only update the try base if we are actually in try status
 		u20.writeModeenv.TryBase = next.Filename()
 		rebootRequired = true
+	} else if u20.modeenv.BaseStatus == TryStatus {
+		// going back to the current base before the try base was
+		// ever booted, drop the pending try base
+		u20.writeModeenv.TryBase = ""
 	}
 
 	// always update the base status
@@ -456,6 +824,45 @@ func (bs20 *bootState20Base) setNext(next snap.PlaceInfo) (rebootRequired bool,
 	return rebootRequired, u20, nil
 }
 
+func (bs20 *bootState20Base) revertTo(prev snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error) {
+	u20, err := newBootStateUpdate20(nil)
+	if err != nil {
+		return false, nil, err
+	}
+	m := u20.modeenv
+
+	if m.Classic {
+		return false, nil, fmt.Errorf("no base on classic systems with modes")
+	}
+	if m.Base == prev.Filename() && m.BaseStatus == DefaultStatus && m.TryBase == "" {
+		// nothing to revert
+		return false, nil, nil
+	}
+
+	booted := m.Base
+	if m.BaseStatus == TryingStatus && m.TryBase != "" {
+		booted = m.TryBase
+	}
+
+	u20.history.record(&BootHistoryEntry{
+		Action:    "revert",
+		SnapType:  snap.TypeBase,
+		Snap:      prev.Filename(),
+		Current:   m.Base,
+		Try:       m.TryBase,
+		OldStatus: m.BaseStatus,
+		NewStatus: DefaultStatus,
+	})
+
+	// the base is only tracked in the modeenv, so everything is
+	// switched over at once
+	u20.writeModeenv.Base = prev.Filename()
+	u20.writeModeenv.TryBase = ""
+	u20.writeModeenv.BaseStatus = DefaultStatus
+
+	return booted != prev.Filename(), u20, nil
+}
+
 // selectAndCommitSnapInitramfsMount chooses which snap should be mounted
 // during the early boot sequence, i.e. the initramfs, and commits that
 // choice if it needs state updated.
@@ -463,6 +870,11 @@ func (bs20 *bootState20Base) setNext(next snap.PlaceInfo) (rebootRequired bool,
 // modeenv, but no state needs to be committed when choosing to mount a
 // kernel snap.
 func (bs20 *bootState20Base) selectAndCommitSnapInitramfsMount(modeenv *Modeenv) (sn snap.PlaceInfo, err error) {
+	if modeenv.Classic {
+		// the root filesystem is not a base snap, nothing to mount
+		return nil, nil
+	}
+
 	// first do the generic choice of which snap to use
 	// the logic in that function is sufficient to pick the base snap entirely,
 	// so we don't ever need to look at the fallback snap, we just need to know
@@ -485,12 +897,17 @@ func (bs20 *bootState20Base) selectAndCommitSnapInitramfsMount(modeenv *Modeenv)
 		if second != nil {
 			modeenv.BaseStatus = TryingStatus
 			modeenvChanged = true
+		} else {
+			modeenv.issues = append(modeenv.issues, fmt.Sprintf("cannot use try base %q, booted base %q",
+				modeenv.TryBase, first.Filename()))
 		}
 	case TryingStatus:
 		// we tried to boot a try base snap and failed, so we need to reset
 		// BaseStatus
 		modeenv.BaseStatus = DefaultStatus
 		modeenvChanged = true
+		modeenv.issues = append(modeenv.issues, fmt.Sprintf("try base %q failed to boot, booted base %q",
+			modeenv.TryBase, first.Filename()))
 	case DefaultStatus:
 		// nothing to do
 	default:
@@ -508,6 +925,72 @@ func (bs20 *bootState20Base) selectAndCommitSnapInitramfsMount(modeenv *Modeenv)
 	return first, nil
 }
 
+//
+// gadget snap methods
+//
+
+// bootState20Gadget implements the bootState interface for gadget snaps on
+// UC20. Gadget snaps are never tried, the boot assets they carry are updated
+// in place and tracked in the modeenv by the trusted assets observer, so
+// setNext() only records the gadget in the modeenv and reports whether the
+// boot relevant content that came with it needs a reboot to take effect.
+type bootState20Gadget struct{}
+
+func (bs20 *bootState20Gadget) revisions() (curSnap, trySnap snap.PlaceInfo, tryingStatus string, err error) {
+	modeenv, err := loadModeenv()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return bs20.revisionsFromModeenv(modeenv)
+}
+
+func (bs20 *bootState20Gadget) revisionsFromModeenv(modeenv *Modeenv) (curSnap, trySnap snap.PlaceInfo, tryingStatus string, err error) {
+	if modeenv.Gadget == "" {
+		return nil, nil, "", fmt.Errorf("cannot get snap revision: modeenv gadget boot variable is empty")
+	}
+
+	gadgetSn, err := snap.ParsePlaceInfoFromSnapFileName(modeenv.Gadget)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("cannot get snap revision: modeenv gadget boot variable is invalid: %v", err)
+	}
+	// gadgets are never tried
+	return gadgetSn, nil, DefaultStatus, nil
+}
+
+func (bs20 *bootState20Gadget) markSuccessful(update bootStateUpdate) (bootStateUpdate, error) {
+	// the gadget itself is not tried, the boot assets and the command
+	// line it provides are handled by their own successful boot states
+	return toBootStateUpdate20(update)
+}
+
+func (bs20 *bootState20Gadget) setNext(next snap.PlaceInfo) (rebootRequired bool, u bootStateUpdate, err error) {
+	u20, err := newBootStateUpdate20(nil)
+	if err != nil {
+		return false, nil, err
+	}
+
+	// note that the gadget may be missing from a modeenv written by an
+	// older snapd, in which case it is recorded now
+	u20.writeModeenv.Gadget = next.Filename()
+
+	return gadgetBootContentPending(u20.writeModeenv), u20, nil
+}
+
+// gadgetBootContentPending returns true when the modeenv carries boot
+// relevant content which was updated together with the gadget, but which
+// the system has not booted with yet, that is there are new trusted boot
+// assets or a new kernel command line waiting to be used.
+func gadgetBootContentPending(m *Modeenv) bool {
+	for _, assets := range []bootAssetsMap{m.CurrentTrustedBootAssets, m.CurrentTrustedRecoveryBootAssets} {
+		for _, hashes := range assets {
+			if len(hashes) > 1 {
+				return true
+			}
+		}
+	}
+	return len(m.CurrentKernelCommandLines) > 1
+}
+
 //
 // generic methods
 //
@@ -521,9 +1004,9 @@ type bootState20 interface {
 
 // genericSetNext implements the generic logic for setting up a snap to be tried
 // for boot and works for both kernel and base snaps (though not
-// simultaneously).
-func genericSetNext(b bootState20, next snap.PlaceInfo) (u20 *bootStateUpdate20, setStatus string, err error) {
-	u20, err = newBootStateUpdate20(nil)
+// simultaneously). An update already created by the caller can be passed.
+func genericSetNext(b bootState20, next snap.PlaceInfo, update bootStateUpdate) (u20 *bootStateUpdate20, setStatus string, err error) {
+	u20, err = toBootStateUpdate20(update)
 	if err != nil {
 		return nil, "", err
 	}
@@ -569,7 +1052,7 @@ func toBootStateUpdate20(update bootStateUpdate) (u20 *bootStateUpdate20, err er
 // boot snap should be marked as successful and use as a valid rollback target.
 // If the first return value is non-nil, the second return value will be the
 // snap that was booted and should be marked as successful.
-func selectSuccessfulBootSnap(b bootState20, update bootStateUpdate) (
+func selectSuccessfulBootSnap(b bootState20, typ snap.Type, update bootStateUpdate) (
 	u20 *bootStateUpdate20,
 	bootedSnap snap.PlaceInfo,
 	err error,
@@ -588,12 +1071,32 @@ func selectSuccessfulBootSnap(b bootState20, update bootStateUpdate) (
 	// kernel_status and base_status go from "" -> "try" (set by snapd), to
 	// "try" -> "trying" (set by the boot script)
 	// so if we are in "trying" mode, then we should choose the try snap
+	bootedSnap = sn
 	if status == TryingStatus && trySnap != nil {
-		return u20, trySnap, nil
+		bootedSnap = trySnap
+	}
+
+	// only the end of a try cycle is worth recording, not every boot
+	if status != DefaultStatus || trySnap != nil {
+		u20.history.record(&BootHistoryEntry{
+			Action:    "mark-successful",
+			SnapType:  typ,
+			Snap:      snapFilename(bootedSnap),
+			Current:   snapFilename(sn),
+			Try:       snapFilename(trySnap),
+			OldStatus: status,
+			NewStatus: DefaultStatus,
+		})
 	}
 
-	// if we are not in trying then choose the normal snap
-	return u20, sn, nil
+	return u20, bootedSnap, nil
+}
+
+func snapFilename(sn snap.PlaceInfo) string {
+	if sn == nil {
+		return ""
+	}
+	return sn.Filename()
 }
 
 // genericInitramfsSelectSnap will run the logic to choose which snap should be
@@ -700,7 +1203,7 @@ func (ba20 *bootState20BootAssets) markSuccessful(update bootStateUpdate) (bootS
 		return u20, nil
 	}
 
-	u20.postModeenv(func() error {
+	u20.postModeenv("remove unused boot assets from the cache", func() error {
 		cache := newTrustedAssetsCache(dirs.SnapBootAssetsDir)
 		// drop listed assets from cache
 		for _, ta := range dropAssets {
@@ -710,7 +1213,8 @@ func (ba20 *bootState20BootAssets) markSuccessful(update bootStateUpdate) (bootS
 				return fmt.Errorf("cannot remove unused boot asset %v:%v: %v", ta.name, ta.hash, err)
 			}
 		}
-		return nil
+		// and whatever else was left behind by interrupted updates
+		return removeUnusedTrustedBootAssets(newM)
 	})
 	return u20, nil
 }
@@ -732,6 +1236,19 @@ func (bcl20 *bootState20CommandLine) markSuccessful(update bootStateUpdate) (boo
 	if err != nil {
 		return nil, err
 	}
+	if u20.modeenv.Mode == ModeRun {
+		commitExtraArgs, err := triedExtraCommandLineArgsCommit()
+		if err != nil {
+			return nil, err
+		}
+		if commitExtraArgs != nil {
+			// commit the extra arguments before the modeenv drops
+			// the command line that was not booted with, so that
+			// the bootloader never uses one that the keys are not
+			// sealed against
+			u20.preModeenv("commit extra kernel command line arguments", commitExtraArgs)
+		}
+	}
 	if len(u20.modeenv.CurrentTrustedBootAssets) == 0 && len(u20.modeenv.CurrentTrustedRecoveryBootAssets) == 0 {
 		// XXX: does this change when we expose the ability to add
 		// things to the command line?
@@ -211,9 +211,12 @@ func (envbks *envRefExtractedKernelBootloaderKernelState) load() error {
 
 	// snap_kernel is the current kernel snap
 	// parse the filename here because the kernel() method doesn't return an err
+	if envbks.env["snap_kernel"] == "" {
+		return fmt.Errorf("cannot identify kernel snap with bootloader %s: snap_kernel is unset", envbks.bl.Name())
+	}
 	sn, err := snap.ParsePlaceInfoFromSnapFileName(envbks.env["snap_kernel"])
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot identify kernel snap with bootloader %s: %v", envbks.bl.Name(), err)
 	}
 
 	envbks.kern = sn
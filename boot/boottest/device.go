@@ -30,6 +30,7 @@ type mockDevice struct {
 	bootSnap string
 	mode     string
 	uc20     bool
+	classic  bool
 
 	model *asserts.Model
 }
@@ -70,6 +71,16 @@ func MockUC20Device(mode string, model *asserts.Model) boot.Device {
 	}
 }
 
+// MockClassicWithModesDevice implements boot.Device for a classic system
+// with modes, it returns true for both Classic and HasModeenv. Arguments
+// are mode (empty means "run"), and model. If model is nil a default model
+// is used (same as MakeMockUC20Model).
+func MockClassicWithModesDevice(mode string, model *asserts.Model) boot.Device {
+	dev := MockUC20Device(mode, model).(*mockDevice)
+	dev.classic = true
+	return dev
+}
+
 func snapAndMode(str string) (snap, mode string, uc20 bool) {
 	parts := strings.SplitN(string(str), "@", 2)
 	if len(parts) == 1 || parts[1] == "" {
@@ -79,7 +90,7 @@ func snapAndMode(str string) (snap, mode string, uc20 bool) {
 }
 
 func (d *mockDevice) Kernel() string   { return d.bootSnap }
-func (d *mockDevice) Classic() bool    { return d.bootSnap == "" }
+func (d *mockDevice) Classic() bool    { return d.classic || d.bootSnap == "" }
 func (d *mockDevice) RunMode() bool    { return d.mode == "run" }
 func (d *mockDevice) HasModeenv() bool { return d.uc20 }
 func (d *mockDevice) Base() string {
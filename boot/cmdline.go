@@ -26,6 +26,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/strutil"
@@ -40,10 +41,13 @@ const (
 	// ModeRecover is a mode in which the device boots into the recovery
 	// system.
 	ModeRecover = "recover"
+	// ModeFactoryReset is a mode in which the device is reinstalled
+	// from a recovery system while keeping the content of ubuntu-save.
+	ModeFactoryReset = "factory-reset"
 )
 
 var (
-	validModes = []string{ModeInstall, ModeRecover, ModeRun}
+	validModes = []string{ModeInstall, ModeRecover, ModeFactoryReset, ModeRun}
 )
 
 // ModeAndRecoverySystemFromKernelCommandLine returns the current system mode
@@ -75,6 +79,8 @@ func ModeAndRecoverySystemFromKernelCommandLine() (mode, sysLabel string, err er
 		return "", "", fmt.Errorf("cannot specify system label without a mode")
 	case mode == ModeInstall && sysLabel == "":
 		return "", "", fmt.Errorf("cannot specify install mode without system label")
+	case mode == ModeFactoryReset && sysLabel == "":
+		return "", "", fmt.Errorf("cannot specify factory-reset mode without system label")
 	case mode == ModeRun && sysLabel != "":
 		// XXX: should we silently ignore the label? at least log for now
 		logger.Noticef(`ignoring recovery system label %q in "run" mode`, sysLabel)
@@ -103,6 +109,37 @@ const (
 	candidateEdition
 )
 
+const (
+	// extraCmdlineArgsVar is the variable of the run mode bootloader
+	// environment which carries the extra kernel command line arguments
+	// provided by the gadget.
+	extraCmdlineArgsVar = "snapd_extra_cmdline_args"
+	// tryExtraCmdlineArgsVar carries the new extra kernel command line
+	// arguments which are being tried.
+	tryExtraCmdlineArgsVar = "snapd_try_extra_cmdline_args"
+	// extraCmdlineArgsStatusVar carries the status of trying new extra
+	// kernel command line arguments, it follows the same protocol as
+	// kernel_status, so that the bootloader falls back to the current
+	// arguments when a boot with the new ones was not successful.
+	extraCmdlineArgsStatusVar = "snapd_extra_cmdline_args_status"
+)
+
+func runModeBootloaderManagingItsAssets() (bootloader.TrustedAssetsBootloader, error) {
+	opts := &bootloader.Options{
+		Role:        bootloader.RoleRunMode,
+		NoSlashBoot: true,
+	}
+	return getBootloaderManagingItsAssets(InitramfsUbuntuBootDir, opts)
+}
+
+func extraCommandLineArgs(bl bootloader.Bootloader) (string, error) {
+	m, err := bl.GetBootVars(extraCmdlineArgsVar)
+	if err != nil {
+		return "", fmt.Errorf("cannot get extra kernel command line arguments: %v", err)
+	}
+	return m[extraCmdlineArgsVar], nil
+}
+
 func composeCommandLine(model *asserts.Model, currentOrCandidate int, mode, system string) (string, error) {
 	if model.Grade() == asserts.ModelGradeUnset {
 		return "", nil
@@ -136,8 +173,14 @@ func composeCommandLine(model *asserts.Model, currentOrCandidate int, mode, syst
 		}
 		return "", err
 	}
-	// TODO:UC20: fetch extra args from gadget
+	// TODO:UC20: fetch extra args of the recovery system from its gadget
 	extraArgs := ""
+	if mode == ModeRun {
+		extraArgs, err = extraCommandLineArgs(mbl)
+		if err != nil {
+			return "", err
+		}
+	}
 	if currentOrCandidate == currentEdition {
 		return mbl.CommandLine(modeArg, systemArg, extraArgs)
 	} else {
@@ -284,6 +327,141 @@ func observeCommandLineUpdate(model *asserts.Model) error {
 	return nil
 }
 
+// UpdateCommandLineForGadgetComponent handles an update of the extra kernel
+// command line arguments provided by the gadget in the given directory. When
+// the kernel command line of the run system changes, the candidate command line
+// is recorded in the modeenv next to the current one, the encryption keys are
+// resealed and the new arguments are set to be tried by the bootloader on the
+// next boot. True is returned in that case and the system needs to be rebooted
+// for the change to take effect. The new arguments are committed as the
+// current ones only once the boot is marked as successful, otherwise the
+// bootloader falls back to the current ones.
+func UpdateCommandLineForGadgetComponent(dev Device, gadgetDir string) (needsReboot bool, err error) {
+	if !dev.HasModeenv() {
+		// only UC20 devices use the command line from the gadget
+		return false, nil
+	}
+	if !dev.RunMode() {
+		return false, fmt.Errorf("internal error: kernel command line can only be updated in run mode")
+	}
+	extraArgs, err := gadget.KernelCommandLineFromGadget(gadgetDir)
+	if err != nil {
+		return false, err
+	}
+
+	mbl, err := runModeBootloaderManagingItsAssets()
+	if err != nil {
+		if err == errBootConfigNotManaged {
+			// we're not managing the kernel command line
+			return false, nil
+		}
+		return false, err
+	}
+
+	m, err := loadModeenv()
+	if err != nil {
+		return false, err
+	}
+	vars, err := mbl.GetBootVars(extraCmdlineArgsVar, extraCmdlineArgsStatusVar)
+	if err != nil {
+		return false, fmt.Errorf("cannot get extra kernel command line arguments: %v", err)
+	}
+	if len(m.CurrentKernelCommandLines) > 1 || vars[extraCmdlineArgsStatusVar] != "" {
+		// the system has not booted with the candidate command line
+		// of a previous change yet
+		return false, fmt.Errorf("cannot update the kernel command line while another update is pending")
+	}
+	// this is the current expected command line, as recorded by bootstate
+	// when the keys are sealed against it
+	var cmdline string
+	if len(m.CurrentKernelCommandLines) == 1 {
+		cmdline = m.CurrentKernelCommandLines[0]
+	} else {
+		cmdline, err = mbl.CommandLine("snapd_recovery_mode=run", "", vars[extraCmdlineArgsVar])
+		if err != nil {
+			return false, err
+		}
+	}
+	// this is the new expected command line, with the boot config the
+	// system booted with
+	candidateCmdline, err := mbl.CommandLine("snapd_recovery_mode=run", "", extraArgs)
+	if err != nil {
+		return false, err
+	}
+	if cmdline == candidateCmdline {
+		// no change in command line contents, nothing to do
+		return false, nil
+	}
+
+	if len(m.CurrentKernelCommandLines) != 0 {
+		m.CurrentKernelCommandLines = bootCommandLines{cmdline, candidateCmdline}
+		if err := m.Write(); err != nil {
+			return false, err
+		}
+
+		expectReseal := true
+		if err := resealKeyToModeenv(dirs.GlobalRootDir, dev.Model(), m, expectReseal); err != nil {
+			return false, err
+		}
+	}
+
+	// the keys are sealed against both command lines at this point, it is
+	// safe to try the new one
+	if err := mbl.SetBootVars(map[string]string{
+		tryExtraCmdlineArgsVar:    extraArgs,
+		extraCmdlineArgsStatusVar: TryStatus,
+	}); err != nil {
+		return false, fmt.Errorf("cannot set extra kernel command line arguments: %v", err)
+	}
+	return true, nil
+}
+
+// triedExtraCommandLineArgsCommit returns a function committing the extra
+// kernel command line arguments the system was successfully booted with as the
+// current ones. If the bootloader fell back to the current arguments instead,
+// the function drops the ones that were tried. A nil function is returned when
+// there is nothing to commit.
+func triedExtraCommandLineArgsCommit() (func() error, error) {
+	mbl, err := runModeBootloaderManagingItsAssets()
+	if err != nil {
+		if err == errBootConfigNotManaged {
+			return nil, nil
+		}
+		return nil, err
+	}
+	vars, err := mbl.GetBootVars(tryExtraCmdlineArgsVar, extraCmdlineArgsStatusVar)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get extra kernel command line arguments: %v", err)
+	}
+	var newVars map[string]string
+	switch vars[extraCmdlineArgsStatusVar] {
+	case TryingStatus:
+		// booted with the new arguments
+		newVars = map[string]string{
+			extraCmdlineArgsVar:       vars[tryExtraCmdlineArgsVar],
+			tryExtraCmdlineArgsVar:    "",
+			extraCmdlineArgsStatusVar: DefaultStatus,
+		}
+	case DefaultStatus:
+		if vars[tryExtraCmdlineArgsVar] == "" {
+			return nil, nil
+		}
+		// the bootloader fell back to the current arguments
+		newVars = map[string]string{
+			tryExtraCmdlineArgsVar: "",
+		}
+	default:
+		// not rebooted yet
+		return nil, nil
+	}
+	return func() error {
+		if err := mbl.SetBootVars(newVars); err != nil {
+			return fmt.Errorf("cannot commit extra kernel command line arguments: %v", err)
+		}
+		return nil
+	}, nil
+}
+
 // kernelCommandLinesForResealWithFallback provides the list of kernel command
 // lines for use during reseal. During normal operation, the command lines will
 // be listed in the modeenv.
@@ -85,6 +85,13 @@ func (s *kernelCommandLineSuite) TestModeAndLabel(c *C) {
 		// no recovery system label
 		cmd: "snapd_recovery_mode=install foo=bar",
 		err: `cannot specify install mode without system label`,
+	}, {
+		cmd:   "snapd_recovery_mode=factory-reset snapd_recovery_system=1234",
+		mode:  boot.ModeFactoryReset,
+		label: "1234",
+	}, {
+		cmd: "snapd_recovery_mode=factory-reset",
+		err: `cannot specify factory-reset mode without system label`,
 	}, {
 		cmd: "snapd_recovery_system=1234",
 		err: `cannot specify system label without a mode`,
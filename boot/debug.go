@@ -20,29 +20,65 @@
 package boot
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
+	"github.com/mvo5/goconfigparser"
+
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/efi"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
 )
 
-// DebugDumpBootVars writes a dump of the snapd bootvars to the given writer
-func DebugDumpBootVars(w io.Writer, dir string, uc20 bool) error {
+// uc20DebugBootVars are the boot variables relevant to all UC20 bootloader
+// implementations.
+var uc20DebugBootVars = []string{
+	"snapd_recovery_mode",
+	"snapd_recovery_system",
+	"snapd_recovery_kernel",
+	"snap_kernel",
+	"snap_try_kernel",
+	"kernel_status",
+	"recovery_system_status",
+	"try_recovery_system",
+}
+
+// DebugDumpBootVars writes a dump of the snapd bootvars to the given writer.
+// On UC20 the run mode bootloader is used, unless recovery is set in which
+// case the recovery bootloader is used. When dumping the run mode bootloader
+// variables of the running UC20 system, the base related modeenv variables are
+// written too.
+func DebugDumpBootVars(w io.Writer, dir string, uc20, recovery bool) error {
 	opts := &bootloader.Options{
 		NoSlashBoot: dir != "" && dir != "/",
 	}
 	switch dir {
 	// is it any of the well-known UC20 boot partition mount locations?
 	case InitramfsUbuntuBootDir:
+		if recovery {
+			return fmt.Errorf("cannot use run bootloader root-dir with a recovery flag")
+		}
 		opts.Role = bootloader.RoleRunMode
 		uc20 = true
 	case InitramfsUbuntuSeedDir:
 		opts.Role = bootloader.RoleRecovery
 		uc20 = true
 	}
+	if recovery {
+		uc20 = true
+		opts.Role = bootloader.RoleRecovery
+		if !opts.NoSlashBoot {
+			// no root dir was provided, use the default one for a
+			// recovery bootloader
+			dir = InitramfsUbuntuSeedDir
+		}
+	}
 	if !opts.NoSlashBoot && !uc20 {
 		// this may still be a UC20 system
 		if osutil.FileExists(dirs.SnapModeenvFile) {
@@ -57,21 +93,11 @@ func DebugDumpBootVars(w io.Writer, dir string, uc20 bool) error {
 		"snap_try_kernel",
 	}
 	if uc20 {
-		if !opts.NoSlashBoot {
+		if !opts.NoSlashBoot && !recovery {
 			// no root directory set, default to run mode
 			opts.Role = bootloader.RoleRunMode
 		}
-		// keys relevant to all uc20 bootloader implementations
-		allKeys = []string{
-			"snapd_recovery_mode",
-			"snapd_recovery_system",
-			"snapd_recovery_kernel",
-			"snap_kernel",
-			"snap_try_kernel",
-			"kernel_status",
-			"recovery_system_status",
-			"try_recovery_system",
-		}
+		allKeys = uc20DebugBootVars
 	}
 	bloader, err := bootloader.Find(dir, opts)
 	if err != nil {
@@ -85,6 +111,39 @@ func DebugDumpBootVars(w io.Writer, dir string, uc20 bool) error {
 	for _, k := range allKeys {
 		fmt.Fprintf(w, "%s=%s\n", k, bootVars[k])
 	}
+
+	if !uc20 || opts.NoSlashBoot || opts.Role != bootloader.RoleRunMode {
+		return nil
+	}
+	// the base is tracked in the modeenv rather than in the bootloader
+	modeenv, err := ReadModeenv("")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "base=%s\n", modeenv.Base)
+	fmt.Fprintf(w, "try_base=%s\n", modeenv.TryBase)
+	fmt.Fprintf(w, "base_status=%s\n", modeenv.BaseStatus)
+	return nil
+}
+
+// debugUEFIVars are the UEFI variables relevant to booting UC20.
+var debugUEFIVars = []string{
+	loaderDevicePartUUID,
+}
+
+// DebugDumpUEFIVars writes a dump of the UEFI variables relevant to booting
+// snapd systems to the given writer.
+func DebugDumpUEFIVars(w io.Writer) error {
+	for _, name := range debugUEFIVars {
+		value, _, err := efi.ReadVarString(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s=%s\n", name, value)
+	}
 	return nil
 }
 
@@ -133,3 +192,111 @@ func DebugSetBootVars(dir string, recoveryBootloader bool, varEqVal []string) er
 	}
 	return bloader.SetBootVars(toSet)
 }
+
+// DebugModeenv returns the raw key/value entries of the modeenv of the
+// running UC20 system.
+func DebugModeenv() (map[string]string, error) {
+	cfg := goconfigparser.New()
+	cfg.AllowNoSectionHeader = true
+	if err := cfg.ReadFile(modeenvFile("")); err != nil {
+		return nil, err
+	}
+	keys, err := cfg.Options("")
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]string, len(keys))
+	for _, k := range keys {
+		val, err := cfg.Get("", k)
+		if err != nil {
+			return nil, err
+		}
+		entries[k] = val
+	}
+	return entries, nil
+}
+
+// DebugBootloaderVars returns the snapd boot variables of the UC20 bootloader
+// with the given role, either the run mode one on ubuntu-boot or the recovery
+// one on ubuntu-seed.
+func DebugBootloaderVars(role bootloader.Role) (map[string]string, error) {
+	var dir string
+	switch role {
+	case bootloader.RoleRunMode:
+		dir = InitramfsUbuntuBootDir
+	case bootloader.RoleRecovery:
+		dir = InitramfsUbuntuSeedDir
+	default:
+		return nil, fmt.Errorf("internal error: unsupported bootloader role %q", role)
+	}
+	opts := &bootloader.Options{
+		Role:        role,
+		NoSlashBoot: true,
+	}
+	bloader, err := bootloader.Find(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return bloader.GetBootVars(uc20DebugBootVars...)
+}
+
+// DebugBootChainsInfo holds the boot chains computed during the last
+// resealing of the encryption keys, along with the boot chains expected for
+// the current state of the system.
+type DebugBootChainsInfo struct {
+	ResealCount        int                   `json:"reseal-count"`
+	BootChains         predictableBootChains `json:"boot-chains"`
+	RecoveryBootChains predictableBootChains `json:"recovery-boot-chains"`
+
+	ExpectedBootChains         json.RawMessage `json:"expected-boot-chains,omitempty"`
+	ExpectedRecoveryBootChains json.RawMessage `json:"expected-recovery-boot-chains,omitempty"`
+	// ResealNeeded is set when the expected boot chains differ from the
+	// stored ones.
+	ResealNeeded bool `json:"reseal-needed,omitempty"`
+}
+
+// DebugBootChains returns the boot chains of the run and recovery keys as
+// stored during the last resealing. If the keys were sealed and a model is
+// provided, the boot chains expected for the current modeenv are computed
+// too, so that they can be compared with the stored ones.
+func DebugBootChains(model *asserts.Model) (*DebugBootChainsInfo, error) {
+	runChains, resealCount, err := readBootChains(bootChainsFileUnder(dirs.GlobalRootDir))
+	if err != nil {
+		return nil, err
+	}
+	recoveryChains, _, err := readBootChains(recoveryBootChainsFileUnder(dirs.GlobalRootDir))
+	if err != nil {
+		return nil, err
+	}
+	info := &DebugBootChainsInfo{
+		ResealCount:        resealCount,
+		BootChains:         runChains,
+		RecoveryBootChains: recoveryChains,
+	}
+	if model == nil || runChains == nil {
+		return info, nil
+	}
+
+	modeenv, err := ReadModeenv("")
+	if err != nil {
+		return nil, err
+	}
+	expected, err := ComputeBootChains(model, modeenv)
+	if err != nil {
+		return nil, err
+	}
+	info.ExpectedBootChains = expected.Run
+	info.ExpectedRecoveryBootChains = expected.Fallback
+
+	// the stored chains are in the predictable form already
+	storedRun, err := json.Marshal(runChains)
+	if err != nil {
+		return nil, err
+	}
+	storedRecovery, err := json.Marshal(recoveryChains)
+	if err != nil {
+		return nil, err
+	}
+	info.ResealNeeded = !bytes.Equal(storedRun, expected.Run) || !bytes.Equal(storedRecovery, expected.Fallback)
+	return info, nil
+}
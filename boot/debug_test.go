@@ -0,0 +1,253 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/bootloader/efi"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/seed"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+type debugSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&debugSuite{})
+
+func (s *debugSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+}
+
+func (s *debugSuite) TestDebugModeenv(c *C) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapModeenvFile), 0755), IsNil)
+	err := ioutil.WriteFile(dirs.SnapModeenvFile, []byte(`mode=run
+recovery_system=20191127
+current_kernels=pc-kernel_1.snap
+some_unknown_key=foo
+`), 0644)
+	c.Assert(err, IsNil)
+
+	entries, err := boot.DebugModeenv()
+	c.Assert(err, IsNil)
+	c.Check(entries, DeepEquals, map[string]string{
+		"mode":             "run",
+		"recovery_system":  "20191127",
+		"current_kernels":  "pc-kernel_1.snap",
+		"some_unknown_key": "foo",
+	})
+}
+
+func (s *debugSuite) TestDebugModeenvNotFound(c *C) {
+	_, err := boot.DebugModeenv()
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *debugSuite) TestDebugBootloaderVars(c *C) {
+	bl := bootloadertest.Mock("mock", c.MkDir())
+	bl.BootVars = map[string]string{
+		"snapd_recovery_mode": "run",
+		"snap_kernel":         "pc-kernel_1.snap",
+		"unrelated":           "foo",
+	}
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+
+	vars, err := boot.DebugBootloaderVars(bootloader.RoleRunMode)
+	c.Assert(err, IsNil)
+	c.Check(vars["snapd_recovery_mode"], Equals, "run")
+	c.Check(vars["snap_kernel"], Equals, "pc-kernel_1.snap")
+	_, ok := vars["unrelated"]
+	c.Check(ok, Equals, false)
+
+	_, err = boot.DebugBootloaderVars(bootloader.RoleSole)
+	c.Check(err, ErrorMatches, `internal error: unsupported bootloader role ""`)
+}
+
+func (s *debugSuite) TestDebugDumpBootVarsUC20WithModeenv(c *C) {
+	bl := bootloadertest.Mock("mock", c.MkDir())
+	bl.BootVars = map[string]string{
+		"snapd_recovery_mode": "run",
+		"snap_kernel":         "pc-kernel_1.snap",
+		"kernel_status":       "try",
+	}
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+
+	m := &boot.Modeenv{
+		Mode:       "run",
+		Base:       "core20_1.snap",
+		TryBase:    "core20_2.snap",
+		BaseStatus: boot.TryingStatus,
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+
+	buf := &bytes.Buffer{}
+	err := boot.DebugDumpBootVars(buf, "", false, false)
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), Equals, `snapd_recovery_mode=run
+snapd_recovery_system=
+snapd_recovery_kernel=
+snap_kernel=pc-kernel_1.snap
+snap_try_kernel=
+kernel_status=try
+recovery_system_status=
+try_recovery_system=
+base=core20_1.snap
+try_base=core20_2.snap
+base_status=trying
+`)
+
+	// the modeenv is not relevant for the recovery bootloader
+	buf.Reset()
+	err = boot.DebugDumpBootVars(buf, "", false, true)
+	c.Assert(err, IsNil)
+	c.Check(buf.String(), Not(testutil.Contains), "base")
+}
+
+func (s *debugSuite) TestDebugDumpBootVarsRecoveryWithRunRootDir(c *C) {
+	err := boot.DebugDumpBootVars(&bytes.Buffer{}, boot.InitramfsUbuntuBootDir, false, true)
+	c.Check(err, ErrorMatches, "cannot use run bootloader root-dir with a recovery flag")
+}
+
+func (s *debugSuite) TestDebugDumpUEFIVars(c *C) {
+	restore := efi.MockVars(map[string][]byte{
+		"LoaderDevicePartUUID-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f": bootloadertest.UTF16Bytes("A9F5C949-AB89-5B47-A7BF-56DD28F96E65"),
+	}, nil)
+	defer restore()
+
+	buf := &bytes.Buffer{}
+	c.Assert(boot.DebugDumpUEFIVars(buf), IsNil)
+	c.Check(buf.String(), Equals, "LoaderDevicePartUUID-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f=A9F5C949-AB89-5B47-A7BF-56DD28F96E65\n")
+
+	restore = efi.MockVars(nil, nil)
+	defer restore()
+	c.Check(boot.DebugDumpUEFIVars(buf), Equals, efi.ErrNoEFISystem)
+}
+
+func (s *debugSuite) TestDebugBootChains(c *C) {
+	info, err := boot.DebugBootChains(nil)
+	c.Assert(err, IsNil)
+	c.Check(info, DeepEquals, &boot.DebugBootChainsInfo{})
+
+	pbc := boot.ToPredictableBootChains([]boot.BootChain{
+		{
+			BrandID:        "mybrand",
+			Model:          "foo",
+			Grade:          "signed",
+			ModelSignKeyID: "my-key-id",
+			Kernel:         "pc-kernel",
+			KernelRevision: "1",
+			KernelCmdlines: []string{"snapd_recovery_mode=run"},
+		},
+	})
+	err = boot.WriteBootChains(pbc, filepath.Join(dirs.SnapFDEDir, "boot-chains"), 3)
+	c.Assert(err, IsNil)
+	err = boot.WriteBootChains(pbc, filepath.Join(dirs.SnapFDEDir, "recovery-boot-chains"), 0)
+	c.Assert(err, IsNil)
+
+	info, err = boot.DebugBootChains(nil)
+	c.Assert(err, IsNil)
+	c.Check(info.ResealCount, Equals, 3)
+	c.Check(info.BootChains, DeepEquals, pbc)
+	c.Check(info.RecoveryBootChains, DeepEquals, pbc)
+}
+
+func (s *debugSuite) TestDebugBootChainsExpected(c *C) {
+	model := boottest.MakeMockUC20Model()
+
+	mtbl := bootloadertest.Mock("trusted", c.MkDir()).WithTrustedAssets()
+	mtbl.StaticCommandLine = "static cmdline"
+	mtbl.BootChainList = []bootloader.BootFile{
+		bootloader.NewBootFile("/var/lib/snapd/snap/pc-kernel_500.snap", "kernel.efi", bootloader.RoleRunMode),
+	}
+	mtbl.RecoveryBootChainList = []bootloader.BootFile{
+		bootloader.NewBootFile("/var/lib/snapd/seed/snaps/pc-kernel_1.snap", "kernel.efi", bootloader.RoleRecovery),
+	}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	restore := boot.MockSeedReadSystemEssential(func(seedDir, label string, essentialTypes []snap.Type, tm timings.Measurer) (*asserts.Model, []*seed.Snap, error) {
+		kernelSnap := &seed.Snap{
+			Path:     "/var/lib/snapd/seed/snaps/pc-kernel_1.snap",
+			SideInfo: &snap.SideInfo{RealName: "pc-kernel", Revision: snap.R(1)},
+		}
+		return model, []*seed.Snap{kernelSnap}, nil
+	})
+	defer restore()
+
+	modeenv := &boot.Modeenv{
+		Mode:                      "run",
+		CurrentRecoverySystems:    []string{"20200825"},
+		GoodRecoverySystems:       []string{"20200825"},
+		CurrentKernels:            []string{"pc-kernel_500.snap"},
+		CurrentKernelCommandLines: boot.BootCommandLines{"snapd_recovery_mode=run static cmdline"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	// the keys were not sealed, there is nothing to compare against
+	info, err := boot.DebugBootChains(model)
+	c.Assert(err, IsNil)
+	c.Check(info, DeepEquals, &boot.DebugBootChainsInfo{})
+
+	// store the chains the keys would be sealed against
+	chains, err := boot.ComputeBootChains(model, modeenv)
+	c.Assert(err, IsNil)
+	var run, fallback boot.PredictableBootChains
+	c.Assert(json.Unmarshal(chains.Run, &run), IsNil)
+	c.Assert(json.Unmarshal(chains.Fallback, &fallback), IsNil)
+	c.Assert(boot.WriteBootChains(run, filepath.Join(dirs.SnapFDEDir, "boot-chains"), 1), IsNil)
+	c.Assert(boot.WriteBootChains(fallback, filepath.Join(dirs.SnapFDEDir, "recovery-boot-chains"), 1), IsNil)
+
+	info, err = boot.DebugBootChains(model)
+	c.Assert(err, IsNil)
+	c.Check(info.BootChains, DeepEquals, run)
+	c.Check(string(info.ExpectedBootChains), Equals, string(chains.Run))
+	c.Check(string(info.ExpectedRecoveryBootChains), Equals, string(chains.Fallback))
+	c.Check(info.ResealNeeded, Equals, false)
+
+	// a new kernel is being tried
+	modeenv.CurrentKernels = append(modeenv.CurrentKernels, "pc-kernel_501.snap")
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	info, err = boot.DebugBootChains(model)
+	c.Assert(err, IsNil)
+	c.Check(info.BootChains, DeepEquals, run)
+	c.Check(string(info.ExpectedBootChains), Not(Equals), string(chains.Run))
+	c.Check(string(info.ExpectedRecoveryBootChains), Equals, string(chains.Fallback))
+	c.Check(info.ResealNeeded, Equals, true)
+}
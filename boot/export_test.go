@@ -21,12 +21,14 @@ package boot
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timings"
 )
 
@@ -39,7 +41,7 @@ func NewCoreBootParticipant(s snap.PlaceInfo, t snap.Type, dev Device) *coreBoot
 }
 
 func NewCoreKernel(s snap.PlaceInfo, d Device) *coreKernel {
-	return &coreKernel{s, bootloaderOptionsForDeviceKernel(d)}
+	return &coreKernel{s, bootloaderOptionsForDeviceKernel(d), d}
 }
 
 type Trivial = trivial
@@ -58,7 +60,9 @@ var (
 	MarshalModeenvEntryTo        = marshalModeenvEntryTo
 	UnmarshalModeenvValueFromCfg = unmarshalModeenvValueFromCfg
 
-	NewTrustedAssetsCache = newTrustedAssetsCache
+	NewTrustedAssetsCache         = newTrustedAssetsCache
+	RemoveUnusedTrustedBootAssets = removeUnusedTrustedBootAssets
+	DropAssetHash                 = dropAssetHash
 
 	ObserveSuccessfulBootWithAssets = observeSuccessfulBootAssets
 	SealKeyToModeenv                = sealKeyToModeenv
@@ -69,6 +73,7 @@ var (
 
 type BootAssetsMap = bootAssetsMap
 type BootCommandLines = bootCommandLines
+type BootIssues = bootIssues
 type TrackedAsset = trackedAsset
 
 func (t *TrackedAsset) Equals(blName, name, hash string) error {
@@ -81,6 +86,10 @@ func (t *TrackedAsset) Equals(blName, name, hash string) error {
 	return nil
 }
 
+func TrackAssetHash(bam *BootAssetsMap, blName, name, hash string) error {
+	return trackAssetHash(bam, &trackedAsset{blName: blName, name: name, hash: hash})
+}
+
 func (o *TrustedAssetsInstallObserver) CurrentTrustedBootAssetsMap() BootAssetsMap {
 	return o.currentTrustedBootAssetsMap()
 }
@@ -105,6 +114,14 @@ func MockSecbootSealKeys(f func(keys []secboot.SealKeyRequest, params *secboot.S
 	}
 }
 
+func MockSecbootCheckKeySealingSupported(f func() error) (restore func()) {
+	old := secbootCheckKeySealingSupported
+	secbootCheckKeySealingSupported = f
+	return func() {
+		secbootCheckKeySealingSupported = old
+	}
+}
+
 func MockSecbootResealKeys(f func(params *secboot.ResealKeysParams) error) (restore func()) {
 	old := secbootResealKeys
 	secbootResealKeys = f
@@ -189,3 +206,28 @@ func MockResealKeyToModeenvUsingFDESetupHook(f func(string, *asserts.Model, *Mod
 		resealKeyToModeenvUsingFDESetupHook = old
 	}
 }
+
+func MockBootTimingsSources(bootID func() (string, error), bootTimestamps func() (*systemd.BootTimestamps, error), monotonic func() (time.Duration, error), now func() time.Time) (restore func()) {
+	oldBootID := osutilBootID
+	oldBootTimestamps := systemdBootTimestamps
+	oldMonotonic := monotonicNow
+	oldNow := timeNow
+	osutilBootID = bootID
+	systemdBootTimestamps = bootTimestamps
+	monotonicNow = monotonic
+	timeNow = now
+	return func() {
+		osutilBootID = oldBootID
+		systemdBootTimestamps = oldBootTimestamps
+		monotonicNow = oldMonotonic
+		timeNow = oldNow
+	}
+}
+
+func MockTimeNow(now func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = now
+	return func() {
+		timeNow = old
+	}
+}
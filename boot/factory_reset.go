@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/bootloader"
+)
+
+// The progress of a factory reset is tracked in the environment of the
+// recovery bootloader, which unlike ubuntu-data is kept across the reset,
+// following the same try/commit discipline as kernels and recovery systems:
+//  - TryStatus: the factory reset was requested, but the device has not booted
+//    into factory-reset mode yet, the request can still be rolled back
+//  - TryingStatus: the device booted into factory-reset mode and ubuntu-data is
+//    being reset, a reboot at this point resumes the factory reset
+//  - DefaultStatus: no factory reset is in progress
+const factoryResetStatusVar = "factory_reset_status"
+
+func findRecoveryBootloader() (bootloader.Bootloader, error) {
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	return bootloader.Find(InitramfsUbuntuSeedDir, opts)
+}
+
+// EnterFactoryReset sets up the recovery bootloader such that the device
+// boots into factory-reset mode of the given recovery system on the next
+// boot. The request can be rolled back with CancelFactoryReset as long as
+// the device has not booted into factory-reset mode.
+func EnterFactoryReset(dev Device, systemLabel string) error {
+	if !dev.HasModeenv() {
+		// only UC20 devices are supported
+		return ErrUnsupportedSystemMode
+	}
+	if systemLabel == "" {
+		return fmt.Errorf("internal error: system label is unset")
+	}
+
+	bl, err := findRecoveryBootloader()
+	if err != nil {
+		return err
+	}
+	return bl.SetBootVars(map[string]string{
+		"snapd_recovery_system": systemLabel,
+		"snapd_recovery_mode":   ModeFactoryReset,
+		factoryResetStatusVar:   TryStatus,
+	})
+}
+
+// FactoryResetStatus returns the status of the factory reset of the device,
+// that is DefaultStatus when no factory reset is in progress, TryStatus when
+// one was requested and TryingStatus when one was started.
+func FactoryResetStatus() (string, error) {
+	bl, err := findRecoveryBootloader()
+	if err != nil {
+		return "", err
+	}
+	m, err := bl.GetBootVars(factoryResetStatusVar)
+	if err != nil {
+		return "", err
+	}
+	status := m[factoryResetStatusVar]
+	switch status {
+	case DefaultStatus, TryStatus, TryingStatus:
+		return status, nil
+	default:
+		return "", fmt.Errorf("internal error: unexpected factory reset status %q", status)
+	}
+}
+
+// MarkFactoryResetStarted, typically called while in initramfs, records that
+// the device booted into factory-reset mode. From then on the factory reset
+// cannot be rolled back anymore, and is resumed if the device reboots before
+// it is finished. It is fine to call it again when resuming a factory reset.
+func MarkFactoryResetStarted() error {
+	bl, err := findRecoveryBootloader()
+	if err != nil {
+		return err
+	}
+	// TODO:UC20: seed may need to be switched to RW
+	return bl.SetBootVars(map[string]string{
+		factoryResetStatusVar: TryingStatus,
+	})
+}
+
+// CancelFactoryReset rolls back a factory reset requested with
+// EnterFactoryReset, such that the device boots into run mode again. It is
+// an error to cancel a factory reset which was already started.
+func CancelFactoryReset(dev Device) error {
+	if !dev.HasModeenv() {
+		// only UC20 devices are supported
+		return ErrUnsupportedSystemMode
+	}
+
+	bl, err := findRecoveryBootloader()
+	if err != nil {
+		return err
+	}
+	m, err := bl.GetBootVars(factoryResetStatusVar)
+	if err != nil {
+		return err
+	}
+	switch m[factoryResetStatusVar] {
+	case DefaultStatus:
+		// nothing to roll back
+		return nil
+	case TryStatus:
+		// not started yet
+	default:
+		return fmt.Errorf("cannot cancel a factory reset which was already started")
+	}
+	return bl.SetBootVars(map[string]string{
+		"snapd_recovery_system": "",
+		"snapd_recovery_mode":   ModeRun,
+		factoryResetStatusVar:   DefaultStatus,
+	})
+}
+
+// FinishFactoryReset, called once the device was reset, sets up the recovery
+// bootloader such that the device boots into run mode of the given recovery
+// system on the next boot and clears the factory reset status.
+func FinishFactoryReset(systemLabel string) error {
+	bl, err := findRecoveryBootloader()
+	if err != nil {
+		return err
+	}
+	m, err := bl.GetBootVars(factoryResetStatusVar)
+	if err != nil {
+		return err
+	}
+	if m[factoryResetStatusVar] == TryStatus {
+		return fmt.Errorf("internal error: cannot finish a factory reset which was not started")
+	}
+	return bl.SetBootVars(map[string]string{
+		"snapd_recovery_system": systemLabel,
+		"snapd_recovery_mode":   ModeRun,
+		factoryResetStatusVar:   DefaultStatus,
+	})
+}
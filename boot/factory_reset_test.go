@@ -0,0 +1,155 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/bootloader/bootloadertest"
+)
+
+type factoryResetSuite struct {
+	baseBootenvSuite
+
+	bootloader *bootloadertest.MockBootloader
+
+	dev boot.Device
+}
+
+var _ = Suite(&factoryResetSuite{})
+
+func (s *factoryResetSuite) SetUpTest(c *C) {
+	s.baseBootenvSuite.SetUpTest(c)
+
+	s.bootloader = bootloadertest.Mock("mock", c.MkDir())
+	s.forceBootloader(s.bootloader)
+
+	s.dev = boottest.MockUC20Device("", nil)
+}
+
+func (s *factoryResetSuite) checkStatus(c *C, expected string) {
+	status, err := boot.FactoryResetStatus()
+	c.Assert(err, IsNil)
+	c.Check(status, Equals, expected)
+}
+
+func (s *factoryResetSuite) TestFactoryResetHappy(c *C) {
+	s.checkStatus(c, boot.DefaultStatus)
+
+	err := boot.EnterFactoryReset(s.dev, "1234")
+	c.Assert(err, IsNil)
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_recovery_system": "1234",
+		"snapd_recovery_mode":   "factory-reset",
+		"factory_reset_status":  "try",
+	})
+	s.checkStatus(c, boot.TryStatus)
+
+	// booted into factory-reset mode
+	c.Assert(boot.MarkFactoryResetStarted(), IsNil)
+	s.checkStatus(c, boot.TryingStatus)
+	c.Check(s.bootloader.BootVars["snapd_recovery_mode"], Equals, "factory-reset")
+
+	// rebooted before the reset was finished, the reset is resumed
+	c.Assert(boot.MarkFactoryResetStarted(), IsNil)
+	s.checkStatus(c, boot.TryingStatus)
+
+	err = boot.FinishFactoryReset("1234")
+	c.Assert(err, IsNil)
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_recovery_system": "1234",
+		"snapd_recovery_mode":   "run",
+		"factory_reset_status":  "",
+	})
+	s.checkStatus(c, boot.DefaultStatus)
+}
+
+func (s *factoryResetSuite) TestEnterFactoryResetNonUC20(c *C) {
+	err := boot.EnterFactoryReset(boottest.MockDevice("some-snap"), "1234")
+	c.Assert(err, Equals, boot.ErrUnsupportedSystemMode)
+	c.Check(s.bootloader.BootVars, HasLen, 0)
+}
+
+func (s *factoryResetSuite) TestEnterFactoryResetErr(c *C) {
+	err := boot.EnterFactoryReset(s.dev, "")
+	c.Assert(err, ErrorMatches, "internal error: system label is unset")
+
+	s.bootloader.SetErr = errors.New("no can do")
+	err = boot.EnterFactoryReset(s.dev, "1234")
+	c.Assert(err, ErrorMatches, "no can do")
+}
+
+func (s *factoryResetSuite) TestCancelFactoryReset(c *C) {
+	err := boot.EnterFactoryReset(s.dev, "1234")
+	c.Assert(err, IsNil)
+
+	err = boot.CancelFactoryReset(s.dev)
+	c.Assert(err, IsNil)
+	c.Check(s.bootloader.BootVars, DeepEquals, map[string]string{
+		"snapd_recovery_system": "",
+		"snapd_recovery_mode":   "run",
+		"factory_reset_status":  "",
+	})
+
+	// nothing to cancel anymore
+	err = boot.CancelFactoryReset(s.dev)
+	c.Assert(err, IsNil)
+	s.checkStatus(c, boot.DefaultStatus)
+}
+
+func (s *factoryResetSuite) TestCancelFactoryResetStarted(c *C) {
+	err := boot.EnterFactoryReset(s.dev, "1234")
+	c.Assert(err, IsNil)
+	c.Assert(boot.MarkFactoryResetStarted(), IsNil)
+
+	err = boot.CancelFactoryReset(s.dev)
+	c.Assert(err, ErrorMatches, "cannot cancel a factory reset which was already started")
+	c.Check(s.bootloader.BootVars["snapd_recovery_mode"], Equals, "factory-reset")
+	s.checkStatus(c, boot.TryingStatus)
+}
+
+func (s *factoryResetSuite) TestFinishFactoryResetNotStarted(c *C) {
+	err := boot.EnterFactoryReset(s.dev, "1234")
+	c.Assert(err, IsNil)
+
+	err = boot.FinishFactoryReset("1234")
+	c.Assert(err, ErrorMatches, "internal error: cannot finish a factory reset which was not started")
+	c.Check(s.bootloader.BootVars["snapd_recovery_mode"], Equals, "factory-reset")
+}
+
+func (s *factoryResetSuite) TestFinishFactoryResetNoStatus(c *C) {
+	// factory reset requested without tracking its status
+	err := boot.SetRecoveryBootSystemAndMode(s.dev, "1234", "factory-reset")
+	c.Assert(err, IsNil)
+
+	err = boot.FinishFactoryReset("1234")
+	c.Assert(err, IsNil)
+	c.Check(s.bootloader.BootVars["snapd_recovery_mode"], Equals, "run")
+}
+
+func (s *factoryResetSuite) TestFactoryResetStatusUnexpected(c *C) {
+	s.bootloader.BootVars["factory_reset_status"] = "tried"
+	_, err := boot.FactoryResetStatus()
+	c.Assert(err, ErrorMatches, `internal error: unexpected factory reset status "tried"`)
+}
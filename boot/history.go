@@ -0,0 +1,131 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+)
+
+// maxBootHistoryEntries is how many of the most recent boot state
+// transitions are kept.
+const maxBootHistoryEntries = 100
+
+// BootHistoryEntry records a transition of the boot state of a kernel or
+// base snap.
+type BootHistoryEntry struct {
+	Time time.Time `json:"time"`
+	// Action is one of "set-next", "mark-successful" or "revert".
+	Action   string    `json:"action"`
+	SnapType snap.Type `json:"snap-type"`
+	// Snap is the snap set up for the next boot or marked as successfully
+	// booted.
+	Snap string `json:"snap,omitempty"`
+	// Current and Try are the current and the try snap at the time of
+	// the transition, a try snap left behind when marking the boot
+	// successful was not booted successfully.
+	Current   string `json:"current,omitempty"`
+	Try       string `json:"try,omitempty"`
+	OldStatus string `json:"old-status"`
+	NewStatus string `json:"new-status"`
+	// Error is set when committing the transition failed.
+	Error string `json:"error,omitempty"`
+}
+
+// bootHistory collects the transitions of a boot state update until the
+// update is committed.
+type bootHistory struct {
+	entries []*BootHistoryEntry
+}
+
+func (h *bootHistory) record(e *BootHistoryEntry) {
+	e.Time = timeNow()
+	h.entries = append(h.entries, e)
+}
+
+// flush appends the collected transitions to the boot history, along with
+// the error committing them, if any. Failing to write the history is only
+// logged, it must not fail the update.
+func (h *bootHistory) flush(commitErr error) {
+	if len(h.entries) == 0 {
+		return
+	}
+	if commitErr != nil {
+		for _, e := range h.entries {
+			e.Error = commitErr.Error()
+		}
+	}
+	if err := appendBootHistory(h.entries); err != nil {
+		logger.Noticef("cannot record boot state transitions: %v", err)
+	}
+	h.entries = nil
+}
+
+func readBootHistory() ([]*BootHistoryEntry, error) {
+	f, err := os.Open(dirs.SnapBootHistoryFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var history []*BootHistoryEntry
+	if err := json.NewDecoder(f).Decode(&history); err != nil {
+		return nil, fmt.Errorf("cannot read boot history: %v", err)
+	}
+	return history, nil
+}
+
+func appendBootHistory(entries []*BootHistoryEntry) error {
+	history, err := readBootHistory()
+	if err != nil {
+		// do not let a broken history get in the way of recording
+		// new transitions
+		logger.Noticef("discarding boot history: %v", err)
+		history = nil
+	}
+	history = append(history, entries...)
+	if len(history) > maxBootHistoryEntries {
+		history = history[len(history)-maxBootHistoryEntries:]
+	}
+	b, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dirs.SnapBootHistoryFile), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(dirs.SnapBootHistoryFile, b, 0644, 0)
+}
+
+// BootHistory returns the most recent transitions of the boot state of the
+// kernel and base snaps, oldest first.
+func BootHistory() ([]*BootHistoryEntry, error) {
+	return readBootHistory()
+}
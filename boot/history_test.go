@@ -0,0 +1,227 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/snap"
+)
+
+var historyTime = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+func (s *bootenvSuite) TestBootHistoryEmpty(c *C) {
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Check(history, HasLen, 0)
+}
+
+func (s *bootenvSuite) TestBootHistoryBroken(c *C) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapBootHistoryFile), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(dirs.SnapBootHistoryFile, []byte("{"), 0644), IsNil)
+
+	_, err := boot.BootHistory()
+	c.Check(err, ErrorMatches, "cannot read boot history: .*")
+}
+
+func (s *bootenvSuite) TestBootHistoryTryCycle(c *C) {
+	s.AddCleanup(boot.MockTimeNow(func() time.Time { return historyTime }))
+	coreDev := boottest.MockDevice("krnl")
+
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+	_, err := boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+
+	// the boot script tries the kernel
+	s.bootloader.BootVars["snap_mode"] = boot.TryingStatus
+	c.Assert(boot.MarkBootSuccessful(coreDev), IsNil)
+	// a boot that does not change anything is not recorded
+	c.Assert(boot.MarkBootSuccessful(coreDev), IsNil)
+
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Check(history, DeepEquals, []*boot.BootHistoryEntry{
+		{
+			Time:      historyTime,
+			Action:    "set-next",
+			SnapType:  snap.TypeKernel,
+			Snap:      "krnl_42.snap",
+			Current:   "krnl_41.snap",
+			OldStatus: boot.DefaultStatus,
+			NewStatus: boot.TryStatus,
+		}, {
+			Time:      historyTime,
+			Action:    "mark-successful",
+			SnapType:  snap.TypeKernel,
+			Snap:      "krnl_42.snap",
+			Try:       "krnl_42.snap",
+			OldStatus: boot.TryingStatus,
+			NewStatus: boot.DefaultStatus,
+		},
+	})
+}
+
+func (s *bootenvSuite) TestBootHistoryFailedTry(c *C) {
+	s.AddCleanup(boot.MockTimeNow(func() time.Time { return historyTime }))
+	coreDev := boottest.MockDevice("krnl")
+
+	// the bootloader fell back to the good kernel
+	s.bootloader.BootVars["snap_mode"] = boot.DefaultStatus
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+	s.bootloader.BootVars["snap_try_kernel"] = "krnl_42.snap"
+	c.Assert(boot.MarkBootSuccessful(coreDev), IsNil)
+
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Check(history, DeepEquals, []*boot.BootHistoryEntry{
+		{
+			Time:      historyTime,
+			Action:    "mark-successful",
+			SnapType:  snap.TypeKernel,
+			Try:       "krnl_42.snap",
+			OldStatus: boot.DefaultStatus,
+			NewStatus: boot.DefaultStatus,
+		},
+	})
+}
+
+func (s *bootenvSuite) TestBootHistoryCommitError(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+	s.bootloader.SetErr = errors.New("zap")
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+	_, err := boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, ErrorMatches, "cannot set next boot: zap")
+
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Action, Equals, "set-next")
+	c.Check(history[0].Snap, Equals, "krnl_42.snap")
+	c.Check(history[0].Error, Equals, "zap")
+}
+
+func (s *bootenvSuite) TestBootHistoryKeepsRecentTransitions(c *C) {
+	var old []*boot.BootHistoryEntry
+	for i := 0; i < 100; i++ {
+		old = append(old, &boot.BootHistoryEntry{
+			Action:   "set-next",
+			SnapType: snap.TypeKernel,
+			Snap:     fmt.Sprintf("krnl_%d.snap", i),
+		})
+	}
+	b, err := json.Marshal(old)
+	c.Assert(err, IsNil)
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapBootHistoryFile), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(dirs.SnapBootHistoryFile, b, 0644), IsNil)
+
+	coreDev := boottest.MockDevice("krnl")
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(100)}, SnapType: snap.TypeKernel}
+	_, err = boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 100)
+	c.Check(history[0].Snap, Equals, "krnl_1.snap")
+	c.Check(history[99].Snap, Equals, "krnl_100.snap")
+}
+
+func (s *bootenv20Suite) TestBootHistoryTryCycle20(c *C) {
+	s.AddCleanup(boot.MockTimeNow(func() time.Time { return historyTime }))
+	coreDev := boottest.MockUC20Device("", nil)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		s.normalDefaultState,
+	)
+	defer r()
+
+	_, err := boot.Participant(s.kern2, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+	_, err = boot.Participant(s.base2, snap.TypeBase, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+
+	// the boot script tries the kernel, the initramfs the base
+	c.Assert(s.bootloader.SetBootVars(map[string]string{"kernel_status": boot.TryingStatus}), IsNil)
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	m.BaseStatus = boot.TryingStatus
+	c.Assert(m.Write(), IsNil)
+
+	c.Assert(boot.MarkBootSuccessful(coreDev), IsNil)
+
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Check(history, DeepEquals, []*boot.BootHistoryEntry{
+		{
+			Time:      historyTime,
+			Action:    "set-next",
+			SnapType:  snap.TypeKernel,
+			Snap:      s.kern2.Filename(),
+			Current:   s.kern1.Filename(),
+			OldStatus: boot.DefaultStatus,
+			NewStatus: boot.TryStatus,
+		}, {
+			Time:      historyTime,
+			Action:    "set-next",
+			SnapType:  snap.TypeBase,
+			Snap:      s.base2.Filename(),
+			Current:   s.base1.Filename(),
+			OldStatus: boot.DefaultStatus,
+			NewStatus: boot.TryStatus,
+		}, {
+			Time:      historyTime,
+			Action:    "mark-successful",
+			SnapType:  snap.TypeBase,
+			Snap:      s.base2.Filename(),
+			Current:   s.base1.Filename(),
+			Try:       s.base2.Filename(),
+			OldStatus: boot.TryingStatus,
+			NewStatus: boot.DefaultStatus,
+		}, {
+			Time:      historyTime,
+			Action:    "mark-successful",
+			SnapType:  snap.TypeKernel,
+			Snap:      s.kern2.Filename(),
+			Current:   s.kern1.Filename(),
+			Try:       s.kern2.Filename(),
+			OldStatus: boot.TryingStatus,
+			NewStatus: boot.DefaultStatus,
+		},
+	})
+}
@@ -20,7 +20,9 @@
 package boot
 
 import (
+	"fmt"
 	"os/exec"
+	"reflect"
 	"time"
 
 	"github.com/snapcore/snapd/bootloader"
@@ -29,7 +31,9 @@ import (
 )
 
 // InitramfsRunModeSelectSnapsToMount returns a map of the snap paths to mount
-// for the specified snap types.
+// for the specified snap types. The issues worked around while selecting
+// them, which leave the system in a degraded state, are recorded in the
+// modeenv as the issues of this boot.
 func InitramfsRunModeSelectSnapsToMount(
 	typs []snap.Type,
 	modeenv *Modeenv,
@@ -41,7 +45,7 @@ func InitramfsRunModeSelectSnapsToMount(
 		// TODO: consider passing a bootStateUpdate20 instead?
 		var selectSnapFn func(*Modeenv) (snap.PlaceInfo, error)
 		switch typ {
-		case snap.TypeBase:
+		case snap.TypeBase, snap.TypeOS:
 			bs := &bootState20Base{}
 			selectSnapFn = bs.selectAndCommitSnapInitramfsMount
 		case snap.TypeKernel:
@@ -55,15 +59,32 @@ func InitramfsRunModeSelectSnapsToMount(
 				blOpts: blOpts,
 			}
 			selectSnapFn = bs.selectAndCommitSnapInitramfsMount
+		default:
+			return nil, fmt.Errorf("internal error: cannot select snap to mount for snap type %q", typ)
 		}
 		sn, err = selectSnapFn(modeenv)
 		if err != nil {
 			return nil, err
 		}
+		if sn == nil {
+			// no base on classic systems with modes
+			continue
+		}
 
 		m[typ] = sn
 	}
 
+	// record the issues worked around during this boot, replacing the
+	// ones of the previous boot
+	if len(modeenv.issues) != 0 || len(modeenv.BootIssues) != 0 {
+		if !reflect.DeepEqual([]string(modeenv.BootIssues), modeenv.issues) {
+			modeenv.BootIssues = modeenv.issues
+			if err := modeenv.Write(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return m, nil
 }
 
@@ -129,7 +129,11 @@ func (s *initramfsSuite) TestInitramfsRunModeSelectSnapsToMount(c *C) {
 	base2, err := snap.ParsePlaceInfoFromSnapFileName("core20_2.snap")
 	c.Assert(err, IsNil)
 
+	core1, err := snap.ParsePlaceInfoFromSnapFileName("core_1.snap")
+	c.Assert(err, IsNil)
+
 	baseT := snap.TypeBase
+	osT := snap.TypeOS
 	kernelT := snap.TypeKernel
 
 	tt := []struct {
@@ -157,6 +161,21 @@ func (s *initramfsSuite) TestInitramfsRunModeSelectSnapsToMount(c *C) {
 			expected:    map[snap.Type]snap.PlaceInfo{baseT: base1},
 			comment:     "default base path",
 		},
+		// core snap as the base, for systems migrated from core
+		{
+			m:           &boot.Modeenv{Mode: "run", Base: core1.Filename()},
+			typs:        []snap.Type{osT},
+			snapsToMake: []snap.PlaceInfo{core1},
+			expected:    map[snap.Type]snap.PlaceInfo{osT: core1},
+			comment:     "default core path",
+		},
+		// unsupported snap type
+		{
+			m:          &boot.Modeenv{Mode: "run", Base: base1.Filename()},
+			typs:       []snap.Type{snap.TypeGadget},
+			errPattern: `internal error: cannot select snap to mount for snap type "gadget"`,
+			comment:    "unsupported snap type",
+		},
 		// default kernel path
 		{
 			m:           &boot.Modeenv{Mode: "run", CurrentKernels: []string{kernel1.Filename()}},
@@ -630,3 +649,224 @@ func (s *initramfsSuite) TestInitramfsRunModeSelectSnapsToMount(c *C) {
 		}
 	}
 }
+
+func (s *initramfsSuite) TestInitramfsRunModeSelectSnapsToMountClassicWithModes(c *C) {
+	kernel1, err := snap.ParsePlaceInfoFromSnapFileName("pc-kernel_1.snap")
+	c.Assert(err, IsNil)
+
+	r := makeSnapFilesOnInitramfsUbuntuData(c, Commentf("kernel snap"), kernel1)
+	defer r()
+
+	bl := boottest.MockUC20RunBootenv(bootloadertest.Mock("mock", c.MkDir()))
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+	bl.SetEnabledKernel(kernel1)
+
+	m := &boot.Modeenv{
+		Mode:           "run",
+		Classic:        true,
+		CurrentKernels: []string{kernel1.Filename()},
+	}
+	c.Assert(m.WriteTo(boot.InitramfsWritableDir), IsNil)
+
+	// there is no base to mount
+	mountSnaps, err := boot.InitramfsRunModeSelectSnapsToMount([]snap.Type{snap.TypeBase, snap.TypeKernel}, m)
+	c.Assert(err, IsNil)
+	c.Check(mountSnaps, DeepEquals, map[snap.Type]snap.PlaceInfo{snap.TypeKernel: kernel1})
+}
+
+func (s *initramfsSuite) TestInitramfsRunModeSelectSnapsToMountBootIssues(c *C) {
+	kernel1, err := snap.ParsePlaceInfoFromSnapFileName("pc-kernel_1.snap")
+	c.Assert(err, IsNil)
+	kernel2, err := snap.ParsePlaceInfoFromSnapFileName("pc-kernel_2.snap")
+	c.Assert(err, IsNil)
+	base1, err := snap.ParsePlaceInfoFromSnapFileName("core20_1.snap")
+	c.Assert(err, IsNil)
+	base2, err := snap.ParsePlaceInfoFromSnapFileName("core20_2.snap")
+	c.Assert(err, IsNil)
+
+	r := makeSnapFilesOnInitramfsUbuntuData(c, Commentf("snaps"), kernel1, kernel2, base1, base2)
+	defer r()
+
+	bl := boottest.MockUC20RunBootenv(bootloadertest.Mock("mock", c.MkDir()))
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+	bl.SetEnabledKernel(kernel1)
+
+	typs := []snap.Type{snap.TypeBase, snap.TypeKernel}
+	selectSnaps := func() *boot.Modeenv {
+		m, err := boot.ReadModeenv(boot.InitramfsWritableDir)
+		c.Assert(err, IsNil)
+		mountSnaps, err := boot.InitramfsRunModeSelectSnapsToMount(typs, m)
+		c.Assert(err, IsNil)
+		c.Check(mountSnaps, DeepEquals, map[snap.Type]snap.PlaceInfo{
+			snap.TypeBase:   base1,
+			snap.TypeKernel: kernel1,
+		})
+		m, err = boot.ReadModeenv(boot.InitramfsWritableDir)
+		c.Assert(err, IsNil)
+		return m
+	}
+
+	// the try base failed to boot
+	m := &boot.Modeenv{
+		Mode:           "run",
+		Base:           base1.Filename(),
+		TryBase:        base2.Filename(),
+		BaseStatus:     boot.TryingStatus,
+		CurrentKernels: []string{kernel1.Filename()},
+	}
+	c.Assert(m.WriteTo(boot.InitramfsWritableDir), IsNil)
+	m = selectSnaps()
+	c.Check(m.BootIssues, DeepEquals, boot.BootIssues{
+		`try base "core20_2.snap" failed to boot, booted base "core20_1.snap"`,
+	})
+
+	// the issues of the previous boot are dropped on a clean boot
+	m = selectSnaps()
+	c.Check(m.BootIssues, HasLen, 0)
+
+	// the bootloader fell back from the try kernel
+	r = bl.SetEnabledTryKernel(kernel2)
+	defer r()
+	m = selectSnaps()
+	c.Check(m.BootIssues, DeepEquals, boot.BootIssues{
+		`try kernel "pc-kernel_2.snap" failed to boot, booted kernel "pc-kernel_1.snap"`,
+	})
+	r()
+
+	// the try kernel is missing while the kernel is to be tried
+	c.Assert(bl.SetBootVars(map[string]string{"kernel_status": boot.TryStatus}), IsNil)
+	m = selectSnaps()
+	c.Check(m.BootIssues, DeepEquals, boot.BootIssues{
+		`try kernel missing while "kernel_status" is "try", booted kernel "pc-kernel_1.snap"`,
+	})
+	c.Assert(bl.SetBootVars(map[string]string{"kernel_status": boot.DefaultStatus}), IsNil)
+
+	// the modeenv got broken and was recovered from its backup
+	c.Assert(ioutil.WriteFile(dirs.SnapModeenvFileUnder(boot.InitramfsWritableDir), []byte("garbage"), 0644), IsNil)
+	m = selectSnaps()
+	c.Assert(m.BootIssues, HasLen, 1)
+	c.Check(m.BootIssues[0], Matches, "modeenv was broken and recovered from backup: .*")
+}
+
+func (s *initramfsSuite) TestInitramfsRunModeSelectSnapsToMountKernelBootAttempts(c *C) {
+	kernel1, err := snap.ParsePlaceInfoFromSnapFileName("pc-kernel_1.snap")
+	c.Assert(err, IsNil)
+	kernel2, err := snap.ParsePlaceInfoFromSnapFileName("pc-kernel_2.snap")
+	c.Assert(err, IsNil)
+
+	r := makeSnapFilesOnInitramfsUbuntuData(c, Commentf("kernel snaps"), kernel1, kernel2)
+	defer r()
+
+	// we use a panic to simulate a reboot
+	r = boot.MockInitramfsReboot(func() error {
+		panic("reboot")
+	})
+	defer r()
+
+	tt := []struct {
+		maxAttempts      int
+		blvars           map[string]string
+		expected         snap.PlaceInfo
+		expRebootPanic   bool
+		expBlvars        map[string]string
+		expFailedKernels []string
+		comment          string
+	}{
+		{
+			maxAttempts: 3,
+			blvars:      map[string]string{"kernel_status": boot.TryingStatus},
+			expected:    kernel2,
+			expBlvars:   map[string]string{"kernel_status": boot.TryingStatus, "kernel_boot_attempts": "1"},
+			comment:     "first attempt",
+		},
+		{
+			maxAttempts: 3,
+			blvars:      map[string]string{"kernel_status": boot.TryingStatus, "kernel_boot_attempts": "2"},
+			expected:    kernel2,
+			expBlvars:   map[string]string{"kernel_status": boot.TryingStatus, "kernel_boot_attempts": "3"},
+			comment:     "last attempt",
+		},
+		{
+			maxAttempts:    3,
+			blvars:         map[string]string{"kernel_status": boot.DefaultStatus, "kernel_boot_attempts": "1"},
+			expRebootPanic: true,
+			expBlvars:      map[string]string{"kernel_status": boot.TryStatus, "kernel_boot_attempts": "1"},
+			comment:        "failed attempt is retried",
+		},
+		{
+			maxAttempts:      3,
+			blvars:           map[string]string{"kernel_status": boot.DefaultStatus, "kernel_boot_attempts": "3"},
+			expected:         kernel1,
+			expBlvars:        map[string]string{"kernel_status": boot.DefaultStatus, "kernel_boot_attempts": ""},
+			expFailedKernels: []string{kernel2.Filename()},
+			comment:          "try kernel is given up on",
+		},
+		{
+			maxAttempts: 3,
+			blvars:      map[string]string{"kernel_status": boot.DefaultStatus, "kernel_boot_attempts": "many"},
+			expected:    kernel1,
+			expBlvars:   map[string]string{"kernel_status": boot.DefaultStatus, "kernel_boot_attempts": "many"},
+			comment:     "broken counter is ignored",
+		},
+		{
+			blvars:    map[string]string{"kernel_status": boot.TryingStatus},
+			expected:  kernel2,
+			expBlvars: map[string]string{"kernel_status": boot.TryingStatus, "kernel_boot_attempts": ""},
+			comment:   "attempts are not counted by default",
+		},
+	}
+
+	type kernelBootloader interface {
+		bootloader.Bootloader
+		SetEnabledKernel(s snap.PlaceInfo) (restore func())
+		SetEnabledTryKernel(s snap.PlaceInfo) (restore func())
+	}
+	for _, mockBl := range []func(*bootloadertest.MockBootloader) kernelBootloader{
+		func(bl *bootloadertest.MockBootloader) kernelBootloader {
+			return boottest.MockUC20RunBootenv(bl)
+		},
+		func(bl *bootloadertest.MockBootloader) kernelBootloader {
+			return boottest.MockUC20EnvRefExtractedKernelRunBootenv(bl)
+		},
+	} {
+		for _, t := range tt {
+			comment := Commentf(t.comment)
+
+			bl := mockBl(bootloadertest.Mock("mock", c.MkDir()))
+			bootloader.Force(bl)
+			defer bootloader.Force(nil)
+			bl.SetEnabledKernel(kernel1)
+			bl.SetEnabledTryKernel(kernel2)
+			c.Assert(bl.SetBootVars(t.blvars), IsNil, comment)
+
+			m := &boot.Modeenv{
+				Mode:                  "run",
+				CurrentKernels:        []string{kernel1.Filename(), kernel2.Filename()},
+				MaxKernelBootAttempts: t.maxAttempts,
+			}
+			c.Assert(m.WriteTo(boot.InitramfsWritableDir), IsNil, comment)
+			m, err := boot.ReadModeenv(boot.InitramfsWritableDir)
+			c.Assert(err, IsNil, comment)
+
+			typs := []snap.Type{snap.TypeKernel}
+			if t.expRebootPanic {
+				f := func() { boot.InitramfsRunModeSelectSnapsToMount(typs, m) }
+				c.Assert(f, PanicMatches, "reboot", comment)
+			} else {
+				mountSnaps, err := boot.InitramfsRunModeSelectSnapsToMount(typs, m)
+				c.Assert(err, IsNil, comment)
+				c.Check(mountSnaps, DeepEquals, map[snap.Type]snap.PlaceInfo{snap.TypeKernel: t.expected}, comment)
+			}
+
+			vars, err := bl.GetBootVars("kernel_status", "kernel_boot_attempts")
+			c.Assert(err, IsNil, comment)
+			c.Check(vars, DeepEquals, t.expBlvars, comment)
+
+			newM, err := boot.ReadModeenv(boot.InitramfsWritableDir)
+			c.Assert(err, IsNil, comment)
+			c.Check(newM.FailedKernels, DeepEquals, t.expFailedKernels, comment)
+		}
+	}
+}
@@ -37,24 +37,76 @@ var _ BootParticipant = (*coreBootParticipant)(nil)
 func (*coreBootParticipant) IsTrivial() bool { return false }
 
 func (bp *coreBootParticipant) SetNextBoot() (rebootRequired bool, err error) {
+	changes, err := SetNextBootWithOptions(bp, nil)
+	if err != nil {
+		return false, err
+	}
+	return changes.RebootRequired, nil
+}
+
+// SetNextOptions carries options for SetNextBootWithOptions.
+type SetNextOptions struct {
+	// DryRun when set computes the changes needed to set up the next
+	// boot without performing them.
+	DryRun bool
+}
+
+// BootChanges describes the changes to the boot state made, or planned in
+// case of a dry run, when setting up the next boot or marking the boot
+// successful.
+type BootChanges struct {
+	// RebootRequired is set if a reboot is needed for the changes to
+	// take effect.
+	RebootRequired bool
+	// Changes describes the planned changes to the modeenv, the
+	// bootloader environment and the kernel assets in the order in
+	// which they would be performed. It is set only for dry runs.
+	Changes []string
+}
+
+// SetNextBootWithOptions is like the SetNextBoot method of the boot
+// participant but with options, in particular it can just report the planned
+// changes without performing them. Trivial participants need no changes.
+func SetNextBootWithOptions(bp BootParticipant, opts *SetNextOptions) (*BootChanges, error) {
 	const errPrefix = "cannot set next boot: %s"
 
-	rebootRequired, u, err := bp.bs.setNext(bp.s)
+	if opts == nil {
+		opts = &SetNextOptions{}
+	}
+	if bp.IsTrivial() {
+		return &BootChanges{}, nil
+	}
+	cbp, ok := bp.(*coreBootParticipant)
+	if !ok {
+		return nil, fmt.Errorf("internal error: unexpected boot participant %T", bp)
+	}
+
+	rebootRequired, u, err := cbp.bs.setNext(cbp.s)
 	if err != nil {
-		return false, fmt.Errorf(errPrefix, err)
+		return nil, fmt.Errorf(errPrefix, err)
 	}
 
-	if u != nil {
-		if err := u.commit(); err != nil {
-			return false, fmt.Errorf(errPrefix, err)
+	changes := &BootChanges{RebootRequired: rebootRequired}
+	if u == nil {
+		return changes, nil
+	}
+	if opts.DryRun {
+		changes.Changes, err = u.describe()
+		if err != nil {
+			return nil, fmt.Errorf(errPrefix, err)
 		}
+		return changes, nil
 	}
-	return rebootRequired, nil
+	if err := u.commit(); err != nil {
+		return nil, fmt.Errorf(errPrefix, err)
+	}
+	return changes, nil
 }
 
 type coreKernel struct {
 	s     snap.PlaceInfo
 	bopts *bootloader.Options
+	dev   Device
 }
 
 // ensure coreKernel is a Kernel
@@ -69,6 +121,14 @@ func (k *coreKernel) RemoveKernelAssets() error {
 		return fmt.Errorf("cannot remove kernel assets: %s", err)
 	}
 
+	// the kernel may have been retained as trusted in the modeenv, stop
+	// trusting it before it is gone
+	if k.dev.HasModeenv() {
+		if err := untrustRemovedKernel(k.s, k.dev); err != nil {
+			return fmt.Errorf("cannot remove kernel assets: %v", err)
+		}
+	}
+
 	// ask bootloader to remove the kernel assets if needed
 	return bootloader.RemoveKernelAssets(k.s)
 }
@@ -113,6 +113,43 @@ func (s *bootenvSuite) TestSetNextBootWithBaseForCore(c *C) {
 	c.Check(reboot, Equals, true)
 }
 
+func (s *bootenvSuite) TestSetNextBootWithOptionsDryRun(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+	s.bootloader.BootVars["snap_core"] = "core_99.snap"
+
+	kernel := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+
+	changes, err := boot.SetNextBootWithOptions(boot.NewCoreBootParticipant(kernel, kernel.Type(), coreDev),
+		&boot.SetNextOptions{DryRun: true})
+	c.Assert(err, IsNil)
+	c.Check(changes, DeepEquals, &boot.BootChanges{
+		RebootRequired: true,
+		Changes: []string{
+			`set boot variable snap_mode="try"`,
+			`set boot variable snap_try_kernel="krnl_42.snap"`,
+		},
+	})
+
+	// nothing was written
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 0)
+	v, err := s.bootloader.GetBootVars("snap_try_kernel", "snap_mode")
+	c.Assert(err, IsNil)
+	c.Check(v, DeepEquals, map[string]string{
+		"snap_try_kernel": "",
+		"snap_mode":       "",
+	})
+}
+
+func (s *bootenvSuite) TestSetNextBootWithOptionsError(c *C) {
+	coreDev := boottest.MockDevice("some-snap")
+
+	s.bootloader.GetErr = errors.New("zap")
+	_, err := boot.SetNextBootWithOptions(boot.NewCoreBootParticipant(&snap.Info{}, snap.TypeKernel, coreDev), nil)
+	c.Check(err, ErrorMatches, `cannot set next boot: zap`)
+}
+
 func (s *bootenvSuite) TestSetNextBootForKernel(c *C) {
 	coreDev := boottest.MockDevice("krnl")
 
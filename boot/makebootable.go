@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapfile"
@@ -191,6 +192,36 @@ func makeBootable20(model *asserts.Model, rootdir string, bootWith *BootableSet)
 		return fmt.Errorf("cannot set recovery environment: %v", err)
 	}
 
+	return makeRecoverySystemBootable(bl, rootdir, bootWith.RecoverySystemDir, &RecoverySystemBootableSet{
+		Kernel:     bootWith.Kernel,
+		KernelPath: bootWith.KernelPath,
+	})
+}
+
+// RecoverySystemBootableSet is a set of snaps relevant to booting a recovery
+// system.
+type RecoverySystemBootableSet struct {
+	Kernel     *snap.Info
+	KernelPath string
+}
+
+// MakeRecoverySystemBootable prepares a recovery system under a path relative
+// to recovery bootloader's rootdir for booting. It is meant to be called at
+// runtime, after the snaps and assertions of the system have been written to
+// the seed.
+func MakeRecoverySystemBootable(rootdir string, relativeRecoverySystemDir string, bootWith *RecoverySystemBootableSet) error {
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(rootdir, opts)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot find bootloader: %v", err)
+	}
+	return makeRecoverySystemBootable(bl, rootdir, relativeRecoverySystemDir, bootWith)
+}
+
+func makeRecoverySystemBootable(bl bootloader.Bootloader, rootdir string, relativeRecoverySystemDir string, bootWith *RecoverySystemBootableSet) error {
 	// on e.g. ARM we need to extract the kernel assets on the recovery
 	// system as well, but the bootloader does not load any environment from
 	// the recovery system
@@ -202,7 +233,7 @@ func makeBootable20(model *asserts.Model, rootdir string, bootWith *BootableSet)
 		}
 
 		err = erkbl.ExtractRecoveryKernelAssets(
-			bootWith.RecoverySystemDir,
+			relativeRecoverySystemDir,
 			bootWith.Kernel,
 			kernelf,
 		)
@@ -224,7 +255,7 @@ func makeBootable20(model *asserts.Model, rootdir string, bootWith *BootableSet)
 	recoveryBlVars := map[string]string{
 		"snapd_recovery_kernel": filepath.Join("/", kernelPath),
 	}
-	if err := rbl.SetRecoverySystemEnv(bootWith.RecoverySystemDir, recoveryBlVars); err != nil {
+	if err := rbl.SetRecoverySystemEnv(relativeRecoverySystemDir, recoveryBlVars); err != nil {
 		return fmt.Errorf("cannot set recovery system environment: %v", err)
 	}
 	return nil
@@ -253,7 +284,12 @@ func MakeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 	if err := os.MkdirAll(snapBlobDir, 0755); err != nil {
 		return err
 	}
-	for _, fn := range []string{bootWith.BasePath, bootWith.KernelPath} {
+	// classic systems with modes do not boot a base snap
+	snapsToCopy := []string{bootWith.KernelPath}
+	if !model.Classic() {
+		snapsToCopy = append([]string{bootWith.BasePath}, snapsToCopy...)
+	}
+	for _, fn := range snapsToCopy {
 		dst := filepath.Join(snapBlobDir, filepath.Base(fn))
 		// if the source filename is a symlink, don't copy the symlink, copy the
 		// target file instead of copying the symlink, as the initramfs won't
@@ -298,12 +334,15 @@ func MakeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 		// installed
 		CurrentKernelCommandLines: nil,
 		// keep this comment to make gofmt 1.9 happy
-		Base:           filepath.Base(bootWith.BasePath),
+		Classic:        model.Classic(),
 		CurrentKernels: []string{bootWith.Kernel.Filename()},
 		BrandID:        model.BrandID(),
 		Model:          model.Model(),
 		Grade:          string(model.Grade()),
 	}
+	if !model.Classic() {
+		modeenv.Base = filepath.Base(bootWith.BasePath)
+	}
 
 	// get the ubuntu-boot bootloader and extract the kernel there
 	opts := &bootloader.Options{
@@ -337,6 +376,18 @@ func MakeRunnableSystem(model *asserts.Model, bootWith *BootableSet, sealer *Tru
 		"kernel_status": "",
 	}
 
+	if _, ok := bl.(bootloader.TrustedAssetsBootloader); ok {
+		// the bootloader composes the kernel command line, which may
+		// include extra arguments from the gadget
+		extraArgs, err := gadget.KernelCommandLineFromGadget(bootWith.UnpackedGadgetDir)
+		if err != nil {
+			return fmt.Errorf("cannot use the kernel command line from the gadget: %v", err)
+		}
+		if extraArgs != "" {
+			blVars[extraCmdlineArgsVar] = extraArgs
+		}
+	}
+
 	ebl, ok := bl.(bootloader.ExtractedRunKernelImageBootloader)
 	if ok {
 		// the bootloader supports additional extracted kernel handling
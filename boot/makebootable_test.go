@@ -247,6 +247,42 @@ version: 5.0
 	c.Check(systemGenv.Get("snapd_recovery_kernel"), Equals, "/snaps/pc-kernel_5.snap")
 }
 
+func (s *makeBootable20Suite) TestMakeRecoverySystemBootable(c *C) {
+	bootloader.Force(nil)
+
+	// the recovery bootloader is already set up on ubuntu-seed
+	err := os.MkdirAll(filepath.Join(s.rootdir, "EFI/ubuntu"), 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(s.rootdir, "EFI/ubuntu/grub.cfg"), []byte("#grub-recovery cfg"), 0644)
+	c.Assert(err, IsNil)
+
+	seedSnapsDirs := filepath.Join(s.rootdir, "/snaps")
+	err = os.MkdirAll(seedSnapsDirs, 0755)
+	c.Assert(err, IsNil)
+	kernelFn, kernelInfo := makeSnapWithFiles(c, "pc-kernel", `name: pc-kernel
+type: kernel
+version: 5.0
+`, snap.R(5), [][]string{
+		{"kernel.efi", "I'm a kernel.efi"},
+	})
+	kernelInSeed := filepath.Join(seedSnapsDirs, kernelInfo.Filename())
+	err = os.Rename(kernelFn, kernelInSeed)
+	c.Assert(err, IsNil)
+
+	err = boot.MakeRecoverySystemBootable(s.rootdir, "systems/20201212", &boot.RecoverySystemBootableSet{
+		Kernel:     kernelInfo,
+		KernelPath: kernelInSeed,
+	})
+	c.Assert(err, IsNil)
+
+	// the main recovery bootloader environment was not touched
+	c.Check(filepath.Join(s.rootdir, "EFI/ubuntu/grubenv"), testutil.FileAbsent)
+
+	systemGenv := grubenv.NewEnv(filepath.Join(s.rootdir, "systems/20201212", "grubenv"))
+	c.Assert(systemGenv.Load(), IsNil)
+	c.Check(systemGenv.Get("snapd_recovery_kernel"), Equals, "/snaps/pc-kernel_5.snap")
+}
+
 func (s *makeBootable20Suite) TestMakeBootableImage20UnsetRecoverySystemLabelError(c *C) {
 	model := boottest.MakeMockUC20Model()
 
@@ -567,6 +603,72 @@ current_kernel_command_lines=["snapd_recovery_mode=run console=ttyS0 console=tty
 	c.Check(filepath.Join(dirs.SnapFDEDirUnder(boot.InstallHostWritableDir), "boot-chains"), testutil.FilePresent)
 }
 
+func (s *makeBootable20Suite) TestMakeRunnableSystem20Classic(c *C) {
+	bootloader.Force(nil)
+
+	model := boottest.MakeMockUC20Model(map[string]interface{}{
+		"classic":      "true",
+		"distribution": "ubuntu",
+	})
+	c.Assert(model.Classic(), Equals, true)
+	seedSnapsDirs := filepath.Join(s.rootdir, "/snaps")
+	err := os.MkdirAll(seedSnapsDirs, 0755)
+	c.Assert(err, IsNil)
+
+	// grub on ubuntu-seed
+	mockSeedGrubDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "EFI", "ubuntu")
+	err = os.MkdirAll(mockSeedGrubDir, 0755)
+	c.Assert(err, IsNil)
+
+	// grub on ubuntu-boot
+	mockBootGrubDir := filepath.Join(boot.InitramfsUbuntuBootDir, "EFI", "ubuntu")
+	err = os.MkdirAll(mockBootGrubDir, 0755)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(filepath.Join(mockBootGrubDir, "grub.cfg"), nil, 0644)
+	c.Assert(err, IsNil)
+
+	unpackedGadgetDir := c.MkDir()
+	err = ioutil.WriteFile(filepath.Join(unpackedGadgetDir, "grub.conf"), nil, 0644)
+	c.Assert(err, IsNil)
+	restore := assets.MockInternal("grub.cfg", []byte("# Snapd-Boot-Config-Edition: 1\n#grub cfg from assets"))
+	defer restore()
+
+	kernelFn, kernelInfo := makeSnapWithFiles(c, "pc-kernel", `name: pc-kernel
+type: kernel
+version: 5.0
+`, snap.R(5),
+		[][]string{
+			{"kernel.efi", "I'm a kernel.efi"},
+		},
+	)
+	kernelInSeed := filepath.Join(seedSnapsDirs, kernelInfo.Filename())
+	err = os.Symlink(kernelFn, kernelInSeed)
+	c.Assert(err, IsNil)
+
+	// there is no base snap to boot
+	bootWith := &boot.BootableSet{
+		RecoverySystemDir: "20191216",
+		KernelPath:        kernelInSeed,
+		Kernel:            kernelInfo,
+		UnpackedGadgetDir: unpackedGadgetDir,
+	}
+
+	err = boot.MakeRunnableSystem(model, bootWith, nil)
+	c.Assert(err, IsNil)
+
+	// only the kernel got copied to /var/lib/snapd/snaps
+	blobs, err := filepath.Glob(filepath.Join(dirs.SnapBlobDirUnder(boot.InstallHostWritableDir), "*"))
+	c.Assert(err, IsNil)
+	c.Check(blobs, DeepEquals, []string{filepath.Join(dirs.SnapBlobDirUnder(boot.InstallHostWritableDir), "pc-kernel_5.snap")})
+
+	// the modeenv tracks no base
+	m, err := boot.ReadModeenv(boot.InstallHostWritableDir)
+	c.Assert(err, IsNil)
+	c.Check(m.Classic, Equals, true)
+	c.Check(m.Base, Equals, "")
+	c.Check(m.CurrentKernels, DeepEquals, []string{"pc-kernel_5.snap"})
+}
+
 func (s *makeBootable20Suite) TestMakeRunnableSystem20ModeInstallBootConfigErr(c *C) {
 	bootloader.Force(nil)
 
@@ -21,18 +21,23 @@ package boot
 
 import (
 	"bytes"
+	"crypto"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/mvo5/goconfigparser"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 )
 
@@ -42,6 +47,10 @@ type bootAssetsMap map[string][]string
 // marshalled as JSON as a comma can be present in the module parameters.
 type bootCommandLines []string
 
+// bootIssues is a list of descriptions of boot issues. The descriptions are
+// marshalled as JSON as they are free form text.
+type bootIssues []string
+
 // Modeenv is a file on UC20 that provides additional information
 // about the current mode (run,recover,install)
 type Modeenv struct {
@@ -79,6 +88,29 @@ type Modeenv struct {
 	// update scenarios.
 	CurrentKernelCommandLines bootCommandLines `key:"current_kernel_command_lines"`
 	// TODO:UC20 add a per recovery system list of kernel command lines
+	// Gadget is the file name of the gadget snap the system is running
+	// with, it is unset when the modeenv was written by an older snapd
+	// and the gadget has not been refreshed since.
+	Gadget string `key:"gadget"`
+	// MaxKernelBootAttempts is the number of times a try kernel is booted
+	// before giving up on it and reverting to the current kernel. When
+	// unset the try kernel is booted only once.
+	MaxKernelBootAttempts int `key:"max_kernel_boot_attempts"`
+	// FailedKernels is a list of the try kernels that were given up on
+	// after failing to boot successfully MaxKernelBootAttempts times.
+	FailedKernels []string `key:"failed_kernels"`
+	// KernelRetention is the number of kernels kept in CurrentKernels
+	// after a kernel booted successfully, including that kernel. When
+	// unset only the kernel that booted successfully is kept.
+	KernelRetention int `key:"kernel_retention"`
+	// Classic is set on classic systems with modes, which use a modeenv
+	// and extracted kernels but whose root filesystem is not a base snap,
+	// the base is not tracked on those.
+	Classic bool `key:"classic"`
+	// BootIssues lists the issues the initramfs worked around during the
+	// last boot, such as falling back from a try snap, which leave the
+	// system in a degraded state.
+	BootIssues bootIssues `key:"boot_issues"`
 
 	// read is set to true when a modenv was read successfully
 	read bool
@@ -87,6 +119,11 @@ type Modeenv struct {
 	// read from, and where it will be written back to
 	originRootdir string
 
+	// issues collects the boot issues found while processing the modeenv
+	// in the initramfs, they are recorded in BootIssues once the snaps to
+	// mount were selected
+	issues []string
+
 	// extrakeys is all the keys in the modeenv we read from the file but don't
 	// understand, we keep track of this so that if we read a new modeenv with
 	// extra keys and need to rewrite it, we will write those new keys as well
@@ -133,12 +170,91 @@ func modeenvFile(rootdir string) string {
 }
 
 // ReadModeenv attempts to read the modeenv file at
-// <rootdir>/var/iib/snapd/modeenv.
+// <rootdir>/var/iib/snapd/modeenv. The modeenv is validated against the
+// checksum carried by the backup copy written along with it. If the modeenv
+// file is missing, broken or does not match the checksum, the modeenv is
+// recovered from the backup copy, if that one is intact.
 func ReadModeenv(rootdir string) (*Modeenv, error) {
 	modeenvPath := modeenvFile(rootdir)
+	content, err := ioutil.ReadFile(modeenvPath)
+	if err == nil {
+		var m *Modeenv
+		m, err = parseModeenv(string(content), rootdir)
+		if err == nil {
+			checksum, checksumErr := modeenvBackupChecksum(rootdir)
+			if checksumErr != nil || checksum == modeenvChecksum(content) {
+				// without a usable backup there is nothing to
+				// validate against
+				return m, nil
+			}
+			err = fmt.Errorf("checksum mismatch")
+		}
+	}
+
+	backup, backupErr := readModeenvBackup(rootdir)
+	if backupErr != nil {
+		if !os.IsNotExist(backupErr) {
+			logger.Noticef("cannot use modeenv backup: %v", backupErr)
+		}
+		return nil, err
+	}
+	logger.Noticef("WARNING: modeenv %s is broken (%v), recovered from backup", modeenvPath, err)
+	backup.issues = append(backup.issues, fmt.Sprintf("modeenv was broken and recovered from backup: %v", err))
+	return backup, nil
+}
+
+// modeenvBackupFile returns the path of the backup copy of the modeenv, which
+// carries the checksum of the modeenv on its last line.
+func modeenvBackupFile(rootdir string) string {
+	return modeenvFile(rootdir) + ".backup"
+}
+
+const modeenvChecksumPrefix = "# sha3-384 "
+
+func modeenvChecksum(content []byte) string {
+	h := crypto.SHA3_384.New()
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// splitModeenvBackup splits the backup copy of the modeenv into the content
+// of the modeenv and its checksum, after verifying that they match.
+func splitModeenvBackup(rootdir string) (content []byte, checksum string, err error) {
+	backup, err := ioutil.ReadFile(modeenvBackupFile(rootdir))
+	if err != nil {
+		return nil, "", err
+	}
+	idx := bytes.LastIndex(bytes.TrimSuffix(backup, []byte("\n")), []byte("\n"))
+	content, checksumLine := backup[:idx+1], string(backup[idx+1:])
+	if !strings.HasPrefix(checksumLine, modeenvChecksumPrefix) {
+		return nil, "", fmt.Errorf("modeenv backup has no checksum")
+	}
+	checksum = strings.TrimSpace(strings.TrimPrefix(checksumLine, modeenvChecksumPrefix))
+	if checksum != modeenvChecksum(content) {
+		return nil, "", fmt.Errorf("modeenv backup checksum mismatch")
+	}
+	return content, checksum, nil
+}
+
+// modeenvBackupChecksum returns the checksum of the modeenv as recorded in
+// its intact backup copy.
+func modeenvBackupChecksum(rootdir string) (string, error) {
+	_, checksum, err := splitModeenvBackup(rootdir)
+	return checksum, err
+}
+
+func readModeenvBackup(rootdir string) (*Modeenv, error) {
+	content, _, err := splitModeenvBackup(rootdir)
+	if err != nil {
+		return nil, err
+	}
+	return parseModeenv(string(content), rootdir)
+}
+
+func parseModeenv(content, rootdir string) (*Modeenv, error) {
 	cfg := goconfigparser.New()
 	cfg.AllowNoSectionHeader = true
-	if err := cfg.ReadFile(modeenvPath); err != nil {
+	if err := cfg.ReadString(content); err != nil {
 		return nil, err
 	}
 
@@ -170,6 +286,12 @@ func ReadModeenv(rootdir string) (*Modeenv, error) {
 	unmarshalModeenvValueFromCfg(cfg, "current_trusted_boot_assets", &m.CurrentTrustedBootAssets)
 	unmarshalModeenvValueFromCfg(cfg, "current_trusted_recovery_boot_assets", &m.CurrentTrustedRecoveryBootAssets)
 	unmarshalModeenvValueFromCfg(cfg, "current_kernel_command_lines", &m.CurrentKernelCommandLines)
+	unmarshalModeenvValueFromCfg(cfg, "gadget", &m.Gadget)
+	unmarshalModeenvValueFromCfg(cfg, "max_kernel_boot_attempts", &m.MaxKernelBootAttempts)
+	unmarshalModeenvValueFromCfg(cfg, "failed_kernels", &m.FailedKernels)
+	unmarshalModeenvValueFromCfg(cfg, "kernel_retention", &m.KernelRetention)
+	unmarshalModeenvValueFromCfg(cfg, "classic", &m.Classic)
+	unmarshalModeenvValueFromCfg(cfg, "boot_issues", &m.BootIssues)
 
 	// save all the rest of the keys we don't understand
 	keys, err := cfg.Options("")
@@ -223,6 +345,14 @@ func (m *Modeenv) Copy() (*Modeenv, error) {
 	// manually copy the unexported fields as they won't be in the JSON
 	m2.read = m.read
 	m2.originRootdir = m.originRootdir
+	// keep the keys we don't understand, so that they are not dropped
+	// when the copy is written, which is how the modeenv is modified
+	if m.extrakeys != nil {
+		m2.extrakeys = make(map[string]string, len(m.extrakeys))
+		for k, v := range m.extrakeys {
+			m2.extrakeys[k] = v
+		}
+	}
 	return m2, nil
 }
 
@@ -242,9 +372,29 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	if err := os.MkdirAll(filepath.Dir(modeenvPath), 0755); err != nil {
 		return err
 	}
+	buf, err := m.marshal()
+	if err != nil {
+		return err
+	}
+
+	// write the backup first, so that whenever the modeenv is written there
+	// is an intact copy of it to recover from
+	backup := make([]byte, 0, buf.Len()+len(modeenvChecksumPrefix)+100)
+	backup = append(backup, buf.Bytes()...)
+	backup = append(backup, modeenvChecksumPrefix+modeenvChecksum(buf.Bytes())+"\n"...)
+	if err := osutil.AtomicWriteFile(modeenvBackupFile(rootdir), backup, 0644, 0); err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(modeenvPath, buf.Bytes(), 0644, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *Modeenv) marshal() (*bytes.Buffer, error) {
 	buf := bytes.NewBuffer(nil)
 	if m.Mode == "" {
-		return fmt.Errorf("internal error: mode is unset")
+		return nil, fmt.Errorf("internal error: mode is unset")
 	}
 	marshalModeenvEntryTo(buf, "mode", m.Mode)
 	marshalModeenvEntryTo(buf, "recovery_system", m.RecoverySystem)
@@ -256,10 +406,10 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	marshalModeenvEntryTo(buf, "current_kernels", strings.Join(m.CurrentKernels, ","))
 	if m.Model != "" || m.Grade != "" {
 		if m.Model == "" {
-			return fmt.Errorf("internal error: model is unset")
+			return nil, fmt.Errorf("internal error: model is unset")
 		}
 		if m.BrandID == "" {
-			return fmt.Errorf("internal error: brand is unset")
+			return nil, fmt.Errorf("internal error: brand is unset")
 		}
 		marshalModeenvEntryTo(buf, "model", &modeenvModel{brandID: m.BrandID, model: m.Model})
 	}
@@ -267,6 +417,12 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	marshalModeenvEntryTo(buf, "current_trusted_boot_assets", m.CurrentTrustedBootAssets)
 	marshalModeenvEntryTo(buf, "current_trusted_recovery_boot_assets", m.CurrentTrustedRecoveryBootAssets)
 	marshalModeenvEntryTo(buf, "current_kernel_command_lines", m.CurrentKernelCommandLines)
+	marshalModeenvEntryTo(buf, "gadget", m.Gadget)
+	marshalModeenvEntryTo(buf, "max_kernel_boot_attempts", m.MaxKernelBootAttempts)
+	marshalModeenvEntryTo(buf, "failed_kernels", m.FailedKernels)
+	marshalModeenvEntryTo(buf, "kernel_retention", m.KernelRetention)
+	marshalModeenvEntryTo(buf, "classic", m.Classic)
+	marshalModeenvEntryTo(buf, "boot_issues", m.BootIssues)
 
 	// write all the extra keys at the end
 	// sort them for test convenience
@@ -278,11 +434,51 @@ func (m *Modeenv) WriteTo(rootdir string) error {
 	for _, k := range extraKeys {
 		marshalModeenvEntryTo(buf, k, m.extrakeys[k])
 	}
+	return buf, nil
+}
 
-	if err := osutil.AtomicWriteFile(modeenvPath, buf.Bytes(), 0644, 0); err != nil {
-		return err
+// changesTo returns descriptions of the changes of the modeenv entries
+// needed to go from m to other.
+func (m *Modeenv) changesTo(other *Modeenv) ([]string, error) {
+	if m.deepEqual(other) {
+		return nil, nil
 	}
-	return nil
+	entries := func(m *Modeenv) (keys []string, values map[string]string, err error) {
+		buf, err := m.marshal()
+		if err != nil {
+			return nil, nil, err
+		}
+		values = make(map[string]string)
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			keys = append(keys, kv[0])
+			values[kv[0]] = kv[1]
+		}
+		return keys, values, nil
+	}
+	oldKeys, oldValues, err := entries(m)
+	if err != nil {
+		return nil, err
+	}
+	newKeys, newValues, err := entries(other)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	for _, k := range newKeys {
+		if oldValue, ok := oldValues[k]; !ok || oldValue != newValues[k] {
+			changes = append(changes, fmt.Sprintf("set modeenv %s=%s", k, newValues[k]))
+		}
+	}
+	for _, k := range oldKeys {
+		if _, ok := newValues[k]; !ok {
+			changes = append(changes, fmt.Sprintf("unset modeenv %s", k))
+		}
+	}
+	return changes, nil
 }
 
 type modeenvValueMarshaller interface {
@@ -308,6 +504,16 @@ func marshalModeenvEntryTo(out io.Writer, key string, what interface{}) error {
 			return nil
 		}
 		asString = asModeenvStringList(v)
+	case int:
+		if v == 0 {
+			return nil
+		}
+		asString = strconv.Itoa(v)
+	case bool:
+		if !v {
+			return nil
+		}
+		asString = strconv.FormatBool(v)
 	default:
 		if vm, ok := what.(modeenvValueMarshaller); ok {
 			marshalled, err := vm.MarshalModeenvValue()
@@ -347,6 +553,26 @@ func unmarshalModeenvValueFromCfg(cfg *goconfigparser.ConfigParser, key string,
 		*v = kv
 	case *[]string:
 		*v = splitModeenvStringList(kv)
+	case *int:
+		if kv == "" {
+			*v = 0
+			return nil
+		}
+		n, err := strconv.Atoi(kv)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal modeenv value %q to int: %v", kv, err)
+		}
+		*v = n
+	case *bool:
+		if kv == "" {
+			*v = false
+			return nil
+		}
+		b, err := strconv.ParseBool(kv)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal modeenv value %q to bool: %v", kv, err)
+		}
+		*v = b
 	default:
 		if vm, ok := v.(modeenvValueUnmarshaller); ok {
 			if err := vm.UnmarshalModeenvValue(kv); err != nil {
@@ -434,3 +660,16 @@ func (s *bootCommandLines) UnmarshalJSON(data []byte) error {
 	*s = bootCommandLines(asList)
 	return nil
 }
+
+func (s bootIssues) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string(s))
+}
+
+func (s *bootIssues) UnmarshalJSON(data []byte) error {
+	var asList []string
+	if err := json.Unmarshal(data, &asList); err != nil {
+		return err
+	}
+	*s = bootIssues(asList)
+	return nil
+}
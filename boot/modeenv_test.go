@@ -33,6 +33,7 @@ import (
 
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -69,6 +70,12 @@ func (s *modeenvSuite) TestKnownKnown(c *C) {
 		"current_kernel_command_lines":         true,
 		"current_trusted_boot_assets":          true,
 		"current_trusted_recovery_boot_assets": true,
+		"gadget":                               true,
+		"max_kernel_boot_attempts":             true,
+		"failed_kernels":                       true,
+		"kernel_retention":                     true,
+		"classic":                              true,
+		"boot_issues":                          true,
 	})
 }
 
@@ -362,6 +369,34 @@ unknown_key=some unknown value
 `)
 }
 
+func (s *modeenvSuite) TestReadModeenvWithUnknownKeysCopyKeepsWrites(c *C) {
+	s.makeMockModeenvFile(c, `mode=run
+base=core20_123.snap
+unknown_key=some unknown value
+`)
+
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	// modeenvs are typically copied before being modified and written
+	modeenv2, err := modeenv.Copy()
+	c.Assert(err, IsNil)
+	modeenv2.Base = "core20_124.snap"
+	c.Assert(modeenv2.Write(), IsNil)
+
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+base=core20_124.snap
+unknown_key=some unknown value
+`)
+
+	// the copy does not share the keys with the original
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+base=core20_123.snap
+unknown_key=some unknown value
+`)
+}
+
 func (s *modeenvSuite) TestReadModeenvWithUnknownKeysDeepEqualsSameWithoutUnknownKeys(c *C) {
 	s.makeMockModeenvFile(c, `first_unknown=thing
 mode=recovery
@@ -412,6 +447,58 @@ base_status=try
 	c.Check(modeenv.BaseStatus, Equals, boot.TryStatus)
 }
 
+func (s *modeenvSuite) TestReadModeWithGadget(c *C) {
+	s.makeMockModeenvFile(c, `mode=run
+recovery_system=20191126
+base=core20_123.snap
+gadget=pc_12.snap
+`)
+
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.Mode, Equals, "run")
+	c.Check(modeenv.Base, Equals, "core20_123.snap")
+	c.Check(modeenv.Gadget, Equals, "pc_12.snap")
+}
+
+func (s *modeenvSuite) TestReadModeWithKernelBootAttempts(c *C) {
+	s.makeMockModeenvFile(c, `mode=run
+max_kernel_boot_attempts=3
+failed_kernels=pc-kernel_2.snap,pc-kernel_3.snap
+kernel_retention=2
+`)
+
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.MaxKernelBootAttempts, Equals, 3)
+	c.Check(modeenv.FailedKernels, DeepEquals, []string{"pc-kernel_2.snap", "pc-kernel_3.snap"})
+	c.Check(modeenv.KernelRetention, Equals, 2)
+
+	// an invalid number of attempts is ignored
+	s.makeMockModeenvFile(c, `mode=run
+max_kernel_boot_attempts=many
+`)
+
+	modeenv, err = boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.MaxKernelBootAttempts, Equals, 0)
+}
+
+func (s *modeenvSuite) TestWriteKernelBootAttempts(c *C) {
+	modeenv := &boot.Modeenv{
+		Mode:                  "run",
+		MaxKernelBootAttempts: 3,
+		FailedKernels:         []string{"pc-kernel_2.snap"},
+	}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+max_kernel_boot_attempts=3
+failed_kernels=pc-kernel_2.snap
+`)
+}
+
 func (s *modeenvSuite) TestReadModeWithGrade(c *C) {
 	s.makeMockModeenvFile(c, `mode=run
 grade=dangerous
@@ -549,6 +636,133 @@ func (s *modeenvSuite) TestWriteFreshError(c *C) {
 	c.Assert(err, ErrorMatches, `internal error: must use WriteTo with modeenv not read from disk`)
 }
 
+func (s *modeenvSuite) TestWriteWritesBackup(c *C) {
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		Base: "core20_321.snap",
+	}
+	err := modeenv.WriteTo(s.tmpdir)
+	c.Assert(err, IsNil)
+
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+base=core20_321.snap
+`)
+	c.Assert(s.mockModeenvPath+".backup", testutil.FileMatches, `(?s)mode=run
+base=core20_321.snap
+# sha3-384 [0-9a-f]{96}
+`)
+}
+
+func (s *modeenvSuite) TestReadRecoversFromBackup(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	modeenv := &boot.Modeenv{
+		Mode:           "run",
+		RecoverySystem: "20191126",
+		Base:           "core20_321.snap",
+	}
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+
+	for _, broken := range []string{
+		// truncated
+		"",
+		"mode",
+		// garbled
+		"\x00\x00\x00\x00",
+		// valid but not matching the checksum
+		"mode=recover\n",
+	} {
+		c.Assert(ioutil.WriteFile(s.mockModeenvPath, []byte(broken), 0644), IsNil)
+
+		m, err := boot.ReadModeenv(s.tmpdir)
+		c.Assert(err, IsNil, Commentf("%q", broken))
+		c.Check(m.Mode, Equals, "run")
+		c.Check(m.RecoverySystem, Equals, "20191126")
+		c.Check(m.Base, Equals, "core20_321.snap")
+		c.Check(logbuf.String(), Matches, `(?s).*WARNING: modeenv .*/modeenv is broken \(.*\), recovered from backup\n`)
+		logbuf.Reset()
+
+		// writing it back fixes the modeenv
+		c.Assert(m.Write(), IsNil)
+		c.Check(s.mockModeenvPath, testutil.FileEquals, `mode=run
+recovery_system=20191126
+base=core20_321.snap
+`)
+	}
+}
+
+func (s *modeenvSuite) TestReadMissingRecoversFromBackup(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	modeenv := &boot.Modeenv{
+		Mode:           "run",
+		RecoverySystem: "20191126",
+	}
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+	c.Assert(os.Remove(s.mockModeenvPath), IsNil)
+
+	m, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(m.Mode, Equals, "run")
+	c.Check(m.RecoverySystem, Equals, "20191126")
+	c.Check(logbuf.String(), Matches, `(?s).*WARNING: modeenv .*/modeenv is broken \(open .*: no such file or directory\), recovered from backup\n`)
+
+	// without the backup either, the modeenv is missing
+	c.Assert(os.Remove(s.mockModeenvPath+".backup"), IsNil)
+	_, err = boot.ReadModeenv(s.tmpdir)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *modeenvSuite) TestReadMatchesBackupChecksum(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	modeenv := &boot.Modeenv{
+		Mode:           "run",
+		RecoverySystem: "20191126",
+	}
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+
+	m, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(m.Mode, Equals, "run")
+	c.Check(m.RecoverySystem, Equals, "20191126")
+	c.Check(logbuf.String(), Equals, "")
+}
+
+func (s *modeenvSuite) TestReadBrokenBackup(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	modeenv := &boot.Modeenv{Mode: "run"}
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+	c.Assert(ioutil.WriteFile(s.mockModeenvPath, nil, 0644), IsNil)
+
+	for _, t := range []struct {
+		backup string
+		err    string
+	}{
+		{"mode=run\n", "modeenv backup has no checksum"},
+		{"mode=recover\n# sha3-384 1234\n", "modeenv backup checksum mismatch"},
+	} {
+		c.Assert(ioutil.WriteFile(s.mockModeenvPath+".backup", []byte(t.backup), 0644), IsNil)
+
+		// the error with the modeenv itself is reported
+		_, err := boot.ReadModeenv(s.tmpdir)
+		c.Check(err, ErrorMatches, "internal error: mode is unset")
+		c.Check(logbuf.String(), Matches, fmt.Sprintf(`(?s).*cannot use modeenv backup: %s\n`, t.err))
+		logbuf.Reset()
+	}
+
+	// without a backup
+	c.Assert(os.Remove(s.mockModeenvPath+".backup"), IsNil)
+	_, err := boot.ReadModeenv(s.tmpdir)
+	c.Check(err, ErrorMatches, "internal error: mode is unset")
+	c.Check(logbuf.String(), Equals, "")
+}
+
 func (s *modeenvSuite) TestWriteToNonExistingFull(c *C) {
 	c.Assert(s.mockModeenvPath, testutil.FileAbsent)
 
@@ -747,3 +961,46 @@ current_kernel_command_lines=["snapd_recovery_mode=run panic=-1 console=ttyS0,io
 		`snapd_recovery_mode=run candidate panic=-1 console=ttyS0,io,9600n8`,
 	})
 }
+
+func (s *modeenvSuite) TestReadWriteClassic(c *C) {
+	s.makeMockModeenvFile(c, `mode=run
+current_kernels=pc-kernel_1.snap
+classic=true
+`)
+
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.Classic, Equals, true)
+	c.Check(modeenv.Base, Equals, "")
+
+	modeenv.Classic = false
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+current_kernels=pc-kernel_1.snap
+`)
+
+	modeenv.Classic = true
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+current_kernels=pc-kernel_1.snap
+classic=true
+`)
+}
+
+func (s *modeenvSuite) TestReadWriteBootIssues(c *C) {
+	s.makeMockModeenvFile(c, `mode=run
+boot_issues=["try base \"core20_2.snap\" failed to boot, booted base \"core20_1.snap\""]
+`)
+
+	modeenv, err := boot.ReadModeenv(s.tmpdir)
+	c.Assert(err, IsNil)
+	c.Check(modeenv.BootIssues, DeepEquals, boot.BootIssues{
+		`try base "core20_2.snap" failed to boot, booted base "core20_1.snap"`,
+	})
+
+	modeenv.BootIssues = []string{"one, with a comma", "two"}
+	c.Assert(modeenv.WriteTo(s.tmpdir), IsNil)
+	c.Assert(s.mockModeenvPath, testutil.FileEquals, `mode=run
+boot_issues=["one, with a comma","two"]
+`)
+}
@@ -0,0 +1,93 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"sync"
+)
+
+// StateObserver is notified when the boot state of the kernel and base
+// snaps changes, that is when snaps are set up for the next boot, marked as
+// successfully booted or reverted to. The transitions are the same as the
+// ones recorded in the boot history.
+type StateObserver interface {
+	// BeforeCommit is called with the transitions about to be committed,
+	// returning an error aborts the commit.
+	BeforeCommit(transitions []*BootHistoryEntry) error
+	// AfterCommit is called with the transitions once they were committed
+	// successfully.
+	AfterCommit(transitions []*BootHistoryEntry)
+}
+
+var (
+	stateObserversMu sync.Mutex
+	stateObservers   []StateObserver
+)
+
+// AddStateObserver registers an observer of the changes of the boot state,
+// the returned function unregisters it.
+func AddStateObserver(o StateObserver) (remove func()) {
+	stateObserversMu.Lock()
+	defer stateObserversMu.Unlock()
+
+	stateObservers = append(stateObservers, o)
+	return func() {
+		stateObserversMu.Lock()
+		defer stateObserversMu.Unlock()
+
+		for i, other := range stateObservers {
+			if other == o {
+				stateObservers = append(stateObservers[:i:i], stateObservers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func currentStateObservers() []StateObserver {
+	stateObserversMu.Lock()
+	defer stateObserversMu.Unlock()
+	return stateObservers
+}
+
+// beforeCommit notifies the observers of the transitions about to be
+// committed, an error from any of them aborts the commit.
+func (h *bootHistory) beforeCommit() error {
+	if len(h.entries) == 0 {
+		return nil
+	}
+	for _, o := range currentStateObservers() {
+		if err := o.BeforeCommit(h.entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterCommit notifies the observers of the transitions committed
+// successfully.
+func (h *bootHistory) afterCommit() {
+	if len(h.entries) == 0 {
+		return
+	}
+	for _, o := range currentStateObservers() {
+		o.AfterCommit(h.entries)
+	}
+}
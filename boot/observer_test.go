@@ -0,0 +1,193 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"errors"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/snap"
+)
+
+type mockStateObserver struct {
+	beforeCommit func(transitions []*boot.BootHistoryEntry) error
+	afterCommit  func(transitions []*boot.BootHistoryEntry)
+	calls        []string
+}
+
+func (o *mockStateObserver) BeforeCommit(transitions []*boot.BootHistoryEntry) error {
+	o.calls = append(o.calls, "before")
+	if o.beforeCommit != nil {
+		return o.beforeCommit(transitions)
+	}
+	return nil
+}
+
+func (o *mockStateObserver) AfterCommit(transitions []*boot.BootHistoryEntry) {
+	o.calls = append(o.calls, "after")
+	if o.afterCommit != nil {
+		o.afterCommit(transitions)
+	}
+}
+
+func (s *bootenvSuite) TestStateObserverSetNextAndMarkSuccessful(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+
+	var seen []string
+	o := &mockStateObserver{
+		beforeCommit: func(transitions []*boot.BootHistoryEntry) error {
+			c.Assert(transitions, HasLen, 1)
+			seen = append(seen, transitions[0].Action+" "+transitions[0].NewStatus)
+			// nothing was written yet
+			c.Check(s.bootloader.BootVars["snap_try_kernel"], Equals, "")
+			return nil
+		},
+		afterCommit: func(transitions []*boot.BootHistoryEntry) {
+			c.Assert(transitions, HasLen, 1)
+			c.Check(transitions[0].Snap, Equals, "krnl_42.snap")
+		},
+	}
+	s.AddCleanup(boot.AddStateObserver(o))
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+	_, err := boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+	c.Check(s.bootloader.BootVars["snap_try_kernel"], Equals, "krnl_42.snap")
+
+	// the boot script tries the kernel
+	s.bootloader.BootVars["snap_mode"] = boot.TryingStatus
+	o.beforeCommit = func(transitions []*boot.BootHistoryEntry) error {
+		c.Assert(transitions, HasLen, 1)
+		seen = append(seen, transitions[0].Action+" "+transitions[0].NewStatus)
+		c.Check(s.bootloader.BootVars["snap_kernel"], Equals, "krnl_41.snap")
+		return nil
+	}
+	c.Assert(boot.MarkBootSuccessful(coreDev), IsNil)
+	c.Check(s.bootloader.BootVars["snap_kernel"], Equals, "krnl_42.snap")
+
+	// a boot that does not change anything is not observed
+	c.Assert(boot.MarkBootSuccessful(coreDev), IsNil)
+
+	c.Check(seen, DeepEquals, []string{"set-next try", "mark-successful "})
+	c.Check(o.calls, DeepEquals, []string{"before", "after", "before", "after"})
+}
+
+func (s *bootenvSuite) TestStateObserverAbortsCommit(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+
+	o := &mockStateObserver{
+		beforeCommit: func(transitions []*boot.BootHistoryEntry) error {
+			return errors.New("not now")
+		},
+	}
+	s.AddCleanup(boot.AddStateObserver(o))
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+	_, err := boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, ErrorMatches, "cannot set next boot: not now")
+
+	// nothing was written
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 0)
+	c.Check(o.calls, DeepEquals, []string{"before"})
+
+	// and the failed transition is recorded
+	history, err := boot.BootHistory()
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Error, Equals, "not now")
+}
+
+func (s *bootenvSuite) TestStateObserverCommitError(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+	s.bootloader.SetErr = errors.New("zap")
+
+	o := &mockStateObserver{}
+	s.AddCleanup(boot.AddStateObserver(o))
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+	_, err := boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, ErrorMatches, "cannot set next boot: zap")
+
+	// the observer is not told about a commit that failed
+	c.Check(o.calls, DeepEquals, []string{"before"})
+}
+
+func (s *bootenvSuite) TestStateObserverRemove(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+
+	o1 := &mockStateObserver{}
+	o2 := &mockStateObserver{}
+	remove1 := boot.AddStateObserver(o1)
+	s.AddCleanup(boot.AddStateObserver(o2))
+	remove1()
+
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "krnl", Revision: snap.R(42)}, SnapType: snap.TypeKernel}
+	_, err := boot.NewCoreBootParticipant(info, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+
+	c.Check(o1.calls, HasLen, 0)
+	c.Check(o2.calls, DeepEquals, []string{"before", "after"})
+}
+
+func (s *bootenv20Suite) TestStateObserver20(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		s.normalDefaultState,
+	)
+	defer r()
+
+	var transitions []*boot.BootHistoryEntry
+	o := &mockStateObserver{
+		beforeCommit: func(ts []*boot.BootHistoryEntry) error {
+			// the modeenv was not written yet
+			m, err := boot.ReadModeenv("")
+			c.Assert(err, IsNil)
+			c.Check(m.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+			return nil
+		},
+		afterCommit: func(ts []*boot.BootHistoryEntry) {
+			// but it was afterwards
+			m, err := boot.ReadModeenv("")
+			c.Assert(err, IsNil)
+			c.Check(m.CurrentKernels, DeepEquals, []string{s.kern1.Filename(), s.kern2.Filename()})
+			transitions = ts
+		},
+	}
+	s.AddCleanup(boot.AddStateObserver(o))
+
+	_, err := boot.Participant(s.kern2, snap.TypeKernel, coreDev).SetNextBoot()
+	c.Assert(err, IsNil)
+
+	c.Check(o.calls, DeepEquals, []string{"before", "after"})
+	c.Assert(transitions, HasLen, 1)
+	c.Check(transitions[0].SnapType, Equals, snap.TypeKernel)
+	c.Check(transitions[0].Snap, Equals, s.kern2.Filename())
+	c.Check(transitions[0].NewStatus, Equals, boot.TryStatus)
+}
@@ -0,0 +1,250 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/boot/boottest"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/snap"
+)
+
+func mockSnapBlobs(c *C, snaps ...snap.PlaceInfo) {
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	for _, sn := range snaps {
+		c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapBlobDir, sn.Filename()), nil, 0644), IsNil)
+	}
+}
+
+func (s *bootenvSuite) TestRevertToUnhappy(c *C) {
+	prev, err := snap.ParsePlaceInfoFromSnapFileName("krnl_41.snap")
+	c.Assert(err, IsNil)
+
+	_, err = boot.RevertTo(boottest.MockDevice(""), snap.TypeKernel, prev)
+	c.Check(err, ErrorMatches, "cannot revert boot snaps on classic")
+
+	_, err = boot.RevertTo(boottest.MockDevice("krnl@recover"), snap.TypeKernel, prev)
+	c.Check(err, ErrorMatches, "cannot revert boot snaps outside of run mode")
+
+	coreDev := boottest.MockDevice("krnl")
+	_, err = boot.RevertTo(coreDev, snap.TypeKernel, prev)
+	c.Check(err, ErrorMatches, `cannot revert kernel to "krnl_41.snap": snap file does not exist`)
+
+	mockSnapBlobs(c, prev)
+	_, err = boot.RevertTo(coreDev, snap.TypeGadget, prev)
+	c.Check(err, ErrorMatches, `internal error: no boot state handling for snap type "gadget"`)
+}
+
+func (s *bootenvSuite) TestRevertToKernel(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+
+	prev, err := snap.ParsePlaceInfoFromSnapFileName("krnl_41.snap")
+	c.Assert(err, IsNil)
+	mockSnapBlobs(c, prev)
+
+	s.bootloader.BootVars["snap_kernel"] = "krnl_42.snap"
+	s.bootloader.BootVars["snap_core"] = "core_100.snap"
+
+	rebootRequired, err := boot.RevertTo(coreDev, snap.TypeKernel, prev)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
+
+	m, err := s.bootloader.GetBootVars("snap_mode", "snap_kernel", "snap_try_kernel", "snap_core", "snap_try_core")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snap_mode":       boot.DefaultStatus,
+		"snap_kernel":     "krnl_41.snap",
+		"snap_try_kernel": "",
+		"snap_core":       "core_100.snap",
+		"snap_try_core":   "",
+	})
+
+	// reverting again is a no-op
+	s.bootloader.SetBootVarsCalls = 0
+	rebootRequired, err = boot.RevertTo(coreDev, snap.TypeKernel, prev)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, false)
+	c.Check(s.bootloader.SetBootVarsCalls, Equals, 0)
+}
+
+func (s *bootenvSuite) TestRevertToKernelWhileTryingCore(c *C) {
+	coreDev := boottest.MockDevice("krnl")
+
+	prev, err := snap.ParsePlaceInfoFromSnapFileName("krnl_41.snap")
+	c.Assert(err, IsNil)
+	mockSnapBlobs(c, prev)
+
+	s.bootloader.BootVars["snap_mode"] = boot.TryingStatus
+	s.bootloader.BootVars["snap_kernel"] = "krnl_41.snap"
+	s.bootloader.BootVars["snap_try_kernel"] = "krnl_42.snap"
+	s.bootloader.BootVars["snap_core"] = "core_100.snap"
+	s.bootloader.BootVars["snap_try_core"] = "core_101.snap"
+
+	// the try kernel was booted
+	rebootRequired, err := boot.RevertTo(coreDev, snap.TypeKernel, prev)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
+
+	// the try core is left alone
+	m, err := s.bootloader.GetBootVars("snap_mode", "snap_kernel", "snap_try_kernel", "snap_core", "snap_try_core")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snap_mode":       boot.TryingStatus,
+		"snap_kernel":     "krnl_41.snap",
+		"snap_try_kernel": "",
+		"snap_core":       "core_100.snap",
+		"snap_try_core":   "core_101.snap",
+	})
+}
+
+func (s *bootenv20Suite) TestRevertToKernel20(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	mockSnapBlobs(c, s.kern1)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				CurrentKernels: []string{s.kern2.Filename()},
+			},
+			kern:       s.kern2,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	rebootRequired, err := boot.RevertTo(coreDev, snap.TypeKernel, s.kern1)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
+
+	// the bootloader now boots the previous kernel
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Check(actual, DeepEquals, []snap.PlaceInfo{s.kern1})
+	_, nDisableTryCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
+	c.Check(nDisableTryCalls, Equals, 1)
+
+	// which is the only one trusted
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+}
+
+func (s *bootenv20Suite) TestRevertToKernel20TrustsPreviousKernelFirst(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	mockSnapBlobs(c, s.kern1)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base1.Filename(),
+				CurrentKernels: []string{s.kern2.Filename()},
+			},
+			kern:       s.kern2,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	// reboot right when the bootloader is pointed to the previous kernel
+	restore := s.bootloader.SetRunKernelImagePanic("EnableKernel")
+	defer restore()
+	c.Assert(func() { boot.RevertTo(coreDev, snap.TypeKernel, s.kern1) }, PanicMatches, "mocked reboot panic in EnableKernel")
+
+	// both kernels are trusted
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentKernels, DeepEquals, []string{s.kern2.Filename(), s.kern1.Filename()})
+}
+
+func (s *bootenv20Suite) TestRevertToKernel20DropsTryKernel(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	mockSnapBlobs(c, s.kern1)
+
+	// kern2 is being tried
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		s.normalTryingKernelState,
+	)
+	defer r()
+	s.bootloader.BootVars["kernel_boot_attempts"] = "2"
+
+	rebootRequired, err := boot.RevertTo(coreDev, snap.TypeKernel, s.kern1)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
+
+	c.Check(s.bootloader.BootVars["kernel_status"], Equals, boot.DefaultStatus)
+	c.Check(s.bootloader.BootVars["kernel_boot_attempts"], Equals, "")
+	actual, _ := s.bootloader.GetRunKernelImageFunctionSnapCalls("EnableKernel")
+	c.Check(actual, HasLen, 0)
+	_, nDisableTryCalls := s.bootloader.GetRunKernelImageFunctionSnapCalls("DisableTryKernel")
+	c.Check(nDisableTryCalls, Equals, 1)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.CurrentKernels, DeepEquals, []string{s.kern1.Filename()})
+}
+
+func (s *bootenv20Suite) TestRevertToBase20(c *C) {
+	coreDev := boottest.MockUC20Device("", nil)
+	mockSnapBlobs(c, s.base1)
+
+	r := setupUC20Bootenv(
+		c,
+		s.bootloader,
+		&bootenv20Setup{
+			modeenv: &boot.Modeenv{
+				Mode:           "run",
+				Base:           s.base2.Filename(),
+				CurrentKernels: []string{s.kern1.Filename()},
+			},
+			kern:       s.kern1,
+			kernStatus: boot.DefaultStatus,
+		},
+	)
+	defer r()
+
+	rebootRequired, err := boot.RevertTo(coreDev, snap.TypeBase, s.base1)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, true)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.Base, Equals, s.base1.Filename())
+	c.Check(m.TryBase, Equals, "")
+	c.Check(m.BaseStatus, Equals, boot.DefaultStatus)
+
+	// reverting again is a no-op
+	rebootRequired, err = boot.RevertTo(coreDev, snap.TypeBase, s.base1)
+	c.Assert(err, IsNil)
+	c.Check(rebootRequired, Equals, false)
+}
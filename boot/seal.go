@@ -29,6 +29,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/bootloader"
@@ -43,8 +44,9 @@ import (
 )
 
 var (
-	secbootSealKeys   = secboot.SealKeys
-	secbootResealKeys = secboot.ResealKeys
+	secbootSealKeys                 = secboot.SealKeys
+	secbootResealKeys               = secboot.ResealKeys
+	secbootCheckKeySealingSupported = secboot.CheckKeySealingSupported
 
 	seedReadSystemEssential = seed.ReadSystemEssential
 )
@@ -318,6 +320,14 @@ func sealedKeysMethod(rootdir string) (sm sealingMethod, err error) {
 // resealKeyToModeenv reseals the existing encryption key to the
 // parameters specified in modeenv.
 func resealKeyToModeenv(rootdir string, model *asserts.Model, modeenv *Modeenv, expectReseal bool) error {
+	const force = false
+	return resealKeyToModeenvImpl(rootdir, model, modeenv, expectReseal, force)
+}
+
+// resealKeyToModeenvImpl reseals the existing encryption key to the
+// parameters specified in modeenv, if force is true the key is resealed even
+// if the boot chains did not change.
+func resealKeyToModeenvImpl(rootdir string, model *asserts.Model, modeenv *Modeenv, expectReseal, force bool) error {
 	method, err := sealedKeysMethod(rootdir)
 	if err == errNoSealedKeys {
 		// nothing to do
@@ -330,7 +340,7 @@ func resealKeyToModeenv(rootdir string, model *asserts.Model, modeenv *Modeenv,
 	case sealingMethodFDESetupHook:
 		return resealKeyToModeenvUsingFDESetupHook(rootdir, model, modeenv, expectReseal)
 	case sealingMethodTPM, sealingMethodLegacyTPM:
-		return resealKeyToModeenvSecboot(rootdir, model, modeenv, expectReseal)
+		return resealKeyToModeenvSecboot(rootdir, model, modeenv, expectReseal, force)
 	default:
 		return fmt.Errorf("unknown key sealing method: %q", method)
 	}
@@ -355,25 +365,82 @@ func resealKeyToModeenvUsingFDESetupHookImpl(rootdir string, model *asserts.Mode
 	return nil
 }
 
-func resealKeyToModeenvSecboot(rootdir string, model *asserts.Model, modeenv *Modeenv, expectReseal bool) error {
+func resealKeyToModeenvSecboot(rootdir string, model *asserts.Model, modeenv *Modeenv, expectReseal, force bool) error {
+	pbc, rpbc, roleToBlName, err := bootChainsForReseal(model, modeenv)
+	if err != nil {
+		return err
+	}
+
+	// reseal the run object
+	needed, nextCount, err := isResealNeeded(pbc, bootChainsFileUnder(rootdir), expectReseal)
+	if err != nil {
+		return err
+	}
+	if !needed && !force {
+		logger.Debugf("reseal not necessary")
+		return nil
+	}
+	pbcJSON, _ := json.Marshal(pbc)
+	logger.Debugf("resealing (%d) to boot chains: %s", nextCount, pbcJSON)
+
+	saveFDEDir := dirs.SnapFDEDirUnderSave(dirs.SnapSaveDirUnder(rootdir))
+	authKeyFile := filepath.Join(saveFDEDir, "tpm-policy-auth-key")
+	if err := resealRunObjectKeys(pbc, authKeyFile, roleToBlName); err != nil {
+		return err
+	}
+	logger.Debugf("resealing (%d) succeeded", nextCount)
+
+	bootChainsPath := bootChainsFileUnder(rootdir)
+	if err := writeBootChains(pbc, bootChainsPath, nextCount); err != nil {
+		return err
+	}
+
+	// reseal the fallback object
+	var nextFallbackCount int
+	needed, nextFallbackCount, err = isResealNeeded(rpbc, recoveryBootChainsFileUnder(rootdir), expectReseal)
+	if err != nil {
+		return err
+	}
+	if !needed && !force {
+		logger.Debugf("fallback reseal not necessary")
+		return nil
+	}
+
+	rpbcJSON, _ := json.Marshal(rpbc)
+	logger.Debugf("resealing (%d) to recovery boot chains: %s", nextCount, rpbcJSON)
+
+	if err := resealFallbackObjectKeys(rpbc, authKeyFile, roleToBlName); err != nil {
+		return err
+	}
+	logger.Debugf("fallback resealing (%d) succeeded", nextFallbackCount)
+
+	recoveryBootChainsPath := recoveryBootChainsFileUnder(rootdir)
+	return writeBootChains(rpbc, recoveryBootChainsPath, nextFallbackCount)
+}
+
+// bootChainsForReseal composes the boot chains the run object is sealed
+// against, that is of run mode and of all the current recovery systems, and
+// the ones the fallback object is sealed against, that is of the recovery
+// systems known to be good.
+func bootChainsForReseal(model *asserts.Model, modeenv *Modeenv) (pbc, rpbc predictableBootChains, roleToBlName map[bootloader.Role]string, err error) {
 	// build the recovery mode boot chain
 	rbl, err := bootloader.Find(InitramfsUbuntuSeedDir, &bootloader.Options{
 		Role: bootloader.RoleRecovery,
 	})
 	if err != nil {
-		return fmt.Errorf("cannot find the recovery bootloader: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot find the recovery bootloader: %v", err)
 	}
 	tbl, ok := rbl.(bootloader.TrustedAssetsBootloader)
 	if !ok {
 		// TODO:UC20: later the exact kind of bootloaders we expect here might change
-		return fmt.Errorf("internal error: sealed keys but not a trusted assets bootloader")
+		return nil, nil, nil, fmt.Errorf("internal error: recovery bootloader does not support trusted assets")
 	}
 
 	// the recovery boot chains for the run key are generated for all
 	// recovery systems, including those that are being tried
 	recoveryBootChainsForRunKey, err := recoveryBootChainsForSystems(modeenv.CurrentRecoverySystems, tbl, model, modeenv)
 	if err != nil {
-		return fmt.Errorf("cannot compose recovery boot chains for run key: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot compose recovery boot chains for run key: %v", err)
 	}
 
 	// the boot chains for recovery keys include only those system that were
@@ -388,7 +455,7 @@ func resealKeyToModeenvSecboot(rootdir string, model *asserts.Model, modeenv *Mo
 	}
 	recoveryBootChains, err := recoveryBootChainsForSystems(testedRecoverySystems, tbl, model, modeenv)
 	if err != nil {
-		return fmt.Errorf("cannot compose recovery boot chains: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot compose recovery boot chains: %v", err)
 	}
 
 	// build the run mode boot chains
@@ -397,71 +464,58 @@ func resealKeyToModeenvSecboot(rootdir string, model *asserts.Model, modeenv *Mo
 		NoSlashBoot: true,
 	})
 	if err != nil {
-		return fmt.Errorf("cannot find the bootloader: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot find the bootloader: %v", err)
 	}
 	cmdlines, err := kernelCommandLinesForResealWithFallback(model, modeenv)
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
 	runModeBootChains, err := runModeBootChains(rbl, bl, model, modeenv, cmdlines)
 	if err != nil {
-		return fmt.Errorf("cannot compose run mode boot chains: %v", err)
+		return nil, nil, nil, fmt.Errorf("cannot compose run mode boot chains: %v", err)
 	}
 
-	// reseal the run object
-	pbc := toPredictableBootChains(append(runModeBootChains, recoveryBootChainsForRunKey...))
-
-	needed, nextCount, err := isResealNeeded(pbc, bootChainsFileUnder(rootdir), expectReseal)
-	if err != nil {
-		return err
-	}
-	if !needed {
-		logger.Debugf("reseal not necessary")
-		return nil
-	}
-	pbcJSON, _ := json.Marshal(pbc)
-	logger.Debugf("resealing (%d) to boot chains: %s", nextCount, pbcJSON)
-
-	roleToBlName := map[bootloader.Role]string{
+	pbc = toPredictableBootChains(append(runModeBootChains, recoveryBootChainsForRunKey...))
+	rpbc = toPredictableBootChains(recoveryBootChains)
+	roleToBlName = map[bootloader.Role]string{
 		bootloader.RoleRecovery: rbl.Name(),
 		bootloader.RoleRunMode:  bl.Name(),
 	}
+	return pbc, rpbc, roleToBlName, nil
+}
 
-	saveFDEDir := dirs.SnapFDEDirUnderSave(dirs.SnapSaveDirUnder(rootdir))
-	authKeyFile := filepath.Join(saveFDEDir, "tpm-policy-auth-key")
-	if err := resealRunObjectKeys(pbc, authKeyFile, roleToBlName); err != nil {
-		return err
-	}
-	logger.Debugf("resealing (%d) succeeded", nextCount)
-
-	bootChainsPath := bootChainsFileUnder(rootdir)
-	if err := writeBootChains(pbc, bootChainsPath, nextCount); err != nil {
-		return err
-	}
-
-	// reseal the fallback object
-	rpbc := toPredictableBootChains(recoveryBootChains)
+// BootChains carries serialized boot chains, that is the sequences of boot
+// assets loaded up to the kernel, along with the kernel command lines, which
+// the encryption keys are sealed against.
+type BootChains struct {
+	// Run are the boot chains of run mode and of all the current
+	// recovery systems, the run key is sealed against those.
+	Run []byte
+	// Fallback are the boot chains of the recovery systems known to be
+	// good, the fallback keys are sealed against those.
+	Fallback []byte
+}
 
-	var nextFallbackCount int
-	needed, nextFallbackCount, err = isResealNeeded(rpbc, recoveryBootChainsFileUnder(rootdir), expectReseal)
+// ComputeBootChains returns the boot chains for the given model and modeenv,
+// taking into account the kernels listed in the modeenv, both the current
+// and the next one, along with the trusted boot assets and kernel command
+// lines. The chains are serialized in the predictable form they are stored
+// in when sealing, so they are suitable for predicting PCR values and for
+// telling whether the keys need to be resealed.
+func ComputeBootChains(model *asserts.Model, modeenv *Modeenv) (*BootChains, error) {
+	pbc, rpbc, _, err := bootChainsForReseal(model, modeenv)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("cannot compute boot chains: %v", err)
 	}
-	if !needed {
-		logger.Debugf("fallback reseal not necessary")
-		return nil
+	run, err := json.Marshal(pbc)
+	if err != nil {
+		return nil, err
 	}
-
-	rpbcJSON, _ := json.Marshal(rpbc)
-	logger.Debugf("resealing (%d) to recovery boot chains: %s", nextCount, rpbcJSON)
-
-	if err := resealFallbackObjectKeys(rpbc, authKeyFile, roleToBlName); err != nil {
-		return err
+	fallback, err := json.Marshal(rpbc)
+	if err != nil {
+		return nil, err
 	}
-	logger.Debugf("fallback resealing (%d) succeeded", nextFallbackCount)
-
-	recoveryBootChainsPath := recoveryBootChainsFileUnder(rootdir)
-	return writeBootChains(rpbc, recoveryBootChainsPath, nextFallbackCount)
+	return &BootChains{Run: run, Fallback: fallback}, nil
 }
 
 func resealRunObjectKeys(pbc predictableBootChains, authKeyFile string, roleToBlName map[bootloader.Role]string) error {
@@ -689,3 +743,122 @@ func isResealNeeded(pbc predictableBootChains, bootChainsFile string, expectRese
 	}
 	return true, c + 1, nil
 }
+
+// ResealKeys reseals the keys protecting the encrypted partitions of the
+// device to the boot chains of the current modeenv, even if those did not
+// change since the keys were last resealed.
+func ResealKeys(dev Device) error {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("cannot reseal keys on a system without modeenv")
+	}
+	method, err := sealedKeysMethod(dirs.GlobalRootDir)
+	if err != nil {
+		if err == errNoSealedKeys {
+			return fmt.Errorf("cannot reseal keys: %v", err)
+		}
+		return err
+	}
+	if method == sealingMethodFDESetupHook {
+		// resealing with the hook is not implemented, see
+		// resealKeyToModeenvUsingFDESetupHook
+		return fmt.Errorf("cannot reseal keys: not supported with fde-setup-hook")
+	}
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	const expectReseal = true
+	const force = true
+	return resealKeyToModeenvImpl(dirs.GlobalRootDir, dev.Model(), m, expectReseal, force)
+}
+
+// SealingStatus describes the state of the keys sealed to protect the
+// encrypted partitions of the device.
+type SealingStatus struct {
+	// Sealed is true when the encryption keys of the device are sealed.
+	Sealed bool
+	// Method is the method used for sealing the keys, either "tpm" or
+	// "fde-setup-hook", when the keys are sealed.
+	Method string
+	// TPMAvailable is true when a TPM usable for sealing keys was found.
+	TPMAvailable bool
+	// LastBootUnlockKey is the key that unlocked ubuntu-data on the
+	// last boot, either "run", "fallback" or "recovery", or empty if not
+	// known.
+	LastBootUnlockKey string
+	// ResealCount is the number of times the run key was resealed.
+	ResealCount int
+	// LastReseal is the time the run key was last (re)sealed to the boot
+	// chains, or the zero time if not known.
+	LastReseal time.Time
+}
+
+// FallbackUsed returns whether ubuntu-data could not be unlocked with the run
+// key on the last boot, in which case the sealed keys need attention.
+func (ss *SealingStatus) FallbackUsed() bool {
+	return ss.LastBootUnlockKey != "" && ss.LastBootUnlockKey != "run"
+}
+
+// lastBootUnlockKey returns the key that unlocked ubuntu-data on the last
+// boot, as recorded by snap-bootstrap.
+func lastBootUnlockKey() (string, error) {
+	// unlocked.json is written in run mode, degraded.json in recover mode
+	// when something did not go as expected
+	for _, name := range []string{"unlocked.json", "degraded.json"} {
+		b, err := ioutil.ReadFile(filepath.Join(dirs.SnapBootstrapRunDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		var unlockState struct {
+			UbuntuData struct {
+				UnlockKey string `json:"unlock-key"`
+			} `json:"ubuntu-data"`
+		}
+		if err := json.Unmarshal(b, &unlockState); err != nil {
+			return "", fmt.Errorf("cannot decode %s: %v", name, err)
+		}
+		return unlockState.UbuntuData.UnlockKey, nil
+	}
+	return "", nil
+}
+
+// GetSealingStatus returns the state of the sealed keys of the device.
+func GetSealingStatus(dev Device) (*SealingStatus, error) {
+	if !dev.HasModeenv() {
+		return nil, fmt.Errorf("cannot get sealing status on a system without modeenv")
+	}
+
+	var status SealingStatus
+	status.TPMAvailable = secbootCheckKeySealingSupported() == nil
+
+	method, err := sealedKeysMethod(dirs.GlobalRootDir)
+	if err != nil && err != errNoSealedKeys {
+		return nil, err
+	}
+	if err == errNoSealedKeys {
+		return &status, nil
+	}
+	status.Sealed = true
+	status.Method = string(method)
+	if method == sealingMethodLegacyTPM {
+		status.Method = string(sealingMethodTPM)
+	}
+
+	bootChainsFile := bootChainsFileUnder(dirs.GlobalRootDir)
+	_, status.ResealCount, err = readBootChains(bootChainsFile)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(bootChainsFile); err == nil {
+		status.LastReseal = fi.ModTime()
+	}
+
+	status.LastBootUnlockKey, err = lastBootUnlockKey()
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
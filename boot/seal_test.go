@@ -20,6 +20,7 @@
 package boot_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -595,6 +596,173 @@ func (s *sealSuite) TestResealKeyToModeenvWithSystemFallback(c *C) {
 	}
 }
 
+func (s *sealSuite) TestResealKeysForced(c *C) {
+	rootdir := dirs.GlobalRootDir
+
+	c.Assert(os.MkdirAll(dirs.SnapFDEDir, 0755), IsNil)
+	err := ioutil.WriteFile(filepath.Join(dirs.SnapFDEDir, "sealed-keys"), []byte("tpm"), 0644)
+	c.Assert(err, IsNil)
+
+	err = createMockGrubCfg(filepath.Join(rootdir, "run/mnt/ubuntu-seed"))
+	c.Assert(err, IsNil)
+	err = createMockGrubCfg(filepath.Join(rootdir, "run/mnt/ubuntu-boot"))
+	c.Assert(err, IsNil)
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825"},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"grubx64.efi": []string{"grub-hash-1"},
+			"bootx64.efi": []string{"shim-hash-1"},
+		},
+		CurrentTrustedBootAssets: boot.BootAssetsMap{
+			"grubx64.efi": []string{"run-grub-hash-1"},
+		},
+		CurrentKernels: []string{"pc-kernel_500.snap"},
+		CurrentKernelCommandLines: boot.BootCommandLines{
+			"snapd_recovery_mode=run console=ttyS0 console=tty1 panic=-1",
+		},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	mockAssetsCache(c, rootdir, "grub", []string{
+		"bootx64.efi-shim-hash-1",
+		"grubx64.efi-grub-hash-1",
+		"grubx64.efi-run-grub-hash-1",
+	})
+
+	model := boottest.MakeMockUC20Model()
+	restore := boot.MockSeedReadSystemEssential(func(seedDir, label string, essentialTypes []snap.Type, tm timings.Measurer) (*asserts.Model, []*seed.Snap, error) {
+		kernelSnap := &seed.Snap{
+			Path: "/var/lib/snapd/seed/snaps/pc-kernel_1.snap",
+			SideInfo: &snap.SideInfo{
+				RealName: "pc-kernel",
+				Revision: snap.Revision{N: 1},
+			},
+		}
+		return model, []*seed.Snap{kernelSnap}, nil
+	})
+	defer restore()
+
+	resealKeysCalls := 0
+	restore = boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		resealKeysCalls++
+		return nil
+	})
+	defer restore()
+
+	// the keys are resealed to the initial boot chains
+	const expectReseal = false
+	err = boot.ResealKeyToModeenv(rootdir, model, modeenv, expectReseal)
+	c.Assert(err, IsNil)
+	c.Check(resealKeysCalls, Equals, 2)
+
+	// the boot chains did not change, nothing is resealed
+	err = boot.ResealKeyToModeenv(rootdir, model, modeenv, expectReseal)
+	c.Assert(err, IsNil)
+	c.Check(resealKeysCalls, Equals, 2)
+
+	// unless forced
+	err = boot.ResealKeys(boottest.MockUC20Device("", model))
+	c.Assert(err, IsNil)
+	c.Check(resealKeysCalls, Equals, 4)
+
+	_, cnt, err := boot.ReadBootChains(filepath.Join(dirs.SnapFDEDir, "boot-chains"))
+	c.Assert(err, IsNil)
+	c.Check(cnt, Equals, 2)
+	_, cnt, err = boot.ReadBootChains(filepath.Join(dirs.SnapFDEDir, "recovery-boot-chains"))
+	c.Assert(err, IsNil)
+	c.Check(cnt, Equals, 2)
+}
+
+func (s *sealSuite) TestResealKeysErrors(c *C) {
+	model := boottest.MakeMockUC20Model()
+
+	err := boot.ResealKeys(boottest.MockDevice("pc-kernel"))
+	c.Check(err, ErrorMatches, "cannot reseal keys on a system without modeenv")
+
+	err = boot.ResealKeys(boottest.MockUC20Device("", model))
+	c.Check(err, ErrorMatches, "cannot reseal keys: no sealed keys")
+
+	c.Assert(os.MkdirAll(dirs.SnapFDEDir, 0755), IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapFDEDir, "sealed-keys"), []byte("fde-setup-hook"), 0644)
+	c.Assert(err, IsNil)
+	err = boot.ResealKeys(boottest.MockUC20Device("", model))
+	c.Check(err, ErrorMatches, "cannot reseal keys: not supported with fde-setup-hook")
+}
+
+func (s *sealSuite) TestGetSealingStatus(c *C) {
+	model := boottest.MakeMockUC20Model()
+	dev := boottest.MockUC20Device("", model)
+
+	tpmErr := errors.New("no tpm")
+	restore := boot.MockSecbootCheckKeySealingSupported(func() error {
+		return tpmErr
+	})
+	defer restore()
+
+	// nothing sealed
+	status, err := boot.GetSealingStatus(dev)
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, &boot.SealingStatus{})
+	c.Check(status.FallbackUsed(), Equals, false)
+
+	// sealed keys with the legacy stamp, unlocked with the run key
+	tpmErr = nil
+	c.Assert(os.MkdirAll(dirs.SnapFDEDir, 0755), IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapFDEDir, "sealed-keys"), nil, 0644)
+	c.Assert(err, IsNil)
+	err = boot.WriteBootChains(nil, filepath.Join(dirs.SnapFDEDir, "boot-chains"), 9)
+	c.Assert(err, IsNil)
+	fi, err := os.Stat(filepath.Join(dirs.SnapFDEDir, "boot-chains"))
+	c.Assert(err, IsNil)
+	c.Assert(os.MkdirAll(dirs.SnapBootstrapRunDir, 0755), IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapBootstrapRunDir, "unlocked.json"),
+		[]byte(`{"ubuntu-data":{"unlock-state":"unlocked","unlock-key":"run"}}`), 0644)
+	c.Assert(err, IsNil)
+
+	status, err = boot.GetSealingStatus(dev)
+	c.Assert(err, IsNil)
+	c.Check(status, DeepEquals, &boot.SealingStatus{
+		Sealed:            true,
+		Method:            "tpm",
+		TPMAvailable:      true,
+		LastBootUnlockKey: "run",
+		ResealCount:       9,
+		LastReseal:        fi.ModTime(),
+	})
+	c.Check(status.FallbackUsed(), Equals, false)
+
+	// unlocked with the recovery key
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapBootstrapRunDir, "unlocked.json"),
+		[]byte(`{"ubuntu-data":{"unlock-state":"unlocked","unlock-key":"recovery"}}`), 0644)
+	c.Assert(err, IsNil)
+	status, err = boot.GetSealingStatus(dev)
+	c.Assert(err, IsNil)
+	c.Check(status.LastBootUnlockKey, Equals, "recovery")
+	c.Check(status.FallbackUsed(), Equals, true)
+
+	// recover mode with the fallback key
+	c.Assert(os.Remove(filepath.Join(dirs.SnapBootstrapRunDir, "unlocked.json")), IsNil)
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapBootstrapRunDir, "degraded.json"),
+		[]byte(`{"ubuntu-data":{"unlock-state":"unlocked","unlock-key":"fallback"},"error-log":[]}`), 0644)
+	c.Assert(err, IsNil)
+	status, err = boot.GetSealingStatus(dev)
+	c.Assert(err, IsNil)
+	c.Check(status.LastBootUnlockKey, Equals, "fallback")
+	c.Check(status.FallbackUsed(), Equals, true)
+
+	// sealed with the fde-setup hook
+	err = ioutil.WriteFile(filepath.Join(dirs.SnapFDEDir, "sealed-keys"), []byte("fde-setup-hook"), 0644)
+	c.Assert(err, IsNil)
+	status, err = boot.GetSealingStatus(dev)
+	c.Assert(err, IsNil)
+	c.Check(status.Method, Equals, "fde-setup-hook")
+
+	_, err = boot.GetSealingStatus(boottest.MockDevice("pc-kernel"))
+	c.Check(err, ErrorMatches, "cannot get sealing status on a system without modeenv")
+}
+
 func (s *sealSuite) TestResealKeyToModeenvRecoveryKeysForGoodSystemsOnly(c *C) {
 	rootdir := c.MkDir()
 	dirs.SetRootDir(rootdir)
@@ -1008,6 +1176,113 @@ func (s *sealSuite) TestResealKeyToModeenvFallbackCmdline(c *C) {
 	})
 }
 
+func (s *sealSuite) TestComputeBootChains(c *C) {
+	rootdir := c.MkDir()
+	dirs.SetRootDir(rootdir)
+	defer dirs.SetRootDir("")
+
+	model := boottest.MakeMockUC20Model()
+
+	// trying a new kernel and a new boot asset
+	modeenv := &boot.Modeenv{
+		CurrentRecoverySystems: []string{"20200825"},
+		GoodRecoverySystems:    []string{"20200825"},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"asset": []string{"asset-hash-1"},
+		},
+		CurrentTrustedBootAssets: boot.BootAssetsMap{
+			"asset": []string{"asset-hash-1", "asset-hash-2"},
+		},
+		CurrentKernels:            []string{"pc-kernel_500.snap", "pc-kernel_501.snap"},
+		CurrentKernelCommandLines: boot.BootCommandLines{"snapd_recovery_mode=run static cmdline"},
+	}
+
+	mtbl := bootloadertest.Mock("trusted", c.MkDir()).WithTrustedAssets()
+	mtbl.StaticCommandLine = "static cmdline"
+	mtbl.BootChainList = []bootloader.BootFile{
+		bootloader.NewBootFile("", "asset", bootloader.RoleRunMode),
+		bootloader.NewBootFile("/var/lib/snapd/snap/pc-kernel_500.snap", "kernel.efi", bootloader.RoleRunMode),
+	}
+	mtbl.RecoveryBootChainList = []bootloader.BootFile{
+		bootloader.NewBootFile("", "asset", bootloader.RoleRecovery),
+		bootloader.NewBootFile("/var/lib/snapd/seed/snaps/pc-kernel_1.snap", "kernel.efi", bootloader.RoleRecovery),
+	}
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	restore := boot.MockSeedReadSystemEssential(func(seedDir, label string, essentialTypes []snap.Type, tm timings.Measurer) (*asserts.Model, []*seed.Snap, error) {
+		kernelSnap := &seed.Snap{
+			Path: "/var/lib/snapd/seed/snaps/pc-kernel_1.snap",
+			SideInfo: &snap.SideInfo{
+				RealName: "pc-kernel",
+				Revision: snap.Revision{N: 1},
+			},
+		}
+		return model, []*seed.Snap{kernelSnap}, nil
+	})
+	defer restore()
+
+	// computing the boot chains does not reseal
+	restore = boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		c.Fatalf("unexpected reseal")
+		return nil
+	})
+	defer restore()
+
+	chains, err := boot.ComputeBootChains(model, modeenv)
+	c.Assert(err, IsNil)
+
+	recoveryChain := boot.BootChain{
+		BrandID:        "my-brand",
+		Model:          "my-model-uc20",
+		Grade:          "dangerous",
+		ModelSignKeyID: "Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij",
+		AssetChain: []boot.BootAsset{
+			{Role: "recovery", Name: "asset", Hashes: []string{"asset-hash-1"}},
+		},
+		Kernel:         "pc-kernel",
+		KernelRevision: "1",
+		KernelCmdlines: []string{
+			"snapd_recovery_mode=recover snapd_recovery_system=20200825 static cmdline",
+		},
+	}
+	runChain := func(rev string) boot.BootChain {
+		return boot.BootChain{
+			BrandID:        "my-brand",
+			Model:          "my-model-uc20",
+			Grade:          "dangerous",
+			ModelSignKeyID: "Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij",
+			AssetChain: []boot.BootAsset{
+				{Role: "run-mode", Name: "asset", Hashes: []string{"asset-hash-1", "asset-hash-2"}},
+			},
+			Kernel:         "pc-kernel",
+			KernelRevision: rev,
+			KernelCmdlines: []string{"snapd_recovery_mode=run static cmdline"},
+		}
+	}
+
+	var run, fallback boot.PredictableBootChains
+	c.Assert(json.Unmarshal(chains.Run, &run), IsNil)
+	c.Check(run, DeepEquals, boot.PredictableBootChains{recoveryChain, runChain("500"), runChain("501")})
+	c.Assert(json.Unmarshal(chains.Fallback, &fallback), IsNil)
+	c.Check(fallback, DeepEquals, boot.PredictableBootChains{recoveryChain})
+
+	// the chains are the same as the ones stored when sealing
+	pbc := boot.ToPredictableBootChains([]boot.BootChain{runChain("501"), recoveryChain, runChain("500")})
+	c.Assert(boot.WriteBootChains(pbc, filepath.Join(dirs.SnapFDEDir, "boot-chains"), 1), IsNil)
+	stored, err := ioutil.ReadFile(filepath.Join(dirs.SnapFDEDir, "boot-chains"))
+	c.Assert(err, IsNil)
+	c.Check(string(stored), Equals, fmt.Sprintf(`{"reseal-count":1,"boot-chains":%s}`+"\n", chains.Run))
+}
+
+func (s *sealSuite) TestComputeBootChainsError(c *C) {
+	bootloader.Force(bootloadertest.Mock("not-trusted", c.MkDir()))
+	defer bootloader.Force(nil)
+
+	_, err := boot.ComputeBootChains(boottest.MakeMockUC20Model(), &boot.Modeenv{})
+	c.Assert(err, ErrorMatches, "cannot compute boot chains: internal error: recovery bootloader does not support trusted assets")
+}
+
 func (s *sealSuite) TestRecoveryBootChainsForSystems(c *C) {
 	for _, tc := range []struct {
 		assetsMap          boot.BootAssetsMap
@@ -49,6 +49,20 @@ func ClearTryRecoverySystem(dev Device, systemLabel string) error {
 		return err
 	}
 
+	// clear both variables, no matter the values they hold, before the
+	// system is dropped from the modeenv, the same way as a try kernel is
+	// disabled in the bootloader before it is dropped from the modeenv, so
+	// that the system stays trusted as long as the bootloader may boot it
+	vars := map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	}
+	if err := bl.SetBootVars(vars); err != nil {
+		// the bootloader may still boot the system, keep it in the
+		// modeenv and the keys sealed against it
+		return err
+	}
+
 	found := false
 	for idx, sys := range m.CurrentRecoverySystems {
 		if sys == systemLabel {
@@ -65,23 +79,11 @@ func ClearTryRecoverySystem(dev Device, systemLabel string) error {
 			return err
 		}
 	}
-	// clear both variables, no matter the values they hold
-	vars := map[string]string{
-		"try_recovery_system":    "",
-		"recovery_system_status": "",
-	}
-	// try to clear regardless of reseal failing
-	blErr := bl.SetBootVars(vars)
 
 	// but we still want to reseal, in case the cleanup did not reach this
 	// point before
 	const expectReseal = true
-	resealErr := resealKeyToModeenv(dirs.GlobalRootDir, dev.Model(), m, expectReseal)
-
-	if resealErr != nil {
-		return resealErr
-	}
-	return blErr
+	return resealKeyToModeenv(dirs.GlobalRootDir, dev.Model(), m, expectReseal)
 }
 
 // SetTryRecoverySystem sets up the boot environment for trying out a recovery
@@ -323,3 +325,88 @@ func InspectTryRecoverySystemOutcome(dev Device) (outcome TryRecoverySystemOutco
 
 	return outcome, trySystem, nil
 }
+
+// PromoteTriedRecoverySystem promotes the provided recovery system, which
+// must have been successfully tried, to be recognized as a good one, and
+// ensures that the system is present in the list of good recovery systems and
+// current recovery systems in modeenv. Once done, the boot variables related to
+// trying a recovery system are cleared. Should resealing fail, the system is
+// dropped from the modeenv.
+func PromoteTriedRecoverySystem(dev Device, systemLabel string) (err error) {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("internal error: recovery systems can only be used on UC20")
+	}
+
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	rewrite := false
+	if !strutil.ListContains(m.CurrentRecoverySystems, systemLabel) {
+		m.CurrentRecoverySystems = append(m.CurrentRecoverySystems, systemLabel)
+		rewrite = true
+	}
+	if !strutil.ListContains(m.GoodRecoverySystems, systemLabel) {
+		m.GoodRecoverySystems = append(m.GoodRecoverySystems, systemLabel)
+		rewrite = true
+	}
+	if rewrite {
+		if err := m.Write(); err != nil {
+			return err
+		}
+	}
+
+	const expectReseal = true
+	if err := resealKeyToModeenv(dirs.GlobalRootDir, dev.Model(), m, expectReseal); err != nil {
+		if cleanupErr := DropRecoverySystem(dev, systemLabel); cleanupErr != nil {
+			err = fmt.Errorf("%v (cleanup failed: %v)", err, cleanupErr)
+		}
+		return err
+	}
+
+	opts := &bootloader.Options{
+		// setup the recovery bootloader
+		Role: bootloader.RoleRecovery,
+	}
+	bl, err := bootloader.Find(InitramfsUbuntuSeedDir, opts)
+	if err != nil {
+		return err
+	}
+	vars := map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	}
+	return bl.SetBootVars(vars)
+}
+
+// DropRecoverySystem drops a provided system from the list of good and current
+// recovery systems, updates the modeenv and reseals the keys. The caller is
+// responsible for removing the system from ubuntu-seed.
+func DropRecoverySystem(dev Device, systemLabel string) error {
+	if !dev.HasModeenv() {
+		return fmt.Errorf("internal error: recovery systems can only be used on UC20")
+	}
+
+	m, err := loadModeenv()
+	if err != nil {
+		return err
+	}
+	m.CurrentRecoverySystems = dropFromList(m.CurrentRecoverySystems, systemLabel)
+	m.GoodRecoverySystems = dropFromList(m.GoodRecoverySystems, systemLabel)
+	if err := m.Write(); err != nil {
+		return err
+	}
+
+	const expectReseal = true
+	return resealKeyToModeenv(dirs.GlobalRootDir, dev.Model(), m, expectReseal)
+}
+
+func dropFromList(list []string, entry string) []string {
+	var newList []string
+	for _, e := range list {
+		if e != entry {
+			newList = append(newList, e)
+		}
+	}
+	return newList
+}
@@ -907,8 +907,73 @@ func (s *systemsSuite) TestClearRecoverySystemSetBootVarsFails(c *C) {
 	err := mtbl.SetBootVars(setVars)
 	c.Assert(err, IsNil)
 	mtbl.SetErr = fmt.Errorf("set boot vars fails")
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
 
-	s.testClearRecoverySystem(c, mtbl, "1234", nil, "set boot vars fails")
+	restore := boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		c.Errorf("unexpected call")
+		return fmt.Errorf("unexpected call")
+	})
+	defer restore()
+
+	err = boot.ClearTryRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, ErrorMatches, "set boot vars fails")
+
+	// the tried system is still trusted
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+}
+
+func (s *systemsSuite) TestClearRecoverySystemRebootBeforeModeenvUpdate(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir)
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	err := mtbl.SetBootVars(map[string]string{
+		"recovery_system_status": "try",
+		"try_recovery_system":    "1234",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := &boot.Modeenv{
+		Mode:                   "run",
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	mtbl.SetErrFunc = func() error {
+		panic("set boot vars panic")
+	}
+	c.Assert(func() {
+		boot.ClearTryRecoverySystem(s.uc20dev, "1234")
+	}, PanicMatches, "set boot vars panic")
+
+	// the bootloader may still boot the tried system, which is thus still
+	// trusted
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+
+	mtbl.SetErrFunc = nil
+	err = boot.ClearTryRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+
+	vars, err := mtbl.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+	modeenvRead, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825"})
 }
 
 func (s *systemsSuite) TestClearRecoverySystemReboot(c *C) {
@@ -1030,6 +1095,124 @@ func (s *systemsSuite) TestClearRecoverySystemReboot(c *C) {
 	checkGoodState()
 }
 
+func (s *systemsSuite) TestPromoteTriedRecoverySystemHappy(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithTrustedAssets()
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	err := mtbl.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		GoodRecoverySystems:    []string{"20200825"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err = boot.PromoteTriedRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+
+	vars, err := mtbl.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825", "1234"})
+}
+
+func (s *systemsSuite) TestPromoteTriedRecoverySystemResealFails(c *C) {
+	mockAssetsCache(c, s.rootdir, "trusted", []string{
+		"asset-asset-hash-1",
+	})
+	mtbl := s.mockTrustedBootloaderWithAssetAndChains(c, s.runKernelBf, s.recoveryKernelBf)
+	defer bootloader.Force(nil)
+
+	err := mtbl.SetBootVars(map[string]string{
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "tried",
+	})
+	c.Assert(err, IsNil)
+
+	// system is encrypted
+	s.stampSealedKeys(c, s.rootdir)
+
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825", "1234"},
+		GoodRecoverySystems:    []string{"20200825"},
+		CurrentKernels:         []string{},
+		CurrentTrustedRecoveryBootAssets: boot.BootAssetsMap{
+			"asset": []string{"asset-hash-1"},
+		},
+		CurrentTrustedBootAssets: boot.BootAssetsMap{
+			"asset": []string{"asset-hash-1"},
+		},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	restore := boot.MockSeedReadSystemEssential(func(seedDir, label string, essentialTypes []snap.Type, tm timings.Measurer) (*asserts.Model, []*seed.Snap, error) {
+		return s.uc20dev.Model(), []*seed.Snap{s.seedKernelSnap}, nil
+	})
+	defer restore()
+
+	resealCalls := 0
+	restore = boot.MockSecbootResealKeys(func(params *secboot.ResealKeysParams) error {
+		resealCalls++
+		if resealCalls == 1 {
+			return fmt.Errorf("reseal fails")
+		}
+		return nil
+	})
+	defer restore()
+
+	err = boot.PromoteTriedRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, ErrorMatches, "cannot reseal the encryption key: reseal fails")
+	// resealed again when dropping the system
+	c.Check(resealCalls, Equals, 3)
+
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825"})
+}
+
+func (s *systemsSuite) TestDropRecoverySystem(c *C) {
+	mtbl := bootloadertest.Mock("trusted", s.bootdir).WithTrustedAssets()
+	bootloader.Force(mtbl)
+	defer bootloader.Force(nil)
+
+	modeenv := &boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{"20200825", "1234", "5678"},
+		GoodRecoverySystems:    []string{"20200825", "1234", "5678"},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	err := boot.DropRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20200825", "5678"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20200825", "5678"})
+
+	// dropping a system that is not there is fine too
+	err = boot.DropRecoverySystem(s.uc20dev, "1234")
+	c.Assert(err, IsNil)
+}
+
 type initramfsMarkTryRecoverySystemSuite struct {
 	baseSystemsSuite
 
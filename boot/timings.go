@@ -0,0 +1,160 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/systemd"
+)
+
+// maxBootTimings is how many of the most recent boots the timings are kept
+// for.
+const maxBootTimings = 10
+
+// BootTimings are the durations of the stages of a boot, up to when snapd
+// was ready.
+type BootTimings struct {
+	BootID string `json:"boot-id"`
+	// Time is when snapd was ready.
+	Time time.Time `json:"time"`
+	// KernelSnap is the kernel snap that was booted, if known.
+	KernelSnap string `json:"kernel-snap,omitempty"`
+
+	Firmware   time.Duration `json:"firmware"`
+	Bootloader time.Duration `json:"bootloader"`
+	Kernel     time.Duration `json:"kernel"`
+	Initramfs  time.Duration `json:"initramfs"`
+	// SnapdReady is the time from the start of the system manager in the
+	// real root until snapd was ready.
+	SnapdReady time.Duration `json:"snapd-ready"`
+}
+
+// Total returns the total duration of the boot up to when snapd was ready.
+func (t *BootTimings) Total() time.Duration {
+	return t.Firmware + t.Bootloader + t.Kernel + t.Initramfs + t.SnapdReady
+}
+
+var (
+	osutilBootID = osutil.BootID
+	timeNow      = time.Now
+
+	systemdBootTimestamps = func() (*systemd.BootTimestamps, error) {
+		return systemd.New(systemd.SystemMode, nil).BootTimestamps()
+	}
+
+	monotonicNow = func() (time.Duration, error) {
+		var ts unix.Timespec
+		if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+			return 0, err
+		}
+		return time.Duration(ts.Nano()), nil
+	}
+)
+
+func readBootTimings() ([]*BootTimings, error) {
+	f, err := os.Open(dirs.SnapBootTimingsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var timings []*BootTimings
+	if err := json.NewDecoder(f).Decode(&timings); err != nil {
+		return nil, fmt.Errorf("cannot read boot timings: %v", err)
+	}
+	return timings, nil
+}
+
+// BootTimingsHistory returns the timings recorded for the most recent boots,
+// oldest first.
+func BootTimingsHistory() ([]*BootTimings, error) {
+	return readBootTimings()
+}
+
+// RecordBootTimings records the timings of the current boot, with snapd being
+// ready now, unless they were recorded already. kernelSnap is the booted
+// kernel snap, if known.
+func RecordBootTimings(kernelSnap string) error {
+	bootID, err := osutilBootID()
+	if err != nil {
+		return err
+	}
+	timings, err := readBootTimings()
+	if err != nil {
+		return err
+	}
+	if len(timings) > 0 && timings[len(timings)-1].BootID == bootID {
+		// snapd was restarted, but not the system
+		return nil
+	}
+
+	ts, err := systemdBootTimestamps()
+	if err != nil {
+		return err
+	}
+	now, err := monotonicNow()
+	if err != nil {
+		return fmt.Errorf("cannot get the time since boot: %v", err)
+	}
+
+	t := &BootTimings{
+		BootID:     bootID,
+		Time:       timeNow(),
+		KernelSnap: kernelSnap,
+		Bootloader: ts.Loader,
+		Kernel:     ts.Userspace,
+		SnapdReady: now - ts.Userspace,
+	}
+	// the firmware and boot loader timestamps count back from the start of
+	// the kernel
+	if ts.Loader != 0 {
+		t.Firmware = ts.Firmware - ts.Loader
+	} else {
+		t.Firmware = ts.Firmware
+	}
+	if ts.InitRD != 0 {
+		t.Kernel = ts.InitRD
+		t.Initramfs = ts.Userspace - ts.InitRD
+	}
+
+	timings = append(timings, t)
+	if len(timings) > maxBootTimings {
+		timings = timings[len(timings)-maxBootTimings:]
+	}
+	b, err := json.Marshal(timings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dirs.SnapBootTimingsFile), 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(dirs.SnapBootTimingsFile, b, 0644, 0)
+}
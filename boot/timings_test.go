@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package boot_test
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type bootTimingsSuite struct {
+	testutil.BaseTest
+
+	bootID string
+	now    time.Time
+}
+
+var _ = Suite(&bootTimingsSuite{})
+
+func (s *bootTimingsSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("/") })
+
+	s.bootID = "boot-1"
+	s.now = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	s.AddCleanup(boot.MockBootTimingsSources(
+		func() (string, error) { return s.bootID, nil },
+		func() (*systemd.BootTimestamps, error) {
+			return &systemd.BootTimestamps{
+				Firmware:  5 * time.Second,
+				Loader:    2 * time.Second,
+				InitRD:    3 * time.Second,
+				Userspace: 10 * time.Second,
+			}, nil
+		},
+		func() (time.Duration, error) { return 30 * time.Second, nil },
+		func() time.Time { return s.now },
+	))
+}
+
+func (s *bootTimingsSuite) TestRecordBootTimings(c *C) {
+	timings, err := boot.BootTimingsHistory()
+	c.Assert(err, IsNil)
+	c.Check(timings, HasLen, 0)
+
+	err = boot.RecordBootTimings("pc-kernel_1.snap")
+	c.Assert(err, IsNil)
+
+	timings, err = boot.BootTimingsHistory()
+	c.Assert(err, IsNil)
+	c.Assert(timings, HasLen, 1)
+	c.Check(timings[0].Time.Equal(s.now), Equals, true)
+	timings[0].Time = time.Time{}
+	c.Check(timings[0], DeepEquals, &boot.BootTimings{
+		BootID:     "boot-1",
+		KernelSnap: "pc-kernel_1.snap",
+		Firmware:   3 * time.Second,
+		Bootloader: 2 * time.Second,
+		Kernel:     3 * time.Second,
+		Initramfs:  7 * time.Second,
+		SnapdReady: 20 * time.Second,
+	})
+	c.Check(timings[0].Total(), Equals, 35*time.Second)
+
+	// recording again in the same boot does nothing
+	err = boot.RecordBootTimings("pc-kernel_2.snap")
+	c.Assert(err, IsNil)
+	timings, err = boot.BootTimingsHistory()
+	c.Assert(err, IsNil)
+	c.Assert(timings, HasLen, 1)
+	c.Check(timings[0].KernelSnap, Equals, "pc-kernel_1.snap")
+}
+
+func (s *bootTimingsSuite) TestRecordBootTimingsKeepsRecentBoots(c *C) {
+	for i := 0; i < 12; i++ {
+		s.bootID = fmt.Sprintf("boot-%d", i)
+		err := boot.RecordBootTimings("")
+		c.Assert(err, IsNil)
+	}
+
+	timings, err := boot.BootTimingsHistory()
+	c.Assert(err, IsNil)
+	c.Assert(timings, HasLen, 10)
+	c.Check(timings[0].BootID, Equals, "boot-2")
+	c.Check(timings[9].BootID, Equals, "boot-11")
+}
+
+func (s *bootTimingsSuite) TestRecordBootTimingsNoInitramfs(c *C) {
+	restore := boot.MockBootTimingsSources(
+		func() (string, error) { return "boot-1", nil },
+		func() (*systemd.BootTimestamps, error) {
+			return &systemd.BootTimestamps{Userspace: 4 * time.Second}, nil
+		},
+		func() (time.Duration, error) { return 5 * time.Second, nil },
+		time.Now,
+	)
+	defer restore()
+
+	err := boot.RecordBootTimings("")
+	c.Assert(err, IsNil)
+
+	timings, err := boot.BootTimingsHistory()
+	c.Assert(err, IsNil)
+	c.Assert(timings, HasLen, 1)
+	c.Check(timings[0].Kernel, Equals, 4*time.Second)
+	c.Check(timings[0].Initramfs, Equals, time.Duration(0))
+	c.Check(timings[0].SnapdReady, Equals, time.Second)
+}
+
+func (s *bootTimingsSuite) TestRecordBootTimingsError(c *C) {
+	restore := boot.MockBootTimingsSources(
+		func() (string, error) { return "boot-1", nil },
+		func() (*systemd.BootTimestamps, error) { return nil, fmt.Errorf("boom") },
+		func() (time.Duration, error) { return 0, nil },
+		time.Now,
+	)
+	defer restore()
+
+	err := boot.RecordBootTimings("")
+	c.Assert(err, ErrorMatches, "boom")
+
+	timings, err := boot.BootTimingsHistory()
+	c.Assert(err, IsNil)
+	c.Check(timings, HasLen, 0)
+}
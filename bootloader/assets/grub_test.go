@@ -38,7 +38,7 @@ type grubAssetsTestSuite struct{}
 
 var _ = Suite(&grubAssetsTestSuite{})
 
-func (s *grubAssetsTestSuite) testGrubConfigContains(c *C, name string, keys ...string) {
+func (s *grubAssetsTestSuite) testGrubConfigContains(c *C, name string, edition int, keys ...string) {
 	a := assets.Internal(name)
 	c.Assert(a, NotNil)
 	as := string(a)
@@ -47,18 +47,19 @@ func (s *grubAssetsTestSuite) testGrubConfigContains(c *C, name string, keys ...
 	}
 	idx := bytes.IndexRune(a, '\n')
 	c.Assert(idx, Not(Equals), -1)
-	c.Assert(string(a[:idx]), Equals, "# Snapd-Boot-Config-Edition: 1")
+	c.Assert(string(a[:idx]), Equals, fmt.Sprintf("# Snapd-Boot-Config-Edition: %d", edition))
 }
 
 func (s *grubAssetsTestSuite) TestGrubConf(c *C) {
-	s.testGrubConfigContains(c, "grub.cfg",
+	s.testGrubConfigContains(c, "grub.cfg", 2,
 		"snapd_recovery_mode",
 		"set snapd_static_cmdline_args='console=ttyS0 console=tty1 panic=-1'",
+		`set snapd_extra_cmdline_args="$snapd_try_extra_cmdline_args"`,
 	)
 }
 
 func (s *grubAssetsTestSuite) TestGrubRecoveryConf(c *C) {
-	s.testGrubConfigContains(c, "grub-recovery.cfg",
+	s.testGrubConfigContains(c, "grub-recovery.cfg", 1,
 		"snapd_recovery_mode",
 		"snapd_recovery_system",
 		"set snapd_static_cmdline_args='console=ttyS0 console=tty1 panic=-1'",
@@ -89,7 +90,7 @@ func (s *grubAssetsTestSuite) TestGrubCmdlineSnippetCrossCheck(c *C) {
 		pattern string
 	}{
 		{
-			asset: "grub.cfg", snippet: "grub.cfg:static-cmdline", edition: 1,
+			asset: "grub.cfg", snippet: "grub.cfg:static-cmdline", edition: 2,
 			content: []byte("console=ttyS0 console=tty1 panic=-1"),
 			pattern: "set snapd_static_cmdline_args='%s'\n",
 		},
@@ -244,10 +244,14 @@ func (g *grub) makeKernelEfiSymlink(s snap.PlaceInfo, name string) error {
 func (g *grub) unlinkKernelEfiSymlink(name string) error {
 	symlink := filepath.Join(g.dir(), name)
 	err := os.Remove(symlink)
-	if err != nil && !os.IsNotExist(err) {
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
 		return err
 	}
-	return nil
+	// make sure the removal is persisted
+	return osutil.SyncDir(g.dir())
 }
 
 func (g *grub) readKernelSymlink(name string) (snap.PlaceInfo, error) {
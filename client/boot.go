@@ -0,0 +1,82 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"golang.org/x/xerrors"
+
+	"github.com/snapcore/snapd/snap"
+)
+
+// BootSnap identifies a kernel or base snap revision used for booting.
+type BootSnap struct {
+	Snap     string        `json:"snap"`
+	Revision snap.Revision `json:"revision"`
+}
+
+// BootSnapStatus describes the boot state of the kernel or the base snap.
+type BootSnapStatus struct {
+	// Current is the snap the device boots by default
+	Current BootSnap `json:"current"`
+	// Try is the snap set up to be tried on the next boot, or being tried
+	// right now
+	Try *BootSnap `json:"try,omitempty"`
+	// Status is the status of trying the try snap, either "try" when
+	// the device needs to be rebooted to try it, or "trying" while it is
+	// being tried
+	Status string `json:"status,omitempty"`
+}
+
+// BootStatus describes the boot state of the device.
+type BootStatus struct {
+	Kernel BootSnapStatus `json:"kernel"`
+	Base   BootSnapStatus `json:"base"`
+	// RebootPending is true when a reboot is needed to try new snaps
+	RebootPending bool `json:"reboot-pending"`
+	// Issues lists the issues worked around during the last boot,
+	// which leave the system in a degraded state.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// BootStatus returns the boot state of the device.
+func (client *Client) BootStatus() (*BootStatus, error) {
+	var status BootStatus
+	if _, err := client.doSync("GET", "/v2/boot", nil, nil, nil, &status); err != nil {
+		return nil, xerrors.Errorf("cannot get boot status: %v", err)
+	}
+	return &status, nil
+}
+
+// CancelBootTry drops the kernel and base snaps set up to be tried on the
+// next boot, so that the device boots the current snaps instead.
+func (client *Client) CancelBootTry() (*BootStatus, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(map[string]string{"action": "cancel-try"}); err != nil {
+		return nil, err
+	}
+	var status BootStatus
+	if _, err := client.doSync("POST", "/v2/boot", nil, nil, &body, &status); err != nil {
+		return nil, xerrors.Errorf("cannot cancel boot try: %v", err)
+	}
+	return &status, nil
+}
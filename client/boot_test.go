@@ -0,0 +1,100 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (cs *clientSuite) TestBootStatus(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": {
+	        "kernel": {
+	            "current": {"snap": "pc-kernel", "revision": "1"},
+	            "try": {"snap": "pc-kernel", "revision": "2"},
+	            "status": "try"
+	        },
+	        "base": {
+	            "current": {"snap": "core20", "revision": "3"}
+	        },
+	        "reboot-pending": true,
+	        "issues": ["try base \"core20_4.snap\" failed to boot, booted base \"core20_3.snap\""]
+	    }
+	}`
+	status, err := cs.cli.BootStatus()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/boot")
+	c.Check(status, check.DeepEquals, &client.BootStatus{
+		Kernel: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "pc-kernel", Revision: snap.R(1)},
+			Try:     &client.BootSnap{Snap: "pc-kernel", Revision: snap.R(2)},
+			Status:  "try",
+		},
+		Base: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "core20", Revision: snap.R(3)},
+		},
+		RebootPending: true,
+		Issues:        []string{`try base "core20_4.snap" failed to boot, booted base "core20_3.snap"`},
+	})
+}
+
+func (cs *clientSuite) TestCancelBootTry(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": {
+	        "kernel": {
+	            "current": {"snap": "pc-kernel", "revision": "1"}
+	        },
+	        "base": {
+	            "current": {"snap": "core20", "revision": "3"}
+	        },
+	        "reboot-pending": false
+	    }
+	}`
+	status, err := cs.cli.CancelBootTry()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/boot")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action": "cancel-try",
+	})
+	c.Check(status, check.DeepEquals, &client.BootStatus{
+		Kernel: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "pc-kernel", Revision: snap.R(1)},
+		},
+		Base: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "core20", Revision: snap.R(3)},
+		},
+	})
+}
@@ -23,8 +23,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
+	"path"
 	"time"
+
+	"golang.org/x/net/websocket"
 )
 
 // A Change is a modification to the system state.
@@ -90,6 +94,59 @@ func (client *Client) Change(id string) (*Change, error) {
 	return &chgd.Change, nil
 }
 
+// WatchChange streams the updates of the change with the given id. The
+// returned channel receives the change every time its status, the progress
+// or the logs of its tasks are updated, and is closed once the change is
+// ready or the connection to snapd goes away. The channel must be drained
+// until it is closed.
+func (client *Client) WatchChange(id string) (<-chan *Change, error) {
+	u := client.baseURL
+	u.Scheme = "ws"
+	u.Path = path.Join(client.baseURL.Path, "/v2/changes", id, "watch")
+	config, err := websocket.NewConfig(u.String(), client.baseURL.String())
+	if err != nil {
+		return nil, RequestError{err}
+	}
+	// the handshake is a regular request, authorize it as such
+	req := &http.Request{Header: config.Header}
+	if client.userAgent != "" {
+		req.Header.Set("User-Agent", client.userAgent)
+	}
+	if !client.disableAuth {
+		if err := client.setAuthorization(req); err != nil {
+			return nil, AuthorizationError{err}
+		}
+	}
+	if client.interactive {
+		req.Header.Set(AllowInteractionHeader, "true")
+	}
+
+	conn, err := client.dial("tcp", u.Host)
+	if err != nil {
+		return nil, ConnectionError{err}
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot watch change %s: %v", id, err)
+	}
+
+	updates := make(chan *Change)
+	go func() {
+		defer close(updates)
+		defer ws.Close()
+		for {
+			var chgd changeAndData
+			if err := websocket.JSON.Receive(ws, &chgd); err != nil {
+				return
+			}
+			chgd.Change.data = chgd.Data
+			updates <- &chgd.Change
+		}
+	}()
+	return updates, nil
+}
+
 // Abort attempts to abort a change that is in not yet ready.
 func (client *Client) Abort(id string) (*Change, error) {
 	var postData struct {
@@ -20,11 +20,19 @@
 package client_test
 
 import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/net/websocket"
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
-	"io/ioutil"
-	"time"
+	"github.com/snapcore/snapd/dirs"
 )
 
 func (cs *clientSuite) TestClientChange(c *check.C) {
@@ -231,3 +239,58 @@ func (cs *clientSuite) TestClientAbort(c *check.C) {
 
 	c.Assert(string(body), check.Equals, "{\"action\":\"abort\"}\n")
 }
+
+func (cs *clientSuite) TestClientWatchChange(c *check.C) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapdSocket), 0755), check.IsNil)
+	l, err := net.Listen("unix", dirs.SnapdSocket)
+	c.Assert(err, check.IsNil)
+
+	watch := func(ws *websocket.Conn) {
+		defer ws.Close()
+		c.Check(ws.Request().URL.Path, check.Equals, "/v2/changes/uno/watch")
+		for _, msg := range []string{
+			`{"id": "uno", "kind": "foo", "summary": "...", "status": "Doing", "ready": false, "tasks": [{"id": "1", "kind": "bar", "summary": "...", "status": "Doing", "progress": {"done": 0, "total": 1}}]}`,
+			`{"id": "uno", "kind": "foo", "summary": "...", "status": "Done", "ready": true, "tasks": [{"id": "1", "kind": "bar", "summary": "...", "status": "Done", "progress": {"done": 1, "total": 1}}], "data": {"snap-names": ["foo"]}}`,
+		} {
+			c.Assert(websocket.Message.Send(ws, msg), check.IsNil)
+		}
+	}
+	srv := &httptest.Server{
+		Listener: l,
+		Config:   &http.Server{Handler: websocket.Handler(watch)},
+	}
+	srv.Start()
+	defer srv.Close()
+
+	cli := client.New(nil)
+	updates, err := cli.WatchChange("uno")
+	c.Assert(err, check.IsNil)
+
+	var chgs []*client.Change
+	for chg := range updates {
+		chgs = append(chgs, chg)
+	}
+	c.Assert(chgs, check.HasLen, 2)
+	c.Check(chgs[0].Status, check.Equals, "Doing")
+	c.Check(chgs[0].Ready, check.Equals, false)
+	c.Check(chgs[0].Tasks[0].Status, check.Equals, "Doing")
+	c.Check(chgs[1].Status, check.Equals, "Done")
+	c.Check(chgs[1].Ready, check.Equals, true)
+	c.Check(chgs[1].Tasks[0].Progress, check.DeepEquals, client.TaskProgress{Done: 1, Total: 1})
+	var snapNames []string
+	c.Assert(chgs[1].Get("snap-names", &snapNames), check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"foo"})
+}
+
+func (cs *clientSuite) TestClientWatchChangeNotFound(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/changes/uno/watch")
+		w.WriteHeader(404)
+		w.Write([]byte(`{"type": "error", "status-code": 404, "result": {"message": "cannot find change with id \"uno\""}}`))
+	}))
+	defer srv.Close()
+
+	cli := client.New(&client.Config{BaseURL: srv.URL})
+	_, err := cli.WatchChange("uno")
+	c.Assert(err, check.ErrorMatches, "cannot watch change uno: bad status")
+}
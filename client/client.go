@@ -81,6 +81,7 @@ type Config struct {
 type Client struct {
 	baseURL url.URL
 	doer    doer
+	dial    func(network, addr string) (net.Conn, error)
 
 	disableAuth bool
 	interactive bool
@@ -108,6 +109,7 @@ func New(config *Config) *Client {
 				Host:   "localhost",
 			},
 			doer:        &http.Client{Transport: transport},
+			dial:        unixDialer(config.Socket),
 			disableAuth: config.DisableAuth,
 			interactive: config.Interactive,
 			userAgent:   config.UserAgent,
@@ -121,6 +123,7 @@ func New(config *Config) *Client {
 	return &Client{
 		baseURL:     *baseURL,
 		doer:        &http.Client{Transport: &http.Transport{DisableKeepAlives: config.DisableKeepAlive}},
+		dial:        net.Dial,
 		disableAuth: config.DisableAuth,
 		interactive: config.Interactive,
 		userAgent:   config.UserAgent,
@@ -631,8 +634,17 @@ type RefreshInfo struct {
 	// Schedule contains the legacy refresh.schedule setting.
 	Schedule string `json:"schedule,omitempty"`
 	Last     string `json:"last,omitempty"`
-	Hold     string `json:"hold,omitempty"`
-	Next     string `json:"next,omitempty"`
+	// Hold is the time until which auto-refreshes of all snaps are
+	// held, or "forever".
+	Hold string `json:"hold,omitempty"`
+	Next string `json:"next,omitempty"`
+	// SnapHolds holds the times until which auto-refreshes of single
+	// snaps are held, or "forever", by snap name.
+	SnapHolds map[string]string `json:"snap-holds,omitempty"`
+	// OnMetered is set if the connection was detected as metered
+	// when auto-refresh last checked, which happens whenever a
+	// refresh is due.
+	OnMetered bool `json:"on-metered,omitempty"`
 }
 
 // SysInfo holds system information
@@ -747,3 +759,14 @@ func (client *Client) SystemRecoveryKeys(result interface{}) error {
 	_, err := client.doSync("GET", "/v2/system-recovery-keys", nil, nil, nil, &result)
 	return err
 }
+
+// RegenerateSystemRecoveryKeys replaces the recovery keys of the device with
+// new ones, the new keys are returned in result.
+func (client *Client) RegenerateSystemRecoveryKeys(result interface{}) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(map[string]string{"action": "regenerate"}); err != nil {
+		return err
+	}
+	_, err := client.doSync("POST", "/v2/system-recovery-keys", nil, nil, &body, &result)
+	return err
+}
@@ -641,3 +641,21 @@ func (cs *clientSuite) TestClientSystemRecoveryKeys(c *C) {
 	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/system-recovery-keys")
 	c.Check(key.RecoveryKey, Equals, "42")
 }
+
+func (cs *clientSuite) TestClientRegenerateSystemRecoveryKeys(c *C) {
+	cs.rsp = `{"type":"sync", "result":{"recovery-key":"42", "reinstall-key":"43"}}`
+
+	var key client.SystemRecoveryKeysResponse
+	err := cs.cli.RegenerateSystemRecoveryKeys(&key)
+	c.Assert(err, IsNil)
+	c.Check(cs.reqs, HasLen, 1)
+	c.Check(cs.reqs[0].Method, Equals, "POST")
+	c.Check(cs.reqs[0].URL.Path, Equals, "/v2/system-recovery-keys")
+	body, err := ioutil.ReadAll(cs.reqs[0].Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Equals, `{"action":"regenerate"}`+"\n")
+	c.Check(key, DeepEquals, client.SystemRecoveryKeysResponse{
+		RecoveryKey:  "42",
+		ReinstallKey: "43",
+	})
+}
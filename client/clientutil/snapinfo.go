@@ -65,6 +65,7 @@ func ClientSnapFromSnapInfo(snapInfo *snap.Info, decorator StatusDecorator) (*cl
 		InstallDate: snapInfo.InstallDate(),
 		Name:        snapInfo.InstanceName(),
 		Revision:    snapInfo.Revision,
+		Epoch:       snapInfo.Epoch,
 		Summary:     snapInfo.Summary(),
 		Type:        string(snapInfo.Type()),
 		Base:        snapInfo.Base,
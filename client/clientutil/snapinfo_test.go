@@ -63,7 +63,8 @@ func (*cmdSuite) TestClientSnapFromSnapInfo(c *C) {
 			DisplayName: "Thingy Inc.",
 			Validation:  "unproven",
 		},
-		Base: "core18",
+		Base:  "core18",
+		Epoch: snap.E("2*"),
 		SideInfo: snap.SideInfo{
 			RealName:          "the-snap",
 			SnapID:            "snapidid",
@@ -141,6 +142,7 @@ func (*cmdSuite) TestClientSnapFromSnapInfo(c *C) {
 	c.Check(ci.Type, Equals, "app")
 	c.Check(ci.ID, Equals, si.ID())
 	c.Check(ci.Revision, Equals, snap.R(99))
+	c.Check(ci.Epoch, DeepEquals, snap.E("2*"))
 	c.Check(ci.Version, Equals, "v1")
 	c.Check(ci.Title, Equals, "the-title")
 	c.Check(ci.Summary, Equals, "the-summary")
@@ -42,3 +42,11 @@ func (client *Client) InternalConsoleConfStart() ([]string, []string, error) {
 	_, err := client.doSyncWithOpts("POST", "/v2/internal/console-conf-start", nil, nil, nil, resp, opts)
 	return resp.ActiveAutoRefreshChanges, resp.ActiveAutoRefreshSnaps, err
 }
+
+// InternalConsoleConfFinish invokes the dedicated console-conf finish support
+// to release the delay of auto-refreshes set up when console-conf started.
+// Not for general use.
+func (client *Client) InternalConsoleConfFinish() error {
+	_, err := client.doSync("POST", "/v2/internal/console-conf-finish", nil, nil, nil, nil)
+	return err
+}
@@ -61,3 +61,17 @@ func (cs *clientSuite) TestClientInternalConsoleConfEndpoint(c *C) {
 	c.Check(cs.req.URL.Path, Equals, "/v2/internal/console-conf-start")
 	c.Check(cs.doCalls, Equals, 1)
 }
+
+func (cs *clientSuite) TestClientInternalConsoleConfFinish(c *C) {
+	cs.status = 200
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": null
+	}`
+
+	err := cs.cli.InternalConsoleConfFinish()
+	c.Assert(err, IsNil)
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/internal/console-conf-finish")
+}
@@ -0,0 +1,64 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"golang.org/x/xerrors"
+)
+
+// DiskPartition contains information about a partition of a disk.
+type DiskPartition struct {
+	// KernelDeviceNode is the device node of the partition, eg. /dev/vda1
+	KernelDeviceNode string `json:"kernel-device-node,omitempty"`
+	// PartitionUUID is the partition uuid
+	PartitionUUID string `json:"partition-uuid,omitempty"`
+	// PartitionLabel is the partition label, encoded like udev does
+	PartitionLabel string `json:"partition-label,omitempty"`
+	// FilesystemLabel is the filesystem label, encoded like udev does
+	FilesystemLabel string `json:"filesystem-label,omitempty"`
+	// FilesystemType is the type of the filesystem on the partition
+	FilesystemType string `json:"filesystem-type,omitempty"`
+	// Size is the size of the partition in bytes
+	Size uint64 `json:"size"`
+	// Encrypted is true when the partition holds an encrypted volume
+	Encrypted bool `json:"encrypted,omitempty"`
+	// SnapdManaged is true when the partition is one of the system
+	// partitions managed by snapd
+	SnapdManaged bool `json:"snapd-managed,omitempty"`
+}
+
+// Disk contains information about a physical disk of the device.
+type Disk struct {
+	// KernelDeviceNode is the device node of the disk, eg. /dev/vda
+	KernelDeviceNode string `json:"kernel-device-node,omitempty"`
+	// Size is the size of the disk in bytes
+	Size uint64 `json:"size"`
+	// Partitions of the disk
+	Partitions []DiskPartition `json:"partitions,omitempty"`
+}
+
+// Disks lists the physical disks of the device along with their partitions.
+func (client *Client) Disks() ([]Disk, error) {
+	var disks []Disk
+	if _, err := client.doSync("GET", "/v2/disks", nil, nil, nil, &disks); err != nil {
+		return nil, xerrors.Errorf("cannot list disks: %v", err)
+	}
+	return disks, nil
+}
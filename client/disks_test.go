@@ -0,0 +1,105 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestDisks(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": [
+	        {
+	            "kernel-device-node": "/dev/vda",
+	            "size": 4194304,
+	            "partitions": [
+	                {
+	                    "kernel-device-node": "/dev/vda1",
+	                    "partition-uuid": "ubuntu-seed-partuuid",
+	                    "partition-label": "ubuntu-seed",
+	                    "filesystem-label": "ubuntu-seed",
+	                    "filesystem-type": "vfat",
+	                    "size": 1048576,
+	                    "snapd-managed": true
+	                }, {
+	                    "kernel-device-node": "/dev/vda2",
+	                    "partition-uuid": "ubuntu-data-partuuid",
+	                    "partition-label": "ubuntu-data",
+	                    "filesystem-label": "ubuntu-data-enc",
+	                    "filesystem-type": "crypto_LUKS",
+	                    "size": 2097152,
+	                    "encrypted": true,
+	                    "snapd-managed": true
+	                }
+	            ]
+	        }, {
+	            "kernel-device-node": "/dev/sda",
+	            "size": 1024
+	        }
+	    ]
+	}`
+	disks, err := cs.cli.Disks()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/disks")
+	c.Check(disks, check.DeepEquals, []client.Disk{
+		{
+			KernelDeviceNode: "/dev/vda",
+			Size:             4194304,
+			Partitions: []client.DiskPartition{
+				{
+					KernelDeviceNode: "/dev/vda1",
+					PartitionUUID:    "ubuntu-seed-partuuid",
+					PartitionLabel:   "ubuntu-seed",
+					FilesystemLabel:  "ubuntu-seed",
+					FilesystemType:   "vfat",
+					Size:             1048576,
+					SnapdManaged:     true,
+				}, {
+					KernelDeviceNode: "/dev/vda2",
+					PartitionUUID:    "ubuntu-data-partuuid",
+					PartitionLabel:   "ubuntu-data",
+					FilesystemLabel:  "ubuntu-data-enc",
+					FilesystemType:   "crypto_LUKS",
+					Size:             2097152,
+					Encrypted:        true,
+					SnapdManaged:     true,
+				},
+			},
+		}, {
+			KernelDeviceNode: "/dev/sda",
+			Size:             1024,
+		},
+	})
+}
+
+func (cs *clientSuite) TestDisksError(c *check.C) {
+	cs.rsp = `{
+	    "type": "error",
+	    "status-code": 500,
+	    "result": {"message": "boom"}
+	}`
+	_, err := cs.cli.Disks()
+	c.Assert(err, check.ErrorMatches, "cannot list disks: boom")
+}
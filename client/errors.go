@@ -135,6 +135,14 @@ const (
 
 	// ErrorKindValidationSetNotFound: validation set cannot be found.
 	ErrorKindValidationSetNotFound ErrorKind = "validation-set-not-found"
+
+	// ErrorKindValidationSetsNotMet: the installed snaps do not satisfy
+	// the validation sets.
+	ErrorKindValidationSetsNotMet ErrorKind = "validation-sets-not-met"
+
+	// ErrorKindNotSupported: the request is not supported on this
+	// system.
+	ErrorKindNotSupported ErrorKind = "not-supported"
 )
 
 // Maintenance error kinds.
@@ -24,7 +24,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/url"
+	"os"
+	"path/filepath"
 
 	"golang.org/x/xerrors"
 
@@ -50,6 +54,73 @@ func (client *Client) Remodel(b []byte) (changeID string, err error) {
 	return client.doAsync("POST", "/v2/model", nil, headers, bytes.NewReader(data))
 }
 
+// RemodelOffline tries to remodel the system with the given assertion data,
+// using the given local snap files and the assertions in the given files to
+// avoid talking to the store.
+func (client *Client) RemodelOffline(b []byte, snapPaths, assertPaths []string) (changeID string, err error) {
+	var files []*os.File
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+		}
+	}()
+	for _, path := range append(append([]string(nil), snapPaths...), assertPaths...) {
+		f, err := os.Open(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot open %q: %v", path, err)
+		}
+		files = append(files, f)
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go sendRemodelFiles(b, files[:len(snapPaths)], files[len(snapPaths):], pw, mw)
+
+	headers := map[string]string{
+		"Content-Type": mw.FormDataContentType(),
+	}
+
+	_, changeID, err = client.doAsyncFull("POST", "/v2/model", nil, headers, pr, doNoTimeoutAndRetry)
+	return changeID, err
+}
+
+func sendRemodelFiles(model []byte, snapFiles, assertFiles []*os.File, pw *io.PipeWriter, mw *multipart.Writer) {
+	defer func() {
+		for _, f := range append(snapFiles, assertFiles...) {
+			f.Close()
+		}
+	}()
+
+	if err := mw.WriteField("new-model", string(model)); err != nil {
+		pw.CloseWithError(err)
+		return
+	}
+	for _, part := range []struct {
+		field string
+		files []*os.File
+	}{
+		{"snap", snapFiles},
+		{"assertion", assertFiles},
+	} {
+		for _, f := range part.files {
+			fw, err := mw.CreateFormFile(part.field, filepath.Base(f.Name()))
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(fw, f); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}
+
+	mw.Close()
+	pw.Close()
+}
+
 // CurrentModelAssertion returns the current model assertion
 func (client *Client) CurrentModelAssertion() (*asserts.Model, error) {
 	assert, err := currentAssertion(client, "/v2/model")
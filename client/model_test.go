@@ -24,6 +24,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"path/filepath"
 
 	"golang.org/x/xerrors"
 
@@ -130,6 +131,40 @@ func (cs *clientSuite) TestClientRemodel(c *C) {
 	c.Check(jsonBody["new-model"], Equals, string(remodelJsonData))
 }
 
+func (cs *clientSuite) TestClientRemodelOffline(c *C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+                "result": {},
+		"change": "d729"
+	}`
+	dir := c.MkDir()
+	snapPath := filepath.Join(dir, "pc-kernel_1.snap")
+	c.Assert(ioutil.WriteFile(snapPath, []byte("snap-data"), 0644), IsNil)
+	assertPath := filepath.Join(dir, "pc-kernel_1.assert")
+	c.Assert(ioutil.WriteFile(assertPath, []byte("assert-data"), 0644), IsNil)
+
+	id, err := cs.cli.RemodelOffline([]byte("model-data"), []string{snapPath}, []string{assertPath})
+	c.Assert(err, IsNil)
+	c.Check(id, Equals, "d729")
+	c.Check(cs.req.Method, Equals, "POST")
+	c.Check(cs.req.URL.Path, Equals, "/v2/model")
+	c.Assert(cs.req.Header.Get("Content-Type"), Matches, "multipart/form-data; boundary=.*")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, IsNil)
+	c.Check(string(body), Matches, "(?s).*Content-Disposition: form-data; name=\"new-model\"\r\n\r\nmodel-data\r\n.*")
+	c.Check(string(body), Matches, "(?s).*Content-Disposition: form-data; name=\"snap\"; filename=\"pc-kernel_1.snap\"\r\n.*\r\n\r\nsnap-data\r\n.*")
+	c.Check(string(body), Matches, "(?s).*Content-Disposition: form-data; name=\"assertion\"; filename=\"pc-kernel_1.assert\"\r\n.*\r\n\r\nassert-data\r\n.*")
+}
+
+func (cs *clientSuite) TestClientRemodelOfflineMissingFile(c *C) {
+	_, err := cs.cli.RemodelOffline([]byte("model-data"), []string{"/does/not/exist.snap"}, nil)
+	c.Check(err, ErrorMatches, `cannot open "/does/not/exist.snap": .*`)
+	c.Check(cs.req, IsNil)
+}
+
 func (cs *clientSuite) TestClientGetModelHappy(c *C) {
 	cs.status = 200
 	cs.rsp = happyModelAssertionResponse
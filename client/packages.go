@@ -55,6 +55,7 @@ type Snap struct {
 	TrackingChannel  string        `json:"tracking-channel,omitempty"`
 	IgnoreValidation bool          `json:"ignore-validation"`
 	Revision         snap.Revision `json:"revision"`
+	Epoch            snap.Epoch    `json:"epoch,omitempty"`
 	Confinement      string        `json:"confinement"`
 	Private          bool          `json:"private"`
 	DevMode          bool          `json:"devmode"`
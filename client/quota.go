@@ -0,0 +1,111 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/xerrors"
+)
+
+// QuotaGroupResult describes a quota group and its current resource usage.
+type QuotaGroupResult struct {
+	GroupName     string   `json:"group-name"`
+	Snaps         []string `json:"snaps,omitempty"`
+	MaxMemory     uint64   `json:"max-memory"`
+	CurrentMemory uint64   `json:"current-memory"`
+}
+
+type postQuotaData struct {
+	Action    string   `json:"action"`
+	GroupName string   `json:"group-name"`
+	Snaps     []string `json:"snaps,omitempty"`
+	MaxMemory uint64   `json:"max-memory,omitempty"`
+}
+
+// EnsureQuota creates a quota group or updates an existing one, the given
+// snaps are added to the group and the memory limit is set if non-zero. It
+// returns the ID of the change carrying out the operation.
+func (client *Client) EnsureQuota(groupName string, snaps []string, maxMemory uint64) (changeID string, err error) {
+	if groupName == "" {
+		return "", fmt.Errorf("cannot create or update quota group without a name")
+	}
+	data := &postQuotaData{
+		Action:    "ensure",
+		GroupName: groupName,
+		Snaps:     snaps,
+		MaxMemory: maxMemory,
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", err
+	}
+	changeID, err = client.doAsync("POST", "/v2/quotas", nil, nil, &body)
+	if err != nil {
+		return "", xerrors.Errorf("cannot create or update quota group: %v", err)
+	}
+	return changeID, nil
+}
+
+// GetQuotaGroup returns the quota group with the given name.
+func (client *Client) GetQuotaGroup(groupName string) (*QuotaGroupResult, error) {
+	if groupName == "" {
+		return nil, fmt.Errorf("cannot get quota group without a name")
+	}
+	var res QuotaGroupResult
+	path := "/v2/quotas/" + url.PathEscape(groupName)
+	if _, err := client.doSync("GET", path, nil, nil, nil, &res); err != nil {
+		return nil, xerrors.Errorf("cannot get quota group: %v", err)
+	}
+	return &res, nil
+}
+
+// Quotas returns all the quota groups.
+func (client *Client) Quotas() ([]*QuotaGroupResult, error) {
+	var res []*QuotaGroupResult
+	if _, err := client.doSync("GET", "/v2/quotas", nil, nil, nil, &res); err != nil {
+		return nil, xerrors.Errorf("cannot get quota groups: %v", err)
+	}
+	return res, nil
+}
+
+// RemoveQuotaGroup removes the quota group with the given name. It returns
+// the ID of the change carrying out the operation.
+func (client *Client) RemoveQuotaGroup(groupName string) (changeID string, err error) {
+	if groupName == "" {
+		return "", fmt.Errorf("cannot remove quota group without a name")
+	}
+	data := &postQuotaData{
+		Action:    "remove",
+		GroupName: groupName,
+	}
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		return "", err
+	}
+	changeID, err = client.doAsync("POST", "/v2/quotas", nil, nil, &body)
+	if err != nil {
+		return "", xerrors.Errorf("cannot remove quota group: %v", err)
+	}
+	return changeID, nil
+}
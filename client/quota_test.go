@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestEnsureQuotaGroupInvalidName(c *check.C) {
+	_, err := cs.cli.EnsureQuota("", nil, 0)
+	c.Check(err, check.ErrorMatches, `cannot create or update quota group without a name`)
+}
+
+func (cs *clientSuite) TestEnsureQuotaGroup(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+
+	chgID, err := cs.cli.EnsureQuota("foo", []string{"snap-a", "snap-b"}, 1001)
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "foo",
+		"snaps":      []interface{}{"snap-a", "snap-b"},
+		"max-memory": float64(1001),
+	})
+}
+
+func (cs *clientSuite) TestEnsureQuotaGroupError(c *check.C) {
+	cs.status = 500
+	cs.rsp = `{"type": "error", "result": {"message": "failed"}}`
+	_, err := cs.cli.EnsureQuota("foo", nil, 1001)
+	c.Check(err, check.ErrorMatches, `cannot create or update quota group: failed`)
+}
+
+func (cs *clientSuite) TestGetQuotaGroup(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"group-name":"foo", "snaps":["a","b"], "max-memory":1000, "current-memory":900}
+	}`
+
+	grp, err := cs.cli.GetQuotaGroup("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas/foo")
+	c.Check(grp, check.DeepEquals, &client.QuotaGroupResult{
+		GroupName:     "foo",
+		Snaps:         []string{"a", "b"},
+		MaxMemory:     1000,
+		CurrentMemory: 900,
+	})
+}
+
+func (cs *clientSuite) TestGetQuotaGroupInvalidName(c *check.C) {
+	_, err := cs.cli.GetQuotaGroup("")
+	c.Check(err, check.ErrorMatches, `cannot get quota group without a name`)
+}
+
+func (cs *clientSuite) TestQuotas(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"group-name":"bar", "max-memory":2000, "current-memory":0},
+			{"group-name":"foo", "snaps":["a"], "max-memory":1000, "current-memory":900}
+		]
+	}`
+
+	grps, err := cs.cli.Quotas()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+	c.Check(grps, check.DeepEquals, []*client.QuotaGroupResult{
+		{GroupName: "bar", MaxMemory: 2000},
+		{GroupName: "foo", Snaps: []string{"a"}, MaxMemory: 1000, CurrentMemory: 900},
+	})
+}
+
+func (cs *clientSuite) TestRemoveQuotaGroup(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"type": "async",
+		"status-code": 202,
+		"change": "42"
+	}`
+
+	chgID, err := cs.cli.RemoveQuotaGroup("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/quotas")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "foo",
+	})
+}
+
+func (cs *clientSuite) TestRemoveQuotaGroupInvalidName(c *check.C) {
+	_, err := cs.cli.RemoveQuotaGroup("")
+	c.Check(err, check.ErrorMatches, `cannot remove quota group without a name`)
+}
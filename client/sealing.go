@@ -0,0 +1,86 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+const (
+	// SealingStatusSealed is the status of a device with sealed
+	// encryption keys that were unlocked with the run key on the last boot.
+	SealingStatusSealed = "sealed"
+	// SealingStatusUnsealed is the status of a device without sealed
+	// encryption keys.
+	SealingStatusUnsealed = "unsealed"
+	// SealingStatusFallbackUsed is the status of a device with sealed
+	// encryption keys where the run key could not be used on the last
+	// boot, which usually means that the keys will not unlock the device
+	// after the next update of the boot chain.
+	SealingStatusFallbackUsed = "fallback-used"
+)
+
+// SealingStatus describes the state of the keys sealed to protect the
+// encrypted partitions of the device.
+type SealingStatus struct {
+	// Status is one of SealingStatusSealed, SealingStatusUnsealed or
+	// SealingStatusFallbackUsed
+	Status string `json:"status"`
+	// Method is the method used for sealing the keys, either "tpm" or
+	// "fde-setup-hook"
+	Method string `json:"method,omitempty"`
+	// TPMAvailable is true when a TPM usable for sealing keys was found
+	TPMAvailable bool `json:"tpm-available"`
+	// LastBootUnlockKey is the key that unlocked the data partition on the
+	// last boot, either "run", "fallback" or "recovery"
+	LastBootUnlockKey string `json:"last-boot-unlock-key,omitempty"`
+	// ResealCount is the number of times the run key was resealed
+	ResealCount int `json:"reseal-count,omitempty"`
+	// LastReseal is the time the run key was last (re)sealed
+	LastReseal time.Time `json:"last-reseal,omitempty"`
+}
+
+// SealingStatus returns the state of the sealed encryption keys of the
+// device.
+func (client *Client) SealingStatus() (*SealingStatus, error) {
+	var status SealingStatus
+	if _, err := client.doSync("GET", "/v2/system-sealing", nil, nil, nil, &status); err != nil {
+		return nil, xerrors.Errorf("cannot get sealing status: %v", err)
+	}
+	return &status, nil
+}
+
+// ResealKeys reseals the encryption keys of the device to its current boot
+// chains.
+func (client *Client) ResealKeys() (*SealingStatus, error) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(map[string]string{"action": "reseal"}); err != nil {
+		return nil, err
+	}
+	var status SealingStatus
+	if _, err := client.doSync("POST", "/v2/system-sealing", nil, nil, &body, &status); err != nil {
+		return nil, xerrors.Errorf("cannot reseal keys: %v", err)
+	}
+	return &status, nil
+}
@@ -0,0 +1,96 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2019 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestSealingStatus(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": {
+	        "status": "fallback-used",
+	        "method": "tpm",
+	        "tpm-available": true,
+	        "last-boot-unlock-key": "recovery",
+	        "reseal-count": 3,
+	        "last-reseal": "2021-05-07T10:00:00Z"
+	    }
+	}`
+	status, err := cs.cli.SealingStatus()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/system-sealing")
+	c.Check(status, check.DeepEquals, &client.SealingStatus{
+		Status:            client.SealingStatusFallbackUsed,
+		Method:            "tpm",
+		TPMAvailable:      true,
+		LastBootUnlockKey: "recovery",
+		ResealCount:       3,
+		LastReseal:        time.Date(2021, 5, 7, 10, 0, 0, 0, time.UTC),
+	})
+}
+
+func (cs *clientSuite) TestResealKeys(c *check.C) {
+	cs.rsp = `{
+	    "type": "sync",
+	    "status-code": 200,
+	    "result": {
+	        "status": "sealed",
+	        "method": "tpm",
+	        "tpm-available": true,
+	        "reseal-count": 4,
+	        "last-reseal": "2021-05-07T10:00:00Z"
+	    }
+	}`
+	status, err := cs.cli.ResealKeys()
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/system-sealing")
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	c.Assert(json.Unmarshal(body, &req), check.IsNil)
+	c.Check(req, check.DeepEquals, map[string]interface{}{
+		"action": "reseal",
+	})
+	c.Check(status, check.DeepEquals, &client.SealingStatus{
+		Status:       client.SealingStatusSealed,
+		Method:       "tpm",
+		TPMAvailable: true,
+		ResealCount:  4,
+		LastReseal:   time.Date(2021, 5, 7, 10, 0, 0, 0, time.UTC),
+	})
+}
+
+func (cs *clientSuite) TestResealKeysError(c *check.C) {
+	cs.status = 500
+	cs.rsp = `{"type": "error", "result": {"message": "boom"}}`
+	_, err := cs.cli.ResealKeys()
+	c.Check(err, check.ErrorMatches, "cannot reseal keys: boom")
+}
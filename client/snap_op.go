@@ -28,6 +28,22 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// TransactionType is the type of the transaction installing or refreshing
+// several snaps together.
+type TransactionType string
+
+const (
+	// TransactionPerSnap has every snap installed or refreshed on its own,
+	// a failure of one of them does not undo the others. It is the
+	// default.
+	TransactionPerSnap TransactionType = "per-snap"
+	// TransactionAllSnaps has all the snaps installed or refreshed
+	// together undone if any of them fails.
+	TransactionAllSnaps TransactionType = "all-snaps"
 )
 
 type SnapOptions struct {
@@ -45,6 +61,8 @@ type SnapOptions struct {
 	Purge            bool   `json:"purge,omitempty"`
 	Amend            bool   `json:"amend,omitempty"`
 
+	Transaction TransactionType `json:"transaction,omitempty"`
+
 	Users []string `json:"users,omitempty"`
 }
 
@@ -89,9 +107,11 @@ type actionData struct {
 }
 
 type multiActionData struct {
-	Action string   `json:"action"`
-	Snaps  []string `json:"snaps,omitempty"`
-	Users  []string `json:"users,omitempty"`
+	Action      string          `json:"action"`
+	Snaps       []string        `json:"snaps,omitempty"`
+	Users       []string        `json:"users,omitempty"`
+	Time        string          `json:"time,omitempty"`
+	Transaction TransactionType `json:"transaction,omitempty"`
 }
 
 // Install adds the snap with the given name from the given channel (or
@@ -183,8 +203,9 @@ func (client *Client) doSnapAction(actionName string, snapName string, options *
 }
 
 func (client *Client) doMultiSnapAction(actionName string, snaps []string, options *SnapOptions) (changeID string, err error) {
-	if options != nil {
-		return "", fmt.Errorf("cannot use options for multi-action") // (yet)
+	if options != nil && !reflect.DeepEqual(*options, SnapOptions{Transaction: options.Transaction}) {
+		// only the transaction type is supported for multi-action (yet)
+		return "", fmt.Errorf("cannot use options for multi-action")
 	}
 	_, changeID, err = client.doMultiSnapActionFull(actionName, snaps, options)
 
@@ -198,8 +219,13 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, o
 	}
 	if options != nil {
 		action.Users = options.Users
+		action.Transaction = options.Transaction
 	}
-	data, err := json.Marshal(&action)
+	return client.doMultiAction(&action)
+}
+
+func (client *Client) doMultiAction(action *multiActionData) (result json.RawMessage, changeID string, err error) {
+	data, err := json.Marshal(action)
 	if err != nil {
 		return nil, "", fmt.Errorf("cannot marshal multi-snap action: %s", err)
 	}
@@ -211,6 +237,31 @@ func (client *Client) doMultiSnapActionFull(actionName string, snaps []string, o
 	return client.doAsyncFull("POST", "/v2/snaps", nil, headers, bytes.NewBuffer(data), nil)
 }
 
+// HoldRefreshes holds the auto-refreshes of the given snaps, or of all snaps
+// if none are given, until the given time or, if it is zero, forever.
+func (client *Client) HoldRefreshes(names []string, until time.Time) (changeID string, err error) {
+	holdTime := "forever"
+	if !until.IsZero() {
+		holdTime = until.Format(time.RFC3339)
+	}
+	_, changeID, err = client.doMultiAction(&multiActionData{
+		Action: "hold",
+		Snaps:  names,
+		Time:   holdTime,
+	})
+	return changeID, err
+}
+
+// UnholdRefreshes removes the holds on the auto-refreshes of the given snaps,
+// or the hold on the auto-refreshes of all snaps if none are given.
+func (client *Client) UnholdRefreshes(names []string) (changeID string, err error) {
+	_, changeID, err = client.doMultiAction(&multiActionData{
+		Action: "unhold",
+		Snaps:  names,
+	})
+	return changeID, err
+}
+
 // InstallPath sideloads the snap with the given path under optional provided name,
 // returning the UUID of the background operation upon success.
 func (client *Client) InstallPath(path, name string, options *SnapOptions) (changeID string, err error) {
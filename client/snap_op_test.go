@@ -29,6 +29,7 @@ import (
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -186,6 +187,73 @@ func (cs *clientSuite) TestClientMultiOpSnap(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientMultiOpSnapTransaction(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	for _, s := range []struct {
+		op     func(*client.Client, []string, *client.SnapOptions) (string, error)
+		action string
+	}{
+		{(*client.Client).RefreshMany, "refresh"},
+		{(*client.Client).InstallMany, "install"},
+	} {
+		id, err := s.op(cs.cli, []string{pkgName}, &client.SnapOptions{Transaction: client.TransactionAllSnaps})
+		c.Assert(err, check.IsNil, check.Commentf(s.action))
+		c.Check(id, check.Equals, "d728", check.Commentf(s.action))
+
+		var jsonBody map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&jsonBody), check.IsNil, check.Commentf(s.action))
+		c.Check(jsonBody, check.DeepEquals, map[string]interface{}{
+			"action":      s.action,
+			"snaps":       []interface{}{pkgName},
+			"transaction": "all-snaps",
+		}, check.Commentf(s.action))
+	}
+
+	// other options are still not supported
+	_, err := cs.cli.InstallMany([]string{pkgName}, &client.SnapOptions{Transaction: client.TransactionAllSnaps, Channel: "edge"})
+	c.Assert(err, check.ErrorMatches, "cannot use options for multi-action")
+}
+
+func (cs *clientSuite) TestClientHoldRefreshes(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+		"change": "d728",
+		"status-code": 202,
+		"type": "async"
+	}`
+	until := time.Date(2021, 3, 4, 5, 6, 0, 0, time.UTC)
+	for _, t := range []struct {
+		until    time.Time
+		snaps    []string
+		expected map[string]interface{}
+	}{
+		{time.Time{}, nil, map[string]interface{}{"action": "hold", "time": "forever"}},
+		{until, []string{pkgName}, map[string]interface{}{"action": "hold", "time": "2021-03-04T05:06:00Z", "snaps": []interface{}{pkgName}}},
+	} {
+		id, err := cs.cli.HoldRefreshes(t.snaps, t.until)
+		c.Assert(err, check.IsNil)
+		c.Check(id, check.Equals, "d728")
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps")
+
+		var jsonBody map[string]interface{}
+		c.Assert(json.NewDecoder(cs.req.Body).Decode(&jsonBody), check.IsNil)
+		c.Check(jsonBody, check.DeepEquals, t.expected)
+	}
+
+	id, err := cs.cli.UnholdRefreshes([]string{pkgName})
+	c.Assert(err, check.IsNil)
+	c.Check(id, check.Equals, "d728")
+	var jsonBody map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&jsonBody), check.IsNil)
+	c.Check(jsonBody, check.DeepEquals, map[string]interface{}{"action": "unhold", "snaps": []interface{}{pkgName}})
+}
+
 func (cs *clientSuite) TestClientMultiSnapshot(c *check.C) {
 	// Note body is essentially the same as TestClientMultiOpSnap; keep in sync
 	cs.status = 202
@@ -26,6 +26,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net/url"
 	"sort"
@@ -33,6 +34,8 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/sha3"
+
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -249,7 +252,36 @@ func (client *Client) SnapshotExport(setID uint64) (stream io.ReadCloser, conten
 		return nil, 0, fmt.Errorf("unexpected snapshot export content type %q", contentType)
 	}
 
-	return rsp.Body, rsp.ContentLength, nil
+	stream = rsp.Body
+	// older snapd do not send the digest of the export
+	if digest := rsp.Header.Get("Snapshot-Sha3-384"); digest != "" {
+		stream = &digestCheckingReadCloser{
+			ReadCloser: rsp.Body,
+			h:          sha3.New384(),
+			expected:   digest,
+		}
+	}
+
+	return stream, rsp.ContentLength, nil
+}
+
+// digestCheckingReadCloser checks that the SHA3-384 digest of the data read
+// matches the expected one once the end of the stream is reached.
+type digestCheckingReadCloser struct {
+	io.ReadCloser
+	h        hash.Hash
+	expected string
+}
+
+func (r *digestCheckingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if actual := fmt.Sprintf("%x", r.h.Sum(nil)); actual != r.expected {
+			return n, fmt.Errorf("snapshot export digest mismatch: expected %s, got %s", r.expected, actual)
+		}
+	}
+	return n, err
 }
 
 // SnapshotImportSet is a snapshot import created by a "snap import-snapshot".
@@ -260,10 +292,20 @@ type SnapshotImportSet struct {
 
 // SnapshotImport imports an exported snapshot set.
 func (client *Client) SnapshotImport(exportStream io.Reader, size int64) (SnapshotImportSet, error) {
+	return client.SnapshotImportWithDigest(exportStream, size, "")
+}
+
+// SnapshotImportWithDigest imports an exported snapshot set, snapd
+// verifies that the hex encoded SHA3-384 digest of the stream matches
+// the given one, if any, before committing the import.
+func (client *Client) SnapshotImportWithDigest(exportStream io.Reader, size int64, sha3_384 string) (SnapshotImportSet, error) {
 	headers := map[string]string{
 		"Content-Type":   SnapshotExportMediaType,
 		"Content-Length": strconv.FormatInt(size, 10),
 	}
+	if sha3_384 != "" {
+		headers["Snapshot-Sha3-384"] = sha3_384
+	}
 
 	var importSet SnapshotImportSet
 	if _, err := client.doSync("POST", "/v2/snapshots", nil, headers, exportStream, &importSet); err != nil {
@@ -21,6 +21,7 @@ package client_test
 
 import (
 	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -28,6 +29,7 @@ import (
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/sha3"
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
@@ -182,6 +184,57 @@ func (cs *clientSuite) TestClientExportSnapshot(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientExportSnapshotDigest(c *check.C) {
+	content := "dummy-export"
+	h := sha3.New384()
+	h.Write([]byte(content))
+	digest := fmt.Sprintf("%x", h.Sum(nil))
+
+	for _, t := range []struct {
+		digest string
+		err    string
+	}{
+		{digest, ""},
+		{"bad-digest", fmt.Sprintf("snapshot export digest mismatch: expected bad-digest, got %s", digest)},
+	} {
+		cs.contentLength = int64(len(content))
+		cs.header = http.Header{
+			"Content-Type":      []string{client.SnapshotExportMediaType},
+			"Snapshot-Sha3-384": []string{t.digest},
+		}
+		cs.rsp = content
+		cs.status = 200
+
+		r, size, err := cs.cli.SnapshotExport(42)
+		c.Assert(err, check.IsNil)
+		c.Check(size, check.Equals, int64(len(content)))
+
+		buf, err := ioutil.ReadAll(r)
+		if t.err == "" {
+			c.Assert(err, check.IsNil)
+			c.Check(string(buf), check.Equals, content)
+		} else {
+			c.Check(err, check.ErrorMatches, t.err)
+		}
+		c.Check(r.Close(), check.IsNil)
+	}
+}
+
+func (cs *clientSuite) TestClientSnapshotImportWithDigest(c *check.C) {
+	cs.status = 200
+	cs.rsp = `{"type": "sync", "result": {"set-id": 42, "snaps": ["baz", "bar", "foo"]}}`
+
+	fakeSnapshotData := "fake"
+	r := strings.NewReader(fakeSnapshotData)
+	importSet, err := cs.cli.SnapshotImportWithDigest(r, int64(len(fakeSnapshotData)), "digest")
+	c.Assert(err, check.IsNil)
+	c.Check(importSet, check.DeepEquals, client.SnapshotImportSet{ID: 42, Snaps: []string{"baz", "bar", "foo"}})
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+	c.Check(cs.req.Header.Get("Content-Type"), check.Equals, client.SnapshotExportMediaType)
+	c.Check(cs.req.Header.Get("Snapshot-Sha3-384"), check.Equals, "digest")
+}
+
 func (cs *clientSuite) TestClientSnapshotImport(c *check.C) {
 	type tableT struct {
 		rsp    string
@@ -138,3 +138,32 @@ func (client *Client) RebootToSystem(systemLabel, mode string) error {
 	}
 	return nil
 }
+
+// CreateRecoverySystem requests a new recovery system with the given label to
+// be created from the currently installed snaps. When testSystem is set, the
+// device reboots to try the new system before it is considered a good one.
+func (client *Client) CreateRecoverySystem(label string, testSystem bool) (changeID string, err error) {
+	if label == "" {
+		return "", fmt.Errorf("cannot create a recovery system without a label")
+	}
+
+	req := struct {
+		Action     string `json:"action"`
+		Label      string `json:"label"`
+		TestSystem bool   `json:"test-system,omitempty"`
+	}{
+		Action:     "create",
+		Label:      label,
+		TestSystem: testSystem,
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(&req); err != nil {
+		return "", err
+	}
+	changeID, err = client.doAsync("POST", "/v2/systems", nil, nil, &body)
+	if err != nil {
+		return "", xerrors.Errorf("cannot create recovery system %q: %v", label, err)
+	}
+	return changeID, nil
+}
@@ -215,3 +215,41 @@ func (cs *clientSuite) TestRequestSystemRebootErrorWithSystem(c *check.C) {
 	c.Check(cs.req.Method, check.Equals, "POST")
 	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems/1234")
 }
+
+func (cs *clientSuite) TestCreateRecoverySystemHappy(c *check.C) {
+	cs.status = 202
+	cs.rsp = `{
+	    "type": "async",
+	    "status-code": 202,
+	    "change": "42"
+	}`
+	chgID, err := cs.cli.CreateRecoverySystem("1234", true)
+	c.Assert(err, check.IsNil)
+	c.Check(chgID, check.Equals, "42")
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/systems")
+
+	body, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	var req map[string]interface{}
+	err = json.Unmarshal(body, &req)
+	c.Assert(err, check.IsNil)
+	c.Assert(req, check.DeepEquals, map[string]interface{}{
+		"action":      "create",
+		"label":       "1234",
+		"test-system": true,
+	})
+}
+
+func (cs *clientSuite) TestCreateRecoverySystemError(c *check.C) {
+	cs.rsp = `{
+	    "type": "error",
+	    "status-code": 409,
+	    "result": {"message": "creating recovery system in progress"}
+	}`
+	_, err := cs.cli.CreateRecoverySystem("1234", false)
+	c.Assert(err, check.ErrorMatches, `cannot create recovery system "1234": creating recovery system in progress`)
+
+	_, err = cs.cli.CreateRecoverySystem("", false)
+	c.Assert(err, check.ErrorMatches, "cannot create a recovery system without a label")
+}
@@ -44,7 +44,17 @@ type ValidationSetResult struct {
 	// set current state
 	Mode  string `json:"mode"`
 	Valid bool   `json:"valid"`
-	// TODO: flags/states for notes column
+	// Violations reports the snaps not satisfying the validation set.
+	Violations *ValidationSetViolations `json:"violations,omitempty"`
+}
+
+// ValidationSetViolations describes the installed snaps which violate a
+// validation set, or the snaps missing to satisfy it.
+type ValidationSetViolations struct {
+	MissingSnaps []string `json:"missing-snaps,omitempty"`
+	InvalidSnaps []string `json:"invalid-snaps,omitempty"`
+	// WrongRevisionSnaps maps snap names to the required revision.
+	WrongRevisionSnaps map[string]string `json:"wrong-revision-snaps,omitempty"`
 }
 
 type postValidationSetData struct {
@@ -64,7 +64,8 @@ func (cs *clientSuite) TestListValidationsSets(c *check.C) {
 		"status-code": 200,
 		"result": [
 			{"account-id": "abc", "name": "def", "mode": "monitor", "sequence": 0},
-			{"account-id": "ghi", "name": "jkl", "mode": "enforce", "sequence": 2}
+			{"account-id": "ghi", "name": "jkl", "mode": "enforce", "sequence": 2},
+			{"account-id": "mno", "name": "pqr", "mode": "enforce", "sequence": 1, "violations": {"missing-snaps": ["foo"], "invalid-snaps": ["bar"], "wrong-revision-snaps": {"baz": "3"}}}
 		]
 	}`
 
@@ -75,6 +76,11 @@ func (cs *clientSuite) TestListValidationsSets(c *check.C) {
 	c.Check(vsets, check.DeepEquals, []*client.ValidationSetResult{
 		{AccountID: "abc", Name: "def", Mode: "monitor", Sequence: 0, Valid: false},
 		{AccountID: "ghi", Name: "jkl", Mode: "enforce", Sequence: 2, Valid: false},
+		{AccountID: "mno", Name: "pqr", Mode: "enforce", Sequence: 1, Valid: false, Violations: &client.ValidationSetViolations{
+			MissingSnaps:       []string{"foo"},
+			InvalidSnaps:       []string{"bar"},
+			WrongRevisionSnaps: map[string]string{"baz": "3"},
+		}},
 	})
 }
 
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#include "bpf-support.h"
+
+#include <errno.h>
+#include <stdint.h>
+#include <string.h>
+#include <sys/syscall.h>
+#include <unistd.h>
+
+static int sys_bpf(enum bpf_cmd cmd, union bpf_attr *attr, size_t size) {
+#ifdef SYS_bpf
+    return syscall(SYS_bpf, cmd, attr, size);
+#else
+    errno = ENOSYS;
+    return -1;
+#endif
+}
+
+static uint64_t ptr_to_u64(const void *ptr) { return (uint64_t)(uintptr_t)ptr; }
+
+int bpf_pin_to_path(int fd, const char *path) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.bpf_fd = fd;
+    attr.pathname = ptr_to_u64(path);
+    return sys_bpf(BPF_OBJ_PIN, &attr, sizeof(attr));
+}
+
+int bpf_get_by_path(const char *path) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.pathname = ptr_to_u64(path);
+    return sys_bpf(BPF_OBJ_GET, &attr, sizeof(attr));
+}
+
+int bpf_load_prog(enum bpf_prog_type type, const struct bpf_insn *insns, size_t insns_cnt, char *log_buf,
+                  size_t log_buf_size) {
+    if (insns_cnt == 0) {
+        errno = EINVAL;
+        return -1;
+    }
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.prog_type = type;
+    attr.insns = ptr_to_u64(insns);
+    attr.insn_cnt = (uint32_t)insns_cnt;
+    attr.license = ptr_to_u64("GPL");
+    if (log_buf != NULL && log_buf_size > 0) {
+        attr.log_buf = ptr_to_u64(log_buf);
+        attr.log_size = (uint32_t)log_buf_size;
+        attr.log_level = 1;
+    }
+    return sys_bpf(BPF_PROG_LOAD, &attr, sizeof(attr));
+}
+
+int bpf_prog_attach(enum bpf_attach_type type, int cgroup_fd, int prog_fd) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.attach_type = type;
+    attr.target_fd = cgroup_fd;
+    attr.attach_bpf_fd = prog_fd;
+    return sys_bpf(BPF_PROG_ATTACH, &attr, sizeof(attr));
+}
+
+int bpf_create_map(enum bpf_map_type type, size_t key_size, size_t value_size, size_t max_entries) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.map_type = type;
+    attr.key_size = (uint32_t)key_size;
+    attr.value_size = (uint32_t)value_size;
+    attr.max_entries = (uint32_t)max_entries;
+    return sys_bpf(BPF_MAP_CREATE, &attr, sizeof(attr));
+}
+
+int bpf_update_map(int map_fd, const void *key, const void *value) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.map_fd = map_fd;
+    attr.key = ptr_to_u64(key);
+    attr.value = ptr_to_u64(value);
+    attr.flags = BPF_ANY;
+    return sys_bpf(BPF_MAP_UPDATE_ELEM, &attr, sizeof(attr));
+}
+
+int bpf_map_delete_elem(int map_fd, const void *key) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.map_fd = map_fd;
+    attr.key = ptr_to_u64(key);
+    return sys_bpf(BPF_MAP_DELETE_ELEM, &attr, sizeof(attr));
+}
+
+int bpf_map_get_next_key(int map_fd, const void *key, void *next_key) {
+    union bpf_attr attr;
+    memset(&attr, 0, sizeof(attr));
+    attr.map_fd = map_fd;
+    attr.key = ptr_to_u64(key);
+    attr.next_key = ptr_to_u64(next_key);
+    return sys_bpf(BPF_MAP_GET_NEXT_KEY, &attr, sizeof(attr));
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#ifndef SNAP_CONFINE_BPF_SUPPORT_H
+#define SNAP_CONFINE_BPF_SUPPORT_H
+
+#include <linux/bpf.h>
+#include <stddef.h>
+
+/**
+ * bpf_pin_to_path pins an object referenced by fd to a path under a bpffs
+ * mount.
+ **/
+int bpf_pin_to_path(int fd, const char *path);
+
+/**
+ * bpf_get_by_path obtains the file descriptor of an object pinned at a path
+ * under a bpffs mount.
+ **/
+int bpf_get_by_path(const char *path);
+
+/**
+ * bpf_load_prog loads a given BPF program.
+ **/
+int bpf_load_prog(enum bpf_prog_type type, const struct bpf_insn *insns, size_t insns_cnt, char *log_buf,
+                  size_t log_buf_size);
+
+/**
+ * bpf_prog_attach attaches a given BPF program to a cgroup.
+ **/
+int bpf_prog_attach(enum bpf_attach_type type, int cgroup_fd, int prog_fd);
+
+/**
+ * bpf_create_map creates a BPF map of a given type, key and value sizes and
+ * the maximum number of entries.
+ **/
+int bpf_create_map(enum bpf_map_type type, size_t key_size, size_t value_size, size_t max_entries);
+
+/**
+ * bpf_update_map updates the value of an element with a given key (or adds a
+ * new one) in a map.
+ **/
+int bpf_update_map(int map_fd, const void *key, const void *value);
+
+/**
+ * bpf_map_delete_elem removes the element with a given key from a map.
+ **/
+int bpf_map_delete_elem(int map_fd, const void *key);
+
+/**
+ * bpf_map_get_next_key obtains the key of the element that follows the
+ * element with a given key, or the first key when key is NULL. Returns -1 with
+ * errno set to ENOENT when there are no more keys.
+ **/
+int bpf_map_get_next_key(int map_fd, const void *key, void *next_key);
+
+#endif /* SNAP_CONFINE_BPF_SUPPORT_H */
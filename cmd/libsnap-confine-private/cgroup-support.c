@@ -97,3 +97,45 @@ bool sc_cgroup_is_v2() {
     }
     return false;
 }
+
+static const char *self_cgroup = "/proc/self/cgroup";
+
+char *sc_cgroup_v2_own_path_full(void) {
+    FILE *in SC_CLEANUP(sc_cleanup_file) = fopen(self_cgroup, "r");
+    if (in == NULL) {
+        die("cannot open %s", self_cgroup);
+    }
+
+    char *own_group = NULL;
+
+    while (true) {
+        char *line SC_CLEANUP(sc_cleanup_string) = NULL;
+        size_t linesz = 0;
+        errno = 0;
+        ssize_t sz = getline(&line, &linesz, in);
+        if (sz < 0 && errno != 0) {
+            die("cannot read line from %s", self_cgroup);
+        }
+        if (sz < 0) {
+            // end of file
+            break;
+        }
+        // the entry of the unified hierarchy has the form: 0::/path/to/group
+        if (!sc_startswith(line, "0::")) {
+            continue;
+        }
+        size_t len = strlen(line);
+        if (len <= 3) {
+            die("unexpected content of group entry %s", line);
+        }
+        // \n does not normally appear inside the group path, but if it did,
+        // it would be escaped anyway
+        char *newline = strchr(line, '\n');
+        if (newline != NULL) {
+            *newline = '\0';
+        }
+        own_group = sc_strdup(line + 3);
+        break;
+    }
+    return own_group;
+}
@@ -37,4 +37,14 @@ void sc_cgroup_create_and_join(const char *parent, const char *name, pid_t pid);
  **/
 bool sc_cgroup_is_v2(void);
 
+/**
+ * sc_cgroup_v2_own_path_full return the full path of the owning cgroup as
+ * seen in the unified hierarchy, e.g.
+ * /user.slice/user-1000.slice/user@1000.service/snap.foo.bar.1234.scope
+ *
+ * The returned path must be freed by the caller. NULL is returned when the
+ * process is not part of the unified hierarchy.
+ **/
+char *sc_cgroup_v2_own_path_full(void);
+
 #endif
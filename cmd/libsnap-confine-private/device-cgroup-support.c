@@ -0,0 +1,476 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// For O_PATH
+#define _GNU_SOURCE
+
+#include "device-cgroup-support.h"
+
+#include <errno.h>
+#include <fcntl.h>
+#include <limits.h>
+#include <stdarg.h>
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/resource.h>
+#include <sys/stat.h>
+#include <sys/vfs.h>
+#include <unistd.h>
+
+#include "bpf-support.h"
+#include "cgroup-support.h"
+#include "cleanup-funcs.h"
+#include "string-utils.h"
+#include "utils.h"
+
+typedef struct sc_cgroup_v1_fds {
+    int devices_allow_fd;
+    int devices_deny_fd;
+    int cgroup_procs_fd;
+} sc_cgroup_v1_fds;
+
+typedef struct sc_cgroup_v2_map_key {
+    uint32_t type;
+    uint32_t major;
+    uint32_t minor;
+} sc_cgroup_v2_map_key;
+
+typedef uint8_t sc_cgroup_v2_map_value;
+
+struct sc_device_cgroup {
+    bool is_v2;
+    char *security_tag;
+    union {
+        sc_cgroup_v1_fds v1;
+        struct {
+            int map_fd;
+            int prog_fd;
+        } v2;
+    };
+};
+
+__attribute__((format(printf, 2, 3))) static void sc_dprintf(int fd, const char *format, ...) {
+    va_list ap1;
+    va_list ap2;
+    int n_expected, n_actual;
+
+    va_start(ap1, format);
+    va_copy(ap2, ap1);
+    n_expected = vsnprintf(NULL, 0, format, ap2);
+    n_actual = vdprintf(fd, format, ap1);
+    if (n_actual == -1 || n_expected != n_actual) {
+        die("cannot write to fd %d", fd);
+    }
+    va_end(ap2);
+    va_end(ap1);
+}
+
+static const char *cgroup_dir = "/sys/fs/cgroup";
+static const char *devices_relpath = "devices";
+
+static int sc_device_cgroup_v1_init(sc_device_cgroup *self, int flags) {
+    self->v1.devices_allow_fd = -1;
+    self->v1.devices_deny_fd = -1;
+    self->v1.cgroup_procs_fd = -1;
+
+    bool from_existing = (flags & SC_DEVICE_CGROUP_FROM_EXISTING) != 0;
+
+    /* Open /sys/fs/cgroup */
+    int SC_CLEANUP(sc_cleanup_close) cgroup_fd = -1;
+    cgroup_fd = open(cgroup_dir, O_PATH | O_DIRECTORY | O_CLOEXEC | O_NOFOLLOW);
+    if (cgroup_fd < 0) {
+        die("cannot open %s", cgroup_dir);
+    }
+
+    /* Open devices relative to /sys/fs/cgroup */
+    int SC_CLEANUP(sc_cleanup_close) devices_fd = -1;
+    devices_fd = openat(cgroup_fd, devices_relpath, O_PATH | O_DIRECTORY | O_CLOEXEC | O_NOFOLLOW);
+    if (devices_fd < 0) {
+        die("cannot open %s/%s", cgroup_dir, devices_relpath);
+    }
+
+    /* Open snap.$SNAP_NAME.$APP_NAME relative to /sys/fs/cgroup/devices,
+     * creating the directory if necessary. Note that we always chown the
+     * resulting directory to root:root. */
+    const char *security_tag_relpath = self->security_tag;
+    if (!from_existing) {
+        sc_identity old = sc_set_effective_identity(sc_root_group_identity());
+        if (mkdirat(devices_fd, security_tag_relpath, 0755) < 0 && errno != EEXIST) {
+            die("cannot create directory %s/%s/%s", cgroup_dir, devices_relpath, security_tag_relpath);
+        }
+        (void)sc_set_effective_identity(old);
+    }
+
+    int SC_CLEANUP(sc_cleanup_close) security_tag_fd = -1;
+    security_tag_fd = openat(devices_fd, security_tag_relpath, O_RDONLY | O_DIRECTORY | O_CLOEXEC | O_NOFOLLOW);
+    if (security_tag_fd < 0) {
+        if (from_existing && errno == ENOENT) {
+            return -1;
+        }
+        die("cannot open %s/%s/%s", cgroup_dir, devices_relpath, security_tag_relpath);
+    }
+
+    /* Open devices.allow relative to /sys/fs/cgroup/devices/snap.$SNAP_NAME.$APP_NAME */
+    const char *devices_allow_relpath = "devices.allow";
+    int SC_CLEANUP(sc_cleanup_close) devices_allow_fd = -1;
+    devices_allow_fd = openat(security_tag_fd, devices_allow_relpath, O_WRONLY | O_CLOEXEC | O_NOFOLLOW);
+    if (devices_allow_fd < 0) {
+        die("cannot open %s/%s/%s/%s", cgroup_dir, devices_relpath, security_tag_relpath, devices_allow_relpath);
+    }
+
+    /* Open devices.deny relative to /sys/fs/cgroup/devices/snap.$SNAP_NAME.$APP_NAME */
+    const char *devices_deny_relpath = "devices.deny";
+    int SC_CLEANUP(sc_cleanup_close) devices_deny_fd = -1;
+    devices_deny_fd = openat(security_tag_fd, devices_deny_relpath, O_WRONLY | O_CLOEXEC | O_NOFOLLOW);
+    if (devices_deny_fd < 0) {
+        die("cannot open %s/%s/%s/%s", cgroup_dir, devices_relpath, security_tag_relpath, devices_deny_relpath);
+    }
+
+    /* Open cgroup.procs relative to /sys/fs/cgroup/devices/snap.$SNAP_NAME.$APP_NAME */
+    const char *cgroup_procs_relpath = "cgroup.procs";
+    int SC_CLEANUP(sc_cleanup_close) cgroup_procs_fd = -1;
+    cgroup_procs_fd = openat(security_tag_fd, cgroup_procs_relpath, O_WRONLY | O_CLOEXEC | O_NOFOLLOW);
+    if (cgroup_procs_fd < 0) {
+        die("cannot open %s/%s/%s/%s", cgroup_dir, devices_relpath, security_tag_relpath, cgroup_procs_relpath);
+    }
+
+    if (!from_existing) {
+        /* Deny device access by default.
+         *
+         * Write 'a' to devices.deny to remove all existing devices that were
+         * added in previous launcher invocations, so that the group only has
+         * what is allowed from now on. */
+        sc_dprintf(devices_deny_fd, "a");
+    }
+
+    /* Everything worked so pack the result and "move" the descriptors over so
+     * that they are not closed by the cleanup functions associated with the
+     * individual variables. */
+    self->v1.devices_allow_fd = devices_allow_fd;
+    self->v1.devices_deny_fd = devices_deny_fd;
+    self->v1.cgroup_procs_fd = cgroup_procs_fd;
+    devices_allow_fd = -1;
+    devices_deny_fd = -1;
+    cgroup_procs_fd = -1;
+    return 0;
+}
+
+static void sc_device_cgroup_v1_close(sc_device_cgroup *self) {
+    sc_cleanup_close(&self->v1.devices_allow_fd);
+    sc_cleanup_close(&self->v1.devices_deny_fd);
+    sc_cleanup_close(&self->v1.cgroup_procs_fd);
+}
+
+static void sc_device_cgroup_v1_write(int fd, int kind, uint32_t major, uint32_t minor) {
+    char type = (kind == S_IFBLK) ? 'b' : 'c';
+    if (minor == SC_DEVICE_MINOR_ANY) {
+        sc_dprintf(fd, "%c %u:* rwm\n", type, major);
+    } else {
+        sc_dprintf(fd, "%c %u:%u rwm\n", type, major, minor);
+    }
+}
+
+static const char *bpf_dir = "/sys/fs/bpf";
+static const char *bpf_snap_dir = "/sys/fs/bpf/snap";
+
+// from statfs(2)
+#ifndef BPF_FS_MAGIC
+#define BPF_FS_MAGIC 0xcafe4a11
+#endif
+
+/* The maximum number of devices that can be allowed for a single snap
+ * application or hook. */
+static const size_t max_devices = 500;
+
+/* Minimal set of BPF instruction helpers, as found in the kernel sources. */
+#define BPF_RAW_INSN(CODE, DST, SRC, OFF, IMM) \
+    ((struct bpf_insn){.code = (CODE), .dst_reg = (DST), .src_reg = (SRC), .off = (OFF), .imm = (IMM)})
+#define BPF_MOV64_REG(DST, SRC) BPF_RAW_INSN(BPF_ALU64 | BPF_MOV | BPF_X, DST, SRC, 0, 0)
+#define BPF_MOV64_IMM(DST, IMM) BPF_RAW_INSN(BPF_ALU64 | BPF_MOV | BPF_K, DST, 0, 0, IMM)
+#define BPF_ALU32_IMM(OP, DST, IMM) BPF_RAW_INSN(BPF_ALU | BPF_OP(OP) | BPF_K, DST, 0, 0, IMM)
+#define BPF_ALU64_IMM(OP, DST, IMM) BPF_RAW_INSN(BPF_ALU64 | BPF_OP(OP) | BPF_K, DST, 0, 0, IMM)
+#define BPF_LDX_MEM(SIZE, DST, SRC, OFF) BPF_RAW_INSN(BPF_LDX | BPF_SIZE(SIZE) | BPF_MEM, DST, SRC, OFF, 0)
+#define BPF_STX_MEM(SIZE, DST, SRC, OFF) BPF_RAW_INSN(BPF_STX | BPF_SIZE(SIZE) | BPF_MEM, DST, SRC, OFF, 0)
+#define BPF_ST_MEM(SIZE, DST, OFF, IMM) BPF_RAW_INSN(BPF_ST | BPF_SIZE(SIZE) | BPF_MEM, DST, 0, OFF, IMM)
+#define BPF_JMP_IMM(OP, DST, IMM, OFF) BPF_RAW_INSN(BPF_JMP | BPF_OP(OP) | BPF_K, DST, 0, OFF, IMM)
+#define BPF_LD_MAP_FD(DST, MAP_FD)                                           \
+    BPF_RAW_INSN(BPF_LD | BPF_DW | BPF_IMM, DST, BPF_PSEUDO_MAP_FD, 0, MAP_FD), \
+        BPF_RAW_INSN(0, 0, 0, 0, 0)
+#define BPF_EMIT_CALL(FUNC) BPF_RAW_INSN(BPF_JMP | BPF_CALL, 0, 0, 0, FUNC)
+#define BPF_EXIT_INSN() BPF_RAW_INSN(BPF_JMP | BPF_EXIT, 0, 0, 0, 0)
+
+static int sc_device_cgroup_v2_load_prog(int map_fd) {
+    /* The program looks up the device being accessed in the map of allowed
+     * devices, first by its exact major and minor numbers, then by its major
+     * number with any minor number. The map key is stored on the stack. */
+    const int key_off = -(int)sizeof(sc_cgroup_v2_map_key);
+    struct bpf_insn prog[] = {
+        /* r6 = ctx, a struct bpf_cgroup_dev_ctx */
+        BPF_MOV64_REG(BPF_REG_6, BPF_REG_1),
+        /* key.type = ctx->access_type & 0xffff */
+        BPF_LDX_MEM(BPF_W, BPF_REG_2, BPF_REG_6, offsetof(struct bpf_cgroup_dev_ctx, access_type)),
+        BPF_ALU32_IMM(BPF_AND, BPF_REG_2, 0xffff),
+        BPF_STX_MEM(BPF_W, BPF_REG_10, BPF_REG_2, key_off + (int)offsetof(sc_cgroup_v2_map_key, type)),
+        /* key.major = ctx->major */
+        BPF_LDX_MEM(BPF_W, BPF_REG_3, BPF_REG_6, offsetof(struct bpf_cgroup_dev_ctx, major)),
+        BPF_STX_MEM(BPF_W, BPF_REG_10, BPF_REG_3, key_off + (int)offsetof(sc_cgroup_v2_map_key, major)),
+        /* key.minor = ctx->minor */
+        BPF_LDX_MEM(BPF_W, BPF_REG_4, BPF_REG_6, offsetof(struct bpf_cgroup_dev_ctx, minor)),
+        BPF_STX_MEM(BPF_W, BPF_REG_10, BPF_REG_4, key_off + (int)offsetof(sc_cgroup_v2_map_key, minor)),
+        /* r0 = bpf_map_lookup_elem(map, &key) */
+        BPF_LD_MAP_FD(BPF_REG_1, map_fd),
+        BPF_MOV64_REG(BPF_REG_2, BPF_REG_10),
+        BPF_ALU64_IMM(BPF_ADD, BPF_REG_2, key_off),
+        BPF_EMIT_CALL(BPF_FUNC_map_lookup_elem),
+        /* found, allow access */
+        BPF_JMP_IMM(BPF_JEQ, BPF_REG_0, 0, 2),
+        BPF_MOV64_IMM(BPF_REG_0, 1),
+        BPF_EXIT_INSN(),
+        /* key.minor = SC_DEVICE_MINOR_ANY */
+        BPF_ST_MEM(BPF_W, BPF_REG_10, key_off + (int)offsetof(sc_cgroup_v2_map_key, minor), -1),
+        /* r0 = bpf_map_lookup_elem(map, &key) */
+        BPF_LD_MAP_FD(BPF_REG_1, map_fd),
+        BPF_MOV64_REG(BPF_REG_2, BPF_REG_10),
+        BPF_ALU64_IMM(BPF_ADD, BPF_REG_2, key_off),
+        BPF_EMIT_CALL(BPF_FUNC_map_lookup_elem),
+        /* found, allow access */
+        BPF_JMP_IMM(BPF_JEQ, BPF_REG_0, 0, 2),
+        BPF_MOV64_IMM(BPF_REG_0, 1),
+        BPF_EXIT_INSN(),
+        /* not found, deny access */
+        BPF_MOV64_IMM(BPF_REG_0, 0),
+        BPF_EXIT_INSN(),
+    };
+
+    char log_buf[4096] = {0};
+    int prog_fd = bpf_load_prog(BPF_PROG_TYPE_CGROUP_DEVICE, prog, sizeof(prog) / sizeof(prog[0]), log_buf,
+                                sizeof(log_buf));
+    if (prog_fd < 0) {
+        die("cannot load device cgroup program:\n%s", log_buf);
+    }
+    return prog_fd;
+}
+
+static char *sc_device_cgroup_v2_map_path(const char *security_tag) {
+    /* Use the same name as the udev tag, where dots are replaced with
+     * underscores, to keep the path of the pinned map simple. */
+    char *tag = sc_strdup(security_tag);
+    for (char *c = strchr(tag, '.'); c != NULL; c = strchr(c, '.')) {
+        *c = '_';
+    }
+    char path[PATH_MAX] = {0};
+    sc_must_snprintf(path, sizeof path, "%s/%s", bpf_snap_dir, tag);
+    free(tag);
+    return sc_strdup(path);
+}
+
+static void sc_device_cgroup_v2_clear_map(int map_fd) {
+    sc_cgroup_v2_map_key key;
+    /* Removing an element invalidates the iteration, start over from the
+     * first key each time. */
+    while (bpf_map_get_next_key(map_fd, NULL, &key) == 0) {
+        if (bpf_map_delete_elem(map_fd, &key) < 0) {
+            die("cannot remove device cgroup map entry");
+        }
+    }
+    if (errno != ENOENT) {
+        die("cannot iterate over device cgroup map entries");
+    }
+}
+
+static int sc_device_cgroup_v2_init(sc_device_cgroup *self, int flags) {
+    self->v2.map_fd = -1;
+    self->v2.prog_fd = -1;
+
+    bool from_existing = (flags & SC_DEVICE_CGROUP_FROM_EXISTING) != 0;
+
+    struct statfs buf;
+    if (statfs(bpf_dir, &buf) != 0 || buf.f_type != BPF_FS_MAGIC) {
+        if (from_existing) {
+            errno = ENOENT;
+            return -1;
+        }
+        die("cannot use device cgroup: bpffs is not mounted at %s", bpf_dir);
+    }
+
+    char *map_path SC_CLEANUP(sc_cleanup_string) = sc_device_cgroup_v2_map_path(self->security_tag);
+
+    /* Older kernels account the memory of BPF objects against the memlock
+     * limit, which is very low by default. */
+    struct rlimit old_limit;
+    if (!from_existing) {
+        if (getrlimit(RLIMIT_MEMLOCK, &old_limit) < 0) {
+            die("cannot obtain the memlock limit");
+        }
+        struct rlimit limit = {.rlim_cur = RLIM_INFINITY, .rlim_max = RLIM_INFINITY};
+        if (setrlimit(RLIMIT_MEMLOCK, &limit) < 0) {
+            die("cannot raise the memlock limit");
+        }
+    }
+
+    int SC_CLEANUP(sc_cleanup_close) map_fd = bpf_get_by_path(map_path);
+    if (map_fd < 0) {
+        if (errno != ENOENT) {
+            die("cannot open device cgroup map %s", map_path);
+        }
+        if (from_existing) {
+            return -1;
+        }
+        /* Make sure the directory for the pinned maps exists. */
+        if (mkdir(bpf_snap_dir, 0700) < 0 && errno != EEXIST) {
+            die("cannot create directory %s", bpf_snap_dir);
+        }
+        map_fd = bpf_create_map(BPF_MAP_TYPE_HASH, sizeof(sc_cgroup_v2_map_key), sizeof(sc_cgroup_v2_map_value),
+                                max_devices);
+        if (map_fd < 0) {
+            die("cannot create device cgroup map");
+        }
+        if (bpf_pin_to_path(map_fd, map_path) < 0) {
+            die("cannot pin device cgroup map to %s", map_path);
+        }
+    } else if (!from_existing) {
+        /* The map is shared with the running processes of the same snap
+         * application or hook, start from scratch, like with cgroup v1. */
+        sc_device_cgroup_v2_clear_map(map_fd);
+    }
+
+    if (!from_existing) {
+        self->v2.prog_fd = sc_device_cgroup_v2_load_prog(map_fd);
+        if (setrlimit(RLIMIT_MEMLOCK, &old_limit) < 0) {
+            die("cannot restore the memlock limit");
+        }
+    }
+
+    self->v2.map_fd = map_fd;
+    map_fd = -1;
+    return 0;
+}
+
+static void sc_device_cgroup_v2_close(sc_device_cgroup *self) {
+    sc_cleanup_close(&self->v2.map_fd);
+    sc_cleanup_close(&self->v2.prog_fd);
+}
+
+static sc_cgroup_v2_map_key sc_device_cgroup_v2_key(int kind, uint32_t major, uint32_t minor) {
+    sc_cgroup_v2_map_key key = {
+        .type = (kind == S_IFBLK) ? BPF_DEVCG_DEV_BLOCK : BPF_DEVCG_DEV_CHAR,
+        .major = major,
+        .minor = minor,
+    };
+    return key;
+}
+
+static int sc_device_cgroup_v2_attach_pid(sc_device_cgroup *self, pid_t pid) {
+    if (pid != getpid()) {
+        die("cannot attach device cgroup to process %d, only the calling process is supported", pid);
+    }
+    if (self->v2.prog_fd < 0) {
+        die("cannot attach device cgroup set up from an existing one");
+    }
+    char *own_group SC_CLEANUP(sc_cleanup_string) = sc_cgroup_v2_own_path_full();
+    if (own_group == NULL) {
+        die("cannot obtain own cgroup v2 group path");
+    }
+    char path[PATH_MAX] = {0};
+    sc_must_snprintf(path, sizeof path, "%s%s", cgroup_dir, own_group);
+    int SC_CLEANUP(sc_cleanup_close) cgroup_fd = open(path, O_PATH | O_DIRECTORY | O_CLOEXEC | O_NOFOLLOW);
+    if (cgroup_fd < 0) {
+        die("cannot open %s", path);
+    }
+    if (bpf_prog_attach(BPF_CGROUP_DEVICE, cgroup_fd, self->v2.prog_fd) < 0) {
+        die("cannot attach device cgroup program to %s", path);
+    }
+    return 0;
+}
+
+sc_device_cgroup *sc_device_cgroup_new(const char *security_tag, int flags) {
+    sc_device_cgroup *self = calloc(1, sizeof(sc_device_cgroup));
+    if (self == NULL) {
+        die("cannot allocate device cgroup");
+    }
+    self->is_v2 = sc_cgroup_is_v2();
+    self->security_tag = sc_strdup(security_tag);
+
+    int ret;
+    if (self->is_v2) {
+        ret = sc_device_cgroup_v2_init(self, flags);
+    } else {
+        ret = sc_device_cgroup_v1_init(self, flags);
+    }
+    if (ret < 0) {
+        sc_device_cgroup_cleanup(&self);
+        errno = ENOENT;
+        return NULL;
+    }
+    return self;
+}
+
+void sc_device_cgroup_cleanup(sc_device_cgroup **self) {
+    if (self == NULL || *self == NULL) {
+        return;
+    }
+    if ((*self)->is_v2) {
+        sc_device_cgroup_v2_close(*self);
+    } else {
+        sc_device_cgroup_v1_close(*self);
+    }
+    sc_cleanup_string(&(*self)->security_tag);
+    free(*self);
+    *self = NULL;
+}
+
+int sc_device_cgroup_allow(sc_device_cgroup *self, int kind, uint32_t major, uint32_t minor) {
+    if (kind != S_IFCHR && kind != S_IFBLK) {
+        die("unsupported device kind 0x%04x", kind);
+    }
+    if (!self->is_v2) {
+        sc_device_cgroup_v1_write(self->v1.devices_allow_fd, kind, major, minor);
+        return 0;
+    }
+    sc_cgroup_v2_map_key key = sc_device_cgroup_v2_key(kind, major, minor);
+    sc_cgroup_v2_map_value value = 1;
+    if (bpf_update_map(self->v2.map_fd, &key, &value) < 0) {
+        die("cannot update device cgroup map");
+    }
+    return 0;
+}
+
+int sc_device_cgroup_deny(sc_device_cgroup *self, int kind, uint32_t major, uint32_t minor) {
+    if (kind != S_IFCHR && kind != S_IFBLK) {
+        die("unsupported device kind 0x%04x", kind);
+    }
+    if (!self->is_v2) {
+        sc_device_cgroup_v1_write(self->v1.devices_deny_fd, kind, major, minor);
+        return 0;
+    }
+    sc_cgroup_v2_map_key key = sc_device_cgroup_v2_key(kind, major, minor);
+    if (bpf_map_delete_elem(self->v2.map_fd, &key) < 0 && errno != ENOENT) {
+        die("cannot update device cgroup map");
+    }
+    return 0;
+}
+
+int sc_device_cgroup_attach_pid(sc_device_cgroup *self, pid_t pid) {
+    if (!self->is_v2) {
+        sc_dprintf(self->v1.cgroup_procs_fd, "%i\n", pid);
+        return 0;
+    }
+    return sc_device_cgroup_v2_attach_pid(self, pid);
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#ifndef SNAP_CONFINE_DEVICE_CGROUP_SUPPORT_H
+#define SNAP_CONFINE_DEVICE_CGROUP_SUPPORT_H
+
+#include <stdint.h>
+#include <sys/types.h>
+
+/**
+ * sc_device_cgroup controls the access to devices of the processes of a snap
+ * application or hook.
+ *
+ * With cgroup v1 it is backed by a group in the devices controller
+ * hierarchy, with cgroup v2 it is backed by an eBPF program attached to the
+ * group of the process and a BPF map of allowed devices, pinned under
+ * /sys/fs/bpf/snap.
+ **/
+typedef struct sc_device_cgroup sc_device_cgroup;
+
+typedef enum sc_device_cgroup_options {
+    /**
+     * SC_DEVICE_CGROUP_FROM_EXISTING uses the device cgroup previously set up
+     * for the security tag instead of setting up a new one. When there is
+     * none, sc_device_cgroup_new returns NULL with errno set to ENOENT.
+     **/
+    SC_DEVICE_CGROUP_FROM_EXISTING = 1,
+} sc_device_cgroup_options;
+
+/**
+ * SC_DEVICE_MINOR_ANY matches all minor numbers of a major number.
+ **/
+#define SC_DEVICE_MINOR_ANY UINT32_MAX
+
+/**
+ * sc_device_cgroup_new returns the device cgroup of a security tag. Unless
+ * SC_DEVICE_CGROUP_FROM_EXISTING is set, access to all devices is denied
+ * initially.
+ **/
+sc_device_cgroup *sc_device_cgroup_new(const char *security_tag, int flags);
+
+/**
+ * sc_device_cgroup_cleanup releases the resources of a device cgroup.
+ **/
+void sc_device_cgroup_cleanup(sc_device_cgroup **self);
+
+/**
+ * sc_device_cgroup_allow allows access to a device, kind is either S_IFCHR
+ * or S_IFBLK.
+ **/
+int sc_device_cgroup_allow(sc_device_cgroup *self, int kind, uint32_t major, uint32_t minor);
+
+/**
+ * sc_device_cgroup_deny denies access to a device, kind is either S_IFCHR
+ * or S_IFBLK.
+ **/
+int sc_device_cgroup_deny(sc_device_cgroup *self, int kind, uint32_t major, uint32_t minor);
+
+/**
+ * sc_device_cgroup_attach_pid makes the device cgroup apply to a process.
+ *
+ * With cgroup v2 the device filter is attached to the group of the process,
+ * which must be the calling process and be part of a group specific to the
+ * snap application or hook.
+ **/
+int sc_device_cgroup_attach_pid(sc_device_cgroup *self, pid_t pid);
+
+#endif /* SNAP_CONFINE_DEVICE_CGROUP_SUPPORT_H */
@@ -139,6 +139,8 @@ func generateInitramfsMounts() (err error) {
 		return generateMountsModeRecover(mst)
 	case "install":
 		return generateMountsModeInstall(mst)
+	case "factory-reset":
+		return generateMountsModeFactoryReset(mst)
 	case "run":
 		return generateMountsModeRun(mst)
 	}
@@ -170,6 +172,57 @@ func generateMountsModeInstall(mst *initramfsMountsState) error {
 	return nil
 }
 
+func generateMountsModeFactoryReset(mst *initramfsMountsState) error {
+	// steps 1 and 2 are shared with install and recover modes
+	model, snaps, err := generateMountsCommonInstallRecover(mst)
+	if err != nil {
+		return err
+	}
+
+	// 3. mount ubuntu-save, whose content is kept across the reset
+	disk, err := disks.DiskFromMountPoint(boot.InitramfsUbuntuSeedDir, nil)
+	if err != nil {
+		return err
+	}
+	// the key of an encrypted ubuntu-save is kept on ubuntu-data, which
+	// is what is being reset
+	if _, err := disk.FindMatchingPartitionUUIDWithFsLabel(secboot.EncryptedPartitionName("ubuntu-save")); err == nil {
+		return fmt.Errorf("cannot factory reset a system with an encrypted ubuntu-save")
+	}
+	partUUID, err := disk.FindMatchingPartitionUUIDWithFsLabel("ubuntu-save")
+	switch err.(type) {
+	case nil:
+		saveDevice := filepath.Join("/dev/disk/by-partuuid", partUUID)
+		if err := doSystemdMount(saveDevice, boot.InitramfsUbuntuSaveDir, nil); err != nil {
+			return err
+		}
+	case disks.PartitionNotFoundError:
+		// ubuntu-save is optional
+		logger.Noticef("ubuntu-save was not found")
+	default:
+		return err
+	}
+
+	// 4. the factory reset cannot be rolled back from here on, and is
+	// resumed if the device reboots before it is finished
+	if err := boot.MarkFactoryResetStarted(); err != nil {
+		return err
+	}
+
+	// 5. final step: write modeenv to tmpfs data dir and disable cloud-init
+	modeEnv, err := mst.EphemeralModeenvForModel(model, snaps)
+	if err != nil {
+		return err
+	}
+	if err := modeEnv.WriteTo(boot.InitramfsWritableDir); err != nil {
+		return err
+	}
+
+	// done, no output, no error indicates to initramfs we are done with
+	// mounting stuff
+	return nil
+}
+
 // copyNetworkConfig copies the network configuration to the target
 // directory. This is used to copy the network configuration
 // data from a real uc20 ubuntu-data partition into a ephemeral one.
@@ -1333,6 +1386,37 @@ func maybeMountSave(disk disks.Disk, rootdir string, encrypted bool, mountOpts *
 	return true, nil
 }
 
+// runModeUnlockState is the state of the partitions unlocked in run mode, it
+// uses the same format as degraded.json in recover mode.
+type runModeUnlockState struct {
+	UbuntuData partitionState `json:"ubuntu-data,omitempty"`
+}
+
+// writeRunModeUnlockState leaves the information about which key unlocked
+// ubuntu-data at an ephemeral location, so that snapd can report when the
+// sealed key could not be used.
+func writeRunModeUnlockState(unlockRes secboot.UnlockResult) error {
+	unlockState := runModeUnlockState{
+		UbuntuData: partitionState{
+			UnlockState: partitionUnlocked,
+		},
+	}
+	switch unlockRes.UnlockMethod {
+	case secboot.UnlockedWithSealedKey:
+		unlockState.UbuntuData.UnlockKey = keyRun
+	case secboot.UnlockedWithRecoveryKey:
+		unlockState.UbuntuData.UnlockKey = keyRecovery
+	}
+	b, err := json.Marshal(unlockState)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirs.SnapBootstrapRunDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dirs.SnapBootstrapRunDir, "unlocked.json"), b, 0644)
+}
+
 func generateMountsModeRun(mst *initramfsMountsState) error {
 	// 1. mount ubuntu-boot
 	if err := mountPartitionMatchingKernelDisk(boot.InitramfsUbuntuBootDir, "ubuntu-boot"); err != nil {
@@ -1389,6 +1473,11 @@ func generateMountsModeRun(mst *initramfsMountsState) error {
 	if err != nil {
 		return err
 	}
+	if unlockRes.IsEncrypted {
+		if err := writeRunModeUnlockState(unlockRes); err != nil {
+			return err
+		}
+	}
 
 	// TODO: do we actually need fsck if we are mounting a mapper device?
 	// probably not?
@@ -31,42 +31,23 @@
 #include "../libsnap-confine-private/snap.h"
 #include "../libsnap-confine-private/string-utils.h"
 #include "../libsnap-confine-private/cgroup-support.h"
+#include "../libsnap-confine-private/device-cgroup-support.h"
 #include "../libsnap-confine-private/utils.h"
 #include "udev-support.h"
 
-__attribute__((format(printf, 2, 3)))
-static void sc_dprintf(int fd, const char *format, ...);
-
-static void sc_dprintf(int fd, const char *format, ...)
-{
-	va_list ap1;
-	va_list ap2;
-	int n_expected, n_actual;
-
-	va_start(ap1, format);
-	va_copy(ap2, ap1);
-	n_expected = vsnprintf(NULL, 0, format, ap2);
-	n_actual = vdprintf(fd, format, ap1);
-	if (n_actual == -1 || n_expected != n_actual) {
-		die("cannot write to fd %d", fd);
-	}
-	va_end(ap2);
-	va_end(ap1);
-}
-
 /* Allow access to common devices. */
-static void sc_udev_allow_common(int devices_allow_fd)
+static void sc_udev_allow_common(sc_device_cgroup * cgroup)
 {
 	/* The devices we add here have static number allocation.
 	 * https://www.kernel.org/doc/html/v4.11/admin-guide/devices.html */
-	sc_dprintf(devices_allow_fd, "c 1:3 rwm\n");	// /dev/null
-	sc_dprintf(devices_allow_fd, "c 1:5 rwm\n");	// /dev/zero
-	sc_dprintf(devices_allow_fd, "c 1:7 rwm\n");	// /dev/full
-	sc_dprintf(devices_allow_fd, "c 1:8 rwm\n");	// /dev/random
-	sc_dprintf(devices_allow_fd, "c 1:9 rwm\n");	// /dev/urandom
-	sc_dprintf(devices_allow_fd, "c 5:0 rwm\n");	// /dev/tty
-	sc_dprintf(devices_allow_fd, "c 5:1 rwm\n");	// /dev/console
-	sc_dprintf(devices_allow_fd, "c 5:2 rwm\n");	// /dev/ptmx
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 1, 3);	// /dev/null
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 1, 5);	// /dev/zero
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 1, 7);	// /dev/full
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 1, 8);	// /dev/random
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 1, 9);	// /dev/urandom
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 5, 0);	// /dev/tty
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 5, 1);	// /dev/console
+	sc_device_cgroup_allow(cgroup, S_IFCHR, 5, 2);	// /dev/ptmx
 }
 
 /** Allow access to current and future PTY slaves.
@@ -77,10 +58,11 @@ static void sc_udev_allow_common(int devices_allow_fd)
  * See also:
  * https://www.kernel.org/doc/Documentation/admin-guide/devices.txt
  **/
-static void sc_udev_allow_pty_slaves(int devices_allow_fd)
+static void sc_udev_allow_pty_slaves(sc_device_cgroup * cgroup)
 {
 	for (unsigned pty_major = 136; pty_major <= 143; pty_major++) {
-		sc_dprintf(devices_allow_fd, "c %u:* rwm\n", pty_major);
+		sc_device_cgroup_allow(cgroup, S_IFCHR, pty_major,
+				       SC_DEVICE_MINOR_ANY);
 	}
 }
 
@@ -96,7 +78,7 @@ static void sc_udev_allow_pty_slaves(int devices_allow_fd)
  *
  * https://www.kernel.org/doc/Documentation/admin-guide/devices.txt
  **/
-static void sc_udev_allow_nvidia(int devices_allow_fd)
+static void sc_udev_allow_nvidia(sc_device_cgroup * cgroup)
 {
 	struct stat sbuf;
 
@@ -112,21 +94,21 @@ static void sc_udev_allow_nvidia(int devices_allow_fd)
 		if (stat(nv_path, &sbuf) < 0) {
 			break;
 		}
-		sc_dprintf(devices_allow_fd, "c %u:%u rwm\n",
-			   major(sbuf.st_rdev), minor(sbuf.st_rdev));
+		sc_device_cgroup_allow(cgroup, S_IFCHR, major(sbuf.st_rdev),
+				       minor(sbuf.st_rdev));
 	}
 
 	if (stat("/dev/nvidiactl", &sbuf) == 0) {
-		sc_dprintf(devices_allow_fd, "c %u:%u rwm\n",
-			   major(sbuf.st_rdev), minor(sbuf.st_rdev));
+		sc_device_cgroup_allow(cgroup, S_IFCHR, major(sbuf.st_rdev),
+				       minor(sbuf.st_rdev));
 	}
 	if (stat("/dev/nvidia-uvm", &sbuf) == 0) {
-		sc_dprintf(devices_allow_fd, "c %u:%u rwm\n",
-			   major(sbuf.st_rdev), minor(sbuf.st_rdev));
+		sc_device_cgroup_allow(cgroup, S_IFCHR, major(sbuf.st_rdev),
+				       minor(sbuf.st_rdev));
 	}
 	if (stat("/dev/nvidia-modeset", &sbuf) == 0) {
-		sc_dprintf(devices_allow_fd, "c %u:%u rwm\n",
-			   major(sbuf.st_rdev), minor(sbuf.st_rdev));
+		sc_device_cgroup_allow(cgroup, S_IFCHR, major(sbuf.st_rdev),
+				       minor(sbuf.st_rdev));
 	}
 }
 
@@ -136,13 +118,13 @@ static void sc_udev_allow_nvidia(int devices_allow_fd)
  * Currently /dev/uhid isn't represented in sysfs, so add it to the device
  * cgroup if it exists and let AppArmor handle the mediation.
  **/
-static void sc_udev_allow_uhid(int devices_allow_fd)
+static void sc_udev_allow_uhid(sc_device_cgroup * cgroup)
 {
 	struct stat sbuf;
 
 	if (stat("/dev/uhid", &sbuf) == 0) {
-		sc_dprintf(devices_allow_fd, "c %u:%u rwm\n",
-			   major(sbuf.st_rdev), minor(sbuf.st_rdev));
+		sc_device_cgroup_allow(cgroup, S_IFCHR, major(sbuf.st_rdev),
+				       minor(sbuf.st_rdev));
 	}
 }
 
@@ -155,13 +137,13 @@ static void sc_udev_allow_uhid(int devices_allow_fd)
  * it unconditionally to the cgroup and rely on AppArmor to mediate the
  * access. LP: #1859084
  **/
-static void sc_udev_allow_dev_net_tun(int devices_allow_fd)
+static void sc_udev_allow_dev_net_tun(sc_device_cgroup * cgroup)
 {
 	struct stat sbuf;
 
 	if (stat("/dev/net/tun", &sbuf) == 0) {
-		sc_dprintf(devices_allow_fd, "c %u:%u rwm\n",
-			   major(sbuf.st_rdev), minor(sbuf.st_rdev));
+		sc_device_cgroup_allow(cgroup, S_IFCHR, major(sbuf.st_rdev),
+				       minor(sbuf.st_rdev));
 	}
 }
 
@@ -172,7 +154,8 @@ static void sc_udev_allow_dev_net_tun(int devices_allow_fd)
  * tags corresponding to snap applications. Here we interrogate udev and allow
  * access to all assigned devices.
  **/
-static void sc_udev_allow_assigned(int devices_allow_fd, struct udev *udev,
+static void sc_udev_allow_assigned(sc_device_cgroup * cgroup,
+				   struct udev *udev,
 				   struct udev_list_entry *assigned)
 {
 	for (struct udev_list_entry * entry = assigned; entry != NULL;
@@ -220,12 +203,10 @@ static void sc_udev_allow_assigned(int devices_allow_fd, struct udev *udev,
 		}
 		switch (file_info.st_mode & S_IFMT) {
 		case S_IFBLK:
-			dprintf(devices_allow_fd, "b %u:%u rwm\n", major,
-				minor);
-			break;
 		case S_IFCHR:
-			dprintf(devices_allow_fd, "c %u:%u rwm\n", major,
-				minor);
+			sc_device_cgroup_allow(cgroup,
+					       file_info.st_mode & S_IFMT,
+					       major, minor);
 			break;
 		default:
 			/* Not a device, ignore it. */
@@ -235,25 +216,18 @@ static void sc_udev_allow_assigned(int devices_allow_fd, struct udev *udev,
 	}
 }
 
-static void sc_udev_setup_acls(int devices_allow_fd, int devices_deny_fd,
-			       struct udev *udev,
+static void sc_udev_setup_acls(sc_device_cgroup * cgroup, struct udev *udev,
 			       struct udev_list_entry *assigned)
 {
-	/* Deny device access by default.
-	 *
-	 * Write 'a' to devices.deny to remove all existing devices that were added
-	 * in previous launcher invocations, then add the static and assigned
-	 * devices. This ensures that at application launch the cgroup only has
-	 * what is currently assigned. */
-	sc_dprintf(devices_deny_fd, "a");
-
-	/* Allow access to various devices. */
-	sc_udev_allow_common(devices_allow_fd);
-	sc_udev_allow_pty_slaves(devices_allow_fd);
-	sc_udev_allow_nvidia(devices_allow_fd);
-	sc_udev_allow_uhid(devices_allow_fd);
-	sc_udev_allow_dev_net_tun(devices_allow_fd);
-	sc_udev_allow_assigned(devices_allow_fd, udev, assigned);
+	/* Allow access to various devices. Access to all other devices is
+	 * denied, the device cgroup starts out empty on each application launch
+	 * so that it only has what is currently assigned. */
+	sc_udev_allow_common(cgroup);
+	sc_udev_allow_pty_slaves(cgroup);
+	sc_udev_allow_nvidia(cgroup);
+	sc_udev_allow_uhid(cgroup);
+	sc_udev_allow_dev_net_tun(cgroup);
+	sc_udev_allow_assigned(cgroup, udev, assigned);
 }
 
 static char *sc_security_to_udev_tag(const char *security_tag)
@@ -281,118 +255,23 @@ static void sc_cleanup_udev_enumerate(struct udev_enumerate **enumerate)
 	}
 }
 
-typedef struct sc_cgroup_fds {
-	int devices_allow_fd;
-	int devices_deny_fd;
-	int cgroup_procs_fd;
-} sc_cgroup_fds;
-
-static sc_cgroup_fds sc_udev_open_cgroup_v1(const char *security_tag)
-{
-	/* Note that -1 is the neutral value for a file descriptor.
-	 * This is relevant as a cleanup handler for sc_cgroup_fds,
-	 * closes all file descriptors that are not -1. */
-	sc_cgroup_fds fds = { -1, -1, -1 };
-
-	/* Open /sys/fs/cgroup */
-	const char *cgroup_path = "/sys/fs/cgroup";
-	int SC_CLEANUP(sc_cleanup_close) cgroup_fd = -1;
-	cgroup_fd = open(cgroup_path,
-			 O_PATH | O_DIRECTORY | O_CLOEXEC | O_NOFOLLOW);
-	if (cgroup_fd < 0) {
-		die("cannot open %s", cgroup_path);
-	}
-
-	/* Open devices relative to /sys/fs/cgroup */
-	const char *devices_relpath = "devices";
-	int SC_CLEANUP(sc_cleanup_close) devices_fd = -1;
-	devices_fd = openat(cgroup_fd, devices_relpath,
-			    O_PATH | O_DIRECTORY | O_CLOEXEC | O_NOFOLLOW);
-	if (devices_fd < 0) {
-		die("cannot open %s/%s", cgroup_path, devices_relpath);
-	}
-
-	/* Open snap.$SNAP_NAME.$APP_NAME relative to /sys/fs/cgroup/devices,
-	 * creating the directory if necessary. Note that we always chown the
-	 * resulting directory to root:root. */
-	const char *security_tag_relpath = security_tag;
-	sc_identity old = sc_set_effective_identity(sc_root_group_identity());
-	if (mkdirat(devices_fd, security_tag_relpath, 0755) < 0) {
-		if (errno != EEXIST) {
-			die("cannot create directory %s/%s/%s", cgroup_path,
-			    devices_relpath, security_tag_relpath);
-		}
-	}
-	(void)sc_set_effective_identity(old);
-
-	int SC_CLEANUP(sc_cleanup_close) security_tag_fd = -1;
-	security_tag_fd = openat(devices_fd, security_tag_relpath,
-				 O_RDONLY | O_DIRECTORY | O_CLOEXEC |
-				 O_NOFOLLOW);
-	if (security_tag_fd < 0) {
-		die("cannot open %s/%s/%s", cgroup_path, devices_relpath,
-		    security_tag_relpath);
-	}
-
-	/* Open devices.allow relative to /sys/fs/cgroup/devices/snap.$SNAP_NAME.$APP_NAME */
-	const char *devices_allow_relpath = "devices.allow";
-	int SC_CLEANUP(sc_cleanup_close) devices_allow_fd = -1;
-	devices_allow_fd = openat(security_tag_fd, devices_allow_relpath,
-				  O_WRONLY | O_CLOEXEC | O_NOFOLLOW);
-	if (devices_allow_fd < 0) {
-		die("cannot open %s/%s/%s/%s", cgroup_path, devices_relpath,
-		    security_tag_relpath, devices_allow_relpath);
-	}
-
-	/* Open devices.deny relative to /sys/fs/cgroup/devices/snap.$SNAP_NAME.$APP_NAME */
-	const char *devices_deny_relpath = "devices.deny";
-	int SC_CLEANUP(sc_cleanup_close) devices_deny_fd = -1;
-	devices_deny_fd = openat(security_tag_fd, devices_deny_relpath,
-				 O_WRONLY | O_CLOEXEC | O_NOFOLLOW);
-	if (devices_deny_fd < 0) {
-		die("cannot open %s/%s/%s/%s", cgroup_path, devices_relpath,
-		    security_tag_relpath, devices_deny_relpath);
-	}
-
-	/* Open cgroup.procs relative to /sys/fs/cgroup/devices/snap.$SNAP_NAME.$APP_NAME */
-	const char *cgroup_procs_relpath = "cgroup.procs";
-	int SC_CLEANUP(sc_cleanup_close) cgroup_procs_fd = -1;
-	cgroup_procs_fd = openat(security_tag_fd, cgroup_procs_relpath,
-				 O_WRONLY | O_CLOEXEC | O_NOFOLLOW);
-	if (cgroup_procs_fd < 0) {
-		die("cannot open %s/%s/%s/%s", cgroup_path, devices_relpath,
-		    security_tag_relpath, cgroup_procs_relpath);
-	}
-
-	/* Everything worked so pack the result and "move" the descriptors over so
-	 * that they are not closed by the cleanup functions associated with the
-	 * individual variables. */
-	fds.devices_allow_fd = devices_allow_fd;
-	fds.devices_deny_fd = devices_deny_fd;
-	fds.cgroup_procs_fd = cgroup_procs_fd;
-	/* Reset the locals so that they are not closed by the cleanup handlers. */
-	devices_allow_fd = -1;
-	devices_deny_fd = -1;
-	cgroup_procs_fd = -1;
-	return fds;
-}
-
-static void sc_cleanup_cgroup_fds(sc_cgroup_fds * fds)
-{
-	if (fds != NULL) {
-		sc_cleanup_close(&fds->devices_allow_fd);
-		sc_cleanup_close(&fds->devices_deny_fd);
-		sc_cleanup_close(&fds->cgroup_procs_fd);
-	}
-}
-
 void sc_setup_device_cgroup(const char *security_tag)
 {
 	debug("setting up device cgroup");
 	if (sc_cgroup_is_v2()) {
-		/* TODO: add support for v2 mode. This is coming but needs several more
-		 * rounds of iteration. */
-		return;
+		/* With cgroup v2 the device filter is attached to the group of the
+		 * process, which is only specific to the snap application or hook
+		 * when the process is tracked by snapd. Otherwise the filter would
+		 * apply to unrelated processes as well. */
+		char *own_group SC_CLEANUP(sc_cleanup_string) = NULL;
+		own_group = sc_cgroup_v2_own_path_full();
+		const char *leaf =
+		    own_group != NULL ? strrchr(own_group, '/') : NULL;
+		if (leaf == NULL || !sc_startswith(leaf + 1, "snap.")) {
+			debug("process is not in a snap specific cgroup, "
+			      "skipping device cgroup setup");
+			return;
+		}
 	}
 
 	/* Derive the udev tag from the snap security tag.
@@ -433,21 +312,18 @@ void sc_setup_device_cgroup(const char *security_tag)
 		return;
 	}
 
-	/* Note that -1 is the neutral value for a file descriptor.
-	 * The cleanup function associated with this variable closes
-	 * descriptors other than -1. */
-	sc_cgroup_fds SC_CLEANUP(sc_cleanup_cgroup_fds) fds = { -1, -1, -1 };
-	fds = sc_udev_open_cgroup_v1(security_tag);
-	if (fds.cgroup_procs_fd < 0) {
-		die("cannot prepare cgroup v1 device hierarchy");
-		return;
+	sc_device_cgroup SC_CLEANUP(sc_device_cgroup_cleanup) * cgroup = NULL;
+	cgroup = sc_device_cgroup_new(security_tag, 0);
+	if (cgroup == NULL) {
+		die("cannot prepare device cgroup");
 	}
 	/* Setup the device group access control list */
-	sc_udev_setup_acls(fds.devices_allow_fd, fds.devices_deny_fd,
-			   udev, assigned);
+	sc_udev_setup_acls(cgroup, udev, assigned);
 
 	/* Move ourselves to the device cgroup */
-	sc_dprintf(fds.cgroup_procs_fd, "%i\n", getpid());
+	if (sc_device_cgroup_attach_pid(cgroup, getpid()) < 0) {
+		die("cannot attach device cgroup");
+	}
 	debug("associated snap application process %i with device cgroup %s",
 	      getpid(), security_tag);
 }
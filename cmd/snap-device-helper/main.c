@@ -0,0 +1,36 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#include <stdio.h>
+
+#include "snap-device-helper.h"
+
+int main(int argc, char **argv) {
+    if (argc < 2) {
+        fprintf(stderr, "Usage: snap-device-helper <action> <appname> <devpath> <major:minor>\n");
+        return 1;
+    }
+    /* missing arguments are reported by snap_device_helper_run, which is
+     * lenient with a missing major:minor pair */
+    struct sdh_invocation inv = {
+        .action = argv[1],
+        .tagname = argc > 2 ? argv[2] : "",
+        .devpath = argc > 3 ? argv[3] : "",
+        .majmin = argc > 4 ? argv[4] : "",
+    };
+    return snap_device_helper_run(&inv);
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#include "snap-device-helper.c"
+
+#include <glib.h>
+
+/* mocks of the device cgroup API, recording the calls made */
+
+struct sc_device_cgroup {
+    char *security_tag;
+};
+
+static struct {
+    /* the security tags with a device cgroup */
+    const char *existing_tags[2];
+    /* the last call made to sc_device_cgroup_new */
+    char *new_tag;
+    int new_flags;
+    /* the last call made to sc_device_cgroup_allow or sc_device_cgroup_deny */
+    const char *op;
+    int kind;
+    uint32_t major;
+    uint32_t minor;
+} mocks;
+
+static void mocks_reset(void) {
+    g_free(mocks.new_tag);
+    memset(&mocks, 0, sizeof mocks);
+}
+
+sc_device_cgroup *sc_device_cgroup_new(const char *security_tag, int flags) {
+    g_free(mocks.new_tag);
+    mocks.new_tag = g_strdup(security_tag);
+    mocks.new_flags = flags;
+    for (size_t i = 0; i < sizeof mocks.existing_tags / sizeof *mocks.existing_tags; i++) {
+        if (g_strcmp0(mocks.existing_tags[i], security_tag) == 0) {
+            sc_device_cgroup *self = g_new0(sc_device_cgroup, 1);
+            self->security_tag = g_strdup(security_tag);
+            return self;
+        }
+    }
+    errno = ENOENT;
+    return NULL;
+}
+
+void sc_device_cgroup_cleanup(sc_device_cgroup **self) {
+    if (*self != NULL) {
+        g_free((*self)->security_tag);
+        g_free(*self);
+        *self = NULL;
+    }
+}
+
+static int mock_device_op(const char *op, int kind, uint32_t major, uint32_t minor) {
+    mocks.op = op;
+    mocks.kind = kind;
+    mocks.major = major;
+    mocks.minor = minor;
+    return 0;
+}
+
+int sc_device_cgroup_allow(sc_device_cgroup *self, int kind, uint32_t major, uint32_t minor) {
+    return mock_device_op("allow", kind, major, minor);
+}
+
+int sc_device_cgroup_deny(sc_device_cgroup *self, int kind, uint32_t major, uint32_t minor) {
+    return mock_device_op("deny", kind, major, minor);
+}
+
+static int run_sdh(const char *action, const char *tagname, const char *devpath, const char *majmin) {
+    struct sdh_invocation inv = {
+        .action = action,
+        .tagname = tagname,
+        .devpath = devpath,
+        .majmin = majmin,
+    };
+    return snap_device_helper_run(&inv);
+}
+
+struct sdh_test_data {
+    const char *action;
+    // snap.foo.bar
+    const char *app;
+    // snap_foo_bar
+    const char *mangled_appname;
+    const char *op;
+};
+
+static void test_sdh_action(gconstpointer test_data) {
+    const struct sdh_test_data *td = test_data;
+
+    mocks_reset();
+    g_test_queue_destroy((GDestroyNotify)mocks_reset, NULL);
+    mocks.existing_tags[0] = td->app;
+
+    int ret = run_sdh(td->action, td->mangled_appname, "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 0);
+    g_assert_cmpstr(mocks.new_tag, ==, td->app);
+    g_assert_cmpint(mocks.new_flags, ==, SC_DEVICE_CGROUP_FROM_EXISTING);
+    g_assert_cmpstr(mocks.op, ==, td->op);
+    g_assert_cmpint(mocks.kind, ==, S_IFBLK);
+    g_assert_cmpuint(mocks.major, ==, 8);
+    g_assert_cmpuint(mocks.minor, ==, 4);
+
+    mocks.op = NULL;
+    ret = run_sdh(td->action, td->mangled_appname, "/devices/foo/tty/ttyS0", "4:64");
+    g_assert_cmpint(ret, ==, 0);
+    g_assert_cmpstr(mocks.op, ==, td->op);
+    g_assert_cmpint(mocks.kind, ==, S_IFCHR);
+    g_assert_cmpuint(mocks.major, ==, 4);
+    g_assert_cmpuint(mocks.minor, ==, 64);
+}
+
+static void test_sdh_nvme(void) {
+    mocks_reset();
+    g_test_queue_destroy((GDestroyNotify)mocks_reset, NULL);
+    mocks.existing_tags[0] = "snap.foo.bar";
+
+    // nvme namespaces and their partitions are block devices
+    int ret = run_sdh("add", "snap_foo_bar", "/devices/pci0000:00/0000:00:01.1/0000:01:00.0/nvme/nvme0/nvme0n1", "259:0");
+    g_assert_cmpint(ret, ==, 0);
+    g_assert_cmpint(mocks.kind, ==, S_IFBLK);
+    ret = run_sdh("add", "snap_foo_bar", "/devices/pci0000:00/0000:00:01.1/0000:01:00.0/nvme/nvme0/nvme0n1/nvme0n1p1",
+                  "259:1");
+    g_assert_cmpint(ret, ==, 0);
+    g_assert_cmpint(mocks.kind, ==, S_IFBLK);
+    // the nvme controller is a char device
+    ret = run_sdh("add", "snap_foo_bar", "/devices/pci0000:00/0000:00:01.1/0000:01:00.0/nvme/nvme0", "242:0");
+    g_assert_cmpint(ret, ==, 0);
+    g_assert_cmpint(mocks.kind, ==, S_IFCHR);
+}
+
+static void test_sdh_no_cgroup(void) {
+    mocks_reset();
+    g_test_queue_destroy((GDestroyNotify)mocks_reset, NULL);
+
+    // the device cgroup only exists once the application was started
+    int ret = run_sdh("add", "snap_foo_bar", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 0);
+    g_assert_cmpstr(mocks.new_tag, ==, "snap.foo.bar");
+    g_assert_null(mocks.op);
+}
+
+static void test_sdh_err(void) {
+    mocks_reset();
+    g_test_queue_destroy((GDestroyNotify)mocks_reset, NULL);
+    mocks.existing_tags[0] = "snap.foo.bar";
+
+    // missing appname
+    int ret = run_sdh("add", "", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    // malformed appname
+    ret = run_sdh("add", "foo_bar", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    ret = run_sdh("add", "snap_foo", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    ret = run_sdh("add", "snap_foo_bar_baz_froz", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    ret = run_sdh("add", "snap_foo_bar_baz_hook_froz", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    ret = run_sdh("add", "snap_foo_bar..", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    // missing devpath
+    ret = run_sdh("add", "snap_foo_bar", "", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    // missing device major:minor numbers
+    ret = run_sdh("add", "snap_foo_bar", "/devices/foo/block/sda/sda4", "");
+    g_assert_cmpint(ret, ==, 0);
+    // malformed device major:minor numbers
+    ret = run_sdh("add", "snap_foo_bar", "/devices/foo/block/sda/sda4", "8");
+    g_assert_cmpint(ret, ==, 1);
+    ret = run_sdh("add", "snap_foo_bar", "/devices/foo/block/sda/sda4", "8:4:2");
+    g_assert_cmpint(ret, ==, 1);
+    g_assert_null(mocks.new_tag);
+
+    ret = run_sdh("badaction", "snap_foo_bar", "/devices/foo/block/sda/sda4", "8:4");
+    g_assert_cmpint(ret, ==, 1);
+    g_assert_null(mocks.op);
+}
+
+static struct sdh_test_data add_data = {"add", "snap.foo.bar", "snap_foo_bar", "allow"};
+static struct sdh_test_data change_data = {"change", "snap.foo.bar", "snap_foo_bar", "allow"};
+static struct sdh_test_data remove_data = {"remove", "snap.foo.bar", "snap_foo_bar", "deny"};
+
+static struct sdh_test_data instance_add_data = {"add", "snap.foo_bar.baz", "snap_foo_bar_baz", "allow"};
+static struct sdh_test_data instance_change_data = {"change", "snap.foo_bar.baz", "snap_foo_bar_baz", "allow"};
+static struct sdh_test_data instance_remove_data = {"remove", "snap.foo_bar.baz", "snap_foo_bar_baz", "deny"};
+
+static struct sdh_test_data add_hook_data = {"add", "snap.foo.hook.configure", "snap_foo_hook_configure", "allow"};
+static struct sdh_test_data instance_add_hook_data = {"add", "snap.foo_bar.hook.configure",
+                                                      "snap_foo_bar_hook_configure", "allow"};
+static struct sdh_test_data instance_add_instance_name_is_hook_data = {"add", "snap.foo_hook.hook.configure",
+                                                                       "snap_foo_hook_hook_configure", "allow"};
+
+static void __attribute__((constructor)) init(void) {
+    g_test_add_data_func("/snap-device-helper/add", &add_data, test_sdh_action);
+    g_test_add_data_func("/snap-device-helper/change", &change_data, test_sdh_action);
+    g_test_add_data_func("/snap-device-helper/remove", &remove_data, test_sdh_action);
+    g_test_add_func("/snap-device-helper/err", test_sdh_err);
+    g_test_add_func("/snap-device-helper/nvme", test_sdh_nvme);
+    g_test_add_func("/snap-device-helper/no-cgroup", test_sdh_no_cgroup);
+    g_test_add_data_func("/snap-device-helper/parallel/add", &instance_add_data, test_sdh_action);
+    g_test_add_data_func("/snap-device-helper/parallel/change", &instance_change_data, test_sdh_action);
+    g_test_add_data_func("/snap-device-helper/parallel/remove", &instance_remove_data, test_sdh_action);
+    // hooks
+    g_test_add_data_func("/snap-device-helper/hook/add", &add_hook_data, test_sdh_action);
+    g_test_add_data_func("/snap-device-helper/hook/parallel/add", &instance_add_hook_data, test_sdh_action);
+    g_test_add_data_func("/snap-device-helper/hook-name-hook/parallel/add", &instance_add_instance_name_is_hook_data,
+                         test_sdh_action);
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#define _GNU_SOURCE
+
+#include <errno.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/stat.h>
+
+#include "../libsnap-confine-private/cleanup-funcs.h"
+#include "../libsnap-confine-private/device-cgroup-support.h"
+#include "../libsnap-confine-private/snap.h"
+#include "../libsnap-confine-private/string-utils.h"
+#include "../libsnap-confine-private/utils.h"
+#include "snap-device-helper.h"
+
+/**
+ * udev_to_security_tag converts the udev tag of an application or hook back
+ * to its security tag.
+ *
+ * The udev tags look like this:
+ * - snap_<snap>_<app>
+ * - snap_<snap>_<instance>_<app>
+ * - snap_<snap>_hook_<hook>
+ * - snap_<snap>_<instance>_hook_<hook>
+ * As neither snap names, instance keys nor application and hook names may
+ * contain underscores, the parts can be told apart by their number. The
+ * corner case of an application of an instance named "hook" is ambiguous
+ * and is taken to be a hook of the snap without instance key.
+ *
+ * The returned string must be freed by the caller, NULL is returned for
+ * malformed tags.
+ **/
+static char *udev_to_security_tag(const char *udev_tag) {
+    if (!sc_startswith(udev_tag, "snap_")) {
+        return NULL;
+    }
+    char *tag_copy SC_CLEANUP(sc_cleanup_string) = sc_strdup(udev_tag + strlen("snap_"));
+    char *parts[4] = {NULL};
+    size_t num_parts = 0;
+    char *saveptr = NULL;
+    for (char *part = strtok_r(tag_copy, "_", &saveptr); part != NULL; part = strtok_r(NULL, "_", &saveptr)) {
+        if (num_parts == sizeof parts / sizeof *parts) {
+            return NULL;
+        }
+        parts[num_parts++] = part;
+    }
+
+    /* the instance name and the application or hook of the tag */
+    char instance_name[SNAP_INSTANCE_LEN + 1] = {0};
+    const char *hook = NULL;
+    const char *app = NULL;
+    switch (num_parts) {
+        case 2:
+            /* snap_<snap>_<app> */
+            sc_must_snprintf(instance_name, sizeof instance_name, "%s", parts[0]);
+            app = parts[1];
+            break;
+        case 3:
+            if (sc_streq(parts[1], "hook")) {
+                /* snap_<snap>_hook_<hook> */
+                sc_must_snprintf(instance_name, sizeof instance_name, "%s", parts[0]);
+                hook = parts[2];
+            } else {
+                /* snap_<snap>_<instance>_<app> */
+                sc_must_snprintf(instance_name, sizeof instance_name, "%s_%s", parts[0], parts[1]);
+                app = parts[2];
+            }
+            break;
+        case 4:
+            if (!sc_streq(parts[2], "hook")) {
+                return NULL;
+            }
+            /* snap_<snap>_<instance>_hook_<hook> */
+            sc_must_snprintf(instance_name, sizeof instance_name, "%s_%s", parts[0], parts[1]);
+            hook = parts[3];
+            break;
+        default:
+            return NULL;
+    }
+
+    char security_tag[SNAP_SECURITY_TAG_MAX_LEN + 1] = {0};
+    if (hook != NULL) {
+        sc_must_snprintf(security_tag, sizeof security_tag, "snap.%s.hook.%s", instance_name, hook);
+    } else {
+        sc_must_snprintf(security_tag, sizeof security_tag, "snap.%s.%s", instance_name, app);
+    }
+    if (!sc_security_tag_validate(security_tag, instance_name)) {
+        return NULL;
+    }
+    return sc_strdup(security_tag);
+}
+
+/**
+ * devpath_is_block returns whether the device at a sysfs path is a block
+ * device.
+ **/
+static bool devpath_is_block(const char *devpath) {
+    if (strstr(devpath, "/block/") != NULL) {
+        return true;
+    }
+    /* char devices are .../nvme/nvme* but block devices are
+     * .../nvme/nvme*\/nvme*n* and .../nvme/nvme*\/nvme*n*p* so if we have a
+     * device that has nvme/nvme*\/nvme*n* in it, treat it as a block device */
+    const char *nvme = strstr(devpath, "/nvme/nvme");
+    if (nvme != NULL) {
+        const char *ns = strstr(nvme + strlen("/nvme/nvme"), "/nvme");
+        if (ns != NULL && strchr(ns + strlen("/nvme"), 'n') != NULL) {
+            return true;
+        }
+    }
+    return false;
+}
+
+int snap_device_helper_run(const struct sdh_invocation *inv) {
+    const char *action = inv->action;
+    const char *tagname = inv->tagname;
+    const char *devpath = inv->devpath;
+    const char *majmin = inv->majmin;
+
+    if (tagname == NULL || tagname[0] == '\0') {
+        fprintf(stderr, "no app name given\n");
+        return 1;
+    }
+    if (devpath == NULL || devpath[0] == '\0') {
+        fprintf(stderr, "no devpath given\n");
+        return 1;
+    }
+    if (majmin == NULL || majmin[0] == '\0') {
+        fprintf(stderr, "no major/minor given\n");
+        return 0;
+    }
+
+    char *security_tag SC_CLEANUP(sc_cleanup_string) = udev_to_security_tag(tagname);
+    if (security_tag == NULL) {
+        fprintf(stderr, "malformed appname %s\n", tagname);
+        return 1;
+    }
+
+    uint32_t major = 0;
+    uint32_t minor = 0;
+    char trailing = 0;
+    if (sscanf(majmin, "%u:%u%c", &major, &minor, &trailing) != 2) {
+        fprintf(stderr, "malformed major/minor %s\n", majmin);
+        return 1;
+    }
+
+    sc_device_cgroup *cgroup SC_CLEANUP(sc_device_cgroup_cleanup) =
+        sc_device_cgroup_new(security_tag, SC_DEVICE_CGROUP_FROM_EXISTING);
+    if (cgroup == NULL) {
+        if (errno == ENOENT) {
+            /* The cgroup is only present after snap start so ignore any
+             * cgroup changes (eg, 'add' on boot, hotplug, hotunplug) when the
+             * cgroup doesn't exist yet. LP: #1762182. */
+            debug("device cgroup of %s does not exist", security_tag);
+            return 0;
+        }
+        die("cannot open device cgroup of %s", security_tag);
+    }
+
+    int kind = devpath_is_block(devpath) ? S_IFBLK : S_IFCHR;
+    if (sc_streq(action, "add") || sc_streq(action, "change")) {
+        if (sc_device_cgroup_allow(cgroup, kind, major, minor) < 0) {
+            die("cannot allow device %s of %s", majmin, security_tag);
+        }
+    } else if (sc_streq(action, "remove")) {
+        if (sc_device_cgroup_deny(cgroup, kind, major, minor) < 0) {
+            die("cannot deny device %s of %s", majmin, security_tag);
+        }
+    } else {
+        fprintf(stderr, "ERROR: unknown action %s\n", action);
+        return 1;
+    }
+    return 0;
+}
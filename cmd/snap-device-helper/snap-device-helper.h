@@ -0,0 +1,42 @@
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+#ifndef SNAP_DEVICE_HELPER_SNAP_DEVICE_HELPER_H
+#define SNAP_DEVICE_HELPER_SNAP_DEVICE_HELPER_H
+
+/**
+ * sdh_invocation holds the arguments snap-device-helper was invoked with by
+ * the udev rules.
+ **/
+struct sdh_invocation {
+    /* action is the udev action, add, change or remove */
+    const char *action;
+    /* tagname is the udev tag of the application, snap_<snap>_<app> */
+    const char *tagname;
+    /* devpath is the path of the device in sysfs, without /sys */
+    const char *devpath;
+    /* majmin is the major and minor number of the device, <major>:<minor> */
+    const char *majmin;
+};
+
+/**
+ * snap_device_helper_run updates the device cgroup of a snap application or
+ * hook and returns the exit status of snap-device-helper.
+ **/
+int snap_device_helper_run(const struct sdh_invocation *inv);
+
+#endif /* SNAP_DEVICE_HELPER_SNAP_DEVICE_HELPER_H */
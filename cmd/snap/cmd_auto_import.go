@@ -184,6 +184,22 @@ func autoImportFromAllMounts(cli *client.Client) (int, error) {
 		return 0, err
 	}
 
+	return autoImportFiles(cli, cands)
+}
+
+// autoImportFromSeed imports the assertions from an auto-import.assert
+// file at the top of ubuntu-seed, this is skipped when looking at all
+// mounts but lets a brand provision system-users at the factory
+func autoImportFromSeed(cli *client.Client) (int, error) {
+	cand := filepath.Join(boot.InitramfsUbuntuSeedDir, autoImportsName)
+	if !osutil.FileExists(cand) {
+		return 0, nil
+	}
+
+	return autoImportFiles(cli, []string{cand})
+}
+
+func autoImportFiles(cli *client.Client, cands []string) (int, error) {
 	added := 0
 	for _, cand := range cands {
 		err := ackFile(cli, cand)
@@ -388,7 +404,12 @@ func (x *cmdAutoImport) Execute(args []string) error {
 		return err
 	}
 
-	if added1+added2 > 0 {
+	added3, err := autoImportFromSeed(x.client)
+	if err != nil {
+		return err
+	}
+
+	if added1+added2+added3 > 0 {
 		return x.autoAddUsers()
 	}
 
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"time"
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
@@ -34,6 +35,10 @@ var shortChangesHelp = i18n.G("List system changes")
 var shortTasksHelp = i18n.G("List a change's tasks")
 var longChangesHelp = i18n.G(`
 The changes command displays a summary of system changes performed recently.
+
+With --follow the changes in progress are followed instead, and the status
+transitions of them and of their tasks are printed as they happen, until no
+change is in progress anymore.
 `)
 var longTasksHelp = i18n.G(`
 The tasks command displays a summary of tasks associated with an individual
@@ -43,6 +48,7 @@ change.
 type cmdChanges struct {
 	clientMixin
 	timeMixin
+	Follow     bool `long:"follow"`
 	Positional struct {
 		Snap string `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -55,7 +61,10 @@ type cmdTasks struct {
 
 func init() {
 	addCommand("changes", shortChangesHelp, longChangesHelp,
-		func() flags.Commander { return &cmdChanges{} }, timeDescs, nil)
+		func() flags.Commander { return &cmdChanges{} }, timeDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"follow": i18n.G("Follow the changes in progress and print their updates as they happen"),
+		}), nil)
 	addCommand("tasks", shortTasksHelp, longTasksHelp,
 		func() flags.Commander { return &cmdTasks{} },
 		changeIDMixinOptDesc.also(timeDescs),
@@ -96,6 +105,10 @@ func (c *cmdChanges) Execute(args []string) error {
 		return nil
 	}
 
+	if c.Follow {
+		return c.followChanges()
+	}
+
 	opts := client.ChangesOptions{
 		SnapName: c.Positional.Snap,
 		Selector: client.ChangesAll,
@@ -130,6 +143,100 @@ func (c *cmdChanges) Execute(args []string) error {
 	return nil
 }
 
+// followPollTime is how often new changes in progress are looked for while
+// following changes
+var followPollTime = 1 * time.Second
+
+type changeUpdate struct {
+	id string
+	// chg is nil once the watch of the change is over
+	chg *client.Change
+}
+
+func (c *cmdChanges) followChanges() error {
+	opts := client.ChangesOptions{
+		SnapName: c.Positional.Snap,
+		Selector: client.ChangesInProgress,
+	}
+
+	updates := make(chan changeUpdate)
+	watched := make(map[string]bool)
+	last := make(map[string]*client.Change)
+	for {
+		changes, err := queryChanges(c.client, &opts)
+		if err != nil {
+			return err
+		}
+		sort.Sort(changesByTime(changes))
+		for _, chg := range changes {
+			if watched[chg.ID] {
+				continue
+			}
+			chgUpdates, err := c.client.WatchChange(chg.ID)
+			if err != nil {
+				return err
+			}
+			watched[chg.ID] = true
+			go func(id string) {
+				for chg := range chgUpdates {
+					updates <- changeUpdate{id: id, chg: chg}
+				}
+				updates <- changeUpdate{id: id}
+			}(chg.ID)
+		}
+		if len(watched) == 0 {
+			if len(last) == 0 {
+				fmt.Fprintln(Stderr, i18n.G("No changes in progress."))
+			}
+			return nil
+		}
+
+		// print the updates until it is time to look for new changes, a
+		// change whose watch ended before it was ready is watched again
+		// if it is still in progress
+		timeout := time.After(followPollTime)
+	loop:
+		for len(watched) > 0 {
+			select {
+			case u := <-updates:
+				if u.chg == nil {
+					delete(watched, u.id)
+					continue
+				}
+				printChangeUpdate(last[u.id], u.chg)
+				last[u.id] = u.chg
+			case <-timeout:
+				break loop
+			}
+		}
+	}
+}
+
+// printChangeUpdate prints the status transitions from prev to chg, of the
+// change and of its tasks. When the change was not seen before only the tasks
+// in progress are printed.
+func printChangeUpdate(prev, chg *client.Change) {
+	if prev == nil || prev.Status != chg.Status {
+		fmt.Fprintf(Stdout, "%s %-7s %s\n", chg.ID, chg.Status, chg.Summary)
+	}
+	prevStatus := make(map[string]string)
+	if prev != nil {
+		for _, t := range prev.Tasks {
+			prevStatus[t.ID] = t.Status
+		}
+	}
+	for _, t := range chg.Tasks {
+		if prev == nil {
+			if t.Status != "Doing" && t.Status != "Undoing" {
+				continue
+			}
+		} else if st, ok := prevStatus[t.ID]; ok && st == t.Status {
+			continue
+		}
+		fmt.Fprintf(Stdout, "%s %-7s   %s\n", chg.ID, t.Status, t.Summary)
+	}
+}
+
 func (c *cmdTasks) Execute([]string) error {
 	chid, err := c.GetChangeID()
 	if err != nil {
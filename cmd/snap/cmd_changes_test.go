@@ -23,7 +23,9 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"golang.org/x/net/websocket"
 	"gopkg.in/check.v1"
 
 	snap "github.com/snapcore/snapd/cmd/snap"
@@ -231,3 +233,78 @@ Doing +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary \(50.00%\)
 `)
 	c.Check(s.Stderr(), check.Equals, "")
 }
+
+func (s *SnapSuite) TestChangesFollow(c *check.C) {
+	// the watch of the change ends well before new changes are looked for
+	restore := snap.MockFollowPollTime(time.Minute)
+	defer restore()
+
+	watch := websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		for _, msg := range []string{
+			`{"id": "42", "summary": "Install \"foo\" snap", "status": "Doing", "tasks": [
+			   {"id": "1", "summary": "Download snap \"foo\"", "status": "Done"},
+			   {"id": "2", "summary": "Mount snap \"foo\"", "status": "Doing"},
+			   {"id": "3", "summary": "Link snap \"foo\"", "status": "Do"}]}`,
+			`{"id": "42", "summary": "Install \"foo\" snap", "status": "Doing", "tasks": [
+			   {"id": "1", "summary": "Download snap \"foo\"", "status": "Done"},
+			   {"id": "2", "summary": "Mount snap \"foo\"", "status": "Done"},
+			   {"id": "3", "summary": "Link snap \"foo\"", "status": "Doing"}]}`,
+			`{"id": "42", "summary": "Install \"foo\" snap", "status": "Done", "ready": true, "tasks": [
+			   {"id": "1", "summary": "Download snap \"foo\"", "status": "Done"},
+			   {"id": "2", "summary": "Mount snap \"foo\"", "status": "Done"},
+			   {"id": "3", "summary": "Link snap \"foo\"", "status": "Done"}]}`,
+		} {
+			c.Assert(websocket.Message.Send(ws, msg), check.IsNil)
+		}
+	})
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/changes":
+			n++
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Query().Get("select"), check.Equals, "in-progress")
+			if n == 1 {
+				fmt.Fprintln(w, `{"type": "sync", "result": [{"id": "42", "summary": "Install \"foo\" snap", "status": "Doing"}]}`)
+			} else {
+				fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+			}
+		case "/v2/changes/42/watch":
+			watch.ServeHTTP(w, r)
+		default:
+			c.Fatalf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--follow"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 2)
+	c.Check(s.Stdout(), check.Equals, `
+42 Doing   Install "foo" snap
+42 Doing     Mount snap "foo"
+42 Done      Mount snap "foo"
+42 Doing     Link snap "foo"
+42 Done    Install "foo" snap
+42 Done      Link snap "foo"
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestChangesFollowNothingInProgress(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.URL.Path, check.Equals, "/v2/changes")
+		c.Check(r.URL.Query().Get("select"), check.Equals, "in-progress")
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"changes", "--follow"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No changes in progress.\n")
+}
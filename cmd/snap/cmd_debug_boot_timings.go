@@ -0,0 +1,87 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdBootTimings struct {
+	clientMixin
+	timeMixin
+}
+
+var shortBootTimingsHelp = i18n.G("Show the timings of recent boots")
+var longBootTimingsHelp = i18n.G(`
+The boot-timings command shows how long the stages of the most recent boots
+took, from the firmware until snapd was ready.
+`)
+
+func init() {
+	addDebugCommand("boot-timings", shortBootTimingsHelp, longBootTimingsHelp, func() flags.Commander {
+		return &cmdBootTimings{}
+	}, timeDescs, nil)
+}
+
+type bootTimings struct {
+	BootID     string        `json:"boot-id"`
+	Time       time.Time     `json:"time"`
+	KernelSnap string        `json:"kernel-snap,omitempty"`
+	Firmware   time.Duration `json:"firmware"`
+	Bootloader time.Duration `json:"bootloader"`
+	Kernel     time.Duration `json:"kernel"`
+	Initramfs  time.Duration `json:"initramfs"`
+	SnapdReady time.Duration `json:"snapd-ready"`
+}
+
+func (x *cmdBootTimings) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	var timings []*bootTimings
+	if err := x.client.DebugGet("boot-timings", &timings, nil); err != nil {
+		return err
+	}
+	if len(timings) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No boot timings recorded yet."))
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Boot ID\tTime\tKernel snap\tFirmware\tBootloader\tKernel\tInitramfs\tSnapd ready\tTotal"))
+	for _, t := range timings {
+		kernelSnap := t.KernelSnap
+		if kernelSnap == "" {
+			kernelSnap = "-"
+		}
+		total := t.Firmware + t.Bootloader + t.Kernel + t.Initramfs + t.SnapdReady
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.BootID, x.fmtTime(t.Time), kernelSnap,
+			formatDuration(t.Firmware), formatDuration(t.Bootloader), formatDuration(t.Kernel),
+			formatDuration(t.Initramfs), formatDuration(t.SnapdReady), formatDuration(total))
+	}
+	w.Flush()
+	return nil
+}
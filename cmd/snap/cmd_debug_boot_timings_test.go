@@ -0,0 +1,62 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestDebugBootTimings(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/debug")
+		c.Check(r.URL.RawQuery, check.Equals, "aspect=boot-timings")
+		fmt.Fprintln(w, `{"type": "sync", "result": [
+{"boot-id": "boot-1", "time": "2021-06-01T10:00:00Z", "kernel-snap": "pc-kernel_1.snap", "firmware": 1500000000, "bootloader": 2000000000, "kernel": 3000000000, "initramfs": 4000000000, "snapd-ready": 5000000000},
+{"boot-id": "boot-2", "time": "2021-06-02T10:00:00Z", "firmware": 1000000000, "bootloader": 500000000, "kernel": 2000000000, "initramfs": 0, "snapd-ready": 3000000000}
+]}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "boot-timings", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, `Boot ID  Time                  Kernel snap       Firmware  Bootloader  Kernel  Initramfs  Snapd ready  Total
+boot-1   2021-06-01T10:00:00Z  pc-kernel_1.snap  1500ms    2000ms      3000ms  4000ms     5000ms       15500ms
+boot-2   2021-06-02T10:00:00Z  -                 1000ms    500ms       2000ms  0ms        3000ms       6500ms
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugBootTimingsNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "result": []}`)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "boot-timings"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "")
+	c.Check(s.Stderr(), check.Equals, "No boot timings recorded yet.\n")
+}
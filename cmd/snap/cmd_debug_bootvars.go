@@ -30,8 +30,10 @@ import (
 )
 
 type cmdBootvarsGet struct {
-	UC20    bool   `long:"uc20"`
-	RootDir string `long:"root-dir"`
+	UC20     bool   `long:"uc20"`
+	RootDir  string `long:"root-dir"`
+	Recovery bool   `long:"recovery"`
+	UEFI     bool   `long:"uefi"`
 }
 
 type cmdBootvarsSet struct {
@@ -51,6 +53,8 @@ func init() {
 		}, map[string]string{
 			"uc20":     i18n.G("Whether to use uc20 boot vars or not"),
 			"root-dir": i18n.G("Root directory to look for boot variables in"),
+			"recovery": i18n.G("Show the variables of the recovery bootloader (implies UC20)"),
+			"uefi":     i18n.G("Show the UEFI variables relevant to booting too"),
 		}, nil)
 
 	cmdSet := addDebugCommand("set-boot-vars",
@@ -73,7 +77,13 @@ func (x *cmdBootvarsGet) Execute(args []string) error {
 	if release.OnClassic {
 		return errors.New(`the "boot-vars" command is not available on classic systems`)
 	}
-	return boot.DebugDumpBootVars(Stdout, x.RootDir, x.UC20)
+	if err := boot.DebugDumpBootVars(Stdout, x.RootDir, x.UC20, x.Recovery); err != nil {
+		return err
+	}
+	if x.UEFI {
+		return boot.DebugDumpUEFIVars(Stdout)
+	}
+	return nil
 }
 
 func (x *cmdBootvarsSet) Execute(args []string) error {
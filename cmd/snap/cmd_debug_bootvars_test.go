@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/bootloader/efi"
 	snap "github.com/snapcore/snapd/cmd/snap"
 	"github.com/snapcore/snapd/release"
 )
@@ -151,3 +152,38 @@ try_recovery_system=9999
 	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "set-boot-vars", "--recovery", "--root-dir", boot.InitramfsUbuntuBootDir, "foo=recovery"})
 	c.Assert(err, check.ErrorMatches, "cannot use run bootloader root-dir with a recovery flag")
 }
+
+func (s *SnapSuite) TestDebugBootvarsRecoveryAndUEFI(c *check.C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+	restore = efi.MockVars(map[string][]byte{
+		"LoaderDevicePartUUID-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f": bootloadertest.UTF16Bytes("A9F5C949-AB89-5B47-A7BF-56DD28F96E65"),
+	}, nil)
+	defer restore()
+	bloader := bootloadertest.Mock("mock", c.MkDir())
+	bootloader.Force(bloader)
+	err := bloader.SetBootVars(map[string]string{
+		"snapd_recovery_system": "1234",
+		"snapd_recovery_mode":   "install",
+		"snapd_recovery_kernel": "/snaps/pc-kernel_1.snap",
+	})
+	c.Assert(err, check.IsNil)
+
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "boot-vars", "--recovery", "--uefi"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `snapd_recovery_mode=install
+snapd_recovery_system=1234
+snapd_recovery_kernel=/snaps/pc-kernel_1.snap
+snap_kernel=
+snap_try_kernel=
+kernel_status=
+recovery_system_status=
+try_recovery_system=
+LoaderDevicePartUUID-4a67b082-0a4c-41cf-b6c7-440b29bb8c4f=A9F5C949-AB89-5B47-A7BF-56DD28F96E65
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"debug", "boot-vars", "--recovery", "--root-dir", boot.InitramfsUbuntuBootDir})
+	c.Assert(err, check.ErrorMatches, "cannot use run bootloader root-dir with a recovery flag")
+}
@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/release"
+)
+
+type cmdKernelStatus struct {
+	clientMixin
+	Cancel bool `long:"cancel"`
+}
+
+var longKernelStatusHelp = i18n.G(`
+The kernel-status command shows the kernel and base snaps the device boots
+and, if any, the kernel and base snaps that are pending a try on the next
+boot or that are being tried right now.
+
+With --cancel the kernel and base snaps pending a try are dropped, so that the
+device boots the current snaps on the next boot. Snaps that are being tried
+already cannot be cancelled, nor can the try snaps of changes that are still
+in progress.
+`)
+
+func init() {
+	cmd := addDebugCommand("kernel-status",
+		i18n.G("Show or cancel the pending try of kernel and base snaps"),
+		longKernelStatusHelp,
+		func() flags.Commander {
+			return &cmdKernelStatus{}
+		}, map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"cancel": i18n.G("Cancel the pending try of kernel and base snaps"),
+		}, nil)
+	if release.OnClassic {
+		cmd.hidden = true
+	}
+}
+
+func fmtBootSnap(s *client.BootSnap) string {
+	return fmt.Sprintf("%s (%s)", s.Snap, s.Revision)
+}
+
+func printBootSnapStatus(w io.Writer, typ string, st *client.BootSnapStatus) {
+	fmt.Fprintf(w, "%s:\t%s\n", typ, fmtBootSnap(&st.Current))
+	if st.Try == nil {
+		return
+	}
+	var status string
+	switch st.Status {
+	case "try":
+		status = i18n.G("reboot pending")
+	case "trying":
+		status = i18n.G("being tried")
+	default:
+		status = st.Status
+	}
+	fmt.Fprintf(w, "try-%s:\t%s, %s\n", typ, fmtBootSnap(st.Try), status)
+}
+
+func (x *cmdKernelStatus) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	if release.OnClassic {
+		return errors.New(i18n.G(`the "kernel-status" command is not available on classic systems`))
+	}
+
+	status, err := x.client.BootStatus()
+	if err != nil {
+		return err
+	}
+	if x.Cancel {
+		if !status.RebootPending {
+			return errors.New(i18n.G("no kernel or base snap is pending a try"))
+		}
+		status, err = x.client.CancelBootTry()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(Stdout, i18n.G("Pending try of kernel and base snaps cancelled."))
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	printBootSnapStatus(w, "kernel", &status.Kernel)
+	printBootSnapStatus(w, "base", &status.Base)
+	fmt.Fprintf(w, "reboot-pending:\t%t\n", status.RebootPending)
+	if len(status.Issues) > 0 {
+		fmt.Fprintf(w, "last-boot-issues:\n")
+		for _, issue := range status.Issues {
+			fmt.Fprintf(w, "  - %s\n", issue)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/release"
+)
+
+const bootStatusTryJSON = `{"type": "sync", "result": {
+  "kernel": {"current": {"snap": "pc-kernel", "revision": "3"}, "try": {"snap": "pc-kernel", "revision": "4"}, "status": "try"},
+  "base": {"current": {"snap": "core20", "revision": "1"}},
+  "reboot-pending": true
+}}`
+
+const bootStatusNoTryJSON = `{"type": "sync", "result": {
+  "kernel": {"current": {"snap": "pc-kernel", "revision": "3"}},
+  "base": {"current": {"snap": "core20", "revision": "1"}},
+  "reboot-pending": false
+}}`
+
+func (s *SnapSuite) TestDebugKernelStatus(c *check.C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/boot")
+		fmt.Fprintln(w, bootStatusTryJSON)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "kernel-status"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, `kernel:          pc-kernel (3)
+try-kernel:      pc-kernel (4), reboot pending
+base:            core20 (1)
+reboot-pending:  true
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugKernelStatusIssues(c *check.C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/boot")
+		fmt.Fprintln(w, `{"type": "sync", "result": {
+  "kernel": {"current": {"snap": "pc-kernel", "revision": "3"}},
+  "base": {"current": {"snap": "core20", "revision": "1"}},
+  "reboot-pending": false,
+  "issues": ["try kernel \"pc-kernel_4.snap\" failed to boot, booted kernel \"pc-kernel_3.snap\""]
+}}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "kernel-status"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `kernel:          pc-kernel (3)
+base:            core20 (1)
+reboot-pending:  false
+last-boot-issues:
+  - try kernel "pc-kernel_4.snap" failed to boot, booted kernel "pc-kernel_3.snap"
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugKernelStatusCancel(c *check.C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.URL.Path, check.Equals, "/v2/boot")
+		switch n {
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			fmt.Fprintln(w, bootStatusTryJSON)
+		case 2:
+			c.Check(r.Method, check.Equals, "POST")
+			body, err := ioutil.ReadAll(r.Body)
+			c.Assert(err, check.IsNil)
+			c.Check(string(body), check.Equals, `{"action":"cancel-try"}`+"\n")
+			fmt.Fprintln(w, bootStatusNoTryJSON)
+		default:
+			c.Fatalf("unexpected request %d", n)
+		}
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "kernel-status", "--cancel"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 2)
+	c.Check(s.Stdout(), check.Equals, `Pending try of kernel and base snaps cancelled.
+kernel:          pc-kernel (3)
+base:            core20 (1)
+reboot-pending:  false
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestDebugKernelStatusCancelNothingPending(c *check.C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "GET")
+		fmt.Fprintln(w, bootStatusNoTryJSON)
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "kernel-status", "--cancel"})
+	c.Assert(err, check.ErrorMatches, "no kernel or base snap is pending a try")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapSuite) TestDebugKernelStatusNotOnClassic(c *check.C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"debug", "kernel-status"})
+	c.Assert(err, check.ErrorMatches, `the "kernel-status" command is not available on classic systems`)
+}
@@ -73,6 +73,13 @@ var timingsTests = []timingsCmdArgs{{
 		"41   Done          210ms            -  lane 1 task baz summary\n" +
 		"42   Done          310ms            -  lane 1 task boo summary\n" +
 		"43   Done          310ms            -  lane 0 task doh summary\n\n",
+}, {
+	args: "debug timings 2",
+	stdout: "ID   Status        Doing      Undoing  Summary\n" +
+		"50   Undone        510ms         20ms  task foo summary\n" +
+		" ^                   5ms            -    foo summary\n" +
+		" ^                     -          2ms    undo foo summary\n" +
+		"  ^                    -          1ms      undo bar summary\n\n",
 }, {
 	args: "debug timings 1 --verbose",
 	stdout: "ID   Status        Doing      Undoing  Label  Summary\n" +
@@ -185,6 +192,19 @@ func (s *SnapSuite) mockCmdTimingsAPI(c *C) {
 						]},
 					"42":{"doing-time":310000000, "status": "Done", "lane": 1, "ready-time": "2016-04-23T01:02:04Z", "kind": "boo", "summary": "lane 1 task boo summary"}
 				}}]}`)
+			case changeID == "2":
+				// a task with both doing and undoing timings
+				fmt.Fprintln(w, `{"type":"sync","status-code":200,"status":"OK","result":[
+				{"change-id":"2", "change-timings":{
+					"50":{"doing-time":510000000, "undoing-time":20000000, "status": "Undone", "kind": "foo", "summary": "task foo summary",
+						"doing-timings":[
+							{"label":"foo", "summary": "foo summary", "duration": 5000001}
+						],
+						"undoing-timings":[
+							{"label":"undo-foo", "summary": "undo foo summary", "duration": 2000001},
+							{"level":1, "label":"undo-bar", "summary": "undo bar summary", "duration": 1000002}
+						]}
+				}}]}`)
 			case ensure == "seed" && all == "false":
 				fmt.Fprintln(w, `{"type":"sync","status-code":200,"status":"OK","result":[
 					{"change-id":"1",
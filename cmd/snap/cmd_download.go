@@ -41,6 +41,7 @@ type cmdDownload struct {
 	TargetDir string `long:"target-directory"`
 
 	CohortKey  string `long:"cohort"`
+	Streams    int    `long:"streams" default:"1"`
 	Positional struct {
 		Snap remoteSnapName
 	} `positional-args:"true" required:"true"`
@@ -50,6 +51,12 @@ var shortDownloadHelp = i18n.G("Download the given snap")
 var longDownloadHelp = i18n.G(`
 The download command downloads the given snap and its supporting assertions
 to the current directory with .snap and .assert file extensions, respectively.
+
+An interrupted download is resumed from where it stopped the next time the
+same snap revision is downloaded, the already downloaded data is verified
+together with the rest of the snap. With --streams large snaps are downloaded
+using the given number of parallel requests, which can speed up downloads over
+slow or flaky links.
 `)
 
 func init() {
@@ -64,6 +71,8 @@ func init() {
 		"basename": i18n.G("Use this basename for the snap and assertion files (defaults to <snap>_<revision>)"),
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"target-directory": i18n.G("Download to this directory (defaults to the current directory)"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"streams": i18n.G("Use up to this number of parallel requests to download large snaps"),
 	}), []argDesc{{
 		name: "<snap>",
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -146,6 +155,7 @@ func (x *cmdDownload) downloadFromStore(snapName string, revision snap.Revision)
 		Revision:  revision,
 		// if something goes wrong, don't force it to start over again
 		LeavePartialOnError: true,
+		Streams:             x.Streams,
 	}
 	return downloadDirect(snapName, revision, dlOpts)
 }
@@ -157,6 +167,9 @@ func (x *cmdDownload) Execute(args []string) error {
 	if err := x.setChannelFromCommandline(); err != nil {
 		return err
 	}
+	if x.Streams < 1 {
+		return fmt.Errorf(i18n.G("cannot use less than one stream to download"))
+	}
 
 	if len(args) > 0 {
 		return ErrExtraArgs
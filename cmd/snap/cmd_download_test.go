@@ -94,6 +94,8 @@ func (s *SnapSuite) TestDownloadDirect(c *check.C) {
 		c.Check(dlOpts.TargetDir, check.Equals, "some-target-dir")
 		c.Check(dlOpts.Channel, check.Equals, "some-channel")
 		c.Check(dlOpts.CohortKey, check.Equals, "some-cohort")
+		c.Check(dlOpts.LeavePartialOnError, check.Equals, true)
+		c.Check(dlOpts.Streams, check.Equals, 1)
 		n++
 		return nil
 	})
@@ -112,6 +114,25 @@ func (s *SnapSuite) TestDownloadDirect(c *check.C) {
 	c.Check(n, check.Equals, 1)
 }
 
+func (s *SnapSuite) TestDownloadDirectStreams(c *check.C) {
+	var n int
+	restore := snapCmd.MockDownloadDirect(func(snapName string, revision snap.Revision, dlOpts image.DownloadOptions) error {
+		c.Check(snapName, check.Equals, "a-snap")
+		c.Check(dlOpts.Streams, check.Equals, 4)
+		n++
+		return nil
+	})
+	defer restore()
+
+	_, err := snapCmd.Parser(snapCmd.Client()).ParseArgs([]string{"download", "--streams=4", "a-snap"})
+	c.Assert(err, check.IsNil)
+	c.Check(n, check.Equals, 1)
+
+	_, err = snapCmd.Parser(snapCmd.Client()).ParseArgs([]string{"download", "--streams=0", "a-snap"})
+	c.Assert(err, check.ErrorMatches, "cannot use less than one stream to download")
+	c.Check(n, check.Equals, 1)
+}
+
 func (s *SnapSuite) TestDownloadDirectErrors(c *check.C) {
 	var n int
 	restore := snapCmd.MockDownloadDirect(func(snapName string, revision snap.Revision, dlOpts image.DownloadOptions) error {
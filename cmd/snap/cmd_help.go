@@ -225,7 +225,12 @@ var helpCategories = []helpCategory{
 	}, {
 		Label:       i18n.G("Device"),
 		Description: i18n.G("manage device"),
-		Commands:    []string{"model", "reboot", "recovery"},
+		Commands:    []string{"model", "reboot", "recovery", "validate"},
+	}, {
+		Label:       i18n.G("Quota Groups"),
+		Other:       true,
+		Description: i18n.G("manage quota groups"),
+		Commands:    []string{"set-quota", "remove-quota", "quotas", "quota"},
 	}, {
 		Label:       i18n.G("Warnings"),
 		Other:       true,
@@ -22,6 +22,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
@@ -68,10 +69,12 @@ model assertion.
 		"gadget",
 		"kernel",
 		"revision",
+		"storage-safety",
 		"store",
 		"system-user-authority",
 		"timestamp",
 		"required-snaps",
+		"snaps",
 		"device-key-sha3-384",
 		"device-key",
 	}
@@ -271,6 +274,26 @@ func (x *cmdModel) Execute(args []string) error {
 					fmt.Fprintf(w, "  - %s\n", headerStringElem)
 				}
 
+			// list of maps of the snaps in uc20 models
+			case "snaps":
+				headerIfaceList, ok := headerValue.([]interface{})
+				if !ok {
+					return invalidTypeErr
+				}
+				if len(headerIfaceList) == 0 {
+					continue
+				}
+				fmt.Fprintf(w, "%s:\t\n", headerName)
+				for _, elem := range headerIfaceList {
+					snapHeaders, ok := elem.(map[string]interface{})
+					if !ok {
+						return invalidTypeErr
+					}
+					if err := printModelSnap(w, snapHeaders); err != nil {
+						return invalidTypeErr
+					}
+				}
+
 			//timestamp needs to be formatted with fmtTime from the timeMixin
 			case "timestamp":
 				timestamp, ok := headerValue.(string)
@@ -335,3 +358,43 @@ func (x *cmdModel) Execute(args []string) error {
 
 	return w.Flush()
 }
+
+// modelSnapOrdering is the order in which the headers of the entries of the
+// snaps header of uc20 models are printed
+var modelSnapOrdering = [...]string{
+	"name",
+	"id",
+	"type",
+	"default-channel",
+	"presence",
+	"modes",
+}
+
+// printModelSnap prints an entry of the snaps header of uc20 models as a
+// yaml-like list item
+func printModelSnap(w io.Writer, snapHeaders map[string]interface{}) error {
+	prefix := "  - "
+	for _, name := range modelSnapOrdering {
+		value, ok := snapHeaders[name]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, name, v)
+		case []interface{}:
+			fmt.Fprintf(w, "%s%s:\n", prefix, name)
+			for _, elem := range v {
+				s, ok := elem.(string)
+				if !ok {
+					return fmt.Errorf(invalidTypeMessage, name)
+				}
+				fmt.Fprintf(w, "      - %s\n", s)
+			}
+		default:
+			return fmt.Errorf(invalidTypeMessage, name)
+		}
+		prefix = "    "
+	}
+	return nil
+}
@@ -591,3 +591,43 @@ func (s *SnapSuite) TestSerialAssertionSerialAssertionMissing(c *check.C) {
 	c.Assert(s.Stdout(), check.Equals, "")
 	c.Assert(s.Stderr(), check.Equals, "")
 }
+
+func (s *SnapSuite) TestModelVerboseUC20(c *check.C) {
+	s.RedirectClientToTestServer(
+		makeHappyTestServerHandler(
+			c,
+			simpleHappyResponder(happyUC20ModelAssertionResponse),
+			simpleHappyResponder(happySerialUC20AssertionResponse),
+			simpleAssertionAccountResponder(happyAccountAssertionResponse),
+		))
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"model", "--verbose", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `
+brand-id:      testrootorg
+model:         test-snapd-core-20-amd64
+grade:         secured
+serial:        7777
+architecture:  amd64
+base:          core20
+timestamp:     2018-09-11T22:00:00Z
+snaps:         
+  - name: pc
+    id: UqFziVZDHLSyO3TqSWgNBoAdHbLI4dAH
+    type: gadget
+    default-channel: 20/edge
+  - name: pc-kernel
+    id: pYVQrBcKmBa0mZ4CCN7ExT6jH8rY1hza
+    type: kernel
+    default-channel: 20/edge
+  - name: core20
+    id: DLqre5XGLbDqg9jPtiAhRRjDuPVa5X1q
+    type: base
+    default-channel: latest/stable
+  - name: snapd
+    id: PMrrV4ml8uWuEUDBT8dSGnKUYbevVhc4
+    type: snapd
+    default-channel: latest/stable
+`[1:])
+	c.Check(s.Stderr(), check.Equals, "")
+}
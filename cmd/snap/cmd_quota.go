@@ -0,0 +1,226 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var shortSetQuotaHelp = i18n.G("Create or update a quota group")
+var longSetQuotaHelp = i18n.G(`
+The set-quota command updates or creates a quota group with the specified set of
+snaps.
+
+A quota group sets resource limits on the set of snaps it contains. Currently
+only maximum memory usage is supported. The limit applies to all the services
+of the snaps in the group, taken together.
+
+If the quota group does not exist, it is created with the given memory limit
+and snaps, in which case --memory is required. If the quota group exists, the
+given snaps are added to it and, if --memory is given, its memory limit is
+updated.
+
+$ snap set-quota iot-group --memory=512MB app1 app2
+`)
+
+var shortQuotaHelp = i18n.G("Show quota group for a set of snaps")
+var longQuotaHelp = i18n.G(`
+The quota command shows information about a quota group, including the set of
+snaps it contains, its resource limits and its current resource usage.
+`)
+
+var shortQuotasHelp = i18n.G("Show quota groups")
+var longQuotasHelp = i18n.G(`
+The quotas command shows all quota groups with their resource limits and their
+current resource usage.
+`)
+
+var shortRemoveQuotaHelp = i18n.G("Remove quota group")
+var longRemoveQuotaHelp = i18n.G(`
+The remove-quota command removes the given quota group.
+
+The snaps of the removed quota group are not removed, they are just no longer
+subject to its resource limits.
+`)
+
+func init() {
+	addCommand("set-quota", shortSetQuotaHelp, longSetQuotaHelp,
+		func() flags.Commander { return &cmdSetQuota{} },
+		waitDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"memory": i18n.G("Memory limit for the quota group (e.g. 512MB)"),
+		}), nil)
+	addCommand("quota", shortQuotaHelp, longQuotaHelp,
+		func() flags.Commander { return &cmdQuota{} },
+		nil, nil)
+	addCommand("quotas", shortQuotasHelp, longQuotasHelp,
+		func() flags.Commander { return &cmdQuotas{} },
+		nil, nil)
+	addCommand("remove-quota", shortRemoveQuotaHelp, longRemoveQuotaHelp,
+		func() flags.Commander { return &cmdRemoveQuota{} },
+		waitDescs, nil)
+}
+
+type cmdSetQuota struct {
+	waitMixin
+
+	MemoryMax  string `long:"memory" optional:"true"`
+	Positional struct {
+		GroupName string              `positional-arg-name:"<group-name>" required:"true"`
+		Snaps     []installedSnapName `positional-arg-name:"<snap>" optional:"true"`
+	} `positional-args:"yes"`
+}
+
+func (x *cmdSetQuota) Execute(args []string) error {
+	if len(args) != 0 {
+		return ErrExtraArgs
+	}
+
+	var maxMemory uint64
+	if x.MemoryMax != "" {
+		value, err := strutil.ParseByteSize(x.MemoryMax)
+		if err != nil {
+			return err
+		}
+		if value == 0 {
+			return fmt.Errorf(i18n.G("cannot use a memory limit of zero"))
+		}
+		maxMemory = uint64(value)
+	}
+
+	groupName := x.Positional.GroupName
+	names := installedSnapNames(x.Positional.Snaps)
+
+	if maxMemory == 0 && len(names) == 0 {
+		return fmt.Errorf(i18n.G("no snaps or memory limit given for quota group %q"), groupName)
+	}
+
+	changeID, err := x.client.EnsureQuota(groupName, names, maxMemory)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+type cmdQuota struct {
+	clientMixin
+
+	Positional struct {
+		GroupName string `positional-arg-name:"<group-name>" required:"true"`
+	} `positional-args:"yes"`
+}
+
+func (x *cmdQuota) Execute(args []string) error {
+	if len(args) != 0 {
+		return ErrExtraArgs
+	}
+
+	group, err := x.client.GetQuotaGroup(x.Positional.GroupName)
+	if err != nil {
+		return err
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w, "name:\t%s\n", group.GroupName)
+	fmt.Fprintf(w, "constraints:\n")
+	fmt.Fprintf(w, "  memory:\t%s\n", strutil.SizeToStr(int64(group.MaxMemory)))
+	fmt.Fprintf(w, "current:\n")
+	fmt.Fprintf(w, "  memory:\t%s\n", strutil.SizeToStr(int64(group.CurrentMemory)))
+	if len(group.Snaps) > 0 {
+		fmt.Fprintf(w, "snaps:\n")
+		for _, snapName := range group.Snaps {
+			fmt.Fprintf(w, "  - %s\n", snapName)
+		}
+	}
+	return nil
+}
+
+type cmdQuotas struct {
+	clientMixin
+}
+
+func (x *cmdQuotas) Execute(args []string) error {
+	if len(args) != 0 {
+		return ErrExtraArgs
+	}
+
+	groups, err := x.client.Quotas()
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No quota groups defined."))
+		return nil
+	}
+
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintln(w, i18n.G("Quota\tConstraints\tCurrent\tSnaps"))
+	for _, group := range groups {
+		snaps := "-"
+		if len(group.Snaps) > 0 {
+			snaps = strings.Join(group.Snaps, ",")
+		}
+		fmt.Fprintf(w, "%s\tmemory=%s\tmemory=%s\t%s\n", group.GroupName,
+			strutil.SizeToStr(int64(group.MaxMemory)), strutil.SizeToStr(int64(group.CurrentMemory)), snaps)
+	}
+	return nil
+}
+
+type cmdRemoveQuota struct {
+	waitMixin
+
+	Positional struct {
+		GroupName string `positional-arg-name:"<group-name>" required:"true"`
+	} `positional-args:"yes"`
+}
+
+func (x *cmdRemoveQuota) Execute(args []string) error {
+	if len(args) != 0 {
+		return ErrExtraArgs
+	}
+
+	changeID, err := x.client.RemoveQuotaGroup(x.Positional.GroupName)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	main "github.com/snapcore/snapd/cmd/snap"
+)
+
+type quotaSuite struct {
+	BaseSnapSuite
+}
+
+var _ = Suite(&quotaSuite{})
+
+func (s *quotaSuite) makeFakeQuotaPostHandler(c *C, expectedBody map[string]interface{}) func(w http.ResponseWriter, r *http.Request) {
+	n := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/quotas")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, expectedBody)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "status-code": 202, "change": "42"}`)
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected request %d to %s", n, r.URL.Path)
+		}
+		n++
+	}
+}
+
+func (s *quotaSuite) TestSetQuota(c *C) {
+	s.RedirectClientToTestServer(s.makeFakeQuotaPostHandler(c, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "iot-group",
+		"snaps":      []interface{}{"app1", "app2"},
+		"max-memory": json.Number("512000000"),
+	}))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"set-quota", "iot-group", "--memory=512MB", "app1", "app2"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *quotaSuite) TestSetQuotaOnlySnaps(c *C) {
+	s.RedirectClientToTestServer(s.makeFakeQuotaPostHandler(c, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "iot-group",
+		"snaps":      []interface{}{"app3"},
+	}))
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"set-quota", "iot-group", "app3"})
+	c.Assert(err, IsNil)
+}
+
+func (s *quotaSuite) TestSetQuotaErrors(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"set-quota", "iot-group"}, `no snaps or memory limit given for quota group "iot-group"`},
+		{[]string{"set-quota", "iot-group", "--memory=512"}, `cannot parse "512": need a number with a unit as input`},
+		{[]string{"set-quota", "iot-group", "--memory=0B", "app1"}, `cannot use a memory limit of zero`},
+		{[]string{"set-quota"}, `the required argument .*<group-name>.* was not provided`},
+	} {
+		_, err := main.Parser(main.Client()).ParseArgs(tc.args)
+		c.Check(err, ErrorMatches, tc.err, Commentf("%v", tc.args))
+	}
+}
+
+func (s *quotaSuite) TestGetQuota(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/quotas/iot-group")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"group-name": "iot-group", "snaps": ["app1", "app2"], "max-memory": 512000000, "current-memory": 123456789}}`)
+	})
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "iot-group"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(s.Stdout(), Equals, `name:  iot-group
+constraints:
+  memory:  512MB
+current:
+  memory:  123MB
+snaps:
+  - app1
+  - app2
+`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *quotaSuite) TestGetQuotaNotFound(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "cannot find quota group \"foo\""}}`)
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"quota", "foo"})
+	c.Assert(err, ErrorMatches, `cannot get quota group: cannot find quota group "foo"`)
+}
+
+func (s *quotaSuite) TestQuotas(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/quotas")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+			{"group-name": "db", "snaps": ["postgres"], "max-memory": 1000000000, "current-memory": 500000000},
+			{"group-name": "empty", "max-memory": 1000, "current-memory": 0}
+		]}`)
+	})
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"quotas"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(s.Stdout(), Equals, `Quota  Constraints  Current       Snaps
+db     memory=1GB   memory=500MB  postgres
+empty  memory=1kB   memory=0B     -
+`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *quotaSuite) TestQuotasNone(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"quotas"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "No quota groups defined.\n")
+}
+
+func (s *quotaSuite) TestRemoveQuota(c *C) {
+	s.RedirectClientToTestServer(s.makeFakeQuotaPostHandler(c, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "iot-group",
+	}))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"remove-quota", "iot-group"})
+	c.Assert(err, IsNil)
+	c.Check(rest, HasLen, 0)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "")
+}
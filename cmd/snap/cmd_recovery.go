@@ -30,20 +30,24 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/strutil"
 )
 
 type cmdRecovery struct {
 	clientMixin
 	colorMixin
 
-	ShowKeys bool `long:"show-keys"`
+	ShowKeys       bool `long:"show-keys"`
+	RegenerateKeys bool `long:"regenerate-keys"`
 }
 
 var shortRecoveryHelp = i18n.G("List available recovery systems")
 var longRecoveryHelp = i18n.G(`
-The recovery command lists the available recovery systems.
+The recovery command lists the available recovery systems, together with their models and the modes they can be used with.
 
 With --show-keys it displays recovery keys that can be used to unlock the encrypted partitions if the device-specific automatic unlocking does not work.
+
+With --regenerate-keys it replaces the recovery keys with new ones and displays them.
 `)
 
 func init() {
@@ -54,6 +58,8 @@ func init() {
 		map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"show-keys": i18n.G("Show recovery keys (if available) to unlock encrypted partitions."),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"regenerate-keys": i18n.G("Replace the recovery keys with new ones and show them."),
 		}), nil)
 }
 
@@ -64,12 +70,37 @@ func notesForSystem(sys *client.System) string {
 	return "-"
 }
 
-func (x *cmdRecovery) showKeys(w io.Writer) error {
+// modesForSystem returns the modes the system can be used with, as
+// advertised by its actions.
+func modesForSystem(sys *client.System) string {
+	if len(sys.Actions) == 0 {
+		return "-"
+	}
+	modes := make([]string, 0, len(sys.Actions))
+	for _, action := range sys.Actions {
+		if strutil.ListContains(modes, action.Mode) {
+			continue
+		}
+		modes = append(modes, action.Mode)
+	}
+	return strings.Join(modes, ",")
+}
+
+func (x *cmdRecovery) showKeys(w io.Writer, regenerate bool) error {
+	cmd := "show-keys"
+	if regenerate {
+		cmd = "regenerate-keys"
+	}
 	if release.OnClassic {
-		return errors.New(`command "show-keys" is not available on classic systems`)
+		return fmt.Errorf("command %q is not available on classic systems", cmd)
 	}
 	var srk *client.SystemRecoveryKeysResponse
-	err := x.client.SystemRecoveryKeys(&srk)
+	var err error
+	if regenerate {
+		err = x.client.RegenerateSystemRecoveryKeys(&srk)
+	} else {
+		err = x.client.SystemRecoveryKeys(&srk)
+	}
 	if err != nil {
 		return err
 	}
@@ -87,8 +118,11 @@ func (x *cmdRecovery) Execute(args []string) error {
 	w := tabWriter()
 	defer w.Flush()
 
-	if x.ShowKeys {
-		return x.showKeys(w)
+	if x.ShowKeys && x.RegenerateKeys {
+		return errors.New(i18n.G("cannot use --show-keys and --regenerate-keys together"))
+	}
+	if x.ShowKeys || x.RegenerateKeys {
+		return x.showKeys(w, x.RegenerateKeys)
 	}
 
 	systems, err := x.client.ListSystems()
@@ -100,13 +134,14 @@ func (x *cmdRecovery) Execute(args []string) error {
 		return nil
 	}
 
-	fmt.Fprintf(w, i18n.G("Label\tBrand%s\tModel\tNotes\n"), fillerPublisher(esc))
+	fmt.Fprintf(w, i18n.G("Label\tBrand%s\tModel\tModes\tNotes\n"), fillerPublisher(esc))
 	for _, sys := range systems {
 		// doing it this way because otherwise it's a sea of %s\t%s\t%s
 		line := []string{
 			sys.Label,
 			shortPublisher(esc, &sys.Brand),
 			sys.Model.Model,
+			modesForSystem(&sys),
 			notesForSystem(&sys),
 		}
 		fmt.Fprintln(w, strings.Join(line, "\t"))
@@ -33,11 +33,15 @@ func (s *SnapSuite) TestRecoveryHelp(c *C) {
 	msg := `Usage:
   snap.test recovery [recovery-OPTIONS]
 
-The recovery command lists the available recovery systems.
+The recovery command lists the available recovery systems, together with their
+models and the modes they can be used with.
 
 With --show-keys it displays recovery keys that can be used to unlock the
 encrypted partitions if the device-specific automatic unlocking does not work.
 
+With --regenerate-keys it replaces the recovery keys with new ones and displays
+them.
+
 [recovery command options]
       --color=[auto|never|always]     Use a little bit of color to highlight
                                       some things. (default: auto)
@@ -45,6 +49,8 @@ encrypted partitions if the device-specific automatic unlocking does not work.
                                       legibility. (default: auto)
       --show-keys                     Show recovery keys (if available) to
                                       unlock encrypted partitions.
+      --regenerate-keys               Replace the recovery keys with new ones
+                                      and show them.
 `
 	s.testSubCommandHelp(c, "recovery", msg)
 }
@@ -88,11 +94,7 @@ func (s *SnapSuite) TestRecovery(c *C) {
                     "id": "brand-id-2",
                     "username": "brand-2",
                     "display-name": "Other Publishing"
-                },
-                "actions": [
-                    {"title": "recover", "mode": "recover"},
-                    {"title": "reinstall", "mode": "install"}
-                ]
+                }
            }
         ]
 }}`)
@@ -106,9 +108,9 @@ func (s *SnapSuite) TestRecovery(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(rest, DeepEquals, []string{})
 	c.Check(s.Stdout(), Equals, `
-Label     Brand    Model       Notes
-20200101  brand-1  model-id-1  current
-20200802  brand-2  model-id-2  -
+Label     Brand    Model       Modes            Notes
+20200101  brand-1  model-id-1  recover,install  current
+20200802  brand-2  model-id-2  -                -
 `[1:])
 	c.Check(s.Stderr(), Equals, "")
 }
@@ -192,3 +194,47 @@ reinstall:  1234
 	c.Check(s.Stderr(), Equals, "")
 	c.Check(n, Equals, 1)
 }
+
+func (s *SnapSuite) TestRecoveryRegenerateKeysHappy(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/system-recovery-keys")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "regenerate",
+			})
+			fmt.Fprintln(w, `{"type": "sync", "result": {"recovery-key": "61665-00531-54469-09783-47273-19035-40077-28287", "reinstall-key":"1234"}}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--regenerate-keys"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `recovery:   61665-00531-54469-09783-47273-19035-40077-28287
+reinstall:  1234
+`)
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 1)
+}
+
+func (s *SnapSuite) TestRecoveryRegenerateKeysErrors(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected server call")
+	})
+
+	restore := release.MockOnClassic(true)
+	defer restore()
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--regenerate-keys"})
+	c.Assert(err, ErrorMatches, `command "regenerate-keys" is not available on classic systems`)
+
+	_, err = snap.Parser(snap.Client()).ParseArgs([]string{"recovery", "--show-keys", "--regenerate-keys"})
+	c.Assert(err, ErrorMatches, "cannot use --show-keys and --regenerate-keys together")
+}
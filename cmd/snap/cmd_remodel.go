@@ -20,11 +20,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/i18n"
 )
 
@@ -36,11 +39,17 @@ revision or a full new model.
 
 In the process it applies any implied changes to the device: new required
 snaps, new kernel or gadget etc.
+
+With --offline the remodel uses the snaps given with --snap and the assertions
+given with --assertion, instead of downloading them from the store.
 `)
 )
 
 type cmdRemodel struct {
 	waitMixin
+	Offline        bool     `long:"offline"`
+	SnapFiles      []string `long:"snap"`
+	AssertionFiles []string `long:"assertion"`
 	RemodelOptions struct {
 		NewModelFile flags.Filename
 	} `positional-args:"true" required:"true"`
@@ -52,7 +61,14 @@ func init() {
 		longRemodelHelp,
 		func() flags.Commander {
 			return &cmdRemodel{}
-		}, nil, []argDesc{{
+		}, waitDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"offline": i18n.G("Do not talk to the store, use the given snaps and assertions"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"snap": i18n.G("Path to a local snap file to use for the remodel (requires --offline)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"assertion": i18n.G("Path to a local assertion file to use for the remodel (requires --offline)"),
+		}), []argDesc{{
 			// TRANSLATORS: This needs to begin with < and end with >
 			name: i18n.G("<new model file>"),
 			// TRANSLATORS: This should not start with a lowercase letter.
@@ -61,16 +77,61 @@ func init() {
 	cmd.hidden = true
 }
 
+// printRemodelSummary prints what changes between the current and the new
+// model, in particular the kernel, gadget and base swaps.
+func printRemodelSummary(w io.Writer, current, new *asserts.Model) {
+	fmt.Fprintf(w, i18n.G("Remodel from %s/%s (revision %d) to %s/%s (revision %d)\n"),
+		current.BrandID(), current.Model(), current.Revision(),
+		new.BrandID(), new.Model(), new.Revision())
+	for _, swap := range []struct {
+		what     string
+		from, to string
+	}{
+		{"kernel", current.Kernel(), new.Kernel()},
+		{"gadget", current.Gadget(), new.Gadget()},
+		{"base", current.Base(), new.Base()},
+	} {
+		if swap.from == swap.to {
+			continue
+		}
+		fmt.Fprintf(w, "  %s:\t%s -> %s\n", swap.what, swap.from, swap.to)
+	}
+}
+
 func (x *cmdRemodel) Execute(args []string) error {
 	if len(args) > 0 {
 		return ErrExtraArgs
 	}
+	if !x.Offline && (len(x.SnapFiles) > 0 || len(x.AssertionFiles) > 0) {
+		return errors.New(i18n.G("cannot use --snap or --assertion without --offline"))
+	}
 	newModelFile := x.RemodelOptions.NewModelFile
 	modelData, err := ioutil.ReadFile(string(newModelFile))
 	if err != nil {
 		return err
 	}
-	changeID, err := x.client.Remodel(modelData)
+	rawNewModel, err := asserts.Decode(modelData)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot decode new model assertion: %v"), err)
+	}
+	newModel, ok := rawNewModel.(*asserts.Model)
+	if !ok {
+		return fmt.Errorf(i18n.G("%q is not a model assertion"), newModelFile)
+	}
+	currentModel, err := x.client.CurrentModelAssertion()
+	if err != nil {
+		return fmt.Errorf("cannot remodel: %v", err)
+	}
+	w := tabWriter()
+	printRemodelSummary(w, currentModel, newModel)
+	w.Flush()
+
+	var changeID string
+	if x.Offline {
+		changeID, err = x.client.RemodelOffline(modelData, x.SnapFiles, x.AssertionFiles)
+	} else {
+		changeID, err = x.client.Remodel(modelData)
+	}
 	if err != nil {
 		return fmt.Errorf("cannot remodel: %v", err)
 	}
@@ -0,0 +1,107 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestRemodelOfflineNeeded(c *check.C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remodel", "--snap", "foo.snap", "new-model"})
+	c.Assert(err, check.ErrorMatches, "cannot use --snap or --assertion without --offline")
+}
+
+func (s *SnapSuite) TestRemodel(c *check.C) {
+	modelPath := filepath.Join(c.MkDir(), "new-model")
+	c.Assert(ioutil.WriteFile(modelPath, []byte(happyUC20ModelAssertionResponse), 0644), check.IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch {
+		case r.URL.Path == "/v2/model" && r.Method == "GET":
+			fmt.Fprintln(w, happyModelAssertionResponse)
+		case r.URL.Path == "/v2/model" && r.Method == "POST":
+			c.Check(r.Header.Get("Content-Type"), check.Equals, "application/json")
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "42"}`)
+		case r.URL.Path == "/v2/changes/42":
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected request %s %q", r.Method, r.URL.Path)
+		}
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"remodel", modelPath})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 3)
+	c.Check(s.Stdout(), check.Equals, fmt.Sprintf(`
+Remodel from mememe/test-model (revision 0) to testrootorg/test-snapd-core-20-amd64 (revision 0)
+  base:  core18 -> core20
+New model %s set
+`[1:], modelPath))
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) TestRemodelOffline(c *check.C) {
+	dir := c.MkDir()
+	modelPath := filepath.Join(dir, "new-model")
+	c.Assert(ioutil.WriteFile(modelPath, []byte(happyUC20ModelAssertionResponse), 0644), check.IsNil)
+	snapPath := filepath.Join(dir, "core20_1.snap")
+	c.Assert(ioutil.WriteFile(snapPath, []byte("snap-data"), 0644), check.IsNil)
+	assertPath := filepath.Join(dir, "core20_1.assert")
+	c.Assert(ioutil.WriteFile(assertPath, []byte("assert-data"), 0644), check.IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/model" && r.Method == "GET":
+			fmt.Fprintln(w, happyModelAssertionResponse)
+		case r.URL.Path == "/v2/model" && r.Method == "POST":
+			mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			c.Assert(err, check.IsNil)
+			c.Check(mediaType, check.Equals, "multipart/form-data")
+			form, err := multipart.NewReader(r.Body, params["boundary"]).ReadForm(1 << 20)
+			c.Assert(err, check.IsNil)
+			c.Check(form.Value["new-model"], check.DeepEquals, []string{happyUC20ModelAssertionResponse})
+			c.Assert(form.File["snap"], check.HasLen, 1)
+			c.Check(form.File["snap"][0].Filename, check.Equals, "core20_1.snap")
+			c.Assert(form.File["assertion"], check.HasLen, 1)
+			c.Check(form.File["assertion"][0].Filename, check.Equals, "core20_1.assert")
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "42"}`)
+		case r.URL.Path == "/v2/changes/42":
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("unexpected request %s %q", r.Method, r.URL.Path)
+		}
+	})
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"remodel", "--offline", "--snap", snapPath, "--assertion", assertPath, modelPath})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `(?s).*  base:  core18 -> core20\n.*`)
+}
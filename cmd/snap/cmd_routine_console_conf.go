@@ -58,6 +58,32 @@ func init() {
 	c.hidden = true
 }
 
+type cmdRoutineConsoleConfFinish struct {
+	clientMixin
+}
+
+var shortRoutineConsoleConfFinishHelp = i18n.G("Finish console-conf snapd routine")
+var longRoutineConsoleConfFinishHelp = i18n.G(`
+The console-conf-finish command ends synchronization with console-conf
+
+This command is used by console-conf once the device has been configured. It
+releases the delay of refreshes set up by console-conf-start.
+`)
+
+func init() {
+	c := addRoutineCommand("console-conf-finish", shortRoutineConsoleConfFinishHelp, longRoutineConsoleConfFinishHelp, func() flags.Commander {
+		return &cmdRoutineConsoleConfFinish{}
+	}, nil, nil)
+	c.hidden = true
+}
+
+func (x *cmdRoutineConsoleConfFinish) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+	return x.client.InternalConsoleConfFinish()
+}
+
 func printfFunc(msg string, format ...interface{}) func() {
 	return func() {
 		fmt.Fprintf(Stderr, msg, format...)
@@ -65,6 +91,10 @@ func printfFunc(msg string, format ...interface{}) func() {
 }
 
 func (x *cmdRoutineConsoleConfStart) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
 	var snapdReloadMsgOnce, systemReloadMsgOnce, snapRefreshMsgOnce sync.Once
 
 	for {
@@ -106,6 +136,8 @@ func (x *cmdRoutineConsoleConfStart) Execute(args []string) error {
 				// if we didn't reboot after 10 minutes something's probably broken
 				return fmt.Errorf("system didn't reboot after 10 minutes even though snapd daemon is in maintenance")
 			}
+			// some other kind of maintenance we don't know how to wait for
+			return err
 		}
 
 		if len(chgs) == 0 {
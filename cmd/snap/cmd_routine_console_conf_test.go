@@ -494,3 +494,75 @@ func (s *SnapSuite) TestRoutineConsoleConfStartKernelRefreshReboot(c *C) {
 	c.Check(s.Stderr(), testutil.Contains, "Snaps (pc-kernel) are refreshing, please wait...\n")
 	c.Assert(n, Equals, 3)
 }
+
+func (s *SnapSuite) TestRoutineConsoleConfStartUnknownMaintenanceErrorReturned(c *C) {
+	// make the command hit the API as fast as possible for testing
+	r := snap.MockSnapdAPIInterval(0)
+	defer r()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		// 1st time we hit the API there is a refresh ongoing and some
+		// maintenance snap does not know about
+		case 1:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/internal/console-conf-start")
+
+			fmt.Fprintf(w, `{
+				"type":"sync",
+				"status-code": 200,
+				"result": {
+					"active-auto-refreshes": ["1"],
+					"active-auto-refresh-snaps": ["pc-kernel"]
+				},
+				"maintenance": {
+					"kind": "some-other-kind",
+					"message": "something else is going on"
+				}
+			}`)
+
+		// 2nd time we hit the API, return nothing so that the client
+		// inspects the maintenance error it does not know how to wait for
+		case 2:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/internal/console-conf-start")
+		default:
+			c.Errorf("unexpected %s request (number %d) to %s", r.Method, n, r.URL.Path)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "console-conf-start"})
+	c.Assert(err, NotNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "Snaps (pc-kernel) are refreshing, please wait...\n")
+	c.Assert(n, Equals, 2)
+}
+
+func (s *SnapSuite) TestRoutineConsoleConfStartExtraArgs(c *C) {
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "console-conf-start", "extra"})
+	c.Assert(err, ErrorMatches, "too many arguments for command")
+}
+
+func (s *SnapSuite) TestRoutineConsoleConfFinish(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		switch n {
+		case 1:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/internal/console-conf-finish")
+
+			fmt.Fprintf(w, `{"type":"sync", "status-code": 200, "result": null}`)
+		default:
+			c.Errorf("unexpected request %v", n)
+		}
+	})
+
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"routine", "console-conf-finish"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "")
+	c.Assert(n, Equals, 1)
+}
@@ -66,6 +66,10 @@ Note that a later refresh will typically undo a revision override, taking the sn
 back to the current revision of the channel it's tracking.
 
 Use --name to set the instance name when installing from snap file.
+
+When several snaps are installed together, with --transaction=all-snaps all
+of them are reverted if the installation of any of them fails, instead of
+just the failing one.
 `)
 
 var longRemoveHelp = i18n.G(`
@@ -92,6 +96,14 @@ have developer access to the snap, either directly or through the
 store's collaboration feature, and to be logged in (see 'snap help login').
 
 Note a later refresh will typically undo a revision override.
+
+With --hold the auto-refreshes of the specified snaps, or of all snaps if none
+are specified, are held for the given duration (e.g. 72h) or, if no duration
+is given, forever. With --unhold the holds are removed again.
+
+When several snaps are refreshed together, with --transaction=all-snaps all
+of them are reverted if the refresh of any of them fails, instead of just the
+failing one.
 `)
 
 var longTryHelp = i18n.G(`
@@ -474,8 +486,9 @@ type cmdInstall struct {
 
 	Name string `long:"name"`
 
-	Cohort        string `long:"cohort"`
-	IgnoreRunning bool   `long:"ignore-running" hidden:"yes"`
+	Cohort        string                 `long:"cohort"`
+	IgnoreRunning bool                   `long:"ignore-running" hidden:"yes"`
+	Transaction   client.TransactionType `long:"transaction" choice:"all-snaps" choice:"per-snap"`
 	Positional    struct {
 		Snaps []remoteSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes" required:"yes"`
@@ -622,7 +635,7 @@ func (x *cmdInstall) Execute([]string) error {
 	if x.Name != "" {
 		return errors.New(i18n.G("cannot use instance name when installing multiple snaps"))
 	}
-	return x.installMany(names, nil)
+	return x.installMany(names, &client.SnapOptions{Transaction: x.Transaction})
 }
 
 type cmdRefresh struct {
@@ -632,19 +645,77 @@ type cmdRefresh struct {
 	channelMixin
 	modeMixin
 
-	Amend            bool   `long:"amend"`
-	Revision         string `long:"revision"`
-	Cohort           string `long:"cohort"`
-	LeaveCohort      bool   `long:"leave-cohort"`
-	List             bool   `long:"list"`
-	Time             bool   `long:"time"`
-	IgnoreValidation bool   `long:"ignore-validation"`
-	IgnoreRunning    bool   `long:"ignore-running" hidden:"yes"`
+	Amend            bool                   `long:"amend"`
+	Revision         string                 `long:"revision"`
+	Cohort           string                 `long:"cohort"`
+	LeaveCohort      bool                   `long:"leave-cohort"`
+	List             bool                   `long:"list"`
+	Time             bool                   `long:"time"`
+	IgnoreValidation bool                   `long:"ignore-validation"`
+	IgnoreRunning    bool                   `long:"ignore-running" hidden:"yes"`
+	Hold             string                 `long:"hold" optional:"yes" optional-value:"forever"`
+	Unhold           bool                   `long:"unhold"`
+	Transaction      client.TransactionType `long:"transaction" choice:"all-snaps" choice:"per-snap"`
 	Positional       struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
 }
 
+func (x *cmdRefresh) holdRefreshes(names []string) error {
+	var until time.Time
+	if x.Hold != "forever" {
+		dur, err := time.ParseDuration(x.Hold)
+		if err != nil || dur <= 0 {
+			return fmt.Errorf(i18n.G(`cannot hold refreshes for %q: expected "forever" or a positive duration (e.g. 72h)`), x.Hold)
+		}
+		until = timeNow().Add(dur)
+	}
+	changeID, err := x.client.HoldRefreshes(names, until)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	what := i18n.G("all snaps")
+	if len(names) > 0 {
+		what = strutil.Quoted(names)
+	}
+	if until.IsZero() {
+		// TRANSLATORS: %s is "all snaps" or a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.G("Auto-refresh of %s held forever.\n"), what)
+	} else {
+		// TRANSLATORS: the first %s is "all snaps" or a comma-separated list of quoted snap names, the second one a time
+		fmt.Fprintf(Stdout, i18n.G("Auto-refresh of %s held until %s.\n"), what, x.fmtTime(until))
+	}
+	return nil
+}
+
+func (x *cmdRefresh) unholdRefreshes(names []string) error {
+	changeID, err := x.client.UnholdRefreshes(names)
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	what := i18n.G("all snaps")
+	if len(names) > 0 {
+		what = strutil.Quoted(names)
+	}
+	// TRANSLATORS: %s is "all snaps" or a comma-separated list of quoted snap names
+	fmt.Fprintf(Stdout, i18n.G("Auto-refresh of %s no longer held.\n"), what)
+	return nil
+}
+
 func (x *cmdRefresh) refreshMany(snaps []string, opts *client.SnapOptions) error {
 	changeID, err := x.client.RefreshMany(snaps, opts)
 	if err != nil {
@@ -726,14 +797,17 @@ func (x *cmdRefresh) showRefreshTimes() error {
 	} else {
 		fmt.Fprintf(Stdout, "last: n/a\n")
 	}
-	if !hold.IsZero() {
+	heldForever := sysinfo.Refresh.Hold == "forever"
+	if heldForever {
+		fmt.Fprintf(Stdout, "hold: forever\n")
+	} else if !hold.IsZero() {
 		fmt.Fprintf(Stdout, "hold: %s\n", x.fmtTime(hold))
 	}
 	// only show "next" if its after "hold" to not confuse users
 	if !next.IsZero() {
 		// Snapstate checks for holdTime.After(limitTime) so we need
 		// to check for before or equal here to be fully correct.
-		if next.Before(hold) || next.Equal(hold) {
+		if heldForever || next.Before(hold) || next.Equal(hold) {
 			fmt.Fprintf(Stdout, "next: %s (but held)\n", x.fmtTime(next))
 		} else {
 			fmt.Fprintf(Stdout, "next: %s\n", x.fmtTime(next))
@@ -741,6 +815,21 @@ func (x *cmdRefresh) showRefreshTimes() error {
 	} else {
 		fmt.Fprintf(Stdout, "next: n/a\n")
 	}
+	if len(sysinfo.Refresh.SnapHolds) > 0 {
+		names := make([]string, 0, len(sysinfo.Refresh.SnapHolds))
+		for name := range sysinfo.Refresh.SnapHolds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(Stdout, "held snaps:\n")
+		for _, name := range names {
+			snapHold := sysinfo.Refresh.SnapHolds[name]
+			if snapHold != "forever" {
+				snapHold = x.fmtTime(parseSysinfoTime(snapHold))
+			}
+			fmt.Fprintf(Stdout, "  %s: %s\n", name, snapHold)
+		}
+	}
 	return nil
 }
 
@@ -794,6 +883,20 @@ func (x *cmdRefresh) Execute([]string) error {
 		return x.listRefresh()
 	}
 
+	if x.Hold != "" || x.Unhold {
+		if x.Hold != "" && x.Unhold {
+			return errors.New(i18n.G("cannot use --hold and --unhold together"))
+		}
+		if x.asksForMode() || x.asksForChannel() || x.Revision != "" || x.Amend || x.Cohort != "" || x.LeaveCohort || x.IgnoreValidation || x.IgnoreRunning || x.Transaction != "" {
+			return errors.New(i18n.G("--hold and --unhold do not take other refresh options"))
+		}
+		names := installedSnapNames(x.Positional.Snaps)
+		if x.Unhold {
+			return x.unholdRefreshes(names)
+		}
+		return x.holdRefreshes(names)
+	}
+
 	if len(x.Positional.Snaps) == 0 && os.Getenv("SNAP_REFRESH_FROM_TIMER") == "1" {
 		fmt.Fprintf(Stdout, "Ignoring `snap refresh` from the systemd timer")
 		return nil
@@ -825,7 +928,7 @@ func (x *cmdRefresh) Execute([]string) error {
 		return errors.New(i18n.G("a single snap name must be specified when ignoring running apps and hooks"))
 	}
 
-	return x.refreshMany(names, nil)
+	return x.refreshMany(names, &client.SnapOptions{Transaction: x.Transaction})
 }
 
 type cmdTry struct {
@@ -1108,6 +1211,8 @@ func init() {
 			"cohort": i18n.G("Install the snap in the given cohort"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"ignore-running": i18n.G("Ignore running hooks or applications blocking the installation"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"transaction": i18n.G("Have one transaction per-snap or one for all the specified snaps"),
 		}), nil)
 	addCommand("refresh", shortRefreshHelp, longRefreshHelp, func() flags.Commander { return &cmdRefresh{} },
 		colorDescs.also(waitDescs).also(channelDescs).also(modeDescs).also(timeDescs).also(map[string]string{
@@ -1127,6 +1232,12 @@ func init() {
 			"cohort": i18n.G("Refresh the snap into the given cohort"),
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"leave-cohort": i18n.G("Refresh the snap out of its cohort"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"hold": i18n.G("Hold auto-refreshes for the given duration, or forever"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"unhold": i18n.G("Remove the hold on auto-refreshes"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"transaction": i18n.G("Have one transaction per-snap or one for all the specified snaps"),
 		}), nil)
 	addCommand("try", shortTryHelp, longTryHelp, func() flags.Commander { return &cmdTry{} }, waitDescs.also(modeDescs), nil)
 	addCommand("enable", shortEnableHelp, longEnableHelp, func() flags.Commander { return &cmdEnable{} }, waitDescs, nil)
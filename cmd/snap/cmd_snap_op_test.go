@@ -1179,6 +1179,98 @@ func (s *SnapSuite) TestRefreshNoTimerNoSchedule(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `internal error: both refresh.timer and refresh.schedule are empty`)
 }
 
+func (s *SnapSuite) TestRefreshTimeUserHolds(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/system-info")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"refresh": {"timer": "0:00-24:00/4", "last": "2017-04-25T17:35:00+02:00", "next": "2017-04-26T00:58:00+02:00", "hold": "forever", "snap-holds": {"foo": "forever", "bar": "2017-05-01T00:00:00+02:00"}}}}`)
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs([]string{"refresh", "--time", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, `timer: 0:00-24:00/4
+last: 2017-04-25T17:35:00+02:00
+hold: forever
+next: 2017-04-26T00:58:00+02:00 (but held)
+held snaps:
+  bar: 2017-05-01T00:00:00+02:00
+  foo: forever
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *SnapSuite) testRefreshHoldOrUnhold(c *check.C, args []string, expectedBody map[string]interface{}, expectedOut string) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, expectedBody)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type": "async", "change": "42", "status-code": 202}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+		n++
+	})
+	rest, err := snap.Parser(snap.Client()).ParseArgs(args)
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Equals, expectedOut)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *SnapSuite) TestRefreshHoldAllForever(c *check.C) {
+	s.testRefreshHoldOrUnhold(c, []string{"refresh", "--hold"}, map[string]interface{}{
+		"action": "hold",
+		"time":   "forever",
+	}, "Auto-refresh of all snaps held forever.\n")
+}
+
+func (s *SnapSuite) TestRefreshHoldSnapsForDuration(c *check.C) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	restore := snap.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	s.testRefreshHoldOrUnhold(c, []string{"refresh", "--abs-time", "--hold=72h", "foo", "bar"}, map[string]interface{}{
+		"action": "hold",
+		"snaps":  []interface{}{"foo", "bar"},
+		"time":   "2021-06-04T10:00:00Z",
+	}, "Auto-refresh of \"foo\", \"bar\" held until 2021-06-04T10:00:00Z.\n")
+}
+
+func (s *SnapSuite) TestRefreshUnholdSnaps(c *check.C) {
+	s.testRefreshHoldOrUnhold(c, []string{"refresh", "--unhold", "foo"}, map[string]interface{}{
+		"action": "unhold",
+		"snaps":  []interface{}{"foo"},
+	}, "Auto-refresh of \"foo\" no longer held.\n")
+}
+
+func (s *SnapSuite) TestRefreshHoldErrors(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+	for _, tc := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"refresh", "--hold", "--unhold"}, "cannot use --hold and --unhold together"},
+		{[]string{"refresh", "--hold=3d", "foo"}, `cannot hold refreshes for "3d": expected "forever" or a positive duration \(e.g. 72h\)`},
+		{[]string{"refresh", "--hold=-1h", "foo"}, `cannot hold refreshes for "-1h": .*`},
+		{[]string{"refresh", "--hold", "--beta", "foo"}, "--hold and --unhold do not take other refresh options"},
+		{[]string{"refresh", "--unhold", "--amend", "foo"}, "--hold and --unhold do not take other refresh options"},
+	} {
+		_, err := snap.Parser(snap.Client()).ParseArgs(tc.args)
+		c.Check(err, check.ErrorMatches, tc.err, check.Commentf("%v", tc.args))
+	}
+}
+
 func (s *SnapOpSuite) TestRefreshOne(c *check.C) {
 	s.RedirectClientToTestServer(s.srv.handle)
 	s.srv.checker = func(r *http.Request) {
@@ -1804,6 +1896,42 @@ func (s *SnapOpSuite) TestInstallManyMixFileAndStore(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `only one snap file can be installed at a time`)
 }
 
+func (s *SnapOpSuite) TestInstallRefreshManyTransaction(c *check.C) {
+	for _, action := range []string{"install", "refresh"} {
+		n := 0
+		s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+			switch n {
+			case 0:
+				c.Check(r.Method, check.Equals, "POST")
+				c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+				c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+					"action":      action,
+					"snaps":       []interface{}{"one", "two"},
+					"transaction": "all-snaps",
+				})
+				w.WriteHeader(202)
+				fmt.Fprintln(w, `{"type":"async", "change": "42", "status-code": 202}`)
+			default:
+				c.Fatalf("expected to get 1 request, now on %d", n+1)
+			}
+			n++
+		})
+
+		rest, err := snap.Parser(snap.Client()).ParseArgs([]string{action, "--transaction=all-snaps", "--no-wait", "one", "two"})
+		c.Assert(err, check.IsNil)
+		c.Assert(rest, check.DeepEquals, []string{})
+		c.Check(s.Stdout(), check.Equals, "42\n")
+		c.Check(n, check.Equals, 1)
+		s.ResetStdStreams()
+	}
+}
+
+func (s *SnapOpSuite) TestInstallManyTransactionInvalid(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser(snap.Client()).ParseArgs([]string{"install", "--transaction=some-snaps", "one", "two"})
+	c.Assert(err, check.ErrorMatches, `Invalid value .some-snaps. for option .--transaction.. Allowed values are: all-snaps or per-snap`)
+}
+
 func (s *SnapOpSuite) TestInstallMany(c *check.C) {
 	total := 4
 	n := 0
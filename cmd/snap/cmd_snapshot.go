@@ -20,6 +20,7 @@
 package main
 
 import (
+	"crypto"
 	"fmt"
 	"io"
 	"os"
@@ -28,7 +29,10 @@ import (
 
 	"github.com/jessevdk/go-flags"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/strutil/quantity"
 )
@@ -62,6 +66,9 @@ for which users, or a combination of these.
 If a snap is included in a save operation, excluding its system and
 configuration data from the snapshot is not currently possible. This
 restriction may be lifted in the future.
+
+With --export the new snapshot is also exported to the given file, which
+can then be imported on another device with the import-snapshot command.
 `)
 var longForgetHelp = i18n.G(`
 The forget command deletes a snapshot. This operation can not be
@@ -104,6 +111,10 @@ restriction may be lifted in the future.
 
 var longExportSnapshotHelp = i18n.G(`
 Export a snapshot to the given filename.
+
+The exported snapshot can be imported on another device with the
+import-snapshot command. Its integrity is verified both while it is
+exported and when it is imported.
 `)
 
 var longImportSnapshotHelp = i18n.G(`
@@ -179,6 +190,7 @@ type saveCmd struct {
 	waitMixin
 	durationMixin
 	Users      string `long:"users"`
+	Export     string `long:"export"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -187,6 +199,9 @@ type saveCmd struct {
 func (x *saveCmd) Execute([]string) error {
 	snaps := installedSnapNames(x.Positional.Snaps)
 	users := strutil.CommaSeparatedList(x.Users)
+	if x.Export != "" && x.NoWait {
+		return fmt.Errorf(i18n.G("cannot use --export with --no-wait"))
+	}
 	setID, changeID, err := x.client.SnapshotMany(snaps, users)
 	if err != nil {
 		return err
@@ -203,7 +218,19 @@ func (x *saveCmd) Execute([]string) error {
 		durationMixin: x.durationMixin,
 		ID:            snapshotID(strconv.FormatUint(setID, 10)),
 	}
-	return y.Execute(nil)
+	if err := y.Execute(nil); err != nil {
+		return err
+	}
+	if x.Export == "" {
+		return nil
+	}
+
+	if err := exportSnapshotSet(x.client, setID, x.Export); err != nil {
+		return err
+	}
+	// TRANSLATORS: the first argument is the identifier of the snapshot, the second one is the file name.
+	fmt.Fprintf(Stdout, i18n.G("Exported snapshot #%d into %q\n"), setID, x.Export)
+	return nil
 }
 
 type forgetCmd struct {
@@ -340,6 +367,8 @@ func init() {
 		}, durationDescs.also(waitDescs).also(map[string]string{
 			// TRANSLATORS: This should not start with a lowercase letter.
 			"users": i18n.G("Snapshot data of only specific users (comma-separated) (default: all users)"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"export": i18n.G("Export the new snapshot to the given file"),
 		}), nil)
 
 	addCommand("restore",
@@ -440,18 +469,30 @@ type exportSnapshotCmd struct {
 	} `positional-args:"yes" required:"yes"`
 }
 
-func (x *exportSnapshotCmd) Execute([]string) (err error) {
+func (x *exportSnapshotCmd) Execute([]string) error {
 	setID, err := x.Positional.ID.ToUint()
 	if err != nil {
 		return err
 	}
 
-	r, expectedSize, err := x.client.SnapshotExport(setID)
+	if err := exportSnapshotSet(x.client, setID, x.Positional.Filename); err != nil {
+		return err
+	}
+
+	// TRANSLATORS: the first argument is the identifier of the snapshot, the second one is the file name.
+	fmt.Fprintf(Stdout, i18n.G("Exported snapshot #%s into %q\n"), x.Positional.ID, x.Positional.Filename)
+	return nil
+}
+
+// exportSnapshotSet streams the given snapshot set into the given file,
+// showing the progress of the export.
+func exportSnapshotSet(cli *client.Client, setID uint64, filename string) (err error) {
+	r, expectedSize, err := cli.SnapshotExport(setID)
 	if err != nil {
 		return err
 	}
+	defer r.Close()
 
-	filename := x.Positional.Filename
 	f, err := os.Create(filename + ".part")
 	if err != nil {
 		return err
@@ -468,7 +509,11 @@ func (x *exportSnapshotCmd) Execute([]string) (err error) {
 		return fmt.Errorf(i18n.G("cannot reserve disk space for snapshot: %v"), err)
 	}
 
-	n, err := io.Copy(f, r)
+	pb := progress.MakeProgressBar()
+	// TRANSLATORS: the %d is the identifier of a snapshot
+	pb.Start(fmt.Sprintf(i18n.G("Exporting snapshot #%d"), setID), float64(expectedSize))
+	n, err := io.Copy(io.MultiWriter(f, pb), r)
+	pb.Finished()
 	if err != nil {
 		return err
 	}
@@ -476,13 +521,7 @@ func (x *exportSnapshotCmd) Execute([]string) (err error) {
 		return fmt.Errorf(i18n.G("unexpected size, got: %v but wanted %v"), n, expectedSize)
 	}
 
-	if err := os.Rename(filename+".part", filename); err != nil {
-		return err
-	}
-
-	// TRANSLATORS: the first argument is the identifier of the snapshot, the second one is the file name.
-	fmt.Fprintf(Stdout, i18n.G("Exported snapshot #%s into %q\n"), x.Positional.ID, x.Positional.Filename)
-	return nil
+	return os.Rename(filename+".part", filename)
 }
 
 type importSnapshotCmd struct {
@@ -505,7 +544,15 @@ func (x *importSnapshotCmd) Execute([]string) error {
 		return fmt.Errorf("cannot stat file: %v", err)
 	}
 
-	importSet, err := x.client.SnapshotImport(f, st.Size())
+	digest, _, err := osutil.FileDigest(filename, crypto.SHA3_384)
+	if err != nil {
+		return fmt.Errorf("cannot compute digest of file: %v", err)
+	}
+
+	pb := progress.MakeProgressBar()
+	pb.Start(i18n.G("Importing snapshot"), float64(st.Size()))
+	importSet, err := x.client.SnapshotImportWithDigest(io.TeeReader(f, pb), st.Size(), fmt.Sprintf("%x", digest))
+	pb.Finished()
 	if err != nil {
 		return err
 	}
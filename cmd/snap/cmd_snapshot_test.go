@@ -113,6 +113,58 @@ func (s *SnapSuite) TestSnapshotExportHappy(c *C) {
 	c.Check(exportedSnapshotPath+".part", testutil.FileAbsent)
 }
 
+func (s *SnapSuite) TestSnapshotSaveExport(c *C) {
+	restore := main.MockIsStdinTTY(true)
+	defer restore()
+
+	var exported bool
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/snaps":
+			c.Check(r.Method, Equals, "POST")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"action": "snapshot",
+				"snaps":  []interface{}{"htop"},
+			})
+			w.WriteHeader(202)
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "9", "result": {"set-id": 5}}`)
+		case "/v2/changes/9":
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done", "data": {}}}`)
+		case "/v2/snapshots":
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Query().Get("set"), Equals, "5")
+			fmt.Fprintf(w, `{"type":"sync","status-code":200,"status":"OK","result":[{"id":5,"snapshots":[{"set":5,"time":%q,"snap":"htop","revision":"1168","snap-id":"Z","epoch":{"read":[0],"write":[0]},"summary":"","version":"2","sha3-384":{"archive.tgz":""},"size":1}]}]}`, time.Now().Format(time.RFC3339))
+		case "/v2/snapshots/5/export":
+			exported = true
+			w.Header().Set("Content-Type", client.SnapshotExportMediaType)
+			fmt.Fprint(w, "Hello World!")
+		default:
+			c.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+
+	exportedSnapshotPath := filepath.Join(c.MkDir(), "htop.snapshot")
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"save", "--export", exportedSnapshotPath, "htop"})
+	c.Assert(err, IsNil)
+	c.Check(exported, Equals, true)
+	c.Check(s.Stderr(), testutil.EqualsWrapped, "")
+	c.Check(s.Stdout(), testutil.MatchesWrapped, `Set  Snap  Age    Version  Rev   Size    Notes
+5    htop  .*  2        1168      1B  -
+Exported snapshot #5 into ".*/htop.snapshot"
+`)
+	c.Check(exportedSnapshotPath, testutil.FileEquals, "Hello World!")
+	c.Check(exportedSnapshotPath+".part", testutil.FileAbsent)
+}
+
+func (s *SnapSuite) TestSnapshotSaveExportNoWait(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Errorf("unexpected request to %q", r.URL.Path)
+	})
+
+	_, err := main.Parser(main.Client()).ParseArgs([]string{"save", "--no-wait", "--export", "htop.snapshot"})
+	c.Assert(err, ErrorMatches, "cannot use --export with --no-wait")
+}
+
 func (s *SnapSuite) mockSnapshotsServer(c *C) {
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -128,6 +180,8 @@ func (s *SnapSuite) mockSnapshotsServer(c *C) {
 			}
 			if r.Method == "POST" {
 				if r.Header.Get("Content-Type") == client.SnapshotExportMediaType {
+					// the digest of the mocked snapshot file
+					c.Check(r.Header.Get("Snapshot-Sha3-384"), Equals, "02100b523799d7d7a1bde27f83e245fb1bb79e85901f66418eae79aa6c2be75014d1f3137e5a9d78428e101f3f45369f")
 					fmt.Fprintln(w, `{"type": "sync", "result": {"set-id": 42, "snaps": ["htop"]}}`)
 				} else {
 
@@ -21,6 +21,7 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -33,9 +34,8 @@ import (
 
 type cmdValidate struct {
 	clientMixin
-	Monitor bool `long:"monitor"`
-	// XXX: enforce mode is not supported yet
-	Enforce    bool `long:"enforce" hidden:"yes"`
+	Monitor    bool `long:"monitor"`
+	Enforce    bool `long:"enforce"`
 	Forget     bool `long:"forget"`
 	Positional struct {
 		ValidationSet string `positional-arg-name:"<validation-set>"`
@@ -45,11 +45,35 @@ type cmdValidate struct {
 
 var shortValidateHelp = i18n.G("List or apply validation sets")
 var longValidateHelp = i18n.G(`
-The validate command lists or applies validations sets
+The validate command lists or applies validations sets that state which snaps
+are required or permitted to be installed together, optionally constrained to
+fixed revisions.
+
+A validation set can either be in monitoring mode, in which case its constraints
+aren't enforced, or in enforcing mode, in which case snapd will not allow
+operations which would result in snaps breaking its constraints.
+
+$ snap validate
+
+Lists the validation sets in the system, together with their mode, sequence
+point and whether the constraints they set are currently met.
+
+$ snap validate <account-id>/<name>[=<sequence>]
+
+Shows whether the constraints of the given validation set are currently met.
+
+$ snap validate --monitor|--enforce <account-id>/<name>[=<sequence>]
+
+Starts monitoring or enforcing the given validation set, pinned at the given
+sequence point if one is specified.
+
+$ snap validate --forget <account-id>/<name>[=<sequence>]
+
+Stops monitoring or enforcing the given validation set.
 `)
 
 func init() {
-	cmd := addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, colorDescs.also(map[string]string{
+	addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, colorDescs.also(map[string]string{
 		// TRANSLATORS: This should not start with a lowercase letter.
 		"monitor": i18n.G("Monitor the given validations set"),
 		// TRANSLATORS: This should not start with a lowercase letter.
@@ -62,8 +86,6 @@ func init() {
 		// TRANSLATORS: This should not start with a lowercase letter.
 		desc: i18n.G("Validation set with an optional pinned sequence point, i.e. account-id/name[=seq]"),
 	}})
-	// XXX: remove once api has landed
-	cmd.hidden = true
 }
 
 func splitValidationSetArg(arg string) (account, name string, seq int, err error) {
@@ -102,6 +124,29 @@ func fmtValid(res *client.ValidationSetResult) string {
 	return fmt.Sprint("invalid")
 }
 
+// fmtNotes lists the snaps violating the validation set.
+func fmtNotes(res *client.ValidationSetResult) string {
+	if res.Violations == nil {
+		return ""
+	}
+	var notes []string
+	if len(res.Violations.MissingSnaps) > 0 {
+		notes = append(notes, "missing:"+strings.Join(res.Violations.MissingSnaps, ","))
+	}
+	if len(res.Violations.InvalidSnaps) > 0 {
+		notes = append(notes, "invalid:"+strings.Join(res.Violations.InvalidSnaps, ","))
+	}
+	if len(res.Violations.WrongRevisionSnaps) > 0 {
+		wrongRev := make([]string, 0, len(res.Violations.WrongRevisionSnaps))
+		for name, rev := range res.Violations.WrongRevisionSnaps {
+			wrongRev = append(wrongRev, fmt.Sprintf("%s(%s)", name, rev))
+		}
+		sort.Strings(wrongRev)
+		notes = append(notes, "wrong-revision:"+strings.Join(wrongRev, ","))
+	}
+	return strings.Join(notes, " ")
+}
+
 func fmtValidationSet(res *client.ValidationSetResult) string {
 	if res.PinnedAt == 0 {
 		return fmt.Sprintf("%s/%s", res.AccountID, res.Name)
@@ -172,15 +217,13 @@ func (cmd *cmdValidate) Execute(args []string) error {
 		// TRANSLATORS: the %s is to insert a filler escape sequence (please keep it flush to the column header, with no extra spaces)
 		fmt.Fprintf(w, i18n.G("Validation\tMode\tSeq\tCurrent\t%s\tNotes\n"), fillerPublisher(esc))
 		for _, res := range vsets {
-			// TODO: fill notes when've clarity about them
-			var notes string
 			// doing it this way because otherwise it's a sea of %s\t%s\t%s
 			line := []string{
 				fmtValidationSet(res),
 				res.Mode,
 				fmt.Sprintf("%d", res.Sequence),
 				fmtValid(res),
-				notes,
+				fmtNotes(res),
 			}
 			fmt.Fprintln(w, strings.Join(line, "\t"))
 		}
@@ -190,7 +233,7 @@ func (cmd *cmdValidate) Execute(args []string) error {
 		if err != nil {
 			return err
 		}
-		fmt.Fprintf(Stdout, fmtValid(vset))
+		fmt.Fprintln(Stdout, fmtValid(vset))
 		// XXX: exit status 1 if invalid?
 	}
 
@@ -186,7 +186,7 @@ func (s *validateSuite) TestValidateQueryOne(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(rest, check.HasLen, 0)
 	c.Check(s.Stderr(), check.Equals, "")
-	c.Check(s.Stdout(), check.Equals, "valid")
+	c.Check(s.Stdout(), check.Equals, "valid\n")
 }
 
 func (s *validateSuite) TestValidateQueryOneInvalid(c *check.C) {
@@ -199,7 +199,7 @@ func (s *validateSuite) TestValidateQueryOneInvalid(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Check(rest, check.HasLen, 0)
 	c.Check(s.Stderr(), check.Equals, "")
-	c.Check(s.Stdout(), check.Equals, "invalid")
+	c.Check(s.Stdout(), check.Equals, "invalid\n")
 }
 
 func (s *validateSuite) TestValidationSetsList(c *check.C) {
@@ -221,6 +221,25 @@ func (s *validateSuite) TestValidationSetsList(c *check.C) {
 	)
 }
 
+func (s *validateSuite) TestValidationSetsListViolations(c *check.C) {
+	restore := main.MockIsStdinTTY(true)
+	defer restore()
+
+	s.RedirectClientToTestServer(makeFakeListValidationsSetsHandler(c, `{"type": "sync", "status-code": 200, "result": [
+		{"account-id":"foo","name":"bar","mode":"enforce","sequence":3,"valid":false,"violations":{"missing-snaps":["snap-a","snap-b"],"invalid-snaps":["snap-c"]}},
+		{"account-id":"foo","name":"baz","mode":"monitor","sequence":1,"valid":false,"violations":{"wrong-revision-snaps":{"snap-e":"2","snap-d":"1"}}}
+	]}`))
+
+	rest, err := main.Parser(main.Client()).ParseArgs([]string{"validate"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "Validation  Mode     Seq  Current       Notes\n"+
+		"foo/bar     enforce  3    invalid  missing:snap-a,snap-b invalid:snap-c\n"+
+		"foo/baz     monitor  1    invalid  wrong-revision:snap-d(1),snap-e(2)\n",
+	)
+}
+
 func (s *validateSuite) TestValidationSetsListEmpty(c *check.C) {
 	restore := main.MockIsStdinTTY(true)
 	defer restore()
@@ -144,6 +144,14 @@ func MockPollTime(d time.Duration) (restore func()) {
 	}
 }
 
+func MockFollowPollTime(d time.Duration) (restore func()) {
+	d0 := followPollTime
+	followPollTime = d
+	return func() {
+		followPollTime = d0
+	}
+}
+
 func MockMaxGoneTime(d time.Duration) (restore func()) {
 	d0 := maxGoneTime
 	maxGoneTime = d
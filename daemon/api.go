@@ -51,6 +51,7 @@ var api = []*Command{
 	assertsCmd,
 	assertsFindManyCmd,
 	stateChangeCmd,
+	changeWatchCmd,
 	stateChangesCmd,
 	createUserCmd,
 	buyCmd,
@@ -76,9 +77,45 @@ var api = []*Command{
 	validationSetsListCmd,
 	validationSetsCmd,
 	routineConsoleConfStartCmd,
+	routineConsoleConfFinishCmd,
 	systemRecoveryKeysCmd,
+	disksCmd,
+	bootCmd,
+	systemSealingCmd,
+	quotaGroupsCmd,
+	quotaGroupInfoCmd,
+	promptsCmd,
+	promptCmd,
+	rulesCmd,
+	ruleCmd,
 }
 
+// refreshControlAccess grants snaps that manage the refresh schedule of the
+// device, through a snapd-control plug with the refresh-schedule attribute
+// set to "managed" or with the refresh-schedule scope, access to finding,
+// refreshing and following the refresh of snaps on the snapd-snap socket.
+// Handlers using it for POST requests must themselves restrict snaps to
+// refresh actions, see isSnapRequest.
+var refreshControlAccess = []interfaceAccess{{
+	Interface: "snapd-control",
+	Attrs:     map[string]interface{}{"refresh-schedule": "managed"},
+}, {
+	Interface: "snapd-control",
+	Scope:     "refresh-schedule",
+}}
+
+// snapshotsControlAccess grants snaps with a snapd-control plug with the
+// snapshots scope access to managing and following the changes of snapshots
+// on the snapd-snap socket.
+var snapshotsControlAccess = []interfaceAccess{{
+	Interface: "snapd-control",
+	Scope:     "snapshots",
+}}
+
+// changeAccess grants access to following changes to the snaps that can
+// start them.
+var changeAccess = append(append([]interfaceAccess(nil), refreshControlAccess...), snapshotsControlAccess...)
+
 // userFromRequest extracts user information from request and return the respective user in state, if valid
 // It requires the state to be locked
 func userFromRequest(st *state.State, req *http.Request) (*auth.UserState, error) {
@@ -0,0 +1,148 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var bootCmd = &Command{
+	Path:     "/v2/boot",
+	GET:      getBootStatus,
+	POST:     postBootAction,
+	RootOnly: true,
+}
+
+// wrapped for unit tests
+var (
+	bootStatus         = boot.Status
+	bootCancelTry      = boot.CancelTry
+	bootLastBootIssues = boot.LastBootIssues
+)
+
+func toClientBootSnapStatus(s *boot.SnapBootStatus) client.BootSnapStatus {
+	status := client.BootSnapStatus{
+		Status: s.Status,
+	}
+	if s.Current != nil {
+		status.Current = client.BootSnap{
+			Snap:     s.Current.SnapName(),
+			Revision: s.Current.SnapRevision(),
+		}
+	}
+	if s.Try != nil {
+		status.Try = &client.BootSnap{
+			Snap:     s.Try.SnapName(),
+			Revision: s.Try.SnapRevision(),
+		}
+	}
+	return status
+}
+
+func bootStatusResponse(dev boot.Device) Response {
+	status, err := bootStatus(dev)
+	if err != nil {
+		return InternalError("cannot get boot status: %v", err)
+	}
+	issues, err := bootLastBootIssues()
+	if err != nil {
+		return InternalError("cannot get boot status: %v", err)
+	}
+	return SyncResponse(&client.BootStatus{
+		Kernel:        toClientBootSnapStatus(&status.Kernel),
+		Base:          toClientBootSnapStatus(&status.Base),
+		RebootPending: status.RebootPending(),
+		Issues:        issues,
+	}, nil)
+}
+
+// bootDevice returns the device booting the kernel and base snaps, the state
+// must be locked.
+func bootDevice(st *state.State) (snapstate.DeviceContext, Response) {
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, InternalError("cannot get device context: %v", err)
+	}
+	if deviceCtx.Classic() {
+		return nil, BadRequest("boot status is not available on classic systems")
+	}
+	return deviceCtx, nil
+}
+
+func getBootStatus(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	dev, rsp := bootDevice(st)
+	if rsp != nil {
+		return rsp
+	}
+	return bootStatusResponse(dev)
+}
+
+type bootActionRequest struct {
+	Action string `json:"action"`
+}
+
+func postBootAction(c *Command, r *http.Request, user *auth.UserState) Response {
+	var req bootActionRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		return BadRequest("cannot decode request body into boot action: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("extra content found in request body")
+	}
+
+	switch req.Action {
+	case "cancel-try":
+		// handled below
+	default:
+		return BadRequest("unsupported boot action %q", req.Action)
+	}
+
+	// the state is kept locked so that no change can modify the boot
+	// state concurrently
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	dev, rsp := bootDevice(st)
+	if rsp != nil {
+		return rsp
+	}
+
+	// the try snaps always belong to a change that is still in progress,
+	// so there is no point in checking for conflicts with it, on the
+	// next boot the change sees the fallback to the current snaps and
+	// is undone
+	if err := bootCancelTry(dev); err != nil {
+		return InternalError("cannot cancel try snaps: %v", err)
+	}
+	return bootStatusResponse(dev)
+}
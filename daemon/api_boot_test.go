@@ -0,0 +1,237 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = Suite(&bootSuite{})
+
+type bootSuite struct {
+	apiBaseSuite
+
+	status *boot.BootStatus
+}
+
+func (s *bootSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.status = &boot.BootStatus{
+		Kernel: boot.SnapBootStatus{
+			Current: snap.MinimalPlaceInfo("pc-kernel", snap.R(1)),
+			Try:     snap.MinimalPlaceInfo("pc-kernel", snap.R(2)),
+			Status:  boot.TryStatus,
+		},
+		Base: boot.SnapBootStatus{
+			Current: snap.MinimalPlaceInfo("core20", snap.R(3)),
+			Status:  boot.DefaultStatus,
+		},
+	}
+	s.AddCleanup(daemon.MockBootLastBootIssues(func() ([]string, error) {
+		return nil, nil
+	}))
+	s.AddCleanup(daemon.MockBootStatus(func(dev boot.Device) (*boot.BootStatus, error) {
+		c.Check(dev.Classic(), Equals, false)
+		return s.status, nil
+	}))
+}
+
+func (s *bootSuite) mockDaemon(c *C) {
+	d := s.daemonWithOverlordMockAndStore(c)
+	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
+	c.Assert(err, IsNil)
+	deviceMgr, err := devicestate.Manager(d.Overlord().State(), hookMgr, d.Overlord().TaskRunner(), nil)
+	c.Assert(err, IsNil)
+	d.Overlord().AddManager(deviceMgr)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	s.mockModel(c, st, nil)
+}
+
+func (s *bootSuite) TestGetBootStatus(c *C) {
+	s.mockDaemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/boot", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, &client.BootStatus{
+		Kernel: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "pc-kernel", Revision: snap.R(1)},
+			Try:     &client.BootSnap{Snap: "pc-kernel", Revision: snap.R(2)},
+			Status:  "try",
+		},
+		Base: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "core20", Revision: snap.R(3)},
+		},
+		RebootPending: true,
+	})
+}
+
+func (s *bootSuite) TestGetBootStatusIssues(c *C) {
+	s.mockDaemon(c)
+
+	defer daemon.MockBootLastBootIssues(func() ([]string, error) {
+		return []string{`try kernel "pc-kernel_2.snap" failed to boot, booted kernel "pc-kernel_1.snap"`}, nil
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/boot", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Assert(rsp.Result, FitsTypeOf, &client.BootStatus{})
+	c.Check(rsp.Result.(*client.BootStatus).Issues, DeepEquals, []string{
+		`try kernel "pc-kernel_2.snap" failed to boot, booted kernel "pc-kernel_1.snap"`,
+	})
+
+	defer daemon.MockBootLastBootIssues(func() ([]string, error) {
+		return nil, fmt.Errorf("boom")
+	})()
+
+	rsp = s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "cannot get boot status: boom")
+}
+
+func (s *bootSuite) TestGetBootStatusError(c *C) {
+	s.mockDaemon(c)
+
+	defer daemon.MockBootStatus(func(dev boot.Device) (*boot.BootStatus, error) {
+		return nil, fmt.Errorf("boom")
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/boot", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "cannot get boot status: boom")
+}
+
+func (s *bootSuite) TestPostBootCancelTry(c *C) {
+	s.mockDaemon(c)
+
+	called := 0
+	defer daemon.MockBootCancelTry(func(dev boot.Device) error {
+		called++
+		s.status.Kernel.Try = nil
+		s.status.Kernel.Status = boot.DefaultStatus
+		return nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/boot", strings.NewReader(`{"action":"cancel-try"}`))
+	c.Assert(err, IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(called, Equals, 1)
+	c.Check(rsp.Result, DeepEquals, &client.BootStatus{
+		Kernel: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "pc-kernel", Revision: snap.R(1)},
+		},
+		Base: client.BootSnapStatus{
+			Current: client.BootSnap{Snap: "core20", Revision: snap.R(3)},
+		},
+	})
+}
+
+func (s *bootSuite) TestPostBootErrors(c *C) {
+	s.mockDaemon(c)
+
+	defer daemon.MockBootCancelTry(func(dev boot.Device) error {
+		return fmt.Errorf("boom")
+	})()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		err    string
+	}{
+		{`{"action":"cancel-try"}`, 500, "cannot cancel try snaps: boom"},
+		{`{"action":"foo"}`, 400, `unsupported boot action "foo"`},
+		{`{"action":"cancel-try"}{}`, 400, "extra content found in request body"},
+		{`{`, 400, "cannot decode request body into boot action: unexpected EOF"},
+	} {
+		req, err := http.NewRequest("POST", "/v2/boot", strings.NewReader(tc.body))
+		c.Assert(err, IsNil)
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, Equals, tc.status, Commentf("%s", tc.body))
+		c.Check(rsp.ErrorResult().Message, Equals, tc.err, Commentf("%s", tc.body))
+	}
+}
+
+func (s *bootSuite) TestPostBootCancelTryChangeInProgress(c *C) {
+	s.mockDaemon(c)
+
+	called := 0
+	defer daemon.MockBootCancelTry(func(dev boot.Device) error {
+		called++
+		return nil
+	})()
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("refresh-snap", "...")
+	t := st.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "kernel", Revision: snap.R(2)},
+	})
+	chg.AddTask(t)
+	st.Unlock()
+
+	req, err := http.NewRequest("POST", "/v2/boot", strings.NewReader(`{"action":"cancel-try"}`))
+	c.Assert(err, IsNil)
+
+	// the change that is trying the kernel does not prevent canceling
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(called, Equals, 1)
+}
+
+func (s *bootSuite) TestBootAsUserErrors(c *C) {
+	s.mockDaemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/boot", nil)
+	c.Assert(err, IsNil)
+
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Assert(rec.Code, Equals, 401)
+}
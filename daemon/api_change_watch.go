@@ -0,0 +1,125 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+)
+
+var changeWatchCmd = &Command{
+	Path:     "/v2/changes/{id}/watch",
+	UserOK:   true,
+	PolkitOK: "io.snapcraft.snapd.manage",
+	GET:      watchChange,
+}
+
+func watchChange(c *Command, r *http.Request, user *auth.UserState) Response {
+	chID := muxVars(r)["id"]
+	st := c.d.overlord.State()
+	st.Lock()
+	chg := st.Change(chID)
+	st.Unlock()
+	if chg == nil {
+		return NotFound("cannot find change with id %q", chID)
+	}
+
+	return &changeWatchResponse{d: c.d, chID: chID}
+}
+
+// A changeWatchResponse's ServeHTTP method upgrades the connection to a
+// websocket and sends the JSON dump of the change, in the same format as
+// /v2/changes/{id}, every time its status, the progress or the logs of its
+// tasks are updated. The websocket is closed once the change is ready.
+type changeWatchResponse struct {
+	d    *Daemon
+	chID string
+}
+
+func (cw *changeWatchResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// the connection is not coming from a browser, so there is no need to
+	// check the origin as the default websocket.Handler does
+	srv := websocket.Server{Handler: cw.watch}
+	srv.ServeHTTP(w, r)
+}
+
+func (cw *changeWatchResponse) watch(ws *websocket.Conn) {
+	defer ws.Close()
+
+	// the client is not expected to send anything, reading detects when it
+	// goes away
+	clientGone := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, ws)
+		close(clientGone)
+	}()
+
+	st := cw.d.overlord.State()
+	// register before the first read so that no update is missed
+	updated, cancel := st.WatchChange(cw.chID)
+	defer cancel()
+
+	var sent []byte
+	for {
+		st.Lock()
+		chg := st.Change(cw.chID)
+		var chgInfo *changeInfo
+		if chg != nil {
+			chgInfo = change2changeInfo(chg)
+		}
+		st.Unlock()
+		if chgInfo == nil {
+			// the change was pruned
+			return
+		}
+
+		buf, err := json.Marshal(chgInfo)
+		if err != nil {
+			logger.Noticef("cannot marshal change %s: %v", cw.chID, err)
+			return
+		}
+		if !bytes.Equal(buf, sent) {
+			if err := websocket.Message.Send(ws, string(buf)); err != nil {
+				logger.Debugf("cannot send change %s update: %v", cw.chID, err)
+				return
+			}
+			sent = buf
+		}
+		if chgInfo.Ready {
+			return
+		}
+
+		select {
+		case <-updated:
+		case <-clientGone:
+			return
+		case <-cw.d.tomb.Dying():
+			return
+		}
+	}
+}
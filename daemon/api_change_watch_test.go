@@ -0,0 +1,135 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"golang.org/x/net/websocket"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = check.Suite(&changeWatchSuite{})
+
+type changeWatchSuite struct {
+	apiBaseSuite
+}
+
+func (s *changeWatchSuite) dialWatch(c *check.C, chID string) *websocket.Conn {
+	req, err := http.NewRequest("GET", "/v2/changes/"+chID+"/watch", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.req(c, req, nil)
+
+	srv := httptest.NewServer(rsp)
+	s.AddCleanup(srv.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/"
+	ws, err := websocket.Dial(wsURL, "", srv.URL)
+	c.Assert(err, check.IsNil)
+	s.AddCleanup(func() { ws.Close() })
+	return ws
+}
+
+func (s *changeWatchSuite) receive(c *check.C, ws *websocket.Conn) map[string]interface{} {
+	var msg string
+	c.Assert(websocket.Message.Receive(ws, &msg), check.IsNil)
+	var chg map[string]interface{}
+	c.Assert(json.Unmarshal([]byte(msg), &chg), check.IsNil)
+	return chg
+}
+
+func (s *changeWatchSuite) TestWatchChange(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("install", "install...")
+	t1 := st.NewTask("download", "1...")
+	chg.AddTask(t1)
+	st.Unlock()
+
+	ws := s.dialWatch(c, chg.ID())
+
+	msg := s.receive(c, ws)
+	c.Check(msg["id"], check.Equals, chg.ID())
+	c.Check(msg["status"], check.Equals, "Do")
+	c.Check(msg["ready"], check.Equals, false)
+
+	st.Lock()
+	t1.SetStatus(state.DoingStatus)
+	t1.SetProgress("downloading", 1, 2)
+	t1.Logf("halfway")
+	st.Unlock()
+
+	msg = s.receive(c, ws)
+	c.Check(msg["status"], check.Equals, "Doing")
+	task := msg["tasks"].([]interface{})[0].(map[string]interface{})
+	c.Check(task["progress"], check.DeepEquals, map[string]interface{}{
+		"label": "downloading", "done": 1., "total": 2.,
+	})
+	c.Check(task["log"], check.HasLen, 1)
+	c.Check(task["log"].([]interface{})[0], check.Matches, ".* INFO halfway")
+
+	st.Lock()
+	t1.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	msg = s.receive(c, ws)
+	c.Check(msg["status"], check.Equals, "Done")
+	c.Check(msg["ready"], check.Equals, true)
+
+	// the websocket is closed once the change is ready
+	var rest string
+	c.Check(websocket.Message.Receive(ws, &rest), check.Equals, io.EOF)
+}
+
+func (s *changeWatchSuite) TestWatchChangeAlreadyReady(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("install", "install...")
+	t1 := st.NewTask("download", "1...")
+	chg.AddTask(t1)
+	t1.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	ws := s.dialWatch(c, chg.ID())
+
+	msg := s.receive(c, ws)
+	c.Check(msg["ready"], check.Equals, true)
+
+	var rest string
+	c.Check(websocket.Message.Receive(ws, &rest), check.Equals, io.EOF)
+}
+
+func (s *changeWatchSuite) TestWatchChangeNotFound(c *check.C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/changes/42/watch", nil)
+	c.Assert(err, check.IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.ErrorResult().Message, check.Equals, `cannot find change with id "42"`)
+}
@@ -34,6 +34,11 @@ var (
 		Path: "/v2/internal/console-conf-start",
 		POST: consoleConfStartRoutine,
 	}
+
+	routineConsoleConfFinishCmd = &Command{
+		Path: "/v2/internal/console-conf-finish",
+		POST: consoleConfFinishRoutine,
+	}
 )
 
 var delayTime = 20 * time.Minute
@@ -47,14 +52,23 @@ type consoleConfStartRoutineResult struct {
 	ActiveAutoRefreshSnaps   []string `json:"active-auto-refresh-snaps,omitempty"`
 }
 
-func consoleConfStartRoutine(c *Command, r *http.Request, _ *auth.UserState) Response {
-	// no body expected, error if we were provided anything
+// checkConsoleConfRoutineBody checks that no body was provided with the
+// console-conf routine request.
+func checkConsoleConfRoutineBody(r *http.Request) Response {
 	defer r.Body.Close()
 	var routineBody struct{}
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&routineBody); err != nil && err != io.EOF {
 		return BadRequest("cannot decode request body into console-conf operation: %v", err)
 	}
+	return nil
+}
+
+func consoleConfStartRoutine(c *Command, r *http.Request, _ *auth.UserState) Response {
+	// no body expected, error if we were provided anything
+	if rsp := checkConsoleConfRoutineBody(r); rsp != nil {
+		return rsp
+	}
 
 	// now run the start routine first by trying to grab a lock on the refreshes
 	// for all snaps, which fails if there are any active changes refreshing
@@ -94,3 +108,23 @@ func consoleConfStartRoutine(c *Command, r *http.Request, _ *auth.UserState) Res
 		ActiveAutoRefreshSnaps:   snapNames,
 	}, nil)
 }
+
+func consoleConfFinishRoutine(c *Command, r *http.Request, _ *auth.UserState) Response {
+	// no body expected, error if we were provided anything
+	if rsp := checkConsoleConfRoutineBody(r); rsp != nil {
+		return rsp
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	// console-conf is done, auto refreshes do not need to wait for the
+	// delay to expire anymore
+	if err := c.d.overlord.SnapManager().ReleaseAutoRefreshDelay(); err != nil {
+		return InternalError(err.Error())
+	}
+	logger.Debugf("Released the delay of auto refreshes after console-conf finished")
+
+	return SyncResponse(nil, nil)
+}
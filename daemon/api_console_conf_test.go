@@ -113,3 +113,45 @@ func (s *consoleConfSuite) TestPostConsoleConfStartRoutine(c *C) {
 		ActiveAutoRefreshSnaps:   []string{"do-snap", "doing-snap"},
 	})
 }
+
+func (s *consoleConfSuite) TestPostConsoleConfFinishRoutine(c *C) {
+	d := s.daemonWithOverlordMock(c)
+	snapMgr, err := snapstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
+	c.Assert(err, IsNil)
+	d.Overlord().AddManager(snapMgr)
+
+	st := d.Overlord().State()
+
+	req, err := http.NewRequest("POST", "/v2/internal/console-conf-start", bytes.NewBuffer(nil))
+	c.Assert(err, IsNil)
+	s.syncReq(c, req, nil)
+
+	st.Lock()
+	tr := config.NewTransaction(st)
+	var t1 time.Time
+	err = tr.Get("core", "refresh.hold", &t1)
+	st.Unlock()
+	c.Assert(err, IsNil)
+
+	req, err = http.NewRequest("POST", "/v2/internal/console-conf-finish", bytes.NewBuffer(nil))
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Check(rsp.Result, IsNil)
+
+	// the refresh hold set at start was released
+	st.Lock()
+	defer st.Unlock()
+	tr = config.NewTransaction(st)
+	err = tr.Get("core", "refresh.hold", &t1)
+	c.Assert(config.IsNoOption(err), Equals, true, Commentf("unexpected error: %v", err))
+}
+
+func (s *consoleConfSuite) TestPostConsoleConfFinishRoutineUnexpectedBody(c *C) {
+	s.daemonWithOverlordMock(c)
+
+	req, err := http.NewRequest("POST", "/v2/internal/console-conf-finish", bytes.NewBufferString(`"foo"`))
+	c.Assert(err, IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.ErrorResult().Message, Matches, "cannot decode request body into console-conf operation: .*")
+}
@@ -290,6 +290,14 @@ func getDebug(c *Command, r *http.Request, user *auth.UserState) Response {
 		return getChangeTimings(st, chgID, ensureTag, startupTag, all == "true")
 	case "seeding":
 		return getSeedingInfo(st)
+	case "modeenv":
+		return getModeenv(r)
+	case "bootloader-vars":
+		return getBootloaderVars(r)
+	case "boot-chains":
+		return getBootChains(c, r)
+	case "boot-timings":
+		return getBootTimings()
 	default:
 		return BadRequest("unknown debug aspect %q", aspect)
 	}
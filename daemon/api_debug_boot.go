@@ -0,0 +1,114 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type bootloaderVars struct {
+	// RunMode are the boot variables of the run mode bootloader.
+	RunMode map[string]string `json:"run-mode"`
+	// Recovery are the boot variables of the recovery bootloader.
+	Recovery map[string]string `json:"recovery"`
+}
+
+// uc20BootDebugUnavailable returns an error response when the boot
+// information of a UC20 system cannot be inspected by the requester. The
+// boot information includes the sealed boot chains and the raw bootloader
+// environment, it is restricted to root.
+func uc20BootDebugUnavailable(r *http.Request, aspect string) Response {
+	if _, uid, _, err := ucrednetGet(r.RemoteAddr); err != nil || uid != 0 {
+		return Forbidden("cannot get %s: permission denied", aspect)
+	}
+	if !osutil.FileExists(dirs.SnapModeenvFile) {
+		return NotSupported("cannot get %s: not available on systems without a modeenv", aspect)
+	}
+	return nil
+}
+
+// getModeenv returns the modeenv of the system, the state must be locked
+// so that the boot state is not modified concurrently.
+func getModeenv(r *http.Request) Response {
+	if rsp := uc20BootDebugUnavailable(r, "modeenv"); rsp != nil {
+		return rsp
+	}
+	modeenv, err := boot.DebugModeenv()
+	if err != nil {
+		return InternalError("cannot get modeenv: %v", err)
+	}
+	return SyncResponse(modeenv, nil)
+}
+
+// getBootloaderVars returns the snapd variables of the run mode and
+// recovery bootloaders, the state must be locked.
+func getBootloaderVars(r *http.Request) Response {
+	if rsp := uc20BootDebugUnavailable(r, "bootloader variables"); rsp != nil {
+		return rsp
+	}
+	runVars, err := boot.DebugBootloaderVars(bootloader.RoleRunMode)
+	if err != nil {
+		return InternalError("cannot get run mode bootloader variables: %v", err)
+	}
+	recoveryVars, err := boot.DebugBootloaderVars(bootloader.RoleRecovery)
+	if err != nil {
+		return InternalError("cannot get recovery bootloader variables: %v", err)
+	}
+	return SyncResponse(&bootloaderVars{
+		RunMode:  runVars,
+		Recovery: recoveryVars,
+	}, nil)
+}
+
+// getBootChains returns the boot chains the encryption keys were sealed
+// with, along with the ones expected for the current state of the system,
+// the state must be locked.
+func getBootChains(c *Command, r *http.Request) Response {
+	if rsp := uc20BootDebugUnavailable(r, "boot chains"); rsp != nil {
+		return rsp
+	}
+	model, err := c.d.overlord.DeviceManager().Model()
+	if err != nil && err != state.ErrNoState {
+		return InternalError("cannot get model: %v", err)
+	}
+	chains, err := boot.DebugBootChains(model)
+	if err != nil {
+		return InternalError("cannot get boot chains: %v", err)
+	}
+	return SyncResponse(chains, nil)
+}
+
+// getBootTimings returns the timings of the most recent boots, oldest first.
+func getBootTimings() Response {
+	timings, err := boot.BootTimingsHistory()
+	if err != nil {
+		return InternalError("cannot get boot timings: %v", err)
+	}
+	if timings == nil {
+		timings = []*boot.BootTimings{}
+	}
+	return SyncResponse(timings, nil)
+}
@@ -0,0 +1,176 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/bootloadertest"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+)
+
+var _ = Suite(&bootDebugSuite{})
+
+type bootDebugSuite struct {
+	apiBaseSuite
+}
+
+func (s *bootDebugSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	d := s.daemonWithOverlordMockAndStore(c)
+	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
+	c.Assert(err, IsNil)
+	deviceMgr, err := devicestate.Manager(d.Overlord().State(), hookMgr, d.Overlord().TaskRunner(), nil)
+	c.Assert(err, IsNil)
+	d.Overlord().AddManager(deviceMgr)
+}
+
+func (s *bootDebugSuite) mockModeenv(c *C) {
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapModeenvFile), 0755), IsNil)
+	err := ioutil.WriteFile(dirs.SnapModeenvFile, []byte("mode=run\nrecovery_system=20191127\n"), 0644)
+	c.Assert(err, IsNil)
+}
+
+func (s *bootDebugSuite) getBootDebug(c *C, aspect string) interface{} {
+	req, err := http.NewRequest("GET", "/v2/debug?aspect="+aspect, nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Type, Equals, daemon.ResponseTypeSync)
+	return rsp.Result
+}
+
+func (s *bootDebugSuite) TestNoModeenv(c *C) {
+	for _, aspect := range []string{"modeenv", "bootloader-vars", "boot-chains"} {
+		req, err := http.NewRequest("GET", "/v2/debug?aspect="+aspect, nil)
+		c.Assert(err, IsNil)
+		req.RemoteAddr = "pid=100;uid=0;socket=;"
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, Equals, 400)
+		c.Check(rsp.ErrorResult().Kind, Equals, client.ErrorKindNotSupported)
+		c.Check(rsp.ErrorResult().Message, Matches, `cannot get .*: not available on systems without a modeenv`)
+	}
+}
+
+func (s *bootDebugSuite) TestNotRoot(c *C) {
+	s.mockModeenv(c)
+
+	for _, aspect := range []string{"modeenv", "bootloader-vars", "boot-chains"} {
+		req, err := http.NewRequest("GET", "/v2/debug?aspect="+aspect, nil)
+		c.Assert(err, IsNil)
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, Equals, 403)
+		c.Check(rsp.ErrorResult().Message, Matches, `cannot get .*: permission denied`)
+	}
+}
+
+func (s *bootDebugSuite) TestModeenv(c *C) {
+	s.mockModeenv(c)
+
+	data := s.getBootDebug(c, "modeenv")
+	c.Check(data, DeepEquals, map[string]string{
+		"mode":            "run",
+		"recovery_system": "20191127",
+	})
+}
+
+func (s *bootDebugSuite) TestBootloaderVars(c *C) {
+	s.mockModeenv(c)
+
+	bl := bootloadertest.Mock("mock", c.MkDir())
+	bl.BootVars = map[string]string{
+		"snapd_recovery_mode": "run",
+		"kernel_status":       "trying",
+	}
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+
+	data := s.getBootDebug(c, "bootloader-vars")
+	c.Assert(data, FitsTypeOf, &daemon.BootloaderVars{})
+	vars := data.(*daemon.BootloaderVars)
+	c.Check(vars.RunMode["snapd_recovery_mode"], Equals, "run")
+	c.Check(vars.RunMode["kernel_status"], Equals, "trying")
+	c.Check(vars.Recovery, DeepEquals, vars.RunMode)
+}
+
+func (s *bootDebugSuite) TestBootloaderVarsError(c *C) {
+	s.mockModeenv(c)
+
+	bootloader.ForceError(bootloader.ErrBootloader)
+	defer bootloader.Force(nil)
+
+	req, err := http.NewRequest("GET", "/v2/debug?aspect=bootloader-vars", nil)
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "cannot get run mode bootloader variables: cannot determine bootloader")
+}
+
+func (s *bootDebugSuite) TestBootChains(c *C) {
+	s.mockModeenv(c)
+
+	data := s.getBootDebug(c, "boot-chains")
+	c.Check(data, DeepEquals, &boot.DebugBootChainsInfo{})
+
+	// with a model, but without sealed keys there is nothing to compare
+	st := s.d.Overlord().State()
+	st.Lock()
+	s.mockModel(c, st, nil)
+	st.Unlock()
+
+	data = s.getBootDebug(c, "boot-chains")
+	c.Check(data, DeepEquals, &boot.DebugBootChainsInfo{})
+}
+
+func (s *bootDebugSuite) TestBootTimings(c *C) {
+	data := s.getBootDebug(c, "boot-timings")
+	c.Check(data, DeepEquals, []*boot.BootTimings{})
+
+	c.Assert(os.MkdirAll(filepath.Dir(dirs.SnapBootTimingsFile), 0755), IsNil)
+	err := ioutil.WriteFile(dirs.SnapBootTimingsFile, []byte(`[{"boot-id":"boot-1","time":"2021-06-01T10:00:00Z","kernel-snap":"pc-kernel_1.snap","firmware":1000000,"bootloader":2000000,"kernel":3000000,"initramfs":4000000,"snapd-ready":5000000}]`), 0644)
+	c.Assert(err, IsNil)
+
+	data = s.getBootDebug(c, "boot-timings")
+	c.Assert(data, FitsTypeOf, []*boot.BootTimings{})
+	timings := data.([]*boot.BootTimings)
+	c.Assert(timings, HasLen, 1)
+	c.Check(timings[0].BootID, Equals, "boot-1")
+	c.Check(timings[0].KernelSnap, Equals, "pc-kernel_1.snap")
+	c.Check(timings[0].Firmware, Equals, time.Millisecond)
+	c.Check(timings[0].SnapdReady, Equals, 5*time.Millisecond)
+}
@@ -22,6 +22,7 @@ package daemon
 import (
 	"time"
 
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -57,6 +58,10 @@ type seedingInfo struct {
 	// preseeded image.
 	SeedRestartSystemKey interface{} `json:"seed-restart-system-key,omitempty"`
 
+	// PreseededSnaps are the snaps that were set up when snap-preseed
+	// was ran.
+	PreseededSnaps []devicestate.PreseededSnap `json:"preseeded-snaps,omitempty"`
+
 	// SeedError is set if no seed change succeeded yet and at
 	// least one was in error. It is set to the error of the
 	// oldest known in error one.
@@ -81,11 +86,16 @@ func getSeedingInfo(st *state.State) Response {
 		return InternalError(err.Error())
 	}
 
+	preseededSnaps, err := devicestate.PreseededSnaps(st)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
 	var seedError string
 	var seedErrorChangeTime time.Time
 	if !seeded {
 		for _, chg := range st.Changes() {
-			if chg.Kind() != "seed" && !chg.IsReady() {
+			if chg.Kind() != "seed" || !chg.IsReady() {
 				continue
 			}
 			if err := chg.Err(); err != nil {
@@ -103,6 +113,7 @@ func getSeedingInfo(st *state.State) Response {
 		Preseeded:            preseeded,
 		PreseedSystemKey:     preseedSysKey,
 		SeedRestartSystemKey: seedRestartSysKey,
+		PreseededSnaps:       preseededSnaps,
 	}
 
 	for _, t := range []struct {
@@ -26,7 +26,9 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 var _ = Suite(&seedingDebugSuite{})
@@ -100,6 +102,26 @@ func (s *seedingDebugSuite) TestSeedingDebug(c *C) {
 	})
 }
 
+func (s *seedingDebugSuite) TestSeedingDebugPreseededSnaps(c *C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	st.Set("preseeded", true)
+	st.Set("preseeded-snaps", []map[string]interface{}{
+		{"name": "core18", "revision": "12", "type": "base"},
+		{"name": "test-snap", "revision": "x1", "type": "app"},
+	})
+	st.Unlock()
+
+	data := s.getSeedingDebug(c)
+	c.Check(data, DeepEquals, &daemon.SeedingInfo{
+		Preseeded: true,
+		PreseededSnaps: []devicestate.PreseededSnap{
+			{Name: "core18", Revision: snap.R(12), Type: snap.TypeBase},
+			{Name: "test-snap", Revision: snap.R(-1), Type: snap.TypeApp},
+		},
+	})
+}
+
 func (s *seedingDebugSuite) TestSeedingDebugSeededNoTimes(c *C) {
 	seedTime, err := time.Parse(time.RFC3339, "2020-01-01T10:00:07Z")
 	c.Assert(err, IsNil)
@@ -178,6 +200,15 @@ func (s *seedingDebugSuite) TestSeedingDebugPreseededSeedError(c *C) {
 
 	st.Set("preseed-time", preseedTime)
 
+	// errors of changes other than seed ones are not relevant
+	chg0 := st.NewChange("install-snap", "install a snap")
+	t01 := st.NewTask("other task", "t01")
+	chg0.AddTask(t01)
+	t01.SetStatus(state.ErrorStatus)
+	t01.Errorf("t01: unrelated error")
+
+	// ensure different spawn time
+	time.Sleep(50 * time.Millisecond)
 	chg1 := st.NewChange("seed", "tentative 1")
 	t11 := st.NewTask("seed task", "t11")
 	t12 := st.NewTask("seed task", "t12")
@@ -0,0 +1,88 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/osutil/disks"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var disksCmd = &Command{
+	Path:     "/v2/disks",
+	GET:      getDisks,
+	RootOnly: true,
+}
+
+// wrapped for unit tests
+var disksAllPhysicalDisks = disks.AllPhysicalDisks
+
+// snapdManagedFilesystemLabels are the filesystem labels of the system
+// partitions created and managed by snapd
+var snapdManagedFilesystemLabels = []string{
+	"ubuntu-seed",
+	"ubuntu-boot",
+	"ubuntu-data",
+	"ubuntu-data-enc",
+	"ubuntu-save",
+	"ubuntu-save-enc",
+}
+
+func getDisks(c *Command, r *http.Request, user *auth.UserState) Response {
+	physicalDisks, err := disksAllPhysicalDisks()
+	if err != nil {
+		return InternalError("cannot list disks: %v", err)
+	}
+
+	rsp := make([]client.Disk, 0, len(physicalDisks))
+	for _, d := range physicalDisks {
+		size, err := d.SizeInBytes()
+		if err != nil {
+			return InternalError("cannot list disks: %v", err)
+		}
+		parts, err := d.Partitions()
+		if err != nil {
+			return InternalError("cannot list partitions of disk %s: %v", d.KernelDeviceNode(), err)
+		}
+
+		disk := client.Disk{
+			KernelDeviceNode: d.KernelDeviceNode(),
+			Size:             size,
+		}
+		for _, p := range parts {
+			disk.Partitions = append(disk.Partitions, client.DiskPartition{
+				KernelDeviceNode: p.KernelDeviceNode,
+				PartitionUUID:    p.PartitionUUID,
+				PartitionLabel:   p.PartitionLabel,
+				FilesystemLabel:  p.FilesystemLabel,
+				FilesystemType:   p.FilesystemType,
+				Size:             p.SizeInBytes,
+				Encrypted:        p.FilesystemType == "crypto_LUKS",
+				SnapdManaged:     strutil.ListContains(snapdManagedFilesystemLabels, p.FilesystemLabel),
+			})
+		}
+		rsp = append(rsp, disk)
+	}
+
+	return SyncResponse(rsp, nil)
+}
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/osutil/disks"
+)
+
+var _ = Suite(&disksSuite{})
+
+type disksSuite struct {
+	apiBaseSuite
+}
+
+func (s *disksSuite) TestGetDisks(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		return []disks.Disk{
+			&disks.MockDiskMapping{
+				DevNum:            "42:0",
+				DevNode:           "/dev/vda",
+				DiskSizeInBytes:   8 * 1024 * 1024,
+				DiskHasPartitions: true,
+				Structure: []disks.Partition{
+					{
+						KernelDeviceNode: "/dev/vda1",
+						PartitionUUID:    "bios-boot-partuuid",
+						PartitionLabel:   "BIOS\\x20Boot",
+						SizeInBytes:      1024 * 1024,
+					}, {
+						KernelDeviceNode: "/dev/vda2",
+						PartitionUUID:    "ubuntu-seed-partuuid",
+						PartitionLabel:   "ubuntu-seed",
+						FilesystemLabel:  "ubuntu-seed",
+						FilesystemType:   "vfat",
+						SizeInBytes:      2 * 1024 * 1024,
+					}, {
+						KernelDeviceNode: "/dev/vda3",
+						PartitionUUID:    "ubuntu-data-partuuid",
+						PartitionLabel:   "ubuntu-data",
+						FilesystemLabel:  "ubuntu-data-enc",
+						FilesystemType:   "crypto_LUKS",
+						SizeInBytes:      4 * 1024 * 1024,
+					},
+				},
+			},
+			&disks.MockDiskMapping{
+				DevNum:          "8:0",
+				DevNode:         "/dev/sda",
+				DiskSizeInBytes: 1024 * 1024,
+			},
+		}, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/disks", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, []client.Disk{
+		{
+			KernelDeviceNode: "/dev/vda",
+			Size:             8 * 1024 * 1024,
+			Partitions: []client.DiskPartition{
+				{
+					KernelDeviceNode: "/dev/vda1",
+					PartitionUUID:    "bios-boot-partuuid",
+					PartitionLabel:   "BIOS\\x20Boot",
+					Size:             1024 * 1024,
+				}, {
+					KernelDeviceNode: "/dev/vda2",
+					PartitionUUID:    "ubuntu-seed-partuuid",
+					PartitionLabel:   "ubuntu-seed",
+					FilesystemLabel:  "ubuntu-seed",
+					FilesystemType:   "vfat",
+					Size:             2 * 1024 * 1024,
+					SnapdManaged:     true,
+				}, {
+					KernelDeviceNode: "/dev/vda3",
+					PartitionUUID:    "ubuntu-data-partuuid",
+					PartitionLabel:   "ubuntu-data",
+					FilesystemLabel:  "ubuntu-data-enc",
+					FilesystemType:   "crypto_LUKS",
+					Size:             4 * 1024 * 1024,
+					Encrypted:        true,
+					SnapdManaged:     true,
+				},
+			},
+		}, {
+			KernelDeviceNode: "/dev/sda",
+			Size:             1024 * 1024,
+		},
+	})
+}
+
+func (s *disksSuite) TestGetDisksError(c *C) {
+	s.daemon(c)
+
+	restore := daemon.MockDisksAllPhysicalDisks(func() ([]disks.Disk, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/disks", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "cannot list disks: boom")
+}
+
+func (s *disksSuite) TestGetDisksAsUserErrors(c *C) {
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/disks", nil)
+	c.Assert(err, IsNil)
+
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Assert(rec.Code, Equals, 401)
+}
@@ -38,9 +38,10 @@ import (
 
 var (
 	findCmd = &Command{
-		Path:   "/v2/find",
-		UserOK: true,
-		GET:    searchStore,
+		Path:           "/v2/find",
+		UserOK:         true,
+		SnapInterfaces: refreshControlAccess,
+		GET:            searchStore,
 	}
 )
 
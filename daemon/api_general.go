@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox"
@@ -56,11 +57,12 @@ var (
 	}
 
 	stateChangeCmd = &Command{
-		Path:     "/v2/changes/{id}",
-		UserOK:   true,
-		PolkitOK: "io.snapcraft.snapd.manage",
-		GET:      getChange,
-		POST:     abortChange,
+		Path:           "/v2/changes/{id}",
+		UserOK:         true,
+		PolkitOK:       "io.snapcraft.snapd.manage",
+		SnapInterfaces: changeAccess,
+		GET:            getChange,
+		POST:           abortChange,
 	}
 
 	stateChangesCmd = &Command{
@@ -81,6 +83,8 @@ var (
 var (
 	buildID     = "unknown"
 	systemdVirt = ""
+
+	snapMgrOnMeteredConnection = (*snapstate.SnapManager).OnMeteredConnection
 )
 
 func init() {
@@ -106,6 +110,7 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 	st.Lock()
 	defer st.Unlock()
 	nextRefresh := snapMgr.NextRefresh()
+	onMetered := snapMgrOnMeteredConnection(snapMgr)
 	lastRefresh, _ := snapMgr.LastRefresh()
 	refreshHold, _ := snapMgr.EffectiveRefreshHold()
 	refreshScheduleStr, legacySchedule, err := snapMgr.RefreshSchedule()
@@ -117,10 +122,29 @@ func sysInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		return InternalError("cannot get user auth data: %s", err)
 	}
 
+	userHold, err := snapstate.SystemRefreshHold(st)
+	if err != nil {
+		return InternalError("cannot get refresh hold: %s", err)
+	}
+	snapHolds, err := snapstate.SnapRefreshHolds(st)
+	if err != nil {
+		return InternalError("cannot get refresh holds: %s", err)
+	}
+
 	refreshInfo := client.RefreshInfo{
-		Last: formatRefreshTime(lastRefresh),
-		Hold: formatRefreshTime(refreshHold),
-		Next: formatRefreshTime(nextRefresh),
+		Last:      formatRefreshTime(lastRefresh),
+		Hold:      formatRefreshTime(refreshHold),
+		Next:      formatRefreshTime(nextRefresh),
+		OnMetered: onMetered,
+	}
+	if userHold != nil && (userHold.Forever() || userHold.Until.After(refreshHold)) {
+		refreshInfo.Hold = formatRefreshHold(userHold)
+	}
+	if len(snapHolds) > 0 {
+		refreshInfo.SnapHolds = make(map[string]string, len(snapHolds))
+		for name, hold := range snapHolds {
+			refreshInfo.SnapHolds[name] = formatRefreshHold(hold)
+		}
 	}
 	if !legacySchedule {
 		refreshInfo.Timer = refreshScheduleStr
@@ -174,6 +198,13 @@ func formatRefreshTime(t time.Time) string {
 	return fmt.Sprintf("%s", t.Truncate(time.Minute).Format(time.RFC3339))
 }
 
+func formatRefreshHold(hold *snapstate.RefreshHold) string {
+	if hold.Forever() {
+		return "forever"
+	}
+	return formatRefreshTime(hold.Until)
+}
+
 func sandboxFeatures(backends []interfaces.SecurityBackend) map[string][]string {
 	result := make(map[string][]string, len(backends)+1)
 	for _, backend := range backends {
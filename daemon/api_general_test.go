@@ -33,11 +33,13 @@ import (
 
 	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces/ifacetest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox"
@@ -223,6 +225,39 @@ func (s *generalSuite) TestSysInfoLegacyRefresh(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, expected)
 }
 
+func (s *generalSuite) TestSysInfoRefreshHolds(c *check.C) {
+	req, err := http.NewRequest("GET", "/v2/system-info", nil)
+	c.Assert(err, check.IsNil)
+
+	d := s.daemon(c)
+
+	until := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	st := d.Overlord().State()
+	st.Lock()
+	st.Set("refresh-holds", map[string]interface{}{
+		"system": map[string]interface{}{},
+		"snaps": map[string]interface{}{
+			"foo": map[string]interface{}{"until": until},
+		},
+	})
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	s.req(c, req, nil).ServeHTTP(rec, nil)
+	c.Check(rec.Code, check.Equals, 200)
+
+	var rsp struct {
+		Result struct {
+			Refresh client.RefreshInfo `json:"refresh"`
+		} `json:"result"`
+	}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), check.IsNil)
+	c.Check(rsp.Result.Refresh.Hold, check.Equals, "forever")
+	c.Check(rsp.Result.Refresh.SnapHolds, check.DeepEquals, map[string]string{
+		"foo": until.Format(time.RFC3339),
+	})
+}
+
 func (s *generalSuite) testSysInfoSystemMode(c *check.C, mode string) {
 	req, err := http.NewRequest("GET", "/v2/system-info", nil)
 	c.Assert(err, check.IsNil)
@@ -328,6 +363,23 @@ func (s *generalSuite) TestSysInfoIsManaged(c *check.C) {
 	c.Check(rsp.Result.(map[string]interface{})["managed"], check.Equals, true)
 }
 
+func (s *generalSuite) TestSysInfoOnMetered(c *check.C) {
+	restore := daemon.MockSnapMgrOnMeteredConnection(func(*snapstate.SnapManager) bool {
+		return true
+	})
+	defer restore()
+
+	s.daemon(c)
+
+	req, err := http.NewRequest("GET", "/v2/system-info", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	refreshInfo, ok := rsp.Result.(map[string]interface{})["refresh"].(client.RefreshInfo)
+	c.Assert(ok, check.Equals, true)
+	c.Check(refreshInfo.OnMetered, check.Equals, true)
+}
+
 func (s *generalSuite) TestSysInfoWorksDegraded(c *check.C) {
 	d := s.daemon(c)
 
@@ -21,13 +21,24 @@ package daemon
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 var (
@@ -37,10 +48,11 @@ var (
 		UserOK: true,
 	}
 	modelCmd = &Command{
-		Path:   "/v2/model",
-		POST:   postModel,
-		GET:    getModel,
-		UserOK: true,
+		Path:     "/v2/model",
+		POST:     postModel,
+		GET:      getModel,
+		UserOK:   true,
+		PolkitOK: "io.snapcraft.snapd.remodel",
 	}
 )
 
@@ -64,6 +76,12 @@ type modelAssertJSON struct {
 
 func postModel(c *Command, r *http.Request, _ *auth.UserState) Response {
 	defer r.Body.Close()
+
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		return remodelOffline(c, r.Body, params["boundary"], r.RemoteAddr)
+	}
+
 	var data postModelData
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(&data); err != nil {
@@ -82,14 +100,156 @@ func postModel(c *Command, r *http.Request, _ *auth.UserState) Response {
 	st.Lock()
 	defer st.Unlock()
 
-	chg, err := devicestateRemodel(st, newModel)
+	chg, err := devicestateRemodel(st, newModel, nil, nil)
+	if err != nil {
+		return BadRequest("cannot remodel device: %v", err)
+	}
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+
+}
+
+// remodelOffline starts a remodel using the snap files and assertions
+// provided in a multipart/form-data payload, together with the new model
+// in the "new-model" field.
+func remodelOffline(c *Command, body io.ReadCloser, boundary, remoteAddr string) Response {
+	form, err := multipart.NewReader(body, boundary).ReadForm(maxReadBuflen)
+	if err != nil {
+		return BadRequest("cannot read POST form: %v", err)
+	}
+	defer form.RemoveAll()
+
+	if len(form.Value["new-model"]) != 1 {
+		return BadRequest(`cannot find exactly one "new-model" field in provided multipart/form-data payload`)
+	}
+	rawNewModel, err := asserts.Decode([]byte(form.Value["new-model"][0]))
+	if err != nil {
+		return BadRequest("cannot decode new model assertion: %v", err)
+	}
+	newModel, ok := rawNewModel.(*asserts.Model)
+	if !ok {
+		return BadRequest("new model is not a model assertion: %v", rawNewModel.Type())
+	}
+
+	// the assertions of the snaps, so that they can be verified offline
+	var assertions []asserts.Assertion
+	if len(form.File["assertion"]) > 0 {
+		// this adds assertions to the system database, which
+		// is not something remodeling otherwise allows
+		if _, uid, _, err := ucrednetGet(remoteAddr); err != nil || uid != 0 {
+			return Forbidden("cannot add assertions together with the snaps: permission denied")
+		}
+		for _, fheader := range form.File["assertion"] {
+			as, err := readAssertionFile(fheader)
+			if err != nil {
+				return BadRequest(err.Error())
+			}
+			assertions = append(assertions, as...)
+		}
+	}
+
+	// we are in charge of the tempfiles life cycle until we hand them
+	// off to the change
+	var paths []string
+	changeTriggered := false
+	defer func() {
+		if !changeTriggered {
+			for _, path := range paths {
+				os.Remove(path)
+			}
+		}
+	}()
+	for _, fheader := range form.File["snap"] {
+		path, err := writeRemodelSnapFile(fheader)
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		paths = append(paths, path)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var db asserts.RODatabase = assertstate.DB(st)
+	var batch *asserts.Batch
+	if len(assertions) > 0 {
+		batch = asserts.NewBatch(nil)
+		for _, a := range assertions {
+			if err := batch.Add(a); err != nil {
+				return BadRequest("cannot add assertions: %v", err)
+			}
+		}
+		// verify the snaps against the assertions without adding
+		// them to the system database yet
+		tmpDB := assertstate.TemporaryDB(st)
+		if err := batch.CommitTo(tmpDB, nil); err != nil {
+			return BadRequest("cannot add assertions: %v", err)
+		}
+		db = tmpDB
+	}
+
+	sideInfos := make([]*snap.SideInfo, 0, len(paths))
+	for i, path := range paths {
+		si, err := snapasserts.DeriveSideInfo(path, db)
+		if err != nil {
+			if asserts.IsNotFound(err) {
+				return BadRequest("cannot find signatures with metadata for snap %q", form.File["snap"][i].Filename)
+			}
+			return BadRequest(err.Error())
+		}
+		sideInfos = append(sideInfos, si)
+	}
+
+	if batch != nil {
+		if err := checkSnapAssertions(assertions, sideInfos...); err != nil {
+			return BadRequest("cannot add assertions: %v", err)
+		}
+	}
+
+	chg, err := devicestateRemodel(st, newModel, sideInfos, paths)
 	if err != nil {
 		return BadRequest("cannot remodel device: %v", err)
 	}
+
+	if batch != nil {
+		// the snaps were verified against the assertions
+		if err := assertstate.AddBatch(st, batch, &asserts.CommitOptions{
+			Precheck: true,
+		}); err != nil {
+			chg.Abort()
+			return InternalError("cannot add assertions: %v", err)
+		}
+	}
 	ensureStateSoon(st)
+	changeTriggered = true
 
 	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
+
+func writeRemodelSnapFile(fheader *multipart.FileHeader) (path string, err error) {
+	snapBody, err := fheader.Open()
+	if err != nil {
+		return "", fmt.Errorf(`cannot open uploaded "snap" file: %v`, err)
+	}
+	defer snapBody.Close()
+
+	// if you change this prefix, look for it in the tests
+	// also see localInstallCleanup in snapstate/snapmgr.go
+	tmpf, err := ioutil.TempFile(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix)
+	if err != nil {
+		return "", fmt.Errorf("cannot create temporary file: %v", err)
+	}
+	defer tmpf.Close()
+
+	if _, err := io.Copy(tmpf, snapBody); err != nil {
+		os.Remove(tmpf.Name())
+		return "", fmt.Errorf("cannot copy request into temporary file: %v", err)
+	}
+	tmpf.Sync()
 
+	return tmpf.Name(), nil
 }
 
 // getModel gets the current model assertion using the DeviceManager
@@ -25,6 +25,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"time"
 
 	"gopkg.in/check.v1"
@@ -33,12 +34,17 @@ import (
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/polkit"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
 )
 
 var modelDefaults = map[string]interface{}{
@@ -66,6 +72,29 @@ func (s *modelSuite) TestPostRemodelUnhappy(c *check.C) {
 	c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Matches, "cannot decode new model assertion: .*")
 }
 
+func (s *modelSuite) TestPostRemodelAsUserPolkit(c *check.C) {
+	s.daemon(c)
+
+	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+		c.Fatalf("unexpected remodel")
+		return nil, nil
+	})()
+	var polkitActionID string
+	defer daemon.MockPolkitCheckAuthorization(func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error) {
+		polkitActionID = actionId
+		return false, nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/model", bytes.NewBufferString(`{"new-model": "garbage"}`))
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Check(rec.Code, check.Equals, 401)
+	c.Check(polkitActionID, check.Equals, "io.snapcraft.snapd.remodel")
+}
+
 func (s *modelSuite) TestPostRemodel(c *check.C) {
 	oldModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults)
 	newModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults, map[string]interface{}{
@@ -94,7 +123,9 @@ func (s *modelSuite) TestPostRemodel(c *check.C) {
 	defer restore()
 
 	var devicestateRemodelGotModel *asserts.Model
-	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model) (*state.Change, error) {
+	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+		c.Check(localSnaps, check.HasLen, 0)
+		c.Check(paths, check.HasLen, 0)
 		devicestateRemodelGotModel = nm
 		chg := st.NewChange("remodel", "...")
 		return chg, nil
@@ -376,3 +407,258 @@ func (s *modelSuite) TestGetModelJSONHasSerialAssertion(c *check.C) {
 	c.Assert(devKey, check.FitsTypeOf, "")
 	c.Assert(devKey.(string), check.Equals, string(encDevKey))
 }
+
+func (s *modelSuite) TestPostRemodelOffline(c *check.C) {
+	oldModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults)
+	newModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults, map[string]interface{}{
+		"revision": "2",
+	})
+
+	d := s.daemonWithOverlordMockAndStore(c)
+	st := d.Overlord().State()
+	st.Lock()
+	assertstatetest.AddMany(st, s.StoreSigning.StoreAccountKey(""))
+	assertstatetest.AddMany(st, s.Brands.AccountsAndKeys("my-brand")...)
+	s.mockModel(c, st, oldModel)
+	st.Unlock()
+
+	dev1Acct := assertstest.NewAccount(s.StoreSigning, "devel1", nil, "")
+	snapDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "x-id",
+		"snap-name":    "x",
+		"publisher-id": dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	snapRev, err := s.StoreSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": "YK0GWATaZf09g_fvspYPqm_qtaiqf-KjaNj5uMEQCjQpuXWPjqQbeBINL5H_A0Lo",
+		"snap-size":     "5",
+		"snap-id":       "x-id",
+		"snap-revision": "41",
+		"developer-id":  dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	var assertsBuf bytes.Buffer
+	enc := asserts.NewEncoder(&assertsBuf)
+	for _, a := range []asserts.Assertion{dev1Acct, snapDecl, snapRev} {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+
+	var gotPaths []string
+	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+		c.Check(nm, check.DeepEquals, newModel)
+		c.Check(localSnaps, check.DeepEquals, []*snap.SideInfo{{
+			RealName: "x",
+			SnapID:   "x-id",
+			Revision: snap.R(41),
+		}})
+		c.Assert(paths, check.HasLen, 1)
+		c.Check(filepath.Base(paths[0]), testutil.Contains, dirs.LocalInstallBlobTempPrefix)
+		c.Check(paths[0], testutil.FileEquals, "xyzzy")
+		// the assertions are not added before the snaps are verified
+		_, err := assertstate.DB(st).Find(asserts.SnapDeclarationType, map[string]string{
+			"series":  "16",
+			"snap-id": "x-id",
+		})
+		c.Check(asserts.IsNotFound(err), check.Equals, true)
+		gotPaths = paths
+		return st.NewChange("remodel", "..."), nil
+	})()
+
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"new-model\"\r\n" +
+		"\r\n" +
+		string(asserts.Encode(newModel)) + "\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"assertion\"; filename=\"x.assert\"\r\n" +
+		"\r\n" +
+		assertsBuf.String() + "\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x.snap\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello----\r\n"
+	req, err := http.NewRequest("POST", "/v2/model", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=--hello--")
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+
+	rsp := s.asyncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 202)
+	c.Assert(gotPaths, check.HasLen, 1)
+	// the snap file is handed off to the change
+	c.Check(gotPaths[0], testutil.FilePresent)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "remodel")
+
+	// and then added
+	_, err = assertstate.DB(st).Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "x-id",
+	})
+	c.Check(err, check.IsNil)
+}
+
+func (s *modelSuite) TestPostRemodelOfflineAssertionsErrors(c *check.C) {
+	oldModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults)
+	newModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults, map[string]interface{}{
+		"revision": "2",
+	})
+
+	d := s.daemonWithOverlordMockAndStore(c)
+	st := d.Overlord().State()
+	st.Lock()
+	assertstatetest.AddMany(st, s.StoreSigning.StoreAccountKey(""))
+	assertstatetest.AddMany(st, s.Brands.AccountsAndKeys("my-brand")...)
+	s.mockModel(c, st, oldModel)
+	st.Unlock()
+
+	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+		c.Fatalf("unexpected remodel")
+		return nil, nil
+	})()
+
+	dev1Acct := assertstest.NewAccount(s.StoreSigning, "devel1", nil, "")
+	snapDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "x-id",
+		"snap-name":    "x",
+		"publisher-id": dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	snapRev, err := s.StoreSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": "YK0GWATaZf09g_fvspYPqm_qtaiqf-KjaNj5uMEQCjQpuXWPjqQbeBINL5H_A0Lo",
+		"snap-size":     "5",
+		"snap-id":       "x-id",
+		"snap-revision": "41",
+		"developer-id":  dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	otherDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "y-id",
+		"snap-name":    "y",
+		"publisher-id": dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	var assertsBuf bytes.Buffer
+	enc := asserts.NewEncoder(&assertsBuf)
+	for _, a := range []asserts.Assertion{dev1Acct, snapDecl, snapRev, otherDecl} {
+		c.Assert(enc.Encode(a), check.IsNil)
+	}
+
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"new-model\"\r\n" +
+		"\r\n" +
+		string(asserts.Encode(newModel)) + "\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"assertion\"; filename=\"x.assert\"\r\n" +
+		"\r\n" +
+		assertsBuf.String() + "\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x.snap\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello----\r\n"
+
+	for _, tc := range []struct {
+		remoteAddr string
+		status     int
+		err        string
+	}{
+		// only root can add assertions
+		{"pid=100;uid=1000;socket=;", 403, "cannot add assertions together with the snaps: permission denied"},
+		{"", 403, "cannot add assertions together with the snaps: permission denied"},
+		// unrelated assertions are refused
+		{"pid=100;uid=0;socket=;", 400, `cannot add assertions: assertion snap-declaration (y-id; series:16) is not related to snap "x"`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/model", bytes.NewBufferString(body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=--hello--")
+		req.RemoteAddr = tc.remoteAddr
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, check.Equals, tc.status)
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, tc.err)
+	}
+
+	// nothing was added to the system database
+	st.Lock()
+	defer st.Unlock()
+	_, err = assertstate.DB(st).Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "x-id",
+	})
+	c.Check(asserts.IsNotFound(err), check.Equals, true)
+
+	// the temporary snap files were removed
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*"))
+	c.Assert(err, check.IsNil)
+	c.Check(matches, check.HasLen, 0)
+}
+
+func (s *modelSuite) TestPostRemodelOfflineNoSignatures(c *check.C) {
+	newModel := s.Brands.Model("my-brand", "my-old-model", modelDefaults, map[string]interface{}{
+		"revision": "2",
+	})
+	s.daemonWithOverlordMockAndStore(c)
+
+	defer daemon.MockDevicestateRemodel(func(st *state.State, nm *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+		c.Fatalf("unexpected remodel")
+		return nil, nil
+	})()
+
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"new-model\"\r\n" +
+		"\r\n" +
+		string(asserts.Encode(newModel)) + "\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x.snap\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello----\r\n"
+	req, err := http.NewRequest("POST", "/v2/model", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=--hello--")
+
+	rsp := s.errorReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, `cannot find signatures with metadata for snap "x.snap"`)
+
+	// the temporary snap file was removed
+	matches, err := filepath.Glob(filepath.Join(dirs.SnapBlobDir, dirs.LocalInstallBlobTempPrefix+"*"))
+	c.Assert(err, check.IsNil)
+	c.Check(matches, check.HasLen, 0)
+}
+
+func (s *modelSuite) TestPostRemodelOfflineNoModel(c *check.C) {
+	s.daemon(c)
+
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x.snap\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello----\r\n"
+	req, err := http.NewRequest("POST", "/v2/model", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=--hello--")
+
+	rsp := s.errorReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, `cannot find exactly one "new-model" field in provided multipart/form-data payload`)
+}
@@ -0,0 +1,276 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+)
+
+var (
+	promptsCmd = &Command{
+		Path:   "/v2/interfaces/requests/prompts",
+		UserOK: true,
+		GET:    getPrompts,
+	}
+
+	// prompts and rules are looked up by the uid of the request, so
+	// users can reply to their own prompts and manage their own rules
+	promptCmd = &Command{
+		Path:        "/v2/interfaces/requests/prompts/{id}",
+		UserOK:      true,
+		UserOwnedOK: true,
+		GET:         getPrompt,
+		POST:        postPrompt,
+	}
+
+	rulesCmd = &Command{
+		Path:   "/v2/interfaces/requests/rules",
+		UserOK: true,
+		GET:    getRules,
+	}
+
+	ruleCmd = &Command{
+		Path:        "/v2/interfaces/requests/rules/{id}",
+		UserOK:      true,
+		UserOwnedOK: true,
+		GET:         getRule,
+		POST:        postRule,
+	}
+)
+
+var promptingEnabled = (*ifacestate.InterfaceManager).PromptingEnabled
+
+// promptingUser returns the UID of the user making the request, or an
+// error response if prompting is not available.
+func promptingUser(c *Command, r *http.Request) (uint32, Response) {
+	if !promptingEnabled(c.d.overlord.InterfaceManager()) {
+		return 0, BadRequest("AppArmor prompting is not enabled")
+	}
+	_, uid, _, err := ucrednetGet(r.RemoteAddr)
+	if err != nil {
+		return 0, Forbidden("cannot get remote user: %s", err)
+	}
+	return uid, nil
+}
+
+func getPrompts(c *Command, r *http.Request, _ *auth.UserState) Response {
+	uid, errRsp := promptingUser(c, r)
+	if errRsp != nil {
+		return errRsp
+	}
+	prompts := c.d.overlord.InterfaceManager().PromptDB().Prompts(uid)
+	return SyncResponse(prompts, nil)
+}
+
+func getPrompt(c *Command, r *http.Request, _ *auth.UserState) Response {
+	uid, errRsp := promptingUser(c, r)
+	if errRsp != nil {
+		return errRsp
+	}
+	id := muxVars(r)["id"]
+	prompt, err := c.d.overlord.InterfaceManager().PromptDB().PromptWithID(uid, id)
+	if err != nil {
+		return NotFound("%v %q", err, id)
+	}
+	return SyncResponse(prompt, nil)
+}
+
+type postPromptData struct {
+	Outcome  prompting.OutcomeType  `json:"outcome"`
+	Lifespan prompting.LifespanType `json:"lifespan"`
+	// PathPattern and Permissions of the rule recorded for a forever
+	// lifespan, they default to the path and permissions of the prompt
+	PathPattern string   `json:"path-pattern,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// postPrompt replies to a prompt of the user. When the decision is to
+// apply forever, it is recorded as a rule and the other prompts of the
+// user matching it are replied to as well. The IDs of the prompts
+// replied to are returned.
+func postPrompt(c *Command, r *http.Request, _ *auth.UserState) Response {
+	uid, errRsp := promptingUser(c, r)
+	if errRsp != nil {
+		return errRsp
+	}
+	id := muxVars(r)["id"]
+
+	var data postPromptData
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return BadRequest("cannot decode prompt reply from request body: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("extra content found in request body")
+	}
+	if err := prompting.ValidateOutcome(data.Outcome); err != nil {
+		return BadRequest("%v", err)
+	}
+	if err := prompting.ValidateLifespan(data.Lifespan); err != nil {
+		return BadRequest("%v", err)
+	}
+
+	promptDB := c.d.overlord.InterfaceManager().PromptDB()
+	prompt, err := promptDB.PromptWithID(uid, id)
+	if err != nil {
+		return NotFound("%v %q", err, id)
+	}
+
+	if data.Lifespan == prompting.LifespanForever {
+		rule := &prompting.Rule{
+			User:        uid,
+			Snap:        prompt.Snap,
+			Interface:   prompt.Interface,
+			PathPattern: data.PathPattern,
+			Permissions: data.Permissions,
+			Outcome:     data.Outcome,
+		}
+		if rule.PathPattern == "" {
+			rule.PathPattern = prompt.Path
+		}
+		if len(rule.Permissions) == 0 {
+			rule.Permissions = prompt.Permissions
+		}
+		if err := rule.Validate(); err != nil {
+			return BadRequest("cannot reply to prompt %q: %v", id, err)
+		}
+		if !rule.Matches(prompt) {
+			return BadRequest("cannot reply to prompt %q: rule does not match the path and permissions of the prompt", id)
+		}
+
+		st := c.d.overlord.State()
+		st.Lock()
+		err := ifacestate.AddPromptingRule(st, rule)
+		st.Unlock()
+		if err != nil {
+			return InternalError("%v", err)
+		}
+
+		replied := []string{}
+		for _, p := range promptDB.Prompts(uid) {
+			if !rule.Matches(p) {
+				continue
+			}
+			if _, err := promptDB.Reply(uid, p.ID, data.Outcome); err == nil {
+				replied = append(replied, p.ID)
+			}
+		}
+		return SyncResponse(replied, nil)
+	}
+
+	if _, err := promptDB.Reply(uid, id, data.Outcome); err != nil {
+		return NotFound("%v %q", err, id)
+	}
+	return SyncResponse([]string{id}, nil)
+}
+
+func getRules(c *Command, r *http.Request, _ *auth.UserState) Response {
+	uid, errRsp := promptingUser(c, r)
+	if errRsp != nil {
+		return errRsp
+	}
+	snapName := r.URL.Query().Get("snap")
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	rules, err := ifacestate.PromptingRules(st, uid, snapName)
+	if err != nil {
+		return InternalError("%v", err)
+	}
+	if rules == nil {
+		rules = []*prompting.Rule{}
+	}
+	return SyncResponse(rules, nil)
+}
+
+func findRule(c *Command, uid uint32, id string) (*prompting.Rule, Response) {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	rules, err := ifacestate.PromptingRules(st, uid, "")
+	if err != nil {
+		return nil, InternalError("%v", err)
+	}
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, NotFound("%v %q", ifacestate.ErrPromptingRuleNotFound, id)
+}
+
+func getRule(c *Command, r *http.Request, _ *auth.UserState) Response {
+	uid, errRsp := promptingUser(c, r)
+	if errRsp != nil {
+		return errRsp
+	}
+	rule, errRsp := findRule(c, uid, muxVars(r)["id"])
+	if errRsp != nil {
+		return errRsp
+	}
+	return SyncResponse(rule, nil)
+}
+
+type postRuleData struct {
+	Action string `json:"action"`
+}
+
+func postRule(c *Command, r *http.Request, _ *auth.UserState) Response {
+	uid, errRsp := promptingUser(c, r)
+	if errRsp != nil {
+		return errRsp
+	}
+	id := muxVars(r)["id"]
+
+	var data postRuleData
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return BadRequest("cannot decode rule action from request body: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("extra content found in request body")
+	}
+
+	switch data.Action {
+	case "remove":
+		st := c.d.overlord.State()
+		st.Lock()
+		defer st.Unlock()
+
+		rule, err := ifacestate.RemovePromptingRule(st, uid, id)
+		if err == ifacestate.ErrPromptingRuleNotFound {
+			return NotFound("%v %q", err, id)
+		}
+		if err != nil {
+			return InternalError("%v", err)
+		}
+		return SyncResponse(rule, nil)
+	default:
+		return BadRequest("unknown rule action %q", data.Action)
+	}
+}
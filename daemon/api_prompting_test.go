@@ -0,0 +1,195 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+)
+
+var _ = Suite(&apiPromptingSuite{})
+
+type apiPromptingSuite struct {
+	apiBaseSuite
+}
+
+func (s *apiPromptingSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.daemon(c)
+
+	s.AddCleanup(daemon.MockPromptingEnabled(true))
+}
+
+func (s *apiPromptingSuite) promptingReq(c *C, method, url, body string) *http.Request {
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	c.Assert(err, IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	return req
+}
+
+func (s *apiPromptingSuite) addPrompt(c *C, uid uint32, path string) *prompting.Prompt {
+	prompt, err := s.d.Overlord().InterfaceManager().PromptDB().Add(&prompting.Prompt{
+		User:        uid,
+		Snap:        "foo",
+		App:         "app",
+		Interface:   "home",
+		Path:        path,
+		Permissions: []string{"read"},
+	})
+	c.Assert(err, IsNil)
+	return prompt
+}
+
+func (s *apiPromptingSuite) TestPromptingNotEnabled(c *C) {
+	restore := daemon.MockPromptingEnabled(false)
+	defer restore()
+
+	for _, url := range []string{"/v2/interfaces/requests/prompts", "/v2/interfaces/requests/rules"} {
+		rsp := s.errorReq(c, s.promptingReq(c, "GET", url, ""), nil)
+		c.Check(rsp.Status, Equals, 400)
+		c.Check(rsp.ErrorResult().Message, Equals, "AppArmor prompting is not enabled")
+	}
+}
+
+func (s *apiPromptingSuite) TestGetPrompts(c *C) {
+	p1 := s.addPrompt(c, 1000, "/home/test/foo.txt")
+	s.addPrompt(c, 1001, "/home/other/foo.txt")
+
+	rsp := s.syncReq(c, s.promptingReq(c, "GET", "/v2/interfaces/requests/prompts", ""), nil)
+	c.Check(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, []*prompting.Prompt{p1})
+
+	rsp = s.syncReq(c, s.promptingReq(c, "GET", "/v2/interfaces/requests/prompts/"+p1.ID, ""), nil)
+	c.Check(rsp.Result, Equals, p1)
+}
+
+func (s *apiPromptingSuite) TestGetPromptOfOtherUser(c *C) {
+	p := s.addPrompt(c, 1001, "/home/other/foo.txt")
+
+	rsp := s.errorReq(c, s.promptingReq(c, "GET", "/v2/interfaces/requests/prompts/"+p.ID, ""), nil)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.ErrorResult().Message, Equals, `cannot find prompt "`+p.ID+`"`)
+}
+
+func (s *apiPromptingSuite) TestReplyPromptSingle(c *C) {
+	p1 := s.addPrompt(c, 1000, "/home/test/foo.txt")
+	p2 := s.addPrompt(c, 1000, "/home/test/foo.txt")
+
+	rsp := s.syncReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/prompts/"+p1.ID, `{"outcome": "allow", "lifespan": "single"}`), nil)
+	c.Check(rsp.Result, DeepEquals, []string{p1.ID})
+	c.Check(<-p1.Replied(), Equals, prompting.OutcomeAllow)
+
+	// other prompts are left alone
+	c.Check(s.d.Overlord().InterfaceManager().PromptDB().Prompts(1000), DeepEquals, []*prompting.Prompt{p2})
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	rules, err := ifacestate.PromptingRules(st, 1000, "")
+	st.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+}
+
+func (s *apiPromptingSuite) TestReplyPromptForever(c *C) {
+	p1 := s.addPrompt(c, 1000, "/home/test/Documents/foo.txt")
+	p2 := s.addPrompt(c, 1000, "/home/test/Documents/bar.txt")
+	p3 := s.addPrompt(c, 1000, "/home/test/baz.txt")
+
+	rsp := s.syncReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/prompts/"+p1.ID, `{"outcome": "deny", "lifespan": "forever", "path-pattern": "/home/test/Documents/**"}`), nil)
+	c.Check(rsp.Result, DeepEquals, []string{p1.ID, p2.ID})
+	c.Check(<-p1.Replied(), Equals, prompting.OutcomeDeny)
+	c.Check(<-p2.Replied(), Equals, prompting.OutcomeDeny)
+	c.Check(s.d.Overlord().InterfaceManager().PromptDB().Prompts(1000), DeepEquals, []*prompting.Prompt{p3})
+
+	rsp = s.syncReq(c, s.promptingReq(c, "GET", "/v2/interfaces/requests/rules?snap=foo", ""), nil)
+	rules, ok := rsp.Result.([]*prompting.Rule)
+	c.Assert(ok, Equals, true)
+	c.Assert(rules, HasLen, 1)
+	c.Check(rules[0].User, Equals, uint32(1000))
+	c.Check(rules[0].Snap, Equals, "foo")
+	c.Check(rules[0].Interface, Equals, "home")
+	c.Check(rules[0].PathPattern, Equals, "/home/test/Documents/**")
+	c.Check(rules[0].Permissions, DeepEquals, []string{"read"})
+	c.Check(rules[0].Outcome, Equals, prompting.OutcomeDeny)
+
+	rsp = s.syncReq(c, s.promptingReq(c, "GET", "/v2/interfaces/requests/rules/"+rules[0].ID, ""), nil)
+	c.Check(rsp.Result, DeepEquals, rules[0])
+}
+
+func (s *apiPromptingSuite) TestReplyPromptErrors(c *C) {
+	p := s.addPrompt(c, 1000, "/home/test/foo.txt")
+
+	for _, t := range []struct {
+		body   string
+		status int
+		err    string
+	}{
+		{`{"outcome": "maybe", "lifespan": "single"}`, 400, `invalid outcome "maybe"`},
+		{`{"outcome": "allow", "lifespan": "sometimes"}`, 400, `invalid lifespan "sometimes"`},
+		{`{"outcome": "allow"`, 400, `cannot decode prompt reply from request body: .*`},
+		{`{"outcome": "allow", "lifespan": "forever", "path-pattern": "/home/test/Documents/**"}`, 400, `cannot reply to prompt "1": rule does not match the path and permissions of the prompt`},
+		{`{"outcome": "allow", "lifespan": "forever", "path-pattern": "foo"}`, 400, `cannot reply to prompt "1": invalid path pattern "foo": .*`},
+	} {
+		rsp := s.errorReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/prompts/"+p.ID, t.body), nil)
+		c.Check(rsp.Status, Equals, t.status)
+		c.Check(rsp.ErrorResult().Message, Matches, t.err)
+	}
+
+	rsp := s.errorReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/prompts/missing", `{"outcome": "allow", "lifespan": "single"}`), nil)
+	c.Check(rsp.Status, Equals, 404)
+
+	// the prompt is still pending
+	c.Check(s.d.Overlord().InterfaceManager().PromptDB().Prompts(1000), DeepEquals, []*prompting.Prompt{p})
+}
+
+func (s *apiPromptingSuite) TestRemoveRule(c *C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	rule := &prompting.Rule{
+		User:        1000,
+		Snap:        "foo",
+		Interface:   "home",
+		PathPattern: "/home/test/**",
+		Permissions: []string{"read"},
+		Outcome:     prompting.OutcomeAllow,
+	}
+	c.Assert(ifacestate.AddPromptingRule(st, rule), IsNil)
+	st.Unlock()
+
+	rsp := s.errorReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/rules/"+rule.ID, `{"action": "frobnicate"}`), nil)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.ErrorResult().Message, Equals, `unknown rule action "frobnicate"`)
+
+	rsp = s.syncReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/rules/"+rule.ID, `{"action": "remove"}`), nil)
+	c.Check(rsp.Result.(*prompting.Rule).ID, Equals, rule.ID)
+
+	rsp = s.syncReq(c, s.promptingReq(c, "GET", "/v2/interfaces/requests/rules", ""), nil)
+	c.Check(rsp.Result, DeepEquals, []*prompting.Rule{})
+
+	rsp = s.errorReq(c, s.promptingReq(c, "POST", "/v2/interfaces/requests/rules/"+rule.ID, `{"action": "remove"}`), nil)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.ErrorResult().Message, Equals, `cannot find prompting rule "1"`)
+}
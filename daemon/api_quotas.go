@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+var (
+	quotaGroupsCmd = &Command{
+		Path:     "/v2/quotas",
+		GET:      getQuotaGroups,
+		POST:     postQuotaGroup,
+		RootOnly: true,
+	}
+	quotaGroupInfoCmd = &Command{
+		Path:     "/v2/quotas/{group}",
+		GET:      getQuotaGroupInfo,
+		RootOnly: true,
+	}
+)
+
+// wrapped for unit tests
+var (
+	servicestateCreateQuota = servicestate.CreateQuota
+	servicestateUpdateQuota = servicestate.UpdateQuota
+	servicestateRemoveQuota = servicestate.RemoveQuota
+
+	quotaGroupCurrentMemoryUsage = (*quota.Group).CurrentMemoryUsage
+)
+
+type postQuotaGroupData struct {
+	Action    string        `json:"action"`
+	GroupName string        `json:"group-name"`
+	Snaps     []string      `json:"snaps,omitempty"`
+	MaxMemory quantity.Size `json:"max-memory,omitempty"`
+}
+
+func quotaGroupResult(grp *quota.Group) (*client.QuotaGroupResult, error) {
+	mem, err := quotaGroupCurrentMemoryUsage(grp)
+	if err != nil {
+		return nil, err
+	}
+	return &client.QuotaGroupResult{
+		GroupName:     grp.Name,
+		Snaps:         grp.Snaps,
+		MaxMemory:     uint64(grp.MemoryLimit),
+		CurrentMemory: uint64(mem),
+	}, nil
+}
+
+// getQuotaGroups returns all quota groups sorted by name.
+func getQuotaGroups(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	quotas, err := servicestate.AllQuotas(st)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+
+	names := make([]string, 0, len(quotas))
+	for name := range quotas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]*client.QuotaGroupResult, 0, len(names))
+	for _, name := range names {
+		res, err := quotaGroupResult(quotas[name])
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		results = append(results, res)
+	}
+	return SyncResponse(results, nil)
+}
+
+// getQuotaGroupInfo returns details of a single quota group.
+func getQuotaGroupInfo(c *Command, r *http.Request, _ *auth.UserState) Response {
+	name := muxVars(r)["group"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	grp, err := servicestate.GetQuota(st, name)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+	if grp == nil {
+		return NotFound("cannot find quota group %q", name)
+	}
+
+	res, err := quotaGroupResult(grp)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+	return SyncResponse(res, nil)
+}
+
+// postQuotaGroup creates, updates, or removes a quota group.
+func postQuotaGroup(c *Command, r *http.Request, _ *auth.UserState) Response {
+	var data postQuotaGroupData
+
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&data); err != nil {
+		return BadRequest("cannot decode quota action from request body: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("extra content found in request body")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var ts *state.TaskSet
+	var summary string
+	switch data.Action {
+	case "ensure":
+		grp, err := servicestate.GetQuota(st, data.GroupName)
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		if grp == nil {
+			// the quota group does not exist, create it
+			ts, err = servicestateCreateQuota(st, data.GroupName, data.Snaps, data.MaxMemory)
+			summary = fmt.Sprintf("Create quota group %q", data.GroupName)
+		} else {
+			ts, err = servicestateUpdateQuota(st, data.GroupName, servicestate.QuotaGroupUpdate{
+				AddSnaps:       data.Snaps,
+				NewMemoryLimit: data.MaxMemory,
+			})
+			summary = fmt.Sprintf("Update quota group %q", data.GroupName)
+		}
+		if err != nil {
+			if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+				return SnapChangeConflict(cce)
+			}
+			return BadRequest(err.Error())
+		}
+	case "remove":
+		grp, err := servicestate.GetQuota(st, data.GroupName)
+		if err != nil {
+			return InternalError(err.Error())
+		}
+		if grp == nil {
+			return NotFound("cannot find quota group %q", data.GroupName)
+		}
+		ts, err = servicestateRemoveQuota(st, data.GroupName)
+		if err != nil {
+			if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+				return SnapChangeConflict(cce)
+			}
+			return InternalError(err.Error())
+		}
+		summary = fmt.Sprintf("Remove quota group %q", data.GroupName)
+	default:
+		return BadRequest("unknown quota action %q", data.Action)
+	}
+
+	chg := newChange(st, "quota-control", summary, []*state.TaskSet{ts}, nil)
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
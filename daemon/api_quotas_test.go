@@ -0,0 +1,289 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+var _ = Suite(&apiQuotaSuite{})
+
+type apiQuotaSuite struct {
+	apiBaseSuite
+}
+
+func (s *apiQuotaSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+	s.daemon(c)
+
+	s.AddCleanup(daemon.MockQuotaGroupCurrentMemoryUsage(func(grp *quota.Group) (quantity.Size, error) {
+		return grp.MemoryLimit / 2, nil
+	}))
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {})
+	s.AddCleanup(restore)
+}
+
+func (s *apiQuotaSuite) mockQuotas(c *C, quotas map[string]*quota.Group) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	st.Set("quotas", quotas)
+}
+
+func (s *apiQuotaSuite) postQuota(c *C, data map[string]interface{}) *http.Request {
+	body, err := json.Marshal(data)
+	c.Assert(err, IsNil)
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewReader(body))
+	c.Assert(err, IsNil)
+	return req
+}
+
+func (s *apiQuotaSuite) TestGetQuotaGroups(c *C) {
+	s.mockQuotas(c, map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: 1000, Snaps: []string{"some-snap"}},
+		"bar": {Name: "bar", MemoryLimit: 2000},
+	})
+
+	req, err := http.NewRequest("GET", "/v2/quotas", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, []*client.QuotaGroupResult{
+		{GroupName: "bar", MaxMemory: 2000, CurrentMemory: 1000},
+		{GroupName: "foo", Snaps: []string{"some-snap"}, MaxMemory: 1000, CurrentMemory: 500},
+	})
+}
+
+func (s *apiQuotaSuite) TestGetQuotaGroupsNone(c *C) {
+	req, err := http.NewRequest("GET", "/v2/quotas", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, []*client.QuotaGroupResult{})
+}
+
+func (s *apiQuotaSuite) TestGetQuotaGroupInfo(c *C) {
+	s.mockQuotas(c, map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: 1000, Snaps: []string{"some-snap"}},
+	})
+
+	req, err := http.NewRequest("GET", "/v2/quotas/foo", nil)
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(rsp.Result, DeepEquals, &client.QuotaGroupResult{
+		GroupName:     "foo",
+		Snaps:         []string{"some-snap"},
+		MaxMemory:     1000,
+		CurrentMemory: 500,
+	})
+}
+
+func (s *apiQuotaSuite) TestGetQuotaGroupInfoNotFound(c *C) {
+	req, err := http.NewRequest("GET", "/v2/quotas/unknown", nil)
+	c.Assert(err, IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.ErrorResult().Message, Equals, `cannot find quota group "unknown"`)
+}
+
+func (s *apiQuotaSuite) TestGetQuotaGroupInfoMemoryUsageError(c *C) {
+	s.mockQuotas(c, map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: 1000},
+	})
+	restore := daemon.MockQuotaGroupCurrentMemoryUsage(func(grp *quota.Group) (quantity.Size, error) {
+		return 0, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/quotas/foo", nil)
+	c.Assert(err, IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "boom")
+}
+
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreate(c *C) {
+	created := 0
+	restore := daemon.MockServicestateCreateQuota(func(st *state.State, name string, snaps []string, memoryLimit quantity.Size) (*state.TaskSet, error) {
+		created++
+		c.Check(name, Equals, "foo")
+		c.Check(snaps, DeepEquals, []string{"some-snap"})
+		c.Check(memoryLimit, Equals, quantity.Size(1000))
+		return state.NewTaskSet(st.NewTask("quota-control", "...")), nil
+	})
+	defer restore()
+
+	req := s.postQuota(c, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "foo",
+		"snaps":      []string{"some-snap"},
+		"max-memory": 1000,
+	})
+	rsp := s.asyncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 202)
+	c.Check(created, Equals, 1)
+	s.checkQuotaChange(c, rsp.Change, `Create quota group "foo"`)
+}
+
+func (s *apiQuotaSuite) checkQuotaChange(c *C, chgID, summary string) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(chgID)
+	c.Assert(chg, NotNil)
+	c.Check(chg.Kind(), Equals, "quota-control")
+	c.Check(chg.Summary(), Equals, summary)
+	c.Check(chg.Tasks(), HasLen, 1)
+}
+
+func (s *apiQuotaSuite) TestPostEnsureQuotaCreateError(c *C) {
+	restore := daemon.MockServicestateCreateQuota(func(st *state.State, name string, snaps []string, memoryLimit quantity.Size) (*state.TaskSet, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	req := s.postQuota(c, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "foo",
+		"max-memory": 1000,
+	})
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.ErrorResult().Message, Equals, "boom")
+}
+
+func (s *apiQuotaSuite) TestPostEnsureQuotaUpdate(c *C) {
+	s.mockQuotas(c, map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: 1000},
+	})
+
+	updated := 0
+	restore := daemon.MockServicestateUpdateQuota(func(st *state.State, name string, opts servicestate.QuotaGroupUpdate) (*state.TaskSet, error) {
+		updated++
+		c.Check(name, Equals, "foo")
+		c.Check(opts, DeepEquals, servicestate.QuotaGroupUpdate{
+			AddSnaps:       []string{"some-snap"},
+			NewMemoryLimit: 9000,
+		})
+		return state.NewTaskSet(st.NewTask("quota-control", "...")), nil
+	})
+	defer restore()
+
+	req := s.postQuota(c, map[string]interface{}{
+		"action":     "ensure",
+		"group-name": "foo",
+		"snaps":      []string{"some-snap"},
+		"max-memory": 9000,
+	})
+	rsp := s.asyncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 202)
+	c.Check(updated, Equals, 1)
+	s.checkQuotaChange(c, rsp.Change, `Update quota group "foo"`)
+}
+
+func (s *apiQuotaSuite) TestPostRemoveQuota(c *C) {
+	s.mockQuotas(c, map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: 1000},
+	})
+
+	removed := 0
+	restore := daemon.MockServicestateRemoveQuota(func(st *state.State, name string) (*state.TaskSet, error) {
+		removed++
+		c.Check(name, Equals, "foo")
+		return state.NewTaskSet(st.NewTask("quota-control", "...")), nil
+	})
+	defer restore()
+
+	req := s.postQuota(c, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "foo",
+	})
+	rsp := s.asyncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 202)
+	c.Check(removed, Equals, 1)
+	s.checkQuotaChange(c, rsp.Change, `Remove quota group "foo"`)
+}
+
+func (s *apiQuotaSuite) TestPostQuotaConflict(c *C) {
+	s.mockQuotas(c, map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: 1000},
+	})
+	conflict := &snapstate.ChangeConflictError{Message: `quota group "foo" has "quota-control" change in progress`}
+	restore := daemon.MockServicestateUpdateQuota(func(st *state.State, name string, opts servicestate.QuotaGroupUpdate) (*state.TaskSet, error) {
+		return nil, conflict
+	})
+	defer restore()
+	restore = daemon.MockServicestateRemoveQuota(func(st *state.State, name string) (*state.TaskSet, error) {
+		return nil, conflict
+	})
+	defer restore()
+
+	for _, action := range []string{"ensure", "remove"} {
+		req := s.postQuota(c, map[string]interface{}{
+			"action":     action,
+			"group-name": "foo",
+		})
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, Equals, 409)
+		c.Check(rsp.ErrorResult().Message, Equals, `quota group "foo" has "quota-control" change in progress`)
+	}
+}
+
+func (s *apiQuotaSuite) TestPostRemoveQuotaNotFound(c *C) {
+	req := s.postQuota(c, map[string]interface{}{
+		"action":     "remove",
+		"group-name": "foo",
+	})
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 404)
+	c.Check(rsp.ErrorResult().Message, Equals, `cannot find quota group "foo"`)
+}
+
+func (s *apiQuotaSuite) TestPostQuotaUnknownAction(c *C) {
+	req := s.postQuota(c, map[string]interface{}{
+		"action":     "foo",
+		"group-name": "bar",
+	})
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.ErrorResult().Message, Equals, `unknown quota action "foo"`)
+}
+
+func (s *apiQuotaSuite) TestPostQuotaInvalidBody(c *C) {
+	req, err := http.NewRequest("POST", "/v2/quotas", bytes.NewBufferString("{"))
+	c.Assert(err, IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.ErrorResult().Message, Matches, `cannot decode quota action from request body: .*`)
+}
@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var systemSealingCmd = &Command{
+	Path:     "/v2/system-sealing",
+	GET:      getSealingStatus,
+	POST:     postSealingAction,
+	RootOnly: true,
+}
+
+// wrapped for unit tests
+var (
+	bootGetSealingStatus = boot.GetSealingStatus
+	bootResealKeys       = boot.ResealKeys
+)
+
+func sealingStatusResponse(dev boot.Device) Response {
+	status, err := bootGetSealingStatus(dev)
+	if err != nil {
+		return InternalError("cannot get sealing status: %v", err)
+	}
+	rsp := &client.SealingStatus{
+		Status:            client.SealingStatusUnsealed,
+		Method:            status.Method,
+		TPMAvailable:      status.TPMAvailable,
+		LastBootUnlockKey: status.LastBootUnlockKey,
+		ResealCount:       status.ResealCount,
+		LastReseal:        status.LastReseal,
+	}
+	switch {
+	case status.Sealed && status.FallbackUsed():
+		rsp.Status = client.SealingStatusFallbackUsed
+	case status.Sealed:
+		rsp.Status = client.SealingStatusSealed
+	}
+	return SyncResponse(rsp, nil)
+}
+
+// sealingDevice returns the device the sealing status is reported for, the
+// state must be locked.
+func sealingDevice(st *state.State) (snapstate.DeviceContext, Response) {
+	deviceCtx, err := snapstate.DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, InternalError("cannot get device context: %v", err)
+	}
+	if !deviceCtx.HasModeenv() {
+		return nil, NotSupported("sealing status is only available on UC20 systems")
+	}
+	return deviceCtx, nil
+}
+
+func getSealingStatus(c *Command, r *http.Request, user *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	dev, rsp := sealingDevice(st)
+	if rsp != nil {
+		return rsp
+	}
+	return sealingStatusResponse(dev)
+}
+
+type sealingActionRequest struct {
+	Action string `json:"action"`
+}
+
+func postSealingAction(c *Command, r *http.Request, user *auth.UserState) Response {
+	var req sealingActionRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		return BadRequest("cannot decode request body into sealing action: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("extra content found in request body")
+	}
+
+	switch req.Action {
+	case "reseal":
+		// handled below
+	default:
+		return BadRequest("unsupported sealing action %q", req.Action)
+	}
+
+	// the state is kept locked so that no change can modify the boot
+	// state concurrently
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	dev, rsp := sealingDevice(st)
+	if rsp != nil {
+		return rsp
+	}
+
+	// changes of the kernel, base or gadget reseal the keys themselves,
+	// refuse to race with them
+	model := dev.Model()
+	base := model.Base()
+	if base == "" {
+		base = "core"
+	}
+	if err := snapstate.CheckChangeConflictMany(st, []string{model.Kernel(), base, model.Gadget()}, ""); err != nil {
+		if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+			return SnapChangeConflict(cce)
+		}
+		return InternalError("cannot reseal keys: %v", err)
+	}
+
+	if err := bootResealKeys(dev); err != nil {
+		return InternalError("cannot reseal keys: %v", err)
+	}
+	return sealingStatusResponse(dev)
+}
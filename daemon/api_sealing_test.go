@@ -0,0 +1,261 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+var _ = Suite(&sealingSuite{})
+
+type sealingSuite struct {
+	apiBaseSuite
+
+	status *boot.SealingStatus
+}
+
+func (s *sealingSuite) SetUpTest(c *C) {
+	s.apiBaseSuite.SetUpTest(c)
+
+	s.status = &boot.SealingStatus{
+		Sealed:            true,
+		Method:            "tpm",
+		TPMAvailable:      true,
+		LastBootUnlockKey: "run",
+		ResealCount:       3,
+		LastReseal:        time.Date(2021, 5, 7, 10, 0, 0, 0, time.UTC),
+	}
+	s.AddCleanup(daemon.MockBootGetSealingStatus(func(dev boot.Device) (*boot.SealingStatus, error) {
+		c.Check(dev.HasModeenv(), Equals, true)
+		return s.status, nil
+	}))
+}
+
+func (s *sealingSuite) mockDaemon(c *C, uc20 bool) {
+	d := s.daemonWithOverlordMockAndStore(c)
+	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
+	c.Assert(err, IsNil)
+	deviceMgr, err := devicestate.Manager(d.Overlord().State(), hookMgr, d.Overlord().TaskRunner(), nil)
+	c.Assert(err, IsNil)
+	d.Overlord().AddManager(deviceMgr)
+
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	if !uc20 {
+		s.mockModel(c, st, nil)
+		return
+	}
+	s.mockModel(c, st, s.Brands.Model("can0nical", "pc-20", map[string]interface{}{
+		"architecture": "amd64",
+		"base":         "core20",
+		"grade":        "dangerous",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              snaptest.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              snaptest.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	}))
+}
+
+func (s *sealingSuite) TestGetSealingStatus(c *C) {
+	s.mockDaemon(c, true)
+
+	for _, tc := range []struct {
+		sealed  bool
+		unlock  string
+		status  string
+		resealN int
+	}{
+		{true, "run", "sealed", 3},
+		{true, "", "sealed", 3},
+		{true, "fallback", "fallback-used", 3},
+		{true, "recovery", "fallback-used", 3},
+		{false, "", "unsealed", 0},
+	} {
+		s.status.Sealed = tc.sealed
+		s.status.LastBootUnlockKey = tc.unlock
+		if !tc.sealed {
+			s.status = &boot.SealingStatus{}
+		}
+
+		req, err := http.NewRequest("GET", "/v2/system-sealing", nil)
+		c.Assert(err, IsNil)
+
+		rsp := s.syncReq(c, req, nil)
+		c.Assert(rsp.Status, Equals, 200)
+		expected := &client.SealingStatus{Status: tc.status}
+		if tc.sealed {
+			expected = &client.SealingStatus{
+				Status:            tc.status,
+				Method:            "tpm",
+				TPMAvailable:      true,
+				LastBootUnlockKey: tc.unlock,
+				ResealCount:       tc.resealN,
+				LastReseal:        time.Date(2021, 5, 7, 10, 0, 0, 0, time.UTC),
+			}
+		}
+		c.Check(rsp.Result, DeepEquals, expected, Commentf("%v", tc))
+	}
+}
+
+func (s *sealingSuite) TestGetSealingStatusNotUC20(c *C) {
+	s.mockDaemon(c, false)
+
+	req, err := http.NewRequest("GET", "/v2/system-sealing", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 400)
+	c.Check(rsp.ErrorResult().Kind, Equals, client.ErrorKindNotSupported)
+	c.Check(rsp.ErrorResult().Message, Equals, "sealing status is only available on UC20 systems")
+}
+
+func (s *sealingSuite) TestGetSealingStatusError(c *C) {
+	s.mockDaemon(c, true)
+
+	defer daemon.MockBootGetSealingStatus(func(dev boot.Device) (*boot.SealingStatus, error) {
+		return nil, fmt.Errorf("boom")
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/system-sealing", nil)
+	c.Assert(err, IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "cannot get sealing status: boom")
+}
+
+func (s *sealingSuite) TestPostReseal(c *C) {
+	s.mockDaemon(c, true)
+
+	called := 0
+	defer daemon.MockBootResealKeys(func(dev boot.Device) error {
+		called++
+		c.Check(dev.Model().Model(), Equals, "pc-20")
+		s.status.ResealCount++
+		return nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/system-sealing", strings.NewReader(`{"action":"reseal"}`))
+	c.Assert(err, IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+	c.Check(called, Equals, 1)
+	c.Check(rsp.Result, DeepEquals, &client.SealingStatus{
+		Status:            "sealed",
+		Method:            "tpm",
+		TPMAvailable:      true,
+		LastBootUnlockKey: "run",
+		ResealCount:       4,
+		LastReseal:        time.Date(2021, 5, 7, 10, 0, 0, 0, time.UTC),
+	})
+}
+
+func (s *sealingSuite) TestPostSealingErrors(c *C) {
+	s.mockDaemon(c, true)
+
+	defer daemon.MockBootResealKeys(func(dev boot.Device) error {
+		return fmt.Errorf("boom")
+	})()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+		err    string
+	}{
+		{`{"action":"reseal"}`, 500, "cannot reseal keys: boom"},
+		{`{"action":"foo"}`, 400, `unsupported sealing action "foo"`},
+		{`{"action":"reseal"}{}`, 400, "extra content found in request body"},
+		{`{`, 400, "cannot decode request body into sealing action: unexpected EOF"},
+	} {
+		req, err := http.NewRequest("POST", "/v2/system-sealing", strings.NewReader(tc.body))
+		c.Assert(err, IsNil)
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, Equals, tc.status, Commentf("%s", tc.body))
+		c.Check(rsp.ErrorResult().Message, Equals, tc.err, Commentf("%s", tc.body))
+	}
+}
+
+func (s *sealingSuite) TestPostResealConflict(c *C) {
+	s.mockDaemon(c, true)
+
+	defer daemon.MockBootResealKeys(func(dev boot.Device) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})()
+
+	st := s.d.Overlord().State()
+	st.Lock()
+	chg := st.NewChange("refresh-snap", "...")
+	t := st.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{RealName: "pc-kernel", Revision: snap.R(2)},
+	})
+	chg.AddTask(t)
+	st.Unlock()
+
+	req, err := http.NewRequest("POST", "/v2/system-sealing", strings.NewReader(`{"action":"reseal"}`))
+	c.Assert(err, IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 409)
+	c.Check(rsp.ErrorResult().Message, Equals, `snap "pc-kernel" has "refresh-snap" change in progress`)
+}
+
+func (s *sealingSuite) TestSealingAsUserErrors(c *C) {
+	s.mockDaemon(c, true)
+
+	for _, method := range []string{"GET", "POST"} {
+		req, err := http.NewRequest(method, "/v2/system-sealing", strings.NewReader(`{"action":"reseal"}`))
+		c.Assert(err, IsNil)
+
+		req.RemoteAddr = "pid=100;uid=1000;socket=;"
+		rec := httptest.NewRecorder()
+		s.serveHTTP(c, rec, req)
+		c.Check(rec.Code, Equals, 401, Commentf("%s", method))
+	}
+}
@@ -43,7 +43,7 @@ import (
 
 const maxReadBuflen = 1024 * 1024
 
-func sideloadOrTrySnap(c *Command, body io.ReadCloser, boundary string, user *auth.UserState) Response {
+func sideloadOrTrySnap(c *Command, body io.ReadCloser, boundary, remoteAddr string, user *auth.UserState) Response {
 	route := c.d.router.Get(stateChangeCmd.Path)
 	if route == nil {
 		return InternalError("cannot find route for change")
@@ -97,6 +97,24 @@ out:
 		return BadRequest(`cannot find "snap" file field in provided multipart/form-data payload`)
 	}
 
+	// any assertions sent along with the snap, so that it can be
+	// installed verified in a single step
+	var assertions []asserts.Assertion
+	if len(form.File["assertion"]) > 0 {
+		// this adds assertions to the system database, which
+		// is not something installing snaps otherwise allows
+		if _, uid, _, err := ucrednetGet(remoteAddr); err != nil || uid != 0 {
+			return Forbidden("cannot add assertions together with the snap: permission denied")
+		}
+		for _, fheader := range form.File["assertion"] {
+			as, err := readAssertionFile(fheader)
+			if err != nil {
+				return BadRequest(err.Error())
+			}
+			assertions = append(assertions, as...)
+		}
+	}
+
 	// we are in charge of the tempfile life cycle until we hand it off to the change
 	changeTriggered := false
 	// if you change this prefix, look for it in the tests
@@ -140,8 +158,26 @@ out:
 	var snapName string
 	var sideInfo *snap.SideInfo
 
+	var db asserts.RODatabase = assertstate.DB(st)
+	var batch *asserts.Batch
+	if len(assertions) > 0 {
+		batch = asserts.NewBatch(nil)
+		for _, a := range assertions {
+			if err := batch.Add(a); err != nil {
+				return BadRequest("cannot add assertions: %v", err)
+			}
+		}
+		// verify the snap against the assertions without
+		// adding them to the system database yet
+		tmpDB := assertstate.TemporaryDB(st)
+		if err := batch.CommitTo(tmpDB, nil); err != nil {
+			return BadRequest("cannot add assertions: %v", err)
+		}
+		db = tmpDB
+	}
+
 	if !dangerousOK {
-		si, err := snapasserts.DeriveSideInfo(tempPath, assertstate.DB(st))
+		si, err := snapasserts.DeriveSideInfo(tempPath, db)
 		switch {
 		case err == nil:
 			snapName = si.RealName
@@ -162,6 +198,15 @@ out:
 		}
 	}
 
+	if batch != nil {
+		if sideInfo == nil {
+			return BadRequest("cannot add assertions: no snap could be verified with them")
+		}
+		if err := checkSnapAssertions(assertions, sideInfo); err != nil {
+			return BadRequest("cannot add assertions: %v", err)
+		}
+	}
+
 	if snapName == "" {
 		// potentially dangerous but dangerous or devmode params were set
 		info, err := unsafeReadSnapInfo(tempPath)
@@ -191,6 +236,15 @@ out:
 		return errToResponse(err, []string{snapName}, InternalError, "cannot install snap file: %v")
 	}
 
+	if batch != nil {
+		// the snap was verified against the assertions
+		if err := assertstate.AddBatch(st, batch, &asserts.CommitOptions{
+			Precheck: true,
+		}); err != nil {
+			return InternalError("cannot add assertions: %v", err)
+		}
+	}
+
 	chg := newChange(st, "install-snap", msg, []*state.TaskSet{tset}, []string{instanceName})
 	chg.Set("api-data", map[string]string{"snap-name": instanceName})
 
@@ -203,6 +257,61 @@ out:
 	return AsyncResponse(nil, &Meta{Change: chg.ID()})
 }
 
+func readAssertionFile(fheader *multipart.FileHeader) ([]asserts.Assertion, error) {
+	f, err := fheader.Open()
+	if err != nil {
+		return nil, fmt.Errorf(`cannot open uploaded "assertion" file: %v`, err)
+	}
+	defer f.Close()
+
+	var as []asserts.Assertion
+	dec := asserts.NewDecoder(f)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode assertions in %q: %v", fheader.Filename, err)
+		}
+		as = append(as, a)
+	}
+	return as, nil
+}
+
+// checkSnapAssertions checks that the assertions sent along with snaps
+// are only the ones needed to verify them: their snap-revision and
+// snap-declaration and the accounts and keys involved in signing them.
+func checkSnapAssertions(as []asserts.Assertion, sis ...*snap.SideInfo) error {
+	for _, a := range as {
+		if !snapAssertionRelated(a, sis) {
+			if len(sis) == 1 {
+				return fmt.Errorf("assertion %s is not related to snap %q", a.Ref(), sis[0].RealName)
+			}
+			return fmt.Errorf("assertion %s is not related to any of the snaps", a.Ref())
+		}
+	}
+	return nil
+}
+
+func snapAssertionRelated(a asserts.Assertion, sis []*snap.SideInfo) bool {
+	for _, si := range sis {
+		switch a := a.(type) {
+		case *asserts.SnapRevision:
+			if a.SnapID() == si.SnapID && a.SnapRevision() == si.Revision.N {
+				return true
+			}
+		case *asserts.SnapDeclaration:
+			if a.SnapID() == si.SnapID {
+				return true
+			}
+		case *asserts.Account, *asserts.AccountKey:
+			return true
+		}
+	}
+	return false
+}
+
 func trySnap(st *state.State, trydir string, flags snapstate.Flags) Response {
 	st.Lock()
 	defer st.Unlock()
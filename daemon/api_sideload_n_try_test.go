@@ -28,6 +28,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"gopkg.in/check.v1"
@@ -37,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -332,6 +334,166 @@ func (s *sideloadSuite) TestLocalInstallSnapDeriveSideInfo(c *check.C) {
 	})
 }
 
+func (s *sideloadSuite) mockSnapXAssertions(c *check.C) []asserts.Assertion {
+	dev1Acct := assertstest.NewAccount(s.StoreSigning, "devel1", nil, "")
+
+	snapDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "x-id",
+		"snap-name":    "x",
+		"publisher-id": dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	snapRev, err := s.StoreSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-sha3-384": "YK0GWATaZf09g_fvspYPqm_qtaiqf-KjaNj5uMEQCjQpuXWPjqQbeBINL5H_A0Lo",
+		"snap-size":     "5",
+		"snap-id":       "x-id",
+		"snap-revision": "41",
+		"developer-id":  dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	return []asserts.Assertion{s.StoreSigning.StoreAccountKey(""), dev1Acct, snapDecl, snapRev}
+}
+
+func encodeAssertions(as ...asserts.Assertion) string {
+	var encoded []string
+	for _, a := range as {
+		encoded = append(encoded, string(asserts.Encode(a)))
+	}
+	return strings.Join(encoded, "\n")
+}
+
+func (s *sideloadSuite) TestLocalInstallSnapWithAssertions(c *check.C) {
+	d := s.daemonWithOverlordMockAndStore(c)
+	st := d.Overlord().State()
+
+	as := s.mockSnapXAssertions(c)
+
+	// the assertions can be split across several "assertion" fields
+	body := "" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"snap\"; filename=\"x.snap\"\r\n" +
+		"\r\n" +
+		"xyzzy\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"assertion\"; filename=\"x.assert\"\r\n" +
+		"\r\n" +
+		encodeAssertions(as[:3]...) + "\r\n" +
+		"----hello--\r\n" +
+		"Content-Disposition: form-data; name=\"assertion\"; filename=\"x-rev.assert\"\r\n" +
+		"\r\n" +
+		encodeAssertions(as[3]) + "\r\n" +
+		"----hello--\r\n"
+	req, err := http.NewRequest("POST", "/v2/snaps", bytes.NewBufferString(body))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/thing; boundary=--hello--")
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+
+	defer daemon.MockSnapstateInstallPath(func(s *state.State, si *snap.SideInfo, path, name, channel string, flags snapstate.Flags) (*state.TaskSet, *snap.Info, error) {
+		c.Check(flags, check.Equals, snapstate.Flags{RemoveSnapPath: true})
+		c.Check(si, check.DeepEquals, &snap.SideInfo{
+			RealName: "x",
+			SnapID:   "x-id",
+			Revision: snap.R(41),
+		})
+		// the assertions are added only once the install is set up
+		_, err := assertstate.DB(s).Find(asserts.SnapRevisionType, map[string]string{
+			"snap-sha3-384": "YK0GWATaZf09g_fvspYPqm_qtaiqf-KjaNj5uMEQCjQpuXWPjqQbeBINL5H_A0Lo",
+		})
+		c.Check(asserts.IsNotFound(err), check.Equals, true)
+
+		return state.NewTaskSet(), &snap.Info{SuggestedName: "x"}, nil
+	})()
+
+	rsp := s.asyncReq(c, req, nil)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Summary(), check.Equals, `Install "x" snap from file "x.snap"`)
+
+	// the assertions were added to the database
+	_, err = assertstate.DB(st).Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": "YK0GWATaZf09g_fvspYPqm_qtaiqf-KjaNj5uMEQCjQpuXWPjqQbeBINL5H_A0Lo",
+	})
+	c.Check(err, check.IsNil)
+}
+
+func (s *sideloadSuite) TestLocalInstallSnapWithAssertionsUnhappy(c *check.C) {
+	d := s.daemonWithOverlordMockAndStore(c)
+	st := d.Overlord().State()
+
+	as := s.mockSnapXAssertions(c)
+	otherDecl, err := s.StoreSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      "other-id",
+		"snap-name":    "other",
+		"publisher-id": as[1].(*asserts.Account).AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+
+	defer daemon.MockSnapstateInstallPath(func(*state.State, *snap.SideInfo, string, string, string, snapstate.Flags) (*state.TaskSet, *snap.Info, error) {
+		c.Fatalf("unexpected install")
+		return nil, nil, nil
+	})()
+
+	mkReq := func(uid, snapData, assertions string) *http.Request {
+		body := "" +
+			"----hello--\r\n" +
+			"Content-Disposition: form-data; name=\"snap\"; filename=\"x.snap\"\r\n" +
+			"\r\n" +
+			snapData + "\r\n" +
+			"----hello--\r\n" +
+			"Content-Disposition: form-data; name=\"assertion\"; filename=\"x.assert\"\r\n" +
+			"\r\n" +
+			assertions + "\r\n" +
+			"----hello--\r\n"
+		req, err := http.NewRequest("POST", "/v2/snaps", bytes.NewBufferString(body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "multipart/thing; boundary=--hello--")
+		req.RemoteAddr = fmt.Sprintf("pid=100;uid=%s;socket=;", uid)
+		return req
+	}
+
+	for _, t := range []struct {
+		uid        string
+		snapData   string
+		assertions string
+		status     int
+		err        string
+	}{
+		// only root can add assertions
+		{"1000", "xyzzy", encodeAssertions(as...), 403, `cannot add assertions together with the snap: permission denied`},
+		// the account-key of the store is missing
+		{"0", "xyzzy", encodeAssertions(as[1]), 400, `cannot add assertions: .*`},
+		// not a stream of assertions
+		{"0", "xyzzy", "garbage", 400, `cannot decode assertions in "x.assert": .*`},
+		// assertions not for the snap being installed
+		{"0", "xyzzy", encodeAssertions(append(as, otherDecl)...), 400, `cannot add assertions: assertion snap-declaration \(other-id; series:16\) is not related to snap "x"`},
+		// the snap does not match the assertions
+		{"0", "other", encodeAssertions(as...), 400, `cannot find signatures with metadata for snap "x.snap"`},
+	} {
+		rsp := s.errorReq(c, mkReq(t.uid, t.snapData, t.assertions), nil)
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf(t.err))
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Matches, t.err)
+	}
+
+	// nothing was added to the database
+	st.Lock()
+	defer st.Unlock()
+	_, err = assertstate.DB(st).Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  "16",
+		"snap-id": "x-id",
+	})
+	c.Check(asserts.IsNotFound(err), check.Equals, true)
+}
+
 func (s *sideloadSuite) TestSideloadSnapNoSignaturesDangerOff(c *check.C) {
 	body := "" +
 		"----hello--\r\n" +
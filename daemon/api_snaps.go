@@ -27,8 +27,10 @@ import (
 	"mime"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/auth"
@@ -53,11 +55,12 @@ var (
 	}
 
 	snapsCmd = &Command{
-		Path:     "/v2/snaps",
-		UserOK:   true,
-		PolkitOK: "io.snapcraft.snapd.manage",
-		GET:      getSnapsInfo,
-		POST:     postSnaps,
+		Path:           "/v2/snaps",
+		UserOK:         true,
+		PolkitOK:       "io.snapcraft.snapd.manage",
+		SnapInterfaces: refreshControlAccess,
+		GET:            getSnapsInfo,
+		POST:           postSnaps,
 	}
 )
 
@@ -195,6 +198,11 @@ type snapInstruction struct {
 	Purge            bool     `json:"purge,omitempty"`
 	Snaps            []string `json:"snaps"`
 	Users            []string `json:"users"`
+	// Time is "forever" or the RFC3339 time until which to hold refreshes
+	Time string `json:"time,omitempty"`
+	// Transaction is the type of transaction of multi-snap install or
+	// refresh
+	Transaction client.TransactionType `json:"transaction,omitempty"`
 
 	// The fields below should not be unmarshalled into. Do not export them.
 	userID int
@@ -240,6 +248,18 @@ func (inst *snapInstruction) validate() error {
 			return fmt.Errorf("leave-cohort can only be specified for refresh or switch")
 		}
 	}
+	if inst.Time != "" && inst.Action != "hold" {
+		return fmt.Errorf("time can only be specified for hold")
+	}
+	switch inst.Transaction {
+	case "":
+	case client.TransactionPerSnap, client.TransactionAllSnaps:
+		if inst.Action != "install" && inst.Action != "refresh" {
+			return fmt.Errorf("transaction type can only be specified for install or refresh")
+		}
+	default:
+		return fmt.Errorf("invalid value for transaction type: %s", inst.Transaction)
+	}
 	if inst.Action == "install" {
 		for _, snapName := range inst.Snaps {
 			// FIXME: alternatively we could simply mutate *inst
@@ -459,6 +479,17 @@ func (inst *snapInstruction) errToResponse(err error) Response {
 	return errToResponse(err, inst.Snaps, BadRequest, "cannot %s %s: %v", inst.Action, strutil.Quoted(inst.Snaps))
 }
 
+// isSnapRequest returns whether the request was made by a snap over the
+// snapd-snap socket, as opposed to by a user or by a snap acting on behalf
+// of a logged in user.
+func isSnapRequest(r *http.Request, user *auth.UserState) bool {
+	if user != nil {
+		return false
+	}
+	_, _, socket, err := ucrednetGet(r.RemoteAddr)
+	return err == nil && socket == dirs.SnapSocket
+}
+
 func postSnaps(c *Command, r *http.Request, user *auth.UserState) Response {
 	contentType := r.Header.Get("Content-Type")
 
@@ -479,7 +510,11 @@ func postSnaps(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("unknown content type: %s", contentType)
 	}
 
-	return sideloadOrTrySnap(c, r.Body, params["boundary"], user)
+	if isSnapRequest(r, user) {
+		return Forbidden("snaps can only refresh other snaps")
+	}
+
+	return sideloadOrTrySnap(c, r.Body, params["boundary"], r.RemoteAddr, user)
 }
 
 func snapOpMany(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -501,6 +536,9 @@ func snapOpMany(c *Command, r *http.Request, user *auth.UserState) Response {
 	if err := inst.validate(); err != nil {
 		return BadRequest("%v", err)
 	}
+	if inst.Action != "refresh" && isSnapRequest(r, user) {
+		return Forbidden("snaps can only refresh other snaps")
+	}
 
 	st := c.d.overlord.State()
 	st.Lock()
@@ -546,6 +584,10 @@ func (inst *snapInstruction) dispatchForMany() (op snapManyActionFunc) {
 	case "snapshot":
 		// see api_snapshots.go
 		op = snapshotMany
+	case "hold":
+		op = snapHoldMany
+	case "unhold":
+		op = snapUnholdMany
 	}
 	return op
 }
@@ -556,7 +598,8 @@ func snapInstallMany(inst *snapInstruction, st *state.State) (*snapInstructionRe
 			return nil, fmt.Errorf(i18n.G("cannot install snap with empty name"))
 		}
 	}
-	installed, tasksets, err := snapstateInstallMany(st, inst.Snaps, inst.userID)
+	flags := &snapstate.Flags{Transaction: inst.Transaction}
+	installed, tasksets, err := snapstateInstallMany(st, inst.Snaps, inst.userID, flags)
 	if err != nil {
 		return nil, err
 	}
@@ -587,7 +630,8 @@ func snapUpdateMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 	}
 
 	// TODO: use a per-request context
-	updated, tasksets, err := snapstateUpdateMany(context.TODO(), st, inst.Snaps, inst.userID, nil)
+	flags := &snapstate.Flags{Transaction: inst.Transaction}
+	updated, tasksets, err := snapstateUpdateMany(context.TODO(), st, inst.Snaps, inst.userID, flags)
 	if err != nil {
 		return nil, err
 	}
@@ -641,6 +685,63 @@ func snapRemoveMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 	}, nil
 }
 
+func snapHoldMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	var until time.Time
+	switch inst.Time {
+	case "":
+		return nil, fmt.Errorf("cannot hold refreshes without a time")
+	case "forever":
+		// the zero time holds refreshes forever
+	default:
+		var err error
+		until, err = time.Parse(time.RFC3339, inst.Time)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse hold time: %v", err)
+		}
+	}
+	if err := snapstate.HoldRefresh(st, inst.Snaps, until); err != nil {
+		return nil, err
+	}
+
+	var msg string
+	switch len(inst.Snaps) {
+	case 0:
+		msg = i18n.G("Hold auto-refreshes of all snaps")
+	case 1:
+		msg = fmt.Sprintf(i18n.G("Hold auto-refreshes of snap %q"), inst.Snaps[0])
+	default:
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Hold auto-refreshes of snaps %s"), strutil.Quoted(inst.Snaps))
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: inst.Snaps,
+	}, nil
+}
+
+func snapUnholdMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
+	if err := snapstate.UnholdRefresh(st, inst.Snaps); err != nil {
+		return nil, err
+	}
+
+	var msg string
+	switch len(inst.Snaps) {
+	case 0:
+		msg = i18n.G("Remove the hold on auto-refreshes of all snaps")
+	case 1:
+		msg = fmt.Sprintf(i18n.G("Remove the hold on auto-refreshes of snap %q"), inst.Snaps[0])
+	default:
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		msg = fmt.Sprintf(i18n.G("Remove the holds on auto-refreshes of snaps %s"), strutil.Quoted(inst.Snaps))
+	}
+
+	return &snapInstructionResult{
+		Summary:  msg,
+		Affected: inst.Snaps,
+	}, nil
+}
+
 // query many snaps
 func getSnapsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 
@@ -437,6 +437,53 @@ func (s *snapsSuite) TestPostSnapsUnsupportedMultiOp(c *check.C) {
 	c.Check(rsp.Result.(*daemon.ErrorResult).Message, testutil.Contains, `unsupported multi-snap operation "switch"`)
 }
 
+func (s *snapsSuite) TestPostSnapsFromSnapOnlyRefresh(c *check.C) {
+	s.daemonWithOverlordMockAndStore(c)
+
+	remoteAddr := "pid=100;uid=1000;socket=" + dirs.SnapSocket + ";"
+	for _, action := range []string{"install", "remove", "snapshot", "hold"} {
+		buf := strings.NewReader(fmt.Sprintf(`{"action": "%s","snaps":["foo"]}`, action))
+		req, err := http.NewRequest("POST", "/v2/snaps", buf)
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, check.Equals, 403, check.Commentf("%s", action))
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, "snaps can only refresh other snaps")
+	}
+
+	// sideloading is not allowed either
+	req, err := http.NewRequest("POST", "/v2/snaps", strings.NewReader(""))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "multipart/thing; boundary=foo")
+	req.RemoteAddr = remoteAddr
+
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 403)
+	c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, "snaps can only refresh other snaps")
+}
+
+func (s *snapsSuite) TestPostSnapsFromSnapRefresh(c *check.C) {
+	defer daemon.MockAssertstateRefreshSnapDeclarations(func(*state.State, int) error { return nil })()
+	defer daemon.MockSnapstateUpdateMany(func(_ context.Context, s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		c.Check(names, check.DeepEquals, []string{"foo"})
+		t := s.NewTask("fake-refresh", "Refreshing foo")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	})()
+
+	s.daemonWithOverlordMockAndStore(c)
+
+	buf := strings.NewReader(`{"action": "refresh","snaps":["foo"]}`)
+	req, err := http.NewRequest("POST", "/v2/snaps", buf)
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "pid=100;uid=1000;socket=" + dirs.SnapSocket + ";"
+
+	rsp := s.asyncReq(c, req, nil)
+	c.Check(rsp.Change, check.Not(check.Equals), "")
+}
+
 func (s *snapsSuite) TestPostSnapsNoWeirdses(c *check.C) {
 	s.daemonWithOverlordMockAndStore(c)
 
@@ -621,8 +668,9 @@ func (s *snapsSuite) TestRefreshMany1(c *check.C) {
 }
 
 func (s *snapsSuite) TestInstallMany(c *check.C) {
-	defer daemon.MockSnapstateInstallMany(func(s *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+	defer daemon.MockSnapstateInstallMany(func(s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
 		c.Check(names, check.HasLen, 2)
+		c.Check(flags, check.DeepEquals, &snapstate.Flags{})
 		t := s.NewTask("fake-install-2", "Install two")
 		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
 	})()
@@ -638,8 +686,54 @@ func (s *snapsSuite) TestInstallMany(c *check.C) {
 	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
 }
 
+func (s *snapsSuite) TestPostSnapsOpTransaction(c *check.C) {
+	defer daemon.MockAssertstateRefreshSnapDeclarations(func(*state.State, int) error { return nil })()
+	var installFlags, updateFlags *snapstate.Flags
+	defer daemon.MockSnapstateInstallMany(func(s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		installFlags = flags
+		t := s.NewTask("fake-install-2", "Install two")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	})()
+	defer daemon.MockSnapstateUpdateMany(func(_ context.Context, s *state.State, names []string, userID int, flags *snapstate.Flags) ([]string, []*state.TaskSet, error) {
+		updateFlags = flags
+		t := s.NewTask("fake-refresh-2", "Refresh two")
+		return names, []*state.TaskSet{state.NewTaskSet(t)}, nil
+	})()
+
+	s.daemonWithOverlordMockAndStore(c)
+
+	for _, action := range []string{"install", "refresh"} {
+		buf := bytes.NewBufferString(fmt.Sprintf(`{"action": %q, "snaps": ["foo", "bar"], "transaction": "all-snaps"}`, action))
+		req, err := http.NewRequest("POST", "/v2/snaps", buf)
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		s.asyncReq(c, req, nil)
+	}
+	c.Check(installFlags, check.DeepEquals, &snapstate.Flags{Transaction: client.TransactionAllSnaps})
+	c.Check(updateFlags, check.DeepEquals, &snapstate.Flags{Transaction: client.TransactionAllSnaps})
+}
+
+func (s *snapsSuite) TestPostSnapsOpTransactionInvalid(c *check.C) {
+	s.daemonWithOverlordMockAndStore(c)
+
+	for _, t := range []struct {
+		body   string
+		errMsg string
+	}{
+		{`{"action": "install", "snaps": ["foo"], "transaction": "some-snaps"}`, `invalid value for transaction type: some-snaps`},
+		{`{"action": "remove", "snaps": ["foo"], "transaction": "all-snaps"}`, `transaction type can only be specified for install or refresh`},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps", bytes.NewBufferString(t.body))
+		c.Assert(err, check.IsNil)
+		req.Header.Set("Content-Type", "application/json")
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Result.(*daemon.ErrorResult).Message, check.Equals, t.errMsg)
+	}
+}
+
 func (s *snapsSuite) TestInstallManyEmptyName(c *check.C) {
-	defer daemon.MockSnapstateInstallMany(func(_ *state.State, _ []string, _ int) ([]string, []*state.TaskSet, error) {
+	defer daemon.MockSnapstateInstallMany(func(_ *state.State, _ []string, _ int, _ *snapstate.Flags) ([]string, []*state.TaskSet, error) {
 		return nil, nil, errors.New("should not be called")
 	})()
 	d := s.daemon(c)
@@ -670,6 +764,74 @@ func (s *snapsSuite) TestRemoveMany(c *check.C) {
 	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
 }
 
+func (s *snapsSuite) TestHoldMany(c *check.C) {
+	d := s.daemon(c)
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(10), true, "")
+	s.mkInstalledInState(c, d, "baz", "bar", "v1", snap.R(10), true, "")
+
+	inst := &daemon.SnapInstruction{Action: "hold", Snaps: []string{"foo", "baz"}, Time: "forever"}
+	c.Assert(inst.Validate(), check.IsNil)
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+	res, err := inst.DispatchForMany()(inst, st)
+	c.Assert(err, check.IsNil)
+	c.Check(res.Summary, check.Equals, `Hold auto-refreshes of snaps "foo", "baz"`)
+	c.Check(res.Affected, check.DeepEquals, inst.Snaps)
+	c.Check(res.Tasksets, check.HasLen, 0)
+
+	holds, err := snapstate.SnapRefreshHolds(st)
+	c.Assert(err, check.IsNil)
+	c.Assert(holds, check.HasLen, 2)
+	c.Check(holds["foo"].Forever(), check.Equals, true)
+
+	until := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	inst = &daemon.SnapInstruction{Action: "hold", Time: until.Format(time.RFC3339)}
+	res, err = inst.DispatchForMany()(inst, st)
+	c.Assert(err, check.IsNil)
+	c.Check(res.Summary, check.Equals, `Hold auto-refreshes of all snaps`)
+	hold, err := snapstate.SystemRefreshHold(st)
+	c.Assert(err, check.IsNil)
+	c.Assert(hold, check.NotNil)
+	c.Check(hold.Until.Equal(until), check.Equals, true)
+
+	inst = &daemon.SnapInstruction{Action: "unhold", Snaps: []string{"foo"}}
+	res, err = inst.DispatchForMany()(inst, st)
+	c.Assert(err, check.IsNil)
+	c.Check(res.Summary, check.Equals, `Remove the hold on auto-refreshes of snap "foo"`)
+	holds, err = snapstate.SnapRefreshHolds(st)
+	c.Assert(err, check.IsNil)
+	c.Check(holds, check.HasLen, 1)
+	c.Check(holds["baz"], check.NotNil)
+}
+
+func (s *snapsSuite) TestHoldManyErrors(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+	st.Lock()
+	defer st.Unlock()
+
+	for _, t := range []struct {
+		time string
+		err  string
+	}{
+		{"", "cannot hold refreshes without a time"},
+		{"soon", `cannot parse hold time: .*`},
+		{"2000-01-01T00:00:00Z", `cannot hold refreshes until a time in the past: .*`},
+	} {
+		inst := &daemon.SnapInstruction{Action: "hold", Time: t.time}
+		_, err := inst.DispatchForMany()(inst, st)
+		c.Check(err, check.ErrorMatches, t.err)
+	}
+
+	inst := &daemon.SnapInstruction{Action: "hold", Snaps: []string{"foo"}, Time: "forever"}
+	_, err := inst.DispatchForMany()(inst, st)
+	c.Check(err, check.ErrorMatches, `snap "foo" is not installed`)
+
+	inst = &daemon.SnapInstruction{Action: "refresh", Time: "forever"}
+	c.Check(inst.Validate(), check.ErrorMatches, "time can only be specified for hold")
+}
+
 func (s *snapsSuite) TestSnapInfoOneIntegration(c *check.C) {
 	d := s.daemon(c)
 
@@ -828,6 +990,7 @@ UnitFileState=enabled
 			Icon:        "/v2/icons/foo/icon",
 			Type:        string(snap.TypeApp),
 			Base:        "base18",
+			Epoch:       snap.E("0"),
 			Private:     false,
 			DevMode:     false,
 			JailMode:    false,
@@ -23,11 +23,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"golang.org/x/crypto/sha3"
+
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/auth"
@@ -38,16 +41,18 @@ import (
 
 var snapshotCmd = &Command{
 	// TODO: also support /v2/snapshots/<id>
-	Path:     "/v2/snapshots",
-	UserOK:   true,
-	PolkitOK: "io.snapcraft.snapd.manage",
-	GET:      listSnapshots,
-	POST:     changeSnapshots,
+	Path:           "/v2/snapshots",
+	UserOK:         true,
+	PolkitOK:       "io.snapcraft.snapd.manage",
+	SnapInterfaces: snapshotsControlAccess,
+	GET:            listSnapshots,
+	POST:           changeSnapshots,
 }
 
 var snapshotExportCmd = &Command{
-	Path: "/v2/snapshots/{id}/export",
-	GET:  getSnapshotExport,
+	Path:           "/v2/snapshots/{id}/export",
+	SnapInterfaces: snapshotsControlAccess,
+	GET:            getSnapshotExport,
 }
 
 var (
@@ -204,6 +209,14 @@ func doSnapshotImport(c *Command, r *http.Request, user *auth.UserState) Respons
 	}
 	// ensure we don't read more than we expect
 	limitedBodyReader := io.LimitReader(r.Body, expectedSize)
+	// older clients do not send the digest of the import
+	if digest := r.Header.Get("Snapshot-Sha3-384"); digest != "" {
+		limitedBodyReader = &digestCheckingReader{
+			Reader:   limitedBodyReader,
+			h:        sha3.New384(),
+			expected: digest,
+		}
+	}
 
 	// XXX: check that we have enough space to import the compressed snapshots
 	st := c.d.overlord.State()
@@ -216,6 +229,25 @@ func doSnapshotImport(c *Command, r *http.Request, user *auth.UserState) Respons
 	return SyncResponse(result, nil)
 }
 
+// digestCheckingReader checks that the SHA3-384 digest of the data read
+// matches the expected one once the end of the stream is reached.
+type digestCheckingReader struct {
+	io.Reader
+	h        hash.Hash
+	expected string
+}
+
+func (r *digestCheckingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if actual := fmt.Sprintf("%x", r.h.Sum(nil)); actual != r.expected {
+			return n, fmt.Errorf("snapshot import digest mismatch: expected %s, got %s", r.expected, actual)
+		}
+	}
+	return n, err
+}
+
 func snapshotMany(inst *snapInstruction, st *state.State) (*snapInstructionResult, error) {
 	setID, snapshotted, ts, err := snapshotSave(st, inst.Snaps, inst.Users)
 	if err != nil {
@@ -27,9 +27,11 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"strings"
 
+	"golang.org/x/crypto/sha3"
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
@@ -315,6 +317,26 @@ func (s *snapshotSuite) TestExportSnapshots(c *check.C) {
 	c.Check(snapshotExportCalled, check.Equals, 1)
 }
 
+func (s *snapshotSuite) TestExportSnapshotsHeaders(c *check.C) {
+	defer daemon.MockSnapshotExport(func(ctx context.Context, st *state.State, setID uint64) (*snapshotstate.SnapshotExport, error) {
+		return &snapshotstate.SnapshotExport{}, nil
+	})()
+
+	req, err := http.NewRequest("GET", "/v2/snapshots/1/export", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := s.req(c, req, nil)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Assert(rec.Code, check.Equals, 200)
+
+	h := sha3.New384()
+	h.Write(rec.Body.Bytes())
+	c.Check(rec.Header().Get("Content-Type"), check.Equals, client.SnapshotExportMediaType)
+	c.Check(rec.Header().Get("Content-Length"), check.Equals, strconv.Itoa(rec.Body.Len()))
+	c.Check(rec.Header().Get("Snapshot-Sha3-384"), check.Equals, fmt.Sprintf("%x", h.Sum(nil)))
+}
+
 func (s *snapshotSuite) TestExportSnapshotsBadRequestOnNonNumericID(c *check.C) {
 	req, err := http.NewRequest("GET", "/v2/snapshots/xxx/export", nil)
 	c.Assert(err, check.IsNil)
@@ -360,6 +382,43 @@ func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
 	c.Check(rsp.Result, check.DeepEquals, map[string]interface{}{"set-id": setID, "snaps": snapNames})
 }
 
+func (s *snapshotSuite) TestImportSnapshotDigest(c *check.C) {
+	data := []byte("mocked snapshot export data file")
+	h := sha3.New384()
+	h.Write(data)
+	digest := fmt.Sprintf("%x", h.Sum(nil))
+
+	defer daemon.MockSnapshotImport(func(ctx context.Context, st *state.State, r io.Reader) (uint64, []string, error) {
+		if _, err := ioutil.ReadAll(r); err != nil {
+			return 0, nil, err
+		}
+		return uint64(3), []string{"foo"}, nil
+	})()
+
+	for _, t := range []struct {
+		digest string
+		err    string
+	}{
+		{digest, ""},
+		{"bad-digest", fmt.Sprintf("snapshot import digest mismatch: expected bad-digest, got %s", digest)},
+	} {
+		req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewReader(data))
+		c.Assert(err, check.IsNil)
+		req.Header.Add("Content-Length", strconv.Itoa(len(data)))
+		req.Header.Set("Content-Type", client.SnapshotExportMediaType)
+		req.Header.Set("Snapshot-Sha3-384", t.digest)
+
+		if t.err == "" {
+			rsp := s.syncReq(c, req, nil)
+			c.Check(rsp.Result, check.DeepEquals, map[string]interface{}{"set-id": uint64(3), "snaps": []string{"foo"}})
+		} else {
+			rsp := s.errorReq(c, req, nil)
+			c.Check(rsp.Status, check.Equals, 400)
+			c.Check(rsp.ErrorResult().Message, check.Equals, t.err)
+		}
+	}
+}
+
 func (s *snapshotSuite) TestImportSnapshotError(c *check.C) {
 	defer daemon.MockSnapshotImport(func(context.Context, *state.State, io.Reader) (uint64, []string, error) {
 		return uint64(0), nil, errors.New("no")
@@ -20,21 +20,46 @@
 package daemon
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"os"
 	"path/filepath"
+	"sync"
 
+	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/secboot"
 )
 
 var systemRecoveryKeysCmd = &Command{
-	Path:     "/v2/system-recovery-keys",
-	GET:      getSystemRecoveryKeys,
-	RootOnly: true,
+	Path:           "/v2/system-recovery-keys",
+	GET:            getSystemRecoveryKeys,
+	POST:           postSystemRecoveryKeys,
+	RootOnly:       true,
+	PolkitOK:       "io.snapcraft.snapd.recovery-keys",
+	SnapInterfaces: recoveryKeysAccess,
 }
 
+// recoveryKeysAccess grants snaps with a connected system-recovery-keys plug
+// access to reading, but not regenerating, the recovery keys on the
+// snapd-snap socket.
+var recoveryKeysAccess = []interfaceAccess{{
+	Interface: "system-recovery-keys",
+	ReadOnly:  true,
+}}
+
+// wrapped for unit tests
+var secbootChangeRecoveryKey = secboot.ChangeRecoveryKey
+
+// recoveryKeysMu serializes concurrent requests to regenerate the recovery
+// keys, the state lock is not held while the keys of the devices are changed
+var recoveryKeysMu sync.Mutex
+
 func getSystemRecoveryKeys(c *Command, r *http.Request, user *auth.UserState) Response {
 	var rsp client.SystemRecoveryKeysResponse
 
@@ -52,3 +77,95 @@ func getSystemRecoveryKeys(c *Command, r *http.Request, user *auth.UserState) Re
 
 	return SyncResponse(&rsp, nil)
 }
+
+type recoveryKeysActionRequest struct {
+	Action string `json:"action"`
+}
+
+func postSystemRecoveryKeys(c *Command, r *http.Request, user *auth.UserState) Response {
+	var req recoveryKeysActionRequest
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&req); err != nil {
+		return BadRequest("cannot decode request body into recovery keys action: %v", err)
+	}
+	if decoder.More() {
+		return BadRequest("extra content found in request body")
+	}
+
+	switch req.Action {
+	case "regenerate":
+		// handled below
+	default:
+		return BadRequest("unsupported recovery keys action %q", req.Action)
+	}
+
+	recoveryKeysMu.Lock()
+	defer recoveryKeysMu.Unlock()
+
+	keys, err := regenerateRecoveryKeys()
+	if err != nil {
+		return InternalError("cannot regenerate recovery keys: %v", err)
+	}
+	return SyncResponse(keys, nil)
+}
+
+// regenerateRecoveryKeys replaces the recovery key of ubuntu-data and the
+// reinstall key of ubuntu-save, if any, with newly generated ones.
+func regenerateRecoveryKeys() (*client.SystemRecoveryKeysResponse, error) {
+	disk, err := disks.DiskFromMountPoint(boot.InitramfsUbuntuBootDir, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find the boot disk: %v", err)
+	}
+
+	var rsp client.SystemRecoveryKeysResponse
+	for _, k := range []struct {
+		partition string
+		keyFile   string
+		key       *string
+	}{
+		{"ubuntu-data", filepath.Join(dirs.SnapFDEDir, "recovery.key"), &rsp.RecoveryKey},
+		{"ubuntu-save", filepath.Join(dirs.SnapFDEDir, "reinstall.key"), &rsp.ReinstallKey},
+	} {
+		if k.partition == "ubuntu-save" && !osutil.FileExists(k.keyFile) {
+			// installed before ubuntu-save was introduced
+			continue
+		}
+		partUUID, err := disk.FindMatchingPartitionUUIDWithFsLabel(secboot.EncryptedPartitionName(k.partition))
+		if err != nil {
+			return nil, err
+		}
+		node := filepath.Join("/dev/disk/by-partuuid", partUUID)
+		newKey, err := regenerateRecoveryKey(node, k.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		*k.key = newKey.String()
+	}
+	return &rsp, nil
+}
+
+// regenerateRecoveryKey replaces the recovery key stored in keyFile of the
+// encrypted device node with a new one. The new key is stored next to the
+// existing one before changing the device, so that it is never lost.
+func regenerateRecoveryKey(node, keyFile string) (*secboot.RecoveryKey, error) {
+	oldKey, err := secboot.RecoveryKeyFromFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	newKey, err := secboot.NewRecoveryKey()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create recovery key: %v", err)
+	}
+	newKeyFile := keyFile + ".new"
+	if err := newKey.Save(newKeyFile); err != nil {
+		return nil, fmt.Errorf("cannot store recovery key: %v", err)
+	}
+	if err := secbootChangeRecoveryKey(node, *oldKey, newKey); err != nil {
+		os.Remove(newKeyFile)
+		return nil, err
+	}
+	if err := os.Rename(newKeyFile, keyFile); err != nil {
+		return nil, err
+	}
+	return &newKey, nil
+}
@@ -21,17 +21,24 @@ package daemon_test
 
 import (
 	"encoding/hex"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil/disks"
+	"github.com/snapcore/snapd/polkit"
 	"github.com/snapcore/snapd/secboot"
+	"github.com/snapcore/snapd/testutil"
 )
 
 var _ = Suite(&recoveryKeysSuite{})
@@ -84,8 +91,167 @@ func (s *recoveryKeysSuite) TestSystemGetRecoveryAsUserErrors(c *C) {
 	req, err := http.NewRequest("GET", "/v2/system-recovery-keys", nil)
 	c.Assert(err, IsNil)
 
+	var polkitActionID string
+	restore := daemon.MockPolkitCheckAuthorization(func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error) {
+		polkitActionID = actionId
+		return false, nil
+	})
+	defer restore()
+
 	req.RemoteAddr = "pid=100;uid=1000;socket=;"
 	rec := httptest.NewRecorder()
 	s.serveHTTP(c, rec, req)
 	c.Assert(rec.Code, Equals, 401)
+	c.Check(polkitActionID, Equals, "io.snapcraft.snapd.recovery-keys")
+}
+
+func (s *recoveryKeysSuite) TestSystemGetRecoveryKeysAsUserPolkitAuthorized(c *C) {
+	s.daemon(c)
+	mockSystemRecoveryKeys(c)
+
+	restore := daemon.MockPolkitCheckAuthorization(func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error) {
+		c.Check(actionId, Equals, "io.snapcraft.snapd.recovery-keys")
+		c.Check(uid, Equals, uint32(1000))
+		return true, nil
+	})
+	defer restore()
+
+	req, err := http.NewRequest("GET", "/v2/system-recovery-keys", nil)
+	c.Assert(err, IsNil)
+
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Assert(rec.Code, Equals, 200)
+}
+
+func (s *recoveryKeysSuite) mockBootDisk(c *C) {
+	restore := disks.MockMountPointDisksToPartitionMapping(map[disks.Mountpoint]*disks.MockDiskMapping{
+		{Mountpoint: boot.InitramfsUbuntuBootDir}: {
+			FilesystemLabelToPartUUID: map[string]string{
+				"ubuntu-boot":     "boot-partuuid",
+				"ubuntu-data-enc": "data-partuuid",
+				"ubuntu-save-enc": "save-partuuid",
+			},
+			DiskHasPartitions: true,
+			DevNum:            "disk",
+		},
+	})
+	s.AddCleanup(restore)
+}
+
+func (s *recoveryKeysSuite) TestSystemRegenerateRecoveryKeys(c *C) {
+	s.daemon(c)
+	mockSystemRecoveryKeys(c)
+	s.mockBootDisk(c)
+
+	oldDataKey, err := secboot.RecoveryKeyFromFile(filepath.Join(dirs.SnapFDEDir, "recovery.key"))
+	c.Assert(err, IsNil)
+	oldSaveKey, err := secboot.RecoveryKeyFromFile(filepath.Join(dirs.SnapFDEDir, "reinstall.key"))
+	c.Assert(err, IsNil)
+
+	var nodes []string
+	newKeys := map[string]secboot.RecoveryKey{}
+	defer daemon.MockSecbootChangeRecoveryKey(func(node string, oldKey, newKey secboot.RecoveryKey) error {
+		nodes = append(nodes, node)
+		switch node {
+		case "/dev/disk/by-partuuid/data-partuuid":
+			c.Check(oldKey, DeepEquals, *oldDataKey)
+		case "/dev/disk/by-partuuid/save-partuuid":
+			c.Check(oldKey, DeepEquals, *oldSaveKey)
+		}
+		c.Check(newKey, Not(DeepEquals), oldKey)
+		newKeys[node] = newKey
+		return nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/system-recovery-keys", strings.NewReader(`{"action":"regenerate"}`))
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+
+	c.Check(nodes, DeepEquals, []string{
+		"/dev/disk/by-partuuid/data-partuuid",
+		"/dev/disk/by-partuuid/save-partuuid",
+	})
+	dataKey := newKeys["/dev/disk/by-partuuid/data-partuuid"]
+	saveKey := newKeys["/dev/disk/by-partuuid/save-partuuid"]
+	c.Check(filepath.Join(dirs.SnapFDEDir, "recovery.key"), testutil.FileEquals, dataKey[:])
+	c.Check(filepath.Join(dirs.SnapFDEDir, "reinstall.key"), testutil.FileEquals, saveKey[:])
+	c.Check(filepath.Join(dirs.SnapFDEDir, "recovery.key.new"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapFDEDir, "reinstall.key.new"), testutil.FileAbsent)
+
+	c.Check(rsp.Result, DeepEquals, &client.SystemRecoveryKeysResponse{
+		RecoveryKey:  dataKey.String(),
+		ReinstallKey: saveKey.String(),
+	})
+}
+
+func (s *recoveryKeysSuite) TestSystemRegenerateRecoveryKeysNoSave(c *C) {
+	s.daemon(c)
+	mockSystemRecoveryKeys(c)
+	s.mockBootDisk(c)
+	c.Assert(os.Remove(filepath.Join(dirs.SnapFDEDir, "reinstall.key")), IsNil)
+
+	var nodes []string
+	defer daemon.MockSecbootChangeRecoveryKey(func(node string, oldKey, newKey secboot.RecoveryKey) error {
+		nodes = append(nodes, node)
+		return nil
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/system-recovery-keys", strings.NewReader(`{"action":"regenerate"}`))
+	c.Assert(err, IsNil)
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, Equals, 200)
+
+	c.Check(nodes, DeepEquals, []string{"/dev/disk/by-partuuid/data-partuuid"})
+	c.Check(rsp.Result.(*client.SystemRecoveryKeysResponse).ReinstallKey, Equals, "")
+}
+
+func (s *recoveryKeysSuite) TestSystemRegenerateRecoveryKeysError(c *C) {
+	s.daemon(c)
+	mockSystemRecoveryKeys(c)
+	s.mockBootDisk(c)
+
+	oldDataKey, err := ioutil.ReadFile(filepath.Join(dirs.SnapFDEDir, "recovery.key"))
+	c.Assert(err, IsNil)
+
+	defer daemon.MockSecbootChangeRecoveryKey(func(node string, oldKey, newKey secboot.RecoveryKey) error {
+		return fmt.Errorf("cannot change the recovery key of %s: boom", node)
+	})()
+
+	req, err := http.NewRequest("POST", "/v2/system-recovery-keys", strings.NewReader(`{"action":"regenerate"}`))
+	c.Assert(err, IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, Equals, 500)
+	c.Check(rsp.ErrorResult().Message, Equals, "cannot regenerate recovery keys: cannot change the recovery key of /dev/disk/by-partuuid/data-partuuid: boom")
+
+	// the existing key is kept
+	c.Check(filepath.Join(dirs.SnapFDEDir, "recovery.key"), testutil.FileEquals, oldDataKey)
+	c.Check(filepath.Join(dirs.SnapFDEDir, "recovery.key.new"), testutil.FileAbsent)
+}
+
+func (s *recoveryKeysSuite) TestSystemRecoveryKeysBadAction(c *C) {
+	s.daemon(c)
+
+	defer daemon.MockSecbootChangeRecoveryKey(func(node string, oldKey, newKey secboot.RecoveryKey) error {
+		c.Fatalf("unexpected call")
+		return nil
+	})()
+
+	for _, tc := range []struct {
+		body string
+		err  string
+	}{
+		{`{"action":"foo"}`, `unsupported recovery keys action "foo"`},
+		{`{"action":"regenerate"}{}`, "extra content found in request body"},
+		{`{`, "cannot decode request body into recovery keys action: unexpected EOF"},
+	} {
+		req, err := http.NewRequest("POST", "/v2/system-recovery-keys", strings.NewReader(tc.body))
+		c.Assert(err, IsNil)
+
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, Equals, 400, Commentf("%s", tc.body))
+		c.Check(rsp.ErrorResult().Message, Equals, tc.err, Commentf("%s", tc.body))
+	}
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -40,12 +41,14 @@ var systemsCmd = &Command{
 	// forward to that one
 	POST:     postSystemsAction,
 	RootOnly: true,
+	PolkitOK: "io.snapcraft.snapd.manage-system",
 }
 
 var systemsActionCmd = &Command{
 	Path:     "/v2/systems/{label}",
 	POST:     postSystemsAction,
 	RootOnly: true,
+	PolkitOK: "io.snapcraft.snapd.manage-system",
 }
 
 type systemsResponse struct {
@@ -101,6 +104,10 @@ func getSystems(c *Command, r *http.Request, user *auth.UserState) Response {
 type systemActionRequest struct {
 	Action string `json:"action"`
 	client.SystemAction
+
+	// Label and TestSystem are used when creating a recovery system
+	Label      string `json:"label,omitempty"`
+	TestSystem bool   `json:"test-system,omitempty"`
 }
 
 func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -114,11 +121,16 @@ func postSystemsAction(c *Command, r *http.Request, user *auth.UserState) Respon
 	if decoder.More() {
 		return BadRequest("extra content found in request body")
 	}
+	if req.Mode == "factory-reset" && (req.Action == "do" || req.Action == "reboot") {
+		return postSystemActionFactoryReset(c, systemLabel)
+	}
 	switch req.Action {
 	case "do":
 		return postSystemActionDo(c, systemLabel, &req)
 	case "reboot":
 		return postSystemActionReboot(c, systemLabel, &req)
+	case "create":
+		return postSystemActionCreate(c, systemLabel, &req)
 	default:
 		return BadRequest("unsupported action %q", req.Action)
 	}
@@ -167,3 +179,86 @@ func postSystemActionDo(c *Command, systemLabel string, req *systemActionRequest
 	}
 	return SyncResponse(nil, nil)
 }
+
+// wrapped for unit tests
+var devicestateFactoryReset = devicestate.FactoryReset
+
+// postSystemActionFactoryReset starts a factory reset of the device, which
+// is only possible using the system the device was installed from.
+func postSystemActionFactoryReset(c *Command, systemLabel string) Response {
+	if systemLabel != "" {
+		systems, err := c.d.overlord.DeviceManager().Systems()
+		if err != nil && err != devicestate.ErrNoSystems {
+			return InternalError(err.Error())
+		}
+		found := false
+		for _, sys := range systems {
+			if sys.Label != systemLabel {
+				continue
+			}
+			if !sys.Current {
+				return BadRequest("cannot factory reset using system %q, it is not the current system", systemLabel)
+			}
+			found = true
+			break
+		}
+		if !found {
+			return NotFound("requested seed system %q does not exist", systemLabel)
+		}
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg, err := devicestateFactoryReset(st)
+	if err != nil {
+		if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+			return SnapChangeConflict(cce)
+		}
+		switch err {
+		case devicestate.ErrFactoryResetNotSeeded, devicestate.ErrFactoryResetUnsupported:
+			return BadRequest(err.Error())
+		}
+		return InternalError("cannot factory reset: %v", err)
+	}
+
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
+
+// wrapped for unit tests
+var devicestateCreateRecoverySystem = devicestate.CreateRecoverySystem
+
+// postSystemActionCreate starts creating a new recovery system from the
+// currently installed snaps.
+func postSystemActionCreate(c *Command, systemLabel string, req *systemActionRequest) Response {
+	if systemLabel != "" {
+		return BadRequest("cannot create a recovery system using an existing system")
+	}
+	if req.Label == "" {
+		return BadRequest("cannot create a recovery system without a label")
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	chg, err := devicestateCreateRecoverySystem(st, req.Label, devicestate.CreateRecoverySystemOptions{
+		TestSystem: req.TestSystem,
+	})
+	if err != nil {
+		if cce, ok := err.(*snapstate.ChangeConflictError); ok {
+			return SnapChangeConflict(cce)
+		}
+		if _, ok := err.(*devicestate.InvalidRecoverySystemError); ok {
+			return BadRequest(err.Error())
+		}
+		return InternalError("cannot create recovery system %q: %v", req.Label, err)
+	}
+
+	ensureStateSoon(st)
+
+	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+}
@@ -42,7 +42,9 @@ import (
 	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/polkit"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/snap"
@@ -183,6 +185,7 @@ func (s *systemsSuite) TestSystemsGetSome(c *check.C) {
 				Actions: []client.SystemAction{
 					{Title: "Reinstall", Mode: "install"},
 					{Title: "Recover", Mode: "recover"},
+					{Title: "Factory reset", Mode: "factory-reset"},
 					{Title: "Run normally", Mode: "run"},
 				},
 			},
@@ -502,6 +505,11 @@ func (s *systemsSuite) TestSystemActionRequestWithSeeded(c *check.C) {
 			}
 		}
 
+		if maint, ok := rspBody["maintenance"].(map[string]interface{}); ok {
+			// the earliest time of the reboot is not predictable
+			c.Check(maint["value"].(map[string]interface{})["restart-at"], check.NotNil, check.Commentf(tc.comment))
+			delete(maint, "value")
+		}
 		c.Assert(rspBody, check.DeepEquals, expResp, check.Commentf(tc.comment))
 
 		cmd.ForgetCalls()
@@ -552,6 +560,12 @@ func (s *systemsSuite) TestSystemActionNonRoot(c *check.C) {
 	c.Assert(err, check.IsNil)
 	d.Overlord().AddManager(mgr)
 
+	restore := daemon.MockPolkitCheckAuthorization(func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error) {
+		c.Check(actionId, check.Equals, "io.snapcraft.snapd.manage-system")
+		return false, nil
+	})
+	defer restore()
+
 	body := `{"action":"do","title":"reinstall","mode":"install"}`
 
 	// pretend to be a simple user
@@ -586,6 +600,10 @@ func (s *systemsSuite) TestSystemRebootNeedsRoot(c *check.C) {
 		return nil
 	})
 	defer restore()
+	restore = daemon.MockPolkitCheckAuthorization(func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error) {
+		return false, nil
+	})
+	defer restore()
 
 	body := `{"action":"reboot"}`
 	url := "/v2/systems"
@@ -598,6 +616,34 @@ func (s *systemsSuite) TestSystemRebootNeedsRoot(c *check.C) {
 	c.Check(rec.Code, check.Equals, 401)
 }
 
+func (s *systemsSuite) TestSystemRebootPolkitAuthorized(c *check.C) {
+	s.daemon(c)
+
+	called := 0
+	restore := daemon.MockDeviceManagerReboot(func(dm *devicestate.DeviceManager, systemLabel, mode string) error {
+		called++
+		c.Check(mode, check.Equals, "recover")
+		return nil
+	})
+	defer restore()
+	restore = daemon.MockPolkitCheckAuthorization(func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error) {
+		c.Check(actionId, check.Equals, "io.snapcraft.snapd.manage-system")
+		c.Check(uid, check.Equals, uint32(1000))
+		return true, nil
+	})
+	defer restore()
+
+	body := `{"action":"reboot","mode":"recover"}`
+	req, err := http.NewRequest("POST", "/v2/systems/20200101", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=1000;socket=;"
+
+	rec := httptest.NewRecorder()
+	s.serveHTTP(c, rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(called, check.Equals, 1)
+}
+
 func (s *systemsSuite) TestSystemRebootHappy(c *check.C) {
 	s.daemon(c)
 
@@ -675,3 +721,153 @@ func (s *systemsSuite) TestSystemRebootUnhappy(c *check.C) {
 		c.Check(result["message"], check.Equals, tc.expectedErr)
 	}
 }
+
+func (s *systemsSuite) TestSystemActionFactoryReset(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+
+	soon := 0
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {
+		soon++
+	})
+	defer restore()
+
+	for _, action := range []string{"do", "reboot"} {
+		called := 0
+		restore := daemon.MockDevicestateFactoryReset(func(st *state.State) (*state.Change, error) {
+			called++
+			return st.NewChange("factory-reset", "..."), nil
+		})
+		defer restore()
+
+		body := fmt.Sprintf(`{"action":"%s","mode":"factory-reset"}`, action)
+		req, err := http.NewRequest("POST", "/v2/systems", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		rsp := s.asyncReq(c, req, nil)
+		c.Check(rsp.Status, check.Equals, 202)
+		c.Check(called, check.Equals, 1)
+
+		st.Lock()
+		chg := st.Change(rsp.Change)
+		c.Assert(chg, check.NotNil)
+		c.Check(chg.Kind(), check.Equals, "factory-reset")
+		st.Unlock()
+	}
+	c.Check(soon, check.Equals, 2)
+}
+
+func (s *systemsSuite) TestSystemActionFactoryResetUnhappy(c *check.C) {
+	s.daemon(c)
+
+	for _, tc := range []struct {
+		resetErr         error
+		expectedHttpCode int
+		expectedErr      string
+	}{
+		{devicestate.ErrFactoryResetNotSeeded, 400, "cannot factory reset until fully seeded"},
+		{devicestate.ErrFactoryResetUnsupported, 400, "cannot factory reset a device without recovery systems"},
+		{fmt.Errorf("boom"), 500, "cannot factory reset: boom"},
+		{&snapstate.ChangeConflictError{Message: "factory reset already in progress"}, 409, "factory reset already in progress"},
+	} {
+		restore := daemon.MockDevicestateFactoryReset(func(st *state.State) (*state.Change, error) {
+			return nil, tc.resetErr
+		})
+		defer restore()
+
+		body := `{"action":"do","mode":"factory-reset"}`
+		req, err := http.NewRequest("POST", "/v2/systems", strings.NewReader(body))
+		c.Assert(err, check.IsNil)
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, check.Equals, tc.expectedHttpCode)
+		c.Check(rsp.ErrorResult().Message, check.Equals, tc.expectedErr)
+	}
+}
+
+func (s *systemsSuite) TestSystemActionFactoryResetNoSuchSystem(c *check.C) {
+	d := s.daemonWithOverlordMockAndStore(c)
+	hookMgr, err := hookstate.Manager(d.Overlord().State(), d.Overlord().TaskRunner())
+	c.Assert(err, check.IsNil)
+	mgr, err := devicestate.Manager(d.Overlord().State(), hookMgr, d.Overlord().TaskRunner(), nil)
+	c.Assert(err, check.IsNil)
+	d.Overlord().AddManager(mgr)
+
+	restore := daemon.MockDevicestateFactoryReset(func(st *state.State) (*state.Change, error) {
+		c.Fatalf("factory reset should not get called")
+		return nil, nil
+	})
+	defer restore()
+
+	body := `{"action":"do","mode":"factory-reset"}`
+	req, err := http.NewRequest("POST", "/v2/systems/20191119", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.errorReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.ErrorResult().Message, check.Equals, `requested seed system "20191119" does not exist`)
+}
+
+func (s *systemsSuite) TestSystemActionCreate(c *check.C) {
+	d := s.daemon(c)
+	st := d.Overlord().State()
+
+	soon := 0
+	_, restore := daemon.MockEnsureStateSoon(func(st *state.State) {
+		soon++
+	})
+	defer restore()
+
+	var gotLabel string
+	var gotOpts devicestate.CreateRecoverySystemOptions
+	restore = daemon.MockDevicestateCreateRecoverySystem(func(st *state.State, label string, opts devicestate.CreateRecoverySystemOptions) (*state.Change, error) {
+		gotLabel = label
+		gotOpts = opts
+		return st.NewChange("create-recovery-system", "..."), nil
+	})
+	defer restore()
+
+	body := `{"action":"create","label":"1234","test-system":true}`
+	req, err := http.NewRequest("POST", "/v2/systems", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+	rsp := s.asyncReq(c, req, nil)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(gotLabel, check.Equals, "1234")
+	c.Check(gotOpts, check.DeepEquals, devicestate.CreateRecoverySystemOptions{TestSystem: true})
+	c.Check(soon, check.Equals, 1)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "create-recovery-system")
+}
+
+func (s *systemsSuite) TestSystemActionCreateUnhappy(c *check.C) {
+	s.daemon(c)
+
+	for _, tc := range []struct {
+		path             string
+		body             string
+		createErr        error
+		expectedHttpCode int
+		expectedErr      string
+	}{
+		{"/v2/systems/20191119", `{"action":"create","label":"1234"}`, nil, 400, "cannot create a recovery system using an existing system"},
+		{"/v2/systems", `{"action":"create"}`, nil, 400, "cannot create a recovery system without a label"},
+		{"/v2/systems", `{"action":"create","label":"1234"}`, &devicestate.InvalidRecoverySystemError{Message: `recovery system "1234" already exists`}, 400, `recovery system "1234" already exists`},
+		{"/v2/systems", `{"action":"create","label":"1234"}`, &snapstate.ChangeConflictError{Message: "creating recovery system in progress"}, 409, "creating recovery system in progress"},
+		{"/v2/systems", `{"action":"create","label":"1234"}`, fmt.Errorf("boom"), 500, `cannot create recovery system "1234": boom`},
+	} {
+		restore := daemon.MockDevicestateCreateRecoverySystem(func(*state.State, string, devicestate.CreateRecoverySystemOptions) (*state.Change, error) {
+			if tc.createErr == nil {
+				c.Fatalf("unexpected call")
+			}
+			return nil, tc.createErr
+		})
+		defer restore()
+
+		req, err := http.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+		c.Assert(err, check.IsNil)
+		rsp := s.errorReq(c, req, nil)
+		c.Check(rsp.Status, check.Equals, tc.expectedHttpCode, check.Commentf("%v", tc))
+		c.Check(rsp.ErrorResult().Message, check.Equals, tc.expectedErr)
+	}
+}
@@ -225,7 +225,7 @@ func installThemes(c *Command, r *http.Request, user *auth.UserState) Response {
 	if user != nil {
 		userID = user.ID
 	}
-	installed, tasksets, err := snapstateInstallMany(st, toInstall, userID)
+	installed, tasksets, err := snapstateInstallMany(st, toInstall, userID, nil)
 	if err != nil {
 		return InternalError("cannot install themes: %s", err)
 	}
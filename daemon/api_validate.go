@@ -56,7 +56,56 @@ type validationSetResult struct {
 	Mode      string `json:"mode,omitempty"`
 	Sequence  int    `json:"sequence,omitempty"`
 	Valid     bool   `json:"valid"`
-	// TODO: attributes for Notes column
+	// Violations reports the snaps that do not satisfy the validation set.
+	Violations *validationSetViolations `json:"violations,omitempty"`
+}
+
+// validationSetViolations describes the installed snaps that violate the
+// validation sets, or the snaps missing to satisfy them.
+type validationSetViolations struct {
+	MissingSnaps []string `json:"missing-snaps,omitempty"`
+	InvalidSnaps []string `json:"invalid-snaps,omitempty"`
+	// WrongRevisionSnaps maps snap names to the required revision.
+	WrongRevisionSnaps map[string]string `json:"wrong-revision-snaps,omitempty"`
+}
+
+func newValidationSetViolations(verr *snapasserts.ValidationSetsValidationError) *validationSetViolations {
+	snapNames := func(snaps map[string][]string) []string {
+		if len(snaps) == 0 {
+			return nil
+		}
+		names := make([]string, 0, len(snaps))
+		for name := range snaps {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	violations := &validationSetViolations{
+		MissingSnaps: snapNames(verr.MissingSnaps),
+		InvalidSnaps: snapNames(verr.InvalidSnaps),
+	}
+	for name, revs := range verr.WrongRevisionSnaps {
+		if violations.WrongRevisionSnaps == nil {
+			violations.WrongRevisionSnaps = make(map[string]string, len(verr.WrongRevisionSnaps))
+		}
+		// conflicting revisions are rejected when the validation sets
+		// are combined, there is a single required revision
+		for rev := range revs {
+			violations.WrongRevisionSnaps[name] = rev.String()
+		}
+	}
+	return violations
+}
+
+// violationsFromError returns the violations reported by the given error
+// from checking the installed snaps, if any.
+func violationsFromError(err error) *validationSetViolations {
+	if verr, ok := err.(*snapasserts.ValidationSetsValidationError); ok {
+		return newValidationSetViolations(verr)
+	}
+	return nil
 }
 
 func modeString(mode assertstate.ValidationSetMode) (string, error) {
@@ -127,12 +176,13 @@ func listValidationSets(c *Command, r *http.Request, _ *auth.UserState) Response
 			return InternalError(err.Error())
 		}
 		results[i] = validationSetResult{
-			AccountID: tr.AccountID,
-			Name:      tr.Name,
-			PinnedAt:  tr.PinnedAt,
-			Mode:      modeStr,
-			Sequence:  tr.Current,
-			Valid:     validErr == nil,
+			AccountID:  tr.AccountID,
+			Name:       tr.Name,
+			PinnedAt:   tr.PinnedAt,
+			Mode:       modeStr,
+			Sequence:   tr.Current,
+			Valid:      validErr == nil,
+			Violations: violationsFromError(validErr),
 		}
 	}
 
@@ -227,12 +277,13 @@ func getValidationSet(c *Command, r *http.Request, user *auth.UserState) Respons
 
 	validErr := checkInstalledSnaps(sets, snaps)
 	res := validationSetResult{
-		AccountID: tr.AccountID,
-		Name:      tr.Name,
-		PinnedAt:  tr.PinnedAt,
-		Mode:      modeStr,
-		Sequence:  tr.Current,
-		Valid:     validErr == nil,
+		AccountID:  tr.AccountID,
+		Name:       tr.Name,
+		PinnedAt:   tr.PinnedAt,
+		Mode:       modeStr,
+		Sequence:   tr.Current,
+		Valid:      validErr == nil,
+		Violations: violationsFromError(validErr),
 	}
 	return SyncResponse(res, nil)
 }
@@ -281,31 +332,37 @@ func applyValidationSet(c *Command, r *http.Request, user *auth.UserState) Respo
 	}
 }
 
-var validationSetAssertionForMonitor = assertstate.ValidationSetAssertionForMonitor
+var (
+	validationSetAssertionForMonitor = assertstate.ValidationSetAssertionForMonitor
+	assertstateEnforceValidationSet  = assertstate.EnforceValidationSet
+)
 
 // updateValidationSet handles snap validate --monitor and --enforce accountId/name[=sequence].
 func updateValidationSet(st *state.State, accountID, name string, reqMode string, sequence int, user *auth.UserState) Response {
-	var mode assertstate.ValidationSetMode
-	// TODO: only monitor mode for now, add enforce.
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+
 	switch reqMode {
 	case "monitor":
-		mode = assertstate.Monitor
+		return monitorValidationSet(st, accountID, name, sequence, userID)
+	case "enforce":
+		return enforceValidationSet(st, accountID, name, sequence, userID)
 	default:
 		return BadRequest("invalid mode %q", reqMode)
 	}
+}
 
+func monitorValidationSet(st *state.State, accountID, name string, sequence, userID int) Response {
 	tr := assertstate.ValidationSetTracking{
 		AccountID: accountID,
 		Name:      name,
-		Mode:      mode,
+		Mode:      assertstate.Monitor,
 		// note, Sequence may be 0, meaning not pinned.
 		PinnedAt: sequence,
 	}
 
-	userID := 0
-	if user != nil {
-		userID = user.ID
-	}
 	pinned := sequence > 0
 	opts := assertstate.ResolveOptions{AllowLocalFallback: true}
 	as, local, err := validationSetAssertionForMonitor(st, accountID, name, sequence, pinned, userID, &opts)
@@ -319,6 +376,28 @@ func updateValidationSet(st *state.State, accountID, name string, reqMode string
 	return SyncResponse(nil, nil)
 }
 
+func enforceValidationSet(st *state.State, accountID, name string, sequence, userID int) Response {
+	snaps, err := installedSnaps(st)
+	if err != nil {
+		return InternalError(err.Error())
+	}
+	if _, err := assertstateEnforceValidationSet(st, accountID, name, sequence, userID, snaps); err != nil {
+		if verr, ok := err.(*snapasserts.ValidationSetsValidationError); ok {
+			return &resp{
+				Type: ResponseTypeError,
+				Result: &errorResult{
+					Message: fmt.Sprintf("cannot enforce validation set: %v", verr),
+					Kind:    client.ErrorKindValidationSetsNotMet,
+					Value:   newValidationSetViolations(verr),
+				},
+				Status: 400,
+			}
+		}
+		return BadRequest("cannot enforce validation set: %v", err)
+	}
+	return SyncResponse(nil, nil)
+}
+
 // forgetValidationSet forgets the validation set.
 // The state needs to be locked by the caller.
 func forgetValidationSet(st *state.State, accountID, name string, sequence int) Response {
@@ -388,12 +467,11 @@ func validateAgainstStore(st *state.State, accountID, name string, sequence int,
 
 	validErr := checkInstalledSnaps(sets, snaps)
 	res := validationSetResult{
-		AccountID: vset.AccountID(),
-		Name:      vset.Name(),
-		Sequence:  vset.Sequence(),
-		// TODO: pass actual err details and implement "verbose" mode
-		// for the client?
-		Valid: validErr == nil,
+		AccountID:  vset.AccountID(),
+		Name:       vset.Name(),
+		Sequence:   vset.Sequence(),
+		Valid:      validErr == nil,
+		Violations: violationsFromError(validErr),
 	}
 	return SyncResponse(res, nil)
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/daemon"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
@@ -239,6 +240,9 @@ func (s *apiValidationSetsSuite) TestListValidationSets(c *check.C) {
 			Mode:      "monitor",
 			Sequence:  2,
 			Valid:     false,
+			Violations: &daemon.ValidationSetViolations{
+				MissingSnaps: []string{"snap-b"},
+			},
 		},
 		{
 			AccountID: s.dev1acct.AccountID(),
@@ -247,6 +251,9 @@ func (s *apiValidationSetsSuite) TestListValidationSets(c *check.C) {
 			Mode:      "enforce",
 			Sequence:  99,
 			Valid:     false,
+			Violations: &daemon.ValidationSetViolations{
+				MissingSnaps: []string{"snap-b"},
+			},
 		},
 	})
 }
@@ -277,6 +284,9 @@ func (s *apiValidationSetsSuite) TestGetValidationSetOne(c *check.C) {
 		Mode:      "monitor",
 		Sequence:  2,
 		Valid:     false,
+		Violations: &daemon.ValidationSetViolations{
+			MissingSnaps: []string{"snap-b"},
+		},
 	})
 }
 
@@ -304,6 +314,9 @@ func (s *apiValidationSetsSuite) TestGetValidationSetPinned(c *check.C) {
 		Mode:      "enforce",
 		Sequence:  99,
 		Valid:     false,
+		Violations: &daemon.ValidationSetViolations{
+			MissingSnaps: []string{"snap-b"},
+		},
 	})
 }
 
@@ -432,10 +445,11 @@ func (s *apiValidationSetsSuite) TestGetValidationSetLatestFromRemoteValidationF
 
 	res := rsp.Result.(daemon.ValidationSetResult)
 	c.Check(res, check.DeepEquals, daemon.ValidationSetResult{
-		AccountID: "foo",
-		Name:      "other",
-		Sequence:  2,
-		Valid:     false,
+		AccountID:  "foo",
+		Name:       "other",
+		Sequence:   2,
+		Valid:      false,
+		Violations: &daemon.ValidationSetViolations{},
 	})
 }
 
@@ -451,11 +465,14 @@ func (s *apiValidationSetsSuite) TestGetValidationSetLatestFromRemoteRealValidat
 	for _, tc := range []struct {
 		revision                 snap.Revision
 		expectedValidationStatus bool
+		expectedViolations       *daemon.ValidationSetViolations
 	}{
 		// required at revision 1 per validationSetAssertion, so it's valid
-		{snap.R(1), true},
+		{snap.R(1), true, nil},
 		// but revision 2 is not valid
-		{snap.R(2), false},
+		{snap.R(2), false, &daemon.ValidationSetViolations{
+			WrongRevisionSnaps: map[string]string{"snap-b": "1"},
+		}},
 	} {
 		st.Lock()
 		snapstate.Set(st, "snap-b", &snapstate.SnapState{
@@ -472,10 +489,11 @@ func (s *apiValidationSetsSuite) TestGetValidationSetLatestFromRemoteRealValidat
 
 		res := rsp.Result.(daemon.ValidationSetResult)
 		c.Check(res, check.DeepEquals, daemon.ValidationSetResult{
-			AccountID: "foo",
-			Name:      "other",
-			Sequence:  2,
-			Valid:     tc.expectedValidationStatus,
+			AccountID:  "foo",
+			Name:       "other",
+			Sequence:   2,
+			Valid:      tc.expectedValidationStatus,
+			Violations: tc.expectedViolations,
 		})
 	}
 }
@@ -889,13 +907,6 @@ func (s *apiValidationSetsSuite) TestApplyValidationSetsErrors(c *check.C) {
 			message:       `invalid mode "bad"`,
 			status:        400,
 		},
-		// XXX: enable when enforcing is implemented.
-		{
-			validationSet: "foo/bar",
-			mode:          "enforce",
-			message:       `invalid mode "enforce"`,
-			status:        400,
-		},
 		{
 			validationSet: "foo/bar",
 			sequence:      "-1",
@@ -928,3 +939,74 @@ func (s *apiValidationSetsSuite) TestApplyValidationSetUnsupportedAction(c *chec
 	c.Check(rsp.Status, check.Equals, 400)
 	c.Check(rsp.ErrorResult().Message, check.Matches, `unsupported action "baz"`)
 }
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceMode(c *check.C) {
+	st := s.d.Overlord().State()
+	st.Lock()
+	snapstate.Set(st, "snap-b", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "snap-b", Revision: snap.R(1), SnapID: "yOqKhntON3vR7kwEbVPsILm7bUViPDzz"}},
+		Current:  snap.R(1),
+	})
+	st.Unlock()
+
+	var called int
+	restore := daemon.MockAssertstateEnforceValidationSet(func(st *state.State, accountID, name string, sequence, userID int, snaps []*snapasserts.InstalledSnap) (*assertstate.ValidationSetTracking, error) {
+		c.Check(accountID, check.Equals, s.dev1acct.AccountID())
+		c.Check(name, check.Equals, "bar")
+		c.Check(sequence, check.Equals, 3)
+		c.Check(userID, check.Equals, 0)
+		c.Check(snaps, check.DeepEquals, []*snapasserts.InstalledSnap{
+			snapasserts.NewInstalledSnap("snap-b", "yOqKhntON3vR7kwEbVPsILm7bUViPDzz", snap.R(1)),
+		})
+		called++
+		return &assertstate.ValidationSetTracking{}, nil
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce","sequence":3}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.syncReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(called, check.Equals, 1)
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceModeNotMet(c *check.C) {
+	restore := daemon.MockAssertstateEnforceValidationSet(func(st *state.State, accountID, name string, sequence, userID int, snaps []*snapasserts.InstalledSnap) (*assertstate.ValidationSetTracking, error) {
+		return nil, &snapasserts.ValidationSetsValidationError{
+			MissingSnaps: map[string][]string{"snap-a": {"acc/bar"}},
+			InvalidSnaps: map[string][]string{"snap-c": {"acc/bar"}, "snap-b": {"acc/bar"}},
+		}
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce"}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 400)
+	c.Check(rsp.ErrorResult().Kind, check.Equals, client.ErrorKindValidationSetsNotMet)
+	c.Check(rsp.ErrorResult().Message, check.Matches, `(?s)cannot enforce validation set: validation sets assertions are not met:\n.*`)
+	c.Check(rsp.ErrorResult().Value, check.DeepEquals, &daemon.ValidationSetViolations{
+		MissingSnaps: []string{"snap-a"},
+		InvalidSnaps: []string{"snap-b", "snap-c"},
+	})
+}
+
+func (s *apiValidationSetsSuite) TestApplyValidationSetEnforceModeError(c *check.C) {
+	restore := daemon.MockAssertstateEnforceValidationSet(func(st *state.State, accountID, name string, sequence, userID int, snaps []*snapasserts.InstalledSnap) (*assertstate.ValidationSetTracking, error) {
+		return nil, fmt.Errorf("boom")
+	})
+	defer restore()
+
+	body := `{"action":"apply","mode":"enforce"}`
+	req, err := http.NewRequest("POST", fmt.Sprintf("/v2/validation-sets/%s/bar", s.dev1acct.AccountID()), strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := s.errorReq(c, req, nil)
+	c.Assert(rsp.Status, check.Equals, 400)
+	c.Check(rsp.ErrorResult().Message, check.Equals, "cannot enforce validation set: boom")
+}
@@ -29,6 +29,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,14 +41,17 @@ import (
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/netutil"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/standby"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/polkit"
+	"github.com/snapcore/snapd/sandbox/cgroup"
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/systemd"
@@ -106,15 +110,107 @@ type Command struct {
 	UserOK bool
 	// is this path accessible on the snapd-snap socket?
 	SnapOK bool
-	// this path is only accessible to root
+	// can non-admin use any verb? set only when the handlers restrict
+	// the request to what belongs to the uid making it
+	UserOwnedOK bool
+	// this path is only accessible to root, or to users authorized by
+	// polkit if PolkitOK is set, but never to users logged in via
+	// `snap login`
 	RootOnly bool
 
 	// can polkit grant access? set to polkit action ID if so
 	PolkitOK string
+	// which connected interfaces grant snaps access on the snapd-snap
+	// socket?
+	SnapInterfaces []interfaceAccess
 
 	d *Daemon
 }
 
+// An interfaceAccess grants snaps with a connected plug of Interface access
+// to a command. When Attrs is set the plug must carry those attributes with
+// the same values. When Scope is set the plug must list it in its scopes
+// attribute. When ReadOnly is set only GET requests are granted.
+type interfaceAccess struct {
+	Interface string
+	Attrs     map[string]interface{}
+	Scope     string
+	ReadOnly  bool
+}
+
+var cgroupSnapNameFromPid = cgroup.SnapNameFromPid
+
+// snapHasInterfaceAccess returns whether the snap the process pid belongs to
+// has a plug connected matching any of the command's interface accesses for
+// the given request method.
+func (c *Command) snapHasInterfaceAccess(method string, pid int32) bool {
+	snapName, err := cgroupSnapNameFromPid(int(pid))
+	if err != nil {
+		logger.Debugf("cannot find the snap of process %v: %v", pid, err)
+		return false
+	}
+
+	st := c.d.state
+	st.Lock()
+	defer st.Unlock()
+	conns, err := ifacestate.ConnectionStates(st)
+	if err != nil {
+		logger.Noticef("cannot get connections: %v", err)
+		return false
+	}
+	for connID, conn := range conns {
+		if conn.Undesired || conn.HotplugGone {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(connID)
+		if err != nil || connRef.PlugRef.Snap != snapName {
+			continue
+		}
+		for _, access := range c.SnapInterfaces {
+			if conn.Interface != access.Interface {
+				continue
+			}
+			if access.ReadOnly && method != "GET" {
+				continue
+			}
+			if plugAttrsMatch(&conn, access.Attrs) && plugHasScope(&conn, access.Scope) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func plugAttrsMatch(conn *ifacestate.ConnectionState, attrs map[string]interface{}) bool {
+	for name, value := range attrs {
+		v, ok := conn.DynamicPlugAttrs[name]
+		if !ok {
+			v, ok = conn.StaticPlugAttrs[name]
+		}
+		if !ok || !reflect.DeepEqual(v, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func plugHasScope(conn *ifacestate.ConnectionState, scope string) bool {
+	if scope == "" {
+		return true
+	}
+	scopes, ok := conn.DynamicPlugAttrs["scopes"]
+	if !ok {
+		scopes = conn.StaticPlugAttrs["scopes"]
+	}
+	list, _ := scopes.([]interface{})
+	for _, s := range list {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
 type accessResult int
 
 const (
@@ -135,10 +231,11 @@ var polkitCheckAuthorization = polkit.CheckAuthorization
 // Otherwise for GET requests the following parameters are honored:
 // - GuestOK: anyone can access GET
 // - UserOK: any uid on the local system can access GET
-// - RootOnly: only root can access this
+// - RootOnly: only root, or users authorized by polkit for PolkitOK, can access this
 // - SnapOK: a snap can access this via `snapctl`
+// - SnapInterfaces: a snap with a matching connected plug can access this
 func (c *Command) canAccess(r *http.Request, user *auth.UserState) accessResult {
-	if c.RootOnly && (c.UserOK || c.GuestOK || c.SnapOK) {
+	if c.RootOnly && (c.UserOK || c.GuestOK || c.SnapOK || c.UserOwnedOK) {
 		// programming error
 		logger.Panicf("Command can't have RootOnly together with any *OK flag")
 	}
@@ -159,11 +256,15 @@ func (c *Command) canAccess(r *http.Request, user *auth.UserState) accessResult
 	}
 	isSnap := (socket == dirs.SnapSocket)
 
-	// ensure that snaps can only access SnapOK things
+	// ensure that snaps can only access SnapOK things, or the things
+	// their connected interfaces grant access to
 	if isSnap {
 		if c.SnapOK {
 			return accessOK
 		}
+		if len(c.SnapInterfaces) > 0 && c.snapHasInterfaceAccess(r.Method, pid) {
+			return accessOK
+		}
 		return accessUnauthorized
 	}
 
@@ -184,12 +285,17 @@ func (c *Command) canAccess(r *http.Request, user *auth.UserState) accessResult
 		return accessUnauthorized
 	}
 
+	if c.UserOwnedOK {
+		// the handlers only act on what belongs to the uid
+		return accessOK
+	}
+
 	if uid == 0 {
 		// Superuser does anything.
 		return accessOK
 	}
 
-	if c.RootOnly {
+	if c.RootOnly && c.PolkitOK == "" {
 		return accessUnauthorized
 	}
 
@@ -268,7 +374,7 @@ func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if rsp, ok := rsp.(*resp); ok {
 		_, rst := st.Restarting()
 		if rst != state.RestartUnset {
-			rsp.Maintenance = maintenanceForRestartType(rst)
+			rsp.Maintenance = maintenanceForRestartType(rst, pendingMaintenanceInfo(st, rst))
 		}
 
 		if rsp.Type != ResponseTypeError {
@@ -539,7 +645,7 @@ func (d *Daemon) updateMaintenanceFile(rst state.RestartType) error {
 	}
 
 	// otherwise marshal and write it out appropriately
-	b, err := json.Marshal(maintenanceForRestartType(rst))
+	b, err := json.Marshal(maintenanceForRestartType(rst, pendingMaintenanceInfo(d.state, rst)))
 	if err != nil {
 		return err
 	}
@@ -666,26 +772,34 @@ func (d *Daemon) rebootDelay(immediate bool) (time.Duration, error) {
 	if err != nil && err != state.ErrNoState {
 		return 0, err
 	}
-	rebootDelay := 1 * time.Minute
-	if immediate {
-		rebootDelay = 0
-	}
+	var rebootDelay time.Duration
 	if err == nil {
 		rebootDelay = rebootAt.Sub(now)
 	} else {
-		ovr := os.Getenv("SNAPD_REBOOT_DELAY") // for tests
-		if ovr != "" && !immediate {
-			d, err := time.ParseDuration(ovr)
-			if err == nil {
-				rebootDelay = d
-			}
-		}
+		rebootDelay = defaultRebootDelay(immediate)
 		rebootAt = now.Add(rebootDelay)
 		d.state.Set("daemon-system-restart-at", rebootAt)
 	}
 	return rebootDelay, nil
 }
 
+// defaultRebootDelay returns the delay of a system reboot that was not
+// scheduled yet.
+func defaultRebootDelay(immediate bool) time.Duration {
+	if immediate {
+		return 0
+	}
+	rebootDelay := 1 * time.Minute
+	ovr := os.Getenv("SNAPD_REBOOT_DELAY") // for tests
+	if ovr != "" {
+		d, err := time.ParseDuration(ovr)
+		if err == nil {
+			rebootDelay = d
+		}
+	}
+	return rebootDelay
+}
+
 func (d *Daemon) doReboot(sigCh chan<- os.Signal, immediate bool, waitTimeout time.Duration) error {
 	rebootDelay, err := d.rebootDelay(immediate)
 	if err != nil {
@@ -198,10 +198,13 @@ func (s *daemonSuite) TestCommandRestartingState(c *check.C) {
 	c.Check(rec.Code, check.Equals, 200)
 	err = json.Unmarshal(rec.Body.Bytes(), &rst)
 	c.Assert(err, check.IsNil)
-	c.Check(rst.Maintenance, check.DeepEquals, &errorResult{
-		Kind:    client.ErrorKindSystemRestart,
-		Message: "system is restarting",
-	})
+	c.Assert(rst.Maintenance, check.NotNil)
+	c.Check(rst.Maintenance.Kind, check.Equals, client.ErrorKindSystemRestart)
+	c.Check(rst.Maintenance.Message, check.Equals, "system is restarting")
+	// the earliest time of the not yet scheduled reboot is reported
+	c.Check(rst.Maintenance.Value, check.FitsTypeOf, map[string]interface{}{})
+	c.Check(rst.Maintenance.Value.(map[string]interface{})["restart-at"], check.NotNil)
+	rst.Maintenance = nil
 
 	state.MockRestarting(d.overlord.State(), state.RestartDaemon)
 	rec = httptest.NewRecorder()
@@ -215,6 +218,45 @@ func (s *daemonSuite) TestCommandRestartingState(c *check.C) {
 	})
 }
 
+func (s *daemonSuite) TestCommandRestartingStateWithReason(c *check.C) {
+	d := newTestDaemon(c)
+
+	cmd := &Command{d: d}
+	cmd.GET = func(*Command, *http.Request, *auth.UserState) Response {
+		return SyncResponse(nil, nil)
+	}
+	req, err := http.NewRequest("GET", "", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;socket=;"
+
+	st := d.overlord.State()
+	st.Lock()
+	st.RequestRestartWithReason(state.RestartDaemon, &state.RestartReason{
+		ChangeID: "42",
+		SnapName: "snapd",
+		SnapType: "snapd",
+	})
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	cmd.ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var rst struct {
+		Maintenance *errorResult `json:"maintenance"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &rst)
+	c.Assert(err, check.IsNil)
+	c.Check(rst.Maintenance, check.DeepEquals, &errorResult{
+		Kind:    client.ErrorKindDaemonRestart,
+		Message: "daemon is restarting",
+		Value: map[string]interface{}{
+			"change-id": "42",
+			"snap-name": "snapd",
+			"snap-type": "snapd",
+		},
+	})
+}
+
 func (s *daemonSuite) TestMaintenanceJsonDeletedOnStart(c *check.C) {
 	// write a maintenance.json file that has that the system is restarting
 	maintErr := &errorResult{
@@ -334,6 +376,197 @@ func (s *daemonSuite) TestSnapctlAccessSnapOKWithRoot(c *check.C) {
 	c.Check(cmd.canAccess(del, nil), check.Equals, accessOK)
 }
 
+func (s *daemonSuite) setSnapInterfaceConns(c *check.C, d *Daemon, conns map[string]interface{}) {
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	st.Set("conns", conns)
+}
+
+func (s *daemonSuite) TestSnapInterfacesAccess(c *check.C) {
+	restore := MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		c.Check(pid, check.Equals, 100)
+		return "refresh-app", nil
+	})
+	defer restore()
+
+	remoteAddr := "pid=100;uid=1000;socket=" + dirs.SnapSocket + ";"
+	get := &http.Request{Method: "GET", RemoteAddr: remoteAddr}
+	pst := &http.Request{Method: "POST", RemoteAddr: remoteAddr}
+
+	d := newTestDaemon(c)
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		"refresh-app:snapd-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"plug-static": map[string]interface{}{
+				"refresh-schedule": "managed",
+			},
+		},
+	})
+
+	cmd := &Command{d: d, SnapInterfaces: refreshControlAccess}
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessOK)
+	c.Check(cmd.canAccess(pst, nil), check.Equals, accessOK)
+
+	// other commands are still off limits
+	cmd = &Command{d: d, UserOK: true}
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
+	c.Check(cmd.canAccess(pst, nil), check.Equals, accessUnauthorized)
+}
+
+func (s *daemonSuite) TestSnapInterfacesAccessDenied(c *check.C) {
+	snapName := "refresh-app"
+	restore := MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		return snapName, nil
+	})
+	defer restore()
+
+	remoteAddr := "pid=100;uid=1000;socket=" + dirs.SnapSocket + ";"
+	get := &http.Request{Method: "GET", RemoteAddr: remoteAddr}
+
+	d := newTestDaemon(c)
+	cmd := &Command{d: d, SnapInterfaces: refreshControlAccess}
+
+	// no connections at all
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
+
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		// attribute mismatch
+		"refresh-app:snapd-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"plug-static": map[string]interface{}{
+				"refresh-schedule": "default",
+			},
+		},
+		// undesired connection
+		"refresh-app:other-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"undesired": true,
+			"plug-static": map[string]interface{}{
+				"refresh-schedule": "managed",
+			},
+		},
+		// different snap
+		"other-app:snapd-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"plug-static": map[string]interface{}{
+				"refresh-schedule": "managed",
+			},
+		},
+	})
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
+
+	// the dynamic attribute takes precedence
+	snapName = "other-app"
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessOK)
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		"other-app:snapd-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"plug-static": map[string]interface{}{
+				"refresh-schedule": "managed",
+			},
+			"plug-dynamic": map[string]interface{}{
+				"refresh-schedule": "default",
+			},
+		},
+	})
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
+
+	// processes that are not part of a snap are denied
+	restore = MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		return "", errors.New("not a snap")
+	})
+	defer restore()
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
+}
+
+func (s *daemonSuite) TestSnapInterfacesAccessScopes(c *check.C) {
+	restore := MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		return "backup-app", nil
+	})
+	defer restore()
+
+	remoteAddr := "pid=100;uid=0;socket=" + dirs.SnapSocket + ";"
+	pst := &http.Request{Method: "POST", RemoteAddr: remoteAddr}
+
+	d := newTestDaemon(c)
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		"backup-app:snapd-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"plug-static": map[string]interface{}{
+				"scopes": []interface{}{"snapshots"},
+			},
+		},
+	})
+
+	snapshots := &Command{d: d, SnapInterfaces: snapshotsControlAccess}
+	changes := &Command{d: d, SnapInterfaces: changeAccess}
+	refresh := &Command{d: d, SnapInterfaces: refreshControlAccess}
+	c.Check(snapshots.canAccess(pst, nil), check.Equals, accessOK)
+	c.Check(changes.canAccess(pst, nil), check.Equals, accessOK)
+	c.Check(refresh.canAccess(pst, nil), check.Equals, accessUnauthorized)
+
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		"backup-app:snapd-control core:snapd-control": map[string]interface{}{
+			"interface": "snapd-control",
+			"plug-static": map[string]interface{}{
+				"scopes": []interface{}{"refresh-schedule"},
+			},
+		},
+	})
+	c.Check(snapshots.canAccess(pst, nil), check.Equals, accessUnauthorized)
+	c.Check(changes.canAccess(pst, nil), check.Equals, accessOK)
+	c.Check(refresh.canAccess(pst, nil), check.Equals, accessOK)
+}
+
+func (s *daemonSuite) TestSnapInterfacesAccessReadOnly(c *check.C) {
+	restore := MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		return "escrow-agent", nil
+	})
+	defer restore()
+
+	remoteAddr := "pid=100;uid=0;socket=" + dirs.SnapSocket + ";"
+	get := &http.Request{Method: "GET", RemoteAddr: remoteAddr}
+	pst := &http.Request{Method: "POST", RemoteAddr: remoteAddr}
+
+	d := newTestDaemon(c)
+	cmd := &Command{d: d, SnapInterfaces: recoveryKeysAccess}
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessUnauthorized)
+
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		"escrow-agent:system-recovery-keys core:system-recovery-keys": map[string]interface{}{
+			"interface": "system-recovery-keys",
+		},
+	})
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessOK)
+	// the keys can be read but not regenerated
+	c.Check(cmd.canAccess(pst, nil), check.Equals, accessUnauthorized)
+}
+
+func (s *daemonSuite) TestSnapInterfacesAccessWithRootOnly(c *check.C) {
+	restore := MockCgroupSnapNameFromPid(func(pid int) (string, error) {
+		return "escrow-agent", nil
+	})
+	defer restore()
+
+	d := newTestDaemon(c)
+	s.setSnapInterfaceConns(c, d, map[string]interface{}{
+		"escrow-agent:system-recovery-keys core:system-recovery-keys": map[string]interface{}{
+			"interface": "system-recovery-keys",
+		},
+	})
+	cmd := &Command{d: d, RootOnly: true, PolkitOK: "polkit.action", SnapInterfaces: recoveryKeysAccess}
+
+	// snaps are granted access through their interfaces
+	get := &http.Request{Method: "GET", RemoteAddr: "pid=100;uid=0;socket=" + dirs.SnapSocket + ";"}
+	c.Check(cmd.canAccess(get, nil), check.Equals, accessOK)
+
+	// but logged in users are not
+	s.authorized = false
+	get = &http.Request{Method: "GET", RemoteAddr: "pid=100;uid=1000;socket=;"}
+	c.Check(cmd.canAccess(get, &auth.UserState{ID: 1}), check.Equals, accessUnauthorized)
+}
+
 func (s *daemonSuite) TestUserAccess(c *check.C) {
 	get := &http.Request{Method: "GET", RemoteAddr: "pid=100;uid=42;socket=;"}
 	put := &http.Request{Method: "PUT", RemoteAddr: "pid=100;uid=42;socket=;"}
@@ -450,6 +683,45 @@ func (s *daemonSuite) TestPolkitAccessForGet(c *check.C) {
 	c.Check(cmd.canAccess(get, nil), check.Equals, accessOK)
 }
 
+func (s *daemonSuite) TestPolkitAccessRootOnly(c *check.C) {
+	put := &http.Request{Method: "PUT", RemoteAddr: "pid=100;uid=42;socket=;"}
+	cmd := &Command{d: newTestDaemon(c), RootOnly: true, PolkitOK: "polkit.action"}
+
+	// polkit says user is not authorised
+	s.authorized = false
+	c.Check(cmd.canAccess(put, nil), check.Equals, accessUnauthorized)
+
+	// logged in users are not enough for root only commands
+	user := &auth.UserState{ID: 1}
+	c.Check(cmd.canAccess(put, user), check.Equals, accessUnauthorized)
+
+	// polkit grants authorisation
+	s.authorized = true
+	c.Check(cmd.canAccess(put, nil), check.Equals, accessOK)
+
+	// root is always fine
+	put.RemoteAddr = "pid=100;uid=0;socket=;"
+	s.authorized = false
+	c.Check(cmd.canAccess(put, nil), check.Equals, accessOK)
+}
+
+func (s *daemonSuite) TestUserOwnedAccess(c *check.C) {
+	post := &http.Request{Method: "POST", RemoteAddr: "pid=100;uid=42;socket=;"}
+	cmd := &Command{d: newTestDaemon(c), UserOwnedOK: true}
+
+	// any identified user, without polkit
+	s.authorized = false
+	c.Check(cmd.canAccess(post, nil), check.Equals, accessOK)
+
+	// but not unidentified ones
+	post.RemoteAddr = ""
+	c.Check(cmd.canAccess(post, nil), check.Equals, accessUnauthorized)
+
+	// nor snaps
+	post.RemoteAddr = "pid=100;uid=42;socket=" + dirs.SnapSocket + ";"
+	c.Check(cmd.canAccess(post, nil), check.Equals, accessUnauthorized)
+}
+
 func (s *daemonSuite) TestPolkitInteractivity(c *check.C) {
 	put := &http.Request{Method: "PUT", RemoteAddr: "pid=100;uid=42;socket=;", Header: make(http.Header)}
 	cmd := &Command{d: newTestDaemon(c), PolkitOK: "polkit.action"}
@@ -911,7 +1183,11 @@ func (s *daemonSuite) testRestartSystemWiring(c *check.C, restartKind state.Rest
 	}
 
 	st.Lock()
-	st.RequestRestart(restartKind)
+	st.RequestRestartWithReason(restartKind, &state.RestartReason{
+		ChangeID: "42",
+		SnapName: "pc-kernel",
+		SnapType: "kernel",
+	})
 	st.Unlock()
 
 	defer func() {
@@ -972,8 +1248,20 @@ func (s *daemonSuite) testRestartSystemWiring(c *check.C, restartKind state.Rest
 	maintErr := &errorResult{}
 	c.Assert(json.Unmarshal(b, maintErr), check.IsNil)
 
-	exp := maintenanceForRestartType(restartKind)
-	c.Assert(maintErr, check.DeepEquals, exp)
+	exp := maintenanceForRestartType(restartKind, nil)
+	c.Check(maintErr.Kind, check.Equals, exp.Kind)
+	c.Check(maintErr.Message, check.Equals, exp.Message)
+	c.Assert(maintErr.Value, check.FitsTypeOf, map[string]interface{}{})
+	value := maintErr.Value.(map[string]interface{})
+	c.Check(value["change-id"], check.Equals, "42")
+	c.Check(value["snap-name"], check.Equals, "pc-kernel")
+	c.Check(value["snap-type"], check.Equals, "kernel")
+	// maintenance.json is written before the reboot is scheduled, so it
+	// reports the earliest time it can happen
+	var restartAt time.Time
+	c.Assert(restartAt.UnmarshalText([]byte(value["restart-at"].(string))), check.IsNil)
+	c.Check(restartAt.After(rebootAt), check.Equals, false)
+	c.Check(restartAt.Before(now), check.Equals, false)
 }
 
 func (s *daemonSuite) TestRestartSystemGracefulWiring(c *check.C) {
@@ -21,6 +21,7 @@ package daemon
 
 type (
 	ConnectivityStatus = connectivityStatus
+	BootloaderVars     = bootloaderVars
 )
 
 var (
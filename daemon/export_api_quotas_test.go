@@ -0,0 +1,59 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap/quota"
+)
+
+func MockServicestateCreateQuota(f func(st *state.State, name string, snaps []string, memoryLimit quantity.Size) (*state.TaskSet, error)) (restore func()) {
+	old := servicestateCreateQuota
+	servicestateCreateQuota = f
+	return func() {
+		servicestateCreateQuota = old
+	}
+}
+
+func MockServicestateUpdateQuota(f func(st *state.State, name string, updateOpts servicestate.QuotaGroupUpdate) (*state.TaskSet, error)) (restore func()) {
+	old := servicestateUpdateQuota
+	servicestateUpdateQuota = f
+	return func() {
+		servicestateUpdateQuota = old
+	}
+}
+
+func MockServicestateRemoveQuota(f func(st *state.State, name string) (*state.TaskSet, error)) (restore func()) {
+	old := servicestateRemoveQuota
+	servicestateRemoveQuota = f
+	return func() {
+		servicestateRemoveQuota = old
+	}
+}
+
+func MockQuotaGroupCurrentMemoryUsage(f func(grp *quota.Group) (quantity.Size, error)) (restore func()) {
+	old := quotaGroupCurrentMemoryUsage
+	quotaGroupCurrentMemoryUsage = f
+	return func() {
+		quotaGroupCurrentMemoryUsage = old
+	}
+}
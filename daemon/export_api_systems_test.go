@@ -21,6 +21,7 @@ package daemon
 
 import (
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
 )
 
 func MockDeviceManagerReboot(f func(*devicestate.DeviceManager, string, string) error) (restore func()) {
@@ -31,6 +32,22 @@ func MockDeviceManagerReboot(f func(*devicestate.DeviceManager, string, string)
 	}
 }
 
+func MockDevicestateFactoryReset(f func(*state.State) (*state.Change, error)) (restore func()) {
+	old := devicestateFactoryReset
+	devicestateFactoryReset = f
+	return func() {
+		devicestateFactoryReset = old
+	}
+}
+
+func MockDevicestateCreateRecoverySystem(f func(*state.State, string, devicestate.CreateRecoverySystemOptions) (*state.Change, error)) (restore func()) {
+	old := devicestateCreateRecoverySystem
+	devicestateCreateRecoverySystem = f
+	return func() {
+		devicestateCreateRecoverySystem = old
+	}
+}
+
 type (
 	SystemsResponse = systemsResponse
 )
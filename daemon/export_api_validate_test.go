@@ -27,7 +27,8 @@ import (
 )
 
 type (
-	ValidationSetResult = validationSetResult
+	ValidationSetResult     = validationSetResult
+	ValidationSetViolations = validationSetViolations
 )
 
 func MockCheckInstalledSnaps(f func(vsets *snapasserts.ValidationSets, snaps []*snapasserts.InstalledSnap) error) func() {
@@ -45,3 +46,11 @@ func MockValidationSetAssertionForMonitor(f func(st *state.State, accountID, nam
 		validationSetAssertionForMonitor = old
 	}
 }
+
+func MockAssertstateEnforceValidationSet(f func(st *state.State, accountID, name string, sequence, userID int, snaps []*snapasserts.InstalledSnap) (*assertstate.ValidationSetTracking, error)) func() {
+	old := assertstateEnforceValidationSet
+	assertstateEnforceValidationSet = f
+	return func() {
+		assertstateEnforceValidationSet = old
+	}
+}
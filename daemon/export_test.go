@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/polkit"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -71,6 +72,14 @@ func MockUcrednetGet(mock func(remoteAddr string) (pid int32, uid uint32, socket
 	}
 }
 
+func MockCgroupSnapNameFromPid(mock func(int) (string, error)) (restore func()) {
+	old := cgroupSnapNameFromPid
+	cgroupSnapNameFromPid = mock
+	return func() {
+		cgroupSnapNameFromPid = old
+	}
+}
+
 func MockEnsureStateSoon(mock func(*state.State)) (original func(*state.State), restore func()) {
 	oldEnsureStateSoon := ensureStateSoon
 	ensureStateSoon = mock
@@ -159,6 +168,14 @@ func MockSnapstateRevert(mock func(*state.State, string, snapstate.Flags) (*stat
 	}
 }
 
+func MockSnapMgrOnMeteredConnection(mock func(*snapstate.SnapManager) bool) (restore func()) {
+	oldSnapMgrOnMeteredConnection := snapMgrOnMeteredConnection
+	snapMgrOnMeteredConnection = mock
+	return func() {
+		snapMgrOnMeteredConnection = oldSnapMgrOnMeteredConnection
+	}
+}
+
 func MockSnapstateRevertToRevision(mock func(*state.State, string, snap.Revision, snapstate.Flags) (*state.TaskSet, error)) (restore func()) {
 	oldSnapstateRevertToRevision := snapstateRevertToRevision
 	snapstateRevertToRevision = mock
@@ -167,7 +184,7 @@ func MockSnapstateRevertToRevision(mock func(*state.State, string, snap.Revision
 	}
 }
 
-func MockSnapstateInstallMany(mock func(*state.State, []string, int) ([]string, []*state.TaskSet, error)) (restore func()) {
+func MockSnapstateInstallMany(mock func(*state.State, []string, int, *snapstate.Flags) ([]string, []*state.TaskSet, error)) (restore func()) {
 	oldSnapstateInstallMany := snapstateInstallMany
 	snapstateInstallMany = mock
 	return func() {
@@ -212,6 +229,10 @@ func (inst *snapInstruction) DispatchForMany() snapManyActionFunc {
 	return inst.dispatchForMany()
 }
 
+func (inst *snapInstruction) Validate() error {
+	return inst.validate()
+}
+
 func (inst *snapInstruction) SetUserID(userID int) {
 	inst.userID = userID
 }
@@ -231,3 +252,11 @@ var (
 	MakeErrorResponder = makeErrorResponder
 	ErrToResponse      = errToResponse
 )
+
+func MockPolkitCheckAuthorization(f func(pid int32, uid uint32, actionId string, details map[string]string, flags polkit.CheckFlags) (bool, error)) (restore func()) {
+	old := polkitCheckAuthorization
+	polkitCheckAuthorization = f
+	return func() {
+		polkitCheckAuthorization = old
+	}
+}
@@ -68,8 +68,50 @@ type resp struct {
 	Maintenance *errorResult `json:"maintenance,omitempty"`
 }
 
-func maintenanceForRestartType(rst state.RestartType) *errorResult {
+// maintenanceInfo holds the details of a pending restart.
+type maintenanceInfo struct {
+	// ChangeID is the ID of the change that requested the restart.
+	ChangeID string `json:"change-id,omitempty"`
+	// SnapName is the name of the snap whose change requires the restart.
+	SnapName string `json:"snap-name,omitempty"`
+	// SnapType is the type of that snap, e.g. kernel or base.
+	SnapType string `json:"snap-type,omitempty"`
+	// RestartAt is the earliest time the system is going to restart.
+	RestartAt *time.Time `json:"restart-at,omitempty"`
+}
+
+// pendingMaintenanceInfo returns the details known about the pending
+// restart of the given type, or nil if there are none.
+func pendingMaintenanceInfo(st *state.State, rst state.RestartType) *maintenanceInfo {
+	var info maintenanceInfo
+	if reason := st.PendingRestartReason(); reason != nil {
+		info.ChangeID = reason.ChangeID
+		info.SnapName = reason.SnapName
+		info.SnapType = reason.SnapType
+	}
+	if rst == state.RestartSystem || rst == state.RestartSystemNow {
+		// the reboot is scheduled only once the daemon is stopping,
+		// until then report the earliest time it can happen
+		st.Lock()
+		var restartAt time.Time
+		err := st.Get("daemon-system-restart-at", &restartAt)
+		st.Unlock()
+		if err != nil {
+			restartAt = time.Now().Add(defaultRebootDelay(rst == state.RestartSystemNow))
+		}
+		info.RestartAt = &restartAt
+	}
+	if info == (maintenanceInfo{}) {
+		return nil
+	}
+	return &info
+}
+
+func maintenanceForRestartType(rst state.RestartType, info *maintenanceInfo) *errorResult {
 	e := &errorResult{}
+	if info != nil {
+		e.Value = info
+	}
 	switch rst {
 	case state.RestartSystem, state.RestartSystemNow:
 		e.Kind = client.ErrorKindSystemRestart
@@ -275,6 +317,7 @@ type snapshotExportResponse struct {
 func (s snapshotExportResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("Content-Length", strconv.FormatInt(s.Size(), 10))
 	w.Header().Add("Content-Type", client.SnapshotExportMediaType)
+	w.Header().Add("Snapshot-Sha3-384", s.Sha3_384())
 	if err := s.StreamTo(w); err != nil {
 		logger.Debugf("cannot export snapshot: %v", err)
 	}
@@ -541,6 +584,20 @@ func AuthCancelled(format string, v ...interface{}) Response {
 	}
 }
 
+// NotSupported is an error responder used when the request is not
+// supported on the system, e.g. because of how it boots.
+func NotSupported(format string, v ...interface{}) Response {
+	res := &errorResult{
+		Message: fmt.Sprintf(format, v...),
+		Kind:    client.ErrorKindNotSupported,
+	}
+	return &resp{
+		Type:   ResponseTypeError,
+		Result: res,
+		Status: 400,
+	}
+}
+
 // InterfacesUnchanged is an error responder used when an operation
 // that would normally change interfaces finds it has nothing to do
 func InterfacesUnchanged(format string, v ...interface{}) Response {
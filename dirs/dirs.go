@@ -49,6 +49,7 @@ var (
 	SnapMountPolicyDir        string
 	SnapUdevRulesDir          string
 	SnapKModModulesDir        string
+	SnapKModModprobeDir       string
 	LocaleDir                 string
 	SnapMetaDir               string
 	SnapdSocket               string
@@ -87,6 +88,7 @@ var (
 	SnapSectionsFile    string
 	SnapCommandsDB      string
 	SnapAuxStoreInfoDir string
+	SnapSeccompCacheDir string
 
 	SnapBinariesDir     string
 	SnapServicesDir     string
@@ -100,11 +102,15 @@ var (
 	SnapDBusSessionServicesDir string
 	SnapDBusSystemServicesDir  string
 
-	SnapModeenvFile   string
-	SnapBootAssetsDir string
-	SnapFDEDir        string
-	SnapSaveDir       string
-	SnapDeviceSaveDir string
+	PolkitActionsDir string
+
+	SnapModeenvFile     string
+	SnapBootAssetsDir   string
+	SnapBootTimingsFile string
+	SnapBootHistoryFile string
+	SnapFDEDir          string
+	SnapSaveDir         string
+	SnapDeviceSaveDir   string
 
 	CloudMetaDataFile     string
 	CloudInstanceDataFile string
@@ -356,12 +362,15 @@ func SetRootDir(rootdir string) {
 	SnapSectionsFile = filepath.Join(SnapCacheDir, "sections")
 	SnapCommandsDB = filepath.Join(SnapCacheDir, "commands.db")
 	SnapAuxStoreInfoDir = filepath.Join(SnapCacheDir, "aux")
+	SnapSeccompCacheDir = filepath.Join(SnapCacheDir, "seccomp")
 
 	SnapSeedDir = SnapSeedDirUnder(rootdir)
 	SnapDeviceDir = SnapDeviceDirUnder(rootdir)
 
 	SnapModeenvFile = SnapModeenvFileUnder(rootdir)
 	SnapBootAssetsDir = SnapBootAssetsDirUnder(rootdir)
+	SnapBootTimingsFile = filepath.Join(rootdir, snappyDir, "boot-timings.json")
+	SnapBootHistoryFile = filepath.Join(rootdir, snappyDir, "boot-history.json")
 	SnapFDEDir = SnapFDEDirUnder(rootdir)
 	SnapSaveDir = SnapSaveDirUnder(rootdir)
 	SnapDeviceSaveDir = filepath.Join(SnapSaveDir, "device")
@@ -386,11 +395,14 @@ func SetRootDir(rootdir string) {
 	SnapDBusSessionServicesDir = filepath.Join(rootdir, snappyDir, "dbus-1", "services")
 	SnapDBusSystemServicesDir = filepath.Join(rootdir, snappyDir, "dbus-1", "system-services")
 
+	PolkitActionsDir = filepath.Join(rootdir, "/usr/share/polkit-1/actions")
+
 	CloudInstanceDataFile = filepath.Join(rootdir, "/run/cloud-init/instance-data.json")
 
 	SnapUdevRulesDir = filepath.Join(rootdir, "/etc/udev/rules.d")
 
 	SnapKModModulesDir = filepath.Join(rootdir, "/etc/modules-load.d/")
+	SnapKModModprobeDir = filepath.Join(rootdir, "/etc/modprobe.d/")
 
 	LocaleDir = filepath.Join(rootdir, "/usr/share/locale")
 	ClassicDir = filepath.Join(rootdir, "/writable/classic")
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/disks"
+	"github.com/snapcore/snapd/strutil"
 )
 
 var ErrDeviceNotFound = errors.New("device not found")
@@ -184,7 +185,7 @@ func findMountPointForStructure(ps *LaidOutStructure) (string, error) {
 func isWritableMount(entry *osutil.MountInfoEntry) bool {
 	// example mountinfo entry:
 	// 26 27 8:3 / /writable rw,relatime shared:7 - ext4 /dev/sda3 rw,data=ordered
-	return entry.Root == "/" && entry.MountDir == "/writable" && entry.FsType == "ext4"
+	return entry.Root == "/" && entry.MountDir == "/writable" && strutil.ListContains([]string{"ext4", "btrfs", "f2fs"}, entry.FsType)
 }
 
 func findDeviceForWritable() (device string, err error) {
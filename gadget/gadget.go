@@ -35,6 +35,7 @@ import (
 	"github.com/snapcore/snapd/gadget/edition"
 	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/metautil"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/naming"
 	"github.com/snapcore/snapd/strutil"
@@ -284,8 +285,11 @@ type Model interface {
 	Grade() asserts.ModelGrade
 }
 
+// classicOrUndetermined returns whether the model is unknown or is a
+// classic model without modes, whose gadget has less expectations put on
+// it; classic models with modes have the same expectations as UC20 ones.
 func classicOrUndetermined(m Model) bool {
-	return m == nil || m.Classic()
+	return m == nil || (m.Classic() && !wantsSystemSeed(m))
 }
 
 func wantsSystemSeed(m Model) bool {
@@ -644,9 +648,12 @@ func validateVolumeStructure(vs *VolumeStructure, vol *Volume) error {
 		}
 		return fmt.Errorf("invalid %s: %v", what, err)
 	}
-	if vs.Filesystem != "" && !strutil.ListContains([]string{"ext4", "vfat", "none"}, vs.Filesystem) {
+	if vs.Filesystem != "" && !strutil.ListContains([]string{"ext4", "vfat", "btrfs", "f2fs", "none"}, vs.Filesystem) {
 		return fmt.Errorf("invalid filesystem %q", vs.Filesystem)
 	}
+	if strutil.ListContains([]string{"btrfs", "f2fs"}, vs.Filesystem) && vs.Role != SystemData {
+		return fmt.Errorf("invalid filesystem %q: only supported for %s role", vs.Filesystem, SystemData)
+	}
 
 	var contentChecker func(*VolumeContent) error
 
@@ -993,3 +1000,31 @@ func SystemDefaults(gadgetDefaults map[string]map[string]interface{}) map[string
 	}
 	return nil
 }
+
+// KernelCommandLineFromGadget returns the extra kernel command line arguments
+// provided by the gadget in the cmdline.extra file at the root of the gadget
+// snap. An empty string is returned when the gadget provides none.
+func KernelCommandLineFromGadget(gadgetSnapRootDir string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(gadgetSnapRootDir, "cmdline.extra"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	cmdline := strings.TrimSpace(string(content))
+	if strings.Contains(cmdline, "\n") {
+		return "", fmt.Errorf("invalid kernel command line in cmdline.extra: unexpected newline")
+	}
+	args, err := osutil.KernelCommandLineSplit(cmdline)
+	if err != nil {
+		return "", fmt.Errorf("invalid kernel command line in cmdline.extra: %v", err)
+	}
+	for _, arg := range args {
+		// the snapd arguments are controlled by snapd only
+		if strings.HasPrefix(arg, "snapd_") {
+			return "", fmt.Errorf("invalid kernel command line in cmdline.extra: disallowed argument %q", arg)
+		}
+	}
+	return cmdline, nil
+}
@@ -1186,7 +1186,9 @@ func (s *gadgetYamlTestSuite) TestValidateFilesystem(c *C) {
 		{"vfat", ""},
 		{"ext4", ""},
 		{"none", ""},
-		{"btrfs", `invalid filesystem "btrfs"`},
+		{"btrfs", `invalid filesystem "btrfs": only supported for system-data role`},
+		{"f2fs", `invalid filesystem "f2fs": only supported for system-data role`},
+		{"xfs", `invalid filesystem "xfs"`},
 	} {
 		c.Logf("tc: %v %+v", i, tc.s)
 
@@ -1199,6 +1201,21 @@ func (s *gadgetYamlTestSuite) TestValidateFilesystem(c *C) {
 	}
 }
 
+func (s *gadgetYamlTestSuite) TestValidateFilesystemSystemData(c *C) {
+	for _, fs := range []string{"ext4", "btrfs", "f2fs"} {
+		c.Logf("fs: %v", fs)
+
+		err := gadget.ValidateVolumeStructure(&gadget.VolumeStructure{
+			Filesystem: fs,
+			Role:       gadget.SystemData,
+			Label:      "writable",
+			Type:       "83,0FC63DAF-8483-4772-8E79-3D69D8477DE4",
+			Size:       123,
+		}, &gadget.Volume{})
+		c.Check(err, IsNil)
+	}
+}
+
 func (s *gadgetYamlTestSuite) TestValidateVolumeSchema(c *C) {
 	for i, tc := range []struct {
 		s   string
@@ -2400,3 +2417,31 @@ volumes:
 	err = gadget.IsCompatible(gi, giNew)
 	c.Check(err, IsNil)
 }
+
+func (s *gadgetYamlTestSuite) TestKernelCommandLineFromGadget(c *C) {
+	// no cmdline.extra
+	cmdline, err := gadget.KernelCommandLineFromGadget(s.dir)
+	c.Assert(err, IsNil)
+	c.Check(cmdline, Equals, "")
+
+	for _, tc := range []struct {
+		content, cmdline, err string
+	}{
+		{content: "", cmdline: ""},
+		{content: "foo bar=baz\n", cmdline: "foo bar=baz"},
+		{content: "  panic=-1 quoted=\"a b\"\n\n", cmdline: `panic=-1 quoted="a b"`},
+		{content: "foo\nbar\n", err: "invalid kernel command line in cmdline.extra: unexpected newline"},
+		{content: "foo=\"bar\n", err: "invalid kernel command line in cmdline.extra: unbalanced quoting"},
+		{content: "foo snapd_recovery_mode=recover", err: `invalid kernel command line in cmdline.extra: disallowed argument "snapd_recovery_mode=recover"`},
+	} {
+		err := ioutil.WriteFile(filepath.Join(s.dir, "cmdline.extra"), []byte(tc.content), 0644)
+		c.Assert(err, IsNil)
+		cmdline, err := gadget.KernelCommandLineFromGadget(s.dir)
+		if tc.err != "" {
+			c.Check(err, ErrorMatches, tc.err, Commentf("content: %q", tc.content))
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("content: %q", tc.content))
+		c.Check(cmdline, Equals, tc.cmdline)
+	}
+}
@@ -55,6 +55,22 @@ func deviceFromRole(lv *gadget.LaidOutVolume, role string) (device string, err e
 // missing ones or recreating installed ones.
 func Run(model gadget.Model, gadgetRoot, kernelRoot, device string, options Options, observer gadget.ContentObserver) (*InstalledSystemSideData, error) {
 	logger.Noticef("installing a new system")
+	return run(model, gadgetRoot, kernelRoot, device, options, observer, nil)
+}
+
+// FactoryReset bootstraps the partitions of a device like Run, except
+// that an existing ubuntu-save partition and its content are kept.
+func FactoryReset(model gadget.Model, gadgetRoot, kernelRoot, device string, options Options, observer gadget.ContentObserver) (*InstalledSystemSideData, error) {
+	logger.Noticef("resetting the system to factory state")
+	if options.Encrypt {
+		// the key of an encrypted ubuntu-save is gone together
+		// with ubuntu-data
+		return nil, fmt.Errorf("cannot factory reset an encrypted system")
+	}
+	return run(model, gadgetRoot, kernelRoot, device, options, observer, []string{gadget.SystemSave})
+}
+
+func run(model gadget.Model, gadgetRoot, kernelRoot, device string, options Options, observer gadget.ContentObserver, keepRoles []string) (*InstalledSystemSideData, error) {
 	logger.Noticef("        gadget data from: %v", gadgetRoot)
 	if options.Encrypt {
 		logger.Noticef("        encryption: on")
@@ -92,7 +108,7 @@ func Run(model gadget.Model, gadgetRoot, kernelRoot, device string, options Opti
 	}
 
 	// remove partitions added during a previous install attempt
-	if err := removeCreatedPartitions(lv, diskLayout); err != nil {
+	if err := removeCreatedPartitions(lv, diskLayout, keepRoles...); err != nil {
 		return nil, fmt.Errorf("cannot remove partitions from previous install: %v", err)
 	}
 	// at this point we removed any existing partition, nuke any
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/osutil/inotify"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -181,11 +183,17 @@ func deviceName(name string, index int) string {
 	return fmt.Sprintf("%s%d", name, index)
 }
 
-// removeCreatedPartitions removes partitions added during a previous install.
-func removeCreatedPartitions(lv *gadget.LaidOutVolume, dl *gadget.OnDiskVolume) error {
+// removeCreatedPartitions removes partitions added during a previous install,
+// except for the ones with the given roles.
+func removeCreatedPartitions(lv *gadget.LaidOutVolume, dl *gadget.OnDiskVolume, keepRoles ...string) error {
 	indexes := make([]string, 0, len(dl.Structure))
 	for i, s := range dl.Structure {
-		if wasCreatedDuringInstall(lv, s) {
+		role := createdDuringInstallRole(lv, s)
+		if strutil.ListContains(keepRoles, role) {
+			logger.Noticef("keeping partition %s with role %s", s.Node, role)
+			continue
+		}
+		if role != "" {
 			logger.Noticef("partition %s was created during previous install", s.Node)
 			indexes = append(indexes, strconv.Itoa(i+1))
 		}
@@ -212,7 +220,7 @@ func removeCreatedPartitions(lv *gadget.LaidOutVolume, dl *gadget.OnDiskVolume)
 	}
 
 	// Ensure all created partitions were removed
-	if remaining := createdDuringInstall(lv, dl); len(remaining) > 0 {
+	if remaining := createdDuringInstall(lv, dl, keepRoles...); len(remaining) > 0 {
 		return fmt.Errorf("cannot remove partitions: %s", strings.Join(remaining, ", "))
 	}
 
@@ -222,27 +230,54 @@ func removeCreatedPartitions(lv *gadget.LaidOutVolume, dl *gadget.OnDiskVolume)
 // ensureNodeExists makes sure the device nodes for all device structures are
 // available and notified to udev, within a specified amount of time.
 func ensureNodesExistImpl(dss []gadget.OnDiskStructure, timeout time.Duration) error {
-	t0 := time.Now()
+	deadline := time.After(timeout)
 	for _, ds := range dss {
-		found := false
-		for time.Since(t0) < timeout {
-			if osutil.FileExists(ds.Node) {
-				found = true
-				break
-			}
-			time.Sleep(100 * time.Millisecond)
+		found, err := waitForNode(ds.Node, deadline)
+		if err != nil {
+			return err
 		}
-		if found {
-			if err := udevTrigger(ds.Node); err != nil {
-				return err
-			}
-		} else {
+		if !found {
 			return fmt.Errorf("device %s not available", ds.Node)
 		}
+		if err := udevTrigger(ds.Node); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// waitForNode waits for the given device node to be created, returning
+// whether it appeared before the deadline.
+func waitForNode(node string, deadline <-chan time.Time) (bool, error) {
+	if osutil.FileExists(node) {
+		return true, nil
+	}
+	w, err := inotify.NewWatcher()
+	if err != nil {
+		return false, err
+	}
+	defer w.Close()
+	if err := w.AddWatch(filepath.Dir(node), inotify.InCreate|inotify.InMovedTo); err != nil {
+		return false, err
+	}
+	// the node may have been created before the watch was set up
+	if osutil.FileExists(node) {
+		return true, nil
+	}
+	for {
+		select {
+		case ev := <-w.Event:
+			if ev.Path == node {
+				return true, nil
+			}
+		case err := <-w.Error:
+			return false, err
+		case <-deadline:
+			return false, nil
+		}
+	}
+}
+
 // reloadPartitionTable instructs the kernel to re-read the partition
 // table of a given block device.
 func reloadPartitionTable(device string) error {
@@ -266,42 +301,43 @@ func udevTrigger(device string) error {
 	return nil
 }
 
-// wasCreatedDuringInstall returns if the OnDiskStructure was created during
-// install by referencing the gadget volume. A structure is only considered to
-// be created during install if it is a role that is created during install and
-// the start offsets match. We specifically don't look at anything on the
-// structure such as filesystem information since this may be incomplete due to
-// a failed installation, or due to the partial layout that is created by some
-// ARM tools (i.e. ptool and fastboot) when flashing images to internal MMC.
-func wasCreatedDuringInstall(lv *gadget.LaidOutVolume, s gadget.OnDiskStructure) bool {
+// createdDuringInstallRole returns the role of the OnDiskStructure if it was
+// created during install by referencing the gadget volume, and an empty string
+// otherwise. A structure is only considered to be created during install if it
+// is a role that is created during install and the start offsets match. We
+// specifically don't look at anything on the structure such as filesystem
+// information since this may be incomplete due to a failed installation, or
+// due to the partial layout that is created by some ARM tools (i.e. ptool and
+// fastboot) when flashing images to internal MMC.
+func createdDuringInstallRole(lv *gadget.LaidOutVolume, s gadget.OnDiskStructure) string {
 	// for a structure to have been created during install, it must be one of
 	// the system-boot, system-data, or system-save roles from the gadget, and
 	// as such the on disk structure must exist in the exact same location as
-	// the role from the gadget, so only return true if the provided structure
-	// has the exact same StartOffset as one of those roles
+	// the role from the gadget, so only return the role if the provided
+	// structure has the exact same StartOffset as one of those roles
 	for _, gs := range lv.LaidOutStructure {
-		// TODO: how to handle ubuntu-save here? maybe a higher level function
-		//       should decide whether to delete it or not?
+		// whether ubuntu-save gets deleted is decided by the callers
 		switch gs.Role {
 		case gadget.SystemSave, gadget.SystemData, gadget.SystemBoot:
 			// then it was created during install or is to be created during
 			// install, see if the offset matches the provided on disk structure
 			// has
 			if s.StartOffset == gs.StartOffset {
-				return true
+				return gs.Role
 			}
 		}
 	}
 
-	return false
+	return ""
 }
 
 // createdDuringInstall returns a list of partitions created during the
-// install process.
-func createdDuringInstall(lv *gadget.LaidOutVolume, layout *gadget.OnDiskVolume) (created []string) {
+// install process, except for the ones with the given roles.
+func createdDuringInstall(lv *gadget.LaidOutVolume, layout *gadget.OnDiskVolume, keepRoles ...string) (created []string) {
 	created = make([]string, 0, len(layout.Structure))
 	for _, s := range layout.Structure {
-		if wasCreatedDuringInstall(lv, s) {
+		role := createdDuringInstallRole(lv, s)
+		if role != "" && !strutil.ListContains(keepRoles, role) {
 			created = append(created, s.Node)
 		}
 	}
@@ -414,6 +414,30 @@ echo '{
 	})
 }
 
+func (s *partitionTestSuite) TestRemovePartitionsKeepRoles(c *C) {
+	cmdSfdisk := testutil.MockCommand(c, "sfdisk", makeSfdiskScript(scriptPartitionsBiosSeedData))
+	defer cmdSfdisk.Restore()
+
+	cmdLsblk := testutil.MockCommand(c, "lsblk", makeLsblkScript(scriptPartitionsBiosSeedData))
+	defer cmdLsblk.Restore()
+
+	dl, err := gadget.OnDiskVolumeFromDevice("node")
+	c.Assert(err, IsNil)
+
+	err = makeMockGadget(s.gadgetRoot, gadgetContent)
+	c.Assert(err, IsNil)
+	pv, err := mustLayOutVolumeFromGadget(c, s.gadgetRoot, "", uc20Mod)
+	c.Assert(err, IsNil)
+
+	// ubuntu-data is kept, nothing gets removed
+	err = install.RemoveCreatedPartitions(pv, dl, gadget.SystemData)
+	c.Assert(err, IsNil)
+
+	c.Assert(cmdSfdisk.Calls(), DeepEquals, [][]string{
+		{"sfdisk", "--json", "node"},
+	})
+}
+
 func (s *partitionTestSuite) TestRemovePartitionsError(c *C) {
 	cmdSfdisk := testutil.MockCommand(c, "sfdisk", makeSfdiskScript(scriptPartitionsBiosSeedData))
 	defer cmdSfdisk.Restore()
@@ -479,6 +503,24 @@ func (s *partitionTestSuite) TestEnsureNodesExistTimeout(c *C) {
 	c.Assert(cmdUdevadm.Calls(), HasLen, 0)
 }
 
+func (s *partitionTestSuite) TestEnsureNodesExistCreatedLater(c *C) {
+	cmdUdevadm := testutil.MockCommand(c, "udevadm", "")
+	defer cmdUdevadm.Restore()
+
+	node := filepath.Join(c.MkDir(), "node")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ioutil.WriteFile(node, nil, 0644)
+	}()
+
+	ds := []gadget.OnDiskStructure{{Node: node}}
+	err := install.EnsureNodesExist(ds, 10*time.Second)
+	c.Assert(err, IsNil)
+	c.Assert(cmdUdevadm.Calls(), DeepEquals, [][]string{
+		{"udevadm", "trigger", "--settle", node},
+	})
+}
+
 const gptGadgetContentWithSave = `volumes:
   pc:
     bootloader: grub
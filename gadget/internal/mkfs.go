@@ -34,8 +34,10 @@ type MkfsFunc func(imgFile, label, contentsRootDir string, deviceSize, sectorSiz
 
 var (
 	mkfsHandlers = map[string]MkfsFunc{
-		"vfat": mkfsVfat,
-		"ext4": mkfsExt4,
+		"vfat":  mkfsVfat,
+		"ext4":  mkfsExt4,
+		"btrfs": mkfsBtrfs,
+		"f2fs":  mkfsF2fs,
 	}
 )
 
@@ -100,13 +102,19 @@ func mkfsExt4(img, label, contentsRootDir string, deviceSize, sectorSize quantit
 	}
 	mkfsArgs = append(mkfsArgs, img)
 
+	return runAsRoot(mkfsArgs)
+}
+
+// runAsRoot runs the given command, through fakeroot if needed so that the
+// files it creates are owned by root.
+func runAsRoot(args []string) error {
 	var cmd *exec.Cmd
 	if os.Geteuid() != 0 {
 		// run through fakeroot so that files are owned by root
-		cmd = exec.Command("fakeroot", mkfsArgs...)
+		cmd = exec.Command("fakeroot", args...)
 	} else {
 		// no need to fake it if we're already root
-		cmd = exec.Command(mkfsArgs[0], mkfsArgs[1:]...)
+		cmd = exec.Command(args[0], args[1:]...)
 	}
 	out, err := cmd.CombinedOutput()
 	if err != nil {
@@ -115,6 +123,71 @@ func mkfsExt4(img, label, contentsRootDir string, deviceSize, sectorSize quantit
 	return nil
 }
 
+// mkfsBtrfs creates a Btrfs filesystem in given image file, with an optional
+// filesystem label, and populates it with the contents of provided root
+// directory.
+func mkfsBtrfs(img, label, contentsRootDir string, deviceSize, sectorSize quantity.Size) error {
+	mkfsArgs := []string{
+		"mkfs.btrfs",
+		// the device may carry a signature of a previous filesystem
+		"-f",
+	}
+	const size1GiB = 1 * quantity.SizeGiB
+	if deviceSize != 0 && deviceSize < size1GiB {
+		// mixing data and metadata block groups is recommended by
+		// btrfs-progs for filesystems smaller than 1GiB, otherwise a
+		// good share of the space is lost to preallocated metadata
+		mkfsArgs = append(mkfsArgs, "--mixed")
+	}
+	if contentsRootDir != "" {
+		// mkfs.btrfs can populate the filesystem with contents of given
+		// root directory
+		mkfsArgs = append(mkfsArgs, "--rootdir", contentsRootDir)
+	}
+	if label != "" {
+		mkfsArgs = append(mkfsArgs, "-L", label)
+	}
+	mkfsArgs = append(mkfsArgs, img)
+
+	return runAsRoot(mkfsArgs)
+}
+
+// mkfsF2fs creates a F2FS filesystem in given image file, with an optional
+// filesystem label, and populates it with the contents of provided root
+// directory.
+func mkfsF2fs(img, label, contentsRootDir string, deviceSize, sectorSize quantity.Size) error {
+	mkfsArgs := []string{
+		"mkfs.f2fs",
+		// the device may carry a signature of a previous filesystem
+		"-f",
+		// checksum the superblock and inodes, extra_attr is required
+		// by inode_checksum
+		"-O", "extra_attr,inode_checksum,sb_checksum",
+	}
+	if label != "" {
+		mkfsArgs = append(mkfsArgs, "-l", label)
+	}
+	mkfsArgs = append(mkfsArgs, img)
+
+	cmd := exec.Command(mkfsArgs[0], mkfsArgs[1:]...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return osutil.OutputErr(out, err)
+	}
+
+	// if there is no content to copy we are done now
+	if contentsRootDir == "" {
+		return nil
+	}
+
+	// mkfs.f2fs does not know how to populate the filesystem with
+	// contents, use sload.f2fs for that
+	if err := runAsRoot([]string{"sload.f2fs", "-f", contentsRootDir, img}); err != nil {
+		return fmt.Errorf("cannot populate f2fs filesystem with contents: %v", err)
+	}
+	return nil
+}
+
 // mkfsVfat creates a VFAT filesystem in given image file, with an optional
 // filesystem label, and populates it with the contents of provided root
 // directory.
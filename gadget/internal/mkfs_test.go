@@ -57,6 +57,15 @@ func (m *mkfsSuite) SetUpTest(c *C) {
 
 	cmdMcopy := testutil.MockCommand(c, "mcopy", "echo 'override in test'; exit 1")
 	m.AddCleanup(cmdMcopy.Restore)
+
+	cmdMkfsBtrfs := testutil.MockCommand(c, "mkfs.btrfs", "echo 'override in test'; exit 1")
+	m.AddCleanup(cmdMkfsBtrfs.Restore)
+
+	cmdMkfsF2fs := testutil.MockCommand(c, "mkfs.f2fs", "echo 'override in test'; exit 1")
+	m.AddCleanup(cmdMkfsF2fs.Restore)
+
+	cmdSloadF2fs := testutil.MockCommand(c, "sload.f2fs", "echo 'override in test'; exit 1")
+	m.AddCleanup(cmdSloadF2fs.Restore)
 }
 
 func (m *mkfsSuite) TestMkfsExt4Happy(c *C) {
@@ -352,6 +361,137 @@ func (m *mkfsSuite) TestMkfsVfatHappyNoContents(c *C) {
 	c.Assert(cmdMcopy.Calls(), HasLen, 0)
 }
 
+func (m *mkfsSuite) TestMkfsBtrfsHappy(c *C) {
+	cmd := testutil.MockCommand(c, "fakeroot", "")
+	defer cmd.Restore()
+
+	err := internal.MkfsWithContent("btrfs", "foo.img", "my-label", "contents", 0, 0)
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{
+			"fakeroot",
+			"mkfs.btrfs",
+			"-f",
+			"--rootdir", "contents",
+			"-L", "my-label",
+			"foo.img",
+		},
+	})
+
+	cmd.ForgetCalls()
+
+	// no label, no content
+	err = internal.Mkfs("btrfs", "foo.img", "", 0, 0)
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{
+			"fakeroot",
+			"mkfs.btrfs",
+			"-f",
+			"foo.img",
+		},
+	})
+}
+
+func (m *mkfsSuite) TestMkfsBtrfsWithSize(c *C) {
+	cmd := testutil.MockCommand(c, "fakeroot", "")
+	defer cmd.Restore()
+
+	err := internal.Mkfs("btrfs", "foo.img", "my-label", 2*1024*1024*1024, 0)
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{
+			"fakeroot",
+			"mkfs.btrfs",
+			"-f",
+			"-L", "my-label",
+			"foo.img",
+		},
+	})
+
+	cmd.ForgetCalls()
+
+	// small filesystems use mixed block groups
+	err = internal.Mkfs("btrfs", "foo.img", "my-label", 512*1024*1024, 0)
+	c.Assert(err, IsNil)
+	c.Check(cmd.Calls(), DeepEquals, [][]string{
+		{
+			"fakeroot",
+			"mkfs.btrfs",
+			"-f",
+			"--mixed",
+			"-L", "my-label",
+			"foo.img",
+		},
+	})
+}
+
+func (m *mkfsSuite) TestMkfsBtrfsError(c *C) {
+	cmd := testutil.MockCommand(c, "fakeroot", "echo 'command failed'; exit 1")
+	defer cmd.Restore()
+
+	err := internal.MkfsWithContent("btrfs", "foo.img", "my-label", "contents", 0, 0)
+	c.Assert(err, ErrorMatches, "command failed")
+}
+
+func (m *mkfsSuite) TestMkfsF2fsHappy(c *C) {
+	cmdMkfs := testutil.MockCommand(c, "mkfs.f2fs", "")
+	defer cmdMkfs.Restore()
+
+	cmdFakeroot := testutil.MockCommand(c, "fakeroot", "")
+	defer cmdFakeroot.Restore()
+
+	err := internal.MkfsWithContent("f2fs", "foo.img", "my-label", "contents", 0, 0)
+	c.Assert(err, IsNil)
+	c.Check(cmdMkfs.Calls(), DeepEquals, [][]string{
+		{
+			"mkfs.f2fs",
+			"-f",
+			"-O", "extra_attr,inode_checksum,sb_checksum",
+			"-l", "my-label",
+			"foo.img",
+		},
+	})
+	c.Check(cmdFakeroot.Calls(), DeepEquals, [][]string{
+		{"fakeroot", "sload.f2fs", "-f", "contents", "foo.img"},
+	})
+
+	cmdMkfs.ForgetCalls()
+	cmdFakeroot.ForgetCalls()
+
+	// no label, no content
+	err = internal.Mkfs("f2fs", "foo.img", "", 0, 0)
+	c.Assert(err, IsNil)
+	c.Check(cmdMkfs.Calls(), DeepEquals, [][]string{
+		{
+			"mkfs.f2fs",
+			"-f",
+			"-O", "extra_attr,inode_checksum,sb_checksum",
+			"foo.img",
+		},
+	})
+	c.Check(cmdFakeroot.Calls(), HasLen, 0)
+}
+
+func (m *mkfsSuite) TestMkfsF2fsError(c *C) {
+	cmd := testutil.MockCommand(c, "mkfs.f2fs", "echo 'command failed'; exit 1")
+	defer cmd.Restore()
+
+	err := internal.MkfsWithContent("f2fs", "foo.img", "my-label", "contents", 0, 0)
+	c.Assert(err, ErrorMatches, "command failed")
+}
+
+func (m *mkfsSuite) TestMkfsF2fsErrorInSload(c *C) {
+	cmdMkfs := testutil.MockCommand(c, "mkfs.f2fs", "")
+	defer cmdMkfs.Restore()
+
+	cmdFakeroot := testutil.MockCommand(c, "fakeroot", "echo 'sload failed'; exit 1")
+	defer cmdFakeroot.Restore()
+
+	err := internal.MkfsWithContent("f2fs", "foo.img", "my-label", "contents", 0, 0)
+	c.Assert(err, ErrorMatches, "cannot populate f2fs filesystem with contents: sload failed")
+}
+
 func (m *mkfsSuite) TestMkfsInvalidFs(c *C) {
 	err := internal.MkfsWithContent("no-fs", "foo.img", "my-label", "", 0, 0)
 	c.Assert(err, ErrorMatches, `cannot create unsupported filesystem "no-fs"`)
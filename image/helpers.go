@@ -223,6 +223,9 @@ type DownloadOptions struct {
 	Basename  string
 
 	LeavePartialOnError bool
+	// Streams is the number of parallel ranged requests to use for
+	// large downloads.
+	Streams int
 }
 
 var (
@@ -341,7 +344,10 @@ func (tsto *ToolingStore) DownloadSnap(name string, opts DownloadOptions) (targe
 		os.Exit(1)
 	}()
 
-	dlOpts := &store.DownloadOptions{LeavePartialOnError: opts.LeavePartialOnError}
+	dlOpts := &store.DownloadOptions{
+		LeavePartialOnError: opts.LeavePartialOnError,
+		Streams:             opts.Streams,
+	}
 	if err = sto.Download(context.TODO(), name, targetFn, &snap.DownloadInfo, pb, tsto.user, dlOpts); err != nil {
 		return "", nil, "", err
 	}
@@ -240,8 +240,9 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options) error {
 	}
 
 	var gadgetUnpackDir, kernelUnpackDir string
-	// create directory for later unpacking the gadget in
-	if !opts.Classic {
+	// create directory for later unpacking the gadget in, classic
+	// models with modes have a gadget and kernel as well
+	if !opts.Classic || core20 {
 		gadgetUnpackDir = filepath.Join(opts.PrepareDir, "gadget")
 		kernelUnpackDir = filepath.Join(opts.PrepareDir, "kernel")
 		for _, unpackDir := range []string{gadgetUnpackDir, kernelUnpackDir} {
@@ -364,8 +365,7 @@ func setupSeed(tsto *ToolingStore, model *asserts.Model, opts *Options) error {
 		return err
 	}
 
-	if opts.Classic {
-		// TODO:UC20: consider Core 20 extended models vs classic
+	if opts.Classic && !core20 {
 		seedFn := filepath.Join(seedDir, "seed.yaml")
 		// warn about ownership if not root:root
 		fi, err := os.Stat(seedFn)
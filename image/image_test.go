@@ -2751,6 +2751,60 @@ func (s *imageSuite) TestSetupSeedCore20Grub(c *C) {
 	})
 }
 
+func (s *imageSuite) TestSetupSeedClassicWithModes(c *C) {
+	bootloader.Force(nil)
+	restore := image.MockTrusted(s.StoreSigning.Trusted)
+	defer restore()
+
+	// a classic model with modes
+	model := s.makeUC20Model(map[string]interface{}{
+		"classic":      "true",
+		"distribution": "ubuntu",
+	})
+
+	prepareDir := c.MkDir()
+
+	s.makeSnap(c, "snapd", nil, snap.R(1), "")
+	s.makeSnap(c, "core20", nil, snap.R(20), "")
+	s.makeSnap(c, "pc-kernel=20", nil, snap.R(1), "")
+	gadgetContent := [][]string{
+		{"grub-recovery.conf", "# recovery grub.cfg"},
+		{"grub.conf", "# boot grub.cfg"},
+		{"meta/gadget.yaml", pcUC20GadgetYaml},
+	}
+	s.makeSnap(c, "pc=20", gadgetContent, snap.R(22), "")
+	s.makeSnap(c, "required20", nil, snap.R(21), "other")
+
+	opts := &image.Options{
+		PrepareDir: prepareDir,
+		Classic:    true,
+	}
+
+	err := image.SetupSeed(s.tsto, model, opts)
+	c.Assert(err, IsNil)
+
+	// the seed is a UC20 one, in system-seed
+	seeddir := filepath.Join(prepareDir, "system-seed")
+	essSnaps, runSnaps, _ := s.loadSeed(c, seeddir)
+	c.Check(essSnaps, HasLen, 4)
+	c.Check(runSnaps, HasLen, 1)
+	c.Check(filepath.Join(prepareDir, "var/lib/snapd/seed/seed.yaml"), testutil.FileAbsent)
+
+	// with the recovery bootloader setup
+	grubCfg := filepath.Join(seeddir, "EFI/ubuntu/grub.cfg")
+	grubRecoveryCfgAsset := assets.Internal("grub-recovery.cfg")
+	c.Assert(grubRecoveryCfgAsset, NotNil)
+	c.Check(grubCfg, testutil.FileEquals, string(grubRecoveryCfgAsset))
+
+	systems, err := filepath.Glob(filepath.Join(seeddir, "systems", "*"))
+	c.Assert(err, IsNil)
+	c.Assert(systems, HasLen, 1)
+	seedGenv := grubenv.NewEnv(filepath.Join(seeddir, "EFI/ubuntu/grubenv"))
+	c.Assert(seedGenv.Load(), IsNil)
+	c.Check(seedGenv.Get("snapd_recovery_system"), Equals, filepath.Base(systems[0]))
+	c.Check(seedGenv.Get("snapd_recovery_mode"), Equals, "install")
+}
+
 func (s *imageSuite) TestSetupSeedCore20UBoot(c *C) {
 	bootloader.Force(nil)
 	restore := image.MockTrusted(s.StoreSigning.Trusted)
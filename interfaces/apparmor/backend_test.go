@@ -2178,6 +2178,39 @@ func (s *backendSuite) TestHomeIxRule(c *C) {
 	}
 }
 
+func (s *backendSuite) TestPromptRule(c *C) {
+	restoreTemplate := apparmor.MockTemplate("template\n###SNIPPETS###\n")
+	defer restoreTemplate()
+	restore := apparmor_sandbox.MockLevel(apparmor_sandbox.Full)
+	defer restore()
+	restore = apparmor.MockIsHomeUsingNFS(func() (bool, error) { return false, nil })
+	defer restore()
+
+	for _, tc := range []struct {
+		enabled  bool
+		expected string
+	}{
+		{enabled: false, expected: "\nowner @{HOME}/needle rw,"},
+		{enabled: true, expected: "\nprompt owner @{HOME}/needle rw,"},
+	} {
+		restore := apparmor.MockPromptingEnabled(s.Backend.(*apparmor.Backend), tc.enabled)
+		s.Iface.AppArmorPermanentSlotCallback = func(spec *apparmor.Specification, slot *snap.SlotInfo) error {
+			spec.AddSnippet("###PROMPT###owner @{HOME}/needle rw,")
+			return nil
+		}
+
+		snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 1)
+		profile := filepath.Join(dirs.SnapAppArmorDir, "snap.samba.smbd")
+		data, err := ioutil.ReadFile(profile)
+		c.Assert(err, IsNil)
+
+		c.Check(string(data), testutil.Contains, tc.expected)
+		c.Check(string(data), Not(testutil.Contains), "###PROMPT###")
+		s.RemoveSnap(c, snapInfo)
+		restore()
+	}
+}
+
 func (s *backendSuite) TestSystemUsernamesPolicy(c *C) {
 	restoreTemplate := apparmor.MockTemplate("template\n###SNIPPETS###\n")
 	defer restoreTemplate()
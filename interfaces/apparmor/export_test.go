@@ -54,6 +54,16 @@ func MockIsRootWritableOverlay(new func() (string, error)) (restore func()) {
 	}
 }
 
+// MockPromptingEnabled mocks whether the backend was initialized with
+// prompting enabled and supported.
+func MockPromptingEnabled(b *Backend, enabled bool) (restore func()) {
+	old := b.prompting
+	b.prompting = enabled
+	return func() {
+		b.prompting = old
+	}
+}
+
 // MockProcSelfExe mocks the location of /proc/self/exe read by setupSnapConfineGeneratedPolicy.
 func MockProcSelfExe(symlink string) (restore func()) {
 	old := procSelfExe
@@ -70,6 +70,9 @@ type ConfinementOptions struct {
 type SecurityBackendOptions struct {
 	// Preseed flag is set when snapd runs in preseed mode.
 	Preseed bool
+	// Prompting flag is set when AppArmor prompting is enabled and
+	// supported.
+	Prompting bool
 }
 
 // SecurityBackend abstracts interactions between the interface system and the
@@ -27,6 +27,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/dbus"
 	"github.com/snapcore/snapd/interfaces/kmod"
 	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/polkit"
 	"github.com/snapcore/snapd/interfaces/seccomp"
 	"github.com/snapcore/snapd/interfaces/systemd"
 	"github.com/snapcore/snapd/interfaces/udev"
@@ -45,6 +46,7 @@ func backends() []interfaces.SecurityBackend {
 		&udev.Backend{},
 		&mount.Backend{},
 		&kmod.Backend{},
+		&polkit.Backend{},
 	}
 
 	// TODO use something like:
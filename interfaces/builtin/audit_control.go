@@ -0,0 +1,61 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const auditControlSummary = `allows managing the kernel audit system and reading its logs`
+
+const auditControlBaseDeclarationSlots = `
+  audit-control:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const auditControlConnectedPlugAppArmor = `
+# Description: Can manage the kernel audit system (eg, with auditctl) and
+# read the audit logs.
+
+# CAP_AUDIT_CONTROL required to enable and disable kernel auditing, change
+# auditing filter rules and retrieve the auditing status and rules per
+# 'man 7 capabilities'
+capability audit_control,
+
+# Allow reading the audit logs and configuration
+/var/log/audit/ r,
+/var/log/audit/** r,
+/etc/audit/ r,
+/etc/audit/** r,
+
+# Allow reading and setting the login uid and session of processes
+@{PROC}/@{pid}/loginuid rw,
+@{PROC}/@{pid}/sessionid r,
+`
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "audit-control",
+		summary:               auditControlSummary,
+		implicitOnCore:        true,
+		implicitOnClassic:     true,
+		baseDeclarationSlots:  auditControlBaseDeclarationSlots,
+		connectedPlugAppArmor: auditControlConnectedPlugAppArmor,
+	})
+}
@@ -19,6 +19,15 @@
 
 package builtin
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/snap"
+)
+
 // Only allow raw disk devices; not loop, ram, CDROM, generic SCSI, network,
 // tape, raid, etc devices or disk partitions. For some devices, allow controller
 // character devices since they are used to configure the corresponding block
@@ -47,7 +56,7 @@ const blockDevicesBaseDeclarationSlots = `
 // /dev/pf[0-3] rw,                         # Parallel port ATAPI
 // /dev/ub[a-z] rw,                         # USB block device
 const blockDevicesConnectedPlugAppArmor = `
-# Description: Allow write access to raw disk block devices.
+# Description: Allow ###ACCESS### access to raw disk block devices.
 
 @{PROC}/devices r,
 /run/udev/data/b[0-9]*:[0-9]* r,
@@ -55,13 +64,13 @@ const blockDevicesConnectedPlugAppArmor = `
 /sys/devices/**/block/** r,
 
 # Access to raw devices, not individual partitions
-/dev/hd[a-t] rw,                                          # IDE, MFM, RLL
-/dev/sd{,[a-h]}[a-z] rw,                                  # SCSI
-/dev/sdi[a-v] rw,                                         # SCSI continued
-/dev/i2o/hd{,[a-c]}[a-z] rw,                              # I2O hard disk
-/dev/i2o/hdd[a-x] rw,                                     # I2O hard disk continued
-/dev/mmcblk[0-9]{,[0-9],[0-9][0-9]} rw,                   # MMC (up to 1000 devices)
-/dev/vd[a-z] rw,                                          # virtio
+/dev/hd[a-t] ###PERMS###,                                           # IDE, MFM, RLL
+/dev/sd{,[a-h]}[a-z] ###PERMS###,                                   # SCSI
+/dev/sdi[a-v] ###PERMS###,                                          # SCSI continued
+/dev/i2o/hd{,[a-c]}[a-z] ###PERMS###,                               # I2O hard disk
+/dev/i2o/hdd[a-x] ###PERMS###,                                      # I2O hard disk continued
+/dev/mmcblk[0-9]{,[0-9],[0-9][0-9]} ###PERMS###,                    # MMC (up to 1000 devices)
+/dev/vd[a-z] ###PERMS###,                                           # virtio
 
 # Allow /dev/nvmeXnY namespace block devices. Please note this grants access to all
 # NVMe namespace block devices and that the numeric suffix on the character device
@@ -74,23 +83,26 @@ const blockDevicesConnectedPlugAppArmor = `
 #   controller's identifier. Do not assume any particular device relationship
 #   based on their names. If you do, you may irrevocably erase data on an
 #   unintended device.
-/dev/nvme{[0-9],[1-9][0-9]}n{[1-9],[1-5][0-9],6[0-3]} rw, # NVMe (up to 100 devices, with 1-63 namespaces)
+/dev/nvme{[0-9],[1-9][0-9]}n{[1-9],[1-5][0-9],6[0-3]} ###PERMS###,  # NVMe (up to 100 devices, with 1-63 namespaces)
 
 # Allow /dev/nvmeX controller character devices. These character devices allow
 # manipulation of the block devices that we also allow above, so grouping this
 # access here makes sense, whereas access to individual partitions is delegated
 # to the raw-volume interface.
-/dev/nvme{[0-9],[1-9][0-9]} rw,                           # NVMe (up to 100 devices)
+/dev/nvme{[0-9],[1-9][0-9]} ###PERMS###,                            # NVMe (up to 100 devices)
 
-# SCSI device commands, et al
+# SCSI device commands, et al, including the ones used to read the SMART data
+# of the disks
 capability sys_rawio,
 
+# Devices for various controllers used with ioctl()
+/dev/mpt2ctl{,_wd} ###PERMS###,
+/dev/megaraid_sas_ioctl_node ###PERMS###,
+`
+
+const blockDevicesConnectedPlugAppArmorWrite = `
 # Perform various privileged block-device ioctl operations
 capability sys_admin,
-
-# Devices for various controllers used with ioctl()
-/dev/mpt2ctl{,_wd} rw,
-/dev/megaraid_sas_ioctl_node rw,
 `
 
 var blockDevicesConnectedPlugUDev = []string{
@@ -107,15 +119,43 @@ type blockDevicesInterface struct {
 	commonInterface
 }
 
+// BeforePreparePlug checks the plug attributes, the "write" attribute is
+// optional but must be a boolean if defined.
+func (iface *blockDevicesInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	if w, ok := plug.Attrs["write"]; ok {
+		if _, ok := w.(bool); !ok {
+			return fmt.Errorf(`block-devices "write" attribute must be a boolean`)
+		}
+	}
+	return nil
+}
+
+func (iface *blockDevicesInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	var write bool
+	_ = plug.Attr("write", &write)
+
+	// the devices are only readable unless 'write: true' is specified
+	access, perms := "read", "r"
+	if write {
+		access, perms = "write", "rw"
+	}
+	snippet := strings.Replace(blockDevicesConnectedPlugAppArmor, "###ACCESS###", access, -1)
+	snippet = strings.Replace(snippet, "###PERMS###", perms, -1)
+	spec.AddSnippet(snippet)
+	if write {
+		spec.AddSnippet(blockDevicesConnectedPlugAppArmorWrite)
+	}
+	return nil
+}
+
 func init() {
 	registerIface(&blockDevicesInterface{commonInterface{
-		name:                  "block-devices",
-		summary:               blockDevicesSummary,
-		implicitOnCore:        true,
-		implicitOnClassic:     true,
-		baseDeclarationPlugs:  blockDevicesBaseDeclarationPlugs,
-		baseDeclarationSlots:  blockDevicesBaseDeclarationSlots,
-		connectedPlugAppArmor: blockDevicesConnectedPlugAppArmor,
-		connectedPlugUDev:     blockDevicesConnectedPlugUDev,
+		name:                 "block-devices",
+		summary:              blockDevicesSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationPlugs: blockDevicesBaseDeclarationPlugs,
+		baseDeclarationSlots: blockDevicesBaseDeclarationSlots,
+		connectedPlugUDev:    blockDevicesConnectedPlugUDev,
 	}})
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/builtin"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -80,12 +81,48 @@ func (s *blockDevicesInterfaceSuite) TestSanitizePlug(c *C) {
 	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
 }
 
+func (s *blockDevicesInterfaceSuite) TestSanitizePlugWriteNotBool(c *C) {
+	const mockSnapYaml = `name: consumer
+version: 0
+plugs:
+ block-devices:
+  write: "yes"
+`
+	info := snaptest.MockInfo(c, mockSnapYaml, nil)
+	plug := info.Plugs["block-devices"]
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, `block-devices "write" attribute must be a boolean`)
+}
+
 func (s *blockDevicesInterfaceSuite) TestAppArmorSpec(c *C) {
 	spec := &apparmor.Specification{}
 	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
 	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `# Description: Allow read access to raw disk block devices.`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `/dev/sd{,[a-h]}[a-z] r,`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `capability sys_rawio,`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), Not(testutil.Contains), ` rw,`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), Not(testutil.Contains), `capability sys_admin,`)
+}
+
+func (s *blockDevicesInterfaceSuite) TestAppArmorSpecWrite(c *C) {
+	const mockSnapYaml = `name: consumer
+version: 0
+plugs:
+ block-devices:
+  write: true
+apps:
+ app:
+  plugs: [block-devices]
+`
+	plug, _ := MockConnectedPlug(c, mockSnapYaml, nil, "block-devices")
+
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
 	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `# Description: Allow write access to raw disk block devices.`)
 	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `/dev/sd{,[a-h]}[a-z] rw,`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `/dev/nvme{[0-9],[1-9][0-9]} rw,`)
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, `capability sys_admin,`)
 }
 
 func (s *blockDevicesInterfaceSuite) TestUDevSpec(c *C) {
@@ -0,0 +1,69 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const cgroupDelegationSummary = `allows the services of the snap to manage their own cgroup subtree`
+
+const cgroupDelegationBaseDeclarationPlugs = `
+  cgroup-delegation:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const cgroupDelegationBaseDeclarationSlots = `
+  cgroup-delegation:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const cgroupDelegationConnectedPlugAppArmor = `
+# Description: Can manage the cgroup subtree delegated by systemd to the
+# services of the snap, eg. to create child cgroups for containers, enable
+# controllers for them and move processes into them. Only the unified
+# hierarchy (cgroup v2) supports safe delegation to unprivileged subtrees.
+
+/sys/fs/cgroup/ r,
+/sys/fs/cgroup/cgroup.controllers r,
+/sys/fs/cgroup/cgroup.subtree_control r,
+/sys/fs/cgroup/{,**/}snap.@{SNAP_INSTANCE_NAME}.*.service/ rw,
+/sys/fs/cgroup/{,**/}snap.@{SNAP_INSTANCE_NAME}.*.service/** rw,
+
+# Allow discovering the cgroup of processes
+@{PROC}/@{pid}/cgroup r,
+`
+
+// systemd only delegates the cgroup subtree of a service to the service when
+// Delegate= is set.
+const cgroupDelegationServiceSnippet = `Delegate=true`
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "cgroup-delegation",
+		summary:               cgroupDelegationSummary,
+		implicitOnCore:        true,
+		implicitOnClassic:     true,
+		baseDeclarationPlugs:  cgroupDelegationBaseDeclarationPlugs,
+		baseDeclarationSlots:  cgroupDelegationBaseDeclarationSlots,
+		connectedPlugAppArmor: cgroupDelegationConnectedPlugAppArmor,
+		serviceSnippets:       []string{cgroupDelegationServiceSnippet},
+	})
+}
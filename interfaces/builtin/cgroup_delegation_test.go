@@ -0,0 +1,100 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type CgroupDelegationInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&CgroupDelegationInterfaceSuite{
+	iface: builtin.MustInterface("cgroup-delegation"),
+})
+
+const cgroupDelegationConsumerYaml = `name: consumer
+version: 0
+apps:
+ app:
+  daemon: simple
+  plugs: [cgroup-delegation]
+`
+
+const cgroupDelegationCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  cgroup-delegation:
+`
+
+func (s *CgroupDelegationInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, cgroupDelegationConsumerYaml, nil, "cgroup-delegation")
+	s.slot, s.slotInfo = MockConnectedSlot(c, cgroupDelegationCoreYaml, nil, "cgroup-delegation")
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "cgroup-delegation")
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/sys/fs/cgroup/{,**/}snap.@{SNAP_INSTANCE_NAME}.*.service/** rw,\n")
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows the services of the snap to manage their own cgroup subtree`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "cgroup-delegation")
+	c.Assert(si.BaseDeclarationPlugs, testutil.Contains, "cgroup-delegation")
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
+
+func (s *CgroupDelegationInterfaceSuite) TestServicePermanentPlugSnippets(c *C) {
+	snips, err := interfaces.PermanentPlugServiceSnippets(s.iface, s.plugInfo)
+	c.Assert(err, IsNil)
+	c.Check(snips, DeepEquals, []string{"Delegate=true"})
+}
@@ -0,0 +1,69 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const desktopLaunchSummary = `allows snaps to identify and launch desktop applications in (or from) other snaps`
+
+const desktopLaunchBaseDeclarationPlugs = `
+  desktop-launch:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const desktopLaunchBaseDeclarationSlots = `
+  desktop-launch:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const desktopLaunchConnectedPlugAppArmor = `
+# Description: Can identify and launch other snaps.
+
+# Access to the desktop and icon files installed by snaps
+/var/lib/snapd/desktop/applications/{,*} r,
+/var/lib/snapd/desktop/icons/{,**} r,
+
+# Allow access to all snap metadata
+/snap/*/*/** r,
+
+#include <abstractions/dbus-session-strict>
+
+# Launch the applications through the privileged launcher of snap
+# userd, which only starts the applications of snaps
+dbus (send)
+    bus=session
+    path=/io/snapcraft/PrivilegedDesktopLauncher
+    interface=io.snapcraft.PrivilegedDesktopLauncher
+    member=OpenDesktopEntry
+    peer=(label=unconfined),
+`
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "desktop-launch",
+		summary:               desktopLaunchSummary,
+		implicitOnClassic:     true,
+		baseDeclarationPlugs:  desktopLaunchBaseDeclarationPlugs,
+		baseDeclarationSlots:  desktopLaunchBaseDeclarationSlots,
+		connectedPlugAppArmor: desktopLaunchConnectedPlugAppArmor,
+	})
+}
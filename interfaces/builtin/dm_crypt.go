@@ -0,0 +1,97 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const dmCryptSummary = `allows encryption and decryption of block storage devices`
+
+const dmCryptBaseDeclarationPlugs = `
+  dm-crypt:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const dmCryptBaseDeclarationSlots = `
+  dm-crypt:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+// Access to the encrypted disks themselves is not granted here, it is expected
+// to come from the block-devices or raw-volume interfaces.
+const dmCryptConnectedPlugAppArmor = `
+# Description: Allow setting up and tearing down dm-crypt/LUKS volumes.
+
+# Allow use of cryptsetup from the base snap
+/{,usr/}sbin/cryptsetup ixr,
+
+# Device mapper control and the mapped devices
+/dev/mapper/ r,
+/dev/mapper/control rw,
+/dev/mapper/* rw,
+/dev/dm-[0-9]* rw,
+/sys/devices/virtual/block/dm-[0-9]*/{,**} r,
+/run/udev/data/b253:[0-9]* r,
+
+# cryptsetup locks the devices while operating on them
+/run/cryptsetup/ rw,
+/run/cryptsetup/* rwk,
+
+# Kernel crypto API, used for the PBKDF benchmarks and the LUKS2 keyslots
+network alg seqpacket,
+
+# Required for the device mapper ioctls
+capability sys_admin,
+
+# Required for locking the memory holding the volume key
+capability ipc_lock,
+`
+
+const dmCryptConnectedPlugSecComp = `
+# Description: Allow setting up and tearing down dm-crypt/LUKS volumes, the
+# volume keys are passed via the kernel keyring.
+add_key
+keyctl
+request_key
+
+# Kernel crypto API, used for the PBKDF benchmarks and the LUKS2 keyslots
+bind
+accept
+`
+
+var dmCryptConnectedPlugUDev = []string{
+	`KERNEL=="device-mapper"`,
+	`KERNEL=="dm-[0-9]*"`,
+}
+
+func init() {
+	registerIface(&commonInterface{
+		name:                  "dm-crypt",
+		summary:               dmCryptSummary,
+		implicitOnCore:        true,
+		implicitOnClassic:     true,
+		baseDeclarationPlugs:  dmCryptBaseDeclarationPlugs,
+		baseDeclarationSlots:  dmCryptBaseDeclarationSlots,
+		connectedPlugAppArmor: dmCryptConnectedPlugAppArmor,
+		connectedPlugSecComp:  dmCryptConnectedPlugSecComp,
+		connectedPlugUDev:     dmCryptConnectedPlugUDev,
+	})
+}
@@ -0,0 +1,171 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/snap"
+)
+
+const kernelModuleLoadSummary = `allows constrained control over kernel module loading`
+
+const kernelModuleLoadBaseDeclarationPlugs = `
+  kernel-module-load:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const kernelModuleLoadBaseDeclarationSlots = `
+  kernel-module-load:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+var (
+	kernelModuleNameRegexp   = regexp.MustCompile(`^[-a-zA-Z0-9_]+$`)
+	kernelModuleOptionRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+(=[-a-zA-Z0-9_.,:/]*)?$`)
+)
+
+// kernelModuleLoadInterface lets snaps declare the kernel modules which snapd
+// loads on their behalf, possibly with options, or prevents from being loaded
+// automatically. Unlike kernel-module-control it does not allow the snap to
+// load modules by itself.
+type kernelModuleLoadInterface struct {
+	commonInterface
+}
+
+type kernelModuleLoadEntry struct {
+	name    string
+	load    string
+	options string
+}
+
+func kernelModuleLoadEntries(attrs interfaces.Attrer) ([]*kernelModuleLoadEntry, error) {
+	var modules []interface{}
+	if err := attrs.Attr("modules", &modules); err != nil {
+		return nil, fmt.Errorf(`kernel-module-load "modules" attribute must be a list of modules`)
+	}
+	if len(modules) == 0 {
+		return nil, fmt.Errorf(`kernel-module-load "modules" attribute must list at least one module`)
+	}
+
+	seen := make(map[string]bool, len(modules))
+	entries := make([]*kernelModuleLoadEntry, 0, len(modules))
+	for _, m := range modules {
+		raw, ok := m.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`kernel-module-load "modules" attribute must be a list of modules`)
+		}
+		entry := &kernelModuleLoadEntry{load: "on-boot"}
+		for key, value := range raw {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf(`kernel-module-load %q attribute must be a string`, key)
+			}
+			switch key {
+			case "name":
+				entry.name = s
+			case "load":
+				entry.load = s
+			case "options":
+				entry.options = s
+			default:
+				return nil, fmt.Errorf(`kernel-module-load has unknown attribute %q`, key)
+			}
+		}
+		if err := entry.validate(); err != nil {
+			return nil, err
+		}
+		if seen[entry.name] {
+			return nil, fmt.Errorf(`kernel-module-load lists module %q more than once`, entry.name)
+		}
+		seen[entry.name] = true
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (e *kernelModuleLoadEntry) validate() error {
+	if !kernelModuleNameRegexp.MatchString(e.name) {
+		return fmt.Errorf(`kernel-module-load "name" attribute is invalid: %q`, e.name)
+	}
+	switch e.load {
+	case "on-boot":
+	case "denied":
+		if e.options != "" {
+			return fmt.Errorf(`kernel-module-load "options" attribute cannot be used for denied module %q`, e.name)
+		}
+	default:
+		return fmt.Errorf(`kernel-module-load "load" attribute must be "on-boot" or "denied", not %q`, e.load)
+	}
+	for _, option := range strings.Fields(e.options) {
+		if !kernelModuleOptionRegexp.MatchString(option) {
+			return fmt.Errorf(`kernel-module-load option %q of module %q is invalid`, option, e.name)
+		}
+	}
+	return nil
+}
+
+func (iface *kernelModuleLoadInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	_, err := kernelModuleLoadEntries(plug)
+	return err
+}
+
+func (iface *kernelModuleLoadInterface) KModConnectedPlug(spec *kmod.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	entries, err := kernelModuleLoadEntries(plug)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.load == "denied" {
+			if err := spec.DisallowModule(e.name); err != nil {
+				return err
+			}
+			continue
+		}
+		if options := strings.Join(strings.Fields(e.options), " "); options != "" {
+			if err := spec.SetModuleOptions(e.name, options); err != nil {
+				return err
+			}
+		}
+		if err := spec.AddModule(e.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	registerIface(&kernelModuleLoadInterface{commonInterface{
+		name:                 "kernel-module-load",
+		summary:              kernelModuleLoadSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationPlugs: kernelModuleLoadBaseDeclarationPlugs,
+		baseDeclarationSlots: kernelModuleLoadBaseDeclarationSlots,
+	}})
+}
@@ -0,0 +1,143 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/kmod"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type kernelModuleLoadInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&kernelModuleLoadInterfaceSuite{
+	iface: builtin.MustInterface("kernel-module-load"),
+})
+
+const kernelModuleLoadConsumerYaml = `name: consumer
+version: 0
+plugs:
+ kmod:
+  interface: kernel-module-load
+  modules:
+  - name: forbidden
+    load: denied
+  - name: mymodule
+    options: p1=3   p2=true
+  - name: other_module
+    load: on-boot
+apps:
+ app:
+  plugs: [kmod]
+`
+
+const kernelModuleLoadCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  kernel-module-load:
+`
+
+func (s *kernelModuleLoadInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, kernelModuleLoadConsumerYaml, nil, "kmod")
+	s.slot, s.slotInfo = MockConnectedSlot(c, kernelModuleLoadCoreYaml, nil, "kernel-module-load")
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "kernel-module-load")
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestSanitizePlugErrors(c *C) {
+	for _, tc := range []struct {
+		modules string
+		errStr  string
+	}{
+		{"", `kernel-module-load "modules" attribute must be a list of modules`},
+		{"modules: []", `kernel-module-load "modules" attribute must list at least one module`},
+		{"modules: [foo]", `kernel-module-load "modules" attribute must be a list of modules`},
+		{"modules: [{load: on-boot}]", `kernel-module-load "name" attribute is invalid: ""`},
+		{"modules: [{name: my/module}]", `kernel-module-load "name" attribute is invalid: "my/module"`},
+		{"modules: [{name: [mymodule]}]", `kernel-module-load "name" attribute must be a string`},
+		{"modules: [{name: mymodule, unknown: foo}]", `kernel-module-load has unknown attribute "unknown"`},
+		{"modules: [{name: mymodule, load: dynamic}]", `kernel-module-load "load" attribute must be "on-boot" or "denied", not "dynamic"`},
+		{"modules: [{name: mymodule, load: denied, options: p1=1}]", `kernel-module-load "options" attribute cannot be used for denied module "mymodule"`},
+		{`modules: [{name: mymodule, options: "p1=1 p2=$(foo)"}]`, `kernel-module-load option "p2=\$\(foo\)" of module "mymodule" is invalid`},
+		{"modules: [{name: mymodule}, {name: mymodule, load: denied}]", `kernel-module-load lists module "mymodule" more than once`},
+	} {
+		yaml := "name: consumer\nversion: 0\nplugs:\n kmod:\n  interface: kernel-module-load\n  " + tc.modules + "\n"
+		info := snaptest.MockInfo(c, yaml, nil)
+		err := interfaces.BeforePreparePlug(s.iface, info.Plugs["kmod"])
+		c.Check(err, ErrorMatches, tc.errStr, Commentf("modules: %s", tc.modules))
+	}
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestKModSpec(c *C) {
+	spec := &kmod.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.Modules(), DeepEquals, map[string]bool{
+		"mymodule":     true,
+		"other_module": true,
+	})
+	c.Check(spec.ModuleOptions(), DeepEquals, map[string]string{
+		"mymodule": "p1=3 p2=true",
+	})
+	c.Check(spec.DisallowedModules(), DeepEquals, map[string]bool{
+		"forbidden": true,
+	})
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.SecurityTags(), HasLen, 0)
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows constrained control over kernel module loading`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "kernel-module-load")
+	c.Assert(si.BaseDeclarationPlugs, testutil.Contains, "kernel-module-load")
+}
+
+func (s *kernelModuleLoadInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
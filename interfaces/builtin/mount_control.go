@@ -0,0 +1,348 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+const mountControlSummary = `allows mounting and unmounting specific filesystems`
+
+const mountControlBaseDeclarationPlugs = `
+  mount-control:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const mountControlBaseDeclarationSlots = `
+  mount-control:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const mountControlConnectedPlugSecComp = `
+# Description: Allow mount and umount syscall access. No filtering here, as
+# the mount operations are mediated by AppArmor.
+mount
+umount
+umount2
+`
+
+const mountControlConnectedPlugAppArmor = `
+# Description: Allow mounting and unmounting the filesystems listed in the
+# "mount" attribute of the plug.
+
+# Required for mounts and unmounts
+capability sys_admin,
+`
+
+// mountControlAllowedOptions are the mount options a plug may request, others
+// like "remount" or "move" would allow to work around the listed mounts.
+var mountControlAllowedOptions = []string{
+	"async",
+	"atime",
+	"bind",
+	"diratime",
+	"dirsync",
+	"iversion",
+	"lazytime",
+	"noatime",
+	"nodev",
+	"nodiratime",
+	"noexec",
+	"noiversion",
+	"nolazytime",
+	"nomand",
+	"norelatime",
+	"nosuid",
+	"nostrictatime",
+	"relatime",
+	"ro",
+	"rw",
+	"strictatime",
+	"sync",
+}
+
+// mountControlDisallowedFSTypes are the filesystem types which give access to
+// kernel interfaces rather than storage, they cannot be mounted with this
+// interface.
+var mountControlDisallowedFSTypes = []string{
+	"bpf",
+	"cgroup",
+	"cgroup2",
+	"configfs",
+	"debugfs",
+	"devpts",
+	"devtmpfs",
+	"hugetlbfs",
+	"mqueue",
+	"overlay",
+	"proc",
+	"pstore",
+	"securityfs",
+	"sysfs",
+	"tracefs",
+}
+
+// mountControlWhereRegexp restricts the mount points to the locations used
+// for removable media and to the writable data directories of the snap.
+var (
+	mountControlWhatRegexp   = regexp.MustCompile(`^(none|/[^"@$]*)$`)
+	mountControlWhereRegexp  = regexp.MustCompile(`^(/media|/mnt|/run/media|\$SNAP_COMMON|\$SNAP_DATA)/[^"@$]+$`)
+	mountControlFSTypeRegexp = regexp.MustCompile(`^[a-z0-9.]+$`)
+)
+
+type mountControlInterface struct {
+	commonInterface
+}
+
+// mountControlEntry is one of the mounts listed in the "mount" attribute of
+// a mount-control plug.
+type mountControlEntry struct {
+	what    string
+	where   string
+	types   []string
+	options []string
+}
+
+func mountControlStringList(entry map[string]interface{}, name string) ([]string, error) {
+	raw, ok := entry[name]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`mount-control %q attribute must be a list of strings`, name)
+	}
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf(`mount-control %q attribute must be a list of strings`, name)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+func mountControlEntries(attrs interfaces.Attrer) ([]*mountControlEntry, error) {
+	var mounts []interface{}
+	if err := attrs.Attr("mount", &mounts); err != nil {
+		return nil, fmt.Errorf(`mount-control "mount" attribute must be a list of mounts`)
+	}
+	if len(mounts) == 0 {
+		return nil, fmt.Errorf(`mount-control "mount" attribute must list at least one mount`)
+	}
+
+	entries := make([]*mountControlEntry, 0, len(mounts))
+	for _, m := range mounts {
+		raw, ok := m.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`mount-control "mount" attribute must be a list of mounts`)
+		}
+		entry := &mountControlEntry{}
+		for _, field := range []struct {
+			name  string
+			value *string
+		}{{"what", &entry.what}, {"where", &entry.where}} {
+			v, ok := raw[field.name].(string)
+			if !ok || v == "" {
+				return nil, fmt.Errorf(`mount-control %q attribute must be a non-empty string`, field.name)
+			}
+			*field.value = v
+		}
+		var err error
+		if entry.types, err = mountControlStringList(raw, "type"); err != nil {
+			return nil, err
+		}
+		if entry.options, err = mountControlStringList(raw, "options"); err != nil {
+			return nil, err
+		}
+		if err := entry.validate(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (e *mountControlEntry) validate() error {
+	if !mountControlWhatRegexp.MatchString(e.what) || filepath.Clean(e.what) != e.what {
+		return fmt.Errorf(`mount-control "what" attribute is invalid: %q`, e.what)
+	}
+	if !mountControlWhereRegexp.MatchString(e.where) || filepath.Clean(e.where) != e.where {
+		return fmt.Errorf(`mount-control "where" attribute is invalid: %q`, e.where)
+	}
+	// the mounted device may be given as a pattern, but only the
+	// simple wildcards are supported
+	if strings.ContainsAny(e.what, "[]{}^\x00") {
+		return fmt.Errorf(`mount-control "what" attribute is invalid: %q`, e.what)
+	}
+	where := strings.TrimPrefix(strings.TrimPrefix(e.where, "$SNAP_COMMON"), "$SNAP_DATA")
+	if err := apparmor.ValidateNoAppArmorRegexp(where); err != nil {
+		return fmt.Errorf(`mount-control "where" attribute is invalid: %v`, err)
+	}
+
+	bind := false
+	for _, o := range e.options {
+		if !strutil.ListContains(mountControlAllowedOptions, o) {
+			return fmt.Errorf(`mount-control option %q is not allowed`, o)
+		}
+		if o == "bind" {
+			bind = true
+		}
+	}
+	if bind && len(e.types) > 0 {
+		return fmt.Errorf(`mount-control "type" attribute cannot be used with the "bind" option`)
+	}
+	if !bind && len(e.types) == 0 {
+		return fmt.Errorf(`mount-control "type" attribute must be set unless the "bind" option is used`)
+	}
+	for _, t := range e.types {
+		if !mountControlFSTypeRegexp.MatchString(t) {
+			return fmt.Errorf(`mount-control filesystem type is invalid: %q`, t)
+		}
+		if strutil.ListContains(mountControlDisallowedFSTypes, t) {
+			return fmt.Errorf(`mount-control forbids mounting filesystems of type %q`, t)
+		}
+	}
+	return nil
+}
+
+// appArmorWhere returns the mount point expressed as an AppArmor path.
+func (e *mountControlEntry) appArmorWhere() string {
+	// parallel-installs: SNAP_{DATA,COMMON} are remapped, need to use
+	// SNAP_NAME, for completeness allow SNAP_INSTANCE_NAME too
+	where := strings.Replace(e.where, "$SNAP_COMMON", "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/common", 1)
+	return strings.Replace(where, "$SNAP_DATA", "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/@{SNAP_REVISION}", 1)
+}
+
+// persistent returns whether the mount can be listed in the mount profile of
+// the snap, so that snap-update-ns performs it when the mount namespace is
+// set up. Mounts of devices given as a pattern, or whose filesystem type is
+// not known upfront, can only be performed by the snap itself.
+func (e *mountControlEntry) persistent() bool {
+	return !strings.ContainsAny(e.what, "*?") && len(e.types) <= 1
+}
+
+func (iface *mountControlInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	_, err := mountControlEntries(plug)
+	return err
+}
+
+func (iface *mountControlInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	entries, err := mountControlEntries(plug)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(mountControlConnectedPlugAppArmor)
+	for _, e := range entries {
+		where := e.appArmorWhere()
+		buf.WriteString("\nmount")
+		if len(e.types) > 0 {
+			fmt.Fprintf(&buf, " fstype=(%s)", strings.Join(e.types, ","))
+		}
+		if len(e.options) > 0 {
+			fmt.Fprintf(&buf, " options=(%s)", strings.Join(e.options, ","))
+		}
+		fmt.Fprintf(&buf, " \"%s\" -> \"%s{,/}\",\n", e.what, where)
+		fmt.Fprintf(&buf, "umount \"%s{,/}\",\n", where)
+	}
+	spec.AddSnippet(buf.String())
+
+	emit := spec.AddUpdateNSf
+	for i, e := range entries {
+		if !e.persistent() {
+			continue
+		}
+		where := plug.Snap().ExpandSnapVariables(e.where)
+		var rule bytes.Buffer
+		rule.WriteString("  mount")
+		if len(e.types) > 0 {
+			fmt.Fprintf(&rule, " fstype=(%s)", e.types[0])
+		}
+		if len(e.options) > 0 {
+			fmt.Fprintf(&rule, " options=(%s)", strings.Join(e.options, ", "))
+		}
+		fmt.Fprintf(&rule, " \"%s\" -> \"%s/\",\n", e.what, where)
+		emit("  # Mount %s (#%d)\n", plug.Ref(), i)
+		emit("%s", rule.String())
+		if strutil.ListContains(e.options, "bind") && strutil.ListContains(e.options, "ro") {
+			emit("  remount options=(bind, ro) \"%s/\",\n", where)
+		}
+		emit("  umount \"%s/\",\n", where)
+	}
+	return nil
+}
+
+func (iface *mountControlInterface) MountConnectedPlug(spec *mount.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	entries, err := mountControlEntries(plug)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.persistent() {
+			continue
+		}
+		// the device may be absent and the mount point is not
+		// created on behalf of the snap
+		options := append([]string{}, e.options...)
+		options = append(options, osutil.XSnapdIgnoreMissing())
+		entry := osutil.MountEntry{
+			Name:    e.what,
+			Dir:     plug.Snap().ExpandSnapVariables(e.where),
+			Options: options,
+		}
+		if len(e.types) > 0 {
+			entry.Type = e.types[0]
+		}
+		if err := spec.AddMountEntry(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	registerIface(&mountControlInterface{commonInterface{
+		name:                 "mount-control",
+		summary:              mountControlSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationPlugs: mountControlBaseDeclarationPlugs,
+		baseDeclarationSlots: mountControlBaseDeclarationSlots,
+		connectedPlugSecComp: mountControlConnectedPlugSecComp,
+	}})
+}
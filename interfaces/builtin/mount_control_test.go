@@ -0,0 +1,209 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/mount"
+	"github.com/snapcore/snapd/interfaces/seccomp"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type mountControlInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&mountControlInterfaceSuite{
+	iface: builtin.MustInterface("mount-control"),
+})
+
+const mountControlConsumerYaml = `name: consumer
+version: 0
+plugs:
+ mntctl:
+  interface: mount-control
+  mount:
+  - what: /dev/sd*
+    where: /media/backup
+    type: [ext4, xfs]
+    options: [rw, nosuid]
+  - what: /usr/share/doc
+    where: $SNAP_COMMON/doc
+    options: [bind, ro]
+apps:
+ app:
+  plugs: [mntctl]
+`
+
+const mountControlCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  mount-control:
+`
+
+func (s *mountControlInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, mountControlConsumerYaml, nil, "mntctl")
+	s.slot, s.slotInfo = MockConnectedSlot(c, mountControlCoreYaml, nil, "mount-control")
+}
+
+func (s *mountControlInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "mount-control")
+}
+
+func (s *mountControlInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *mountControlInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *mountControlInterfaceSuite) TestSanitizePlugErrors(c *C) {
+	for _, tc := range []struct {
+		mount  string
+		errStr string
+	}{
+		{"", `mount-control "mount" attribute must be a list of mounts`},
+		{"mount: []", `mount-control "mount" attribute must list at least one mount`},
+		{"mount: foo", `mount-control "mount" attribute must be a list of mounts`},
+		{"mount: [foo]", `mount-control "mount" attribute must be a list of mounts`},
+		{"mount: [{where: /media/foo, type: [ext4]}]", `mount-control "what" attribute must be a non-empty string`},
+		{"mount: [{what: /dev/sda, type: [ext4]}]", `mount-control "where" attribute must be a non-empty string`},
+		{"mount: [{what: dev/sda, where: /media/foo, type: [ext4]}]", `mount-control "what" attribute is invalid: "dev/sda"`},
+		{"mount: [{what: /dev/sda, where: /media/../foo, type: [ext4]}]", `mount-control "where" attribute is invalid: "/media/../foo"`},
+		{`mount: [{what: /dev/sda, where: "$HOME/foo", type: [ext4]}]`, `mount-control "where" attribute is invalid: "\$HOME/foo"`},
+		{"mount: [{what: /dev/sda, where: /etc/foo, type: [ext4]}]", `mount-control "where" attribute is invalid: "/etc/foo"`},
+		{"mount: [{what: /dev/sda, where: /media, type: [ext4]}]", `mount-control "where" attribute is invalid: "/media"`},
+		{"mount: [{what: /dev/sda, where: /mediafoo/bar, type: [ext4]}]", `mount-control "where" attribute is invalid: "/mediafoo/bar"`},
+		{`mount: [{what: /dev/sda, where: "$SNAP/foo", type: [ext4]}]`, `mount-control "where" attribute is invalid: "\$SNAP/foo"`},
+		{"mount: [{what: /dev/sda, where: /media/foo*, type: [ext4]}]", `mount-control "where" attribute is invalid: .* contains a reserved apparmor char .*`},
+		{`mount: [{what: "/dev/sd[ab]", where: /media/foo, type: [ext4]}]`, `mount-control "what" attribute is invalid: "/dev/sd\[ab\]"`},
+		{"mount: [{what: /dev/sda, where: /media/foo}]", `mount-control "type" attribute must be set unless the "bind" option is used`},
+		{"mount: [{what: /dev/sda, where: /media/foo, type: ext4}]", `mount-control "type" attribute must be a list of strings`},
+		{"mount: [{what: /dev/sda, where: /media/foo, type: [ext4], options: [remount]}]", `mount-control option "remount" is not allowed`},
+		{"mount: [{what: /dev/sda, where: /media/foo, type: [ext4], options: [bind]}]", `mount-control "type" attribute cannot be used with the "bind" option`},
+		{"mount: [{what: none, where: /media/foo, type: [proc]}]", `mount-control forbids mounting filesystems of type "proc"`},
+		{`mount: [{what: none, where: /media/foo, type: ["ext4)"]}]`, `mount-control filesystem type is invalid: "ext4\)"`},
+	} {
+		yaml := "name: consumer\nversion: 0\nplugs:\n mntctl:\n  interface: mount-control\n  " + tc.mount + "\n"
+		info := snaptest.MockInfo(c, yaml, nil)
+		err := interfaces.BeforePreparePlug(s.iface, info.Plugs["mntctl"])
+		c.Check(err, ErrorMatches, tc.errStr, Commentf("mount: %s", tc.mount))
+	}
+}
+
+func (s *mountControlInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	snippet := spec.SnippetForTag("snap.consumer.app")
+	c.Check(snippet, testutil.Contains, "capability sys_admin,\n")
+	c.Check(snippet, testutil.Contains, `mount fstype=(ext4,xfs) options=(rw,nosuid) "/dev/sd*" -> "/media/backup{,/}",`+"\n")
+	c.Check(snippet, testutil.Contains, `umount "/media/backup{,/}",`+"\n")
+	c.Check(snippet, testutil.Contains, `mount options=(bind,ro) "/usr/share/doc" -> "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/common/doc{,/}",`+"\n")
+	c.Check(snippet, testutil.Contains, `umount "/var/snap/{@{SNAP_NAME},@{SNAP_INSTANCE_NAME}}/common/doc{,/}",`+"\n")
+
+	// only the mount of a known device is performed by snap-update-ns
+	c.Check(spec.UpdateNS(), DeepEquals, []string{
+		"  # Mount consumer:mntctl (#1)\n",
+		`  mount options=(bind, ro) "/usr/share/doc" -> "/var/snap/consumer/common/doc/",` + "\n",
+		`  remount options=(bind, ro) "/var/snap/consumer/common/doc/",` + "\n",
+		`  umount "/var/snap/consumer/common/doc/",` + "\n",
+	})
+}
+
+func (s *mountControlInterfaceSuite) TestMountSpec(c *C) {
+	spec := &mount.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+
+	entries := spec.MountEntries()
+	c.Assert(entries, HasLen, 1)
+	c.Check(entries[0].Name, Equals, "/usr/share/doc")
+	c.Check(entries[0].Dir, Equals, "/var/snap/consumer/common/doc")
+	c.Check(entries[0].Type, Equals, "")
+	c.Check(entries[0].Options, DeepEquals, []string{"bind", "ro", "x-snapd.ignore-missing"})
+	c.Check(spec.UserMountEntries(), HasLen, 0)
+}
+
+func (s *mountControlInterfaceSuite) TestMountSpecDevice(c *C) {
+	const yaml = `name: consumer
+version: 0
+plugs:
+ mntctl:
+  interface: mount-control
+  mount:
+  - what: /dev/sdb1
+    where: $SNAP_DATA/backup
+    type: [ext4]
+    options: [rw]
+apps:
+ app:
+  plugs: [mntctl]
+`
+	plug, _ := MockConnectedPlug(c, yaml, &snap.SideInfo{Revision: snap.R(42)}, "mntctl")
+
+	spec := &mount.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	entries := spec.MountEntries()
+	c.Assert(entries, HasLen, 1)
+	c.Check(entries[0].Name, Equals, "/dev/sdb1")
+	c.Check(entries[0].Dir, Equals, "/var/snap/consumer/42/backup")
+	c.Check(entries[0].Type, Equals, "ext4")
+	c.Check(entries[0].Options, DeepEquals, []string{"rw", "x-snapd.ignore-missing"})
+
+	aaSpec := &apparmor.Specification{}
+	c.Assert(aaSpec.AddConnectedPlug(s.iface, plug, s.slot), IsNil)
+	c.Check(aaSpec.UpdateNS(), DeepEquals, []string{
+		"  # Mount consumer:mntctl (#0)\n",
+		`  mount fstype=(ext4) options=(rw) "/dev/sdb1" -> "/var/snap/consumer/42/backup/",` + "\n",
+		`  umount "/var/snap/consumer/42/backup/",` + "\n",
+	})
+}
+
+func (s *mountControlInterfaceSuite) TestSecCompSpec(c *C) {
+	spec := &seccomp.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "\nmount\n")
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "\numount2\n")
+}
+
+func (s *mountControlInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows mounting and unmounting specific filesystems`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "mount-control")
+	c.Assert(si.BaseDeclarationPlugs, testutil.Contains, "mount-control")
+}
+
+func (s *mountControlInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package builtin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/snap"
+)
+
+const polkitSummary = `allows installing polkit actions and checking authorizations for them`
+
+const polkitBaseDeclarationPlugs = `
+  polkit:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const polkitBaseDeclarationSlots = `
+  polkit:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+const polkitConnectedPlugAppArmor = `
+# Description: Can check authorizations for the polkit actions installed
+# for the snap.
+#include <abstractions/dbus-strict>
+
+dbus (send)
+    bus=system
+    path=/org/freedesktop/PolicyKit1/Authority
+    interface=org.freedesktop.PolicyKit1.Authority
+    member={CheckAuthorization,CancelCheckAuthorization}
+    peer=(label=unconfined),
+
+dbus (send)
+    bus=system
+    path=/org/freedesktop/PolicyKit1/Authority
+    interface=org.freedesktop.DBus.Properties
+    member=Get{,All}
+    peer=(label=unconfined),
+`
+
+// polkitPolicyDir is the directory of the snap with the polkit policies
+// installed for the polkit plugs. Only policies declaring actions are
+// supported, polkit rules are arbitrary code and cannot be restricted to
+// the actions of the snap.
+const polkitPolicyDir = "meta/polkit"
+
+// polkitReservedNamespaces are the namespaces of the actions of the system,
+// snaps cannot install actions in them, nor claim an action-prefix covering
+// them.
+var polkitReservedNamespaces = []string{
+	"com.canonical",
+	"com.ubuntu",
+	"io.snapcraft",
+	"org.debian",
+	"org.freedesktop",
+	"org.gnome",
+	"org.kde",
+}
+
+type polkitInterface struct {
+	commonInterface
+}
+
+func (iface *polkitInterface) BeforePreparePlug(plug *snap.PlugInfo) error {
+	_, err := polkitActionPrefix(plug)
+	return err
+}
+
+func polkitActionPrefix(attrs interfaces.Attrer) (string, error) {
+	var prefix string
+	if err := attrs.Attr("action-prefix", &prefix); err != nil || prefix == "" {
+		return "", fmt.Errorf("snap must have an action-prefix attribute for the polkit plug")
+	}
+	// action ids follow the same rules as D-Bus names
+	if err := interfaces.ValidateDBusBusName(prefix); err != nil {
+		return "", fmt.Errorf("polkit plug has invalid action-prefix: %v", err)
+	}
+	for _, ns := range polkitReservedNamespaces {
+		if prefix == ns || strings.HasPrefix(prefix, ns+".") || strings.HasPrefix(ns, prefix+".") {
+			return "", fmt.Errorf("polkit plug cannot use reserved action-prefix %q", prefix)
+		}
+	}
+	return prefix, nil
+}
+
+func (iface *polkitInterface) PolkitConnectedPlug(spec *polkit.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	prefix, err := polkitActionPrefix(plug)
+	if err != nil {
+		return err
+	}
+
+	policyFile := filepath.Join(plug.Snap().MountDir(), polkitPolicyDir, plug.Name()+".policy")
+	content, err := ioutil.ReadFile(policyFile)
+	if err != nil {
+		return fmt.Errorf("cannot read polkit policy of plug %q: %v", plug.Name(), err)
+	}
+	if _, err := polkit.ValidatePolicy(bytes.NewReader(content), prefix); err != nil {
+		return fmt.Errorf("cannot use polkit policy of plug %q: %v", plug.Name(), err)
+	}
+	return spec.AddPolicy(plug.Name(), polkit.Policy(content))
+}
+
+func init() {
+	registerIface(&polkitInterface{commonInterface{
+		name:                  "polkit",
+		summary:               polkitSummary,
+		implicitOnClassic:     true,
+		baseDeclarationPlugs:  polkitBaseDeclarationPlugs,
+		baseDeclarationSlots:  polkitBaseDeclarationSlots,
+		connectedPlugAppArmor: polkitConnectedPlugAppArmor,
+	}})
+}
@@ -0,0 +1,179 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package builtin_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type polkitInterfaceSuite struct {
+	testutil.BaseTest
+
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&polkitInterfaceSuite{
+	iface: builtin.MustInterface("polkit"),
+})
+
+const polkitConsumerYaml = `name: consumer
+version: 0
+plugs:
+ polkit:
+  action-prefix: org.example.foo
+apps:
+ app:
+  plugs: [polkit]
+`
+
+const polkitCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  polkit:
+`
+
+const polkitTestPolicy = `<policyconfig>
+  <action id="org.example.foo.manage">
+    <defaults>
+      <allow_active>auth_admin</allow_active>
+    </defaults>
+  </action>
+</policyconfig>
+`
+
+func (s *polkitInterfaceSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.plug, s.plugInfo = MockConnectedPlug(c, polkitConsumerYaml, &snap.SideInfo{Revision: snap.R(1)}, "polkit")
+	s.slot, s.slotInfo = MockConnectedSlot(c, polkitCoreYaml, nil, "polkit")
+}
+
+func (s *polkitInterfaceSuite) mockPolicy(c *C, content string) {
+	policyDir := filepath.Join(s.plugInfo.Snap.MountDir(), "meta/polkit")
+	c.Assert(os.MkdirAll(policyDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(policyDir, "polkit.policy"), []byte(content), 0644), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "polkit")
+}
+
+func (s *polkitInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *polkitInterfaceSuite) TestSanitizePlugUnhappy(c *C) {
+	const mockSnapYaml = `name: consumer
+version: 0
+plugs:
+ polkit:
+  $t
+apps:
+ app:
+  plugs: [polkit]
+`
+	for _, t := range []struct {
+		attr string
+		err  string
+	}{
+		{"foo: bar", `snap must have an action-prefix attribute for the polkit plug`},
+		{"action-prefix: [foo]", `snap must have an action-prefix attribute for the polkit plug`},
+		{"action-prefix: foo", `polkit plug has invalid action-prefix: invalid DBus bus name: "foo"`},
+		{"action-prefix: org.example..foo", `polkit plug has invalid action-prefix: invalid DBus bus name: "org.example..foo"`},
+		{"action-prefix: org.freedesktop", `polkit plug cannot use reserved action-prefix "org.freedesktop"`},
+		{"action-prefix: org.freedesktop.policykit", `polkit plug cannot use reserved action-prefix "org.freedesktop.policykit"`},
+		{"action-prefix: io.snapcraft.snapd", `polkit plug cannot use reserved action-prefix "io.snapcraft.snapd"`},
+		{"action-prefix: com.ubuntu.foo", `polkit plug cannot use reserved action-prefix "com.ubuntu.foo"`},
+	} {
+		info := snaptest.MockInfo(c, strings.Replace(mockSnapYaml, "$t", t.attr, -1), nil)
+		plug := info.Plugs["polkit"]
+		c.Check(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, t.err, Commentf(t.attr))
+	}
+}
+
+func (s *polkitInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
+	c.Check(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "interface=org.freedesktop.PolicyKit1.Authority\n")
+}
+
+func (s *polkitInterfaceSuite) TestPolkitSpec(c *C) {
+	s.mockPolicy(c, polkitTestPolicy)
+
+	spec := &polkit.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.Policies(), DeepEquals, map[string]polkit.Policy{
+		"polkit": polkit.Policy(polkitTestPolicy),
+	})
+}
+
+func (s *polkitInterfaceSuite) TestPolkitSpecMissingPolicy(c *C) {
+	spec := &polkit.Specification{}
+	err := spec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Check(err, ErrorMatches, `cannot read polkit policy of plug "polkit": open .*/meta/polkit/polkit.policy: no such file or directory`)
+}
+
+func (s *polkitInterfaceSuite) TestPolkitSpecInvalidPolicy(c *C) {
+	s.mockPolicy(c, `<policyconfig><action id="org.other.manage"/></policyconfig>`)
+
+	spec := &polkit.Specification{}
+	err := spec.AddConnectedPlug(s.iface, s.plug, s.slot)
+	c.Check(err, ErrorMatches, `cannot use polkit policy of plug "polkit": polkit action "org.other.manage" does not use the prefix "org.example.foo"`)
+	c.Check(spec.Policies(), HasLen, 0)
+}
+
+func (s *polkitInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, false)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows installing polkit actions and checking authorizations for them`)
+	c.Assert(si.BaseDeclarationPlugs, testutil.Contains, "polkit")
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "polkit")
+}
+
+func (s *polkitInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/snap"
+)
+
+const pwmSummary = `allows access to specific PWM channel`
+
+const pwmBaseDeclarationSlots = `
+  pwm:
+    allow-installation:
+      slot-snap-type:
+        - core
+        - gadget
+    deny-auto-connection: true
+`
+
+var pwmSysfsPwmChipBase = "/sys/class/pwm/pwmchip"
+
+// pwmInterface type
+type pwmInterface struct{}
+
+// String returns the same value as Name().
+func (iface *pwmInterface) String() string {
+	return iface.Name()
+}
+
+// Name of the pwmInterface
+func (iface *pwmInterface) Name() string {
+	return "pwm"
+}
+
+func (iface *pwmInterface) StaticInfo() interfaces.StaticInfo {
+	return interfaces.StaticInfo{
+		Summary:              pwmSummary,
+		BaseDeclarationSlots: pwmBaseDeclarationSlots,
+	}
+}
+
+// BeforePrepareSlot checks the slot definition is valid
+func (iface *pwmInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
+	for _, attr := range []string{"chip-number", "channel"} {
+		value, ok := slot.Attrs[attr]
+		if !ok {
+			return fmt.Errorf("pwm slot must have a %s attribute", attr)
+		}
+		n, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("pwm slot %s attribute must be an int", attr)
+		}
+		if n < 0 {
+			return fmt.Errorf("pwm slot %s attribute must be a positive integer", attr)
+		}
+	}
+
+	// Slot is good
+	return nil
+}
+
+func pwmChipAndChannel(slot *interfaces.ConnectedSlot) (chipNumber, channel int64, err error) {
+	if err := slot.Attr("chip-number", &chipNumber); err != nil {
+		return 0, 0, err
+	}
+	if err := slot.Attr("channel", &channel); err != nil {
+		return 0, 0, err
+	}
+	return chipNumber, channel, nil
+}
+
+func (iface *pwmInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	chipNumber, channel, err := pwmChipAndChannel(slot)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprint(pwmSysfsPwmChipBase, chipNumber)
+	// Entries in /sys/class/pwm for PWM chips are just symlinks to their
+	// correct device part in the sysfs tree. Given AppArmor requires
+	// symlinks to be dereferenced, evaluate the PWM chip path and add the
+	// correct absolute path to the AppArmor snippet.
+	dereferencedPath, err := evalSymlinks(path)
+	if err != nil && os.IsNotExist(err) {
+		// If the specific pwm chip is not available there is no point
+		// exporting its channel, we should also not fail because this
+		// will block snapd updates
+		logger.Noticef("cannot export not existing pwm channel %s/pwm%d", path, channel)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	spec.AddSnippet(fmt.Sprintf("%s/pwm%d/* rwk,", dereferencedPath, channel))
+	return nil
+}
+
+func (iface *pwmInterface) SystemdConnectedSlot(spec *systemd.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	chipNumber, channel, err := pwmChipAndChannel(slot)
+	if err != nil {
+		return err
+	}
+
+	chipPath := fmt.Sprint(pwmSysfsPwmChipBase, chipNumber)
+	serviceName := interfaces.InterfaceServiceName(slot.Snap().InstanceName(), fmt.Sprintf("pwmchip%d-pwm%d", chipNumber, channel))
+	service := &systemd.Service{
+		Type:            "oneshot",
+		RemainAfterExit: true,
+		ExecStart:       fmt.Sprintf("/bin/sh -c 'test -e %s/pwm%d || echo %d > %s/export'", chipPath, channel, channel, chipPath),
+		ExecStop:        fmt.Sprintf("/bin/sh -c 'test ! -e %s/pwm%d || echo %d > %s/unexport'", chipPath, channel, channel, chipPath),
+	}
+	return spec.AddService(serviceName, service)
+}
+
+func (iface *pwmInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bool {
+	// allow what declarations allowed
+	return true
+}
+
+func init() {
+	registerIface(&pwmInterface{})
+}
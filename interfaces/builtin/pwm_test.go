@@ -0,0 +1,173 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	"os"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/systemd"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type PwmInterfaceSuite struct {
+	testutil.BaseTest
+
+	iface          interfaces.Interface
+	gadgetInfo     *snap.Info
+	gadgetSlotInfo *snap.SlotInfo
+	gadgetSlot     *interfaces.ConnectedSlot
+	gadgetPlugInfo *snap.PlugInfo
+	gadgetPlug     *interfaces.ConnectedPlug
+	osSlotInfo     *snap.SlotInfo
+}
+
+var _ = Suite(&PwmInterfaceSuite{
+	iface: builtin.MustInterface("pwm"),
+})
+
+func (s *PwmInterfaceSuite) SetUpTest(c *C) {
+	s.gadgetInfo = snaptest.MockInfo(c, `
+name: my-device
+version: 0
+type: gadget
+slots:
+    my-pwm:
+        interface: pwm
+        chip-number: 0
+        channel: 2
+    missing-channel:
+        interface: pwm
+        chip-number: 0
+    missing-chip-number:
+        interface: pwm
+        channel: 1
+    bad-channel:
+        interface: pwm
+        chip-number: 0
+        channel: two
+    negative-chip-number:
+        interface: pwm
+        chip-number: -1
+        channel: 1
+plugs:
+    plug: pwm
+apps:
+    svc:
+        command: bin/foo.sh
+`, nil)
+	s.gadgetSlotInfo = s.gadgetInfo.Slots["my-pwm"]
+	s.gadgetSlot = interfaces.NewConnectedSlot(s.gadgetSlotInfo, nil, nil)
+	s.gadgetPlugInfo = s.gadgetInfo.Plugs["plug"]
+	s.gadgetPlug = interfaces.NewConnectedPlug(s.gadgetPlugInfo, nil, nil)
+
+	osInfo := snaptest.MockInfo(c, `
+name: my-core
+version: 0
+type: os
+slots:
+    my-pwm:
+        interface: pwm
+        chip-number: 1
+        channel: 0
+`, nil)
+	s.osSlotInfo = osInfo.Slots["my-pwm"]
+}
+
+func (s *PwmInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "pwm")
+}
+
+func (s *PwmInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.gadgetSlotInfo), IsNil)
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.osSlotInfo), IsNil)
+
+	for slot, errStr := range map[string]string{
+		"missing-channel":      "pwm slot must have a channel attribute",
+		"missing-chip-number":  "pwm slot must have a chip-number attribute",
+		"bad-channel":          "pwm slot channel attribute must be an int",
+		"negative-chip-number": "pwm slot chip-number attribute must be a positive integer",
+	} {
+		c.Check(interfaces.BeforePrepareSlot(s.iface, s.gadgetInfo.Slots[slot]), ErrorMatches, errStr)
+	}
+}
+
+func (s *PwmInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.gadgetPlugInfo), IsNil)
+}
+
+func (s *PwmInterfaceSuite) TestSystemdConnectedSlot(c *C) {
+	spec := &systemd.Specification{}
+	err := spec.AddConnectedSlot(s.iface, s.gadgetPlug, s.gadgetSlot)
+	c.Assert(err, IsNil)
+	c.Assert(spec.Services(), DeepEquals, map[string]*systemd.Service{
+		"snap.my-device.interface.pwmchip0-pwm2.service": {
+			Type:            "oneshot",
+			RemainAfterExit: true,
+			ExecStart:       `/bin/sh -c 'test -e /sys/class/pwm/pwmchip0/pwm2 || echo 2 > /sys/class/pwm/pwmchip0/export'`,
+			ExecStop:        `/bin/sh -c 'test ! -e /sys/class/pwm/pwmchip0/pwm2 || echo 2 > /sys/class/pwm/pwmchip0/unexport'`,
+		},
+	})
+}
+
+func (s *PwmInterfaceSuite) TestApparmorConnectedPlugIgnoresMissingSymlink(c *C) {
+	log, restore := logger.MockLogger()
+	defer restore()
+
+	builtin.MockEvalSymlinks(&s.BaseTest, func(path string) (string, error) {
+		c.Assert(path, Equals, "/sys/class/pwm/pwmchip0")
+		return "", os.ErrNotExist
+	})
+
+	spec := &apparmor.Specification{}
+	err := spec.AddConnectedPlug(s.iface, s.gadgetPlug, s.gadgetSlot)
+	c.Assert(err, IsNil)
+	c.Assert(spec.Snippets(), HasLen, 0)
+	c.Assert(log.String(), testutil.Contains, "cannot export not existing pwm channel /sys/class/pwm/pwmchip0/pwm2")
+}
+
+func (s *PwmInterfaceSuite) TestApparmorConnectedPlug(c *C) {
+	builtin.MockEvalSymlinks(&s.BaseTest, func(path string) (string, error) {
+		c.Assert(path, Equals, "/sys/class/pwm/pwmchip0")
+		return "/sys/devices/platform/soc/fe20c000.pwm/pwm/pwmchip0", nil
+	})
+
+	spec := &apparmor.Specification{}
+	err := spec.AddConnectedPlug(s.iface, s.gadgetPlug, s.gadgetSlot)
+	c.Assert(err, IsNil)
+	c.Assert(spec.SnippetForTag("snap.my-device.svc"), testutil.Contains, `/sys/devices/platform/soc/fe20c000.pwm/pwm/pwmchip0/pwm2/* rwk,`)
+}
+
+func (s *PwmInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.Summary, Equals, `allows access to specific PWM channel`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "pwm")
+}
+
+func (s *PwmInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
@@ -21,12 +21,15 @@ package builtin
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/udev"
+	"github.com/snapcore/snapd/osutil/disks"
 	"github.com/snapcore/snapd/snap"
 )
 
@@ -117,14 +120,57 @@ var rawVolumePartitionPattern = regexp.MustCompile(fmt.Sprintf("^/dev/(%s|%s|%s|
 
 const invalidDeviceNodeSlotPathErrFmt = "slot %q path attribute must be a valid device node"
 
+// Structure names are used as the partition names of the GPT, which are
+// limited to 36 characters.
+var rawVolumeStructurePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,35}$`)
+
 // Check validity of the defined slot
 func (iface *rawVolumeInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
-	_, err := verifySlotPathAttribute(&interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name}, slot, rawVolumePartitionPattern, invalidDeviceNodeSlotPathErrFmt)
+	slotRef := &interfaces.SlotRef{Snap: slot.Snap.InstanceName(), Name: slot.Name}
+	if _, ok := slot.Attrs["structure"]; ok {
+		// the gadget can refer to one of the structures of its
+		// volumes instead of a device node
+		if _, ok := slot.Attrs["path"]; ok {
+			return fmt.Errorf("slot %q cannot have both path and structure attributes", slotRef)
+		}
+		if slot.Snap.Type() != snap.TypeGadget {
+			return fmt.Errorf("slot %q structure attribute can only be used by gadget snaps", slotRef)
+		}
+		var structure string
+		if err := slot.Attr("structure", &structure); err != nil || !rawVolumeStructurePattern.MatchString(structure) {
+			return fmt.Errorf("slot %q structure attribute must be a valid structure name", slotRef)
+		}
+		return nil
+	}
+	_, err := verifySlotPathAttribute(slotRef, slot, rawVolumePartitionPattern, invalidDeviceNodeSlotPathErrFmt)
 	return err
 }
 
+// slotDevice returns the device node of the partition of the slot, either
+// from its path attribute or by looking up the partition of the gadget
+// structure it refers to.
+func (iface *rawVolumeInterface) slotDevice(slot *interfaces.ConnectedSlot) (string, error) {
+	var structure string
+	if err := slot.Attr("structure", &structure); err != nil {
+		return verifySlotPathAttribute(slot.Ref(), slot, rawVolumePartitionPattern, invalidDeviceNodeSlotPathErrFmt)
+	}
+	byPartlabel := filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel/", disks.BlkIDEncodeLabel(structure))
+	target, err := filepath.EvalSymlinks(byPartlabel)
+	if err != nil {
+		return "", fmt.Errorf("cannot find partition of structure %q: %v", structure, err)
+	}
+	if !strings.HasPrefix(target, dirs.GlobalRootDir) {
+		return "", fmt.Errorf("cannot use partition %q of structure %q", target, structure)
+	}
+	devicePath := dirs.StripRootDir(target)
+	if !rawVolumePartitionPattern.MatchString(devicePath) {
+		return "", fmt.Errorf("cannot use partition %q of structure %q", devicePath, structure)
+	}
+	return devicePath, nil
+}
+
 func (iface *rawVolumeInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
-	cleanedPath, err := verifySlotPathAttribute(slot.Ref(), slot, rawVolumePartitionPattern, invalidDeviceNodeSlotPathErrFmt)
+	cleanedPath, err := iface.slotDevice(slot)
 	if err != nil {
 		return nil
 	}
@@ -135,7 +181,7 @@ func (iface *rawVolumeInterface) AppArmorConnectedPlug(spec *apparmor.Specificat
 }
 
 func (iface *rawVolumeInterface) UDevConnectedPlug(spec *udev.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
-	cleanedPath, err := verifySlotPathAttribute(slot.Ref(), slot, rawVolumePartitionPattern, invalidDeviceNodeSlotPathErrFmt)
+	cleanedPath, err := iface.slotDevice(slot)
 	if err != nil {
 		return nil
 	}
@@ -21,6 +21,9 @@ package builtin_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	. "gopkg.in/check.v1"
@@ -333,6 +336,117 @@ func (s *rawVolumeInterfaceSuite) TestAppArmorSpec(c *C) {
 	c.Assert(spec.SnippetForTag("snap.client-snap.app-accessing-3-part"), testutil.Contains, `capability sys_admin,`)
 }
 
+func (s *rawVolumeInterfaceSuite) TestSanitizeSlotStructure(c *C) {
+	const mockSnapYaml = `name: raw-volume-slot-snap
+type: gadget
+version: 1.0
+slots:
+  raw-volume:
+    structure: $t
+`
+
+	for _, name := range []string{"app-a", "app_b", "AppData.1", "a", "abcdefghijabcdefghijabcdefghijabcdef"} {
+		yml := strings.Replace(mockSnapYaml, "$t", name, -1)
+		info := snaptest.MockInfo(c, yml, nil)
+		slot := info.Slots["raw-volume"]
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slot), IsNil, Commentf("unexpected error for %q", name))
+	}
+
+	for _, name := range []string{`""`, "-app", "app/a", "../app", "abcdefghijabcdefghijabcdefghijabcdefg", "[app]"} {
+		yml := strings.Replace(mockSnapYaml, "$t", name, -1)
+		info := snaptest.MockInfo(c, yml, nil)
+		slot := info.Slots["raw-volume"]
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slot), ErrorMatches, `slot "raw-volume-slot-snap:raw-volume" structure attribute must be a valid structure name`, Commentf("unexpected success for %q", name))
+	}
+}
+
+func (s *rawVolumeInterfaceSuite) TestSanitizeSlotStructureUnhappy(c *C) {
+	info := snaptest.MockInfo(c, `name: raw-volume-slot-snap
+type: gadget
+version: 1.0
+slots:
+  raw-volume:
+    structure: app-a
+    path: /dev/vda1
+`, nil)
+	c.Check(interfaces.BeforePrepareSlot(s.iface, info.Slots["raw-volume"]), ErrorMatches, `slot "raw-volume-slot-snap:raw-volume" cannot have both path and structure attributes`)
+
+	info = snaptest.MockInfo(c, `name: core
+type: os
+version: 1.0
+slots:
+  raw-volume:
+    structure: app-a
+`, nil)
+	c.Check(interfaces.BeforePrepareSlot(s.iface, info.Slots["raw-volume"]), ErrorMatches, `slot "core:raw-volume" structure attribute can only be used by gadget snaps`)
+}
+
+func (s *rawVolumeInterfaceSuite) TestStructureSpecs(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	gadgetSnapInfo := snaptest.MockInfo(c, `
+name: some-device
+version: 0
+type: gadget
+slots:
+  app-a:
+    interface: raw-volume
+    structure: app-a
+`, nil)
+	slot := interfaces.NewConnectedSlot(gadgetSnapInfo.Slots["app-a"], nil, nil)
+
+	// the partition of the structure cannot be found
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.testPlugPart1, slot), IsNil)
+	c.Check(apparmorSpec.SecurityTags(), HasLen, 0)
+	udevSpec := &udev.Specification{}
+	c.Assert(udevSpec.AddConnectedPlug(s.iface, s.testPlugPart1, slot), IsNil)
+	c.Check(udevSpec.Snippets(), HasLen, 0)
+
+	byPartlabel := filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel")
+	c.Assert(os.MkdirAll(byPartlabel, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/dev/vda3"), nil, 0644), IsNil)
+	c.Assert(os.Symlink("../../vda3", filepath.Join(byPartlabel, "app-a")), IsNil)
+
+	apparmorSpec = &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.testPlugPart1, slot), IsNil)
+	c.Assert(apparmorSpec.SecurityTags(), DeepEquals, []string{"snap.client-snap.app-accessing-1-part"})
+	c.Check(apparmorSpec.SnippetForTag("snap.client-snap.app-accessing-1-part"), testutil.Contains, `/dev/vda3 rw,`)
+
+	udevSpec = &udev.Specification{}
+	c.Assert(udevSpec.AddConnectedPlug(s.iface, s.testPlugPart1, slot), IsNil)
+	c.Assert(udevSpec.Snippets(), HasLen, 2)
+	c.Check(udevSpec.Snippets()[0], Equals, `# raw-volume
+KERNEL=="vda3", TAG+="snap_client-snap_app-accessing-1-part"`)
+}
+
+func (s *rawVolumeInterfaceSuite) TestStructureNotAPartition(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("")
+
+	gadgetSnapInfo := snaptest.MockInfo(c, `
+name: some-device
+version: 0
+type: gadget
+slots:
+  app-a:
+    interface: raw-volume
+    structure: app-a
+`, nil)
+	slot := interfaces.NewConnectedSlot(gadgetSnapInfo.Slots["app-a"], nil, nil)
+
+	// the whole disk is never exposed
+	byPartlabel := filepath.Join(dirs.GlobalRootDir, "/dev/disk/by-partlabel")
+	c.Assert(os.MkdirAll(byPartlabel, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/dev/vda"), nil, 0644), IsNil)
+	c.Assert(os.Symlink("../../vda", filepath.Join(byPartlabel, "app-a")), IsNil)
+
+	apparmorSpec := &apparmor.Specification{}
+	c.Assert(apparmorSpec.AddConnectedPlug(s.iface, s.testPlugPart1, slot), IsNil)
+	c.Check(apparmorSpec.SecurityTags(), HasLen, 0)
+}
+
 func (s *rawVolumeInterfaceSuite) TestStaticInfo(c *C) {
 	si := interfaces.StaticInfoOf(s.iface)
 	c.Assert(si.ImplicitOnCore, Equals, false)
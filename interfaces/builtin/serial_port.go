@@ -80,6 +80,10 @@ var serialDeviceNodePattern = regexp.MustCompile("^/dev/tty(mxc|USB|ACM|AMA|XRUS
 // are also specified
 var serialUDevSymlinkPattern = regexp.MustCompile("^/dev/serial-port-[a-z0-9]+$")
 
+// Pattern of the persistent symlinks created by udev for USB serial devices,
+// which are set as the stable-path attribute of the hotplugged slots
+var serialStablePathPattern = regexp.MustCompile("^/dev/serial/by-id/[^/]+$")
+
 // BeforePrepareSlot checks validity of the defined slot
 func (iface *serialPortInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
 	// Check slot has a path attribute identify serial device
@@ -94,6 +98,12 @@ func (iface *serialPortInterface) BeforePrepareSlot(slot *snap.SlotInfo) error {
 	// performs additional verification.
 	path = filepath.Clean(path)
 
+	if stablePath, ok := slot.Attrs["stable-path"]; ok {
+		if p, ok := stablePath.(string); !ok || !serialStablePathPattern.MatchString(p) {
+			return fmt.Errorf("serial-port stable-path attribute must be a persistent device symlink")
+		}
+	}
+
 	if iface.hasUsbAttrs(slot) {
 		// Must be path attribute where symlink will be placed and usb vendor and product identifiers
 		// Check the path attribute is in the allowable pattern
@@ -227,6 +237,16 @@ func (iface *serialPortInterface) HotplugDeviceDetected(di *hotplug.HotplugDevic
 	if product, ok := di.Attribute("ID_MODEL_ID"); ok {
 		slot.Attrs["usb-product"] = product
 	}
+	// the device node may change when the device is plugged again, expose
+	// the symlink to it which is stable across replugs
+	if devlinks, ok := di.Attribute("DEVLINKS"); ok {
+		for _, link := range strings.Fields(devlinks) {
+			if serialStablePathPattern.MatchString(link) {
+				slot.Attrs["stable-path"] = link
+				break
+			}
+		}
+	}
 	return &slot, nil
 }
 
@@ -593,6 +593,41 @@ func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetected(c *C) {
 	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{"path": "/dev/ttyUSB0", "usb-vendor": "1234", "usb-product": "5678"}})
 }
 
+func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetectedStablePath(c *C) {
+	hotplugIface := s.iface.(hotplug.Definer)
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/ttyUSB0", "ID_VENDOR_ID": "1234", "ID_MODEL_ID": "5678", "ACTION": "add", "SUBSYSTEM": "tty", "ID_BUS": "usb",
+		"DEVLINKS": "/dev/serial/by-path/pci-0000:00:14.0-usb-0:2:1.0-port0 /dev/serial/by-id/usb-FTDI_FT232R_USB_UART_AH06W0EQ-if00-port0"})
+	c.Assert(err, IsNil)
+	proposedSlot, err := hotplugIface.HotplugDeviceDetected(di)
+	c.Assert(err, IsNil)
+	c.Assert(proposedSlot, DeepEquals, &hotplug.ProposedSlot{Attrs: map[string]interface{}{
+		"path":        "/dev/ttyUSB0",
+		"usb-vendor":  "1234",
+		"usb-product": "5678",
+		"stable-path": "/dev/serial/by-id/usb-FTDI_FT232R_USB_UART_AH06W0EQ-if00-port0",
+	}})
+
+	slotInfo := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "core", SnapType: snap.TypeOS},
+		Name:      "serial",
+		Interface: "serial-port",
+		Attrs:     proposedSlot.Attrs,
+	}
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), IsNil)
+}
+
+func (s *SerialPortInterfaceSuite) TestSanitizeSlotInvalidStablePath(c *C) {
+	for _, stablePath := range []interface{}{"/dev/ttyUSB0", "/dev/serial/by-id/../../ttyUSB0", "/dev/serial/by-path/pci-0000:00:14.0-usb-0:2:1.0-port0", 1} {
+		slotInfo := &snap.SlotInfo{
+			Snap:      &snap.Info{SuggestedName: "core", SnapType: snap.TypeOS},
+			Name:      "serial",
+			Interface: "serial-port",
+			Attrs:     map[string]interface{}{"path": "/dev/ttyUSB0", "stable-path": stablePath},
+		}
+		c.Check(interfaces.BeforePrepareSlot(s.iface, slotInfo), ErrorMatches, "serial-port stable-path attribute must be a persistent device symlink", Commentf("%v", stablePath))
+	}
+}
+
 func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetectedNotSerialPort(c *C) {
 	hotplugIface := s.iface.(hotplug.Definer)
 	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/sys/foo/bar", "DEVNAME": "/dev/other", "ID_VENDOR_ID": "1234", "ID_MODEL_ID": "5678", "ACTION": "add", "SUBSYSTEM": "tty", "ID_BUS": "usb"})
@@ -22,7 +22,10 @@ package builtin
 import (
 	"fmt"
 
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 )
 
 const snapdControlSummary = `allows communicating with snapd`
@@ -47,6 +50,28 @@ const snapdControlConnectedPlugAppArmor = `
 /run/snapd.socket rw,
 `
 
+// snapdControlScopes are the scopes a snapd-control plug can be limited to,
+// a scoped plug only grants access to the snapd API endpoints of its scopes
+// on the snapd-snap socket, instead of the whole API.
+var snapdControlScopes = []string{"refresh-schedule", "snapshots"}
+
+// snapdControlPlugScopes returns the scopes the plug is limited to, if any.
+func snapdControlPlugScopes(attrs interfaces.Attrer) ([]string, error) {
+	var raw []interface{}
+	if err := attrs.Attr("scopes", &raw); err != nil {
+		return nil, err
+	}
+	scopes := make([]string, 0, len(raw))
+	for _, s := range raw {
+		scope, ok := s.(string)
+		if !ok {
+			return nil, fmt.Errorf("snapd-control scope must be a string, not %T", s)
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
 type snapControlInterface struct {
 	commonInterface
 }
@@ -58,17 +83,38 @@ func (iface *snapControlInterface) BeforePreparePlug(plug *snap.PlugInfo) error
 		}
 	}
 
+	if _, ok := plug.Attrs["scopes"]; ok {
+		scopes, err := snapdControlPlugScopes(plug)
+		if err != nil || len(scopes) == 0 {
+			return fmt.Errorf(`snapd-control "scopes" attribute must be a non-empty list of strings`)
+		}
+		for _, scope := range scopes {
+			if !strutil.ListContains(snapdControlScopes, scope) {
+				return fmt.Errorf("unsupported snapd-control scope: %q", scope)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (iface *snapControlInterface) AppArmorConnectedPlug(spec *apparmor.Specification, plug *interfaces.ConnectedPlug, slot *interfaces.ConnectedSlot) error {
+	if scopes, err := snapdControlPlugScopes(plug); err == nil && len(scopes) > 0 {
+		// scoped plugs only talk to snapd on the snapd-snap socket,
+		// which is allowed by the base template
+		return nil
+	}
+	spec.AddSnippet(snapdControlConnectedPlugAppArmor)
 	return nil
 }
 
 func init() {
 	registerIface(&snapControlInterface{commonInterface{
-		name:                  "snapd-control",
-		summary:               snapdControlSummary,
-		implicitOnCore:        true,
-		implicitOnClassic:     true,
-		baseDeclarationPlugs:  snapdControlBaseDeclarationPlugs,
-		baseDeclarationSlots:  snapdControlBaseDeclarationSlots,
-		connectedPlugAppArmor: snapdControlConnectedPlugAppArmor,
+		name:                 "snapd-control",
+		summary:              snapdControlSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationPlugs: snapdControlBaseDeclarationPlugs,
+		baseDeclarationSlots: snapdControlBaseDeclarationSlots,
 	}})
 }
@@ -97,6 +97,53 @@ plugs:
 	c.Assert(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, `unsupported refresh-schedule value: "unsupported-value"`)
 }
 
+func (s *SnapdControlInterfaceSuite) TestSanitizePlugWithScopes(c *C) {
+	const mockSnapYaml = `name: snapd-manager
+version: 1.0
+plugs:
+ snapd-control:
+  scopes: [refresh-schedule, snapshots]
+`
+	info := snaptest.MockInfo(c, mockSnapYaml, nil)
+	plug := info.Plugs["snapd-control"]
+	c.Assert(interfaces.BeforePreparePlug(s.iface, plug), IsNil)
+}
+
+func (s *SnapdControlInterfaceSuite) TestSanitizePlugWithScopesNotHappy(c *C) {
+	for _, tc := range []struct {
+		scopes string
+		errStr string
+	}{
+		{"[]", `snapd-control "scopes" attribute must be a non-empty list of strings`},
+		{"snapshots", `snapd-control "scopes" attribute must be a non-empty list of strings`},
+		{"[1]", `snapd-control "scopes" attribute must be a non-empty list of strings`},
+		{"[snapshots, install]", `unsupported snapd-control scope: "install"`},
+	} {
+		mockSnapYaml := "name: snapd-manager\nversion: 1.0\nplugs:\n snapd-control:\n  scopes: " + tc.scopes + "\n"
+		info := snaptest.MockInfo(c, mockSnapYaml, nil)
+		plug := info.Plugs["snapd-control"]
+		c.Check(interfaces.BeforePreparePlug(s.iface, plug), ErrorMatches, tc.errStr, Commentf("scopes: %s", tc.scopes))
+	}
+}
+
+func (s *SnapdControlInterfaceSuite) TestScopedPlugNoSnapdSocketAccess(c *C) {
+	const mockSnapYaml = `name: snapd-manager
+version: 1.0
+plugs:
+ snapd-control:
+  scopes: [snapshots]
+apps:
+ app:
+  command: foo
+  plugs: [snapd-control]
+`
+	plug, _ := MockConnectedPlug(c, mockSnapYaml, nil, "snapd-control")
+	apparmorSpec := &apparmor.Specification{}
+	err := apparmorSpec.AddConnectedPlug(s.iface, plug, s.slot)
+	c.Assert(err, IsNil)
+	c.Assert(apparmorSpec.SecurityTags(), HasLen, 0)
+}
+
 func (s *SnapdControlInterfaceSuite) TestUsedSecuritySystems(c *C) {
 	// connected plugs have a non-nil security snippet for apparmor
 	apparmorSpec := &apparmor.Specification{}
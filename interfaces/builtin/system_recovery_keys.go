@@ -0,0 +1,50 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin
+
+const systemRecoveryKeysSummary = `allows reading the recovery keys of the system`
+
+const systemRecoveryKeysBaseDeclarationPlugs = `
+  system-recovery-keys:
+    allow-installation: false
+    deny-auto-connection: true
+`
+
+const systemRecoveryKeysBaseDeclarationSlots = `
+  system-recovery-keys:
+    allow-installation:
+      slot-snap-type:
+        - core
+    deny-auto-connection: true
+`
+
+func init() {
+	// the recovery keys are only readable through the snapd API, which
+	// checks for the connection of the interface itself, so no further
+	// rules are needed
+	registerIface(&commonInterface{
+		name:                 "system-recovery-keys",
+		summary:              systemRecoveryKeysSummary,
+		implicitOnCore:       true,
+		implicitOnClassic:    true,
+		baseDeclarationPlugs: systemRecoveryKeysBaseDeclarationPlugs,
+		baseDeclarationSlots: systemRecoveryKeysBaseDeclarationSlots,
+	})
+}
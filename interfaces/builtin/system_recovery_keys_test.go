@@ -0,0 +1,92 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package builtin_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type systemRecoveryKeysInterfaceSuite struct {
+	iface    interfaces.Interface
+	slotInfo *snap.SlotInfo
+	slot     *interfaces.ConnectedSlot
+	plugInfo *snap.PlugInfo
+	plug     *interfaces.ConnectedPlug
+}
+
+var _ = Suite(&systemRecoveryKeysInterfaceSuite{
+	iface: builtin.MustInterface("system-recovery-keys"),
+})
+
+const systemRecoveryKeysConsumerYaml = `name: consumer
+version: 0
+apps:
+ app:
+  plugs: [system-recovery-keys]
+`
+
+const systemRecoveryKeysCoreYaml = `name: core
+version: 0
+type: os
+slots:
+  system-recovery-keys:
+`
+
+func (s *systemRecoveryKeysInterfaceSuite) SetUpTest(c *C) {
+	s.plug, s.plugInfo = MockConnectedPlug(c, systemRecoveryKeysConsumerYaml, nil, "system-recovery-keys")
+	s.slot, s.slotInfo = MockConnectedSlot(c, systemRecoveryKeysCoreYaml, nil, "system-recovery-keys")
+}
+
+func (s *systemRecoveryKeysInterfaceSuite) TestName(c *C) {
+	c.Assert(s.iface.Name(), Equals, "system-recovery-keys")
+}
+
+func (s *systemRecoveryKeysInterfaceSuite) TestSanitizeSlot(c *C) {
+	c.Assert(interfaces.BeforePrepareSlot(s.iface, s.slotInfo), IsNil)
+}
+
+func (s *systemRecoveryKeysInterfaceSuite) TestSanitizePlug(c *C) {
+	c.Assert(interfaces.BeforePreparePlug(s.iface, s.plugInfo), IsNil)
+}
+
+func (s *systemRecoveryKeysInterfaceSuite) TestAppArmorSpec(c *C) {
+	spec := &apparmor.Specification{}
+	c.Assert(spec.AddConnectedPlug(s.iface, s.plug, s.slot), IsNil)
+	c.Check(spec.SecurityTags(), HasLen, 0)
+}
+
+func (s *systemRecoveryKeysInterfaceSuite) TestStaticInfo(c *C) {
+	si := interfaces.StaticInfoOf(s.iface)
+	c.Assert(si.ImplicitOnCore, Equals, true)
+	c.Assert(si.ImplicitOnClassic, Equals, true)
+	c.Assert(si.Summary, Equals, `allows reading the recovery keys of the system`)
+	c.Assert(si.BaseDeclarationSlots, testutil.Contains, "system-recovery-keys")
+	c.Assert(si.BaseDeclarationPlugs, testutil.Contains, "system-recovery-keys")
+}
+
+func (s *systemRecoveryKeysInterfaceSuite) TestInterfaces(c *C) {
+	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
+}
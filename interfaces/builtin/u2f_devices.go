@@ -132,6 +132,26 @@ var u2fDevices = []u2fDevice{
 		VendorIDPattern:  "2c97",
 		ProductIDPattern: "0000|0001|0004|0005|0015|1005|1015|4005|4015",
 	},
+	{
+		Name:             "Nitrokey FIDO2 + Nitrokey 3",
+		VendorIDPattern:  "20a0",
+		ProductIDPattern: "42b1|42b2",
+	},
+	{
+		Name:             "Google Titan v2",
+		VendorIDPattern:  "18d1",
+		ProductIDPattern: "9470",
+	},
+	{
+		Name:             "Trezor",
+		VendorIDPattern:  "534c",
+		ProductIDPattern: "0001",
+	},
+	{
+		Name:             "Trezor v2",
+		VendorIDPattern:  "1209",
+		ProductIDPattern: "53c1",
+	},
 }
 
 const u2fDevicesConnectedPlugAppArmor = `
@@ -50,6 +50,12 @@ const uinputConnectedPlugAppArmor = `
 
 /dev/uinput rw,
 /dev/input/uinput rw,
+
+# Allow finding the virtual input devices created through uinput (eg, with
+# libevdev_uinput_get_syspath())
+/sys/devices/virtual/input/ r,
+/sys/devices/virtual/input/input[0-9]*/ r,
+/sys/devices/virtual/input/input[0-9]*/** r,
 `
 
 // The uinput device allows for injecting arbitrary input, so its default
@@ -86,6 +86,7 @@ func (s *uinputInterfaceSuite) TestAppArmorSpec(c *C) {
 	c.Assert(spec.SecurityTags(), DeepEquals, []string{"snap.consumer.app"})
 	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "# Description: Allow write access to the uinput device for emulating")
 	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/dev/uinput rw,")
+	c.Assert(spec.SnippetForTag("snap.consumer.app"), testutil.Contains, "/sys/devices/virtual/input/input[0-9]*/** r,")
 }
 
 func (s *uinputInterfaceSuite) TestUDevSpec(c *C) {
@@ -26,9 +26,11 @@
 // kernel modules. The KMod backend stores all the modules needed by given
 // snap in /etc/modules-load.d/snap.<snapname>.conf file ensuring they are
 // loaded when the system boots and also loads these modules via modprobe.
+// The options of the modules and the modules which must not be loaded
+// automatically are stored in /etc/modprobe.d/snap.<snapname>.conf.
 // If a snap is uninstalled or respective interface gets disconnected, the
-// corresponding /etc/modules-load.d/ config file gets removed, however no
-// kernel modules are unloaded. This is by design.
+// corresponding /etc/modules-load.d/ and /etc/modprobe.d/ config files get
+// removed, however no kernel modules are unloaded. This is by design.
 //
 // Note: this mechanism should not be confused with kernel-module-interface;
 // kmod only loads a well-defined list of modules provided by interface definition
@@ -81,19 +83,26 @@ func (b *Backend) Setup(snapInfo *snap.Info, confinement interfaces.ConfinementO
 	}
 
 	content, modules := deriveContent(spec.(*Specification), snapInfo)
+	modprobeContent := deriveModprobeContent(spec.(*Specification), snapInfo)
 	// synchronize the content with the filesystem
 	glob := interfaces.SecurityTagGlob(snapName)
-	dir := dirs.SnapKModModulesDir
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("cannot create directory for kmod files %q: %s", dir, err)
+	for _, dir := range []string{dirs.SnapKModModulesDir, dirs.SnapKModModprobeDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("cannot create directory for kmod files %q: %s", dir, err)
+		}
 	}
 
+	// the options must be in place before the modules are loaded
+	modprobeChanged, _, err := osutil.EnsureDirState(dirs.SnapKModModprobeDir, glob, modprobeContent)
+	if err != nil {
+		return err
+	}
 	changed, _, err := osutil.EnsureDirState(dirs.SnapKModModulesDir, glob, content)
 	if err != nil {
 		return err
 	}
 
-	if len(changed) > 0 {
+	if len(changed) > 0 || len(modprobeChanged) > 0 {
 		b.loadModules(modules)
 	}
 	return nil
@@ -106,7 +115,10 @@ func (b *Backend) Setup(snapInfo *snap.Info, confinement interfaces.ConfinementO
 // If the method fails it should be re-tried (with a sensible strategy) by the caller.
 func (b *Backend) Remove(snapName string) error {
 	glob := interfaces.SecurityTagGlob(snapName)
-	_, _, err := osutil.EnsureDirState(dirs.SnapKModModulesDir, glob, nil)
+	if _, _, err := osutil.EnsureDirState(dirs.SnapKModModulesDir, glob, nil); err != nil {
+		return err
+	}
+	_, _, err := osutil.EnsureDirState(dirs.SnapKModModprobeDir, glob, nil)
 	return err
 }
 
@@ -134,6 +146,36 @@ func deriveContent(spec *Specification, snapInfo *snap.Info) (map[string]osutil.
 	return content, modules
 }
 
+func deriveModprobeContent(spec *Specification, snapInfo *snap.Info) map[string]osutil.FileState {
+	if len(spec.moduleOptions) == 0 && len(spec.disallowedModules) == 0 {
+		return nil
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("# This file is automatically generated.\n")
+	var modules []string
+	for k := range spec.disallowedModules {
+		modules = append(modules, k)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		fmt.Fprintf(&buffer, "blacklist %s\n", module)
+	}
+	modules = modules[:0]
+	for k := range spec.moduleOptions {
+		modules = append(modules, k)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		fmt.Fprintf(&buffer, "options %s %s\n", module, spec.moduleOptions[module])
+	}
+	return map[string]osutil.FileState{
+		fmt.Sprintf("%s.conf", snap.SecurityTag(snapInfo.InstanceName())): &osutil.MemoryFileState{
+			Content: buffer.Bytes(),
+			Mode:    0644,
+		},
+	}
+}
+
 func (b *Backend) NewSpecification() interfaces.Specification {
 	return &Specification{}
 }
@@ -118,6 +118,34 @@ func (s *backendSuite) TestRemovingSnapRemovesModulesConf(c *C) {
 	}
 }
 
+func (s *backendSuite) TestInstallingSnapCreatesModprobeConf(c *C) {
+	s.Iface.KModPermanentSlotCallback = func(spec *kmod.Specification, slot *snap.SlotInfo) error {
+		spec.AddModule("module1")
+		spec.SetModuleOptions("module1", "p1=1 p2")
+		spec.DisallowModule("module2")
+		return nil
+	}
+
+	modulesPath := filepath.Join(dirs.SnapKModModulesDir, "snap.samba.conf")
+	modprobePath := filepath.Join(dirs.SnapKModModprobeDir, "snap.samba.conf")
+	c.Assert(osutil.FileExists(modprobePath), Equals, false)
+
+	for _, opts := range testedConfinementOpts {
+		s.modprobeCmd.ForgetCalls()
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+
+		c.Assert(modulesPath, testutil.FileEquals, "# This file is automatically generated.\nmodule1\n")
+		c.Assert(modprobePath, testutil.FileEquals, "# This file is automatically generated.\nblacklist module2\noptions module1 p1=1 p2\n")
+		c.Assert(s.modprobeCmd.Calls(), DeepEquals, [][]string{
+			{"modprobe", "--syslog", "module1"},
+		})
+
+		s.RemoveSnap(c, snapInfo)
+		c.Assert(osutil.FileExists(modulesPath), Equals, false)
+		c.Assert(osutil.FileExists(modprobePath), Equals, false)
+	}
+}
+
 func (s *backendSuite) TestSecurityIsStable(c *C) {
 	// NOTE: Hand out a permanent snippet so that .conf file is generated.
 	s.Iface.KModPermanentSlotCallback = func(spec *kmod.Specification, slot *snap.SlotInfo) error {
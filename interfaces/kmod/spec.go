@@ -20,6 +20,7 @@
 package kmod
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/snapcore/snapd/interfaces"
@@ -33,6 +34,9 @@ import (
 // setup process.
 type Specification struct {
 	modules map[string]bool
+
+	moduleOptions     map[string]string
+	disallowedModules map[string]bool
 }
 
 // AddModule adds a kernel module, trimming spaces and ignoring duplicated modules.
@@ -57,6 +61,48 @@ func (spec *Specification) Modules() map[string]bool {
 	return result
 }
 
+// SetModuleOptions sets the options to use when loading the given kernel
+// module. Different options cannot be set for the same module.
+func (spec *Specification) SetModuleOptions(module, options string) error {
+	if spec.moduleOptions == nil {
+		spec.moduleOptions = make(map[string]string)
+	}
+	if old, ok := spec.moduleOptions[module]; ok && old != options {
+		return fmt.Errorf("cannot set options for kernel module %q to %q, already set to %q", module, options, old)
+	}
+	spec.moduleOptions[module] = options
+	return nil
+}
+
+// ModuleOptions returns a copy of the kernel module options, by module name.
+func (spec *Specification) ModuleOptions() map[string]string {
+	result := make(map[string]string, len(spec.moduleOptions))
+	for k, v := range spec.moduleOptions {
+		result[k] = v
+	}
+	return result
+}
+
+// DisallowModule prevents the given kernel module from being loaded
+// automatically.
+func (spec *Specification) DisallowModule(module string) error {
+	if spec.disallowedModules == nil {
+		spec.disallowedModules = make(map[string]bool)
+	}
+	spec.disallowedModules[module] = true
+	return nil
+}
+
+// DisallowedModules returns a copy of the names of the kernel modules which
+// are prevented from being loaded automatically.
+func (spec *Specification) DisallowedModules() map[string]bool {
+	result := make(map[string]bool, len(spec.disallowedModules))
+	for k, v := range spec.disallowedModules {
+		result[k] = v
+	}
+	return result
+}
+
 // Implementation of methods required by interfaces.Specification
 
 // AddConnectedPlug records kmod-specific side-effects of having a connected plug.
@@ -127,3 +127,20 @@ func (s *specSuite) TestSpecificationIface(c *C) {
 	c.Assert(s.spec.Modules(), DeepEquals, map[string]bool{
 		"module1": true, "module2": true, "module3": true, "module4": true})
 }
+
+func (s *specSuite) TestModuleOptions(c *C) {
+	c.Assert(s.spec.SetModuleOptions("module1", "p1=1 p2"), IsNil)
+	c.Assert(s.spec.SetModuleOptions("module2", "p3=3"), IsNil)
+	// setting the same options again is fine
+	c.Assert(s.spec.SetModuleOptions("module1", "p1=1 p2"), IsNil)
+	c.Assert(s.spec.SetModuleOptions("module1", "p1=2"), ErrorMatches,
+		`cannot set options for kernel module "module1" to "p1=2", already set to "p1=1 p2"`)
+	c.Assert(s.spec.ModuleOptions(), DeepEquals, map[string]string{
+		"module1": "p1=1 p2", "module2": "p3=3"})
+}
+
+func (s *specSuite) TestDisallowModule(c *C) {
+	c.Assert(s.spec.DisallowModule("module1"), IsNil)
+	c.Assert(s.spec.DisallowModule("module1"), IsNil)
+	c.Assert(s.spec.DisallowedModules(), DeepEquals, map[string]bool{"module1": true})
+}
@@ -624,6 +624,7 @@ var (
 		"power-control":           {"core"},
 		"ppp":                     {"core"},
 		"pulseaudio":              {"app", "core"},
+		"pwm":                     {"core", "gadget"},
 		"raw-volume":              {"core", "gadget"},
 		"serial-port":             {"core", "gadget"},
 		"spi":                     {"core", "gadget"},
@@ -702,17 +703,24 @@ func (s *baseDeclSuite) TestPlugInstallation(c *C) {
 	restricted := map[string]bool{
 		"block-devices":         true,
 		"classic-support":       true,
+		"cgroup-delegation":     true,
+		"desktop-launch":        true,
+		"dm-crypt":              true,
 		"docker-support":        true,
 		"greengrass-support":    true,
 		"gpio-control":          true,
 		"kernel-module-control": true,
+		"kernel-module-load":    true,
 		"kubernetes-support":    true,
 		"lxd-support":           true,
+		"mount-control":         true,
 		"multipass-support":     true,
 		"packagekit-control":    true,
 		"personal-files":        true,
+		"polkit":                true,
 		"snapd-control":         true,
 		"system-files":          true,
+		"system-recovery-keys":  true,
 		"tee":                   true,
 		"uinput":                true,
 		"unity8":                true,
@@ -933,19 +941,26 @@ func (s *baseDeclSuite) TestSanity(c *C) {
 	bothSides := map[string]bool{
 		"block-devices":         true,
 		"audio-playback":        true,
+		"cgroup-delegation":     true,
 		"classic-support":       true,
 		"core-support":          true,
+		"desktop-launch":        true,
+		"dm-crypt":              true,
 		"docker-support":        true,
 		"greengrass-support":    true,
 		"gpio-control":          true,
 		"kernel-module-control": true,
+		"kernel-module-load":    true,
 		"kubernetes-support":    true,
 		"lxd-support":           true,
+		"mount-control":         true,
 		"multipass-support":     true,
 		"packagekit-control":    true,
 		"personal-files":        true,
+		"polkit":                true,
 		"snapd-control":         true,
 		"system-files":          true,
+		"system-recovery-keys":  true,
 		"tee":                   true,
 		"udisks2":               true,
 		"uinput":                true,
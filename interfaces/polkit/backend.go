@@ -0,0 +1,127 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+// Package polkit implements interaction between snapd and polkit.
+//
+// Snapd installs polkit policy files on behalf of snaps that describe
+// administrative actions they may perform.  Polkit can then be used
+// by the snap to authorise those actions for its clients.
+package polkit
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/timings"
+)
+
+func polkitPolicyName(snapName, nameSuffix string) string {
+	return snap.ScopedSecurityTag(snapName, "interface", nameSuffix) + ".policy"
+}
+
+// Backend is responsible for maintaining polkit policy files.
+type Backend struct{}
+
+// Initialize does nothing.
+func (b *Backend) Initialize(*interfaces.SecurityBackendOptions) error {
+	return nil
+}
+
+// Name returns the name of the backend.
+func (b *Backend) Name() interfaces.SecuritySystem {
+	return interfaces.SecurityPolkit
+}
+
+// Setup installs the polkit policy files specific to a given snap.
+//
+// Polkit has no concept of a complain mode so confinement type is ignored.
+func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions, repo *interfaces.Repository, tm timings.Measurer) error {
+	snapName := snapInfo.InstanceName()
+	// Get the policies that apply to this snap
+	spec, err := repo.SnapSpecification(b.Name(), snapName)
+	if err != nil {
+		return fmt.Errorf("cannot obtain polkit specification for snap %q: %s", snapName, err)
+	}
+
+	// Get the files that this snap should have
+	content := deriveContent(spec.(*Specification), snapInfo)
+
+	glob := polkitPolicyName(snapName, "*")
+	dir := dirs.PolkitActionsDir
+	if len(content) == 0 {
+		// Make sure that the polkit actions directory is only
+		// created when needed
+		if !osutil.IsDirectory(dir) {
+			return nil
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory for polkit policy files %q: %s", dir, err)
+	}
+	_, _, err = osutil.EnsureDirState(dir, glob, content)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize polkit policy files for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// Remove removes polkit policy files of a given snap.
+//
+// This method should be called after removing a snap.
+func (b *Backend) Remove(snapName string) error {
+	glob := polkitPolicyName(snapName, "*")
+	if !osutil.IsDirectory(dirs.PolkitActionsDir) {
+		return nil
+	}
+	_, _, err := osutil.EnsureDirState(dirs.PolkitActionsDir, glob, nil)
+	if err != nil {
+		return fmt.Errorf("cannot synchronize polkit policy files for snap %q: %s", snapName, err)
+	}
+	return nil
+}
+
+// deriveContent combines the policies collected from all the interfaces
+// affecting a given snap into a content map applicable to EnsureDirState.
+func deriveContent(spec *Specification, snapInfo *snap.Info) map[string]osutil.FileState {
+	policies := spec.Policies()
+	if len(policies) == 0 {
+		return nil
+	}
+	content := make(map[string]osutil.FileState, len(policies))
+	for nameSuffix, policy := range policies {
+		filename := polkitPolicyName(snapInfo.InstanceName(), nameSuffix)
+		content[filename] = &osutil.MemoryFileState{
+			Content: policy,
+			Mode:    0644,
+		}
+	}
+	return content
+}
+
+// NewSpecification returns a new polkit specification.
+func (b *Backend) NewSpecification() interfaces.Specification {
+	return &Specification{}
+}
+
+// SandboxFeatures returns list of features supported by snapd for polkit.
+func (b *Backend) SandboxFeatures() []string {
+	return []string{"mediated-actions"}
+}
@@ -0,0 +1,123 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package polkit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/interfaces/polkit"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+func Test(t *testing.T) {
+	TestingT(t)
+}
+
+type backendSuite struct {
+	ifacetest.BackendSuite
+}
+
+var _ = Suite(&backendSuite{})
+
+var testedConfinementOpts = []interfaces.ConfinementOptions{
+	{},
+	{DevMode: true},
+	{JailMode: true},
+	{Classic: true},
+}
+
+func (s *backendSuite) SetUpTest(c *C) {
+	s.Backend = &polkit.Backend{}
+	s.BackendSuite.SetUpTest(c)
+	c.Assert(s.Repo.AddBackend(s.Backend), IsNil)
+}
+
+func (s *backendSuite) TearDownTest(c *C) {
+	s.BackendSuite.TearDownTest(c)
+}
+
+func (s *backendSuite) TestName(c *C) {
+	c.Check(s.Backend.Name(), Equals, interfaces.SecurityPolkit)
+}
+
+func (s *backendSuite) TestInstallingSnapWritesPolicyFiles(c *C) {
+	// NOTE: Hand out a permanent policy so that .policy file is generated.
+	s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+		return spec.AddPolicy("foo", polkit.Policy("<policyconfig/>"))
+	}
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		policy := filepath.Join(dirs.PolkitActionsDir, "snap.samba.interface.foo.policy")
+		// file called "snap.samba.interface.foo.policy" was created
+		c.Check(policy, testutil.FileEquals, "<policyconfig/>")
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestRemovingSnapRemovesPolicyFiles(c *C) {
+	// NOTE: Hand out a permanent policy so that .policy file is generated.
+	s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+		return spec.AddPolicy("foo", polkit.Policy("<policyconfig/>"))
+	}
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		s.RemoveSnap(c, snapInfo)
+		policy := filepath.Join(dirs.PolkitActionsDir, "snap.samba.interface.foo.policy")
+		// file called "snap.samba.interface.foo.policy" was removed
+		c.Check(osutil.FileExists(policy), Equals, false)
+	}
+}
+
+func (s *backendSuite) TestNoPolicyFiles(c *C) {
+	for _, opts := range testedConfinementOpts {
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		// the polkit actions directory is not created when unneeded
+		c.Check(osutil.IsDirectory(dirs.PolkitActionsDir), Equals, false)
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestUpdatingSnapRemovesStalePolicyFiles(c *C) {
+	c.Assert(os.MkdirAll(dirs.PolkitActionsDir, 0755), IsNil)
+	for _, opts := range testedConfinementOpts {
+		s.Iface.PolkitPermanentSlotCallback = func(spec *polkit.Specification, slot *snap.SlotInfo) error {
+			return spec.AddPolicy("foo", polkit.Policy("<policyconfig/>"))
+		}
+		snapInfo := s.InstallSnap(c, opts, "", ifacetest.SambaYamlV1, 0)
+		s.Iface.PolkitPermanentSlotCallback = nil
+		c.Assert(s.Backend.Setup(snapInfo, opts, s.Repo, timings.New(nil)), IsNil)
+		policy := filepath.Join(dirs.PolkitActionsDir, "snap.samba.interface.foo.policy")
+		c.Check(osutil.FileExists(policy), Equals, false)
+		s.RemoveSnap(c, snapInfo)
+	}
+}
+
+func (s *backendSuite) TestSandboxFeatures(c *C) {
+	c.Assert(s.Backend.SandboxFeatures(), DeepEquals, []string{"mediated-actions"})
+}
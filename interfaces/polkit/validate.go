@@ -0,0 +1,104 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package polkit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// policyConfig is the structure of a polkit policy file, see polkit(8).
+type policyConfig struct {
+	XMLName xml.Name `xml:"policyconfig"`
+	Actions []action `xml:"action"`
+}
+
+type action struct {
+	ID       string     `xml:"id,attr"`
+	Defaults defaults   `xml:"defaults"`
+	Annotate []annotate `xml:"annotate"`
+}
+
+type defaults struct {
+	AllowAny      string `xml:"allow_any"`
+	AllowInactive string `xml:"allow_inactive"`
+	AllowActive   string `xml:"allow_active"`
+}
+
+type annotate struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var validAuthorizations = map[string]bool{
+	"":                true,
+	"no":              true,
+	"yes":             true,
+	"auth_self":       true,
+	"auth_admin":      true,
+	"auth_self_keep":  true,
+	"auth_admin_keep": true,
+}
+
+// ValidatePolicy checks that the polkit policy read from r is well formed
+// and only declares actions whose ids start with the given prefix. It
+// returns the ids of the declared actions.
+func ValidatePolicy(r io.Reader, actionPrefix string) (actionIDs []string, err error) {
+	var config policyConfig
+	if err := xml.NewDecoder(r).Decode(&config); err != nil {
+		return nil, fmt.Errorf("cannot decode polkit policy: %v", err)
+	}
+
+	validID := func(id string) bool {
+		return strings.HasPrefix(id, actionPrefix+".")
+	}
+	for _, a := range config.Actions {
+		if !validID(a.ID) {
+			return nil, fmt.Errorf("polkit action %q does not use the prefix %q", a.ID, actionPrefix)
+		}
+		for _, auth := range []string{a.Defaults.AllowAny, a.Defaults.AllowInactive, a.Defaults.AllowActive} {
+			if !validAuthorizations[strings.TrimSpace(auth)] {
+				return nil, fmt.Errorf("polkit action %q has invalid default authorization %q", a.ID, auth)
+			}
+		}
+		for _, annotation := range a.Annotate {
+			switch annotation.Key {
+			case "org.freedesktop.policykit.imply":
+				// implied actions are authorized together
+				// with the action itself
+				for _, implied := range strings.Fields(annotation.Value) {
+					if !validID(implied) {
+						return nil, fmt.Errorf("polkit action %q implies action %q which does not use the prefix %q", a.ID, implied, actionPrefix)
+					}
+				}
+			case "org.freedesktop.policykit.owner":
+				// only trusted policies may change the
+				// owners of actions
+				return nil, fmt.Errorf("polkit action %q cannot use the annotation %q", a.ID, annotation.Key)
+			}
+		}
+		actionIDs = append(actionIDs, a.ID)
+	}
+	if len(actionIDs) == 0 {
+		return nil, fmt.Errorf("polkit policy does not declare any action")
+	}
+	return actionIDs, nil
+}
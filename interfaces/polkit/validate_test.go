@@ -0,0 +1,86 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+package polkit_test
+
+import (
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/polkit"
+)
+
+type validateSuite struct{}
+
+var _ = Suite(&validateSuite{})
+
+const samplePolicy = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE policyconfig PUBLIC
+ "-//freedesktop//DTD PolicyKit Policy Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/PolicyKit/1/policyconfig.dtd">
+<policyconfig>
+  <vendor>Example</vendor>
+  <action id="org.example.foo.manage">
+    <description>Manage foo</description>
+    <message>Authentication is required to manage foo</message>
+    <defaults>
+      <allow_any>no</allow_any>
+      <allow_inactive>no</allow_inactive>
+      <allow_active>auth_admin_keep</allow_active>
+    </defaults>
+    <annotate key="org.freedesktop.policykit.imply">org.example.foo.read</annotate>
+  </action>
+  <action id="org.example.foo.read">
+    <description>Read foo</description>
+    <message>Authentication is required to read foo</message>
+    <defaults>
+      <allow_active>yes</allow_active>
+    </defaults>
+  </action>
+</policyconfig>
+`
+
+func (s *validateSuite) TestValidatePolicyHappy(c *C) {
+	ids, err := polkit.ValidatePolicy(strings.NewReader(samplePolicy), "org.example.foo")
+	c.Assert(err, IsNil)
+	c.Check(ids, DeepEquals, []string{"org.example.foo.manage", "org.example.foo.read"})
+}
+
+func (s *validateSuite) TestValidatePolicyUnhappy(c *C) {
+	for _, t := range []struct {
+		policy string
+		prefix string
+		err    string
+	}{
+		{samplePolicy, "org.example.bar", `polkit action "org.example.foo.manage" does not use the prefix "org.example.bar"`},
+		{samplePolicy, "org.example.fo", `polkit action "org.example.foo.manage" does not use the prefix "org.example.fo"`},
+		{`<policyconfig><action id="org.example.foo.a"><annotate key="org.freedesktop.policykit.imply">org.example.foo.b org.other.c</annotate></action></policyconfig>`, "org.example.foo",
+			`polkit action "org.example.foo.a" implies action "org.other.c" which does not use the prefix "org.example.foo"`},
+		{`<policyconfig><action id="org.example.foo.a"><annotate key="org.freedesktop.policykit.owner">unix-user:1000</annotate></action></policyconfig>`, "org.example.foo",
+			`polkit action "org.example.foo.a" cannot use the annotation "org.freedesktop.policykit.owner"`},
+		{`<policyconfig><action id="org.example.foo.a"><defaults><allow_any>sure</allow_any></defaults></action></policyconfig>`, "org.example.foo",
+			`polkit action "org.example.foo.a" has invalid default authorization "sure"`},
+		{`<policyconfig></policyconfig>`, "org.example.foo", `polkit policy does not declare any action`},
+		{`<busconfig></busconfig>`, "org.example.foo", `cannot decode polkit policy: expected element type <policyconfig> but have <busconfig>`},
+		{`<policyconfig>`, "org.example.foo", `cannot decode polkit policy: XML syntax error on line 1: unexpected EOF`},
+	} {
+		_, err := polkit.ValidatePolicy(strings.NewReader(t.policy), t.prefix)
+		c.Check(err, ErrorMatches, t.err, Commentf(t.policy))
+	}
+}
@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package prompting implements the data model shared by the prompting
+// client facing API and the AppArmor backend: the requests for which
+// the user is prompted and the rules recording their decisions.
+package prompting
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/features"
+	"github.com/snapcore/snapd/sandbox/apparmor"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// OutcomeType is the decision of the user for a prompt or rule.
+type OutcomeType string
+
+const (
+	OutcomeAllow OutcomeType = "allow"
+	OutcomeDeny  OutcomeType = "deny"
+)
+
+// LifespanType describes how long a decision of the user applies.
+type LifespanType string
+
+const (
+	// LifespanSingle applies the decision only to the prompt it replies to.
+	LifespanSingle LifespanType = "single"
+	// LifespanForever records the decision as a rule which applies to
+	// all future requests matching it.
+	LifespanForever LifespanType = "forever"
+)
+
+// availablePermissions are the file access permissions which may be
+// prompted for.
+var availablePermissions = []string{"read", "write", "execute"}
+
+// ValidateOutcome returns an error if the given outcome is not known.
+func ValidateOutcome(outcome OutcomeType) error {
+	switch outcome {
+	case OutcomeAllow, OutcomeDeny:
+		return nil
+	}
+	return fmt.Errorf("invalid outcome %q", outcome)
+}
+
+// ValidateLifespan returns an error if the given lifespan is not known.
+func ValidateLifespan(lifespan LifespanType) error {
+	switch lifespan {
+	case LifespanSingle, LifespanForever:
+		return nil
+	}
+	return fmt.Errorf("invalid lifespan %q", lifespan)
+}
+
+// ValidatePermissions returns an error if the given list of
+// permissions is empty or contains unknown permissions.
+func ValidatePermissions(permissions []string) error {
+	if len(permissions) == 0 {
+		return fmt.Errorf("permissions cannot be empty")
+	}
+	for _, perm := range permissions {
+		if !strutil.ListContains(availablePermissions, perm) {
+			return fmt.Errorf("invalid permission %q", perm)
+		}
+	}
+	return nil
+}
+
+// ValidatePathPattern returns an error if the given path pattern is not
+// an absolute, clean path, optionally ending with /** to match all the
+// files beneath a directory.
+func ValidatePathPattern(pattern string) error {
+	path := pattern
+	if strings.HasSuffix(pattern, "/**") {
+		path = strings.TrimSuffix(pattern, "/**")
+		if path == "" {
+			path = "/"
+		}
+	}
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return fmt.Errorf("invalid path pattern %q: must be an absolute, clean path", pattern)
+	}
+	if strings.Contains(path, "**") {
+		return fmt.Errorf("invalid path pattern %q: ** is only supported at the end", pattern)
+	}
+	if _, err := filepath.Match(path, ""); err != nil {
+		return fmt.Errorf("invalid path pattern %q: %v", pattern, err)
+	}
+	return nil
+}
+
+// PathPatternMatches returns whether the given path is matched by the
+// given path pattern, which must be valid.
+func PathPatternMatches(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "/**") {
+		dir := strings.TrimSuffix(pattern, "/**")
+		return strings.HasPrefix(path, dir+"/")
+	}
+	matched, _ := filepath.Match(pattern, path)
+	return matched
+}
+
+type confGetter interface {
+	GetMaybe(snapName, key string, result interface{}) error
+}
+
+// PromptingEnabled returns whether prompting is both enabled via the
+// apparmor-prompting experimental feature in the given configuration and
+// supported by AppArmor on this system.
+func PromptingEnabled(tr confGetter) bool {
+	enabled, err := features.Flag(tr, features.AppArmorPrompting)
+	if err != nil || !enabled {
+		return false
+	}
+	supported, _ := apparmor.PromptingSupported()
+	return supported
+}
@@ -0,0 +1,109 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting_test
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/sandbox/apparmor"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type promptingSuite struct{}
+
+var _ = Suite(&promptingSuite{})
+
+func (s *promptingSuite) TestValidateOutcome(c *C) {
+	c.Check(prompting.ValidateOutcome(prompting.OutcomeAllow), IsNil)
+	c.Check(prompting.ValidateOutcome(prompting.OutcomeDeny), IsNil)
+	c.Check(prompting.ValidateOutcome("maybe"), ErrorMatches, `invalid outcome "maybe"`)
+	c.Check(prompting.ValidateOutcome(""), ErrorMatches, `invalid outcome ""`)
+}
+
+func (s *promptingSuite) TestValidateLifespan(c *C) {
+	c.Check(prompting.ValidateLifespan(prompting.LifespanSingle), IsNil)
+	c.Check(prompting.ValidateLifespan(prompting.LifespanForever), IsNil)
+	c.Check(prompting.ValidateLifespan("sometimes"), ErrorMatches, `invalid lifespan "sometimes"`)
+}
+
+func (s *promptingSuite) TestValidatePermissions(c *C) {
+	c.Check(prompting.ValidatePermissions([]string{"read"}), IsNil)
+	c.Check(prompting.ValidatePermissions([]string{"read", "write", "execute"}), IsNil)
+	c.Check(prompting.ValidatePermissions(nil), ErrorMatches, `permissions cannot be empty`)
+	c.Check(prompting.ValidatePermissions([]string{"read", "append"}), ErrorMatches, `invalid permission "append"`)
+}
+
+func (s *promptingSuite) TestValidatePathPattern(c *C) {
+	for _, pattern := range []string{"/", "/**", "/home/test/Documents/**", "/home/test/foo.txt", "/home/test/*.txt"} {
+		c.Check(prompting.ValidatePathPattern(pattern), IsNil, Commentf("pattern: %s", pattern))
+	}
+	for _, pattern := range []string{"", "foo", "/home/test/", "/home/test/../foo", "**", "/home/**/foo", "/home/[test"} {
+		c.Check(prompting.ValidatePathPattern(pattern), ErrorMatches, `invalid path pattern .*`, Commentf("pattern: %s", pattern))
+	}
+}
+
+func (s *promptingSuite) TestPathPatternMatches(c *C) {
+	for _, t := range []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"/home/test/foo.txt", "/home/test/foo.txt", true},
+		{"/home/test/foo.txt", "/home/test/bar.txt", false},
+		{"/home/test/*.txt", "/home/test/bar.txt", true},
+		{"/home/test/*.txt", "/home/test/sub/bar.txt", false},
+		{"/home/test/**", "/home/test/sub/bar.txt", true},
+		{"/home/test/**", "/home/test", false},
+		{"/home/test/**", "/home/tester/foo", false},
+		{"/**", "/etc/passwd", true},
+	} {
+		c.Check(prompting.PathPatternMatches(t.pattern, t.path), Equals, t.matches, Commentf("pattern %s, path %s", t.pattern, t.path))
+	}
+}
+
+type mockConf map[string]interface{}
+
+func (m mockConf) GetMaybe(snapName, key string, result interface{}) error {
+	if v, ok := m[snapName+"."+key]; ok {
+		*result.(*interface{}) = v
+	}
+	return nil
+}
+
+func (s *promptingSuite) TestPromptingEnabled(c *C) {
+	restore := apparmor.MockFeatures([]string{"policy", "policy:permstable32:prompt"}, nil, []string{"prompt", "unsafe"}, nil)
+	defer restore()
+
+	// the feature is not enabled
+	c.Check(prompting.PromptingEnabled(mockConf{}), Equals, false)
+	c.Check(prompting.PromptingEnabled(mockConf{"core.experimental.apparmor-prompting": false}), Equals, false)
+
+	enabled := mockConf{"core.experimental.apparmor-prompting": true}
+	c.Check(prompting.PromptingEnabled(enabled), Equals, true)
+
+	// the feature is enabled but not supported
+	restore = apparmor.MockFeatures([]string{"policy"}, nil, []string{"prompt", "unsafe"}, nil)
+	defer restore()
+	c.Check(prompting.PromptingEnabled(enabled), Equals, false)
+}
@@ -0,0 +1,140 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Prompt is a request from a snap to access a file which the user must
+// allow or deny.
+type Prompt struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// User is the UID of the user who owns the process making the request.
+	User        uint32   `json:"-"`
+	Snap        string   `json:"snap"`
+	App         string   `json:"app"`
+	Interface   string   `json:"interface"`
+	Path        string   `json:"path"`
+	Permissions []string `json:"permissions"`
+
+	// seq orders the prompts by creation
+	seq uint64
+	// replied is notified with the outcome of the prompt
+	replied chan OutcomeType
+}
+
+// Replied returns a channel which receives the outcome of the prompt
+// once the user replied to it.
+func (p *Prompt) Replied() <-chan OutcomeType {
+	return p.replied
+}
+
+// ErrPromptNotFound is returned when a prompt does not exist or does
+// not belong to the given user.
+var ErrPromptNotFound = fmt.Errorf("cannot find prompt")
+
+// PromptDB holds the prompts awaiting a reply from the user. Prompts are
+// not persisted, the processes waiting for them do not survive a
+// restart of snapd either.
+type PromptDB struct {
+	mu      sync.Mutex
+	lastID  uint64
+	prompts map[string]*Prompt
+}
+
+// NewPromptDB returns a new, empty prompt database.
+func NewPromptDB() *PromptDB {
+	return &PromptDB{
+		prompts: make(map[string]*Prompt),
+	}
+}
+
+// Add records a new prompt for the given request and returns it. The
+// ID and timestamp of the given prompt are filled in.
+func (db *PromptDB) Add(p *Prompt) (*Prompt, error) {
+	if err := ValidatePermissions(p.Permissions); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.lastID++
+	prompt := *p
+	prompt.seq = db.lastID
+	prompt.ID = strconv.FormatUint(db.lastID, 16)
+	prompt.Timestamp = time.Now()
+	prompt.replied = make(chan OutcomeType, 1)
+	db.prompts[prompt.ID] = &prompt
+	return &prompt, nil
+}
+
+// Prompts returns the prompts of the given user which await a reply,
+// oldest first.
+func (db *PromptDB) Prompts(user uint32) []*Prompt {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	prompts := make([]*Prompt, 0, len(db.prompts))
+	for _, p := range db.prompts {
+		if p.User == user {
+			prompts = append(prompts, p)
+		}
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].seq < prompts[j].seq
+	})
+	return prompts
+}
+
+// PromptWithID returns the prompt of the given user with the given ID.
+func (db *PromptDB) PromptWithID(user uint32, id string) (*Prompt, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	p, ok := db.prompts[id]
+	if !ok || p.User != user {
+		return nil, ErrPromptNotFound
+	}
+	return p, nil
+}
+
+// Reply removes the prompt of the given user with the given ID and
+// notifies whoever is waiting for it of the outcome.
+func (db *PromptDB) Reply(user uint32, id string, outcome OutcomeType) (*Prompt, error) {
+	if err := ValidateOutcome(outcome); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	p, ok := db.prompts[id]
+	if !ok || p.User != user {
+		return nil, ErrPromptNotFound
+	}
+	delete(db.prompts, id)
+	p.replied <- outcome
+	return p, nil
+}
@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+)
+
+type promptsSuite struct{}
+
+var _ = Suite(&promptsSuite{})
+
+func (s *promptsSuite) TestAddPrompts(c *C) {
+	db := prompting.NewPromptDB()
+
+	p1, err := db.Add(&prompting.Prompt{User: 1000, Snap: "foo", App: "app", Interface: "home", Path: "/home/test/foo.txt", Permissions: []string{"read"}})
+	c.Assert(err, IsNil)
+	c.Check(p1.ID, Equals, "1")
+	c.Check(p1.Timestamp.IsZero(), Equals, false)
+	p2, err := db.Add(&prompting.Prompt{User: 1000, Snap: "bar", App: "app", Interface: "home", Path: "/home/test/bar.txt", Permissions: []string{"write"}})
+	c.Assert(err, IsNil)
+	c.Check(p2.ID, Equals, "2")
+	p3, err := db.Add(&prompting.Prompt{User: 1001, Snap: "foo", App: "app", Interface: "home", Path: "/home/other/foo.txt", Permissions: []string{"read"}})
+	c.Assert(err, IsNil)
+
+	c.Check(db.Prompts(1000), DeepEquals, []*prompting.Prompt{p1, p2})
+	c.Check(db.Prompts(1001), DeepEquals, []*prompting.Prompt{p3})
+	c.Check(db.Prompts(1002), HasLen, 0)
+
+	p, err := db.PromptWithID(1000, p2.ID)
+	c.Assert(err, IsNil)
+	c.Check(p, Equals, p2)
+	// prompts of other users are not visible
+	_, err = db.PromptWithID(1001, p2.ID)
+	c.Check(err, Equals, prompting.ErrPromptNotFound)
+}
+
+func (s *promptsSuite) TestAddInvalidPrompt(c *C) {
+	db := prompting.NewPromptDB()
+	_, err := db.Add(&prompting.Prompt{User: 1000, Snap: "foo", Path: "/home/test/foo.txt"})
+	c.Check(err, ErrorMatches, "permissions cannot be empty")
+}
+
+func (s *promptsSuite) TestReply(c *C) {
+	db := prompting.NewPromptDB()
+	p1, err := db.Add(&prompting.Prompt{User: 1000, Snap: "foo", Path: "/home/test/foo.txt", Permissions: []string{"read"}})
+	c.Assert(err, IsNil)
+
+	_, err = db.Reply(1000, p1.ID, "maybe")
+	c.Check(err, ErrorMatches, `invalid outcome "maybe"`)
+	_, err = db.Reply(1001, p1.ID, prompting.OutcomeAllow)
+	c.Check(err, Equals, prompting.ErrPromptNotFound)
+	_, err = db.Reply(1000, "missing", prompting.OutcomeAllow)
+	c.Check(err, Equals, prompting.ErrPromptNotFound)
+
+	p, err := db.Reply(1000, p1.ID, prompting.OutcomeDeny)
+	c.Assert(err, IsNil)
+	c.Check(p, Equals, p1)
+	c.Check(<-p1.Replied(), Equals, prompting.OutcomeDeny)
+	c.Check(db.Prompts(1000), HasLen, 0)
+
+	// a prompt cannot be replied to twice
+	_, err = db.Reply(1000, p1.ID, prompting.OutcomeAllow)
+	c.Check(err, Equals, prompting.ErrPromptNotFound)
+}
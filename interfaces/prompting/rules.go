@@ -0,0 +1,74 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/strutil"
+)
+
+// Rule records the decision of a user to allow or deny a snap access to
+// the files matching a path pattern, so that they are not prompted again.
+type Rule struct {
+	ID          string      `json:"id"`
+	Timestamp   time.Time   `json:"timestamp"`
+	User        uint32      `json:"user"`
+	Snap        string      `json:"snap"`
+	Interface   string      `json:"interface"`
+	PathPattern string      `json:"path-pattern"`
+	Permissions []string    `json:"permissions"`
+	Outcome     OutcomeType `json:"outcome"`
+}
+
+// Validate returns an error if the rule is incomplete or invalid.
+func (r *Rule) Validate() error {
+	if r.Snap == "" {
+		return fmt.Errorf("rule must specify a snap")
+	}
+	if r.Interface == "" {
+		return fmt.Errorf("rule must specify an interface")
+	}
+	if err := ValidatePathPattern(r.PathPattern); err != nil {
+		return err
+	}
+	if err := ValidatePermissions(r.Permissions); err != nil {
+		return err
+	}
+	return ValidateOutcome(r.Outcome)
+}
+
+// Matches returns whether the rule applies to all the permissions
+// requested by the given prompt.
+func (r *Rule) Matches(p *Prompt) bool {
+	if r.User != p.User || r.Snap != p.Snap || r.Interface != p.Interface {
+		return false
+	}
+	if !PathPatternMatches(r.PathPattern, p.Path) {
+		return false
+	}
+	for _, perm := range p.Permissions {
+		if !strutil.ListContains(r.Permissions, perm) {
+			return false
+		}
+	}
+	return true
+}
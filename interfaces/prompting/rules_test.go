@@ -0,0 +1,88 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package prompting_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+)
+
+type rulesSuite struct{}
+
+var _ = Suite(&rulesSuite{})
+
+func (s *rulesSuite) TestValidate(c *C) {
+	rule := &prompting.Rule{
+		User:        1000,
+		Snap:        "foo",
+		Interface:   "home",
+		PathPattern: "/home/test/Documents/**",
+		Permissions: []string{"read", "write"},
+		Outcome:     prompting.OutcomeAllow,
+	}
+	c.Check(rule.Validate(), IsNil)
+
+	for _, t := range []struct {
+		mutate func(r *prompting.Rule)
+		err    string
+	}{
+		{func(r *prompting.Rule) { r.Snap = "" }, "rule must specify a snap"},
+		{func(r *prompting.Rule) { r.Interface = "" }, "rule must specify an interface"},
+		{func(r *prompting.Rule) { r.PathPattern = "Documents" }, `invalid path pattern "Documents": .*`},
+		{func(r *prompting.Rule) { r.Permissions = nil }, "permissions cannot be empty"},
+		{func(r *prompting.Rule) { r.Outcome = "" }, `invalid outcome ""`},
+	} {
+		r := *rule
+		t.mutate(&r)
+		c.Check(r.Validate(), ErrorMatches, t.err)
+	}
+}
+
+func (s *rulesSuite) TestMatches(c *C) {
+	rule := &prompting.Rule{
+		User:        1000,
+		Snap:        "foo",
+		Interface:   "home",
+		PathPattern: "/home/test/Documents/**",
+		Permissions: []string{"read", "write"},
+		Outcome:     prompting.OutcomeAllow,
+	}
+	prompt := &prompting.Prompt{
+		User:        1000,
+		Snap:        "foo",
+		Interface:   "home",
+		Path:        "/home/test/Documents/foo.txt",
+		Permissions: []string{"read"},
+	}
+	c.Check(rule.Matches(prompt), Equals, true)
+
+	for _, mutate := range []func(p *prompting.Prompt){
+		func(p *prompting.Prompt) { p.User = 1001 },
+		func(p *prompting.Prompt) { p.Snap = "bar" },
+		func(p *prompting.Prompt) { p.Interface = "removable-media" },
+		func(p *prompting.Prompt) { p.Path = "/home/test/foo.txt" },
+		func(p *prompting.Prompt) { p.Permissions = []string{"read", "execute"} },
+	} {
+		p := *prompt
+		mutate(&p)
+		c.Check(rule.Matches(&p), Equals, false)
+	}
+}
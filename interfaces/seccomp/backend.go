@@ -25,8 +25,10 @@
 // profile is read and "compiled" to an eBPF program and injected into the
 // kernel for the duration of the execution of the process.
 //
-// There is no binary cache for seccomp, each time the launcher starts an
-// application the profile is parsed and re-compiled.
+// The compiled profiles are loaded by the launcher each time it starts an
+// application. snapd keeps a cache of compiled profiles so that profiles
+// are only re-compiled when their content, the compiler or the seccomp
+// features of the kernel change.
 //
 // The actual profiles are stored in /var/lib/snappy/seccomp/bpf/*.{src,bin}.
 // This directory is hard-coded in snap-confine.
@@ -43,6 +45,7 @@ import (
 	"github.com/snapcore/snapd/arch"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/sandbox/apparmor"
@@ -77,6 +80,7 @@ type Compiler interface {
 type Backend struct {
 	snapSeccomp Compiler
 	versionInfo seccomp.VersionInfo
+	cache       *compiledProfileCache
 }
 
 var globalProfileLE = []byte{
@@ -146,6 +150,12 @@ func (b *Backend) Initialize(*interfaces.SecurityBackendOptions) error {
 		return fmt.Errorf("cannot obtain snap-seccomp version information: %v", err)
 	}
 	b.versionInfo = versionInfo
+
+	b.cache = newCompiledProfileCache(dirs.SnapSeccompCacheDir, versionInfo, kernelFeatures())
+	// drop the cached profiles that are of no use anymore
+	if err := b.cache.prune(dirs.SnapSeccompDir); err != nil {
+		logger.Noticef("cannot prune cache of compiled seccomp profiles: %v", err)
+	}
 	return nil
 }
 
@@ -162,7 +172,9 @@ func bpfBinPath(srcName string) string {
 	return filepath.Join(dirs.SnapSeccompDir, strings.TrimSuffix(srcName, ".src")+".bin")
 }
 
-func parallelCompile(compiler Compiler, profiles []string) error {
+// parallelCompile compiles the given profiles, using the cache of compiled
+// profiles if it is not nil.
+func parallelCompile(compiler Compiler, cache *compiledProfileCache, profiles []string) error {
 	if len(profiles) == 0 {
 		// no profiles, nothing to do
 		return nil
@@ -200,11 +212,19 @@ func parallelCompile(compiler Compiler, profiles []string) error {
 					continue
 				}
 
+				if cache != nil && cache.get(in, out) {
+					res <- nil
+					continue
+				}
+
 				// snap-seccomp uses AtomicWriteFile internally, on failure the
 				// output file is unlinked
 				if err := compiler.Compile(in, out); err != nil {
 					res <- fmt.Errorf("cannot compile %s: %v", in, err)
 				} else {
+					if cache != nil {
+						cache.put(in, out)
+					}
 					res <- nil
 				}
 			}
@@ -286,7 +306,7 @@ func (b *Backend) Setup(snapInfo *snap.Info, opts interfaces.ConfinementOptions,
 		}
 	}
 
-	return parallelCompile(b.snapSeccomp, changed)
+	return parallelCompile(b.snapSeccomp, b.cache, changed)
 }
 
 // Remove removes seccomp profiles of a given snap.
@@ -874,6 +874,103 @@ fi
 
 }
 
+func (s *backendSuite) mockCachingSnapSeccomp(c *C, versionInfo string) *testutil.MockCmd {
+	snapSeccomp := testutil.MockLockedCommand(c, filepath.Join(dirs.DistroLibExecDir, "snap-seccomp"), fmt.Sprintf(`
+if [ "$1" = "version-info" ]; then
+    echo "%s"
+elif [ "$1" = "compile" ]; then
+    echo "compiled $(basename "$2")" > "$3"
+fi
+`, versionInfo))
+	err := s.Backend.Initialize(nil)
+	c.Assert(err, IsNil)
+	snapSeccomp.ForgetCalls()
+	return snapSeccomp
+}
+
+func (s *backendSuite) TestCompiledProfilesAreCached(c *C) {
+	restore := seccomp_sandbox.MockActions([]string{"log"})
+	defer restore()
+	snapSeccomp := s.mockCachingSnapSeccomp(c, "abcdef 1.2.3 1234abcd -")
+	defer snapSeccomp.Restore()
+
+	profile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd")
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", profile + ".src", profile + ".bin"},
+	})
+	c.Check(profile+".bin", testutil.FileEquals, "compiled snap.samba.smbd.src\n")
+	cached, err := filepath.Glob(filepath.Join(dirs.SnapSeccompCacheDir, "*.bin"))
+	c.Assert(err, IsNil)
+	c.Assert(cached, HasLen, 1)
+	c.Check(cached[0], testutil.FileEquals, "compiled snap.samba.smbd.src\n")
+
+	// the profiles are regenerated, but not recompiled
+	s.RemoveSnap(c, snapInfo)
+	c.Check(profile+".bin", testutil.FileAbsent)
+	snapSeccomp.ForgetCalls()
+	s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(snapSeccomp.Calls(), HasLen, 0)
+	c.Check(profile+".bin", testutil.FileEquals, "compiled snap.samba.smbd.src\n")
+
+	// a profile with different content is compiled
+	snapSeccomp.ForgetCalls()
+	s.UpdateSnap(c, snapInfo, interfaces.ConfinementOptions{DevMode: true}, ifacetest.SambaYamlV1, 0)
+	c.Check(snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", profile + ".src", profile + ".bin"},
+	})
+	cached, err = filepath.Glob(filepath.Join(dirs.SnapSeccompCacheDir, "*.bin"))
+	c.Assert(err, IsNil)
+	c.Check(cached, HasLen, 2)
+
+	// only the entry of the current profile is kept
+	err = s.Backend.Initialize(nil)
+	c.Assert(err, IsNil)
+	cached, err = filepath.Glob(filepath.Join(dirs.SnapSeccompCacheDir, "*.bin"))
+	c.Assert(err, IsNil)
+	c.Check(cached, HasLen, 1)
+}
+
+func (s *backendSuite) TestCompiledProfilesCacheKeyedOnVersionAndKernelFeatures(c *C) {
+	restore := seccomp_sandbox.MockActions([]string{"log"})
+	defer restore()
+	snapSeccomp := s.mockCachingSnapSeccomp(c, "abcdef 1.2.3 1234abcd -")
+	defer snapSeccomp.Restore()
+
+	profile := filepath.Join(dirs.SnapSeccompDir, "snap.samba.smbd")
+	snapInfo := s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(snapSeccomp.Calls(), HasLen, 1)
+	s.RemoveSnap(c, snapInfo)
+
+	// different seccomp features of the kernel
+	restore = seccomp_sandbox.MockActions([]string{"log", "user_notif"})
+	defer restore()
+	err := s.Backend.Initialize(nil)
+	c.Assert(err, IsNil)
+	snapSeccomp.ForgetCalls()
+	snapInfo = s.InstallSnap(c, interfaces.ConfinementOptions{}, "", ifacetest.SambaYamlV1, 0)
+	c.Check(snapSeccomp.Calls(), DeepEquals, [][]string{
+		{"snap-seccomp", "compile", profile + ".src", profile + ".bin"},
+	})
+}
+
+func (s *backendSuite) TestCompiledProfilesCacheKey(c *C) {
+	src := []byte("# profile\n")
+	p := seccomp.CompiledProfileCachePath("/cache", "abcdef 1.2.3 1234abcd -", []string{"log"}, src)
+	c.Check(filepath.Dir(p), Equals, "/cache")
+	c.Check(p, Matches, "/cache/[0-9a-f]{64}.bin")
+	// stable
+	c.Check(seccomp.CompiledProfileCachePath("/cache", "abcdef 1.2.3 1234abcd -", []string{"log"}, src), Equals, p)
+
+	for _, other := range []string{
+		seccomp.CompiledProfileCachePath("/cache", "abcdef 1.2.3 1234abcd -", []string{"log"}, []byte("# other\n")),
+		seccomp.CompiledProfileCachePath("/cache", "abcdef 2.3.4 1234abcd -", []string{"log"}, src),
+		seccomp.CompiledProfileCachePath("/cache", "abcdef 1.2.3 1234abcd -", []string{"log", "user_notif"}, src),
+	} {
+		c.Check(other, Not(Equals), p)
+	}
+}
+
 type mockedSyncedCompiler struct {
 	lock     sync.Mutex
 	profiles []string
@@ -905,7 +1002,7 @@ func (s *backendSuite) TestParallelCompileHappy(c *C) {
 	for i := range profiles {
 		profiles[i] = fmt.Sprintf("profile-%03d", i)
 	}
-	err := seccomp.ParallelCompile(&m, profiles)
+	err := seccomp.ParallelCompile(&m, nil, profiles)
 	c.Assert(err, IsNil)
 
 	sort.Strings(m.profiles)
@@ -940,7 +1037,7 @@ func (s *backendSuite) TestParallelCompileError(c *C) {
 		// pretend compilation of those 2 fails
 		whichFail: []string{"profile-005.bin", "profile-009.bin"},
 	}
-	err = seccomp.ParallelCompile(&m, profiles)
+	err = seccomp.ParallelCompile(&m, nil, profiles)
 	c.Assert(err, ErrorMatches, "cannot compile .*/bpf/profile-00[59]: failed profile-00[59].bin")
 
 	// make sure all compiled profiles were removed
@@ -965,6 +1062,6 @@ func (s *backendSuite) TestParallelCompileRemovesFirst(c *C) {
 	defer os.Chmod(dirs.SnapSeccompDir, 0755)
 
 	m := mockedSyncedCompiler{}
-	err = seccomp.ParallelCompile(&m, []string{"profile-001"})
+	err = seccomp.ParallelCompile(&m, nil, []string{"profile-001"})
 	c.Assert(err, ErrorMatches, "remove .*/profile-001.bin: permission denied")
 }
@@ -0,0 +1,130 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package seccomp
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/sandbox/seccomp"
+)
+
+// compiledProfileCache is a cache of compiled seccomp profiles.
+//
+// The compiled profiles are keyed on the content of the profile source,
+// the version of snap-seccomp and the seccomp features of the kernel, so
+// that a profile is only compiled again when any of these change. This
+// avoids most of the compilation work when all the profiles are
+// regenerated, e.g. on first boot or on refreshes of snapd.
+type compiledProfileCache struct {
+	dir string
+	// salt captures the compiler version and kernel features
+	salt string
+}
+
+func newCompiledProfileCache(dir string, versionInfo seccomp.VersionInfo, kernelFeatures []string) *compiledProfileCache {
+	return &compiledProfileCache{
+		dir:  dir,
+		salt: fmt.Sprintf("%s\n%s\n", versionInfo, strings.Join(kernelFeatures, " ")),
+	}
+}
+
+// path returns the path of the cached compiled profile for the given
+// profile source.
+func (pc *compiledProfileCache) path(src []byte) string {
+	h := sha256.New()
+	h.Write([]byte(pc.salt))
+	h.Write(src)
+	return filepath.Join(pc.dir, fmt.Sprintf("%x.bin", h.Sum(nil)))
+}
+
+// get writes the cached compiled profile for the profile source in to
+// out and returns whether there was one.
+func (pc *compiledProfileCache) get(in, out string) bool {
+	src, err := ioutil.ReadFile(in)
+	if err != nil {
+		return false
+	}
+	compiled, err := ioutil.ReadFile(pc.path(src))
+	if err != nil {
+		return false
+	}
+	if err := osutil.AtomicWriteFile(out, compiled, 0644, 0); err != nil {
+		logger.Noticef("cannot use cached compiled seccomp profile for %s: %v", in, err)
+		return false
+	}
+	return true
+}
+
+// put adds the profile compiled from in to out to the cache. Errors are
+// only logged, the cache is merely an optimization.
+func (pc *compiledProfileCache) put(in, out string) {
+	src, err := ioutil.ReadFile(in)
+	if err != nil {
+		return
+	}
+	compiled, err := ioutil.ReadFile(out)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(pc.dir, 0755); err != nil {
+		logger.Noticef("cannot create seccomp profile cache directory: %v", err)
+		return
+	}
+	if err := osutil.AtomicWriteFile(pc.path(src), compiled, 0644, 0); err != nil {
+		logger.Noticef("cannot cache compiled seccomp profile for %s: %v", in, err)
+	}
+}
+
+// prune removes the cached compiled profiles that do not correspond to
+// any of the profile sources in the given directory.
+func (pc *compiledProfileCache) prune(srcDir string) error {
+	cached, err := filepath.Glob(filepath.Join(pc.dir, "*.bin"))
+	if err != nil || len(cached) == 0 {
+		return err
+	}
+	sources, err := filepath.Glob(filepath.Join(srcDir, "*.src"))
+	if err != nil {
+		return err
+	}
+	inUse := make(map[string]bool, len(sources))
+	for _, in := range sources {
+		src, err := ioutil.ReadFile(in)
+		if err != nil {
+			return err
+		}
+		inUse[pc.path(src)] = true
+	}
+	for _, p := range cached {
+		if inUse[p] {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -86,6 +86,10 @@ func MockSeccompCompilerLookup(f func(string) (string, error)) (restore func())
 	}
 }
 
+func CompiledProfileCachePath(dir string, versionInfo seccomp_compiler.VersionInfo, kernelFeatures []string, src []byte) string {
+	return newCompiledProfileCache(dir, versionInfo, kernelFeatures).path(src)
+}
+
 func (b *Backend) VersionInfo() seccomp_compiler.VersionInfo {
 	return b.versionInfo
 }
@@ -62,9 +62,36 @@ type Disk interface {
 	// does not have partitions for example.
 	HasPartitions() bool
 
-	// TODO: add function to get some properties like an associated /dev node
-	//       for a disk for better user error reporting, i.e. /dev/vda3 is much
-	//       more helpful than 252:3
+	// KernelDeviceNode returns the full path of the device node of the disk,
+	// such as /dev/vda or /dev/mmcblk0. It is empty if the device node could
+	// not be determined.
+	KernelDeviceNode() string
+
+	// SizeInBytes returns the size of the disk in bytes.
+	SizeInBytes() (uint64, error)
+
+	// Partitions returns all the partitions found on the disk.
+	Partitions() ([]Partition, error)
+}
+
+// Partition describes a single partition of a disk.
+type Partition struct {
+	// KernelDeviceNode is the full path of the device node of the
+	// partition, such as /dev/vda1.
+	KernelDeviceNode string
+	// PartitionUUID is the partition uuid of the partition.
+	PartitionUUID string
+	// PartitionLabel is the udev encoded partition label, it is empty on MBR
+	// disks.
+	PartitionLabel string
+	// FilesystemLabel is the udev encoded label of the filesystem on the
+	// partition, if any.
+	FilesystemLabel string
+	// FilesystemType is the type of the filesystem on the partition, such as
+	// vfat, ext4 or crypto_LUKS for an encrypted partition, if any.
+	FilesystemType string
+	// SizeInBytes is the size of the partition in bytes.
+	SizeInBytes uint64
 }
 
 // PartitionNotFoundError is an error where a partition matching the SearchType
@@ -40,3 +40,8 @@ func DiskFromMountPoint(mountpoint string, opts *Options) (Disk, error) {
 var diskFromMountPoint = func(mountpoint string, opts *Options) (Disk, error) {
 	return nil, osutil.ErrDarwin
 }
+
+// AllPhysicalDisks is not implemented on darwin
+func AllPhysicalDisks() ([]Disk, error) {
+	return nil, osutil.ErrDarwin
+}
@@ -142,11 +142,55 @@ var diskFromDeviceName = func(deviceName string) (Disk, error) {
 	// DiskFromName but it might be useful eventually
 
 	return &disk{
-		major: major,
-		minor: minor,
+		major:   major,
+		minor:   minor,
+		devNode: props["DEVNAME"],
 	}, nil
 }
 
+// AllPhysicalDisks returns all the physical disks of the system, virtual
+// block devices such as loop or device mapper devices are not included.
+func AllPhysicalDisks() ([]Disk, error) {
+	// the entries in /sys/block are symlinks to the actual devices, the
+	// virtual ones are located under /sys/devices/virtual/block
+	paths, err := filepath.Glob(filepath.Join(dirs.SysfsDir, "block", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list block devices: %v", err)
+	}
+	sort.Strings(paths)
+
+	var disks []Disk
+	for _, path := range paths {
+		devPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve block device %s: %v", filepath.Base(path), err)
+		}
+		if strings.Contains(devPath, "/devices/virtual/") {
+			continue
+		}
+		d, err := diskFromDeviceName(filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		disks = append(disks, d)
+	}
+	return disks, nil
+}
+
+// sysfsSizeInBytes returns the size of a block device as indicated in the
+// given sysfs size file, which is always expressed in 512 byte sectors.
+func sysfsSizeInBytes(sizeFile string) (uint64, error) {
+	content, err := ioutil.ReadFile(sizeFile)
+	if err != nil {
+		return 0, err
+	}
+	sectors, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse size of block device: %v", err)
+	}
+	return sectors * 512, nil
+}
+
 // DiskFromMountPoint finds a matching Disk for the specified mount point.
 func DiskFromMountPoint(mountpoint string, opts *Options) (Disk, error) {
 	// call the unexported version that may be mocked by tests
@@ -155,13 +199,20 @@ func DiskFromMountPoint(mountpoint string, opts *Options) (Disk, error) {
 
 type partition struct {
 	fsLabel   string
+	fsType    string
 	partLabel string
 	partUUID  string
+	devNode   string
+	size      uint64
 }
 
 type disk struct {
 	major int
 	minor int
+	// devNode is the device node of the disk, such as /dev/vda, it is only
+	// known once the disk was queried with udev by its name or its
+	// partitions have been populated
+	devNode string
 	// partitions is the set of discovered partitions for the disk, each
 	// partition must have a partition uuid, but may or may not have either a
 	// partition label or a filesystem label
@@ -328,6 +379,20 @@ func diskFromMountPointImpl(mountpoint string, opts *Options) (*disk, error) {
 }
 
 func (d *disk) populatePartitions() error {
+	if err := d.discoverPartitions(); err != nil {
+		return err
+	}
+
+	// if we didn't find any partitions then return an error, this is because
+	// all disks we search for partitions are expected to have some partitions
+	if len(d.partitions) == 0 {
+		return fmt.Errorf("no partitions found for disk %s", d.Dev())
+	}
+
+	return nil
+}
+
+func (d *disk) discoverPartitions() error {
 	if d.partitions == nil {
 		d.partitions = []partition{}
 
@@ -351,6 +416,9 @@ func (d *disk) populatePartitions() error {
 		}
 		// the DEVNAME as returned by udev includes the /dev/mmcblk0 path, we
 		// just want mmcblk0 for example
+		if d.devNode == "" {
+			d.devNode = devName
+		}
 		devName = filepath.Base(devName)
 
 		// get the device path in sysfs
@@ -416,6 +484,15 @@ func (d *disk) populatePartitions() error {
 			// Go strings that are encoded with BlkIDEncodeLabel.
 			part.fsLabel = udevProps["ID_FS_LABEL_ENC"]
 
+			// the filesystem type is crypto_LUKS for encrypted partitions
+			part.fsType = udevProps["ID_FS_TYPE"]
+			part.devNode = udevProps["DEVNAME"]
+
+			size, err := sysfsSizeInBytes(filepath.Join(path, "size"))
+			if err == nil {
+				part.size = size
+			}
+
 			// prepend the partition to the front, this has the effect that if
 			// two partitions have the same label (either filesystem or
 			// partition though it is unclear whether you could actually in
@@ -432,13 +509,6 @@ func (d *disk) populatePartitions() error {
 		}
 	}
 
-	// if we didn't find any partitions from above then return an error, this is
-	// because all disks we search for partitions are expected to have some
-	// partitions
-	if len(d.partitions) == 0 {
-		return fmt.Errorf("no partitions found for disk %s", d.Dev())
-	}
-
 	return nil
 }
 
@@ -506,3 +576,43 @@ func (d *disk) HasPartitions() bool {
 	//       d.partitions is empty or not
 	return d.hasPartitions
 }
+
+func (d *disk) KernelDeviceNode() string {
+	if d.devNode == "" {
+		// the error is ignored on purpose, the device node is only
+		// informational
+		if udevProps, err := udevProperties(filepath.Join("/dev/block", d.Dev())); err == nil {
+			d.devNode = udevProps["DEVNAME"]
+		}
+	}
+	return d.devNode
+}
+
+func (d *disk) SizeInBytes() (uint64, error) {
+	size, err := sysfsSizeInBytes(filepath.Join(dirs.SysfsDir, "dev", "block", d.Dev(), "size"))
+	if err != nil {
+		return 0, fmt.Errorf("cannot get size of disk %s: %v", d.Dev(), err)
+	}
+	return size, nil
+}
+
+func (d *disk) Partitions() ([]Partition, error) {
+	if err := d.discoverPartitions(); err != nil {
+		return nil, err
+	}
+	// the partitions are kept in reverse order of discovery, see
+	// discoverPartitions
+	parts := make([]Partition, 0, len(d.partitions))
+	for i := len(d.partitions) - 1; i >= 0; i-- {
+		p := d.partitions[i]
+		parts = append(parts, Partition{
+			KernelDeviceNode: p.devNode,
+			PartitionUUID:    p.partUUID,
+			PartitionLabel:   p.partLabel,
+			FilesystemLabel:  p.fsLabel,
+			FilesystemType:   p.fsType,
+			SizeInBytes:      p.size,
+		})
+	}
+	return parts, nil
+}
@@ -728,3 +728,123 @@ func (s *diskSuite) TestDiskFromMountPointDecryptedDevicePartitionsHappy(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(matches, Equals, true)
 }
+
+func (s *diskSuite) TestDiskPartitionsHappy(c *C) {
+	restore := disks.MockUdevPropertiesForDevice(func(dev string) (map[string]string, error) {
+		switch dev {
+		case "vda":
+			return map[string]string{
+				"MAJOR":   "42",
+				"MINOR":   "0",
+				"DEVTYPE": "disk",
+				"DEVNAME": "/dev/vda",
+			}, nil
+		case "/dev/block/42:0":
+			return diskUdevPropMap, nil
+		case "vda1":
+			return map[string]string{
+				"ID_PART_ENTRY_UUID": "ubuntu-seed-partuuid",
+				"ID_FS_LABEL_ENC":    "ubuntu-seed",
+				"ID_PART_ENTRY_NAME": "ubuntu-seed",
+				"ID_FS_TYPE":         "vfat",
+				"DEVNAME":            "/dev/vda1",
+			}, nil
+		case "vda2":
+			return map[string]string{
+				"ID_PART_ENTRY_UUID": "ubuntu-data-partuuid",
+				"ID_FS_LABEL_ENC":    "ubuntu-data-enc",
+				"ID_PART_ENTRY_NAME": "ubuntu-data",
+				"ID_FS_TYPE":         "crypto_LUKS",
+				"DEVNAME":            "/dev/vda2",
+			}, nil
+		default:
+			c.Errorf("unexpected udev device properties requested: %s", dev)
+			return nil, fmt.Errorf("unexpected udev device: %s", dev)
+		}
+	})
+	defer restore()
+
+	createVirtioDevicesInSysfs(c, map[string]bool{
+		"vda1": true,
+		"vda2": true,
+	})
+	diskDir := filepath.Join(dirs.SysfsDir, virtioDiskDevPath)
+	c.Assert(ioutil.WriteFile(filepath.Join(diskDir, "vda1", "size"), []byte("2048\n"), 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(diskDir, "vda2", "size"), []byte("4096\n"), 0644), IsNil)
+	sizeDir := filepath.Join(dirs.SysfsDir, "dev/block/42:0")
+	c.Assert(os.MkdirAll(sizeDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(sizeDir, "size"), []byte("8192\n"), 0644), IsNil)
+
+	d, err := disks.DiskFromDeviceName("vda")
+	c.Assert(err, IsNil)
+	c.Check(d.KernelDeviceNode(), Equals, "/dev/vda")
+
+	size, err := d.SizeInBytes()
+	c.Assert(err, IsNil)
+	c.Check(size, Equals, uint64(8192*512))
+
+	parts, err := d.Partitions()
+	c.Assert(err, IsNil)
+	c.Check(parts, DeepEquals, []disks.Partition{
+		{
+			KernelDeviceNode: "/dev/vda1",
+			PartitionUUID:    "ubuntu-seed-partuuid",
+			PartitionLabel:   "ubuntu-seed",
+			FilesystemLabel:  "ubuntu-seed",
+			FilesystemType:   "vfat",
+			SizeInBytes:      2048 * 512,
+		}, {
+			KernelDeviceNode: "/dev/vda2",
+			PartitionUUID:    "ubuntu-data-partuuid",
+			PartitionLabel:   "ubuntu-data",
+			FilesystemLabel:  "ubuntu-data-enc",
+			FilesystemType:   "crypto_LUKS",
+			SizeInBytes:      4096 * 512,
+		},
+	})
+}
+
+func (s *diskSuite) TestAllPhysicalDisks(c *C) {
+	restore := disks.MockUdevPropertiesForDevice(func(dev string) (map[string]string, error) {
+		switch dev {
+		case "vda":
+			return map[string]string{
+				"MAJOR":   "42",
+				"MINOR":   "0",
+				"DEVTYPE": "disk",
+				"DEVNAME": "/dev/vda",
+			}, nil
+		case "sda":
+			return map[string]string{
+				"MAJOR":   "8",
+				"MINOR":   "0",
+				"DEVTYPE": "disk",
+				"DEVNAME": "/dev/sda",
+			}, nil
+		default:
+			c.Errorf("unexpected udev device properties requested: %s", dev)
+			return nil, fmt.Errorf("unexpected udev device: %s", dev)
+		}
+	})
+	defer restore()
+
+	blockDir := filepath.Join(dirs.SysfsDir, "block")
+	c.Assert(os.MkdirAll(blockDir, 0755), IsNil)
+	for name, devPath := range map[string]string{
+		"vda":   "devices/pci0000:00/0000:00:03.0/virtio1/block/vda",
+		"sda":   "devices/pci0000:00/0000:00:01.1/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+		"loop0": "devices/virtual/block/loop0",
+		"dm-0":  "devices/virtual/block/dm-0",
+	} {
+		c.Assert(os.MkdirAll(filepath.Join(dirs.SysfsDir, devPath), 0755), IsNil)
+		c.Assert(os.Symlink(filepath.Join("..", devPath), filepath.Join(blockDir, name)), IsNil)
+	}
+
+	all, err := disks.AllPhysicalDisks()
+	c.Assert(err, IsNil)
+	c.Assert(all, HasLen, 2)
+	c.Check(all[0].KernelDeviceNode(), Equals, "/dev/sda")
+	c.Check(all[0].Dev(), Equals, "8:0")
+	c.Check(all[1].KernelDeviceNode(), Equals, "/dev/vda")
+	c.Check(all[1].Dev(), Equals, "42:0")
+}
@@ -40,6 +40,12 @@ type MockDiskMapping struct {
 	PartitionLabelToPartUUID map[string]string
 	DiskHasPartitions        bool
 	DevNum                   string
+	// DevNode is the device node of the mocked disk, such as /dev/vda.
+	DevNode string
+	// DiskSizeInBytes is the size of the mocked disk.
+	DiskSizeInBytes uint64
+	// Structure is the list of partitions of the mocked disk.
+	Structure []Partition
 }
 
 // FindMatchingPartitionUUIDWithFsLabel returns a matching PartitionUUID
@@ -100,6 +106,25 @@ func (d *MockDiskMapping) Dev() string {
 	return d.DevNum
 }
 
+// KernelDeviceNode returns the mocked device node of the disk. Part of the
+// Disk interface.
+func (d *MockDiskMapping) KernelDeviceNode() string {
+	return d.DevNode
+}
+
+// SizeInBytes returns the mocked size of the disk. Part of the Disk interface.
+func (d *MockDiskMapping) SizeInBytes() (uint64, error) {
+	osutil.MustBeTestBinary("mock disks only to be used in tests")
+	return d.DiskSizeInBytes, nil
+}
+
+// Partitions returns the mocked partitions of the disk. Part of the Disk
+// interface.
+func (d *MockDiskMapping) Partitions() ([]Partition, error) {
+	osutil.MustBeTestBinary("mock disks only to be used in tests")
+	return d.Structure, nil
+}
+
 // Mountpoint is a combination of a mountpoint location and whether that
 // mountpoint is a decrypted device. It is only used in identifying mount points
 // with MountPointIsFromDisk and DiskFromMountPoint with
@@ -0,0 +1,351 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package inotify watches files and directories for changes using the
+// inotify(7) interface of the kernel.
+package inotify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Masks of the events that can be watched, see inotify(7).
+const (
+	InAccess       = unix.IN_ACCESS
+	InAttrib       = unix.IN_ATTRIB
+	InCloseWrite   = unix.IN_CLOSE_WRITE
+	InCloseNowrite = unix.IN_CLOSE_NOWRITE
+	InCreate       = unix.IN_CREATE
+	InDelete       = unix.IN_DELETE
+	InDeleteSelf   = unix.IN_DELETE_SELF
+	InModify       = unix.IN_MODIFY
+	InMoveSelf     = unix.IN_MOVE_SELF
+	InMovedFrom    = unix.IN_MOVED_FROM
+	InMovedTo      = unix.IN_MOVED_TO
+	InOpen         = unix.IN_OPEN
+
+	InAllEvents = unix.IN_ALL_EVENTS
+
+	// set by the kernel in the mask of events
+	InIgnored   = unix.IN_IGNORED
+	InIsDir     = unix.IN_ISDIR
+	InQOverflow = unix.IN_Q_OVERFLOW
+)
+
+// Event is a change of a watched file or directory, or of a file in a
+// watched directory.
+type Event struct {
+	// Path is the path of the file the event is about.
+	Path string
+	// Mask describes the event.
+	Mask uint32
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%q: %#x", e.Path, e.Mask)
+}
+
+type watch struct {
+	path      string
+	mask      uint32
+	recursive bool
+	// implicit is set for the watches of the directories under a
+	// recursive watch
+	implicit bool
+}
+
+// Watcher delivers the events of the watched files and directories on
+// its Event channel, and the errors met while reading them on its Error
+// channel. Both channels are closed once the watcher is closed.
+type Watcher struct {
+	Event chan Event
+	Error chan error
+
+	fd   int
+	file *os.File
+
+	mu      sync.Mutex
+	watches map[int]*watch
+	wds     map[string]int
+
+	done chan struct{}
+}
+
+// NewWatcher returns a new watcher without any watches.
+func NewWatcher() (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize inotify: %v", err)
+	}
+	w := &Watcher{
+		Event: make(chan Event),
+		Error: make(chan error),
+		fd:    fd,
+		// the fd is non blocking, reads from the file go through
+		// the runtime poller and are interrupted by Close
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		watches: make(map[int]*watch),
+		wds:     make(map[string]int),
+		done:    make(chan struct{}),
+	}
+	go w.readEvents()
+	return w, nil
+}
+
+// AddWatch watches the given file or directory for the events in mask.
+// Watching a path that is already watched replaces its mask.
+func (w *Watcher) AddWatch(path string, mask uint32) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.addWatch(filepath.Clean(path), mask, false, false)
+}
+
+// AddRecursiveWatch watches the given directory and all the directories
+// under it for the events in mask. Directories created later under it
+// are watched as well.
+func (w *Watcher) AddRecursiveWatch(dir string, mask uint32) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.addRecursiveWatch(filepath.Clean(dir), mask, false)
+}
+
+// RemoveWatch stops watching the given path, together with all the
+// directories under it if it was watched recursively. No InIgnored
+// event is delivered for watches removed this way.
+func (w *Watcher) RemoveWatch(path string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path = filepath.Clean(path)
+	wd, ok := w.wds[path]
+	if !ok {
+		return fmt.Errorf("cannot remove watch: %q is not watched", path)
+	}
+	if w.watches[wd].recursive {
+		prefix := path + "/"
+		for subpath, subwd := range w.wds {
+			if strings.HasPrefix(subpath, prefix) && w.watches[subwd].implicit {
+				w.removeWatch(subpath, subwd)
+			}
+		}
+	}
+	if _, err := unix.InotifyRmWatch(w.fd, uint32(wd)); err != nil {
+		return fmt.Errorf("cannot remove watch of %q: %v", path, err)
+	}
+	w.forget(path, wd)
+	return nil
+}
+
+// Close removes all the watches and releases the resources of the
+// watcher.
+func (w *Watcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+	}
+	close(w.done)
+	return w.file.Close()
+}
+
+func (w *Watcher) addWatch(path string, mask uint32, recursive, implicit bool) error {
+	wmask := mask
+	if recursive {
+		// new directories need to be watched as well
+		wmask |= InCreate | InMovedTo
+	}
+	wd, err := unix.InotifyAddWatch(w.fd, path, wmask)
+	if err != nil {
+		return &os.PathError{Op: "watch", Path: path, Err: err}
+	}
+	w.watches[wd] = &watch{path: path, mask: mask, recursive: recursive, implicit: implicit}
+	w.wds[path] = wd
+	return nil
+}
+
+func (w *Watcher) addRecursiveWatch(dir string, mask uint32, implicit bool) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			err = w.addWatch(path, mask, true, implicit || path != dir)
+		}
+		if err != nil && path != dir && os.IsNotExist(err) {
+			// removed in the meantime
+			return nil
+		}
+		return err
+	})
+}
+
+func (w *Watcher) removeWatch(path string, wd int) {
+	// errors are fine here, the directory may already be gone
+	unix.InotifyRmWatch(w.fd, uint32(wd))
+	w.forget(path, wd)
+}
+
+func (w *Watcher) forget(path string, wd int) {
+	delete(w.watches, wd)
+	if w.wds[path] == wd {
+		delete(w.wds, path)
+	}
+}
+
+type rawEvent struct {
+	wd   int
+	mask uint32
+	name string
+}
+
+// parseEvents parses the inotify events read from the kernel.
+func parseEvents(buf []byte) ([]rawEvent, error) {
+	var events []rawEvent
+	for len(buf) > 0 {
+		if len(buf) < unix.SizeofInotifyEvent {
+			return nil, fmt.Errorf("cannot parse inotify event: short buffer")
+		}
+		raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := unix.SizeofInotifyEvent + int(raw.Len)
+		if len(buf) < end {
+			return nil, fmt.Errorf("cannot parse inotify event: short buffer")
+		}
+		name := buf[unix.SizeofInotifyEvent:end]
+		// the name is padded with NULs
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		events = append(events, rawEvent{
+			wd:   int(raw.Wd),
+			mask: raw.Mask,
+			name: string(name),
+		})
+		buf = buf[end:]
+	}
+	return events, nil
+}
+
+// coalesceEvents drops the events that repeat the previous event about
+// the same file, as is the case when a file is written in many chunks.
+// The kernel does this only for events that directly follow each other.
+func coalesceEvents(events []rawEvent) []rawEvent {
+	type key struct {
+		wd   int
+		name string
+	}
+	last := make(map[key]uint32, len(events))
+	coalesced := events[:0]
+	for _, ev := range events {
+		k := key{ev.wd, ev.name}
+		if mask, ok := last[k]; ok && mask == ev.mask {
+			continue
+		}
+		last[k] = ev.mask
+		coalesced = append(coalesced, ev)
+	}
+	return coalesced
+}
+
+func (w *Watcher) readEvents() {
+	defer close(w.Event)
+	defer close(w.Error)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			select {
+			case <-w.done:
+				return
+			default:
+			}
+			w.sendError(fmt.Errorf("cannot read inotify events: %v", err))
+			return
+		}
+		raws, err := parseEvents(buf[:n])
+		if err != nil {
+			w.sendError(err)
+			continue
+		}
+		events, errs := w.processEvents(coalesceEvents(raws))
+		for _, ev := range events {
+			select {
+			case w.Event <- ev:
+			case <-w.done:
+				return
+			}
+		}
+		for _, err := range errs {
+			w.sendError(err)
+		}
+	}
+}
+
+func (w *Watcher) sendError(err error) {
+	select {
+	case w.Error <- err:
+	case <-w.done:
+	}
+}
+
+// processEvents maps the raw events to the watched paths, it takes care
+// of watching the directories created under recursive watches and of
+// forgetting the watches removed by the kernel.
+func (w *Watcher) processEvents(raws []rawEvent) (events []Event, errs []error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	events = make([]Event, 0, len(raws))
+	for _, raw := range raws {
+		if raw.mask&InQOverflow != 0 {
+			events = append(events, Event{Mask: raw.mask})
+			continue
+		}
+		wt := w.watches[raw.wd]
+		if wt == nil {
+			// removed already
+			continue
+		}
+		path := wt.path
+		if raw.name != "" {
+			path = filepath.Join(wt.path, raw.name)
+		}
+		if wt.recursive && raw.mask&InIsDir != 0 && raw.mask&(InCreate|InMovedTo) != 0 {
+			if err := w.addRecursiveWatch(path, wt.mask, true); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+		if raw.mask&InIgnored != 0 {
+			w.forget(wt.path, raw.wd)
+			if wt.implicit {
+				continue
+			}
+		} else if raw.mask&wt.mask == 0 {
+			// only watched because of the recursion
+			continue
+		}
+		events = append(events, Event{Path: path, Mask: raw.mask})
+	}
+	return events, errs
+}
@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package inotify_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/osutil/inotify"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type inotifySuite struct {
+	dir string
+	w   *inotify.Watcher
+}
+
+var _ = Suite(&inotifySuite{})
+
+func (s *inotifySuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+	w, err := inotify.NewWatcher()
+	c.Assert(err, IsNil)
+	s.w = w
+}
+
+func (s *inotifySuite) TearDownTest(c *C) {
+	c.Check(s.w.Close(), IsNil)
+}
+
+func (s *inotifySuite) nextEvent(c *C) inotify.Event {
+	select {
+	case ev, ok := <-s.w.Event:
+		c.Assert(ok, Equals, true)
+		return ev
+	case err := <-s.w.Error:
+		c.Fatalf("unexpected error: %v", err)
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timeout waiting for an event")
+	}
+	return inotify.Event{}
+}
+
+func (s *inotifySuite) TestAddWatch(c *C) {
+	c.Assert(s.w.AddWatch(s.dir, inotify.InCreate|inotify.InDelete), IsNil)
+
+	foo := filepath.Join(s.dir, "foo")
+	c.Assert(ioutil.WriteFile(foo, []byte("foo"), 0644), IsNil)
+	c.Check(s.nextEvent(c), Equals, inotify.Event{Path: foo, Mask: inotify.InCreate})
+
+	c.Assert(os.Remove(foo), IsNil)
+	c.Check(s.nextEvent(c), Equals, inotify.Event{Path: foo, Mask: inotify.InDelete})
+}
+
+func (s *inotifySuite) TestAddWatchError(c *C) {
+	err := s.w.AddWatch(filepath.Join(s.dir, "missing"), inotify.InCreate)
+	c.Check(err, ErrorMatches, `watch .*/missing: no such file or directory`)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
+
+func (s *inotifySuite) TestAddRecursiveWatch(c *C) {
+	c.Assert(os.MkdirAll(filepath.Join(s.dir, "a/b"), 0755), IsNil)
+	c.Assert(s.w.AddRecursiveWatch(s.dir, inotify.InCloseWrite), IsNil)
+
+	// existing directories are watched
+	foo := filepath.Join(s.dir, "a/b/foo")
+	c.Assert(ioutil.WriteFile(foo, []byte("foo"), 0644), IsNil)
+	c.Check(s.nextEvent(c), Equals, inotify.Event{Path: foo, Mask: inotify.InCloseWrite})
+
+	// as well as new ones, including what was created in them before
+	// they were watched
+	c.Assert(os.MkdirAll(filepath.Join(s.dir, "c/d"), 0755), IsNil)
+	bar := filepath.Join(s.dir, "c/d/bar")
+	for i := 0; i < 100; i++ {
+		if err := ioutil.WriteFile(bar, []byte("bar"), 0644); err != nil {
+			c.Assert(os.IsNotExist(err), Equals, true)
+		}
+		select {
+		case ev := <-s.w.Event:
+			c.Check(ev, Equals, inotify.Event{Path: bar, Mask: inotify.InCloseWrite})
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	c.Fatalf("timeout waiting for an event")
+}
+
+func (s *inotifySuite) TestRemoveWatch(c *C) {
+	c.Assert(os.MkdirAll(filepath.Join(s.dir, "a"), 0755), IsNil)
+	c.Assert(s.w.AddRecursiveWatch(s.dir, inotify.InCreate), IsNil)
+
+	c.Assert(s.w.RemoveWatch(s.dir), IsNil)
+
+	// neither the directory nor the ones under it are watched anymore
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "foo"), nil, 0644), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "a/foo"), nil, 0644), IsNil)
+	select {
+	case ev := <-s.w.Event:
+		c.Fatalf("unexpected event: %v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	c.Check(s.w.RemoveWatch(s.dir), ErrorMatches, `cannot remove watch: ".*" is not watched`)
+}
+
+func (s *inotifySuite) TestClose(c *C) {
+	w, err := inotify.NewWatcher()
+	c.Assert(err, IsNil)
+	c.Assert(w.AddWatch(s.dir, inotify.InCreate), IsNil)
+	c.Assert(w.Close(), IsNil)
+
+	select {
+	case _, ok := <-w.Event:
+		c.Check(ok, Equals, false)
+	case <-time.After(5 * time.Second):
+		c.Fatalf("event channel not closed")
+	}
+	// closing again is fine
+	c.Check(w.Close(), IsNil)
+}
+
+func rawEventBytes(wd int32, mask uint32, name string) []byte {
+	var buf bytes.Buffer
+	nameLen := 0
+	if name != "" {
+		// NUL terminated and padded
+		nameLen = (len(name)/16 + 1) * 16
+	}
+	binary.Write(&buf, binary.LittleEndian, wd)
+	binary.Write(&buf, binary.LittleEndian, mask)
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	binary.Write(&buf, binary.LittleEndian, uint32(nameLen))
+	buf.WriteString(name)
+	buf.Write(make([]byte, nameLen-len(name)))
+	return buf.Bytes()
+}
+
+func (s *inotifySuite) TestParseEvents(c *C) {
+	buf := append(rawEventBytes(1, inotify.InCreate, "foo"), rawEventBytes(2, inotify.InDeleteSelf, "")...)
+	events, err := inotify.ParseEvents(buf)
+	c.Assert(err, IsNil)
+	c.Check(events, DeepEquals, []inotify.RawEvent{
+		inotify.NewRawEvent(1, inotify.InCreate, "foo"),
+		inotify.NewRawEvent(2, inotify.InDeleteSelf, ""),
+	})
+
+	_, err = inotify.ParseEvents(buf[:len(buf)-1])
+	c.Check(err, ErrorMatches, "cannot parse inotify event: short buffer")
+}
+
+func (s *inotifySuite) TestCoalesceEvents(c *C) {
+	events := inotify.CoalesceEvents([]inotify.RawEvent{
+		inotify.NewRawEvent(1, inotify.InCreate, "foo"),
+		inotify.NewRawEvent(1, inotify.InModify, "foo"),
+		inotify.NewRawEvent(1, inotify.InModify, "bar"),
+		inotify.NewRawEvent(1, inotify.InModify, "foo"),
+		inotify.NewRawEvent(2, inotify.InModify, "foo"),
+		inotify.NewRawEvent(1, inotify.InDelete, "foo"),
+		inotify.NewRawEvent(1, inotify.InCreate, "foo"),
+		inotify.NewRawEvent(1, inotify.InModify, "foo"),
+	})
+	c.Check(events, DeepEquals, []inotify.RawEvent{
+		inotify.NewRawEvent(1, inotify.InCreate, "foo"),
+		inotify.NewRawEvent(1, inotify.InModify, "foo"),
+		inotify.NewRawEvent(1, inotify.InModify, "bar"),
+		inotify.NewRawEvent(2, inotify.InModify, "foo"),
+		inotify.NewRawEvent(1, inotify.InDelete, "foo"),
+		inotify.NewRawEvent(1, inotify.InCreate, "foo"),
+		inotify.NewRawEvent(1, inotify.InModify, "foo"),
+	})
+}
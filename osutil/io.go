@@ -258,13 +258,14 @@ func AtomicRename(oldName, newName string) error {
 		oldDirPath := filepath.Dir(oldName)
 		newDirPath := filepath.Dir(newName)
 
-		oldDir, err := os.Open(oldDirPath)
+		var err error
+		oldDir, err = os.Open(oldDirPath)
 		if err != nil {
 			return err
 		}
 		defer oldDir.Close()
 
-		newDir, err := os.Open(newDirPath)
+		newDir, err = os.Open(newDirPath)
 		if err != nil {
 			return err
 		}
@@ -304,6 +305,22 @@ func AtomicRename(oldName, newName string) error {
 	return err2
 }
 
+// SyncDir flushes the entries of the given directory to permanent storage,
+// so that files created, renamed or removed in it survive a power cut.
+func SyncDir(dirPath string) error {
+	// snapdUnsafeIO controls the ability to ignore expensive disk
+	// synchronization. It is only used inside tests.
+	if snapdUnsafeIO {
+		return nil
+	}
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
 const maxSymlinkTries = 10
 
 // AtomicSymlink attempts to atomically create a symlink at linkPath, pointing
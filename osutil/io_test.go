@@ -451,6 +451,21 @@ func (ts *AtomicRenameTestSuite) TestAtomicRename(c *C) {
 	c.Assert(err, ErrorMatches, "rename /.*/does-not-exist /.*/nested/bar: no such file or directory")
 }
 
+func (ts *AtomicRenameTestSuite) TestSyncDir(c *C) {
+	d := c.MkDir()
+
+	err := osutil.SyncDir(d)
+	c.Assert(err, IsNil)
+
+	err = osutil.SyncDir(filepath.Join(d, "does-not-exist"))
+	if !osutil.GetUnsafeIO() {
+		c.Assert(err, ErrorMatches, "open /.*/does-not-exist: no such file or directory")
+	} else {
+		// nothing is synced with unsafe IO
+		c.Assert(err, IsNil)
+	}
+}
+
 // SafeIoAtomicTestSuite runs all Atomic* tests with safe
 // io enabled
 type SafeIoAtomicTestSuite struct {
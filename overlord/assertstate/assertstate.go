@@ -47,6 +47,13 @@ func AddBatch(s *state.State, batch *asserts.Batch, opts *asserts.CommitOptions)
 	return batch.CommitTo(cachedDB(s), opts)
 }
 
+// TemporaryDB returns a database stacked on top of the system
+// assertion database. Assertions added to it are not added to the
+// system assertion database.
+func TemporaryDB(s *state.State) *asserts.Database {
+	return cachedDB(s).WithStackedBackstore(asserts.NewMemoryBackstore())
+}
+
 func findError(format string, ref *asserts.Ref, err error) error {
 	if asserts.IsNotFound(err) {
 		return fmt.Errorf(format, ref)
@@ -478,3 +485,58 @@ func ValidationSetAssertionForMonitor(st *state.State, accountID, name string, s
 	}
 	return as, false, err
 }
+
+// EnforceValidationSet tries to fetch the given validation set and enforce it.
+// The validation set is checked for conflicts with the other enforced
+// validation sets and the given installed snaps must satisfy it, otherwise
+// a *snapasserts.ValidationSetsValidationError is returned. If all checks
+// pass, the validation set is tracked in enforcing mode.
+func EnforceValidationSet(st *state.State, accountID, name string, sequence, userID int, snaps []*snapasserts.InstalledSnap) (*ValidationSetTracking, error) {
+	pinned := sequence > 0
+	// unlike in monitor mode, a local-only assertion cannot be used
+	as, _, err := ValidationSetAssertionForMonitor(st, accountID, name, sequence, pinned, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	valsets, err := ValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+	db := DB(st)
+	sets := snapasserts.NewValidationSets()
+	if err := sets.Add(as); err != nil {
+		return nil, err
+	}
+	key := ValidationSetKey(accountID, name)
+	for vskey, vs := range valsets {
+		// the validation set being enforced replaces its tracking
+		if vs.Mode != Enforce || vskey == key {
+			continue
+		}
+		other, err := trackedValidationSet(db, vs)
+		if err != nil {
+			return nil, err
+		}
+		if err := sets.Add(other); err != nil {
+			return nil, err
+		}
+	}
+	if err := sets.Conflict(); err != nil {
+		return nil, err
+	}
+	if err := sets.CheckInstalledSnaps(snaps); err != nil {
+		return nil, err
+	}
+
+	tr := &ValidationSetTracking{
+		AccountID: accountID,
+		Name:      name,
+		Mode:      Enforce,
+		// note, PinnedAt may be 0, meaning not pinned.
+		PinnedAt: sequence,
+		Current:  as.Sequence(),
+	}
+	UpdateValidationSet(st, tr)
+	return tr, nil
+}
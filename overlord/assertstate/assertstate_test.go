@@ -39,6 +39,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/asserts/sysdb"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/httputil"
@@ -2564,3 +2565,161 @@ func (s *assertMgrSuite) TestValidationSetAssertionForMonitorUnpinnedNotFound(c
 	_, _, err := assertstate.ValidationSetAssertionForMonitor(st, s.dev1Acct.AccountID(), "bar", 0, false, 0, nil)
 	c.Assert(err, check.ErrorMatches, fmt.Sprintf(`cannot fetch and resolve assertions:\n - validation-set/16/%s/bar: validation-set assertion not found.*`, s.dev1Acct.AccountID()))
 }
+
+func (s *assertMgrSuite) TestEnforceValidationSetAssertion(c *C) {
+	st := s.state
+
+	st.Lock()
+	defer st.Unlock()
+
+	// have a model and the store assertion available
+	storeAs := s.setupModelAndStore(c)
+	c.Assert(s.storeSigning.Add(storeAs), check.IsNil)
+
+	vsetAs := s.validationSetAssert(c, "bar", "2", "1")
+	c.Assert(s.storeSigning.Add(vsetAs), check.IsNil)
+
+	snaps := []*snapasserts.InstalledSnap{
+		snapasserts.NewInstalledSnap("foo", "qOqKhntON3vR7kwEbVPsILm7bUViPDzz", snap.R(1)),
+	}
+	tr, err := assertstate.EnforceValidationSet(st, s.dev1Acct.AccountID(), "bar", 0, 0, snaps)
+	c.Assert(err, IsNil)
+	c.Check(tr, DeepEquals, &assertstate.ValidationSetTracking{
+		AccountID: s.dev1Acct.AccountID(),
+		Name:      "bar",
+		Mode:      assertstate.Enforce,
+		Current:   2,
+	})
+
+	var stored assertstate.ValidationSetTracking
+	c.Assert(assertstate.GetValidationSet(st, s.dev1Acct.AccountID(), "bar", &stored), IsNil)
+	c.Check(&stored, DeepEquals, tr)
+
+	// the assertion was fetched
+	_, err = assertstate.DB(st).Find(asserts.ValidationSetType, map[string]string{
+		"series":     "16",
+		"account-id": s.dev1Acct.AccountID(),
+		"name":       "bar",
+		"sequence":   "2",
+	})
+	c.Assert(err, IsNil)
+
+	sets, err := assertstate.EnforcedValidationSets(st)
+	c.Assert(err, IsNil)
+	c.Check(sets.CheckInstalledSnaps(snaps), IsNil)
+	c.Check(sets.CheckInstalledSnaps(nil), ErrorMatches, `validation sets assertions are not met:\n- missing required snaps:\n  - foo \(required by sets .*/bar\)`)
+}
+
+func (s *assertMgrSuite) TestEnforceValidationSetAssertionUnmet(c *C) {
+	st := s.state
+
+	st.Lock()
+	defer st.Unlock()
+
+	// have a model and the store assertion available
+	storeAs := s.setupModelAndStore(c)
+	c.Assert(s.storeSigning.Add(storeAs), check.IsNil)
+
+	vsetAs := s.validationSetAssert(c, "bar", "1", "1")
+	c.Assert(s.storeSigning.Add(vsetAs), check.IsNil)
+
+	snaps := []*snapasserts.InstalledSnap{
+		snapasserts.NewInstalledSnap("foo", "qOqKhntON3vR7kwEbVPsILm7bUViPDzz", snap.R(3)),
+	}
+	_, err := assertstate.EnforceValidationSet(st, s.dev1Acct.AccountID(), "bar", 1, 0, snaps)
+	verr, ok := err.(*snapasserts.ValidationSetsValidationError)
+	c.Assert(ok, Equals, true, Commentf("unexpected error: %v", err))
+	c.Check(verr.WrongRevisionSnaps, DeepEquals, map[string]map[snap.Revision][]string{
+		"foo": {snap.R(1): {s.dev1Acct.AccountID() + "/bar"}},
+	})
+
+	// not tracked
+	var tr assertstate.ValidationSetTracking
+	c.Check(assertstate.GetValidationSet(st, s.dev1Acct.AccountID(), "bar", &tr), Equals, state.ErrNoState)
+}
+
+func (s *assertMgrSuite) TestEnforceValidationSetAssertionConflict(c *C) {
+	st := s.state
+
+	st.Lock()
+	defer st.Unlock()
+
+	// have a model and the store assertion available
+	storeAs := s.setupModelAndStore(c)
+	c.Assert(s.storeSigning.Add(storeAs), check.IsNil)
+	c.Assert(assertstate.Add(st, s.storeSigning.StoreAccountKey("")), check.IsNil)
+	c.Assert(assertstate.Add(st, s.dev1Acct), check.IsNil)
+	c.Assert(assertstate.Add(st, s.dev1AcctKey), check.IsNil)
+
+	// bar is already enforced and requires foo at revision 1
+	vsetAs1 := s.validationSetAssert(c, "bar", "1", "1")
+	c.Assert(assertstate.Add(st, vsetAs1), check.IsNil)
+	assertstate.UpdateValidationSet(st, &assertstate.ValidationSetTracking{
+		AccountID: s.dev1Acct.AccountID(),
+		Name:      "bar",
+		Mode:      assertstate.Enforce,
+		Current:   1,
+	})
+
+	// baz wants foo to be invalid
+	a, err := s.dev1Signing.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"series":       "16",
+		"account-id":   s.dev1Acct.AccountID(),
+		"authority-id": s.dev1Acct.AccountID(),
+		"publisher-id": s.dev1Acct.AccountID(),
+		"name":         "baz",
+		"sequence":     "1",
+		"snaps": []interface{}{map[string]interface{}{
+			"id":       "qOqKhntON3vR7kwEbVPsILm7bUViPDzz",
+			"name":     "foo",
+			"presence": "invalid",
+		}},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	c.Assert(s.storeSigning.Add(a), check.IsNil)
+
+	_, err = assertstate.EnforceValidationSet(st, s.dev1Acct.AccountID(), "baz", 0, 0, nil)
+	c.Assert(err, ErrorMatches, `validation sets are in conflict:\n- cannot constrain snap "foo" as both invalid \(.*/baz\) and required at revision 1 \(.*/bar\)`)
+}
+
+func (s *assertMgrSuite) TestTemporaryDB(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	err := assertstate.Add(st, s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+
+	a, err := s.storeSigning.Sign(asserts.ModelType, map[string]interface{}{
+		"series":       "16",
+		"brand-id":     s.storeSigning.AuthorityID,
+		"model":        "my-model",
+		"architecture": "amd64",
+		"gadget":       "gadget",
+		"kernel":       "krnl",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	model := a.(*asserts.Model)
+
+	hdrs := map[string]string{
+		"series":   "16",
+		"model":    "my-model",
+		"brand-id": s.storeSigning.AuthorityID,
+	}
+	// the model is not in the system db
+	_, err = assertstate.DB(st).Find(asserts.ModelType, hdrs)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+
+	tmpDB := assertstate.TemporaryDB(st)
+	c.Assert(tmpDB, NotNil)
+	c.Assert(tmpDB.Add(model), IsNil)
+
+	// the model is in the temporary db
+	_, err = tmpDB.Find(asserts.ModelType, hdrs)
+	c.Check(err, IsNil)
+	// but still not in the system db
+	_, err = assertstate.DB(st).Find(asserts.ModelType, hdrs)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
@@ -23,7 +23,10 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
 )
 
 // ValidationSetMode reflects the mode of respective validation set, which is
@@ -131,3 +134,51 @@ func ValidationSets(st *state.State) (map[string]*ValidationSetTracking, error)
 	}
 	return vsmap, nil
 }
+
+// Sequence returns the sequence number of the currently used validation set.
+func (vs *ValidationSetTracking) Sequence() int {
+	if vs.PinnedAt > 0 {
+		return vs.PinnedAt
+	}
+	return vs.Current
+}
+
+// EnforcedValidationSets returns ValidationSets object with all currently
+// tracked validation sets that are in enforcing mode.
+func EnforcedValidationSets(st *state.State) (*snapasserts.ValidationSets, error) {
+	valsets, err := ValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+
+	db := DB(st)
+	sets := snapasserts.NewValidationSets()
+	for _, vs := range valsets {
+		if vs.Mode != Enforce {
+			continue
+		}
+		as, err := trackedValidationSet(db, vs)
+		if err != nil {
+			return nil, err
+		}
+		if err := sets.Add(as); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// trackedValidationSet finds the validation set assertion currently used by
+// the given tracking in the assertion database.
+func trackedValidationSet(db asserts.RODatabase, vs *ValidationSetTracking) (*asserts.ValidationSet, error) {
+	as, err := db.Find(asserts.ValidationSetType, map[string]string{
+		"series":     release.Series,
+		"account-id": vs.AccountID,
+		"name":       vs.Name,
+		"sequence":   fmt.Sprintf("%d", vs.Sequence()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return as.(*asserts.ValidationSet), nil
+}
@@ -22,18 +22,38 @@ package overlord
 import (
 	"time"
 
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
+// stateBackupInterval is the minimum time between two backups of the state
+// taken when it gets checkpointed.
+var stateBackupInterval = 10 * time.Minute
+
 type overlordStateBackend struct {
 	path           string
 	ensureBefore   func(d time.Duration)
 	requestRestart func(t state.RestartType)
+
+	lastBackup time.Time
 }
 
 func (osb *overlordStateBackend) Checkpoint(data []byte) error {
-	return osutil.AtomicWriteFile(osb.path, data, 0600, 0)
+	if err := osutil.AtomicWriteFile(osb.path, data, 0600, 0); err != nil {
+		return err
+	}
+	now := timeNow()
+	if now.Sub(osb.lastBackup) < stateBackupInterval {
+		return nil
+	}
+	// the state itself was written, failing to back it up is not fatal
+	if err := writeStateBackup(stateBackupPath(osb.path), data); err != nil {
+		logger.Noticef("cannot back up state: %v", err)
+		return nil
+	}
+	osb.lastBackup = now
+	return nil
 }
 
 func (osb *overlordStateBackend) EnsureBefore(d time.Duration) {
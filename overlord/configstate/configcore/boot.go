@@ -0,0 +1,71 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+)
+
+func init() {
+	// add supported configuration of this module
+	supportedConfigurations["core.system.boot.kernel-retention"] = true
+}
+
+// maxKernelRetention is the maximum number of kernels that can be kept
+// trusted, each of them is part of the boot chains the encryption keys are
+// sealed to.
+const maxKernelRetention = 4
+
+func kernelRetention(tr config.ConfGetter) (int, error) {
+	output, err := coreCfg(tr, "system.boot.kernel-retention")
+	if err != nil {
+		return 0, err
+	}
+	if output == "" {
+		return 0, nil
+	}
+	kernels, err := strconv.Atoi(output)
+	if err != nil || kernels < 1 || kernels > maxKernelRetention {
+		return 0, fmt.Errorf("system.boot.kernel-retention must be a number between 1 and %d, not %q", maxKernelRetention, output)
+	}
+	return kernels, nil
+}
+
+func validateKernelRetention(tr config.ConfGetter) error {
+	_, err := kernelRetention(tr)
+	return err
+}
+
+func handleKernelRetention(tr config.ConfGetter, opts *fsOnlyContext) error {
+	kernels, err := kernelRetention(tr)
+	if err != nil {
+		return err
+	}
+	rootDir := dirs.GlobalRootDir
+	if opts != nil {
+		rootDir = opts.RootDir
+	}
+	return boot.SetKernelRetention(rootDir, kernels)
+}
@@ -0,0 +1,120 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+	"github.com/snapcore/snapd/release"
+)
+
+type bootSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&bootSuite{})
+
+func (s *bootSuite) SetUpTest(c *C) {
+	s.configcoreSuite.SetUpTest(c)
+
+	restore := release.MockOnClassic(false)
+	s.AddCleanup(restore)
+
+	err := os.MkdirAll(filepath.Join(dirs.GlobalRootDir, "/etc/"), 0755)
+	c.Assert(err, IsNil)
+
+	m := &boot.Modeenv{
+		Mode:           "run",
+		CurrentKernels: []string{"pc-kernel_1.snap"},
+	}
+	c.Assert(m.WriteTo(""), IsNil)
+}
+
+func (s *bootSuite) TestConfigureKernelRetention(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.boot.kernel-retention": "2",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	m, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.KernelRetention, Equals, 2)
+
+	// unsetting it goes back to the default
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"system.boot.kernel-retention": "",
+		},
+	})
+	c.Assert(err, IsNil)
+
+	m, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(m.KernelRetention, Equals, 0)
+}
+
+func (s *bootSuite) TestConfigureKernelRetentionInvalid(c *C) {
+	for _, v := range []string{"0", "5", "-1", "all"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"system.boot.kernel-retention": v,
+			},
+		})
+		c.Check(err, ErrorMatches, `system.boot.kernel-retention must be a number between 1 and 4, not ".*"`)
+	}
+}
+
+func (s *bootSuite) TestFilesystemOnlyApply(c *C) {
+	tmpDir := c.MkDir()
+	m := &boot.Modeenv{Mode: "run"}
+	c.Assert(m.WriteTo(tmpDir), IsNil)
+
+	conf := configcore.PlainCoreConfig(map[string]interface{}{
+		"system.boot.kernel-retention": "3",
+	})
+	c.Assert(configcore.FilesystemOnlyApply(tmpDir, conf, nil), IsNil)
+
+	m, err := boot.ReadModeenv(tmpDir)
+	c.Assert(err, IsNil)
+	c.Check(m.KernelRetention, Equals, 3)
+
+	// the modeenv under the global root is left alone
+	m, err = boot.ReadModeenv(dirs.GlobalRootDir)
+	c.Assert(err, IsNil)
+	c.Check(m.KernelRetention, Equals, 0)
+}
+
+func (s *bootSuite) TestFilesystemOnlyApplyNoModeenv(c *C) {
+	conf := configcore.PlainCoreConfig(map[string]interface{}{
+		"system.boot.kernel-retention": "3",
+	})
+	c.Assert(configcore.FilesystemOnlyApply(c.MkDir(), conf, nil), IsNil)
+}
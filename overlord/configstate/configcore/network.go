@@ -26,6 +26,7 @@ import (
 	"path/filepath"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 )
@@ -73,8 +74,14 @@ func handleNetworkConfiguration(tr config.ConfGetter, opts *fsOnlyContext) error
 		}
 	}
 
-	// write the new config
+	// remember the old config in case the new one cannot be loaded
 	glob := name
+	oldDirContent, err := currentDirState(dir, glob)
+	if err != nil {
+		return err
+	}
+
+	// write the new config
 	changed, removed, err := osutil.EnsureDirState(dir, glob, dirContent)
 	if err != nil {
 		return err
@@ -85,6 +92,7 @@ func handleNetworkConfiguration(tr config.ConfGetter, opts *fsOnlyContext) error
 		if len(changed) > 0 || len(removed) > 0 {
 			output, err := exec.Command("sysctl", "-w", sysctl).CombinedOutput()
 			if err != nil {
+				restoreNetworkConfiguration(dir, glob, oldDirContent)
 				return osutil.OutputErr(output, err)
 			}
 		}
@@ -92,3 +100,19 @@ func handleNetworkConfiguration(tr config.ConfGetter, opts *fsOnlyContext) error
 
 	return nil
 }
+
+// restoreNetworkConfiguration puts back the previous network configuration
+// and loads it into the kernel again.
+func restoreNetworkConfiguration(dir, glob string, oldDirContent map[string]osutil.FileState) {
+	if _, _, err := osutil.EnsureDirState(dir, glob, oldDirContent); err != nil {
+		logger.Noticef("cannot restore previous network configuration: %v", err)
+		return
+	}
+	sysctl := "net.ipv6.conf.all.disable_ipv6=0"
+	if len(oldDirContent) > 0 {
+		sysctl = "net.ipv6.conf.all.disable_ipv6=1"
+	}
+	if output, err := exec.Command("sysctl", "-w", sysctl).CombinedOutput(); err != nil {
+		logger.Noticef("cannot load previous network configuration: %v", osutil.OutputErr(output, err))
+	}
+}
@@ -20,6 +20,7 @@
 package configcore_test
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -94,6 +95,44 @@ func (s *networkSuite) TestConfigureNetworkIntegrationIPv6(c *C) {
 	c.Check(s.mockSysctl.Calls(), HasLen, 0)
 }
 
+func (s *networkSuite) TestConfigureNetworkIntegrationIPv6SysctlFailsRollsBack(c *C) {
+	s.mockSysctl = testutil.MockCommand(c, "sysctl", "echo sysctl failed; exit 1")
+	defer s.mockSysctl.Restore()
+
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"network.disable-ipv6": true,
+		},
+	})
+	c.Assert(err, ErrorMatches, "sysctl failed")
+
+	// the config that could not be applied is not left behind
+	c.Check(osutil.FileExists(s.mockNetworkSysctlPath), Equals, false)
+	// and the previous setting is loaded again
+	c.Check(s.mockSysctl.Calls(), DeepEquals, [][]string{
+		{"sysctl", "-w", "net.ipv6.conf.all.disable_ipv6=1"},
+		{"sysctl", "-w", "net.ipv6.conf.all.disable_ipv6=0"},
+	})
+	s.mockSysctl.ForgetCalls()
+
+	// and an existing config is restored
+	err = ioutil.WriteFile(s.mockNetworkSysctlPath, []byte("net.ipv6.conf.all.disable_ipv6=1\n"), 0644)
+	c.Assert(err, IsNil)
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"network.disable-ipv6": false,
+		},
+	})
+	c.Assert(err, ErrorMatches, "sysctl failed")
+	c.Check(s.mockNetworkSysctlPath, testutil.FileEquals, "net.ipv6.conf.all.disable_ipv6=1\n")
+	c.Check(s.mockSysctl.Calls(), DeepEquals, [][]string{
+		{"sysctl", "-w", "net.ipv6.conf.all.disable_ipv6=0"},
+		{"sysctl", "-w", "net.ipv6.conf.all.disable_ipv6=1"},
+	})
+}
+
 func (s *networkSuite) TestConfigureNetworkIntegrationNoSetting(c *C) {
 	err := configcore.Run(&mockConf{
 		state: s.state,
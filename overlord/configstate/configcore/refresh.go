@@ -23,10 +23,12 @@ package configcore
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timeutil"
 )
@@ -37,9 +39,36 @@ func init() {
 	supportedConfigurations["core.refresh.timer"] = true
 	supportedConfigurations["core.refresh.metered"] = true
 	supportedConfigurations["core.refresh.retain"] = true
+	for _, typ := range retainSnapTypes {
+		supportedConfigurations["core.refresh.retain-"+string(typ)] = true
+	}
 	supportedConfigurations["core.refresh.rate-limit"] = true
 }
 
+// retainSnapTypes are the snap types for which the number of retained
+// revisions can be set with refresh.retain-<type>.
+var retainSnapTypes = []snap.Type{
+	snap.TypeApp,
+	snap.TypeGadget,
+	snap.TypeKernel,
+	snap.TypeBase,
+	snap.TypeOS,
+	snap.TypeSnapd,
+}
+
+func validateRefreshRetain(tr config.Conf, option string) error {
+	refreshRetainStr, err := coreCfg(tr, option)
+	if err != nil {
+		return err
+	}
+	if refreshRetainStr != "" {
+		if n, err := strconv.ParseUint(refreshRetainStr, 10, 8); err != nil || (n < 2 || n > 20) {
+			return fmt.Errorf("%s must be a number between 2 and 20, not %q", strings.TrimPrefix(option, "refresh."), refreshRetainStr)
+		}
+	}
+	return nil
+}
+
 func reportOrIgnoreInvalidManageRefreshes(tr config.Conf, optName string) error {
 	// check if the option is set as part of transaction changes; if not than
 	// it's already set in the config state and we shouldn't error out about it
@@ -54,13 +83,12 @@ func reportOrIgnoreInvalidManageRefreshes(tr config.Conf, optName string) error
 }
 
 func validateRefreshSchedule(tr config.Conf) error {
-	refreshRetainStr, err := coreCfg(tr, "refresh.retain")
-	if err != nil {
+	if err := validateRefreshRetain(tr, "refresh.retain"); err != nil {
 		return err
 	}
-	if refreshRetainStr != "" {
-		if n, err := strconv.ParseUint(refreshRetainStr, 10, 8); err != nil || (n < 2 || n > 20) {
-			return fmt.Errorf("retain must be a number between 2 and 20, not %q", refreshRetainStr)
+	for _, typ := range retainSnapTypes {
+		if err := validateRefreshRetain(tr, "refresh.retain-"+string(typ)); err != nil {
+			return err
 		}
 	}
 
@@ -79,7 +107,7 @@ func validateRefreshSchedule(tr config.Conf) error {
 		return err
 	}
 	switch refreshOnMeteredStr {
-	case "", "hold":
+	case "", "hold", "allow":
 		// noop
 	default:
 		return fmt.Errorf("refresh.metered value %q is invalid", refreshOnMeteredStr)
@@ -161,6 +161,14 @@ func (s *refreshSuite) TestConfigureRefreshHoldOnMeteredHappy(c *C) {
 	})
 	c.Assert(err, IsNil)
 
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.metered": "allow",
+		},
+	})
+	c.Assert(err, IsNil)
+
 	err = configcore.Run(&mockConf{
 		state: s.state,
 		conf: map[string]interface{}{
@@ -200,6 +208,28 @@ func (s *refreshSuite) TestConfigureRefreshRetainOverRange(c *C) {
 	c.Assert(err, ErrorMatches, `retain must be a number between 2 and 20, not "100"`)
 }
 
+func (s *refreshSuite) TestConfigureRefreshRetainPerTypeHappy(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.retain":        "2",
+			"refresh.retain-kernel": "4",
+			"refresh.retain-app":    "3",
+		},
+	})
+	c.Assert(err, IsNil)
+}
+
+func (s *refreshSuite) TestConfigureRefreshRetainPerTypeOutOfRange(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"refresh.retain-kernel": "21",
+		},
+	})
+	c.Assert(err, ErrorMatches, `retain-kernel must be a number between 2 and 20, not "21"`)
+}
+
 func (s *refreshSuite) TestConfigureRefreshRetainInvalid(c *C) {
 	err := configcore.Run(&mockConf{
 		state: s.state,
@@ -110,7 +110,7 @@ func switchDisableConsoleConfService(sysd systemd.Systemd, serviceName string, d
 		//      defaults and compare with the setting and exit if
 		//      they are the same but that requires some more changes.
 		mode, _, _ := boot.ModeAndRecoverySystemFromKernelCommandLine()
-		if mode == boot.ModeInstall {
+		if mode == boot.ModeInstall || mode == boot.ModeFactoryReset {
 			return nil
 		}
 
@@ -23,7 +23,12 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
+
+	"github.com/snapcore/snapd/osutil"
 )
 
 // first match is if it is comment, second is key, third value
@@ -120,3 +125,29 @@ func applyFilters(flagFilter func(f flags) filterFunc, values map[string]interfa
 
 	return filteredValues, filteredHandlers
 }
+
+// currentDirState returns the content of the files in dir matching glob
+// in the form taken by osutil.EnsureDirState, so that it can be put
+// back if applying a new configuration fails.
+func currentDirState(dir, glob string) (map[string]osutil.FileState, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, glob))
+	if err != nil {
+		return nil, err
+	}
+	content := make(map[string]osutil.FileState, len(matches))
+	for _, path := range matches {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content[filepath.Base(path)] = &osutil.MemoryFileState{
+			Content: data,
+			Mode:    fi.Mode().Perm(),
+		}
+	}
+	return content, nil
+}
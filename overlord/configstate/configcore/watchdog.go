@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/systemd"
@@ -72,6 +73,11 @@ func updateWatchdogConfig(config map[string]uint, opts *fsOnlyContext) error {
 	}
 
 	glob := name
+	// remember the old config in case systemd cannot pick up the new one
+	oldDirContent, err := currentDirState(dir, glob)
+	if err != nil {
+		return err
+	}
 	changed, removed, err := osutil.EnsureDirState(dir, glob, dirContent)
 	if err != nil {
 		return err
@@ -79,7 +85,16 @@ func updateWatchdogConfig(config map[string]uint, opts *fsOnlyContext) error {
 
 	// something was changed, reexec systemd manager
 	if sysd != nil && (len(changed) > 0 || len(removed) > 0) {
-		return sysd.DaemonReexec()
+		if err := sysd.DaemonReexec(); err != nil {
+			// put back the previous config and have systemd
+			// pick it up again
+			if _, _, rerr := osutil.EnsureDirState(dir, glob, oldDirContent); rerr != nil {
+				logger.Noticef("cannot restore previous watchdog configuration: %v", rerr)
+			} else if rerr := sysd.DaemonReexec(); rerr != nil {
+				logger.Noticef("cannot load previous watchdog configuration: %v", rerr)
+			}
+			return err
+		}
 	}
 
 	return nil
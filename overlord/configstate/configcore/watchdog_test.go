@@ -32,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/configstate/configcore"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/testutil"
 )
 
@@ -214,6 +215,36 @@ func (s *watchdogSuite) TestConfigureWatchdogNoFileUpdate(c *C) {
 	c.Check(s.systemctlArgs, HasLen, 0)
 }
 
+func (s *watchdogSuite) TestConfigureWatchdogReexecFailsRollsBack(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	err := os.MkdirAll(dirs.SnapSystemdConfDir, 0755)
+	c.Assert(err, IsNil)
+	content := "[Manager]\nRuntimeWatchdogSec=10\n"
+	err = ioutil.WriteFile(s.mockEtcEnvironment, []byte(content), 0644)
+	c.Assert(err, IsNil)
+
+	restore = systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		s.systemctlArgs = append(s.systemctlArgs, args[:])
+		return nil, fmt.Errorf("systemctl failed")
+	})
+	defer restore()
+
+	err = configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"watchdog.runtime-timeout": "20s",
+		},
+	})
+	c.Assert(err, ErrorMatches, "systemctl failed")
+	// systemd is asked to pick up the previous config again
+	c.Check(s.systemctlArgs, DeepEquals, [][]string{{"daemon-reexec"}, {"daemon-reexec"}})
+
+	// the previous config is back in place
+	c.Check(s.mockEtcEnvironment, testutil.FileEquals, content)
+}
+
 func (s *watchdogSuite) TestConfigureWatchdogRemovesIfEmpty(c *C) {
 	restore := release.MockOnClassic(false)
 	defer restore()
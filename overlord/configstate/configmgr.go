@@ -44,6 +44,7 @@ func Init(st *state.State, hookManager *hookstate.HookManager) error {
 	// Most configuration is handled via the "configure" hook of the
 	// snaps. However some configuration is internally handled
 	hookManager.Register(regexp.MustCompile("^configure$"), newConfigureHandler)
+	hookManager.Register(regexp.MustCompile("^default-configure$"), newDefaultConfigureHandler)
 	// Ensure that we run configure for the core snap internally.
 	// Note that we use the func() indirection so that mocking configcoreRun
 	// in tests works correctly.
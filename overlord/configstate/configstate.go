@@ -39,6 +39,7 @@ import (
 
 func init() {
 	snapstate.Configure = Configure
+	snapstate.DefaultConfigure = DefaultConfigure
 }
 
 func ConfigureHookTimeout() time.Duration {
@@ -129,6 +130,23 @@ func Configure(st *state.State, snapName string, patch map[string]interface{}, f
 	return state.NewTaskSet(task)
 }
 
+// DefaultConfigure returns a task to run the default-configure hook of a
+// snap, which is executed with the gadget configuration defaults applied on
+// first installation, before the services of the snap are started.
+func DefaultConfigure(st *state.State, snapName string) *state.Task {
+	summary := fmt.Sprintf(i18n.G("Run default-configure hook of %q snap if present"), snapName)
+	hooksup := &hookstate.HookSetup{
+		Snap:     snapName,
+		Hook:     "default-configure",
+		Optional: true,
+		// all configure hooks must finish within this timeout
+		Timeout: ConfigureHookTimeout(),
+	}
+	// the default-configure hook always uses the gadget defaults
+	contextData := map[string]interface{}{"use-defaults": true}
+	return hookstate.HookTask(st, summary, hooksup, contextData)
+}
+
 // RemapSnapFromRequest renames a snap as received from an API request
 func RemapSnapFromRequest(snapName string) string {
 	if snapName == "system" {
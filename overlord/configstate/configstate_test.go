@@ -153,6 +153,28 @@ func (s *tasksetsSuite) TestConfigureInstalled(c *C) {
 	}
 }
 
+func (s *tasksetsSuite) TestDefaultConfigure(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	task := configstate.DefaultConfigure(s.state, "test-snap")
+	c.Assert(task.Kind(), Equals, "run-hook")
+	c.Check(task.Summary(), Equals, `Run default-configure hook of "test-snap" snap if present`)
+
+	var hooksup hookstate.HookSetup
+	c.Assert(task.Get("hook-setup", &hooksup), IsNil)
+	c.Check(hooksup, DeepEquals, hookstate.HookSetup{
+		Snap:     "test-snap",
+		Hook:     "default-configure",
+		Optional: true,
+		Timeout:  5 * time.Minute,
+	})
+
+	var contextData map[string]interface{}
+	c.Assert(task.Get("hook-context", &contextData), IsNil)
+	c.Check(contextData, DeepEquals, map[string]interface{}{"use-defaults": true})
+}
+
 func (s *tasksetsSuite) TestConfigureInstalledConflict(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
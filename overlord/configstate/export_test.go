@@ -23,7 +23,10 @@ import (
 	"github.com/snapcore/snapd/overlord/configstate/config"
 )
 
-var NewConfigureHandler = newConfigureHandler
+var (
+	NewConfigureHandler        = newConfigureHandler
+	NewDefaultConfigureHandler = newDefaultConfigureHandler
+)
 
 func MockConfigcoreExportExperimentalFlags(mock func(tr config.ConfGetter) error) (restore func()) {
 	old := configcoreExportExperimentalFlags
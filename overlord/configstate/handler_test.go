@@ -261,6 +261,65 @@ volumes:
 	c.Check(err, ErrorMatches, `cannot apply gadget config defaults for snap "test-snap", no configure hook`)
 }
 
+func (s *configureHandlerSuite) TestDefaultConfigureBeforeAppliesDefaults(c *C) {
+	r := release.MockOnClassic(false)
+	defer r()
+
+	const mockGadgetSnapYaml = `
+name: canonical-pc
+type: gadget
+`
+	var mockGadgetYaml = []byte(`
+defaults:
+  testsnapidididididididididididid:
+      bar: baz
+
+volumes:
+    volume-id:
+        bootloader: grub
+`)
+
+	info := snaptest.MockSnap(c, mockGadgetSnapYaml, &snap.SideInfo{Revision: snap.R(1)})
+	err := ioutil.WriteFile(filepath.Join(info.MountDir(), "meta", "gadget.yaml"), mockGadgetYaml, 0644)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	snapstate.Set(s.state, "canonical-pc", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "canonical-pc", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "gadget",
+	})
+
+	r = snapstatetest.MockDeviceModel(makeModel(map[string]interface{}{
+		"gadget": "canonical-pc",
+	}))
+	defer r()
+
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "test-snap", Revision: snap.R(11), SnapID: "testsnapidididididididididididid"},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+	s.state.Unlock()
+
+	handler := configstate.NewDefaultConfigureHandler(s.context)
+	c.Assert(handler.Before(), IsNil)
+
+	s.context.Lock()
+	tr := configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+
+	var value string
+	c.Check(tr.Get("test-snap", "bar", &value), IsNil)
+	c.Check(value, Equals, "baz")
+}
+
 type configcoreHandlerSuite struct {
 	testutil.BaseTest
 
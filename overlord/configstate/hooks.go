@@ -132,3 +132,47 @@ func (h *configureHandler) Done() error {
 func (h *configureHandler) Error(err error) error {
 	return nil
 }
+
+// defaultConfigureHandler is the handler for the default-configure hook.
+type defaultConfigureHandler struct {
+	context *hookstate.Context
+}
+
+func newDefaultConfigureHandler(context *hookstate.Context) hookstate.Handler {
+	return &defaultConfigureHandler{context: context}
+}
+
+// Before is called by the HookManager before the default-configure hook is run.
+func (h *defaultConfigureHandler) Before() error {
+	h.context.Lock()
+	defer h.context.Unlock()
+
+	tr := ContextTransaction(h.context)
+
+	instanceName := h.context.InstanceName()
+	st := h.context.State()
+	task, _ := h.context.Task()
+	deviceCtx, err := snapstate.DeviceCtx(st, task, nil)
+	if err != nil {
+		return err
+	}
+
+	patch, err := snapstate.ConfigDefaults(st, deviceCtx, instanceName)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+
+	return config.Patch(tr, instanceName, patch)
+}
+
+// Done is called by the HookManager after the default-configure hook has
+// exited successfully.
+func (h *defaultConfigureHandler) Done() error {
+	return nil
+}
+
+// Error is called by the HookManager after the default-configure hook has
+// exited non-zero, and includes the error.
+func (h *defaultConfigureHandler) Error(err error) error {
+	return nil
+}
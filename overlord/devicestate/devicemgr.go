@@ -53,6 +53,7 @@ import (
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snapfile"
 	"github.com/snapcore/snapd/snapdenv"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/sysconfig"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timings"
@@ -61,6 +62,8 @@ import (
 var (
 	cloudInitStatus   = sysconfig.CloudInitStatus
 	restrictCloudInit = sysconfig.RestrictCloudInit
+
+	bootRecordBootTimings = boot.RecordBootTimings
 )
 
 // EarlyConfig is a hook set by configstate that can process early configuration
@@ -95,6 +98,8 @@ type DeviceManager struct {
 
 	ensureInstalledRan bool
 
+	ensureFactoryResetReportedRan bool
+
 	cloudInitAlreadyRestricted           bool
 	cloudInitErrorAttemptStart           *time.Time
 	cloudInitEnabledInactiveAttemptStart *time.Time
@@ -138,12 +143,17 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 	hookManager.Register(regexp.MustCompile("^prepare-device$"), newBasicHookStateHandler)
 	hookManager.Register(regexp.MustCompile("^install-device$"), newBasicHookStateHandler)
 
-	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
+	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, m.undoGenerateDeviceKey)
 	runner.AddHandler("request-serial", m.doRequestSerial, nil)
 	runner.AddHandler("mark-preseeded", m.doMarkPreseeded, nil)
 	runner.AddHandler("mark-seeded", m.doMarkSeeded, nil)
 	runner.AddHandler("setup-run-system", m.doSetupRunSystem, nil)
 	runner.AddHandler("restart-system-to-run-mode", m.doRestartSystemToRunMode, nil)
+	runner.AddHandler("request-factory-reset", m.doRequestFactoryReset, nil)
+	runner.AddHandler("factory-reset-run-system", m.doFactoryResetRunSystem, nil)
+	runner.AddHandler("finish-factory-reset", m.doFinishFactoryReset, nil)
+	runner.AddHandler("create-recovery-system", m.doCreateRecoverySystem, m.undoCreateRecoverySystem)
+	runner.AddHandler("finalize-recovery-system", m.doFinalizeRecoverySystem, m.undoFinalizeRecoverySystem)
 	runner.AddHandler("prepare-remodeling", m.doPrepareRemodeling, nil)
 	runner.AddCleanup("prepare-remodeling", m.cleanupRemodel)
 	// this *must* always run last and finalizes a remodel
@@ -685,6 +695,18 @@ func (m *DeviceManager) ensureSeeded() error {
 	return nil
 }
 
+// recordBootTimings records the timings of the current boot, failing to do
+// so is not fatal.
+func (m *DeviceManager) recordBootTimings(deviceCtx snapstate.DeviceContext) {
+	var kernelSnap string
+	if kernel, err := boot.GetCurrentBoot(snap.TypeKernel, deviceCtx); err == nil {
+		kernelSnap = kernel.Filename()
+	}
+	if err := bootRecordBootTimings(kernelSnap); err != nil {
+		logger.Noticef("cannot record boot timings: %v", err)
+	}
+}
+
 // ResetBootOk is only useful for integration testing
 func (m *DeviceManager) ResetBootOk() {
 	m.bootOkRan = false
@@ -704,21 +726,36 @@ func (m *DeviceManager) ensureBootOk() error {
 		return nil
 	}
 
+	if m.bootOkRan && m.bootRevisionsUpdated {
+		return nil
+	}
+
+	perfTimings := timings.New(map[string]string{"ensure": "boot-ok"})
+	defer perfTimings.Save(m.state)
+
 	if !m.bootOkRan {
 		deviceCtx, err := DeviceCtx(m.state, nil, nil)
 		if err != nil && err != state.ErrNoState {
 			return err
 		}
 		if err == nil {
-			if err := boot.MarkBootSuccessful(deviceCtx); err != nil {
+			timings.Run(perfTimings, "mark-boot-successful", "mark boot successful", func(timings.Measurer) {
+				err = boot.MarkBootSuccessful(deviceCtx)
+			})
+			if err != nil {
 				return err
 			}
+			m.recordBootTimings(deviceCtx)
 		}
 		m.bootOkRan = true
 	}
 
 	if !m.bootRevisionsUpdated {
-		if err := snapstate.UpdateBootRevisions(m.state); err != nil {
+		var err error
+		timings.Run(perfTimings, "update-boot-revisions", "update boot revisions", func(timings.Measurer) {
+			err = snapstate.UpdateBootRevisions(m.state)
+		})
+		if err != nil {
 			return err
 		}
 		m.bootRevisionsUpdated = true
@@ -854,6 +891,14 @@ func (m *DeviceManager) ensureCloudInitRestricted() error {
 			// not work differently for later boots, so it's sufficient that
 			// NoCloud runs on first-boot and never again
 			opts.DisableAfterLocalDatasourcesRun = true
+
+			// the gadget can also limit which datasources cloud-init
+			// may keep using, others get cloud-init disabled
+			allowed, err := m.gadgetCloudInitDatasources()
+			if err != nil {
+				return err
+			}
+			opts.AllowedDatasources = allowed
 		}
 
 		// now restrict/disable cloud-init
@@ -867,6 +912,9 @@ func (m *DeviceManager) ensureCloudInitRestricted() error {
 		switch res.Action {
 		case "disable":
 			actionMsg = "disabled permanently"
+			if len(opts.AllowedDatasources) > 0 && res.DataSource != "" && !strutil.ListContains(opts.AllowedDatasources, res.DataSource) {
+				actionMsg = fmt.Sprintf("disabled permanently, datasource %s is not allowed by the gadget", res.DataSource)
+			}
 		case "restrict":
 			// log different messages depending on what datasource was used
 			if res.DataSource == "NoCloud" {
@@ -886,6 +934,23 @@ func (m *DeviceManager) ensureCloudInitRestricted() error {
 	return nil
 }
 
+// gadgetCloudInitDatasources returns the datasources the gadget allows
+// cloud-init to use, if it declares any.
+func (m *DeviceManager) gadgetCloudInitDatasources() ([]string, error) {
+	deviceCtx, err := DeviceCtx(m.state, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	gadgetInfo, err := snapstate.GadgetInfo(m.state, deviceCtx)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sysconfig.GadgetCloudInitDatasources(gadgetInfo.MountDir())
+}
+
 func (m *DeviceManager) ensureInstalled() error {
 	m.state.Lock()
 	defer m.state.Unlock()
@@ -898,9 +963,12 @@ func (m *DeviceManager) ensureInstalled() error {
 		return nil
 	}
 
-	if m.SystemMode() != "install" {
+	// a factory reset is an install that keeps ubuntu-save
+	systemMode := m.SystemMode()
+	if systemMode != "install" && systemMode != "factory-reset" {
 		return nil
 	}
+	factoryReset := systemMode == "factory-reset"
 
 	var seeded bool
 	err := m.state.Get("seeded", &seeded)
@@ -911,7 +979,11 @@ func (m *DeviceManager) ensureInstalled() error {
 		return nil
 	}
 
-	if m.changeInFlight("install-system") {
+	changeKind := "install-system"
+	if factoryReset {
+		changeKind = "factory-reset"
+	}
+	if m.changeInFlight(changeKind) {
 		return nil
 	}
 
@@ -935,7 +1007,12 @@ func (m *DeviceManager) ensureInstalled() error {
 	m.ensureInstalledRan = true
 
 	var prev *state.Task
-	setupRunSystem := m.state.NewTask("setup-run-system", i18n.G("Setup system for run mode"))
+	var setupRunSystem *state.Task
+	if factoryReset {
+		setupRunSystem = m.state.NewTask("factory-reset-run-system", i18n.G("Perform factory reset of the system"))
+	} else {
+		setupRunSystem = m.state.NewTask("setup-run-system", i18n.G("Setup system for run mode"))
+	}
 
 	tasks := []*state.Task{setupRunSystem}
 	addTask := func(t *state.Task) {
@@ -961,12 +1038,60 @@ func (m *DeviceManager) ensureInstalled() error {
 	restartSystem := m.state.NewTask("restart-system-to-run-mode", i18n.G("Ensure next boot to run mode"))
 	addTask(restartSystem)
 
-	chg := m.state.NewChange("install-system", i18n.G("Install the system"))
+	summary := i18n.G("Install the system")
+	if factoryReset {
+		summary = i18n.G("Perform factory reset of the system")
+	}
+	chg := m.state.NewChange(changeKind, summary)
 	chg.AddAll(state.NewTaskSet(tasks...))
 
 	return nil
 }
 
+// ensureFactoryResetReported reports a factory reset that was carried
+// out before rebooting into run mode with a change.
+func (m *DeviceManager) ensureFactoryResetReported() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	if release.OnClassic {
+		return nil
+	}
+
+	if m.ensureFactoryResetReportedRan {
+		return nil
+	}
+
+	if m.SystemMode() != "run" {
+		return nil
+	}
+
+	var seeded bool
+	err := m.state.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !seeded {
+		return nil
+	}
+
+	m.ensureFactoryResetReportedRan = true
+
+	marker, err := readFactoryResetMarker()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	finish := m.state.NewTask("finish-factory-reset", fmt.Sprintf(i18n.G("Finish factory reset from recovery system %q"), marker.RecoverySystem))
+	chg := m.state.NewChange("factory-reset", i18n.G("Factory reset the device"))
+	chg.AddTask(finish)
+
+	return nil
+}
+
 var timeNow = time.Now
 
 // StartOfOperationTime returns the time when snapd started operating,
@@ -1095,6 +1220,10 @@ func (m *DeviceManager) Ensure() error {
 		if err := m.ensureInstalled(); err != nil {
 			errs = append(errs, err)
 		}
+
+		if err := m.ensureFactoryResetReported(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
 	if len(errs) > 0 {
@@ -1197,12 +1326,17 @@ func (m *DeviceManager) keyPair() (asserts.PrivateKey, error) {
 		return nil, err
 	}
 
+	return m.keyPairFor(device)
+}
+
+// keyPairFor returns the key pair with the key id of the given device state.
+func (m *DeviceManager) keyPairFor(device *auth.DeviceState) (asserts.PrivateKey, error) {
 	if device.KeyID == "" {
 		return nil, state.ErrNoState
 	}
 
 	var privKey asserts.PrivateKey
-	err = m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) (err error) {
+	err := m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) (err error) {
 		privKey, err = keypairMgr.Get(device.KeyID)
 		if err != nil {
 			return fmt.Errorf("cannot read device key pair: %v", err)
@@ -1265,6 +1399,7 @@ var defaultSystemActions = []SystemAction{
 var currentSystemActions = []SystemAction{
 	{Title: "Reinstall", Mode: "install"},
 	{Title: "Recover", Mode: "recover"},
+	{Title: "Factory reset", Mode: "factory-reset"},
 	{Title: "Run normally", Mode: "run"},
 }
 var recoverSystemActions = []SystemAction{
@@ -1281,8 +1416,8 @@ func (m *DeviceManager) Systems() ([]*System, error) {
 	systemMode := m.SystemMode()
 	currentSys, _ := currentSystemForMode(m.state, systemMode)
 
-	systemLabels, err := filepath.Glob(filepath.Join(dirs.SnapSeedDir, "systems", "*"))
-	if err != nil && !os.IsNotExist(err) {
+	systemLabels, err := seed.ListSystems(dirs.SnapSeedDir)
+	if err != nil {
 		return nil, fmt.Errorf("cannot list available systems: %v", err)
 	}
 	if len(systemLabels) == 0 {
@@ -1291,8 +1426,7 @@ func (m *DeviceManager) Systems() ([]*System, error) {
 	}
 
 	var systems []*System
-	for _, fpLabel := range systemLabels {
-		label := filepath.Base(fpLabel)
+	for _, label := range systemLabels {
 		system, err := systemFromSeed(label, currentSys)
 		if err != nil {
 			// TODO:UC20 add a Broken field to the seed system like
@@ -1407,6 +1541,10 @@ func (m *DeviceManager) switchToSystemAndMode(systemLabel, mode string, sameSyst
 		// XXX: provide more context here like what mode was requested?
 		return ErrUnsupportedAction
 	}
+	if sysAction.Mode == "factory-reset" {
+		// factory reset is carried out by a change, see FactoryReset
+		return fmt.Errorf("internal error: factory reset must be requested through a change")
+	}
 
 	// XXX: requested mode is valid; only current system has 'run' and
 	// recover 'actions'
@@ -23,14 +23,18 @@ package devicestate
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"sync"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/netutil"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
@@ -39,13 +43,15 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/naming"
 )
 
 var (
-	snapstateInstallWithDeviceContext = snapstate.InstallWithDeviceContext
-	snapstateUpdateWithDeviceContext  = snapstate.UpdateWithDeviceContext
+	snapstateInstallWithDeviceContext     = snapstate.InstallWithDeviceContext
+	snapstateInstallPathWithDeviceContext = snapstate.InstallPathWithDeviceContext
+	snapstateUpdateWithDeviceContext      = snapstate.UpdateWithDeviceContext
 )
 
 // findModel returns the device model assertion.
@@ -346,13 +352,51 @@ func notInstalled(st *state.State, name string) (bool, error) {
 	return false, err
 }
 
-func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Model, deviceCtx snapstate.DeviceContext, fromChange string) ([]*state.TaskSet, error) {
+// localSnapFiles holds the snap files provided locally for a remodel,
+// together with their side infos.
+type localSnapFiles struct {
+	sideInfos []*snap.SideInfo
+	paths     []string
+}
+
+// find returns the side info and the path of the given snap if it was
+// provided locally.
+func (l *localSnapFiles) find(name string) (*snap.SideInfo, string) {
+	if l == nil {
+		return nil, ""
+	}
+	for i, si := range l.sideInfos {
+		if si.RealName == name {
+			return si, l.paths[i]
+		}
+	}
+	return nil, ""
+}
+
+func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Model, deviceCtx snapstate.DeviceContext, fromChange string, local *localSnapFiles) ([]*state.TaskSet, error) {
 	userID := 0
 	var tss []*state.TaskSet
 
+	// snaps provided locally are used instead of the ones from the store,
+	// the files are removed once installed
+	installTasks := func(name string, opts *snapstate.RevisionOptions, flags snapstate.Flags) (*state.TaskSet, error) {
+		if si, path := local.find(name); si != nil {
+			flags.RemoveSnapPath = true
+			return snapstateInstallPathWithDeviceContext(st, si, path, name, opts, userID, flags, deviceCtx, fromChange)
+		}
+		return snapstateInstallWithDeviceContext(ctx, st, name, opts, userID, flags, deviceCtx, fromChange)
+	}
+	updateTasks := func(name string, opts *snapstate.RevisionOptions, flags snapstate.Flags) (*state.TaskSet, error) {
+		if si, path := local.find(name); si != nil {
+			flags.RemoveSnapPath = true
+			return snapstateInstallPathWithDeviceContext(st, si, path, name, opts, userID, flags, deviceCtx, fromChange)
+		}
+		return snapstateUpdateWithDeviceContext(st, name, opts, userID, flags, deviceCtx, fromChange)
+	}
+
 	// kernel
 	if current.Kernel() == new.Kernel() && current.KernelTrack() != new.KernelTrack() {
-		ts, err := snapstateUpdateWithDeviceContext(st, new.Kernel(), &snapstate.RevisionOptions{Channel: new.KernelTrack()}, userID, snapstate.Flags{NoReRefresh: true}, deviceCtx, fromChange)
+		ts, err := updateTasks(new.Kernel(), &snapstate.RevisionOptions{Channel: new.KernelTrack()}, snapstate.Flags{NoReRefresh: true})
 		if err != nil {
 			return nil, err
 		}
@@ -366,7 +410,7 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 			return nil, err
 		}
 		if needsInstall {
-			ts, err = snapstateInstallWithDeviceContext(ctx, st, new.Kernel(), &snapstate.RevisionOptions{Channel: new.KernelTrack()}, userID, snapstate.Flags{}, deviceCtx, fromChange)
+			ts, err = installTasks(new.Kernel(), &snapstate.RevisionOptions{Channel: new.KernelTrack()}, snapstate.Flags{})
 		} else {
 			ts, err = snapstate.LinkNewBaseOrKernel(st, new.Base())
 		}
@@ -381,7 +425,7 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 			return nil, err
 		}
 		if needsInstall {
-			ts, err = snapstateInstallWithDeviceContext(ctx, st, new.Base(), nil, userID, snapstate.Flags{}, deviceCtx, fromChange)
+			ts, err = installTasks(new.Base(), nil, snapstate.Flags{})
 		} else {
 			ts, err = snapstate.LinkNewBaseOrKernel(st, new.Base())
 		}
@@ -392,14 +436,14 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 	}
 	// gadget
 	if current.Gadget() == new.Gadget() && current.GadgetTrack() != new.GadgetTrack() {
-		ts, err := snapstateUpdateWithDeviceContext(st, new.Gadget(), &snapstate.RevisionOptions{Channel: new.GadgetTrack()}, userID, snapstate.Flags{NoReRefresh: true}, deviceCtx, fromChange)
+		ts, err := updateTasks(new.Gadget(), &snapstate.RevisionOptions{Channel: new.GadgetTrack()}, snapstate.Flags{NoReRefresh: true})
 		if err != nil {
 			return nil, err
 		}
 		tss = append(tss, ts)
 	}
 	if current.Gadget() != new.Gadget() {
-		ts, err := snapstateInstallWithDeviceContext(ctx, st, new.Gadget(), &snapstate.RevisionOptions{Channel: new.GadgetTrack()}, userID, snapstate.Flags{}, deviceCtx, fromChange)
+		ts, err := installTasks(new.Gadget(), &snapstate.RevisionOptions{Channel: new.GadgetTrack()}, snapstate.Flags{})
 		if err != nil {
 			return nil, err
 		}
@@ -417,7 +461,7 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 		}
 		if needsInstall {
 			// If the snap is not installed we need to install it now.
-			ts, err := snapstateInstallWithDeviceContext(ctx, st, snapRef.SnapName(), nil, userID, snapstate.Flags{Required: true}, deviceCtx, fromChange)
+			ts, err := installTasks(snapRef.SnapName(), nil, snapstate.Flags{Required: true})
 			if err != nil {
 				return nil, err
 			}
@@ -496,7 +540,9 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 
 // Remodel takes a new model assertion and generates a change that
 // takes the device from the old to the new model or an error if the
-// transition is not possible.
+// transition is not possible. The snaps given with localSnaps and paths
+// are used instead of fetching them from the store, which allows to
+// remodel offline, the snap files are removed once installed.
 //
 // TODO:
 // - Check estimated disk size delta
@@ -504,7 +550,11 @@ func remodelTasks(ctx context.Context, st *state.State, current, new *asserts.Mo
 //   (need to check that even unchanged snaps are accessible)
 // - Make sure this works with Core 20 as well, in the Core 20 case
 //   we must enforce the default-channels from the model as well
-func Remodel(st *state.State, new *asserts.Model) (*state.Change, error) {
+func Remodel(st *state.State, new *asserts.Model, localSnaps []*snap.SideInfo, paths []string) (*state.Change, error) {
+	if len(localSnaps) != len(paths) {
+		return nil, fmt.Errorf("internal error: each local snap must have a path")
+	}
+
 	var seeded bool
 	err := st.Get("seeded", &seeded)
 	if err != nil && err != state.ErrNoState {
@@ -563,6 +613,9 @@ func Remodel(st *state.State, new *asserts.Model) (*state.Change, error) {
 	var tss []*state.TaskSet
 	switch remodelKind {
 	case ReregRemodel:
+		if len(localSnaps) != 0 {
+			return nil, fmt.Errorf("cannot remodel to a new brand or model with local snaps yet")
+		}
 		// nothing else can be in-flight
 		for _, chg := range st.Changes() {
 			if !chg.IsReady() {
@@ -591,7 +644,8 @@ func Remodel(st *state.State, new *asserts.Model) (*state.Change, error) {
 		fallthrough
 	case UpdateRemodel:
 		var err error
-		tss, err = remodelTasks(context.TODO(), st, current, new, remodCtx, "")
+		local := &localSnapFiles{sideInfos: localSnaps, paths: paths}
+		tss, err = remodelTasks(context.TODO(), st, current, new, remodCtx, "", local)
 		if err != nil {
 			return nil, err
 		}
@@ -637,3 +691,180 @@ func Remodeling(st *state.State) bool {
 	}
 	return false
 }
+
+// RotateDeviceKey sets up a change to generate a new device key and
+// to request a new serial assertion for it, proving possession of the
+// current key, for registered devices whose key needs to be replaced.
+// The device switches to the new key only once the new serial is
+// obtained.
+func RotateDeviceKey(st *state.State) (*state.Change, error) {
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if !seeded {
+		return nil, fmt.Errorf("cannot rotate device key until fully seeded")
+	}
+
+	device, err := internal.Device(st)
+	if err != nil {
+		return nil, err
+	}
+	if device.KeyID == "" || device.Serial == "" {
+		return nil, fmt.Errorf("cannot rotate device key of a device that is not registered yet")
+	}
+
+	if Remodeling(st) {
+		return nil, &snapstate.ChangeConflictError{Message: "cannot rotate device key while remodeling"}
+	}
+	for _, chg := range st.Changes() {
+		if chg.IsReady() {
+			continue
+		}
+		switch chg.Kind() {
+		case "rotate-device-key":
+			return nil, &snapstate.ChangeConflictError{Message: "cannot rotate device key, clashing with concurrent rotation"}
+		case "become-operational":
+			return nil, &snapstate.ChangeConflictError{Message: "cannot rotate device key while the device is being registered"}
+		}
+		for _, t := range chg.Tasks() {
+			if !t.Status().Ready() && t.Kind() == "request-serial" {
+				return nil, &snapstate.ChangeConflictError{Message: "cannot rotate device key while a serial is being requested"}
+			}
+		}
+	}
+
+	genKey := st.NewTask("generate-device-key", i18n.G("Generate new device key"))
+	requestSerial := st.NewTask("request-serial", i18n.G("Request new device serial"))
+	requestSerial.WaitFor(genKey)
+
+	chg := st.NewChange("rotate-device-key", i18n.G("Rotate device key"))
+	chg.AddTask(genKey)
+	chg.AddTask(requestSerial)
+
+	return chg, nil
+}
+
+var (
+	// ErrFactoryResetNotSeeded is returned by FactoryReset before the
+	// device is fully seeded.
+	ErrFactoryResetNotSeeded = errors.New("cannot factory reset until fully seeded")
+	// ErrFactoryResetUnsupported is returned by FactoryReset for devices
+	// without recovery systems.
+	ErrFactoryResetUnsupported = errors.New("cannot factory reset a device without recovery systems")
+)
+
+// FactoryReset creates a change that reboots the device into the
+// recovery system it was installed from to reinstall it, discarding
+// the data of the run system.
+func FactoryReset(st *state.State) (*state.Change, error) {
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if !seeded {
+		return nil, ErrFactoryResetNotSeeded
+	}
+
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if deviceCtx.Model().Grade() == asserts.ModelGradeUnset {
+		return nil, ErrFactoryResetUnsupported
+	}
+
+	if Remodeling(st) {
+		return nil, &snapstate.ChangeConflictError{Message: "cannot factory reset while remodeling"}
+	}
+	for _, chg := range st.Changes() {
+		if !chg.IsReady() && chg.Kind() == "factory-reset" {
+			return nil, &snapstate.ChangeConflictError{Message: "factory reset already in progress"}
+		}
+	}
+
+	requestReset := st.NewTask("request-factory-reset", i18n.G("Request factory reset"))
+
+	chg := st.NewChange("factory-reset", i18n.G("Factory reset the device"))
+	chg.AddTask(requestReset)
+
+	return chg, nil
+}
+
+// CreateRecoverySystemOptions carries the options for creating a new
+// recovery system.
+type CreateRecoverySystemOptions struct {
+	// TestSystem is set when the new recovery system should be test booted
+	// before it is considered a good one.
+	TestSystem bool
+}
+
+// InvalidRecoverySystemError is returned by CreateRecoverySystem when a
+// recovery system with the given label cannot be created on the device.
+type InvalidRecoverySystemError struct {
+	Message string
+}
+
+func (e *InvalidRecoverySystemError) Error() string {
+	return e.Message
+}
+
+// CreateRecoverySystem creates a change that writes a new recovery system with
+// the given label to ubuntu-seed using the currently installed snap revisions
+// and their assertions. Once the new system is recognized as a good one, old
+// recovery systems exceeding the retention limit are removed.
+func CreateRecoverySystem(st *state.State, label string, opts CreateRecoverySystemOptions) (*state.Change, error) {
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if !seeded {
+		return nil, &InvalidRecoverySystemError{Message: "cannot create new recovery systems until fully seeded"}
+	}
+	if err := seed.ValidateUC20SeedSystemLabel(label); err != nil {
+		return nil, &InvalidRecoverySystemError{Message: fmt.Sprintf("cannot create recovery system: %v", err)}
+	}
+
+	deviceCtx, err := DeviceCtx(st, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if deviceCtx.Model().Grade() == asserts.ModelGradeUnset {
+		return nil, &InvalidRecoverySystemError{Message: "cannot create new recovery systems on a non UC20 device"}
+	}
+	if osutil.FileExists(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)) {
+		return nil, &InvalidRecoverySystemError{Message: fmt.Sprintf("recovery system %q already exists", label)}
+	}
+
+	if Remodeling(st) {
+		return nil, &snapstate.ChangeConflictError{Message: "cannot create a recovery system while remodeling"}
+	}
+	for _, chg := range st.Changes() {
+		if !chg.IsReady() && chg.Kind() == "create-recovery-system" {
+			return nil, &snapstate.ChangeConflictError{
+				ChangeKind: "create-recovery-system",
+				Message:    "creating recovery system in progress",
+			}
+		}
+	}
+
+	create := st.NewTask("create-recovery-system", fmt.Sprintf(i18n.G("Create recovery system with label %q"), label))
+	create.Set("recovery-system-setup", &recoverySystemSetup{
+		Label:      label,
+		Directory:  filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label),
+		TestSystem: opts.TestSystem,
+	})
+	finalize := st.NewTask("finalize-recovery-system", fmt.Sprintf(i18n.G("Finalize recovery system with label %q"), label))
+	finalize.WaitFor(create)
+	// finalize needs to know the label too
+	finalize.Set("recovery-system-setup-task", create.ID())
+
+	chg := st.NewChange("create-recovery-system", fmt.Sprintf(i18n.G("Create new recovery system with label %q"), label))
+	chg.AddTask(create)
+	chg.AddTask(finalize)
+
+	return chg, nil
+}
@@ -16,7 +16,10 @@ import (
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/sysconfig"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -137,6 +140,48 @@ func (s *cloudInitUC20Suite) TestCloudInitUC20CloudGadgetNoDisable(c *C) {
 	c.Assert(strings.TrimSpace(s.logbuf.String()), Matches, `.*System initialized, cloud-init reported to be done, set datasource_list to \[ GCE \].*`)
 }
 
+func (s *cloudInitUC20Suite) TestCloudInitUC20GadgetDatasourceNotAllowedDisables(c *C) {
+	// the gadget only allows Azure
+	si := &snap.SideInfo{RealName: "pc", Revision: snap.R(1), SnapID: "pcididididididididididididididid"}
+	snaptest.MockSnapWithFiles(c, "name: pc\ntype: gadget\nversion: 1", si, [][]string{
+		{"cloud.conf", "datasource_list: [Azure]\n"},
+	})
+	s.state.Lock()
+	snapstate.Set(s.state, "pc", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+		SnapType: "gadget",
+	})
+	s.state.Unlock()
+
+	r := devicestate.MockCloudInitStatus(func() (sysconfig.CloudInitState, error) {
+		return sysconfig.CloudInitDone, nil
+	})
+	defer r()
+
+	restrictCalls := 0
+	r = devicestate.MockRestrictCloudInit(func(state sysconfig.CloudInitState, opts *sysconfig.CloudInitRestrictOptions) (sysconfig.CloudInitRestrictionResult, error) {
+		restrictCalls++
+		c.Assert(state, Equals, sysconfig.CloudInitDone)
+		c.Assert(opts, DeepEquals, &sysconfig.CloudInitRestrictOptions{
+			DisableAfterLocalDatasourcesRun: true,
+			AllowedDatasources:              []string{"Azure"},
+		})
+		// GCE is not allowed so it got disabled
+		return sysconfig.CloudInitRestrictionResult{
+			Action:     "disable",
+			DataSource: "GCE",
+		}, nil
+	})
+	defer r()
+
+	err := devicestate.EnsureCloudInitRestricted(s.mgr)
+	c.Assert(err, IsNil)
+	c.Assert(restrictCalls, Equals, 1)
+	c.Assert(strings.TrimSpace(s.logbuf.String()), Matches, `.*System initialized, cloud-init reported to be done, disabled permanently, datasource GCE is not allowed by the gadget.*`)
+}
+
 func (s *cloudInitUC20Suite) TestCloudInitUC20NoCloudGadgetDisables(c *C) {
 	// pretend that cloud-init never ran
 	statusCalls := 0
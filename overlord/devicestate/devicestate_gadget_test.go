@@ -372,6 +372,57 @@ func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnCoreNoUpdateNeeded(c *C) {
 	c.Check(s.restartRequests, HasLen, 0)
 }
 
+func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnUC20CommandLineUpdated(c *C) {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
+		return gadget.ErrNoUpdate
+	})
+	defer restore()
+	var passedGadgetDir string
+	restore = devicestate.MockBootUpdateCommandLineForGadgetComponent(func(dev boot.Device, gadgetDir string) (bool, error) {
+		c.Check(dev.HasModeenv(), Equals, true)
+		passedGadgetDir = gadgetDir
+		return true, nil
+	})
+	defer restore()
+
+	chg, t := s.setupGadgetUpdate(c, "dangerous", uc20gadgetYaml, "")
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), IsNil)
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(passedGadgetDir, Equals, filepath.Join(dirs.SnapMountDir, "foo-gadget/34"))
+	// the system is rebooted to try the new command line
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystem})
+}
+
+func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnUC20CommandLineUpdateError(c *C) {
+	restore := devicestate.MockGadgetUpdate(func(current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, _ gadget.ContentUpdateObserver) error {
+		return gadget.ErrNoUpdate
+	})
+	defer restore()
+	restore = devicestate.MockBootUpdateCommandLineForGadgetComponent(func(dev boot.Device, gadgetDir string) (bool, error) {
+		return false, errors.New("mocked error")
+	})
+	defer restore()
+
+	chg, t := s.setupGadgetUpdate(c, "dangerous", uc20gadgetYaml, "")
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Assert(chg.IsReady(), Equals, true)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot update kernel command line from gadget: mocked error.*`)
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
 func (s *deviceMgrGadgetSuite) TestUpdateGadgetOnCoreRollbackDirCreateFailed(c *C) {
 	if os.Geteuid() == 0 {
 		c.Skip("this test cannot run as root (permissions are not honored)")
@@ -354,7 +354,7 @@ func (s *deviceMgrInstallModeSuite) TestInstallTaskErrors(c *C) {
 	defer restore()
 
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -384,7 +384,7 @@ func (s *deviceMgrInstallModeSuite) TestInstallExpTasks(c *C) {
 	defer restore()
 
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -440,7 +440,7 @@ func (s *deviceMgrInstallModeSuite) TestInstallWithInstallDeviceHookExpTasks(c *
 	defer restore()
 
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -506,7 +506,7 @@ func (s *deviceMgrInstallModeSuite) TestInstallWithBrokenInstallDeviceHookUnhapp
 	defer restore()
 
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -559,7 +559,7 @@ func (s *deviceMgrInstallModeSuite) TestInstallSetupRunSystemTaskNoRestarts(c *C
 	defer restore()
 
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -785,7 +785,7 @@ func (s *deviceMgrInstallModeSuite) testInstallEncryptionSanityChecks(c *C, errM
 	defer restore()
 
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -1026,7 +1026,7 @@ func (s *deviceMgrInstallModeSuite) TestInstallModeWritesModel(c *C) {
 
 func (s *deviceMgrInstallModeSuite) testInstallGadgetNoSave(c *C) {
 	err := ioutil.WriteFile(filepath.Join(dirs.GlobalRootDir, "/var/lib/snapd/modeenv"),
-		[]byte("mode=install\n"), 0644)
+		[]byte("mode=install\nrecovery_system=20191218\n"), 0644)
 	c.Assert(err, IsNil)
 
 	s.state.Lock()
@@ -1365,3 +1365,149 @@ func (s *deviceMgrInstallModeSuite) TestInstallCheckEncryptedErrorsLogsHook(c *C
 	c.Check(err, IsNil)
 	c.Check(logbuf.String(), Matches, "(?s).*: not encrypting device storage as querying kernel fde-setup hook did not succeed:.*\n")
 }
+
+func (s *deviceMgrInstallModeSuite) findChange(kind string) *state.Change {
+	for _, chg := range s.state.Changes() {
+		if chg.Kind() == kind {
+			return chg
+		}
+	}
+	return nil
+}
+
+func (s *deviceMgrInstallModeSuite) mockFactoryResetMode(c *C, grade string) *asserts.Model {
+	restore := devicestate.MockInstallRun(func(gadget.Model, string, string, string, install.Options, gadget.ContentObserver) (*install.InstalledSystemSideData, error) {
+		c.Fatalf("unexpected install")
+		return nil, nil
+	})
+	s.AddCleanup(restore)
+	restore = devicestate.MockBootMakeSystemRunnable(func(*asserts.Model, *boot.BootableSet, *boot.TrustedAssetsInstallObserver) error {
+		return nil
+	})
+	s.AddCleanup(restore)
+
+	modeenv := boot.Modeenv{
+		Mode:           "factory-reset",
+		RecoverySystem: "20191218",
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	// normally done by snap-bootstrap
+	c.Assert(os.MkdirAll(boot.InitramfsUbuntuBootDir, 0755), IsNil)
+	c.Assert(os.MkdirAll(boot.InitramfsUbuntuSaveDir, 0755), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	model := s.makeMockInstalledPcGadget(c, grade, "", "")
+	devicestate.SetSystemMode(s.mgr, "factory-reset")
+	return model
+}
+
+func (s *deviceMgrInstallModeSuite) TestFactoryResetModeHappy(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	s.mockFactoryResetMode(c, "dangerous")
+
+	var resetOpts []install.Options
+	restore = devicestate.MockInstallFactoryReset(func(mod gadget.Model, gadgetRoot, kernelRoot, device string, options install.Options, _ gadget.ContentObserver) (*install.InstalledSystemSideData, error) {
+		resetOpts = append(resetOpts, options)
+		return &install.InstalledSystemSideData{}, nil
+	})
+	defer restore()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(s.findInstallSystem(), IsNil)
+	chg := s.findChange("factory-reset")
+	c.Assert(chg, NotNil)
+	c.Check(chg.Err(), IsNil)
+	c.Check(chg.Summary(), Equals, "Perform factory reset of the system")
+
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Kind(), Equals, "factory-reset-run-system")
+	c.Check(tasks[1].Kind(), Equals, "restart-system-to-run-mode")
+
+	c.Check(resetOpts, DeepEquals, []install.Options{{Mount: true}})
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystemNow})
+
+	// the result of the reset is recorded in ubuntu-save
+	c.Check(filepath.Join(boot.InitramfsUbuntuSaveDir, "device/factory-reset"), testutil.FileContains, `"recovery-system":"20191218"`)
+}
+
+func (s *deviceMgrInstallModeSuite) TestFactoryResetModeEncryptedUnhappy(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	restore = devicestate.MockSecbootCheckKeySealingSupported(func() error { return nil })
+	defer restore()
+
+	s.mockFactoryResetMode(c, "signed")
+
+	restore = devicestate.MockInstallFactoryReset(func(gadget.Model, string, string, string, install.Options, gadget.ContentObserver) (*install.InstalledSystemSideData, error) {
+		c.Fatalf("unexpected factory reset")
+		return nil, nil
+	})
+	defer restore()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.findChange("factory-reset")
+	c.Assert(chg, NotNil)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot factory reset an encrypted system.*`)
+	c.Check(s.restartRequests, HasLen, 0)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSaveDir, "device/factory-reset"), testutil.FileAbsent)
+}
+
+func (s *deviceMgrInstallModeSuite) TestFactoryResetReportedInRunMode(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	marker := filepath.Join(dirs.SnapDeviceSaveDir, "factory-reset")
+	c.Assert(os.MkdirAll(filepath.Dir(marker), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(marker, []byte(`{"recovery-system":"20191218","time":"2021-01-01T00:00:00Z"}`), 0644), IsNil)
+
+	s.state.Lock()
+	s.makeMockInstalledPcGadget(c, "dangerous", "", "")
+	devicestate.SetSystemMode(s.mgr, "run")
+	devicestate.SetBootOkRan(s.mgr, true)
+	devicestate.SetBootRevisionsUpdated(s.mgr, true)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.findChange("factory-reset")
+	c.Assert(chg, NotNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 1)
+	c.Check(tasks[0].Kind(), Equals, "finish-factory-reset")
+	c.Check(tasks[0].Summary(), Equals, `Finish factory reset from recovery system "20191218"`)
+	c.Check(marker, testutil.FileAbsent)
+	c.Check(logbuf.String(), testutil.Contains, `device was factory reset from recovery system "20191218"`)
+
+	// reported only once
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+	n := 0
+	for _, chg := range s.state.Changes() {
+		if chg.Kind() == "factory-reset" {
+			n++
+		}
+	}
+	c.Check(n, Equals, 1)
+}
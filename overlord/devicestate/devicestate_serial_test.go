@@ -21,6 +21,7 @@ package devicestate_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
@@ -2017,3 +2018,215 @@ func (s *deviceMgrSerialSuite) TestFullDeviceRegistrationUC20Happy(c *C) {
 	})
 	c.Assert(err, IsNil)
 }
+
+func (s *deviceMgrSerialSuite) setupForKeyRotation(c *C) *asserts.Serial {
+	assertstest.AddMany(s.storeSigning, s.brands.AccountsAndKeys("rereg-brand")...)
+	assertstatetest.AddMany(s.state, s.brands.AccountsAndKeys("rereg-brand")...)
+
+	s.makeModelAssertionInState(c, "rereg-brand", "rereg-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+
+	devicestatetest.MockGadget(c, s.state, "pc", snap.R(2), nil)
+
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:           "rereg-brand",
+		Model:           "rereg-model",
+		KeyID:           devKey.PublicKey().ID(),
+		Serial:          "1234",
+		SessionMacaroon: "session-macaroon",
+	})
+	devicestate.KeypairManager(s.mgr).Put(devKey)
+
+	s.state.Set("seeded", true)
+
+	return s.makeSerialAssertionInState(c, "rereg-brand", "rereg-model", "1234")
+}
+
+func (s *deviceMgrSerialSuite) TestRotateDeviceKey(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, "REQID-1", nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupForKeyRotation(c)
+
+	chg, err := devicestate.RotateDeviceKey(s.state)
+	c.Assert(err, IsNil)
+	c.Check(chg.Kind(), Equals, "rotate-device-key")
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Kind(), Equals, "generate-device-key")
+	c.Check(tasks[1].Kind(), Equals, "request-serial")
+	c.Check(tasks[1].WaitTasks(), DeepEquals, []*state.Task{tasks[0]})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.DoneStatus, Commentf("%s", tasks[1].Log()))
+
+	var newKeyID string
+	c.Assert(chg.Get("new-device-key-id", &newKeyID), IsNil)
+	c.Check(newKeyID, Not(Equals), devKey.PublicKey().ID())
+
+	device, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.KeyID, Equals, newKeyID)
+	c.Check(device.Serial, Equals, "9999")
+	c.Check(device.SessionMacaroon, Equals, "")
+
+	serials, err := s.db.FindMany(asserts.SerialType, map[string]string{
+		"brand-id":            "rereg-brand",
+		"model":               "rereg-model",
+		"device-key-sha3-384": newKeyID,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(serials, HasLen, 1)
+	c.Check(serials[0].(*asserts.Serial).Serial(), Equals, "9999")
+
+	privKey, err := devicestate.KeypairManager(s.mgr).Get(newKeyID)
+	c.Assert(err, IsNil)
+	c.Check(privKey, NotNil)
+
+	// the old key is gone
+	_, err = devicestate.KeypairManager(s.mgr).Get(devKey.PublicKey().ID())
+	c.Check(err, ErrorMatches, "cannot find key pair")
+}
+
+func (s *deviceMgrSerialSuite) TestRotateDeviceKeyFailureRemovesNewKey(c *C) {
+	r1 := devicestate.MockKeyLength(testKeyLength)
+	defer r1()
+
+	mockServer := s.mockServer(c, devicestatetest.ReqIDBadRequest, nil)
+	defer mockServer.Close()
+
+	r2 := devicestate.MockBaseStoreURL(mockServer.URL)
+	defer r2()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupForKeyRotation(c)
+
+	chg, err := devicestate.RotateDeviceKey(s.state)
+	c.Assert(err, IsNil)
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(tasks[0].Status(), Equals, state.UndoneStatus)
+	c.Check(tasks[1].Status(), Equals, state.ErrorStatus)
+
+	var newKeyID string
+	c.Check(chg.Get("new-device-key-id", &newKeyID), Equals, state.ErrNoState)
+
+	// still using the old key and serial
+	device, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.KeyID, Equals, devKey.PublicKey().ID())
+	c.Check(device.Serial, Equals, "1234")
+	c.Check(device.SessionMacaroon, Equals, "session-macaroon")
+
+	_, err = devicestate.KeypairManager(s.mgr).Get(devKey.PublicKey().ID())
+	c.Check(err, IsNil)
+	// the only key left is the old one
+	keysDir := filepath.Join(dirs.SnapDeviceDir, "private-keys-v1")
+	keys, err := ioutil.ReadDir(keysDir)
+	c.Assert(err, IsNil)
+	c.Assert(keys, HasLen, 1)
+	c.Check(keys[0].Name(), Equals, devKey.PublicKey().ID())
+}
+
+func (s *deviceMgrSerialSuite) TestRotateDeviceKeySerialRequest(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	serial0 := s.setupForKeyRotation(c)
+
+	newKey, _ := assertstest.GenerateKey(testKeyLength)
+	devicestate.KeypairManager(s.mgr).Put(newKey)
+
+	chg := s.state.NewChange("rotate-device-key", "...")
+	t := s.state.NewTask("request-serial", "test")
+	chg.AddTask(t)
+	chg.Set("new-device-key-id", newKey.PublicKey().ID())
+
+	regCtx, err := devicestate.RegistrationCtx(s.mgr, t)
+	c.Assert(err, IsNil)
+
+	device, err := regCtx.Device()
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{
+		Brand: "rereg-brand",
+		Model: "rereg-model",
+		KeyID: newKey.PublicKey().ID(),
+	})
+	c.Check(regCtx.SerialRequestExtraHeaders(), IsNil)
+	c.Check(regCtx.SerialRequestAncillaryAssertions(), HasLen, 2)
+	c.Check(regCtx.SerialRequestAncillaryAssertions()[1], DeepEquals, serial0)
+
+	// the device is switched over only at the end
+	current, err := devicestatetest.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(current.KeyID, Equals, devKey.PublicKey().ID())
+	c.Check(current.SessionMacaroon, Equals, "session-macaroon")
+}
+
+func (s *deviceMgrSerialSuite) TestRotateDeviceKeyErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := devicestate.RotateDeviceKey(s.state)
+	c.Check(err, ErrorMatches, "cannot rotate device key until fully seeded")
+
+	s.state.Set("seeded", true)
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand: "canonical",
+		Model: "pc",
+	})
+	_, err = devicestate.RotateDeviceKey(s.state)
+	c.Check(err, ErrorMatches, "cannot rotate device key of a device that is not registered yet")
+
+	s.setupForKeyRotation(c)
+
+	chg := s.state.NewChange("remodel", "...")
+	chg.AddTask(s.state.NewTask("fake-remodel", "..."))
+	_, err = devicestate.RotateDeviceKey(s.state)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, "cannot rotate device key while remodeling")
+	chg.SetStatus(state.DoneStatus)
+
+	chg = s.state.NewChange("become-operational", "...")
+	chg.AddTask(s.state.NewTask("generate-device-key", "..."))
+	_, err = devicestate.RotateDeviceKey(s.state)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, "cannot rotate device key while the device is being registered")
+	chg.SetStatus(state.DoneStatus)
+
+	chg = s.state.NewChange("other", "...")
+	chg.AddTask(s.state.NewTask("request-serial", "..."))
+	_, err = devicestate.RotateDeviceKey(s.state)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, "cannot rotate device key while a serial is being requested")
+	chg.SetStatus(state.DoneStatus)
+
+	_, err = devicestate.RotateDeviceKey(s.state)
+	c.Assert(err, IsNil)
+	_, err = devicestate.RotateDeviceKey(s.state)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+	c.Check(err, ErrorMatches, "cannot rotate device key, clashing with concurrent rotation")
+}
@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -31,17 +32,24 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/bootloader"
+	"github.com/snapcore/snapd/bootloader/bootloadertest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate/assertstatetest"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
 	"github.com/snapcore/snapd/overlord/devicestate/devicestatetest"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/seed"
 	"github.com/snapcore/snapd/seed/seedtest"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/timings"
 )
 
 type mockedSystemSeed struct {
@@ -241,6 +249,7 @@ var defaultSystemActions []devicestate.SystemAction = []devicestate.SystemAction
 var currentSystemActions []devicestate.SystemAction = []devicestate.SystemAction{
 	{Title: "Reinstall", Mode: "install"},
 	{Title: "Recover", Mode: "recover"},
+	{Title: "Factory reset", Mode: "factory-reset"},
 	{Title: "Run normally", Mode: "run"},
 }
 
@@ -631,6 +640,8 @@ func (s *deviceMgrSystemsSuite) TestRequestAction1618(c *C) {
 	// no modeenv either
 	err := os.Remove(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir))
 	c.Assert(err, IsNil)
+	err = os.RemoveAll(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir) + ".backup")
+	c.Assert(err, IsNil)
 
 	s.state.Lock()
 	s.state.Set("seeded-systems", nil)
@@ -827,3 +838,496 @@ func (s *deviceMgrSystemsSuite) TestRebootUnhappy(c *C) {
 	}
 	c.Check(s.logbuf.String(), Equals, "")
 }
+
+func (s *deviceMgrSystemsSuite) TestFactoryResetHappy(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{
+			System:  s.mockedSystemSeeds[0].label,
+			Model:   s.mockedSystemSeeds[0].model.Model(),
+			BrandID: s.mockedSystemSeeds[0].brand.AccountID(),
+		},
+	})
+
+	chg, err := devicestate.FactoryReset(s.state)
+	c.Assert(err, IsNil)
+	c.Check(chg.Kind(), Equals, "factory-reset")
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 1)
+	c.Check(tasks[0].Kind(), Equals, "request-factory-reset")
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_system": "20191119",
+		"snapd_recovery_mode":   "factory-reset",
+	})
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystemNow})
+	c.Check(s.logbuf.String(), Matches, `.*: rebooting into system "20191119" to factory reset the device\n`)
+}
+
+func (s *deviceMgrSystemsSuite) TestFactoryResetEncryptedSystem(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{
+			System:  s.mockedSystemSeeds[0].label,
+			Model:   s.mockedSystemSeeds[0].model.Model(),
+			BrandID: s.mockedSystemSeeds[0].brand.AccountID(),
+		},
+	})
+	// the keys were sealed at install time
+	c.Assert(os.MkdirAll(dirs.SnapFDEDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapFDEDir, "sealed-keys"), nil, 0644), IsNil)
+
+	chg, err := devicestate.FactoryReset(s.state)
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot factory reset an encrypted system.*`)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrSystemsSuite) TestFactoryResetBrokenRecoverySystem(c *C) {
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{
+			System:  s.mockedSystemSeeds[0].label,
+			Model:   s.mockedSystemSeeds[0].model.Model(),
+			BrandID: s.mockedSystemSeeds[0].brand.AccountID(),
+		},
+	})
+	// break the recovery system
+	err := os.Remove(filepath.Join(dirs.SnapSeedDir, "systems", "20191119", "model"))
+	c.Assert(err, IsNil)
+
+	chg, err := devicestate.FactoryReset(s.state)
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot use recovery system "20191119": cannot load assertions: .*`)
+	m, err := s.bootloader.GetBootVars("snapd_recovery_mode", "snapd_recovery_system")
+	c.Assert(err, IsNil)
+	c.Check(m, DeepEquals, map[string]string{
+		"snapd_recovery_system": "",
+		"snapd_recovery_mode":   "",
+	})
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrSystemsSuite) TestFactoryResetNotInRunMode(c *C) {
+	devicestate.SetSystemMode(s.mgr, "recover")
+	devicestate.SetBootOkRan(s.mgr, true)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg, err := devicestate.FactoryReset(s.state)
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot factory reset the device from "recover" mode.*`)
+	c.Check(s.restartRequests, HasLen, 0)
+}
+
+func (s *deviceMgrSystemsSuite) TestFactoryResetErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("seeded", false)
+	_, err := devicestate.FactoryReset(s.state)
+	c.Check(err, ErrorMatches, "cannot factory reset until fully seeded")
+	s.state.Set("seeded", true)
+
+	chg := s.state.NewChange("factory-reset", "...")
+	chg.AddTask(s.state.NewTask("request-factory-reset", "..."))
+	_, err = devicestate.FactoryReset(s.state)
+	c.Check(err, ErrorMatches, "factory reset already in progress")
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+}
+
+func (s *deviceMgrSystemsSuite) mockInstalledSnapsForRecoverySystem(c *C) *bootloadertest.MockRecoveryAwareBootloader {
+	ss := &seedtest.SeedSnaps{
+		StoreSigning: s.storeSigning,
+		Brands:       s.brands,
+	}
+	snapYamls := map[string]string{
+		"snapd":     "name: snapd\nversion: 1\ntype: snapd",
+		"pc":        "name: pc\nversion: 1\ntype: gadget\nbase: core20",
+		"pc-kernel": "name: pc-kernel\nversion: 1\ntype: kernel",
+		"core20":    "name: core20\nversion: 1\ntype: base",
+	}
+	for _, name := range []string{"snapd", "pc", "pc-kernel", "core20"} {
+		decl, rev := ss.MakeAssertedSnap(c, snapYamls[name], nil, snap.R(1), "canonical")
+		assertstatetest.AddMany(s.state, decl, rev)
+
+		si := &snap.SideInfo{
+			RealName: name,
+			SnapID:   ss.AssertedSnapID(name),
+			Revision: snap.R(1),
+		}
+		info := snaptest.MockSnap(c, snapYamls[name], si)
+		c.Assert(osutil.CopyFile(ss.AssertedSnap(name), info.MountFile(), osutil.CopyFlagOverwrite), IsNil)
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			SnapType: string(info.Type()),
+			Active:   true,
+			Sequence: []*snap.SideInfo{si},
+			Current:  si.Revision,
+		})
+	}
+
+	s.makeModelAssertionInState(c, "canonical", "pc-recovery", map[string]interface{}{
+		"architecture": "amd64",
+		"grade":        "dangerous",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              ss.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              ss.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+	devicestatetest.SetDevice(s.state, &auth.DeviceState{
+		Brand:  "canonical",
+		Model:  "pc-recovery",
+		Serial: "serialserialserial",
+	})
+	s.state.Set("seeded-systems", []devicestate.SeededSystem{
+		{
+			System:  s.mockedSystemSeeds[0].label,
+			Model:   s.mockedSystemSeeds[0].model.Model(),
+			BrandID: s.mockedSystemSeeds[0].brand.AccountID(),
+		},
+	})
+
+	modeenv := boot.Modeenv{
+		Mode: "run",
+		// keep this comment to make old gofmt happy
+		CurrentRecoverySystems: []string{s.mockedSystemSeeds[0].label},
+		GoodRecoverySystems:    []string{s.mockedSystemSeeds[0].label},
+	}
+	c.Assert(modeenv.WriteTo(""), IsNil)
+
+	// the boot snaps are mocked in state only
+	devicestate.SetBootOkRan(s.mgr, true)
+	devicestate.SetBootRevisionsUpdated(s.mgr, true)
+
+	rbl := s.bootloader.RecoveryAware()
+	bootloader.Force(rbl)
+	return rbl
+}
+
+func (s *deviceMgrSystemsSuite) checkRecoverySystemSeed(c *C, label string) {
+	sd, err := seed.Open(boot.InitramfsUbuntuSeedDir, label)
+	c.Assert(err, IsNil)
+	c.Assert(sd.LoadAssertions(nil, nil), IsNil)
+	c.Check(sd.Model().Model(), Equals, "pc-recovery")
+	c.Assert(sd.LoadMeta(timings.New(nil)), IsNil)
+	var names []string
+	for _, sn := range sd.EssentialSnaps() {
+		names = append(names, sn.SnapName())
+		c.Check(sn.Path, Equals, filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", sn.SnapName()+"_1.snap"))
+	}
+	c.Check(names, DeepEquals, []string{"snapd", "pc-kernel", "core20", "pc"})
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemHappy(c *C) {
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	rbl := s.mockInstalledSnapsForRecoverySystem(c)
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Assert(err, IsNil)
+	c.Check(chg.Kind(), Equals, "create-recovery-system")
+	tasks := chg.Tasks()
+	c.Assert(tasks, HasLen, 2)
+	c.Check(tasks[0].Kind(), Equals, "create-recovery-system")
+	c.Check(tasks[1].Kind(), Equals, "finalize-recovery-system")
+	c.Check(tasks[1].WaitTasks(), DeepEquals, []*state.Task{tasks[0]})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	// no test boot was needed
+	c.Check(s.restartRequests, HasLen, 0)
+
+	s.checkRecoverySystemSeed(c, "1234")
+	c.Check(rbl.RecoverySystemDir, Equals, "/systems/1234")
+	c.Check(rbl.RecoverySystemBootVars, DeepEquals, map[string]string{
+		"snapd_recovery_kernel": "/snaps/pc-kernel_1.snap",
+	})
+
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20191119", "1234"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20191119", "1234"})
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemTestedHappy(c *C) {
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	rbl := s.mockInstalledSnapsForRecoverySystem(c)
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{
+		TestSystem: true,
+	})
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	s.se.Ensure()
+	s.se.Wait()
+	s.state.Lock()
+
+	tasks := chg.Tasks()
+	c.Check(tasks[0].Status(), Equals, state.DoneStatus)
+	c.Check(tasks[1].Status(), Equals, state.DoStatus)
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystemNow})
+
+	s.checkRecoverySystemSeed(c, "1234")
+	vars, err := rbl.GetBootVars("snapd_recovery_mode", "snapd_recovery_system",
+		"try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"snapd_recovery_mode":    "recover",
+		"snapd_recovery_system":  "1234",
+		"try_recovery_system":    "1234",
+		"recovery_system_status": "try",
+	})
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20191119", "1234"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20191119"})
+
+	// the system was successfully tried, and the device is back in run mode
+	state.MockRestarting(s.state, state.RestartUnset)
+	c.Assert(rbl.SetBootVars(map[string]string{
+		"snapd_recovery_mode":    "run",
+		"snapd_recovery_system":  "",
+		"recovery_system_status": "tried",
+	}), IsNil)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	vars, err = rbl.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+	modeenvRead, err = boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20191119", "1234"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20191119", "1234"})
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemTestedFailedUndo(c *C) {
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	rbl := s.mockInstalledSnapsForRecoverySystem(c)
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{
+		TestSystem: true,
+	})
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	s.se.Ensure()
+	s.se.Wait()
+	s.state.Lock()
+	c.Check(s.restartRequests, DeepEquals, []state.RestartType{state.RestartSystemNow})
+
+	// booting the system failed, the status was left as "try"
+	state.MockRestarting(s.state, state.RestartUnset)
+	c.Assert(rbl.SetBootVars(map[string]string{
+		"snapd_recovery_mode":   "run",
+		"snapd_recovery_system": "",
+	}), IsNil)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot promote recovery system "1234": system failed to boot.*`)
+	tasks := chg.Tasks()
+	c.Check(tasks[0].Status(), Equals, state.UndoneStatus)
+
+	// the system and the snaps were removed
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	snaps, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/*"))
+	c.Assert(err, IsNil)
+	c.Check(snaps, HasLen, 0)
+	vars, err := rbl.GetBootVars("try_recovery_system", "recovery_system_status")
+	c.Assert(err, IsNil)
+	c.Check(vars, DeepEquals, map[string]string{
+		"try_recovery_system":    "",
+		"recovery_system_status": "",
+	})
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20191119"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20191119"})
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemPrunesOldSystems(c *C) {
+	restore := devicestate.MockMaxRecoverySystems(2)
+	defer restore()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockInstalledSnapsForRecoverySystem(c)
+
+	// a snap file not used by any of the systems
+	unused := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/unused_1.snap")
+	c.Assert(os.MkdirAll(filepath.Dir(unused), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(unused, nil, 0644), IsNil)
+
+	for _, label := range []string{"1234", "5678"} {
+		chg, err := devicestate.CreateRecoverySystem(s.state, label, devicestate.CreateRecoverySystemOptions{})
+		c.Assert(err, IsNil)
+
+		s.state.Unlock()
+		s.settle(c)
+		s.state.Lock()
+
+		c.Assert(chg.Err(), IsNil)
+	}
+
+	// the system the device was seeded from is kept
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20191119", "5678"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20191119", "5678"})
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	s.checkRecoverySystemSeed(c, "5678")
+	c.Check(s.logbuf.String(), testutil.Contains, `removed old recovery system "1234"`)
+	c.Check(unused, testutil.FileAbsent)
+	c.Check(s.logbuf.String(), testutil.Contains, `removed unused snap "unused_1.snap" from the seed`)
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemRerunCleansUp(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockInstalledSnapsForRecoverySystem(c)
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Assert(err, IsNil)
+
+	// leftovers of a previous interrupted attempt
+	systemDir := filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234")
+	c.Assert(os.MkdirAll(systemDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(systemDir, "model"), []byte("partial"), 0644), IsNil)
+	partialSnap := filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps/pc-kernel_1.snap")
+	c.Assert(os.MkdirAll(filepath.Dir(partialSnap), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(partialSnap+".partial", []byte("partial"), 0644), IsNil)
+	tsk := chg.Tasks()[0]
+	tsk.Set("recovery-system-setup", map[string]interface{}{
+		"label":      "1234",
+		"directory":  systemDir,
+		"snap-files": []string{partialSnap},
+	})
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(partialSnap+".partial", testutil.FileAbsent)
+	s.checkRecoverySystemSeed(c, "1234")
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemUndoFinalize(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.mockInstalledSnapsForRecoverySystem(c)
+
+	chg, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Assert(err, IsNil)
+	tasks := chg.Tasks()
+	terr := s.state.NewTask("error-trigger", "provoking undo")
+	terr.WaitFor(tasks[1])
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*provoking undo.*`)
+	c.Check(tasks[0].Status(), Equals, state.UndoneStatus)
+	c.Check(tasks[1].Status(), Equals, state.UndoneStatus)
+	c.Check(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), testutil.FileAbsent)
+	modeenvRead, err := boot.ReadModeenv("")
+	c.Assert(err, IsNil)
+	c.Check(modeenvRead.CurrentRecoverySystems, DeepEquals, []string{"20191119"})
+	c.Check(modeenvRead.GoodRecoverySystems, DeepEquals, []string{"20191119"})
+}
+
+func (s *deviceMgrSystemsSuite) TestCreateRecoverySystemErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("seeded", false)
+	_, err := devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Check(err, ErrorMatches, "cannot create new recovery systems until fully seeded")
+	c.Check(err, FitsTypeOf, &devicestate.InvalidRecoverySystemError{})
+	s.state.Set("seeded", true)
+
+	for _, label := range []string{"", "Upper", "../1234", "with_underscore", "-1234"} {
+		_, err = devicestate.CreateRecoverySystem(s.state, label, devicestate.CreateRecoverySystemOptions{})
+		c.Check(err, ErrorMatches, `cannot create recovery system: invalid seed system label: .*`, Commentf("label %q", label))
+		c.Check(err, FitsTypeOf, &devicestate.InvalidRecoverySystemError{})
+	}
+
+	err = os.MkdirAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems/1234"), 0755)
+	c.Assert(err, IsNil)
+	_, err = devicestate.CreateRecoverySystem(s.state, "1234", devicestate.CreateRecoverySystemOptions{})
+	c.Check(err, ErrorMatches, `recovery system "1234" already exists`)
+	c.Check(err, FitsTypeOf, &devicestate.InvalidRecoverySystemError{})
+
+	chg := s.state.NewChange("create-recovery-system", "...")
+	chg.AddTask(s.state.NewTask("create-recovery-system", "..."))
+	_, err = devicestate.CreateRecoverySystem(s.state, "5678", devicestate.CreateRecoverySystemOptions{})
+	c.Check(err, ErrorMatches, "creating recovery system in progress")
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+}
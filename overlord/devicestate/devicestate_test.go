@@ -20,9 +20,12 @@
 package devicestate_test
 
 import (
+	"crypto"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -39,6 +42,7 @@ import (
 	"github.com/snapcore/snapd/gadget"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/assertstate"
@@ -152,6 +156,7 @@ func (s *deviceMgrBaseSuite) SetUpTest(c *C) {
 	s.AddCleanup(func() { bootloader.Force(nil) })
 
 	s.AddCleanup(release.MockOnClassic(false))
+	s.AddCleanup(devicestate.MockBootRecordBootTimings(func(string) error { return nil }))
 
 	s.storeSigning = assertstest.NewStoreStack("canonical", nil)
 	s.o = overlord.MockWithStateAndRestartHandler(nil, func(req state.RestartType) {
@@ -420,6 +425,12 @@ func (s *deviceMgrBaseSuite) setPCModelInState(c *C) {
 func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkBootloaderHappy(c *C) {
 	s.setPCModelInState(c)
 
+	oldDurationThreshold := timings.DurationThreshold
+	defer func() {
+		timings.DurationThreshold = oldDurationThreshold
+	}()
+	timings.DurationThreshold = 0
+
 	s.bootloader.SetBootVars(map[string]string{
 		"snap_mode":     boot.TryingStatus,
 		"snap_try_core": "core_1.snap",
@@ -443,6 +454,47 @@ func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkBootloaderHappy(c *C) {
 	m, err := s.bootloader.GetBootVars("snap_mode")
 	c.Assert(err, IsNil)
 	c.Assert(m, DeepEquals, map[string]string{"snap_mode": ""})
+
+	// the boot commit is timed
+	tms, err := timings.Get(s.state, -1, func(tags map[string]string) bool {
+		return tags["ensure"] == "boot-ok"
+	})
+	c.Assert(err, IsNil)
+	c.Assert(tms, HasLen, 1)
+	var labels []string
+	for _, tm := range tms[0].NestedTimings {
+		labels = append(labels, tm.Label)
+	}
+	c.Check(labels, DeepEquals, []string{"mark-boot-successful", "update-boot-revisions"})
+}
+
+func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkRecordsBootTimings(c *C) {
+	s.setPCModelInState(c)
+
+	s.bootloader.SetBootVars(map[string]string{
+		"snap_kernel": "pc-kernel_1.snap",
+		"snap_core":   "core_1.snap",
+	})
+
+	var kernels []string
+	restore := devicestate.MockBootRecordBootTimings(func(kernelSnap string) error {
+		kernels = append(kernels, kernelSnap)
+		return fmt.Errorf("boom")
+	})
+	defer restore()
+
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	err := devicestate.EnsureBootOk(s.mgr)
+	c.Assert(err, IsNil)
+	c.Check(kernels, DeepEquals, []string{"pc-kernel_1.snap"})
+	c.Check(logbuf.String(), testutil.Contains, "cannot record boot timings: boom")
+
+	// only recorded once per boot
+	err = devicestate.EnsureBootOk(s.mgr)
+	c.Assert(err, IsNil)
+	c.Check(kernels, HasLen, 1)
 }
 
 func (s *deviceMgrSuite) TestDeviceManagerEnsureBootOkUpdateBootRevisionsHappy(c *C) {
@@ -1266,6 +1318,8 @@ func (s *deviceMgrSuite) TestDeviceManagerStartupUC20UbuntuSaveErr(c *C) {
 func (s *deviceMgrSuite) TestDeviceManagerStartupNonUC20NoUbuntuSave(c *C) {
 	err := os.RemoveAll(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir))
 	c.Assert(err, IsNil)
+	err = os.RemoveAll(dirs.SnapModeenvFileUnder(dirs.GlobalRootDir) + ".backup")
+	c.Assert(err, IsNil)
 	// create a new manager so that we know it does not see the modeenv
 	mgr, err := devicestate.Manager(s.state, s.hookMgr, s.o.TaskRunner(), s.newStore)
 	c.Assert(err, IsNil)
@@ -1574,3 +1628,57 @@ func (s *startOfOperationTimeSuite) TestStartOfOperationErrorIfPreseed(c *C) {
 	_, err := mgr.StartOfOperationTime()
 	c.Assert(err, ErrorMatches, `internal error: unexpected call to StartOfOperationTime in preseed mode`)
 }
+
+func (s *deviceMgrSuite) TestCheckPreseedArtifact(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	model := s.makeModelAssertionInState(c, "my-brand", "my-model", map[string]interface{}{
+		"architecture": "amd64",
+		"kernel":       "pc-kernel",
+		"gadget":       "pc",
+	})
+
+	// nothing to verify without an artifact and a preseed assertion
+	c.Check(devicestate.CheckPreseedArtifact(s.state, model, "20210302"), IsNil)
+
+	err := devicestate.CheckPreseedArtifact(s.state, model, "")
+	c.Check(err, ErrorMatches, `internal error: cannot verify preseed artifact without a seed system label`)
+
+	artifact := filepath.Join(dirs.SnapSeedDir, "systems", "20210302", "preseed.tgz")
+	c.Assert(os.MkdirAll(filepath.Dir(artifact), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(artifact, []byte("preseeded data"), 0644), IsNil)
+
+	err = devicestate.CheckPreseedArtifact(s.state, model, "20210302")
+	c.Check(err, ErrorMatches, `cannot use preseeded data of system "20210302": missing preseed assertion`)
+
+	digest, _, err := osutil.FileDigest(artifact, crypto.SHA3_384)
+	c.Assert(err, IsNil)
+	sha3_384, err := asserts.EncodeDigest(crypto.SHA3_384, digest)
+	c.Assert(err, IsNil)
+	preseed, err := s.brands.Signing("my-brand").Sign(asserts.PreseedType, map[string]interface{}{
+		"series":            "16",
+		"brand-id":          "my-brand",
+		"model":             "my-model",
+		"system-label":      "20210302",
+		"artifact-sha3-384": sha3_384,
+		"snaps": []interface{}{
+			map[string]interface{}{"name": "pc-kernel"},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	assertstatetest.AddMany(s.state, preseed)
+
+	c.Check(devicestate.CheckPreseedArtifact(s.state, model, "20210302"), IsNil)
+
+	// tampered artifact
+	c.Assert(ioutil.WriteFile(artifact, []byte("tampered data"), 0644), IsNil)
+	err = devicestate.CheckPreseedArtifact(s.state, model, "20210302")
+	c.Check(err, ErrorMatches, `cannot use preseeded data of system "20210302": artifact digest does not match the preseed assertion`)
+
+	// missing artifact when the preseed assertion expects one
+	c.Assert(os.Remove(artifact), IsNil)
+	err = devicestate.CheckPreseedArtifact(s.state, model, "20210302")
+	c.Check(err, ErrorMatches, `cannot use preseeded data of system "20210302": missing preseed artifact`)
+}
@@ -165,6 +165,13 @@ func MockDeviceService(c *C, bhv *DeviceServiceBehavior) *httptest.Server {
 			brandID := serialReq.BrandID()
 			model := serialReq.Model()
 			reqID := serialReq.RequestID()
+			for _, a1 := range extra {
+				// proof of possession of a previous device key
+				if proofReq, ok := a1.(*asserts.SerialRequest); ok {
+					c.Check(asserts.SignatureCheck(proofReq, proofReq.DeviceKey()), IsNil)
+					c.Check(proofReq.RequestID(), Equals, reqID)
+				}
+			}
 			if reqID == ReqIDBadRequest {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(400)
@@ -32,6 +32,7 @@ import (
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/overlord/storecontext"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/sysconfig"
 	"github.com/snapcore/snapd/timings"
 )
@@ -72,6 +73,14 @@ func MockMaxTentatives(max int) (restore func()) {
 	}
 }
 
+func MockMaxRecoverySystems(max int) (restore func()) {
+	old := maxRecoverySystems
+	maxRecoverySystems = max
+	return func() {
+		maxRecoverySystems = old
+	}
+}
+
 func MockTimeNow(f func() time.Time) (restore func()) {
 	old := timeNow
 	timeNow = f
@@ -140,6 +149,14 @@ func MockSnapstateInstallWithDeviceContext(f func(ctx context.Context, st *state
 	}
 }
 
+func MockSnapstateInstallPathWithDeviceContext(f func(st *state.State, si *snap.SideInfo, path, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags, deviceCtx snapstate.DeviceContext, fromChange string) (*state.TaskSet, error)) (restore func()) {
+	old := snapstateInstallPathWithDeviceContext
+	snapstateInstallPathWithDeviceContext = f
+	return func() {
+		snapstateInstallPathWithDeviceContext = old
+	}
+}
+
 func MockSnapstateUpdateWithDeviceContext(f func(st *state.State, name string, opts *snapstate.RevisionOptions, userID int, flags snapstate.Flags, deviceCtx snapstate.DeviceContext, fromChange string) (*state.TaskSet, error)) (restore func()) {
 	old := snapstateUpdateWithDeviceContext
 	snapstateUpdateWithDeviceContext = f
@@ -176,6 +193,10 @@ func SetBootOkRan(m *DeviceManager, b bool) {
 	m.bootOkRan = b
 }
 
+func SetBootRevisionsUpdated(m *DeviceManager, b bool) {
+	m.bootRevisionsUpdated = b
+}
+
 func SetInstalledRan(m *DeviceManager, b bool) {
 	m.ensureInstalledRan = b
 }
@@ -224,8 +245,18 @@ var (
 	PendingGadgetInfo   = pendingGadgetInfo
 
 	CriticalTaskEdges = criticalTaskEdges
+
+	CheckPreseedArtifact = checkPreseedArtifact
 )
 
+func MockBootUpdateCommandLineForGadgetComponent(f func(dev boot.Device, gadgetDir string) (bool, error)) (restore func()) {
+	old := bootUpdateCommandLineForGadgetComponent
+	bootUpdateCommandLineForGadgetComponent = f
+	return func() {
+		bootUpdateCommandLineForGadgetComponent = old
+	}
+}
+
 func MockGadgetUpdate(mock func(current, update gadget.GadgetData, path string, policy gadget.UpdatePolicyFunc, observer gadget.ContentUpdateObserver) error) (restore func()) {
 	old := gadgetUpdate
 	gadgetUpdate = mock
@@ -290,6 +321,14 @@ func MockInstallRun(f func(model gadget.Model, gadgetRoot, kernelRoot, device st
 	}
 }
 
+func MockInstallFactoryReset(f func(model gadget.Model, gadgetRoot, kernelRoot, device string, options install.Options, observer gadget.ContentObserver) (*install.InstalledSystemSideData, error)) (restore func()) {
+	old := installFactoryReset
+	installFactoryReset = f
+	return func() {
+		installFactoryReset = old
+	}
+}
+
 func MockCloudInitStatus(f func() (sysconfig.CloudInitState, error)) (restore func()) {
 	old := cloudInitStatus
 	cloudInitStatus = f
@@ -321,3 +360,11 @@ func DeviceManagerCheckEncryption(mgr *DeviceManager, st *state.State, deviceCtx
 func DeviceManagerCheckFDEFeatures(mgr *DeviceManager, st *state.State) error {
 	return mgr.checkFDEFeatures(st)
 }
+
+func MockBootRecordBootTimings(f func(kernelSnap string) error) (restore func()) {
+	old := bootRecordBootTimings
+	bootRecordBootTimings = f
+	return func() {
+		bootRecordBootTimings = old
+	}
+}
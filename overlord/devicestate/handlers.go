@@ -19,18 +19,116 @@
 package devicestate
 
 import (
+	"crypto"
 	"fmt"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
+// PreseededSnap records a snap that was set up (mounted, security
+// profiles generated, service units created) when preseeding the
+// image.
+type PreseededSnap struct {
+	Name     string        `json:"name"`
+	Revision snap.Revision `json:"revision"`
+	Type     snap.Type     `json:"type,omitempty"`
+}
+
+// PreseededSnaps returns the snaps that were set up when preseeding the
+// image, if any.
+func PreseededSnaps(st *state.State) ([]PreseededSnap, error) {
+	var preseeded []PreseededSnap
+	if err := st.Get("preseeded-snaps", &preseeded); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	return preseeded, nil
+}
+
+// checkPreseededSnaps compares the snaps recorded at preseeding time
+// with the current ones and logs any that changed in between. Nothing
+// else needs to be done for them as the security profiles of all snaps
+// are regenerated on startup anyway.
+func checkPreseededSnaps(st *state.State, snaps map[string]*snapstate.SnapState) error {
+	preseeded, err := PreseededSnaps(st)
+	if err != nil {
+		return err
+	}
+	for _, ps := range preseeded {
+		snapSt, ok := snaps[ps.Name]
+		if !ok {
+			logger.Noticef("snap %q was preseeded but is not installed anymore", ps.Name)
+			continue
+		}
+		if snapSt.Current != ps.Revision {
+			logger.Noticef("snap %q was preseeded with revision %s but revision %s is current", ps.Name, ps.Revision, snapSt.Current)
+		}
+	}
+	return nil
+}
+
+// checkPreseedArtifact verifies the preseeding artifact of the given
+// seed system against the preseed assertion signed by the brand for the
+// model, so that preseeded data that was tampered with after the image
+// was built is never used. It must be called before the artifact is
+// applied. An artifact without a preseed assertion, as well as a preseed
+// assertion without its artifact, is an error.
+func checkPreseedArtifact(st *state.State, model *asserts.Model, systemLabel string) error {
+	if systemLabel == "" {
+		return fmt.Errorf("internal error: cannot verify preseed artifact without a seed system label")
+	}
+	artifact := filepath.Join(dirs.SnapSeedDir, "systems", systemLabel, "preseed.tgz")
+	hasArtifact := osutil.FileExists(artifact)
+
+	a, err := assertstate.DB(st).Find(asserts.PreseedType, map[string]string{
+		"series":       model.Series(),
+		"brand-id":     model.BrandID(),
+		"model":        model.Model(),
+		"system-label": systemLabel,
+	})
+	if asserts.IsNotFound(err) {
+		if hasArtifact {
+			return fmt.Errorf("cannot use preseeded data of system %q: missing preseed assertion", systemLabel)
+		}
+		// not a preseeded system
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	preseed := a.(*asserts.Preseed)
+
+	if !hasArtifact {
+		return fmt.Errorf("cannot use preseeded data of system %q: missing preseed artifact", systemLabel)
+	}
+
+	digest, _, err := osutil.FileDigest(artifact, crypto.SHA3_384)
+	if err != nil {
+		return fmt.Errorf("cannot compute digest of preseed artifact: %v", err)
+	}
+	sha3_384, err := asserts.EncodeDigest(crypto.SHA3_384, digest)
+	if err != nil {
+		return err
+	}
+	if sha3_384 != preseed.ArtifactSHA3_384() {
+		return fmt.Errorf("cannot use preseeded data of system %q: artifact digest does not match the preseed assertion", systemLabel)
+	}
+	return nil
+}
+
 func (m *DeviceManager) doMarkPreseeded(t *state.Task, _ *tomb.Tomb) error {
 	st := t.State()
 	st.Lock()
@@ -61,11 +159,17 @@ func (m *DeviceManager) doMarkPreseeded(t *state.Task, _ *tomb.Tomb) error {
 			t.Set("preseeded", preseeded)
 			// unmount all snaps
 			// TODO: move to snapstate.UnmountAllSnaps.
+			preseededSnaps := make([]PreseededSnap, 0, len(snaps))
 			for _, snapSt := range snaps {
 				info, err := snapSt.CurrentInfo()
 				if err != nil {
 					return err
 				}
+				preseededSnaps = append(preseededSnaps, PreseededSnap{
+					Name:     info.InstanceName(),
+					Revision: info.Revision,
+					Type:     info.Type(),
+				})
 				logger.Debugf("unmounting snap %s at %s", info.InstanceName(), info.MountDir())
 				if _, err := exec.Command("umount", "-d", "-l", info.MountDir()).CombinedOutput(); err != nil {
 					return err
@@ -75,6 +179,12 @@ func (m *DeviceManager) doMarkPreseeded(t *state.Task, _ *tomb.Tomb) error {
 			st.Set("preseeded", preseeded)
 			st.Set("preseed-system-key", systemKey)
 			st.Set("preseed-time", timeNow())
+			// record what was set up at image build time, first boot
+			// only redoes the device specific parts
+			sort.Slice(preseededSnaps, func(i, j int) bool {
+				return preseededSnaps[i].Name < preseededSnaps[j].Name
+			})
+			st.Set("preseeded-snaps", preseededSnaps)
 
 			// do not mark this task done as this makes it racy against taskrunner tear down (the next task
 			// could start). Let this task finish after snapd restart when preseed mode is off.
@@ -86,6 +196,9 @@ func (m *DeviceManager) doMarkPreseeded(t *state.Task, _ *tomb.Tomb) error {
 
 	// normal snapd run after snapd restart (not in preseed mode anymore)
 
+	if err := checkPreseededSnaps(st, snaps); err != nil {
+		return err
+	}
 	st.Set("seed-restart-system-key", systemKey)
 	if err := m.setTimeOnce("seed-restart-time", startTime); err != nil {
 		return err
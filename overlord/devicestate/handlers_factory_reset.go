@@ -0,0 +1,138 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// factoryResetMarker is written to ubuntu-save at the end of a factory
+// reset, so that its result can be reported once in run mode.
+type factoryResetMarker struct {
+	RecoverySystem string    `json:"recovery-system"`
+	Time           time.Time `json:"time"`
+}
+
+func factoryResetMarkerFile(saveDeviceDir string) string {
+	return filepath.Join(saveDeviceDir, "factory-reset")
+}
+
+func writeFactoryResetMarker(recoverySystem string) error {
+	if !osutil.IsDirectory(boot.InitramfsUbuntuSaveDir) {
+		logger.Noticef("ubuntu-save is not available, the factory reset will not be reported")
+		return nil
+	}
+	marker := factoryResetMarkerFile(filepath.Join(boot.InitramfsUbuntuSaveDir, "device"))
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		return fmt.Errorf("cannot write factory reset marker: %v", err)
+	}
+	b, err := json.Marshal(&factoryResetMarker{
+		RecoverySystem: recoverySystem,
+		Time:           timeNow(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := osutil.AtomicWriteFile(marker, b, 0644, 0); err != nil {
+		return fmt.Errorf("cannot write factory reset marker: %v", err)
+	}
+	return nil
+}
+
+func readFactoryResetMarker() (*factoryResetMarker, error) {
+	b, err := ioutil.ReadFile(factoryResetMarkerFile(dirs.SnapDeviceSaveDir))
+	if err != nil {
+		return nil, err
+	}
+	var marker factoryResetMarker
+	if err := json.Unmarshal(b, &marker); err != nil {
+		return nil, fmt.Errorf("cannot decode factory reset marker: %v", err)
+	}
+	return &marker, nil
+}
+
+func (m *DeviceManager) doRequestFactoryReset(t *state.Task, _ *tomb.Tomb) error {
+	systemMode := m.SystemMode()
+	if systemMode != "run" {
+		return fmt.Errorf("cannot factory reset the device from %q mode", systemMode)
+	}
+
+	// the system the device was installed from is the one it gets
+	// reinstalled from, make sure it is still there and usable
+	currentSys, err := currentSystemForMode(m.state, systemMode)
+	if err != nil {
+		return fmt.Errorf("cannot determine the current recovery system: %v", err)
+	}
+	system, err := systemFromSeed(currentSys.System, currentSys)
+	if err != nil {
+		return fmt.Errorf("cannot use recovery system %q: %v", currentSys.System, err)
+	}
+	if !system.Current {
+		return fmt.Errorf("cannot use recovery system %q: it does not match the seeded system", currentSys.System)
+	}
+	// the key of an encrypted ubuntu-save is kept on ubuntu-data
+	if osutil.FileExists(filepath.Join(dirs.SnapFDEDir, "sealed-keys")) {
+		return fmt.Errorf("cannot factory reset an encrypted system")
+	}
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return err
+	}
+	if err := boot.EnterFactoryReset(deviceCtx, system.Label); err != nil {
+		return fmt.Errorf("cannot set device to boot into system %q in mode %q: %v", system.Label, boot.ModeFactoryReset, err)
+	}
+
+	logger.Noticef("rebooting into system %q to factory reset the device", system.Label)
+	st.RequestRestart(state.RestartSystemNow)
+
+	return nil
+}
+
+func (m *DeviceManager) doFinishFactoryReset(t *state.Task, _ *tomb.Tomb) error {
+	marker, err := readFactoryResetMarker()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if marker != nil {
+		logger.Noticef("device was factory reset from recovery system %q at %v", marker.RecoverySystem, marker.Time)
+	}
+	if err := os.Remove(factoryResetMarkerFile(dirs.SnapDeviceSaveDir)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("cannot remove factory reset marker: %v", err)
+	}
+	return nil
+}
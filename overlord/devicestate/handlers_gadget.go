@@ -77,6 +77,8 @@ func pendingGadgetInfo(snapsup *snapstate.SnapSetup, pendingDeviceCtx snapstate.
 
 var (
 	gadgetUpdate = gadget.Update
+
+	bootUpdateCommandLineForGadgetComponent = boot.UpdateCommandLineForGadgetComponent
 )
 
 func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error {
@@ -191,14 +193,27 @@ func (m *DeviceManager) doUpdateGadgetAssets(t *state.Task, _ *tomb.Tomb) error
 	// on top of that we do not expect the update to be moving large amounts
 	// of data
 	err = gadgetUpdate(*currentData, *updateData, snapRollbackDir, updatePolicy, updateObserver)
-	if err != nil {
-		if err == gadget.ErrNoUpdate {
-			// no update needed
-			t.Logf("No gadget assets update needed")
-			return nil
-		}
+	if err != nil && err != gadget.ErrNoUpdate {
 		return err
 	}
+	assetsUpdated := err == nil
+
+	cmdlineUpdated := false
+	if snapsup.Type == snap.TypeGadget {
+		// the new kernel command line arguments are tried on the next
+		// boot, the bootloader falls back to the current ones unless
+		// the boot is marked as successful
+		cmdlineUpdated, err = bootUpdateCommandLineForGadgetComponent(remodelCtx, updateData.RootDir)
+		if err != nil {
+			return fmt.Errorf("cannot update kernel command line from gadget: %v", err)
+		}
+	}
+
+	if !assetsUpdated && !cmdlineUpdated {
+		// no update needed
+		t.Logf("No gadget assets update needed")
+		return nil
+	}
 
 	t.SetStatus(state.DoneStatus)
 
@@ -45,6 +45,7 @@ var (
 	bootMakeRunnable            = boot.MakeRunnableSystem
 	bootEnsureNextBootToRunMode = boot.EnsureNextBootToRunMode
 	installRun                  = install.Run
+	installFactoryReset         = install.FactoryReset
 
 	sysconfigConfigureTargetSystem = sysconfig.ConfigureTargetSystem
 )
@@ -124,6 +125,16 @@ func writeLogs(rootdir string) error {
 }
 
 func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
+	return m.setupRunSystem(t, false)
+}
+
+func (m *DeviceManager) doFactoryResetRunSystem(t *state.Task, _ *tomb.Tomb) error {
+	return m.setupRunSystem(t, true)
+}
+
+// setupRunSystem creates the partitions of the run system and makes it
+// bootable. For a factory reset the content of ubuntu-save is kept.
+func (m *DeviceManager) setupRunSystem(t *state.Task, factoryReset bool) error {
 	st := t.State()
 	st.Lock()
 	defer st.Unlock()
@@ -156,6 +167,12 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("missing modeenv, cannot proceed")
 	}
 
+	// verify the preseeding artifact of the seed system before anything
+	// of the run system is set up
+	if err := checkPreseedArtifact(st, deviceCtx.Model(), modeEnv.RecoverySystem); err != nil {
+		return err
+	}
+
 	// bootstrap
 	bopts := install.Options{
 		Mount: true,
@@ -165,6 +182,11 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 	bopts.Encrypt = useEncryption
+	if factoryReset && useEncryption {
+		// the key of ubuntu-save was kept on the ubuntu-data
+		// that is being reset
+		return fmt.Errorf("cannot factory reset an encrypted system")
+	}
 
 	model := deviceCtx.Model()
 
@@ -202,9 +224,16 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 	func() {
 		st.Unlock()
 		defer st.Lock()
-		installedSystem, err = installRun(model, gadgetDir, kernelDir, "", bopts, installObserver)
+		if factoryReset {
+			installedSystem, err = installFactoryReset(model, gadgetDir, kernelDir, "", bopts, installObserver)
+		} else {
+			installedSystem, err = installRun(model, gadgetDir, kernelDir, "", bopts, installObserver)
+		}
 	}()
 	if err != nil {
+		if factoryReset {
+			return fmt.Errorf("cannot perform factory reset: %v", err)
+		}
 		return fmt.Errorf("cannot install system: %v", err)
 	}
 
@@ -269,6 +298,13 @@ func (m *DeviceManager) doSetupRunSystem(t *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("cannot make system runnable: %v", err)
 	}
 
+	if factoryReset {
+		// so that the result can be reported once in run mode
+		if err := writeFactoryResetMarker(modeEnv.RecoverySystem); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -420,7 +456,13 @@ func (m *DeviceManager) doRestartSystemToRunMode(t *state.Task, _ *tomb.Tomb) er
 	}
 
 	// ensure the next boot goes into run mode
-	if err := bootEnsureNextBootToRunMode(modeEnv.RecoverySystem); err != nil {
+	if modeEnv.Mode == boot.ModeFactoryReset {
+		// this also marks the factory reset as done, such that it is
+		// not resumed anymore
+		if err := boot.FinishFactoryReset(modeEnv.RecoverySystem); err != nil {
+			return err
+		}
+	} else if err := bootEnsureNextBootToRunMode(modeEnv.RecoverySystem); err != nil {
 		return err
 	}
 
@@ -90,7 +90,18 @@ func (m *DeviceManager) doGenerateDeviceKey(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	if device.KeyID != "" {
+	rotating := isKeyRotation(t)
+	if rotating {
+		var newKeyID string
+		err := t.Change().Get("new-device-key-id", &newKeyID)
+		if err != nil && err != state.ErrNoState {
+			return err
+		}
+		if newKeyID != "" {
+			// nothing to do
+			return nil
+		}
+	} else if device.KeyID != "" {
 		// nothing to do
 		return nil
 	}
@@ -113,6 +124,14 @@ func (m *DeviceManager) doGenerateDeviceKey(t *state.Task, _ *tomb.Tomb) error {
 		return fmt.Errorf("cannot store device key pair: %v", err)
 	}
 
+	if rotating {
+		// the device switches to the new key only once a serial
+		// for it has been obtained
+		t.Change().Set("new-device-key-id", privKey.PublicKey().ID())
+		t.SetStatus(state.DoneStatus)
+		return nil
+	}
+
 	device.KeyID = privKey.PublicKey().ID()
 	err = m.setDevice(device)
 	if err != nil {
@@ -122,6 +141,44 @@ func (m *DeviceManager) doGenerateDeviceKey(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+func (m *DeviceManager) undoGenerateDeviceKey(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if !isKeyRotation(t) {
+		// nothing to do, the first device key is kept around
+		return nil
+	}
+
+	var newKeyID string
+	err := t.Change().Get("new-device-key-id", &newKeyID)
+	if err == state.ErrNoState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	device, err := m.device()
+	if err != nil {
+		return err
+	}
+	if device.KeyID == newKeyID {
+		// the device already switched to the new key
+		return nil
+	}
+
+	// drop the new key that never got a serial
+	err = m.withKeypairMgr(func(keypairMgr asserts.KeypairManager) error {
+		return keypairMgr.DeleteByID(newKeyID)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot remove new device key pair: %v", err)
+	}
+	t.Change().Set("new-device-key-id", nil)
+	return nil
+}
+
 func newEnoughProxy(st *state.State, proxyURL *url.URL, client *http.Client) bool {
 	st.Unlock()
 	defer st.Lock()
@@ -245,6 +302,115 @@ func (rc *initialRegistrationContext) FinishRegistration(serial *asserts.Serial)
 	return nil
 }
 
+// isKeyRotation returns whether the task is part of a device key rotation.
+func isKeyRotation(t *state.Task) bool {
+	if t == nil {
+		return false
+	}
+	chg := t.Change()
+	return chg != nil && chg.Kind() == "rotate-device-key"
+}
+
+// keyRotationRegistrationContext implements registrationContext for
+// requesting a serial for a new device key, the request carries proof
+// of possession of the current device key
+type keyRotationRegistrationContext struct {
+	deviceMgr *DeviceManager
+
+	model      *asserts.Model
+	newKeyID   string
+	origSerial *asserts.Serial
+	origKey    asserts.PrivateKey
+}
+
+func newKeyRotationRegistrationContext(m *DeviceManager, t *state.Task, model *asserts.Model) (*keyRotationRegistrationContext, error) {
+	var newKeyID string
+	if err := t.Change().Get("new-device-key-id", &newKeyID); err != nil {
+		return nil, fmt.Errorf("internal error: cannot find new device key id: %v", err)
+	}
+	origSerial, err := findSerial(m.state, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot find current serial before proceeding with device key rotation: %v", err)
+	}
+	origKey, err := m.keyPair()
+	if err != nil {
+		return nil, fmt.Errorf("cannot find current device key pair: %v", err)
+	}
+	return &keyRotationRegistrationContext{
+		deviceMgr:  m,
+		model:      model,
+		newKeyID:   newKeyID,
+		origSerial: origSerial,
+		origKey:    origKey,
+	}, nil
+}
+
+func (rc *keyRotationRegistrationContext) ForRemodeling() bool {
+	return false
+}
+
+func (rc *keyRotationRegistrationContext) Device() (*auth.DeviceState, error) {
+	device, err := rc.deviceMgr.device()
+	if err != nil {
+		return nil, err
+	}
+	// the identity to register is the current one with the new key
+	return &auth.DeviceState{
+		Brand: device.Brand,
+		Model: device.Model,
+		KeyID: rc.newKeyID,
+	}, nil
+}
+
+func (rc *keyRotationRegistrationContext) Model() *asserts.Model {
+	return rc.model
+}
+
+func (rc *keyRotationRegistrationContext) GadgetForSerialRequestConfig() string {
+	return rc.model.Gadget()
+}
+
+func (rc *keyRotationRegistrationContext) SerialRequestExtraHeaders() map[string]interface{} {
+	return nil
+}
+
+func (rc *keyRotationRegistrationContext) SerialRequestAncillaryAssertions() []asserts.Assertion {
+	return []asserts.Assertion{rc.model, rc.origSerial}
+}
+
+// PreviousDeviceKey returns the current device key with which to
+// sign the proof of possession accompanying the serial-request.
+func (rc *keyRotationRegistrationContext) PreviousDeviceKey() asserts.PrivateKey {
+	return rc.origKey
+}
+
+func (rc *keyRotationRegistrationContext) FinishRegistration(serial *asserts.Serial) error {
+	device, err := rc.deviceMgr.device()
+	if err != nil {
+		return err
+	}
+
+	// switch over to the new key and serial at once
+	oldKeyID := device.KeyID
+	device.KeyID = rc.newKeyID
+	device.Serial = serial.Serial()
+	// the session was established with the old key
+	device.SessionMacaroon = ""
+	if err := rc.deviceMgr.setDevice(device); err != nil {
+		return err
+	}
+
+	// the old key is not needed anymore
+	err = rc.deviceMgr.withKeypairMgr(func(keypairMgr asserts.KeypairManager) error {
+		return keypairMgr.DeleteByID(oldKeyID)
+	})
+	if err != nil {
+		// the rotation itself succeeded
+		logger.Noticef("cannot remove previous device key pair %q: %v", oldKeyID, err)
+	}
+	return nil
+}
+
 // registrationCtx returns a registrationContext appropriate for the task and its change.
 func (m *DeviceManager) registrationCtx(t *state.Task) (registrationContext, error) {
 	remodCtx, err := remodelCtxFromTask(t)
@@ -259,6 +425,10 @@ func (m *DeviceManager) registrationCtx(t *state.Task) (registrationContext, err
 		return nil, err
 	}
 
+	if isKeyRotation(t) {
+		return newKeyRotationRegistrationContext(m, t, model)
+	}
+
 	return &initialRegistrationContext{
 		deviceMgr: m,
 		model:     model,
@@ -411,6 +581,30 @@ func prepareSerialRequest(t *state.Task, regCtx registrationContext, privKey ass
 
 	}
 
+	if prevKeyCtx, ok := regCtx.(interface {
+		PreviousDeviceKey() asserts.PrivateKey
+	}); ok {
+		// prove possession of the previous device key with
+		// a serial-request for the same identity signed by it
+		prevKey := prevKeyCtx.PreviousDeviceKey()
+		encodedPrevPubKey, err := asserts.EncodePublicKey(prevKey.PublicKey())
+		if err != nil {
+			return "", fmt.Errorf("internal error: cannot encode previous device public key: %v", err)
+		}
+		proofHeaders := make(map[string]interface{}, len(headers))
+		for k, v := range headers {
+			proofHeaders[k] = v
+		}
+		proofHeaders["device-key"] = string(encodedPrevPubKey)
+		proofReq, err := asserts.SignWithoutAuthority(asserts.SerialRequestType, proofHeaders, cfg.body, prevKey)
+		if err != nil {
+			return "", err
+		}
+		if err := encoder.Encode(proofReq); err != nil {
+			return "", fmt.Errorf("cannot encode previous device key proof: %v", err)
+		}
+	}
+
 	return buf.String(), nil
 }
 
@@ -664,8 +858,7 @@ func (m *DeviceManager) doRequestSerial(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
-	// NB: the keyPair is fixed for now
-	privKey, err := m.keyPair()
+	privKey, err := m.keyPairFor(device)
 	if err == state.ErrNoState {
 		return fmt.Errorf("internal error: cannot find device key pair")
 	}
@@ -0,0 +1,363 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package devicestate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/seed"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timings"
+)
+
+// maxRecoverySystems is the number of recovery systems kept on ubuntu-seed,
+// older systems above the limit are removed once a new recovery system has
+// been created.
+var maxRecoverySystems = 3
+
+// recoverySystemSetup is the data kept by the create-recovery-system task.
+type recoverySystemSetup struct {
+	// Label of the recovery system
+	Label string `json:"label"`
+	// Directory of the recovery system inside the seed
+	Directory string `json:"directory"`
+	// TestSystem is set when the system is test booted before it gets
+	// promoted to a good recovery system
+	TestSystem bool `json:"test-system,omitempty"`
+	// SnapFiles are the snap files newly written to the seed while
+	// creating the recovery system
+	SnapFiles []string `json:"snap-files,omitempty"`
+}
+
+func taskRecoverySystemSetup(t *state.Task) (*recoverySystemSetup, *state.Task, error) {
+	var setup recoverySystemSetup
+	err := t.Get("recovery-system-setup", &setup)
+	if err == nil {
+		return &setup, t, nil
+	}
+	if err != state.ErrNoState {
+		return nil, nil, err
+	}
+	// find the task which holds the data
+	var id string
+	if err := t.Get("recovery-system-setup-task", &id); err != nil {
+		return nil, nil, err
+	}
+	ts := t.State().Task(id)
+	if ts == nil {
+		return nil, nil, fmt.Errorf("internal error: tasks are being pruned")
+	}
+	if err := ts.Get("recovery-system-setup", &setup); err != nil {
+		return nil, nil, err
+	}
+	return &setup, ts, nil
+}
+
+func (m *DeviceManager) doCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return err
+	}
+	setup, _, err := taskRecoverySystemSetup(t)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot obtain recovery system setup information: %v", err)
+	}
+	label := setup.Label
+
+	// the task may be re-run after snapd was interrupted while creating the
+	// system, start from a clean slate; the directory did not exist when the
+	// change was created so anything found there is a leftover
+	if len(setup.SnapFiles) != 0 || osutil.FileExists(setup.Directory) {
+		if err := removeRecoverySystemFiles(setup); err != nil {
+			return fmt.Errorf("cannot clean up partially created recovery system %q: %v", label, err)
+		}
+		setup.SnapFiles = nil
+		t.Set("recovery-system-setup", setup)
+	}
+
+	db := assertstate.DB(st)
+	getInfo := func(name string) (*snap.Info, string, bool, error) {
+		info, err := snapstate.CurrentInfo(st, name)
+		if err != nil {
+			if _, ok := err.(*snap.NotInstalledError); ok {
+				return nil, "", false, nil
+			}
+			return nil, "", false, err
+		}
+		return info, info.MountFile(), true, nil
+	}
+	observeWrite := func(where string) error {
+		// keep track of the files so that they can be removed on undo
+		setup.SnapFiles = append(setup.SnapFiles, where)
+		t.Set("recovery-system-setup", setup)
+		return nil
+	}
+
+	if _, err := createSystemForModelFromValidatedSnaps(deviceCtx.Model(), label, db, getInfo, observeWrite); err != nil {
+		return fmt.Errorf("cannot create recovery system %q: %v", label, err)
+	}
+
+	if !setup.TestSystem {
+		// the system will be promoted without being tried
+		return nil
+	}
+
+	// add the new system to the modeenv and set up the bootloader
+	// to try it out
+	if err := boot.SetTryRecoverySystem(deviceCtx, label); err != nil {
+		return fmt.Errorf("cannot attempt booting into recovery system %q: %v", label, err)
+	}
+	if err := boot.SetRecoveryBootSystemAndMode(deviceCtx, label, boot.ModeRecover); err != nil {
+		return fmt.Errorf("cannot set device to boot into system %q in mode %q: %v", label, boot.ModeRecover, err)
+	}
+
+	logger.Noticef("rebooting into recovery system %q to test it", label)
+	st.RequestRestart(state.RestartSystemNow)
+
+	return nil
+}
+
+func (m *DeviceManager) undoCreateRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return err
+	}
+	setup, _, err := taskRecoverySystemSetup(t)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot obtain recovery system setup information: %v", err)
+	}
+	label := setup.Label
+
+	var undoErrors []string
+	if err := boot.ClearTryRecoverySystem(deviceCtx, label); err != nil {
+		undoErrors = append(undoErrors, fmt.Sprintf("cannot clear recovery system %q: %v", label, err))
+	}
+	if err := removeRecoverySystemFiles(setup); err != nil {
+		undoErrors = append(undoErrors, err.Error())
+	}
+	if len(undoErrors) != 0 {
+		return fmt.Errorf("cannot undo creation of recovery system %q:\n- %s", label, strings.Join(undoErrors, "\n- "))
+	}
+	return nil
+}
+
+// removeRecoverySystemFiles removes the snap files written to the seed and
+// the directory of the recovery system described by setup.
+func removeRecoverySystemFiles(setup *recoverySystemSetup) error {
+	for _, fn := range setup.SnapFiles {
+		// also remove an interrupted copy
+		for _, p := range []string{fn, fn + ".partial"} {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("cannot remove snap file %q: %v", p, err)
+			}
+		}
+	}
+	if err := os.RemoveAll(setup.Directory); err != nil {
+		return fmt.Errorf("cannot remove recovery system %q: %v", setup.Label, err)
+	}
+	return nil
+}
+
+func (m *DeviceManager) doFinalizeRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
+	if ok, _ := m.state.Restarting(); ok {
+		// don't continue until we are in the restarted snapd
+		t.Logf("Waiting for system reboot...")
+		return &state.Retry{}
+	}
+
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return err
+	}
+	setup, _, err := taskRecoverySystemSetup(t)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot obtain recovery system setup information: %v", err)
+	}
+	label := setup.Label
+
+	if setup.TestSystem {
+		outcome, triedLabel, err := boot.InspectTryRecoverySystemOutcome(deviceCtx)
+		if err != nil {
+			return fmt.Errorf("cannot inspect the outcome of trying recovery system %q: %v", label, err)
+		}
+		switch {
+		case outcome == boot.TryRecoverySystemOutcomeNoneTried:
+			return fmt.Errorf("recovery system %q was not tried", label)
+		case triedLabel != label:
+			return fmt.Errorf("internal error: tried recovery system %q is not %q", triedLabel, label)
+		case outcome != boot.TryRecoverySystemOutcomeSuccess:
+			return fmt.Errorf("cannot promote recovery system %q: system failed to boot", label)
+		}
+	}
+
+	if err := boot.PromoteTriedRecoverySystem(deviceCtx, label); err != nil {
+		return fmt.Errorf("cannot promote recovery system %q: %v", label, err)
+	}
+	logger.Noticef("promoted recovery system %q", label)
+
+	// the new system is in place, failing to remove old ones is not fatal
+	if err := pruneRecoverySystems(st, deviceCtx, label); err != nil {
+		t.Logf("cannot remove old recovery systems: %v", err)
+	}
+	if err := removeUnusedSeedSnaps(); err != nil {
+		t.Logf("cannot remove unused snaps from the seed: %v", err)
+	}
+	return nil
+}
+
+func (m *DeviceManager) undoFinalizeRecoverySystem(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	deviceCtx, err := DeviceCtx(st, t, nil)
+	if err != nil {
+		return err
+	}
+	setup, _, err := taskRecoverySystemSetup(t)
+	if err != nil {
+		return fmt.Errorf("internal error: cannot obtain recovery system setup information: %v", err)
+	}
+	label := setup.Label
+
+	// the system is removed from ubuntu-seed when undoing its creation,
+	// old systems that were pruned cannot be brought back though
+	if err := boot.DropRecoverySystem(deviceCtx, label); err != nil {
+		return fmt.Errorf("cannot drop recovery system %q: %v", label, err)
+	}
+	return nil
+}
+
+// pruneRecoverySystems removes the oldest recovery systems exceeding the
+// retention limit, the system the device was seeded from and the given newly
+// created system are always kept.
+func pruneRecoverySystems(st *state.State, deviceCtx snapstate.DeviceContext, created string) error {
+	modeEnv, err := maybeReadModeenv()
+	if err != nil {
+		return err
+	}
+	if modeEnv == nil {
+		return fmt.Errorf("internal error: modeenv does not exist")
+	}
+	var keep []string
+	var whatseeded []seededSystem
+	if err := st.Get("seeded-systems", &whatseeded); err != nil && err != state.ErrNoState {
+		return err
+	}
+	if len(whatseeded) > 0 {
+		keep = append(keep, whatseeded[0].System)
+	}
+	keep = append(keep, created)
+
+	excess := len(modeEnv.CurrentRecoverySystems) - maxRecoverySystems
+	// recovery systems are listed in the order they were added
+	for _, label := range modeEnv.CurrentRecoverySystems {
+		if excess <= 0 {
+			break
+		}
+		if strutil.ListContains(keep, label) {
+			continue
+		}
+		if err := boot.DropRecoverySystem(deviceCtx, label); err != nil {
+			return fmt.Errorf("cannot drop recovery system %q: %v", label, err)
+		}
+		if err := os.RemoveAll(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", label)); err != nil {
+			return fmt.Errorf("cannot remove recovery system %q: %v", label, err)
+		}
+		logger.Noticef("removed old recovery system %q", label)
+		excess--
+	}
+	return nil
+}
+
+// removeUnusedSeedSnaps removes the snap files from ubuntu-seed that are not
+// used by any of the recovery systems present there. Nothing is removed if
+// any of the systems cannot be loaded.
+func removeUnusedSeedSnaps() error {
+	systems, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "systems", "*"))
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, systemDir := range systems {
+		label := filepath.Base(systemDir)
+		sd, err := seed.Open(boot.InitramfsUbuntuSeedDir, label)
+		if err != nil {
+			return err
+		}
+		if err := sd.LoadAssertions(nil, nil); err != nil {
+			return fmt.Errorf("cannot load assertions of recovery system %q: %v", label, err)
+		}
+		if err := sd.LoadMeta(timings.New(nil)); err != nil {
+			return fmt.Errorf("cannot load recovery system %q: %v", label, err)
+		}
+		for _, sn := range sd.EssentialSnaps() {
+			used[sn.Path] = true
+		}
+		for _, mode := range []string{boot.ModeRun, boot.ModeInstall, boot.ModeRecover} {
+			snaps, err := sd.ModeSnaps(mode)
+			if err != nil {
+				return fmt.Errorf("cannot load recovery system %q: %v", label, err)
+			}
+			for _, sn := range snaps {
+				used[sn.Path] = true
+			}
+		}
+	}
+
+	snapFiles, err := filepath.Glob(filepath.Join(boot.InitramfsUbuntuSeedDir, "snaps", "*.snap"))
+	if err != nil {
+		return err
+	}
+	for _, fn := range snapFiles {
+		if used[fn] {
+			continue
+		}
+		if err := os.Remove(fn); err != nil {
+			return err
+		}
+		logger.Noticef("removed unused snap %q from the seed", filepath.Base(fn))
+	}
+	return nil
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/devicestate"
@@ -538,6 +539,12 @@ func (s *preseedModeSuite) TestDoMarkPreseeded(c *C) {
 	c.Assert(st.Get("preseed-time", &preseededTime), IsNil)
 	c.Check(preseededTime.Equal(now), Equals, true)
 
+	var preseededSnaps []map[string]interface{}
+	c.Assert(st.Get("preseeded-snaps", &preseededSnaps), IsNil)
+	c.Check(preseededSnaps, DeepEquals, []map[string]interface{}{
+		{"name": "test-snap", "revision": "3", "type": "app"},
+	})
+
 	// core snap was "manually" unmounted
 	c.Check(s.cmdUmount.Calls(), DeepEquals, [][]string{
 		{"umount", "-d", "-l", filepath.Join(dirs.SnapMountDir, "test-snap/3")},
@@ -631,3 +638,31 @@ func (s *preseedDoneSuite) TestDoMarkPreseededAfterFirstboot(c *C) {
 	c.Assert(st.Get("seed-restart-time", &seedRestartTime), IsNil)
 	c.Check(seedRestartTime.Equal(devicestate.StartTime()), Equals, true)
 }
+
+func (s *preseedDoneSuite) TestDoMarkPreseededAfterFirstbootPreseededSnapsChanged(c *C) {
+	logbuf, restore := logger.MockLogger()
+	defer restore()
+
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	st.Set("preseeded-snaps", []map[string]interface{}{
+		{"name": "test-snap", "revision": "2", "type": "app"},
+		{"name": "other-snap", "revision": "1", "type": "app"},
+	})
+
+	chg := st.NewChange("firstboot seeding", "...")
+	t := st.NewTask("mark-preseeded", "...")
+	chg.AddTask(t)
+	t.SetStatus(state.DoingStatus)
+
+	st.Unlock()
+	s.se.Ensure()
+	s.se.Wait()
+	st.Lock()
+
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(logbuf.String(), testutil.Contains, `snap "test-snap" was preseeded with revision 2 but revision 3 is current`)
+	c.Check(logbuf.String(), testutil.Contains, `snap "other-snap" was preseeded but is not installed anymore`)
+}
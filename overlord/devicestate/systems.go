@@ -21,11 +21,20 @@ package devicestate
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/boot"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/seed"
+	"github.com/snapcore/snapd/seed/seedwriter"
+	"github.com/snapcore/snapd/snap"
 )
 
 func checkSystemRequestConflict(st *state.State, systemLabel string) error {
@@ -115,8 +124,8 @@ func currentSystemForMode(st *state.State, mode string) (*currentSystem, error)
 	case "run":
 		actions = currentSystemActions
 		system, err = currentSeededSystem(st)
-	case "install":
-		// there is no current system for install mode
+	case "install", "factory-reset":
+		// there is no current system for install or factory-reset mode
 		return nil, nil
 	case "recover":
 		actions = recoverSystemActions
@@ -176,3 +185,210 @@ func seededSystemFromModeenv() (*seededSystem, error) {
 	}
 	return seededSys, nil
 }
+
+// getSnapInfoFunc is expected to return for a given snap name a snap.Info for
+// that snap, a path to the snap file and whether the snap is present.
+type getSnapInfoFunc func(name string) (info *snap.Info, path string, present bool, err error)
+
+// snapWriteObserveFunc is called with the path of a snap file that is about to
+// be written to the seed.
+type snapWriteObserveFunc func(where string) error
+
+// createSystemForModelFromValidatedSnaps creates a new recovery system for the
+// specified model with the specified label using the snaps in the database
+// and the provided snap information. The function returns the directory of the
+// new recovery system.
+//
+// The snaps must have been validated and their assertions must be present in
+// the database db. The observeWrite callback is invoked for each snap file
+// that gets copied into the seed, which allows the caller to keep track of
+// files that need to be removed when cleaning up.
+func createSystemForModelFromValidatedSnaps(model *asserts.Model, label string, db asserts.RODatabase, getInfo getSnapInfoFunc, observeWrite snapWriteObserveFunc) (dir string, err error) {
+	if model.Grade() == asserts.ModelGradeUnset {
+		return "", fmt.Errorf("cannot create a system for non UC20 model")
+	}
+
+	logger.Noticef("creating recovery system with label %q for %q", label, model.Model())
+
+	// TODO: check that all snaps are from the same store and there is no
+	// channel switch required
+	wOpts := &seedwriter.Options{
+		// RW mount of ubuntu-seed
+		SeedDir: boot.InitramfsUbuntuSeedDir,
+		Label:   label,
+	}
+	w, err := seedwriter.New(model, wOpts)
+	if err != nil {
+		return "", err
+	}
+
+	var optsSnaps []*seedwriter.OptionsSnap
+	// snap information and file path keyed by snap name
+	infos := make(map[string]*snap.Info)
+	snapPaths := make(map[string]string)
+
+	getModelSnap := func(modSnap *asserts.ModelSnap) error {
+		name := modSnap.SnapName()
+		if _, ok := infos[name]; ok {
+			return nil
+		}
+		info, snapPath, present, err := getInfo(name)
+		if err != nil {
+			return fmt.Errorf("cannot obtain information of snap %q: %v", name, err)
+		}
+		if !present {
+			if modSnap.Presence != "optional" {
+				return fmt.Errorf("cannot create a recovery system without required snap %q", name)
+			}
+			// not present and not required either
+			return nil
+		}
+		if info.ID() == "" {
+			return fmt.Errorf("cannot create a recovery system with unasserted snap %q", name)
+		}
+		if !osutil.FileExists(snapPath) {
+			return fmt.Errorf("internal error: snap %q not present at %q", name, snapPath)
+		}
+		infos[name] = info
+		snapPaths[name] = snapPath
+		if modSnap.Presence == "optional" {
+			// optional snaps need to be explicitly requested
+			optsSnaps = append(optsSnaps, &seedwriter.OptionsSnap{
+				Name: name,
+			})
+		}
+		return nil
+	}
+
+	// snapd is implicitly required
+	snapdSnap := &asserts.ModelSnap{Name: "snapd", Presence: "required"}
+	for _, modSnap := range append([]*asserts.ModelSnap{snapdSnap}, model.EssentialSnaps()...) {
+		if err := getModelSnap(modSnap); err != nil {
+			return "", err
+		}
+	}
+	for _, modSnap := range model.SnapsWithoutEssential() {
+		if err := getModelSnap(modSnap); err != nil {
+			return "", err
+		}
+	}
+	if err := w.SetOptionsSnaps(optsSnaps); err != nil {
+		return "", err
+	}
+
+	newFetcher := func(save func(asserts.Assertion) error) asserts.Fetcher {
+		// the assertions are already in the database, no need to
+		// retrieve them from anywhere else
+		retrieve := func(ref *asserts.Ref) (asserts.Assertion, error) {
+			return ref.Resolve(db.Find)
+		}
+		return asserts.NewFetcher(db, retrieve, save)
+	}
+	f, err := w.Start(db, newFetcher)
+	if err != nil {
+		return "", err
+	}
+	// past this point the system directory is present
+
+	localSnaps, err := w.LocalSnaps()
+	if err != nil {
+		return "", err
+	}
+	// we do not expect local snaps
+	if len(localSnaps) != 0 {
+		return "", fmt.Errorf("internal error: unexpected local snaps")
+	}
+
+	if err := w.InfoDerived(); err != nil {
+		return "", err
+	}
+
+	for {
+		// get the list of snaps we need in this iteration
+		toDownload, err := w.SnapsToDownload()
+		if err != nil {
+			return "", err
+		}
+		// all snaps are already present on the system, nothing gets
+		// downloaded
+		for _, sn := range toDownload {
+			info, ok := infos[sn.SnapName()]
+			if !ok {
+				return "", fmt.Errorf("internal error: no snap info for %q", sn.SnapName())
+			}
+			if err := w.SetInfo(sn, info); err != nil {
+				return "", err
+			}
+			snapPath := snapPaths[sn.SnapName()]
+			if err := copySnapToSeed(snapPath, sn.Path, observeWrite); err != nil {
+				return "", err
+			}
+
+			sha3_384, _, err := asserts.SnapFileSHA3_384(snapPath)
+			if err != nil {
+				return "", err
+			}
+			prev := len(f.Refs())
+			if err := snapasserts.FetchSnapAssertions(f, sha3_384); err != nil {
+				return "", err
+			}
+			sn.ARefs = f.Refs()[prev:]
+		}
+
+		complete, err := w.Downloaded()
+		if err != nil {
+			return "", err
+		}
+		if complete {
+			break
+		}
+	}
+
+	copySnap := func(name, src, dst string) error {
+		// there are no local snaps
+		return fmt.Errorf("internal error: unexpected local snap %q", name)
+	}
+	if err := w.SeedSnaps(copySnap); err != nil {
+		return "", err
+	}
+	if err := w.WriteMeta(); err != nil {
+		return "", err
+	}
+
+	bootSnaps, err := w.BootSnaps()
+	if err != nil {
+		return "", err
+	}
+	bootWith := &boot.RecoverySystemBootableSet{}
+	for _, sn := range bootSnaps {
+		if sn.Info.Type() == snap.TypeKernel {
+			bootWith.Kernel = sn.Info
+			bootWith.KernelPath = sn.Path
+		}
+	}
+	recoverySystemDir := filepath.Join("/systems", label)
+	if err := boot.MakeRecoverySystemBootable(boot.InitramfsUbuntuSeedDir, recoverySystemDir, bootWith); err != nil {
+		return "", fmt.Errorf("cannot make candidate recovery system %q bootable: %v", label, err)
+	}
+	logger.Noticef("created recovery system %q", label)
+
+	return filepath.Join(boot.InitramfsUbuntuSeedDir, recoverySystemDir), nil
+}
+
+func copySnapToSeed(src, dst string, observeWrite snapWriteObserveFunc) error {
+	if osutil.FileExists(dst) {
+		// snap files in the seed are shared between systems
+		return nil
+	}
+	if err := observeWrite(dst); err != nil {
+		return err
+	}
+	// copy to a temporary location first so that an interrupted copy
+	// does not leave a partial snap file in the seed
+	tmpDst := dst + ".partial"
+	if err := osutil.CopyFile(src, tmpDst, osutil.CopyFlagSync); err != nil {
+		os.Remove(tmpDst)
+		return err
+	}
+	return os.Rename(tmpDst, dst)
+}
@@ -97,3 +97,21 @@ func MockPreseedExitWithError(f func(err error)) (restore func()) {
 		preseedExitWithError = old
 	}
 }
+
+var (
+	WriteStateBackup = writeStateBackup
+	StateBackupPath  = stateBackupPath
+)
+
+// MockStateBackupInterval sets the minimum time between backups of the state.
+func MockStateBackupInterval(d time.Duration) (restore func()) {
+	old := stateBackupInterval
+	stateBackupInterval = d
+	return func() { stateBackupInterval = old }
+}
+
+func MockTimeNow(f func() time.Time) (restore func()) {
+	old := timeNow
+	timeNow = f
+	return func() { timeNow = old }
+}
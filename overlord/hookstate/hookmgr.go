@@ -421,7 +421,16 @@ func (m *HookManager) runHook(context *Context, snapst *snapstate.SnapState, hoo
 }
 
 func runHookImpl(c *Context, tomb *tomb.Tomb) ([]byte, error) {
-	return runHookAndWait(c.InstanceName(), c.SnapRevision(), c.HookName(), c.ID(), c.Timeout(), tomb)
+	var extraEnv []string
+	switch c.HookName() {
+	case "pre-refresh", "post-refresh":
+		env, err := refreshHookEnv(c)
+		if err != nil {
+			logger.Noticef("cannot determine the epochs crossed by the refresh of snap %q: %v", c.InstanceName(), err)
+		}
+		extraEnv = env
+	}
+	return runHookAndWait(c.InstanceName(), c.SnapRevision(), c.HookName(), c.ID(), c.Timeout(), extraEnv, tomb)
 }
 
 var runHook = runHookImpl
@@ -460,7 +469,7 @@ func snapCmd() string {
 
 var defaultHookTimeout = 10 * time.Minute
 
-func runHookAndWait(snapName string, revision snap.Revision, hookName, hookContext string, timeout time.Duration, tomb *tomb.Tomb) ([]byte, error) {
+func runHookAndWait(snapName string, revision snap.Revision, hookName, hookContext string, timeout time.Duration, extraEnv []string, tomb *tomb.Tomb) ([]byte, error) {
 	argv := []string{snapCmd(), "run", "--hook", hookName, "-r", revision.String(), snapName}
 	if timeout == 0 {
 		timeout = defaultHookTimeout
@@ -475,6 +484,7 @@ func runHookAndWait(snapName string, revision snap.Revision, hookName, hookConte
 		// hook would fail during transition.
 		fmt.Sprintf("SNAP_CONTEXT=%s", hookContext),
 	}
+	env = append(env, extraEnv...)
 
 	return osutil.RunAndWait(argv, env, timeout, tomb)
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 func init() {
@@ -70,6 +71,80 @@ func SetupPreRefreshHook(st *state.State, snapName string) *state.Task {
 	return task
 }
 
+// refreshEpochs returns the epochs of the revisions the snap is
+// refreshed from and to in the change of the given pre-refresh or
+// post-refresh hook task.
+func refreshEpochs(task *state.Task, snapName string) (from, to snap.Epoch, err error) {
+	st := task.State()
+	chg := task.Change()
+	if chg == nil {
+		return from, to, fmt.Errorf("internal error: hook task %s is not part of a change", task.ID())
+	}
+	var snapsup *snapstate.SnapSetup
+	var linkTask *state.Task
+	for _, t := range chg.Tasks() {
+		sup, err := snapstate.TaskSnapSetup(t)
+		if err != nil || sup.InstanceName() != snapName {
+			continue
+		}
+		snapsup = sup
+		if t.Kind() == "link-snap" {
+			linkTask = t
+		}
+	}
+	if snapsup == nil {
+		return from, to, fmt.Errorf("internal error: cannot find the refresh of snap %q in change %s", snapName, chg.ID())
+	}
+
+	curInfo, err := snapstate.CurrentInfo(st, snapName)
+	if err != nil {
+		return from, to, err
+	}
+	if curInfo.Revision != snapsup.Revision() {
+		// pre-refresh, the new revision is mounted but not
+		// current yet
+		newInfo, err := snap.ReadInfo(snapName, snapsup.SideInfo)
+		if err != nil {
+			return from, to, err
+		}
+		return curInfo.Epoch, newInfo.Epoch, nil
+	}
+
+	// post-refresh, the new revision is current already
+	if linkTask == nil {
+		return from, to, fmt.Errorf("internal error: cannot find the link of snap %q in change %s", snapName, chg.ID())
+	}
+	var oldRev snap.Revision
+	if err := linkTask.Get("old-current", &oldRev); err != nil {
+		return from, to, err
+	}
+	oldInfo, err := snapstate.Info(st, snapName, oldRev)
+	if err != nil {
+		return from, to, err
+	}
+	return oldInfo.Epoch, curInfo.Epoch, nil
+}
+
+// refreshHookEnv returns the environment of pre-refresh and
+// post-refresh hooks that tells them about the epochs crossed by the
+// refresh, so that they can migrate the data of the snap accordingly.
+func refreshHookEnv(c *Context) ([]string, error) {
+	task, ok := c.Task()
+	if !ok {
+		return nil, nil
+	}
+	c.Lock()
+	defer c.Unlock()
+	from, to, err := refreshEpochs(task, c.InstanceName())
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		fmt.Sprintf("SNAP_REFRESH_FROM_EPOCH=%s", from),
+		fmt.Sprintf("SNAP_REFRESH_TO_EPOCH=%s", to),
+	}, nil
+}
+
 type snapHookHandler struct {
 }
 
@@ -576,6 +576,75 @@ func (s *hookManagerSuite) TestHookTaskCorrectlyIncludesContext(c *C) {
 	checkTaskLogContains(c, s.task, `.*SNAP_COOKIE=\S+`)
 }
 
+func (s *hookManagerSuite) mockRefreshHookTask(c *C, hook string) *state.Task {
+	const snapYamlEpoch = `
+name: test-snap
+version: 2.0
+epoch: 1*
+hooks:
+    pre-refresh:
+    post-refresh:
+`
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	si2 := &snap.SideInfo{RealName: "test-snap", SnapID: "some-snap-id", Revision: snap.R(2)}
+	snaptest.MockSnap(c, snapYamlEpoch, si2)
+	snapsup := &snapstate.SnapSetup{SideInfo: si2}
+
+	chg := s.state.NewChange("refresh-snap", "...")
+	mount := s.state.NewTask("mount-snap", "...")
+	mount.Set("snap-setup", snapsup)
+	chg.AddTask(mount)
+	link := s.state.NewTask("link-snap", "...")
+	link.Set("snap-setup-task", mount.ID())
+	link.Set("old-current", snap.R(1))
+	chg.AddTask(link)
+
+	hooksup := &hookstate.HookSetup{
+		Snap: "test-snap",
+		Hook: hook,
+	}
+	task := hookstate.HookTask(s.state, "refresh hook", hooksup, nil)
+	chg.AddTask(task)
+	// only the hook is run here
+	mount.SetStatus(state.DoneStatus)
+	link.SetStatus(state.DoneStatus)
+
+	if hook == "post-refresh" {
+		var snapst snapstate.SnapState
+		c.Assert(snapstate.Get(s.state, "test-snap", &snapst), IsNil)
+		snapst.Sequence = append(snapst.Sequence, si2)
+		snapst.Current = si2.Revision
+		snapstate.Set(s.state, "test-snap", &snapst)
+	}
+	// the previous revision is at epoch 0 and has the hooks too
+	snaptest.MockSnap(c, "name: test-snap\nversion: 1.0\nhooks:\n  pre-refresh:\n  post-refresh:\n", &snap.SideInfo{RealName: "test-snap", SnapID: "some-snap-id", Revision: snap.R(1)})
+
+	// the configure hook task of the suite is not part of this
+	s.change.SetStatus(state.DoneStatus)
+	s.task.SetStatus(state.DoneStatus)
+	return task
+}
+
+func (s *hookManagerSuite) TestRefreshHooksIncludeEpochs(c *C) {
+	cmd := testutil.MockCommand(
+		c, "snap", ">&2 echo \"FROM=$SNAP_REFRESH_FROM_EPOCH TO=$SNAP_REFRESH_TO_EPOCH\"; exit 1")
+	defer cmd.Restore()
+
+	for _, hook := range []string{"pre-refresh", "post-refresh"} {
+		task := s.mockRefreshHookTask(c, hook)
+
+		s.se.Ensure()
+		s.se.Wait()
+
+		s.state.Lock()
+		c.Check(task.Status(), Equals, state.ErrorStatus, Commentf(hook))
+		checkTaskLogContains(c, task, `.*FROM=0 TO=1\*`)
+		s.state.Unlock()
+	}
+}
+
 func (s *hookManagerSuite) TestHookTaskHandlerBeforeError(c *C) {
 	s.mockHandler.BeforeError = true
 
@@ -67,7 +67,10 @@ func (m *InterfaceManager) addInterfaces(extra []interfaces.Interface) error {
 }
 
 func (m *InterfaceManager) addBackends(extra []interfaces.SecurityBackend) error {
-	opts := interfaces.SecurityBackendOptions{Preseed: m.preseed}
+	opts := interfaces.SecurityBackendOptions{
+		Preseed:   m.preseed,
+		Prompting: m.promptingEnabled,
+	}
 	for _, backend := range backends.All {
 		if err := backend.Initialize(&opts); err != nil {
 			return err
@@ -757,6 +760,13 @@ func (c *autoConnectChecker) addAutoConnections(newconns map[string]*interfaces.
 
 		if filter != nil {
 			applicable = filter(applicable)
+			if len(applicable) == 0 {
+				// a slot whose rule allows the plug to be
+				// connected to any number of slots
+				// (slots-per-plug: *) connects greedily even
+				// when the other candidates are ambiguous
+				applicable = filter(anySlotsPerPlugSlots(candSlots, arities))
+			}
 		}
 
 		if len(applicable) == 0 {
@@ -778,6 +788,18 @@ func (c *autoConnectChecker) addAutoConnections(newconns map[string]*interfaces.
 	return nil
 }
 
+// anySlotsPerPlugSlots returns the candidate slots whose arity allows
+// the plug to be connected to any number of slots.
+func anySlotsPerPlugSlots(candSlots []*snap.SlotInfo, arities []interfaces.SideArity) []*snap.SlotInfo {
+	var res []*snap.SlotInfo
+	for i, candSlot := range candSlots {
+		if arities[i].SlotsPerPlugAny() {
+			res = append(res, candSlot)
+		}
+	}
+	return res
+}
+
 type connectChecker struct {
 	st        *state.State
 	deviceCtx snapstate.DeviceContext
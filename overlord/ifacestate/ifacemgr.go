@@ -26,7 +26,9 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/backends"
+	"github.com/snapcore/snapd/interfaces/prompting"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
 	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
@@ -63,6 +65,11 @@ type InterfaceManager struct {
 	extraInterfaces []interfaces.Interface
 	extraBackends   []interfaces.SecurityBackend
 
+	// prompts awaiting a reply from the user
+	promptDB *prompting.PromptDB
+	// whether prompting was enabled and supported at startup
+	promptingEnabled bool
+
 	preseed bool
 }
 
@@ -86,6 +93,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, runner *state.T
 		// extras
 		extraInterfaces: extraInterfaces,
 		extraBackends:   extraBackends,
+		promptDB:        prompting.NewPromptDB(),
 		preseed:         snapdenv.Preseeding(),
 	}
 
@@ -151,6 +159,10 @@ func (m *InterfaceManager) StartUp() error {
 	// on state, in memory and in API responses, respectively.
 	m.selectInterfaceMapper(snaps)
 
+	// changes to the apparmor-prompting feature take effect when snapd
+	// is restarted, as they require regenerating the security profiles
+	m.promptingEnabled = prompting.PromptingEnabled(config.NewTransaction(s))
+
 	if err := m.addInterfaces(m.extraInterfaces); err != nil {
 		return err
 	}
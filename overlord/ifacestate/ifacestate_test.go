@@ -8138,6 +8138,115 @@ func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectsDeclBasedAnyS
 	s.testDoSetupSnapSecurityAutoConnectsDeclBasedAnySlotsPerPlug(c, check)
 }
 
+func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectsDeclBasedAnySlotsPerPlugGreedySlot(c *C) {
+	s.MockModel(c, nil)
+
+	// the greedy producer snap
+	s.MockSnapDecl(c, "theme1", "one-publisher", map[string]interface{}{
+		"format": "1",
+		"slots": map[string]interface{}{
+			"content": map[string]interface{}{
+				"allow-auto-connection": map[string]interface{}{
+					"slots-per-plug": "*",
+				},
+			},
+		},
+	})
+
+	// 2nd producer snap
+	s.MockSnapDecl(c, "theme2", "one-publisher", map[string]interface{}{
+		"format": "1",
+		"slots": map[string]interface{}{
+			"content": map[string]interface{}{
+				"allow-auto-connection": map[string]interface{}{
+					"slots-per-plug": "1",
+				},
+			},
+		},
+	})
+
+	// the consumers
+	s.MockSnapDecl(c, "theme-consumer", "one-publisher", nil)
+	s.MockSnapDecl(c, "theme-consumer2", "one-publisher", nil)
+
+	const theme2Yaml = `
+name: theme2
+version: 1
+slots:
+  slot:
+    interface: content
+    content: themes
+`
+	s.mockSnap(c, theme2Yaml)
+	const themeConsumerYaml = `
+name: theme-consumer
+version: 1
+plugs:
+  plug:
+    interface: content
+    content: themes
+`
+	s.mockSnap(c, themeConsumerYaml)
+	const themeConsumer2Yaml = `
+name: theme-consumer2
+version: 1
+plugs:
+  plug:
+    interface: content
+    content: themes
+`
+	s.mockSnap(c, themeConsumer2Yaml)
+
+	mgr := s.manager(c)
+
+	const theme1Yaml = `
+name: theme1
+version: 1
+slots:
+  slot:
+    interface: content
+    content: themes
+`
+	snapInfo := s.mockSnap(c, theme1Yaml)
+
+	// Run the setup-snap-security task and let it finish.
+	change := s.addSetupSnapSecurityChange(c, &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: snapInfo.SnapName(),
+			SnapID:   snapInfo.SnapID,
+			Revision: snapInfo.Revision,
+		},
+	})
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// Ensure that the task succeeded.
+	c.Assert(change.Status(), Equals, state.DoneStatus)
+
+	var conns map[string]interface{}
+	_ = s.state.Get("conns", &conns)
+
+	// the greedy slot got connected to all the plugs even if the
+	// candidates for them are ambiguous because of theme2
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"theme-consumer:plug theme1:slot": map[string]interface{}{
+			"auto":        true,
+			"interface":   "content",
+			"plug-static": map[string]interface{}{"content": "themes"},
+			"slot-static": map[string]interface{}{"content": "themes"},
+		},
+		"theme-consumer2:plug theme1:slot": map[string]interface{}{
+			"auto":        true,
+			"interface":   "content",
+			"plug-static": map[string]interface{}{"content": "themes"},
+			"slot-static": map[string]interface{}{"content": "themes"},
+		},
+	})
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 2)
+}
+
 func (s *interfaceManagerSuite) TestDoSetupSnapSecurityAutoConnectsDeclBasedSlotNames(c *C) {
 	s.MockModel(c, nil)
 
@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+// promptingRules is the state of the prompting rules recorded from the
+// decisions of the users, by snap.
+type promptingRules struct {
+	LastID uint64                       `json:"last-id"`
+	Snaps  map[string][]*prompting.Rule `json:"snaps,omitempty"`
+}
+
+func getPromptingRules(st *state.State) (*promptingRules, error) {
+	var rules promptingRules
+	if err := st.Get("prompting-rules", &rules); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if rules.Snaps == nil {
+		rules.Snaps = make(map[string][]*prompting.Rule)
+	}
+	return &rules, nil
+}
+
+// ErrPromptingRuleNotFound is returned when a prompting rule does not exist
+// or does not belong to the given user.
+var ErrPromptingRuleNotFound = fmt.Errorf("cannot find prompting rule")
+
+// PromptingRules returns the prompting rules of the given user for the
+// given snap, or for all snaps if the snap name is empty, oldest first.
+func PromptingRules(st *state.State, user uint32, snapName string) ([]*prompting.Rule, error) {
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return nil, err
+	}
+	var result []*prompting.Rule
+	for name, snapRules := range rules.Snaps {
+		if snapName != "" && name != snapName {
+			continue
+		}
+		for _, rule := range snapRules {
+			if rule.User == user {
+				result = append(result, rule)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result, nil
+}
+
+// AddPromptingRule validates and records the given prompting rule. The ID
+// and timestamp of the rule are filled in.
+func AddPromptingRule(st *state.State, rule *prompting.Rule) error {
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("cannot add prompting rule: %v", err)
+	}
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return err
+	}
+	rules.LastID++
+	rule.ID = strconv.FormatUint(rules.LastID, 16)
+	rule.Timestamp = time.Now()
+	rules.Snaps[rule.Snap] = append(rules.Snaps[rule.Snap], rule)
+	st.Set("prompting-rules", rules)
+	return nil
+}
+
+// RemovePromptingRule removes the prompting rule of the given user with
+// the given ID and returns it.
+func RemovePromptingRule(st *state.State, user uint32, id string) (*prompting.Rule, error) {
+	rules, err := getPromptingRules(st)
+	if err != nil {
+		return nil, err
+	}
+	for name, snapRules := range rules.Snaps {
+		for i, rule := range snapRules {
+			if rule.ID != id || rule.User != user {
+				continue
+			}
+			snapRules = append(snapRules[:i], snapRules[i+1:]...)
+			if len(snapRules) == 0 {
+				delete(rules.Snaps, name)
+			} else {
+				rules.Snaps[name] = snapRules
+			}
+			st.Set("prompting-rules", rules)
+			return rule, nil
+		}
+	}
+	return nil, ErrPromptingRuleNotFound
+}
+
+// PromptingEnabled returns whether AppArmor prompting was enabled and
+// supported when snapd started.
+func (m *InterfaceManager) PromptingEnabled() bool {
+	return m.promptingEnabled
+}
+
+// PromptDB returns the database of the prompts awaiting a reply from the
+// users.
+func (m *InterfaceManager) PromptDB() *prompting.PromptDB {
+	return m.promptDB
+}
+
+// RequestPrompt decides the given access request using the prompting
+// rules of the user for the snap if one matches, in which case no prompt
+// is returned. The most recent matching rule takes precedence. Otherwise
+// a prompt is added for the user to reply to.
+func (m *InterfaceManager) RequestPrompt(req *prompting.Prompt) (*prompting.Prompt, prompting.OutcomeType, error) {
+	m.state.Lock()
+	rules, err := PromptingRules(m.state, req.User, req.Snap)
+	m.state.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].Matches(req) {
+			return nil, rules[i].Outcome, nil
+		}
+	}
+	prompt, err := m.promptDB.Add(req)
+	if err != nil {
+		return nil, "", err
+	}
+	return prompt, "", nil
+}
@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/prompting"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type promptingSuite struct {
+	st *state.State
+}
+
+var _ = Suite(&promptingSuite{})
+
+func (s *promptingSuite) SetUpTest(c *C) {
+	s.st = state.New(nil)
+}
+
+func mockRule(user uint32, snapName, pattern string, outcome prompting.OutcomeType) *prompting.Rule {
+	return &prompting.Rule{
+		User:        user,
+		Snap:        snapName,
+		Interface:   "home",
+		PathPattern: pattern,
+		Permissions: []string{"read"},
+		Outcome:     outcome,
+	}
+}
+
+func (s *promptingSuite) TestAddPromptingRules(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	r1 := mockRule(1000, "foo", "/home/test/**", prompting.OutcomeAllow)
+	c.Assert(ifacestate.AddPromptingRule(s.st, r1), IsNil)
+	c.Check(r1.ID, Equals, "1")
+	c.Check(r1.Timestamp.IsZero(), Equals, false)
+	r2 := mockRule(1000, "bar", "/home/test/foo.txt", prompting.OutcomeDeny)
+	c.Assert(ifacestate.AddPromptingRule(s.st, r2), IsNil)
+	c.Check(r2.ID, Equals, "2")
+	r3 := mockRule(1001, "foo", "/home/other/**", prompting.OutcomeAllow)
+	c.Assert(ifacestate.AddPromptingRule(s.st, r3), IsNil)
+
+	rules, err := ifacestate.PromptingRules(s.st, 1000, "")
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 2)
+	c.Check(rules[0].ID, Equals, r1.ID)
+	c.Check(rules[1].ID, Equals, r2.ID)
+
+	rules, err = ifacestate.PromptingRules(s.st, 1000, "bar")
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 1)
+	c.Check(rules[0].PathPattern, Equals, "/home/test/foo.txt")
+
+	rules, err = ifacestate.PromptingRules(s.st, 1001, "")
+	c.Assert(err, IsNil)
+	c.Assert(rules, HasLen, 1)
+	c.Check(rules[0].ID, Equals, r3.ID)
+}
+
+func (s *promptingSuite) TestAddInvalidPromptingRule(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	err := ifacestate.AddPromptingRule(s.st, mockRule(1000, "foo", "home/test", prompting.OutcomeAllow))
+	c.Check(err, ErrorMatches, `cannot add prompting rule: invalid path pattern "home/test": .*`)
+
+	rules, err := ifacestate.PromptingRules(s.st, 1000, "")
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+}
+
+func (s *promptingSuite) TestRemovePromptingRule(c *C) {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	r1 := mockRule(1000, "foo", "/home/test/**", prompting.OutcomeAllow)
+	c.Assert(ifacestate.AddPromptingRule(s.st, r1), IsNil)
+
+	// rules of other users cannot be removed
+	_, err := ifacestate.RemovePromptingRule(s.st, 1001, r1.ID)
+	c.Check(err, Equals, ifacestate.ErrPromptingRuleNotFound)
+
+	removed, err := ifacestate.RemovePromptingRule(s.st, 1000, r1.ID)
+	c.Assert(err, IsNil)
+	c.Check(removed.ID, Equals, r1.ID)
+
+	rules, err := ifacestate.PromptingRules(s.st, 1000, "")
+	c.Assert(err, IsNil)
+	c.Check(rules, HasLen, 0)
+
+	_, err = ifacestate.RemovePromptingRule(s.st, 1000, r1.ID)
+	c.Check(err, Equals, ifacestate.ErrPromptingRuleNotFound)
+}
+
+func (s *interfaceManagerSuite) TestRequestPrompt(c *C) {
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	c.Assert(ifacestate.AddPromptingRule(s.state, mockRule(1000, "foo", "/home/test/**", prompting.OutcomeAllow)), IsNil)
+	c.Assert(ifacestate.AddPromptingRule(s.state, mockRule(1000, "foo", "/home/test/secret/**", prompting.OutcomeDeny)), IsNil)
+	s.state.Unlock()
+
+	req := &prompting.Prompt{User: 1000, Snap: "foo", Interface: "home", Path: "/home/test/foo.txt", Permissions: []string{"read"}}
+	prompt, outcome, err := mgr.RequestPrompt(req)
+	c.Assert(err, IsNil)
+	c.Check(prompt, IsNil)
+	c.Check(outcome, Equals, prompting.OutcomeAllow)
+
+	// the most recent rule takes precedence
+	req.Path = "/home/test/secret/foo.txt"
+	prompt, outcome, err = mgr.RequestPrompt(req)
+	c.Assert(err, IsNil)
+	c.Check(prompt, IsNil)
+	c.Check(outcome, Equals, prompting.OutcomeDeny)
+
+	// no rule matches, the user is prompted
+	req.Permissions = []string{"write"}
+	prompt, outcome, err = mgr.RequestPrompt(req)
+	c.Assert(err, IsNil)
+	c.Check(outcome, Equals, prompting.OutcomeType(""))
+	c.Assert(prompt, NotNil)
+	c.Check(mgr.PromptDB().Prompts(1000), DeepEquals, []*prompting.Prompt{prompt})
+}
@@ -1279,7 +1279,7 @@ func (s *mgrsSuite) TestHappyRemoteInstallAndUpdateManyWithEpochBump(c *C) {
 	st.Lock()
 	defer st.Unlock()
 
-	affected, tasksets, err := snapstate.InstallMany(st, snapNames, 0)
+	affected, tasksets, err := snapstate.InstallMany(st, snapNames, 0, nil)
 	c.Assert(err, IsNil)
 	sort.Strings(affected)
 	c.Check(affected, DeepEquals, snapNames)
@@ -1360,7 +1360,7 @@ func (s *mgrsSuite) TestHappyRemoteInstallAndUpdateManyWithEpochBumpAndOneFailin
 	st.Lock()
 	defer st.Unlock()
 
-	affected, tasksets, err := snapstate.InstallMany(st, snapNames, 0)
+	affected, tasksets, err := snapstate.InstallMany(st, snapNames, 0, nil)
 	c.Assert(err, IsNil)
 	sort.Strings(affected)
 	c.Check(affected, DeepEquals, snapNames)
@@ -4061,7 +4061,7 @@ func (s *mgrsSuite) TestRemodelRequiredSnapsAdded(c *C) {
 		"revision":       "1",
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	c.Check(devicestate.Remodeling(st), Equals, true)
@@ -4163,7 +4163,7 @@ func (s *mgrsSuite) TestRemodelRequiredSnapsAddedUndo(c *C) {
 	devicestate.InjectSetModelError(fmt.Errorf("boom"))
 	defer devicestate.InjectSetModelError(nil)
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4232,7 +4232,7 @@ type: base`
 		"revision": "1",
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, ErrorMatches, "cannot remodel from core to bases yet")
 	c.Assert(chg, IsNil)
 }
@@ -4320,7 +4320,7 @@ version: 20.04`
 		"required-snaps": []interface{}{"foo"},
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4468,7 +4468,7 @@ version: 20.04`
 	devicestate.InjectSetModelError(fmt.Errorf("boom"))
 	defer devicestate.InjectSetModelError(nil)
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4608,7 +4608,7 @@ version: 20.04`
 		"required-snaps": []interface{}{"foo"},
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4761,7 +4761,7 @@ func (s *kernelSuite) TestRemodelSwitchKernelTrack(c *C) {
 		"required-snaps": []interface{}{"foo"},
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4815,7 +4815,7 @@ func (ms *kernelSuite) TestRemodelSwitchToDifferentKernel(c *C) {
 		"required-snaps": []interface{}{"foo"},
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4907,7 +4907,7 @@ func (ms *kernelSuite) TestRemodelSwitchToDifferentKernelUndo(c *C) {
 	devicestate.InjectSetModelError(fmt.Errorf("boom"))
 	defer devicestate.InjectSetModelError(nil)
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -4963,7 +4963,7 @@ func (ms *kernelSuite) TestRemodelSwitchToDifferentKernelUndoOnRollback(c *C) {
 	devicestate.InjectSetModelError(fmt.Errorf("boom"))
 	defer devicestate.InjectSetModelError(nil)
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -5079,7 +5079,7 @@ func (s *mgrsSuite) TestRemodelStoreSwitch(c *C) {
 	s.expectedStore = "switched-store"
 	s.sessionMacaroon = "switched-store-session"
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -5161,7 +5161,7 @@ volumes:
 		"revision": "1",
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -5293,7 +5293,7 @@ volumes:
 		"revision": "1",
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -5419,7 +5419,7 @@ volumes:
 		"revision": "1",
 	})
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -5640,7 +5640,7 @@ func (s *mgrsSuite) TestRemodelReregistration(c *C) {
 	s.expectedStore = "my-brand-substore"
 	s.sessionMacaroon = "other-store-session"
 
-	chg, err := devicestate.Remodel(st, newModel)
+	chg, err := devicestate.Remodel(st, newModel, nil, nil)
 	c.Assert(err, IsNil)
 
 	st.Unlock()
@@ -248,18 +248,16 @@ func loadState(backend state.Backend, restartBehavior RestartBehavior) (*state.S
 		return s, nil
 	}
 
-	r, err := os.Open(dirs.SnapStateFile)
-	if err != nil {
-		return nil, fmt.Errorf("cannot read the state file: %s", err)
-	}
-	defer r.Close()
-
 	var s *state.State
 	timings.Run(perfTimings, "read-state", "read snapd state from disk", func(tm timings.Measurer) {
-		s, err = state.ReadState(backend, r)
+		s, err = readState(backend, dirs.SnapStateFile)
 	})
 	if err != nil {
-		return nil, err
+		// fall back to the last good backup of the state
+		s, err = recoverState(backend, dirs.SnapStateFile, err)
+		if err != nil {
+			return nil, err
+		}
 	}
 	s.Lock()
 	perfTimings.Save(s)
@@ -278,6 +276,16 @@ func loadState(backend state.Backend, restartBehavior RestartBehavior) (*state.S
 	return s, nil
 }
 
+func readState(backend state.Backend, statePath string) (*state.State, error) {
+	r, err := os.Open(statePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the state file: %s", err)
+	}
+	defer r.Close()
+
+	return state.ReadState(backend, r)
+}
+
 func verifyReboot(s *state.State, curBootID string, restartBehavior RestartBehavior) error {
 	s.Lock()
 	defer s.Unlock()
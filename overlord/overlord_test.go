@@ -212,6 +212,106 @@ func (ovs *overlordSuite) TestNewWithInvalidState(c *C) {
 	c.Assert(err, ErrorMatches, "cannot read state: EOF")
 }
 
+func (ovs *overlordSuite) TestNewWithInvalidStateRecoversFromBackup(c *C) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	restore := overlord.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	err := ioutil.WriteFile(dirs.SnapStateFile, []byte(`{"data":{"pa`), 0600)
+	c.Assert(err, IsNil)
+	goodState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"some":"data","refresh-privacy-key":"0123456789ABCDEF"},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, snapdtool.Version))
+	err = overlord.WriteStateBackup(overlord.StateBackupPath(dirs.SnapStateFile), goodState)
+	c.Assert(err, IsNil)
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+	var some string
+	c.Assert(st.Get("some", &some), IsNil)
+	c.Check(some, Equals, "data")
+	c.Check(st.AllWarnings(), HasLen, 1)
+
+	// the recovered state is in place and the broken one was kept
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"some":"data"`)
+	c.Check(fmt.Sprintf("%s.corrupt.%d", dirs.SnapStateFile, now.Unix()), testutil.FileEquals, `{"data":{"pa`)
+}
+
+func (ovs *overlordSuite) TestNewWithInvalidStateRecoveredReconciled(c *C) {
+	err := ioutil.WriteFile(dirs.SnapStateFile, nil, 0600)
+	c.Assert(err, IsNil)
+	goodState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d,"patch-sublevel":%d,"patch-sublevel-last-version":%q,"refresh-privacy-key":"0123456789ABCDEF","snaps":{
+"gone":{"type":"app","sequence":[{"name":"gone","revision":"1"}],"current":"1","active":true},
+"old":{"type":"app","sequence":[{"name":"old","snap-id":"old-id","revision":"1"}],"current":"1","active":true},
+"reverted":{"type":"app","sequence":[{"name":"reverted","revision":"1"},{"name":"reverted","revision":"2"}],"current":"2","active":true},
+"disabled":{"type":"app","sequence":[{"name":"disabled","revision":"1"}],"current":"1","active":true},
+"enabled":{"type":"app","sequence":[{"name":"enabled","revision":"1"}],"current":"1"},
+"same":{"type":"app","sequence":[{"name":"same","revision":"3"}],"current":"3","active":true}
+}},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level, patch.Sublevel, snapdtool.Version))
+	err = overlord.WriteStateBackup(overlord.StateBackupPath(dirs.SnapStateFile), goodState)
+	c.Assert(err, IsNil)
+
+	for name, rev := range map[string]string{"old": "2", "reverted": "1", "enabled": "1", "same": "3", "new": "1"} {
+		d := filepath.Join(dirs.SnapMountDir, name)
+		c.Assert(os.MkdirAll(filepath.Join(d, rev), 0755), IsNil)
+		c.Assert(os.Symlink(rev, filepath.Join(d, "current")), IsNil)
+	}
+	// installed but not linked
+	c.Assert(os.MkdirAll(filepath.Join(dirs.SnapMountDir, "disabled", "1"), 0755), IsNil)
+	// not a snap
+	c.Assert(os.MkdirAll(filepath.Join(dirs.SnapMountDir, "bin"), 0755), IsNil)
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+
+	st := o.State()
+	st.Lock()
+	defer st.Unlock()
+	var msgs []string
+	for _, w := range st.AllWarnings() {
+		msgs = append(msgs, w.String())
+	}
+	c.Check(msgs, DeepEquals, []string{
+		"the state of snapd could not be read and was restored from a backup, recent changes to the system may have been lost",
+		`snap "disabled" is not active on the system, it was marked as disabled`,
+		`snap "enabled" is active on the system, it was marked as enabled`,
+		`snap "gone" is tracked by the recovered state but is not installed, it was removed from the state`,
+		`snap "old" is installed at revision 2, the recovered state was updated from revision 1`,
+		`snap "reverted" is installed at revision 1, the recovered state was updated from revision 2`,
+		`snap "new" is installed but is not tracked by the recovered state, it needs to be installed again`,
+	})
+
+	all, err := snapstate.All(st)
+	c.Assert(err, IsNil)
+	c.Check(all, HasLen, 5)
+	c.Check(all["gone"], IsNil)
+	c.Check(all["disabled"].Active, Equals, false)
+	c.Check(all["enabled"].Active, Equals, true)
+	c.Check(all["old"].Current, Equals, snap.R(2))
+	c.Check(all["old"].Active, Equals, true)
+	c.Check(all["old"].Sequence, DeepEquals, []*snap.SideInfo{
+		{RealName: "old", SnapID: "old-id", Revision: snap.R(1)},
+		{RealName: "old", SnapID: "old-id", Revision: snap.R(2)},
+	})
+	c.Check(all["reverted"].Current, Equals, snap.R(1))
+	c.Check(all["reverted"].Sequence, HasLen, 2)
+	c.Check(all["same"].Current, Equals, snap.R(3))
+}
+
+func (ovs *overlordSuite) TestNewWithInvalidStateCorruptedBackup(c *C) {
+	err := ioutil.WriteFile(dirs.SnapStateFile, nil, 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(overlord.StateBackupPath(dirs.SnapStateFile), []byte("1234\n{}"), 0600)
+	c.Assert(err, IsNil)
+
+	_, err = overlord.New(nil)
+	c.Assert(err, ErrorMatches, `cannot read state: EOF, and cannot recover from backup: state backup ".*" is corrupted: digest mismatch`)
+	// nothing was touched
+	c.Check(dirs.SnapStateFile, testutil.FileEquals, "")
+}
+
 func (ovs *overlordSuite) TestNewWithPatches(c *C) {
 	p := func(s *state.State) error {
 		s.Set("patched", true)
@@ -855,6 +955,90 @@ func (ovs *overlordSuite) TestCheckpoint(c *C) {
 	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":1`)
 }
 
+func (ovs *overlordSuite) TestCheckpointBacksUpState(c *C) {
+	now := time.Now()
+	restore := overlord.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	restore = overlord.MockStateBackupInterval(time.Minute)
+	defer restore()
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	backupPath := overlord.StateBackupPath(dirs.SnapStateFile)
+	// the state was backed up when it was first written
+	c.Check(backupPath, testutil.FilePresent)
+
+	now = now.Add(time.Minute)
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 1)
+	s.Unlock()
+
+	st, err := os.Stat(backupPath)
+	c.Assert(err, IsNil)
+	c.Check(st.Mode(), Equals, os.FileMode(0600))
+	c.Check(backupPath, testutil.FileMatches, "(?s)[0-9a-f]{96}\n.*\"mark\":1.*")
+
+	// no new backup within the interval
+	now = now.Add(30 * time.Second)
+	s.Lock()
+	s.Set("mark", 2)
+	s.Unlock()
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":2`)
+	c.Check(backupPath, testutil.FileContains, `"mark":1`)
+
+	now = now.Add(time.Minute)
+	s.Lock()
+	s.Set("mark", 3)
+	s.Unlock()
+	c.Check(backupPath, testutil.FileContains, `"mark":3`)
+}
+
+func (ovs *overlordSuite) TestCheckpointBackupRotationRetriedOnError(c *C) {
+	now := time.Now()
+	restore := overlord.MockTimeNow(func() time.Time { return now })
+	defer restore()
+	restore = overlord.MockStateBackupInterval(time.Minute)
+	defer restore()
+
+	o, err := overlord.New(nil)
+	c.Assert(err, IsNil)
+	backupPath := overlord.StateBackupPath(dirs.SnapStateFile)
+	c.Check(backupPath, testutil.FilePresent)
+
+	// the backup cannot be replaced
+	c.Assert(os.Remove(backupPath), IsNil)
+	c.Assert(os.Mkdir(backupPath, 0755), IsNil)
+	now = now.Add(time.Minute)
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 1)
+	s.Unlock()
+	// the state itself was written nonetheless
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":1`)
+
+	// the backup is attempted again on the next checkpoint even within
+	// the interval
+	c.Assert(os.Remove(backupPath), IsNil)
+	now = now.Add(time.Second)
+	s.Lock()
+	s.Set("mark", 2)
+	s.Unlock()
+	c.Check(backupPath, testutil.FileContains, `"mark":2`)
+
+	// and the previous good backup is kept until the interval passed
+	now = now.Add(30 * time.Second)
+	s.Lock()
+	s.Set("mark", 3)
+	s.Unlock()
+	c.Check(backupPath, testutil.FileContains, `"mark":2`)
+	now = now.Add(30 * time.Second)
+	s.Lock()
+	s.Set("mark", 4)
+	s.Unlock()
+	c.Check(backupPath, testutil.FileContains, `"mark":4`)
+}
+
 type sampleManager struct {
 	ensureCallback func()
 }
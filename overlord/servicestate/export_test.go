@@ -19,6 +19,18 @@
 
 package servicestate
 
+import (
+	userclient "github.com/snapcore/snapd/usersession/client"
+)
+
 var (
 	UpdateSnapstateServices = updateSnapstateServices
 )
+
+func MockUserServicesStatus(f func(units []string) (map[int][]userclient.ServiceUnitStatus, error)) (restore func()) {
+	old := userServicesStatus
+	userServicesStatus = f
+	return func() {
+		userServicesStatus = old
+	}
+}
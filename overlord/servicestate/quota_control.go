@@ -0,0 +1,552 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tomb "gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/strutil"
+	"github.com/snapcore/snapd/timings"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+// QuotaGroupUpdate reflects all of the modifications that can be performed
+// on a quota group in one operation.
+type QuotaGroupUpdate struct {
+	// AddSnaps is the set of snaps to add to the quota group.
+	AddSnaps []string
+	// NewMemoryLimit is the new memory limit to be used for the quota group,
+	// it is left unchanged when zero.
+	NewMemoryLimit quantity.Size
+}
+
+// QuotaControlAction is the action carried out by a quota-control task.
+type QuotaControlAction struct {
+	// QuotaName is the name of the quota group.
+	QuotaName string `json:"quota-name"`
+	// Action is one of "create", "update" or "remove".
+	Action string `json:"action"`
+	// MemoryLimit is the memory limit of a created group, or the new limit
+	// of an updated one.
+	MemoryLimit quantity.Size `json:"memory-limit,omitempty"`
+	// AddSnaps are the snaps placed in the group.
+	AddSnaps []string `json:"snaps,omitempty"`
+}
+
+// quotaControlUndo is the state of the quota group before the quota-control
+// task modified it.
+type quotaControlUndo struct {
+	Group *quota.Group `json:"group,omitempty"`
+}
+
+// AllQuotas returns all the quota groups known in the state, keyed by name.
+func AllQuotas(st *state.State) (map[string]*quota.Group, error) {
+	var quotas map[string]*quota.Group
+	if err := st.Get("quotas", &quotas); err != nil {
+		if err != state.ErrNoState {
+			return nil, err
+		}
+		return map[string]*quota.Group{}, nil
+	}
+	return quotas, nil
+}
+
+// GetQuota returns the quota group with the given name, or nil if no such
+// group exists.
+func GetQuota(st *state.State, name string) (*quota.Group, error) {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	return quotas[name], nil
+}
+
+// quotaGroupForSnap returns the quota group the given snap belongs to, or nil
+// if it is not part of any group.
+func quotaGroupForSnap(st *state.State, instanceName string) (*quota.Group, error) {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	for _, grp := range quotas {
+		if strutil.ListContains(grp.Snaps, instanceName) {
+			return grp, nil
+		}
+	}
+	return nil, nil
+}
+
+// removeSnapFromQuota drops the given snap, which is being removed from the
+// system, from the quota group it belongs to.
+func removeSnapFromQuota(st *state.State, instanceName string) error {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	for _, grp := range quotas {
+		if !strutil.ListContains(grp.Snaps, instanceName) {
+			continue
+		}
+		snaps := make([]string, 0, len(grp.Snaps)-1)
+		for _, name := range grp.Snaps {
+			if name != instanceName {
+				snaps = append(snaps, name)
+			}
+		}
+		grp.Snaps = snaps
+		st.Set("quotas", quotas)
+		return nil
+	}
+	return nil
+}
+
+// CreateQuota returns a task set that creates a quota group with the given
+// memory limit containing the given snaps. The systemd slice of the group is
+// created and the services of the snaps are moved into it.
+func CreateQuota(st *state.State, name string, snaps []string, memoryLimit quantity.Size) (*state.TaskSet, error) {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := quotas[name]; ok {
+		return nil, fmt.Errorf("group %q already exists", name)
+	}
+
+	grp, err := quota.NewGroup(name, memoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create quota group: %v", err)
+	}
+	if err := validateSnapsForGroup(st, quotas, name, snaps); err != nil {
+		return nil, err
+	}
+	grp.Snaps = snaps
+	if err := checkQuotaControlConflict(st, name, snaps); err != nil {
+		return nil, err
+	}
+
+	return quotaControlTaskSet(st, fmt.Sprintf("Create quota group %q", name), &QuotaControlAction{
+		QuotaName:   name,
+		Action:      "create",
+		MemoryLimit: memoryLimit,
+		AddSnaps:    snaps,
+	}), nil
+}
+
+// UpdateQuota returns a task set that updates the quota group with the given
+// name, snaps can be added to the group and the memory limit can be
+// increased.
+func UpdateQuota(st *state.State, name string, updateOpts QuotaGroupUpdate) (*state.TaskSet, error) {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	grp, ok := quotas[name]
+	if !ok {
+		return nil, fmt.Errorf("group %q does not exist", name)
+	}
+	action := &QuotaControlAction{
+		QuotaName:   name,
+		Action:      "update",
+		MemoryLimit: updateOpts.NewMemoryLimit,
+		AddSnaps:    updateOpts.AddSnaps,
+	}
+	if _, err := updatedQuotaGroup(st, quotas, grp, action); err != nil {
+		return nil, err
+	}
+	if err := checkQuotaControlConflict(st, name, append(grp.Snaps, updateOpts.AddSnaps...)); err != nil {
+		return nil, err
+	}
+
+	return quotaControlTaskSet(st, fmt.Sprintf("Update quota group %q", name), action), nil
+}
+
+// RemoveQuota returns a task set that removes the quota group with the given
+// name. The services of the snaps in the group are moved out of its slice,
+// which is then removed.
+func RemoveQuota(st *state.State, name string) (*state.TaskSet, error) {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return nil, err
+	}
+	grp, ok := quotas[name]
+	if !ok {
+		return nil, fmt.Errorf("cannot remove non-existent quota group %q", name)
+	}
+	if err := checkQuotaControlConflict(st, name, grp.Snaps); err != nil {
+		return nil, err
+	}
+
+	return quotaControlTaskSet(st, fmt.Sprintf("Remove quota group %q", name), &QuotaControlAction{
+		QuotaName: name,
+		Action:    "remove",
+	}), nil
+}
+
+func quotaControlTaskSet(st *state.State, summary string, action *QuotaControlAction) *state.TaskSet {
+	t := st.NewTask("quota-control", summary)
+	t.Set("quota-control-action", action)
+	return state.NewTaskSet(t)
+}
+
+// checkQuotaControlConflict checks that neither the quota group nor any of
+// the given snaps are being modified by changes in progress.
+func checkQuotaControlConflict(st *state.State, name string, snaps []string) error {
+	for _, t := range st.Tasks() {
+		if t.Status().Ready() || t.Kind() != "quota-control" {
+			continue
+		}
+		var action QuotaControlAction
+		if err := t.Get("quota-control-action", &action); err != nil {
+			return fmt.Errorf("internal error: cannot get quota-control action: %v", err)
+		}
+		if action.QuotaName == name {
+			return &snapstate.ChangeConflictError{
+				Message:    fmt.Sprintf("quota group %q has %q change in progress", name, t.Change().Kind()),
+				ChangeKind: t.Change().Kind(),
+			}
+		}
+	}
+	return snapstate.CheckChangeConflictMany(st, snaps, "")
+}
+
+// quotaControlAffectedSnaps returns the snaps whose services are moved by
+// the quota-control task, for conflict detection.
+func quotaControlAffectedSnaps(t *state.Task) ([]string, error) {
+	var action QuotaControlAction
+	if err := t.Get("quota-control-action", &action); err != nil {
+		return nil, fmt.Errorf("internal error: cannot obtain quota-control action from task: %s", t.Summary())
+	}
+	snaps := append([]string(nil), action.AddSnaps...)
+	grp, err := GetQuota(t.State(), action.QuotaName)
+	if err != nil {
+		return nil, err
+	}
+	if grp != nil {
+		snaps = append(snaps, grp.Snaps...)
+	}
+	return snaps, nil
+}
+
+func validateSnapsForGroup(st *state.State, quotas map[string]*quota.Group, name string, snaps []string) error {
+	for _, instanceName := range snaps {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, instanceName, &snapst); err != nil {
+			if err == state.ErrNoState {
+				return fmt.Errorf("cannot use snap %q in group %q: snap %q is not installed", instanceName, name, instanceName)
+			}
+			return err
+		}
+		for _, grp := range quotas {
+			if strutil.ListContains(grp.Snaps, instanceName) {
+				return fmt.Errorf("cannot add snap %q to group %q: snap already in quota group %q", instanceName, name, grp.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// updatedQuotaGroup returns a copy of the given group with the update action
+// applied.
+func updatedQuotaGroup(st *state.State, quotas map[string]*quota.Group, grp *quota.Group, action *QuotaControlAction) (*quota.Group, error) {
+	newGrp := *grp
+	if action.MemoryLimit != 0 {
+		// decreasing the limit could make the group exceed it with
+		// the services already running
+		if action.MemoryLimit < grp.MemoryLimit {
+			return nil, fmt.Errorf("cannot decrease memory limit of existing quota-group, remove and re-create it to decrease the limit")
+		}
+		newGrp.MemoryLimit = action.MemoryLimit
+	}
+	if err := validateSnapsForGroup(st, quotas, grp.Name, action.AddSnaps); err != nil {
+		return nil, err
+	}
+	newGrp.Snaps = append(append([]string(nil), grp.Snaps...), action.AddSnaps...)
+	if err := newGrp.Validate(); err != nil {
+		return nil, fmt.Errorf("cannot update quota group: %v", err)
+	}
+	return &newGrp, nil
+}
+
+// quotaSnap carries what is needed to regenerate the services of a snap
+// without holding the state lock.
+type quotaSnap struct {
+	info         *snap.Info
+	vitalityRank int
+}
+
+// quotaSnaps returns the active snaps among the given ones which have
+// services.
+func quotaSnaps(st *state.State, snaps []string) ([]*quotaSnap, error) {
+	// the order of the snaps is not relevant, use a stable one
+	snaps = append([]string(nil), snaps...)
+	sort.Strings(snaps)
+
+	var res []*quotaSnap
+	for _, instanceName := range snaps {
+		var snapst snapstate.SnapState
+		err := snapstate.Get(st, instanceName, &snapst)
+		if err == state.ErrNoState {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// inactive snaps get their services generated when they become
+		// active again
+		if !snapst.Active {
+			continue
+		}
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return nil, err
+		}
+		if len(info.Services()) == 0 {
+			continue
+		}
+		rank, err := vitalityRank(st, instanceName)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, &quotaSnap{info: info, vitalityRank: rank})
+	}
+	return res, nil
+}
+
+func (m *ServiceManager) doQuotaControl(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var action QuotaControlAction
+	if err := t.Get("quota-control-action", &action); err != nil {
+		return fmt.Errorf("internal error: cannot get quota-control action: %v", err)
+	}
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+
+	// the task may be re-run, always start from the group as it was
+	// before the first run
+	var undo quotaControlUndo
+	if err := t.Get("quota-control-undo", &undo); err != nil {
+		if err != state.ErrNoState {
+			return err
+		}
+		undo.Group = quotas[action.QuotaName]
+		t.Set("quota-control-undo", &undo)
+	}
+	oldGrp := undo.Group
+	delete(quotas, action.QuotaName)
+
+	var newGrp *quota.Group
+	// the group the affected snaps are moved into, and back into if
+	// applying the change fails
+	var snapsGrp, rollbackSnapsGrp *quota.Group
+	var affected []string
+	switch action.Action {
+	case "create":
+		if oldGrp != nil {
+			return fmt.Errorf("group %q already exists", action.QuotaName)
+		}
+		newGrp, err = quota.NewGroup(action.QuotaName, action.MemoryLimit)
+		if err != nil {
+			return fmt.Errorf("cannot create quota group: %v", err)
+		}
+		if err := validateSnapsForGroup(st, quotas, action.QuotaName, action.AddSnaps); err != nil {
+			return err
+		}
+		newGrp.Snaps = action.AddSnaps
+		snapsGrp = newGrp
+		affected = action.AddSnaps
+	case "update":
+		if oldGrp == nil {
+			return fmt.Errorf("group %q does not exist", action.QuotaName)
+		}
+		newGrp, err = updatedQuotaGroup(st, quotas, oldGrp, &action)
+		if err != nil {
+			return err
+		}
+		snapsGrp = newGrp
+		affected = action.AddSnaps
+	case "remove":
+		if oldGrp == nil {
+			return fmt.Errorf("cannot remove non-existent quota group %q", action.QuotaName)
+		}
+		rollbackSnapsGrp = oldGrp
+		affected = oldGrp.Snaps
+	default:
+		return fmt.Errorf("internal error: unknown quota action %q", action.Action)
+	}
+
+	snaps, err := quotaSnaps(st, affected)
+	if err != nil {
+		return err
+	}
+
+	// Note - state must be unlocked when calling wrappers below.
+	st.Unlock()
+	err = switchQuotaGroup(oldGrp, newGrp, snapsGrp, snaps)
+	if err != nil {
+		if rerr := switchQuotaGroup(newGrp, oldGrp, rollbackSnapsGrp, snaps); rerr != nil {
+			logger.Noticef("cannot roll back quota group %q: %v", action.QuotaName, rerr)
+		}
+	}
+	st.Lock()
+	if err != nil {
+		return err
+	}
+
+	return setQuotaGroup(st, action.QuotaName, newGrp)
+}
+
+func (m *ServiceManager) undoQuotaControl(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var action QuotaControlAction
+	if err := t.Get("quota-control-action", &action); err != nil {
+		return fmt.Errorf("internal error: cannot get quota-control action: %v", err)
+	}
+	var undo quotaControlUndo
+	if err := t.Get("quota-control-undo", &undo); err != nil {
+		return fmt.Errorf("internal error: cannot get quota-control undo information: %v", err)
+	}
+	curGrp, err := GetQuota(st, action.QuotaName)
+	if err != nil {
+		return err
+	}
+
+	var snapsGrp *quota.Group
+	affected := action.AddSnaps
+	if action.Action == "remove" {
+		snapsGrp = undo.Group
+		affected = undo.Group.Snaps
+	}
+	snaps, err := quotaSnaps(st, affected)
+	if err != nil {
+		return err
+	}
+
+	st.Unlock()
+	err = switchQuotaGroup(curGrp, undo.Group, snapsGrp, snaps)
+	st.Lock()
+	if err != nil {
+		return err
+	}
+
+	return setQuotaGroup(st, action.QuotaName, undo.Group)
+}
+
+func setQuotaGroup(st *state.State, name string, grp *quota.Group) error {
+	quotas, err := AllQuotas(st)
+	if err != nil {
+		return err
+	}
+	if grp == nil {
+		delete(quotas, name)
+	} else {
+		quotas[name] = grp
+	}
+	st.Set("quotas", quotas)
+	return nil
+}
+
+// switchQuotaGroup replaces the slice of the oldGrp quota group with the one
+// of newGrp, either of which can be nil, and regenerates the services of the
+// given snaps so that they are placed in the slice of snapsGrp. The state
+// must not be locked.
+func switchQuotaGroup(oldGrp, newGrp, snapsGrp *quota.Group, snaps []*quotaSnap) error {
+	if newGrp != nil {
+		if err := wrappers.EnsureQuotaGroupSlice(newGrp, progress.Null); err != nil {
+			return err
+		}
+	}
+	if err := regenerateSnapServices(snaps, snapsGrp); err != nil {
+		return err
+	}
+	if newGrp == nil && oldGrp != nil {
+		return wrappers.RemoveQuotaGroupSlice(oldGrp, progress.Null)
+	}
+	return nil
+}
+
+// regenerateSnapServices rewrites the service units of the given snaps using
+// the given quota group, which can be nil, and restarts the enabled services
+// so that they are moved into or out of the group slice.
+func regenerateSnapServices(snaps []*quotaSnap, grp *quota.Group) error {
+	for _, sn := range snaps {
+		disabledSvcs, err := wrappers.QueryDisabledServices(sn.info, progress.Null)
+		if err != nil {
+			return err
+		}
+
+		opts := &wrappers.AddSnapServicesOptions{
+			VitalityRank: sn.vitalityRank,
+			QuotaGroup:   grp,
+		}
+		if err := wrappers.AddSnapServices(sn.info, opts, progress.Null); err != nil {
+			return err
+		}
+
+		var restart []*snap.AppInfo
+		for _, app := range sn.info.Services() {
+			// only system services are placed in the slice
+			if app.DaemonScope != snap.SystemDaemon || strutil.ListContains(disabledSvcs, app.Name) {
+				continue
+			}
+			restart = append(restart, app)
+		}
+		if err := wrappers.RestartServices(restart, nil, progress.Null, timings.New(nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vitalityRank returns the rank of the snap in the resilience.vitality-hint
+// setting, or 0 if the snap is not listed there.
+func vitalityRank(st *state.State, instanceName string) (int, error) {
+	tr := config.NewTransaction(st)
+
+	var vitalityStr string
+	if err := tr.GetMaybe("core", "resilience.vitality-hint", &vitalityStr); err != nil {
+		return 0, err
+	}
+	for i, s := range strings.Split(vitalityStr, ",") {
+		if s == instanceName {
+			return i + 1, nil
+		}
+	}
+	return 0, nil
+}
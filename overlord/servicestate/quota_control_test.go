@@ -0,0 +1,396 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/overlord"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type quotaControlSuite struct {
+	testutil.BaseTest
+	o          *overlord.Overlord
+	state      *state.State
+	sysctlArgs [][]string
+	failStart  string
+}
+
+var _ = Suite(&quotaControlSuite{})
+
+func (s *quotaControlSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.o = overlord.Mock()
+	s.state = s.o.State()
+
+	s.sysctlArgs = nil
+	s.failStart = ""
+	s.AddCleanup(systemd.MockSystemctl(func(cmd ...string) (buf []byte, err error) {
+		s.sysctlArgs = append(s.sysctlArgs, cmd)
+		if cmd[0] == "start" && cmd[1] == s.failStart {
+			// fail only once
+			s.failStart = ""
+			return nil, fmt.Errorf("cannot start")
+		}
+		if cmd[0] == "show" {
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		return nil, nil
+	}))
+
+	// the service manager hooks into snapstate
+	mgr := servicestate.Manager(s.state, s.o.TaskRunner())
+	s.o.AddManager(mgr)
+	s.o.AddManager(s.o.TaskRunner())
+	s.o.TaskRunner().AddHandler("error-trigger", func(t *state.Task, _ *tomb.Tomb) error {
+		return errors.New("error out")
+	}, nil)
+	c.Assert(s.o.StartUp(), IsNil)
+}
+
+func (s *quotaControlSuite) mockTestSnap(c *C) {
+	si := snap.SideInfo{
+		RealName: "test-snap",
+		Revision: snap.R(7),
+	}
+	snaptest.MockSnap(c, servicesSnapYaml1, &si)
+	snapstate.Set(s.state, "test-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+}
+
+// runQuotaChange runs the given quota-control task set in a change, the
+// state must be locked.
+func (s *quotaControlSuite) runQuotaChange(c *C, ts *state.TaskSet, extra ...*state.Task) *state.Change {
+	chg := s.state.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+	for _, t := range extra {
+		t.WaitAll(ts)
+		chg.AddTask(t)
+	}
+	s.state.Unlock()
+	defer s.state.Lock()
+	c.Assert(s.o.Settle(5*time.Second), IsNil)
+	return chg
+}
+
+func (s *quotaControlSuite) createQuota(c *C, name string, snaps []string, memoryLimit quantity.Size) {
+	ts, err := servicestate.CreateQuota(s.state, name, snaps, memoryLimit)
+	c.Assert(err, IsNil)
+	chg := s.runQuotaChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+}
+
+func (s *quotaControlSuite) TestCreateQuota(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	ts, err := servicestate.CreateQuota(st, "foo", []string{"test-snap"}, quantity.SizeMiB)
+	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 1)
+	c.Check(ts.Tasks()[0].Kind(), Equals, "quota-control")
+	c.Check(ts.Tasks()[0].Summary(), Equals, `Create quota group "foo"`)
+	// nothing happens until the change runs
+	c.Check(s.sysctlArgs, HasLen, 0)
+
+	chg := s.runQuotaChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+
+	quotas, err := servicestate.AllQuotas(st)
+	c.Assert(err, IsNil)
+	c.Check(quotas, DeepEquals, map[string]*quota.Group{
+		"foo": {
+			Name:        "foo",
+			MemoryLimit: quantity.SizeMiB,
+			Snaps:       []string{"test-snap"},
+		},
+	})
+
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileContains, "MemoryMax=1048576\n")
+	for _, svc := range []string{"abc", "foo", "bar"} {
+		svcFile := filepath.Join(dirs.SnapServicesDir, "snap.test-snap."+svc+".service")
+		c.Check(svcFile, testutil.FileContains, "\nSlice=snap.foo.slice\n")
+	}
+	c.Check(s.sysctlArgs[0], DeepEquals, []string{"daemon-reload"})
+	c.Check(s.sysctlArgs, testutil.DeepContains, []string{"stop", "snap.test-snap.foo.service"})
+	c.Check(s.sysctlArgs, testutil.DeepContains, []string{"start", "snap.test-snap.foo.service"})
+
+	// the group is looked up when linking the snap
+	grp, err := snapstate.QuotaGroupForSnap(st, "test-snap")
+	c.Assert(err, IsNil)
+	c.Check(grp, DeepEquals, quotas["foo"])
+	grp, err = snapstate.QuotaGroupForSnap(st, "other-snap")
+	c.Assert(err, IsNil)
+	c.Check(grp, IsNil)
+}
+
+func (s *quotaControlSuite) TestCreateQuotaErrors(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	_, err := servicestate.CreateQuota(st, "foo", []string{"test-snap"}, 0)
+	c.Check(err, ErrorMatches, `cannot create quota group: quota group must have a memory limit set`)
+
+	_, err = servicestate.CreateQuota(st, "foo", []string{"missing-snap"}, quantity.SizeMiB)
+	c.Check(err, ErrorMatches, `cannot use snap "missing-snap" in group "foo": snap "missing-snap" is not installed`)
+
+	s.createQuota(c, "foo", []string{"test-snap"}, quantity.SizeMiB)
+
+	_, err = servicestate.CreateQuota(st, "foo", nil, quantity.SizeMiB)
+	c.Check(err, ErrorMatches, `group "foo" already exists`)
+
+	_, err = servicestate.CreateQuota(st, "bar", []string{"test-snap"}, quantity.SizeMiB)
+	c.Check(err, ErrorMatches, `cannot add snap "test-snap" to group "bar": snap already in quota group "foo"`)
+}
+
+func (s *quotaControlSuite) TestQuotaConflicts(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	ts, err := servicestate.CreateQuota(st, "foo", []string{"test-snap"}, quantity.SizeMiB)
+	c.Assert(err, IsNil)
+	chg := st.NewChange("quota-control", "...")
+	chg.AddAll(ts)
+
+	// the group is being created
+	_, err = servicestate.CreateQuota(st, "foo", nil, quantity.SizeMiB)
+	c.Check(err, ErrorMatches, `quota group "foo" has "quota-control" change in progress`)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+
+	// the snap services are being moved
+	err = snapstate.CheckChangeConflict(st, "test-snap", nil)
+	c.Check(err, ErrorMatches, `snap "test-snap" has "quota-control" change in progress`)
+
+	chg.SetStatus(state.DoneStatus)
+	for _, t := range chg.Tasks() {
+		t.SetStatus(state.DoneStatus)
+	}
+
+	// a change of the snap is in progress
+	other := st.NewChange("refresh-snap", "...")
+	t := st.NewTask("link-snap", "...")
+	t.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "test-snap"}})
+	other.AddTask(t)
+	_, err = servicestate.CreateQuota(st, "bar", []string{"test-snap"}, quantity.SizeMiB)
+	c.Check(err, ErrorMatches, `snap "test-snap" has "refresh-snap" change in progress`)
+	c.Check(err, FitsTypeOf, &snapstate.ChangeConflictError{})
+}
+
+func (s *quotaControlSuite) TestCreateQuotaRollsBackOnFailure(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	ts, err := servicestate.CreateQuota(st, "foo", []string{"test-snap"}, quantity.SizeMiB)
+	c.Assert(err, IsNil)
+	s.failStart = "snap.test-snap.foo.service"
+	chg := s.runQuotaChange(c, ts)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot start.*`)
+
+	quotas, err := servicestate.AllQuotas(st)
+	c.Assert(err, IsNil)
+	c.Check(quotas, HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.test-snap.foo.service"), Not(testutil.FileContains), "Slice=")
+}
+
+func (s *quotaControlSuite) TestCreateQuotaUndo(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	ts, err := servicestate.CreateQuota(st, "foo", []string{"test-snap"}, quantity.SizeMiB)
+	c.Assert(err, IsNil)
+	chg := s.runQuotaChange(c, ts, st.NewTask("error-trigger", "provoking undo"))
+	c.Check(chg.Err(), ErrorMatches, `(?s).*error out.*`)
+	c.Check(ts.Tasks()[0].Status(), Equals, state.UndoneStatus)
+
+	quotas, err := servicestate.AllQuotas(st)
+	c.Assert(err, IsNil)
+	c.Check(quotas, HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.test-snap.foo.service"), Not(testutil.FileContains), "Slice=")
+}
+
+func (s *quotaControlSuite) TestUpdateQuota(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	s.createQuota(c, "foo", nil, quantity.SizeMiB)
+
+	ts, err := servicestate.UpdateQuota(st, "foo", servicestate.QuotaGroupUpdate{
+		AddSnaps:       []string{"test-snap"},
+		NewMemoryLimit: 2 * quantity.SizeMiB,
+	})
+	c.Assert(err, IsNil)
+	chg := s.runQuotaChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+
+	grp, err := servicestate.GetQuota(st, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp, DeepEquals, &quota.Group{
+		Name:        "foo",
+		MemoryLimit: 2 * quantity.SizeMiB,
+		Snaps:       []string{"test-snap"},
+	})
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileContains, "MemoryMax=2097152\n")
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.test-snap.foo.service"), testutil.FileContains, "\nSlice=snap.foo.slice\n")
+
+	_, err = servicestate.UpdateQuota(st, "foo", servicestate.QuotaGroupUpdate{NewMemoryLimit: quantity.SizeMiB})
+	c.Check(err, ErrorMatches, `cannot decrease memory limit of existing quota-group, remove and re-create it to decrease the limit`)
+
+	_, err = servicestate.UpdateQuota(st, "bar", servicestate.QuotaGroupUpdate{})
+	c.Check(err, ErrorMatches, `group "bar" does not exist`)
+}
+
+func (s *quotaControlSuite) TestUpdateQuotaRollsBackOnFailure(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	s.createQuota(c, "foo", nil, quantity.SizeMiB)
+
+	ts, err := servicestate.UpdateQuota(st, "foo", servicestate.QuotaGroupUpdate{
+		AddSnaps:       []string{"test-snap"},
+		NewMemoryLimit: 2 * quantity.SizeMiB,
+	})
+	c.Assert(err, IsNil)
+	s.failStart = "snap.test-snap.foo.service"
+	chg := s.runQuotaChange(c, ts)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*cannot start.*`)
+
+	grp, err := servicestate.GetQuota(st, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp, DeepEquals, &quota.Group{
+		Name:        "foo",
+		MemoryLimit: quantity.SizeMiB,
+	})
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileContains, "MemoryMax=1048576\n")
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.test-snap.foo.service"), Not(testutil.FileContains), "Slice=")
+}
+
+func (s *quotaControlSuite) TestRemoveQuota(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	s.createQuota(c, "foo", []string{"test-snap"}, quantity.SizeMiB)
+
+	ts, err := servicestate.RemoveQuota(st, "foo")
+	c.Assert(err, IsNil)
+	chg := s.runQuotaChange(c, ts)
+	c.Assert(chg.Err(), IsNil)
+
+	quotas, err := servicestate.AllQuotas(st)
+	c.Assert(err, IsNil)
+	c.Check(quotas, HasLen, 0)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileAbsent)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.test-snap.foo.service"), Not(testutil.FileContains), "Slice=")
+
+	_, err = servicestate.RemoveQuota(st, "foo")
+	c.Check(err, ErrorMatches, `cannot remove non-existent quota group "foo"`)
+}
+
+func (s *quotaControlSuite) TestRemoveQuotaUndo(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	s.mockTestSnap(c)
+
+	s.createQuota(c, "foo", []string{"test-snap"}, quantity.SizeMiB)
+
+	ts, err := servicestate.RemoveQuota(st, "foo")
+	c.Assert(err, IsNil)
+	chg := s.runQuotaChange(c, ts, st.NewTask("error-trigger", "provoking undo"))
+	c.Check(chg.Err(), ErrorMatches, `(?s).*error out.*`)
+
+	grp, err := servicestate.GetQuota(st, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp, DeepEquals, &quota.Group{
+		Name:        "foo",
+		MemoryLimit: quantity.SizeMiB,
+		Snaps:       []string{"test-snap"},
+	})
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FilePresent)
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.test-snap.foo.service"), testutil.FileContains, "\nSlice=snap.foo.slice\n")
+}
+
+func (s *quotaControlSuite) TestRemoveSnapFromQuota(c *C) {
+	st := s.state
+	st.Lock()
+	defer st.Unlock()
+
+	st.Set("quotas", map[string]*quota.Group{
+		"foo": {Name: "foo", MemoryLimit: quantity.SizeMiB, Snaps: []string{"test-snap", "other-snap"}},
+	})
+
+	err := snapstate.RemoveSnapFromQuota(st, "test-snap")
+	c.Assert(err, IsNil)
+	err = snapstate.RemoveSnapFromQuota(st, "unknown-snap")
+	c.Assert(err, IsNil)
+
+	grp, err := servicestate.GetQuota(st, "foo")
+	c.Assert(err, IsNil)
+	c.Check(grp.Snaps, DeepEquals, []string{"other-snap"})
+}
@@ -39,6 +39,7 @@ func Manager(st *state.State, runner *state.TaskRunner) *ServiceManager {
 	}
 	// TODO: undo handler
 	runner.AddHandler("service-control", m.doServiceControl, nil)
+	runner.AddHandler("quota-control", m.doQuotaControl, m.undoQuotaControl)
 	return m
 }
 
@@ -50,6 +51,10 @@ func (m *ServiceManager) Ensure() error {
 func delayedCrossMgrInit() {
 	// hook into conflict checks mechanisms
 	snapstate.AddAffectedSnapsByAttr("service-action", serviceControlAffectedSnaps)
+	snapstate.AddAffectedSnapsByAttr("quota-control-action", quotaControlAffectedSnaps)
+	// services of snaps in a quota group are placed in its slice
+	snapstate.QuotaGroupForSnap = quotaGroupForSnap
+	snapstate.RemoveSnapFromQuota = removeSnapFromQuota
 }
 
 func serviceControlAffectedSnaps(t *state.Task) ([]string, error) {
@@ -20,18 +20,22 @@
 package servicestate
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"sort"
 	"time"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/cmdstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
+	userclient "github.com/snapcore/snapd/usersession/client"
 )
 
 type Instruction struct {
@@ -219,6 +223,34 @@ func NewStatusDecorator(rep interface {
 	}
 }
 
+// userServicesStatus queries the status of the given user service units
+// in all active user sessions, keyed by the uid of the session owner.
+var userServicesStatus = func(units []string) (map[int][]userclient.ServiceUnitStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout.DefaultTimeout))
+	defer cancel()
+	return userclient.New().ServicesStatus(ctx, units)
+}
+
+// activeInUserSessions returns the set of the given user service units
+// that are active in at least one user session.
+func activeInUserSessions(units []string) map[string]bool {
+	status, err := userServicesStatus(units)
+	if err != nil {
+		// the global enablement is still reported, sessions that
+		// could not be queried are treated as not running the units
+		logger.Noticef("cannot get status of user services %v from all sessions: %v", units, err)
+	}
+	active := make(map[string]bool, len(units))
+	for _, sts := range status {
+		for _, st := range sts {
+			if st.Active {
+				active[st.UnitName] = true
+			}
+		}
+	}
+	return active
+}
+
 // DecorateWithStatus adds service status information to the given
 // client.AppInfo associated with the given snap.AppInfo.
 // If the snap is inactive or the app is not service it does nothing.
@@ -268,6 +300,14 @@ func (sd *StatusDecorator) DecorateWithStatus(appInfo *client.AppInfo, snapApp *
 	if len(sts) != len(serviceNames) {
 		return fmt.Errorf("cannot get status of services of app %q: expected %d results, got %d", appInfo.Name, len(serviceNames), len(sts))
 	}
+	if snapApp.DaemonScope == snap.UserDaemon {
+		// the global user instance only knows about enablement, the
+		// units are running (or not) in the users' sessions
+		active := activeInUserSessions(serviceNames)
+		for _, st := range sts {
+			st.Active = active[st.UnitName]
+		}
+	}
 	for _, st := range sts {
 		switch filepath.Ext(st.UnitName) {
 		case ".service":
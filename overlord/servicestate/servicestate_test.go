@@ -33,6 +33,7 @@ import (
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/systemd"
+	userclient "github.com/snapcore/snapd/usersession/client"
 )
 
 type statusDecoratorSuite struct{}
@@ -209,7 +210,7 @@ UnitFileState=%s
 			{Name: "org.example.Svc", Type: "dbus", Active: true, Enabled: true},
 		})
 
-		// No state is currently extracted for user daemons
+		// without user sessions user daemons are not reported as active
 		app = &client.AppInfo{
 			Snap:   snp.InstanceName(),
 			Name:   "svc",
@@ -253,5 +254,35 @@ UnitFileState=%s
 			{Name: "svc", Type: "timer", Active: false, Enabled: enabled},
 			{Name: "org.example.Svc", Type: "dbus", Active: true, Enabled: true},
 		})
+
+		// user daemons are active if running in any of the user sessions
+		restore := servicestate.MockUserServicesStatus(func(units []string) (map[int][]userclient.ServiceUnitStatus, error) {
+			c.Check(units, DeepEquals, []string{"snap.foo.svc.service", "snap.foo.svc.socket1.socket", "snap.foo.svc.timer"})
+			return map[int][]userclient.ServiceUnitStatus{
+				1000: {
+					{UnitName: "snap.foo.svc.service", Active: false},
+					{UnitName: "snap.foo.svc.socket1.socket", Active: enabled},
+				},
+				1001: {
+					{UnitName: "snap.foo.svc.service", Active: enabled},
+					{UnitName: "snap.foo.svc.socket1.socket", Active: false},
+				},
+			}, fmt.Errorf("cannot reach the session agent of uid 1002")
+		})
+		app = &client.AppInfo{
+			Snap:   snp.InstanceName(),
+			Name:   "svc",
+			Daemon: "simple",
+		}
+		err = sd.DecorateWithStatus(app, snapApp)
+		restore()
+		c.Assert(err, IsNil)
+		c.Check(app.Active, Equals, enabled)
+		c.Check(app.Enabled, Equals, enabled)
+		c.Check(app.Activators, DeepEquals, []client.AppActivator{
+			{Name: "socket1", Type: "socket", Active: enabled, Enabled: enabled},
+			{Name: "svc", Type: "timer", Active: false, Enabled: enabled},
+			{Name: "org.example.Svc", Type: "dbus", Active: true, Enabled: true},
+		})
 	}
 }
@@ -694,6 +694,12 @@ func Import(ctx context.Context, id uint64, r io.Reader, flags *ImportFlags) (sn
 		}
 		return nil, fmt.Errorf("%s: %v", errPrefix, err)
 	}
+	// consume the rest of the stream (e.g. the tar padding) so that
+	// readers verifying the stream once fully read can report errors
+	// before the import is committed
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return nil, fmt.Errorf("%s: %v", errPrefix, err)
+	}
 	if err := tr.Commit(); err != nil {
 		return nil, err
 	}
@@ -859,6 +865,14 @@ type SnapshotExport struct {
 
 	// cached size, needs to be calculated with CalculateSize
 	size int64
+
+	// cached hex encoded SHA3-384 digest of the export stream, calculated
+	// together with the size
+	sha3_384 string
+
+	// time recorded in the export, fixed so that the stream is the same
+	// every time it is generated
+	exportTime time.Time
 }
 
 // NewSnapshotExport will return a SnapshotExport structure. It must be
@@ -912,7 +926,7 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 	if err != nil {
 		return nil, fmt.Errorf("cannot calculate content hash for snapshot export %v: %v", setID, err)
 	}
-	se = &SnapshotExport{snapshotFiles: snapshotFiles, setID: setID, contentHash: h}
+	se = &SnapshotExport{snapshotFiles: snapshotFiles, setID: setID, contentHash: h, exportTime: timeNow()}
 
 	// ensure we never leak FDs even if the user does not call close
 	runtime.SetFinalizer(se, (*SnapshotExport).Close)
@@ -920,23 +934,22 @@ func NewSnapshotExport(ctx context.Context, setID uint64) (se *SnapshotExport, e
 	return se, nil
 }
 
-// Init will calculate the snapshot size. This can take some time
-// so it should be called without any locks. The SnapshotExport
+// Init will calculate the snapshot size and digest. This can take some
+// time so it should be called without any locks. The SnapshotExport
 // keeps the FDs open so even files moved/deleted will be found.
 func (se *SnapshotExport) Init() error {
 	// Export once into a dummy writer so that we can set the size
-	// of the export. This is then used to set the Content-Length
-	// in the response correctly.
-	//
-	// Note that the size of the generated tar could change if the
-	// time switches between this export and the export we stream
-	// to the client to a time after the year 2242. This is unlikely
-	// but a known issue with this approach here.
+	// and the digest of the export. This is then used to set the
+	// Content-Length and the integrity headers in the response
+	// correctly. The export time is fixed when the export is
+	// created, so the stream sent to the client is the same.
 	var sz osutil.Sizer
-	if err := se.StreamTo(&sz); err != nil {
+	h := crypto.SHA3_384.New()
+	if err := se.StreamTo(io.MultiWriter(&sz, h)); err != nil {
 		return fmt.Errorf("cannot calculcate the size for %v: %s", se.setID, err)
 	}
 	se.size = sz.Size()
+	se.sha3_384 = fmt.Sprintf("%x", h.Sum(nil))
 	return nil
 }
 
@@ -944,6 +957,12 @@ func (se *SnapshotExport) Size() int64 {
 	return se.size
 }
 
+// Sha3_384 returns the hex encoded SHA3-384 digest of the export stream,
+// it is only available after Init.
+func (se *SnapshotExport) Sha3_384() string {
+	return se.sha3_384
+}
+
 func (se *SnapshotExport) Close() {
 	for _, f := range se.snapshotFiles {
 		f.Close()
@@ -971,7 +990,7 @@ func (se *SnapshotExport) StreamTo(w io.Writer) error {
 		Name:     "content.json",
 		Size:     int64(len(h)),
 		Mode:     0640,
-		ModTime:  timeNow(),
+		ModTime:  se.exportTime,
 	}
 	if err := tw.WriteHeader(hdr); err != nil {
 		return err
@@ -1011,7 +1030,7 @@ func (se *SnapshotExport) StreamTo(w io.Writer) error {
 	// validate the archive is complete
 	meta := exportMetadata{
 		Format: 1,
-		Date:   timeNow(),
+		Date:   se.exportTime,
 		Files:  files,
 	}
 	metaDataBuf, err := json.Marshal(&meta)
@@ -1023,7 +1042,7 @@ func (se *SnapshotExport) StreamTo(w io.Writer) error {
 		Name:     "export.json",
 		Size:     int64(len(metaDataBuf)),
 		Mode:     0640,
-		ModTime:  timeNow(),
+		ModTime:  se.exportTime,
 	}
 	if err := tw.WriteHeader(hdr); err != nil {
 		return err
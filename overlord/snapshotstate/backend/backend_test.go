@@ -990,6 +990,37 @@ func (s *snapshotSuite) TestImportCheckError(c *check.C) {
 	c.Assert(err, check.ErrorMatches, `cannot import snapshot 14: validation failed for .+/14_foo_1.0_199.zip": snapshot entry "archive.tgz" expected hash \(d5ef563…\) does not match actual \(6655519…\)`)
 }
 
+type failingAtEOFReader struct {
+	io.Reader
+}
+
+func (r failingAtEOFReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("digest mismatch")
+	}
+	return n, err
+}
+
+func (s *snapshotSuite) TestImportReadsWholeStreamBeforeCommit(c *check.C) {
+	// create snapshot export file
+	tarFile1 := path.Join(c.MkDir(), "exported1.snapshot")
+	err := createTestExportFile(tarFile1, &createTestExportFlags{exportJSON: true})
+	c.Assert(err, check.IsNil)
+
+	f, err := os.Open(tarFile1)
+	c.Assert(err, check.IsNil)
+	defer f.Close()
+
+	_, err = backend.Import(context.Background(), 14, failingAtEOFReader{f}, nil)
+	c.Assert(err, check.ErrorMatches, "cannot import snapshot 14: digest mismatch")
+
+	// the import was cancelled
+	names, err := filepath.Glob(filepath.Join(dirs.SnapshotsDir, "*"))
+	c.Assert(err, check.IsNil)
+	c.Check(names, check.HasLen, 0)
+}
+
 func (s *snapshotSuite) TestImportDuplicated(c *check.C) {
 	err := os.MkdirAll(dirs.SnapshotsDir, 0755)
 	c.Assert(err, check.IsNil)
@@ -1217,6 +1248,41 @@ func (s *snapshotSuite) TestExportTwice(c *check.C) {
 	c.Check(buf.Len(), check.Equals, int(expectedSize))
 }
 
+func (s *snapshotSuite) TestExportSha3_384(c *check.C) {
+	info := &snap.Info{
+		SideInfo: snap.SideInfo{
+			RealName: "hello-snap",
+			Revision: snap.R(42),
+			SnapID:   "hello-id",
+		},
+		Version: "v1.33",
+	}
+	shID := uint64(12)
+	_, err := backend.Save(context.TODO(), shID, info, nil, nil)
+	c.Check(err, check.IsNil)
+
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	restore := backend.MockTimeNow(func() time.Time { return now })
+	defer restore()
+
+	se, err := backend.NewSnapshotExport(context.Background(), shID)
+	c.Assert(err, check.IsNil)
+	defer se.Close()
+	c.Check(se.Sha3_384(), check.Equals, "")
+	err = se.Init()
+	c.Assert(err, check.IsNil)
+
+	// time moves on between the size calculation and the actual export
+	now = now.Add(time.Hour)
+
+	buf := bytes.NewBuffer(nil)
+	err = se.StreamTo(buf)
+	c.Assert(err, check.IsNil)
+	h := crypto.SHA3_384.New()
+	h.Write(buf.Bytes())
+	c.Check(se.Sha3_384(), check.Equals, fmt.Sprintf("%x", h.Sum(nil)))
+}
+
 func (s *snapshotSuite) TestExportUnhappy(c *check.C) {
 	se, err := backend.NewSnapshotExport(context.Background(), 5)
 	c.Assert(err, check.ErrorMatches, "no snapshot data found for 5")
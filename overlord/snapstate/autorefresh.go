@@ -67,6 +67,7 @@ type autoRefresh struct {
 	nextRefresh         time.Time
 	lastRefreshAttempt  time.Time
 	managedDeniedLogged bool
+	onMetered           bool
 }
 
 func newAutoRefresh(st *state.State) *autoRefresh {
@@ -88,6 +89,12 @@ func (m *autoRefresh) NextRefresh() time.Time {
 	return m.nextRefresh
 }
 
+// OnMeteredConnection returns whether the connection was found to be
+// metered when last checked before an auto-refresh.
+func (m *autoRefresh) OnMeteredConnection() bool {
+	return m.onMetered
+}
+
 // LastRefresh returns when the last refresh happened.
 func (m *autoRefresh) LastRefresh() (time.Time, error) {
 	return getTime(m.state, "last-refresh")
@@ -130,9 +137,44 @@ func (m *autoRefresh) EffectiveRefreshHold() (time.Time, error) {
 	return holdTime, nil
 }
 
+// refreshDelay records a delay of auto-refreshes until it is released,
+// e.g. while console-conf is running.
+type refreshDelay struct {
+	// Since is when the delay started.
+	Since time.Time `json:"since"`
+	// Hold is the refresh.hold value set for the delay, if any.
+	Hold time.Time `json:"hold"`
+	// PreviousHold is the refresh.hold value before the delay.
+	PreviousHold time.Time `json:"previous-hold"`
+}
+
+// maxRefreshDelay is the maximum time auto-refreshes are held by a delay
+// that is never released.
+var maxRefreshDelay = 24 * time.Hour
+
+func (m *autoRefresh) refreshDelay() (*refreshDelay, error) {
+	var delay refreshDelay
+	err := m.state.Get("refresh-delay", &delay)
+	if err == state.ErrNoState {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delay, nil
+}
+
 func (m *autoRefresh) ensureRefreshHoldAtLeast(duration time.Duration) error {
 	now := time.Now()
 
+	delay, err := m.refreshDelay()
+	if err != nil {
+		return err
+	}
+	if delay == nil {
+		delay = &refreshDelay{Since: now}
+	}
+
 	// get the effective refresh hold and check if it is sooner than the
 	// specified duration in the future
 	effective, err := m.EffectiveRefreshHold()
@@ -143,15 +185,59 @@ func (m *autoRefresh) ensureRefreshHoldAtLeast(duration time.Duration) error {
 	if effective.IsZero() || effective.Sub(now) < duration {
 		// the effective refresh hold is sooner than the desired delay, so
 		// move it out to the specified duration
-		holdTime := now.Add(duration)
+		var prevHold time.Time
 		tr := config.NewTransaction(m.state)
+		if err := tr.Get("core", "refresh.hold", &prevHold); err != nil && !config.IsNoOption(err) {
+			return err
+		}
+		// remember the hold from before the delay so that it can be
+		// restored when the delay is released
+		if delay.Hold.IsZero() || !delay.Hold.Equal(prevHold) {
+			delay.PreviousHold = prevHold
+		}
+		holdTime := now.Add(duration)
 		err := tr.Set("core", "refresh.hold", &holdTime)
 		if err != nil && !config.IsNoOption(err) {
 			return err
 		}
 		tr.Commit()
+		delay.Hold = holdTime
+	}
+	m.state.Set("refresh-delay", delay)
+
+	return nil
+}
+
+// releaseRefreshHold releases the delay set up by ensureRefreshHoldAtLeast,
+// restoring the refresh.hold from before, unless refresh.hold was changed
+// since.
+func (m *autoRefresh) releaseRefreshHold() error {
+	delay, err := m.refreshDelay()
+	if err != nil || delay == nil {
+		return err
 	}
+	m.state.Set("refresh-delay", nil)
 
+	if delay.Hold.IsZero() {
+		// refresh.hold was not touched
+		return nil
+	}
+	var holdTime time.Time
+	tr := config.NewTransaction(m.state)
+	if err := tr.Get("core", "refresh.hold", &holdTime); err != nil && !config.IsNoOption(err) {
+		return err
+	}
+	// an expired hold is cleared by Ensure
+	if !holdTime.IsZero() && !holdTime.Equal(delay.Hold) {
+		// changed in the meantime
+		return nil
+	}
+	if delay.PreviousHold.After(time.Now()) {
+		tr.Set("core", "refresh.hold", delay.PreviousHold)
+		tr.Commit()
+		return nil
+	}
+	m.clearRefreshHold()
 	return nil
 }
 
@@ -195,18 +281,17 @@ func canRefreshOnMeteredConnection(st *state.State) (bool, error) {
 }
 
 func (m *autoRefresh) canRefreshRespectingMetered(now, lastRefresh time.Time) (can bool, err error) {
+	// ignore any errors that occurred while checking if we are on a metered
+	// connection, the result is remembered so that it can be reported
+	// regardless of refreshes being held on metered connections
+	metered, _ := IsOnMeteredConnection()
+	m.onMetered = metered
+
 	can, err = canRefreshOnMeteredConnection(m.state)
 	if err != nil {
 		return false, err
 	}
-	if can {
-		return true, nil
-	}
-
-	// ignore any errors that occurred while checking if we are on a metered
-	// connection
-	metered, _ := IsOnMeteredConnection()
-	if !metered {
+	if can || !metered {
 		return true, nil
 	}
 
@@ -263,13 +348,16 @@ func (m *autoRefresh) Ensure() error {
 		return nil
 	}
 
-	now := time.Now()
+	// strip the monotonic clock reading so that the next refresh time is
+	// compared using the wall clock, the monotonic clock does not advance
+	// while the system is suspended
+	now := time.Now().Round(0)
 	// compute next refresh attempt time (if needed)
 	if m.nextRefresh.IsZero() {
 		// store attempts in memory so that we can backoff
 		if !lastRefresh.IsZero() {
 			delta := timeutil.Next(refreshSchedule, lastRefresh, maxPostponement)
-			now = time.Now()
+			now = time.Now().Round(0)
 			m.nextRefresh = now.Add(delta)
 		} else {
 			// make sure either seed-time or last-refresh
@@ -285,6 +373,17 @@ func (m *autoRefresh) Ensure() error {
 	if err != nil {
 		return err
 	}
+	if !held {
+		// refreshes wait until a delay is released, but not forever
+		delay, err := m.refreshDelay()
+		if err != nil {
+			return err
+		}
+		if delay != nil && now.Before(delay.Since.Add(maxRefreshDelay)) {
+			logger.Debugf("Auto refresh delayed until released or %s.", delay.Since.Add(maxRefreshDelay).Format(time.RFC3339))
+			return nil
+		}
+	}
 
 	// do refresh attempt (if needed)
 	if !held {
@@ -294,7 +393,7 @@ func (m *autoRefresh) Ensure() error {
 			if m.nextRefresh.Before(holdTime) {
 				// next refresh is obsolete, compute the next one
 				delta := timeutil.Next(refreshSchedule, holdTime, maxPostponement)
-				now = time.Now()
+				now = time.Now().Round(0)
 				m.nextRefresh = now.Add(delta)
 			}
 		}
@@ -345,6 +444,14 @@ func (m *autoRefresh) isRefreshHeld(refreshSchedule []*timeutil.Schedule) (bool,
 	if holdTime.After(now) {
 		return true, holdTime, nil
 	}
+	// or did the user hold them?
+	userHold, err := SystemRefreshHold(m.state)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if userHold != nil {
+		return true, userHold.Until, nil
+	}
 
 	return false, holdTime, nil
 }
@@ -420,6 +420,32 @@ func (s *autoRefreshTestSuite) TestLastRefreshRefreshHold(c *C) {
 	c.Check(t1.Equal(holdTime), Equals, true)
 }
 
+func (s *autoRefreshTestSuite) TestLastRefreshUserHoldForever(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// held by the user beyond the max postponement
+	s.state.Set("last-refresh", time.Now().Add(-90*24*time.Hour))
+	c.Assert(snapstate.HoldRefresh(s.state, nil, time.Time{}), IsNil)
+
+	af := snapstate.NewAutoRefresh(s.state)
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+
+	// no refresh
+	c.Check(s.store.ops, HasLen, 0)
+
+	// until the hold is removed
+	c.Assert(snapstate.UnholdRefresh(s.state, nil), IsNil)
+	s.state.Unlock()
+	err = af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+}
+
 func (s *autoRefreshTestSuite) TestLastRefreshRefreshHoldExpired(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -661,6 +687,65 @@ func (s *autoRefreshTestSuite) TestEnsureRefreshHoldAtLeast(c *C) {
 	c.Assert(t1.Format(time.RFC3339), Equals, t2.Format(time.RFC3339))
 }
 
+func (s *autoRefreshTestSuite) TestRefreshDelayedUntilReleased(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	t0 := time.Now()
+	s.state.Set("last-refresh", t0.Add(-12*time.Hour))
+
+	af := snapstate.NewAutoRefresh(s.state)
+	err := af.EnsureRefreshHoldAtLeast(time.Hour)
+	c.Assert(err, IsNil)
+
+	// the hold set by the delay expired already
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "refresh.hold", t0.Add(-time.Minute))
+	tr.Commit()
+
+	s.state.Unlock()
+	err = af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+
+	// no refresh until the delay is released
+	c.Check(s.store.ops, HasLen, 0)
+
+	err = af.ReleaseRefreshHold()
+	c.Assert(err, IsNil)
+
+	s.state.Unlock()
+	err = af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+
+	// refresh happened
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+}
+
+func (s *autoRefreshTestSuite) TestRefreshDelayedNotForever(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	t0 := time.Now()
+	s.state.Set("last-refresh", t0.Add(-48*time.Hour))
+
+	// the delay was never released
+	s.state.Set("refresh-delay", map[string]interface{}{
+		"since": t0.Add(-25 * time.Hour),
+		"hold":  t0.Add(-25*time.Hour + 20*time.Minute),
+	})
+
+	af := snapstate.NewAutoRefresh(s.state)
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+
+	// refresh happened
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+}
+
 func (s *autoRefreshTestSuite) TestEffectiveRefreshHold(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -858,6 +943,7 @@ func (s *autoRefreshTestSuite) TestRefreshOnMeteredConnIsMetered(c *C) {
 	c.Check(s.store.ops, HasLen, 0)
 
 	c.Check(af.NextRefresh(), DeepEquals, time.Time{})
+	c.Check(af.OnMeteredConnection(), Equals, true)
 
 	// last refresh over 60 days ago, new one is launched regardless of
 	// connection being metered
@@ -891,6 +977,29 @@ func (s *autoRefreshTestSuite) TestRefreshOnMeteredConnNotMetered(c *C) {
 	s.state.Lock()
 	c.Check(err, IsNil)
 	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+	c.Check(af.OnMeteredConnection(), Equals, false)
+}
+
+func (s *autoRefreshTestSuite) TestRefreshOnMeteredConnNotHeld(c *C) {
+	// pretend we're on metered connection
+	revert := snapstate.MockIsOnMeteredConnection(func() (bool, error) {
+		return true, nil
+	})
+	defer revert()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	af := snapstate.NewAutoRefresh(s.state)
+
+	s.state.Set("last-refresh", time.Now().Add(-5*24*time.Hour))
+	s.state.Unlock()
+	err := af.Ensure()
+	s.state.Lock()
+	c.Check(err, IsNil)
+	// refreshes are not held but the metered connection is reported
+	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
+	c.Check(af.OnMeteredConnection(), Equals, true)
 }
 
 func (s *autoRefreshTestSuite) TestInitialInhibitRefreshWithinInhibitWindow(c *C) {
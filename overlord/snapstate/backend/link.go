@@ -29,6 +29,7 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/timings"
 	"github.com/snapcore/snapd/wrappers"
 )
@@ -44,6 +45,10 @@ type LinkContext struct {
 	// protected from the OOM killer
 	VitalityRank int
 
+	// QuotaGroup is the quota group the services of the snap are placed
+	// in, if any
+	QuotaGroup *quota.Group
+
 	// RunInhibitHint is used only in Unlink snap, and can be used to
 	// establish run inhibition lock for refresh operations.
 	RunInhibitHint runinhibit.Hint
@@ -132,7 +137,10 @@ func (b Backend) LinkSnap(info *snap.Info, dev boot.Device, linkCtx LinkContext,
 		})
 	}
 
-	reboot, err := boot.Participant(info, info.Type(), dev).SetNextBoot()
+	var reboot bool
+	timings.Run(tm, "set-next-boot", fmt.Sprintf("set next boot for snap %s", info.InstanceName()), func(timings.Measurer) {
+		reboot, err = boot.Participant(info, info.Type(), dev).SetNextBoot()
+	})
 	if err != nil {
 		return false, err
 	}
@@ -196,6 +204,7 @@ func (b Backend) generateWrappers(s *snap.Info, linkCtx LinkContext) error {
 	opts := &wrappers.AddSnapServicesOptions{
 		Preseeding:              b.preseed,
 		VitalityRank:            linkCtx.VitalityRank,
+		QuotaGroup:              linkCtx.QuotaGroup,
 		RequireMountedSnapdSnap: linkCtx.RequireMountedSnapdSnap,
 	}
 	if err = wrappers.AddSnapServices(s, opts, progress.Null); err != nil {
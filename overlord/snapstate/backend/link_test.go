@@ -37,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/cmd/snaplock/runinhibit"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
@@ -197,6 +198,44 @@ type: base
 	c.Check(reboot, Equals, true)
 }
 
+func (s *linkSuite) TestLinkSetNextBootTimings(c *C) {
+	coreDev := boottest.MockDevice("base")
+
+	bl := boottest.MockUC16Bootenv(bootloadertest.Mock("mock", c.MkDir()))
+	bootloader.Force(bl)
+	defer bootloader.Force(nil)
+	bl.SetBootBase("base_1.snap")
+
+	oldDurationThreshold := timings.DurationThreshold
+	defer func() {
+		timings.DurationThreshold = oldDurationThreshold
+	}()
+	timings.DurationThreshold = 0
+
+	const yaml = `name: base
+version: 1.0
+type: base
+`
+	info := snaptest.MockSnap(c, yaml, &snap.SideInfo{Revision: snap.R(11)})
+
+	_, err := s.be.LinkSnap(info, coreDev, backend.LinkContext{}, s.perfTimings)
+	c.Assert(err, IsNil)
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+	s.perfTimings.Save(st)
+
+	var allTimings []map[string]interface{}
+	c.Assert(st.Get("timings", &allTimings), IsNil)
+	c.Assert(allTimings, HasLen, 1)
+	var labels []string
+	for _, tm := range allTimings[0]["timings"].([]interface{}) {
+		labels = append(labels, tm.(map[string]interface{})["label"].(string))
+	}
+	c.Check(labels, DeepEquals, []string{"generate-wrappers", "set-next-boot"})
+}
+
 func (s *linkSuite) TestLinkDoIdempotent(c *C) {
 	// make sure that a retry wouldn't stumble on partial work
 
@@ -75,6 +75,7 @@ type fakeOp struct {
 	disabledServices []string
 
 	vitalityRank int
+	quotaGroup   string
 
 	inhibitHint runinhibit.Hint
 
@@ -157,6 +158,7 @@ type fakeStore struct {
 	storetest.Store
 
 	downloads           []fakeDownload
+	downloadError       map[string]error
 	refreshRevnos       map[string]snap.Revision
 	fakeBackend         *fakeSnappyBackend
 	fakeCurrentProgress int
@@ -614,6 +616,13 @@ func (f *fakeStore) SuggestedCurrency() string {
 	return "XTS"
 }
 
+// bootSnapDownloadOpts are the options used to download kernel and base
+// snaps.
+var bootSnapDownloadOpts = &store.DownloadOptions{
+	LeavePartialOnError: true,
+	Streams:             4,
+}
+
 func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInfo *snap.DownloadInfo, pb progress.Meter, user *auth.UserState, dlOpts *store.DownloadOptions) error {
 	f.pokeStateLock()
 
@@ -636,6 +645,10 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	})
 	f.fakeBackend.appendOp(&fakeOp{op: "storesvc-download", name: name})
 
+	if err := f.downloadError[name]; err != nil {
+		return err
+	}
+
 	pb.SetTotal(float64(f.fakeTotalProgress))
 	pb.Set(float64(f.fakeCurrentProgress))
 
@@ -806,6 +819,9 @@ func (f *fakeSnappyBackend) ReadInfo(name string, si *snap.SideInfo) (*snap.Info
 		info.Epoch = snap.Epoch{}
 	case "some-epoch-snap":
 		info.Epoch = snap.E("13")
+	case "epoch-bump-snap":
+		// every revision has its own epoch
+		info.Epoch = snap.E(si.Revision.String())
 	case "some-snap-with-base":
 		info.Base = "core18"
 	case "gadget", "brand-gadget":
@@ -896,6 +912,9 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, dev boot.Device, linkCtx b
 		vitalityRank:        linkCtx.VitalityRank,
 		requireSnapdTooling: linkCtx.RequireMountedSnapdSnap,
 	}
+	if linkCtx.QuotaGroup != nil {
+		op.quotaGroup = linkCtx.QuotaGroup.Name
+	}
 
 	if info.MountDir() == f.linkSnapFailTrigger {
 		op.op = "link-snap.failed"
@@ -83,7 +83,7 @@ func (r *catalogRefresh) Ensure() error {
 		return err
 	}
 
-	if deviceCtx.SystemMode() == "install" {
+	if mode := deviceCtx.SystemMode(); mode == "install" || mode == "factory-reset" {
 		// skip the refresh
 		return nil
 	}
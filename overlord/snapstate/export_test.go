@@ -303,6 +303,10 @@ func (m *autoRefresh) EnsureRefreshHoldAtLeast(d time.Duration) error {
 	return m.ensureRefreshHoldAtLeast(d)
 }
 
+func (m *autoRefresh) ReleaseRefreshHold() error {
+	return m.releaseRefreshHold()
+}
+
 func MockSecurityProfilesDiscardLate(fn func(snapName string, rev snap.Revision, typ snap.Type) error) (restore func()) {
 	old := SecurityProfilesRemoveLate
 	SecurityProfilesRemoveLate = fn
@@ -19,6 +19,10 @@
 
 package snapstate
 
+import (
+	"github.com/snapcore/snapd/client"
+)
+
 // Flags are used to pass additional flags to operations and to keep track of
 // snap modes.
 type Flags struct {
@@ -84,6 +88,10 @@ type Flags struct {
 	// This may eventually be set for specific snaps mentioned in the model
 	// assertion for non-dangerous grade models too.
 	ApplySnapDevMode bool `json:"apply-snap-devmode,omitempty"`
+
+	// Transaction is set to client.TransactionAllSnaps to have all the
+	// snaps installed or refreshed together undone if any of them fails.
+	Transaction client.TransactionType `json:"transaction,omitempty"`
 }
 
 // DevModeAllowed returns whether a snap can be installed with devmode
@@ -100,5 +108,6 @@ func (f Flags) ForSnapSetup() Flags {
 	f.NoReRefresh = false
 	f.RequireTypeBase = false
 	f.ApplySnapDevMode = false
+	f.Transaction = ""
 	return f
 }
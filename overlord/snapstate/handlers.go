@@ -47,6 +47,7 @@ import (
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/timings"
@@ -552,6 +553,10 @@ func downloadSnapParams(st *state.State, t *state.Task) (*SnapSetup, StoreServic
 	return snapsup, sto, user, nil
 }
 
+// bootDownloadStreams is the number of parallel streams used to download
+// kernel and base snaps.
+var bootDownloadStreams = 4
+
 func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	st := t.State()
 	var rate int64
@@ -575,6 +580,14 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		IsAutoRefresh: snapsup.IsAutoRefresh,
 		RateLimit:     rate,
 	}
+	switch snapsup.Type {
+	case snap.TypeKernel, snap.TypeBase, snap.TypeOS:
+		// kernels and bases are big, keep what was downloaded so that
+		// an interrupted download can be resumed, e.g. after a restart
+		// of snapd, and use parallel streams for them
+		dlOpts.LeavePartialOnError = true
+		dlOpts.Streams = bootDownloadStreams
+	}
 	if snapsup.DownloadInfo == nil {
 		var storeInfo store.SnapActionResult
 		// COMPATIBILITY - this task was created from an older version
@@ -594,6 +607,11 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		})
 	}
 	if err != nil {
+		if tomb.Alive() {
+			// the task is not retried after snapd is restarted,
+			// there is nothing to resume
+			removePartialDownload(targetFn)
+		}
 		return err
 	}
 
@@ -608,6 +626,30 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	return nil
 }
 
+func (m *SnapManager) undoDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	snapsup, err := TaskSnapSetup(t)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+	// the download may have been interrupted and left a partial file to
+	// resume from
+	removePartialDownload(snapsup.MountFile())
+
+	return m.undoPrepareSnap(t, tomb)
+}
+
+// removePartialDownload removes the partial file left behind by a resumable
+// download to the given target.
+func removePartialDownload(targetFn string) {
+	partialFn := targetFn + ".partial"
+	if err := os.Remove(partialFn); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("cannot remove partial download %q: %v", partialFn, err)
+	}
+}
+
 var (
 	mountPollInterval = 1 * time.Second
 )
@@ -895,9 +937,14 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	quotaGrp, err := quotaGroup(st, snapsup.InstanceName())
+	if err != nil {
+		return err
+	}
 	linkCtx := backend.LinkContext{
 		FirstInstall: false,
 		VitalityRank: vitalityRank,
+		QuotaGroup:   quotaGrp,
 	}
 	reboot, err := m.backend.LinkSnap(oldInfo, deviceCtx, linkCtx, perfTimings)
 	if err != nil {
@@ -1082,6 +1129,15 @@ func missingDisabledServices(svcs []string, info *snap.Info) ([]string, []string
 	return foundSvcs, missingSvcs, nil
 }
 
+// quotaGroup returns the quota group the given snap belongs to, or nil if
+// the snap is not part of any group.
+func quotaGroup(st *state.State, instanceName string) (*quota.Group, error) {
+	if QuotaGroupForSnap == nil {
+		return nil, nil
+	}
+	return QuotaGroupForSnap(st, instanceName)
+}
+
 func vitalityRank(st *state.State, instanceName string) (rank int, err error) {
 	tr := config.NewTransaction(st)
 
@@ -1244,10 +1300,15 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) (err error) {
 	if err != nil {
 		return err
 	}
+	quotaGrp, err := quotaGroup(st, snapsup.InstanceName())
+	if err != nil {
+		return err
+	}
 	firstInstall := oldCurrent.Unset()
 	linkCtx := backend.LinkContext{
 		FirstInstall: firstInstall,
 		VitalityRank: vitalityRank,
+		QuotaGroup:   quotaGrp,
 	}
 	// on UC18+, snap tooling comes from the snapd snap so we need generated
 	// mount units to depend on the snapd snap mount units
@@ -1395,9 +1456,17 @@ func (m *SnapManager) maybeRestart(t *state.Task, info *snap.Info, rebootRequire
 
 	st := t.State()
 
+	reason := &state.RestartReason{
+		SnapName: info.InstanceName(),
+		SnapType: string(info.Type()),
+	}
+	if chg := t.Change(); chg != nil {
+		reason.ChangeID = chg.ID()
+	}
+
 	if rebootRequired {
 		t.Logf("Requested system restart.")
-		st.RequestRestart(state.RestartSystem)
+		st.RequestRestartWithReason(state.RestartSystem, reason)
 		return
 	}
 
@@ -1416,7 +1485,7 @@ func (m *SnapManager) maybeRestart(t *state.Task, info *snap.Info, rebootRequire
 	}
 
 	t.Logf(restartReason)
-	st.RequestRestart(state.RestartDaemon)
+	st.RequestRestartWithReason(state.RestartDaemon, reason)
 }
 
 func daemonRestartReason(st *state.State, typ snap.Type) string {
@@ -2134,9 +2203,14 @@ func (m *SnapManager) undoUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
+	quotaGrp, err := quotaGroup(st, snapsup.InstanceName())
+	if err != nil {
+		return err
+	}
 	linkCtx := backend.LinkContext{
 		FirstInstall: false,
 		VitalityRank: vitalityRank,
+		QuotaGroup:   quotaGrp,
 	}
 	reboot, err := m.backend.LinkSnap(info, deviceCtx, linkCtx, perfTimings)
 	if err != nil {
@@ -2248,6 +2322,15 @@ func (m *SnapManager) doDiscardSnap(t *state.Task, _ *tomb.Tomb) error {
 		if err != nil {
 			return err
 		}
+		if RemoveSnapFromQuota != nil {
+			if err := RemoveSnapFromQuota(st, snapsup.InstanceName()); err != nil {
+				return err
+			}
+		}
+		// a hold on the refreshes is not carried over to a new install
+		if err := UnholdRefresh(st, []string{snapsup.InstanceName()}); err != nil {
+			return err
+		}
 		err = m.backend.DiscardSnapNamespace(snapsup.InstanceName())
 		if err != nil {
 			t.Errorf("cannot discard snap namespace %q, will retry in 3 mins: %s", snapsup.InstanceName(), err)
@@ -20,6 +20,9 @@
 package snapstate_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
@@ -31,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/testutil"
 )
 
 type downloadSnapSuite struct {
@@ -167,6 +171,84 @@ func (s *downloadSnapSuite) TestDoDownloadSnapNormal(c *C) {
 	})
 }
 
+func (s *downloadSnapSuite) TestDoDownloadSnapKernelResumableParallel(c *C) {
+	s.state.Lock()
+
+	si := &snap.SideInfo{
+		RealName: "kernel",
+		SnapID:   "kernel-id",
+		Revision: snap.R(11),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si,
+		Type:     snap.TypeKernel,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
+
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(s.fakeStore.downloads, DeepEquals, []fakeDownload{
+		{
+			name:   "kernel",
+			target: filepath.Join(dirs.SnapBlobDir, "kernel_11.snap"),
+			opts: &store.DownloadOptions{
+				LeavePartialOnError: true,
+				Streams:             4,
+			},
+		},
+	})
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapKernelErrorRemovesPartial(c *C) {
+	s.fakeStore.downloadError = map[string]error{
+		"kernel": errors.New("download failed"),
+	}
+	partialFn := filepath.Join(dirs.SnapBlobDir, "kernel_11.snap.partial")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(partialFn, []byte("partial"), 0600), IsNil)
+
+	s.state.Lock()
+	si := &snap.SideInfo{
+		RealName: "kernel",
+		SnapID:   "kernel-id",
+		Revision: snap.R(11),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si,
+		Type:     snap.TypeKernel,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+		},
+	})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.se.Ensure()
+	s.se.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*download failed.*`)
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	// a failed download is not resumed, the partial file is gone
+	c.Check(partialFn, testutil.FileAbsent)
+}
+
 func (s *downloadSnapSuite) TestDoDownloadSnapWithDeviceContext(c *C) {
 	s.state.Lock()
 
@@ -233,6 +315,11 @@ func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 
 	s.state.Unlock()
 
+	// mock a partial download left behind by an interrupted download
+	partialFn := filepath.Join(dirs.SnapBlobDir, "foo_33.snap.partial")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(partialFn, []byte("partial"), 0600), IsNil)
+
 	for i := 0; i < 3; i++ {
 		s.se.Ensure()
 		s.se.Wait()
@@ -243,6 +330,8 @@ func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 
 	// task was undone
 	c.Check(t.Status(), Equals, state.UndoneStatus)
+	// and no partial download is left behind
+	c.Check(partialFn, testutil.FileAbsent)
 
 	// and nothing is in the state for "foo"
 	var snapst snapstate.SnapState
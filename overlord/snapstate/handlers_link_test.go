@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/bootloader"
 	"github.com/snapcore/snapd/bootloader/bootloadertest"
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -41,6 +42,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -662,6 +664,50 @@ func (s *linkSnapSuite) TestDoLinkSnapWithVitalityScore(c *C) {
 	c.Check(s.fakeBackend.ops, DeepEquals, expected)
 }
 
+func (s *linkSnapSuite) TestDoLinkSnapWithQuotaGroup(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	oldQuotaGroupForSnap := snapstate.QuotaGroupForSnap
+	snapstate.QuotaGroupForSnap = func(st *state.State, instanceName string) (*quota.Group, error) {
+		c.Check(instanceName, Equals, "foo")
+		return quota.NewGroup("foo-group", quantity.SizeMiB)
+	}
+	defer func() { snapstate.QuotaGroupForSnap = oldQuotaGroupForSnap }()
+
+	si := &snap.SideInfo{
+		RealName: "foo",
+		Revision: snap.R(33),
+	}
+	t := s.state.NewTask("link-snap", "test")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: si,
+	})
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
+
+	s.state.Unlock()
+
+	for i := 0; i < 6; i++ {
+		s.se.Ensure()
+		s.se.Wait()
+	}
+
+	s.state.Lock()
+	expected := fakeOps{
+		{
+			op:    "candidate",
+			sinfo: *si,
+		},
+		{
+			op:         "link-snap",
+			path:       filepath.Join(dirs.SnapMountDir, "foo/33"),
+			quotaGroup: "foo-group",
+		},
+	}
+	c.Check(s.fakeBackend.ops, DeepEquals, expected)
+}
+
 func (s *linkSnapSuite) TestDoLinkSnapTryToCleanupOnError(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -824,7 +870,8 @@ func (s *linkSnapSuite) TestDoLinkSnapSuccessRebootForCoreBase(c *C) {
 	t.Set("snap-setup", &snapstate.SnapSetup{
 		SideInfo: si,
 	})
-	s.state.NewChange("dummy", "...").AddTask(t)
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
 
 	s.state.Unlock()
 	s.se.Ensure()
@@ -835,6 +882,10 @@ func (s *linkSnapSuite) TestDoLinkSnapSuccessRebootForCoreBase(c *C) {
 	c.Check(s.stateBackend.restartRequested, DeepEquals, []state.RestartType{state.RestartSystem})
 	c.Assert(t.Log(), HasLen, 1)
 	c.Check(t.Log()[0], Matches, `.*INFO Requested system restart.*`)
+	reason := s.state.PendingRestartReason()
+	c.Assert(reason, NotNil)
+	c.Check(reason.ChangeID, Equals, chg.ID())
+	c.Check(reason.SnapName, Equals, "core18")
 }
 
 func (s *linkSnapSuite) TestDoLinkSnapSuccessSnapdRestartsOnClassic(c *C) {
@@ -0,0 +1,149 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// RefreshHold is a hold on auto-refreshes requested by the user. Unlike
+// refresh.hold, it is not limited by the max postponement of refreshes.
+type RefreshHold struct {
+	// Until is the time until which auto-refreshes are held, it is
+	// unset if they are held forever.
+	Until time.Time `json:"until,omitempty"`
+}
+
+// Forever returns whether auto-refreshes are held forever.
+func (h *RefreshHold) Forever() bool {
+	return h.Until.IsZero()
+}
+
+func (h *RefreshHold) activeAt(now time.Time) bool {
+	return h.Forever() || h.Until.After(now)
+}
+
+// refreshHolds is the state of the holds on auto-refreshes requested by the
+// user, both of all snaps and of single snaps.
+type refreshHolds struct {
+	System *RefreshHold            `json:"system,omitempty"`
+	Snaps  map[string]*RefreshHold `json:"snaps,omitempty"`
+}
+
+func getRefreshHolds(st *state.State) (*refreshHolds, error) {
+	var holds refreshHolds
+	if err := st.Get("refresh-holds", &holds); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	// expired holds are not relevant anymore
+	now := time.Now()
+	if holds.System != nil && !holds.System.activeAt(now) {
+		holds.System = nil
+	}
+	for name, hold := range holds.Snaps {
+		if !hold.activeAt(now) {
+			delete(holds.Snaps, name)
+		}
+	}
+	return &holds, nil
+}
+
+func setRefreshHolds(st *state.State, holds *refreshHolds) {
+	if holds.System == nil && len(holds.Snaps) == 0 {
+		st.Set("refresh-holds", nil)
+		return
+	}
+	st.Set("refresh-holds", holds)
+}
+
+// HoldRefresh holds the auto-refreshes of the given snaps, or of all snaps
+// when none are given, until the given time. A zero time holds them forever.
+func HoldRefresh(st *state.State, snaps []string, until time.Time) error {
+	if !until.IsZero() && !until.After(time.Now()) {
+		return fmt.Errorf("cannot hold refreshes until a time in the past: %s", until.Format(time.RFC3339))
+	}
+	holds, err := getRefreshHolds(st)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		holds.System = &RefreshHold{Until: until}
+		setRefreshHolds(st, holds)
+		return nil
+	}
+	for _, name := range snaps {
+		var snapst SnapState
+		if err := Get(st, name, &snapst); err != nil {
+			if err == state.ErrNoState {
+				return &snap.NotInstalledError{Snap: name}
+			}
+			return err
+		}
+	}
+	if holds.Snaps == nil {
+		holds.Snaps = make(map[string]*RefreshHold, len(snaps))
+	}
+	for _, name := range snaps {
+		holds.Snaps[name] = &RefreshHold{Until: until}
+	}
+	setRefreshHolds(st, holds)
+	return nil
+}
+
+// UnholdRefresh removes the holds on the auto-refreshes of the given snaps,
+// or the hold on the auto-refreshes of all snaps when none are given.
+func UnholdRefresh(st *state.State, snaps []string) error {
+	holds, err := getRefreshHolds(st)
+	if err != nil {
+		return err
+	}
+	if len(snaps) == 0 {
+		holds.System = nil
+	}
+	for _, name := range snaps {
+		delete(holds.Snaps, name)
+	}
+	setRefreshHolds(st, holds)
+	return nil
+}
+
+// SystemRefreshHold returns the hold on the auto-refreshes of all snaps
+// requested by the user, or nil if there is none.
+func SystemRefreshHold(st *state.State) (*RefreshHold, error) {
+	holds, err := getRefreshHolds(st)
+	if err != nil {
+		return nil, err
+	}
+	return holds.System, nil
+}
+
+// SnapRefreshHolds returns the holds on the auto-refreshes of single snaps
+// requested by the user, by snap name.
+func SnapRefreshHolds(st *state.State) (map[string]*RefreshHold, error) {
+	holds, err := getRefreshHolds(st)
+	if err != nil {
+		return nil, err
+	}
+	return holds.Snaps, nil
+}
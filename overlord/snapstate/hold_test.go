@@ -0,0 +1,134 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) setupHoldSnaps() {
+	for _, name := range []string{"some-snap", "some-other-snap"} {
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, SnapID: name + "-id", Revision: snap.R(2)},
+			},
+			Current:  snap.R(2),
+			SnapType: "app",
+		})
+	}
+}
+
+func (s *snapmgrTestSuite) TestHoldRefreshSnapsSkippedByAutoRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupHoldSnaps()
+
+	err := snapstate.HoldRefresh(s.state, []string{"some-snap"}, time.Time{})
+	c.Assert(err, IsNil)
+
+	holds, err := snapstate.SnapRefreshHolds(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(holds, HasLen, 1)
+	c.Check(holds["some-snap"].Forever(), Equals, true)
+	systemHold, err := snapstate.SystemRefreshHold(s.state)
+	c.Assert(err, IsNil)
+	c.Check(systemHold, IsNil)
+
+	updated, _, err := snapstate.AutoRefresh(context.Background(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"some-other-snap"})
+
+	// a manual refresh is not affected
+	updated, _, err = snapstate.UpdateMany(context.Background(), s.state, nil, 0, nil)
+	c.Assert(err, IsNil)
+	c.Check(updated, DeepEquals, []string{"some-other-snap", "some-snap"})
+
+	c.Assert(snapstate.UnholdRefresh(s.state, []string{"some-snap"}), IsNil)
+	holds, err = snapstate.SnapRefreshHolds(s.state)
+	c.Assert(err, IsNil)
+	c.Check(holds, HasLen, 0)
+	var raw interface{}
+	c.Check(s.state.Get("refresh-holds", &raw), NotNil)
+}
+
+func (s *snapmgrTestSuite) TestHoldRefreshSystem(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	until := time.Now().Add(72 * time.Hour)
+	err := snapstate.HoldRefresh(s.state, nil, until)
+	c.Assert(err, IsNil)
+
+	hold, err := snapstate.SystemRefreshHold(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(hold, NotNil)
+	c.Check(hold.Forever(), Equals, false)
+	c.Check(hold.Until.Equal(until), Equals, true)
+
+	c.Assert(snapstate.UnholdRefresh(s.state, nil), IsNil)
+	hold, err = snapstate.SystemRefreshHold(s.state)
+	c.Assert(err, IsNil)
+	c.Check(hold, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestHoldRefreshErrors(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupHoldSnaps()
+
+	err := snapstate.HoldRefresh(s.state, []string{"some-snap", "unknown-snap"}, time.Time{})
+	c.Check(err, ErrorMatches, `snap "unknown-snap" is not installed`)
+	holds, err := snapstate.SnapRefreshHolds(s.state)
+	c.Assert(err, IsNil)
+	c.Check(holds, HasLen, 0)
+
+	err = snapstate.HoldRefresh(s.state, []string{"some-snap"}, time.Now().Add(-time.Hour))
+	c.Check(err, ErrorMatches, `cannot hold refreshes until a time in the past: .*`)
+}
+
+func (s *snapmgrTestSuite) TestHoldRefreshExpired(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+	s.setupHoldSnaps()
+
+	past := time.Now().Add(-time.Hour)
+	s.state.Set("refresh-holds", map[string]interface{}{
+		"system": map[string]interface{}{"until": past},
+		"snaps": map[string]interface{}{
+			"some-snap":       map[string]interface{}{"until": past},
+			"some-other-snap": map[string]interface{}{},
+		},
+	})
+
+	hold, err := snapstate.SystemRefreshHold(s.state)
+	c.Assert(err, IsNil)
+	c.Check(hold, IsNil)
+	holds, err := snapstate.SnapRefreshHolds(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(holds, HasLen, 1)
+	c.Check(holds["some-other-snap"].Forever(), Equals, true)
+}
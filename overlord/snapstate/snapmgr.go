@@ -42,6 +42,7 @@ import (
 	"github.com/snapcore/snapd/sandbox"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/channel"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snapdenv"
 	"github.com/snapcore/snapd/store"
 )
@@ -289,6 +290,14 @@ var AutomaticSnapshot func(st *state.State, instanceName string) (ts *state.Task
 var AutomaticSnapshotExpiration func(st *state.State) (time.Duration, error)
 var EstimateSnapshotSize func(st *state.State, instanceName string, users []string) (uint64, error)
 
+// QuotaGroupForSnap allows to hook the service manager's lookup of the quota
+// group a snap belongs to.
+var QuotaGroupForSnap func(st *state.State, instanceName string) (*quota.Group, error)
+
+// RemoveSnapFromQuota allows to hook dropping a snap that is removed from the
+// system from its quota group.
+var RemoveSnapFromQuota func(st *state.State, instanceName string) error
+
 func readInfo(name string, si *snap.SideInfo, flags int) (*snap.Info, error) {
 	info, err := snapReadInfo(name, si)
 	if err != nil && flags&errorOnBroken != 0 {
@@ -426,7 +435,7 @@ func Manager(st *state.State, runner *state.TaskRunner) (*SnapManager, error) {
 	// remove anything that is not referenced anymore
 	runner.AddHandler("prerequisites", m.doPrerequisites, nil)
 	runner.AddHandler("prepare-snap", m.doPrepareSnap, m.undoPrepareSnap)
-	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoPrepareSnap)
+	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoDownloadSnap)
 	runner.AddHandler("mount-snap", m.doMountSnap, m.undoMountSnap)
 	runner.AddHandler("unlink-current-snap", m.doUnlinkCurrentSnap, m.undoUnlinkCurrentSnap)
 	runner.AddHandler("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
@@ -530,6 +539,14 @@ func (m *SnapManager) NextRefresh() time.Time {
 	return m.autoRefresh.NextRefresh()
 }
 
+// OnMeteredConnection returns whether auto-refresh found the connection
+// to be metered the last time it checked, which it does whenever a
+// refresh is due.
+// The caller should be holding the state lock.
+func (m *SnapManager) OnMeteredConnection() bool {
+	return m.autoRefresh.OnMeteredConnection()
+}
+
 // EffectiveRefreshHold returns the time until to which refreshes are
 // held if refresh.hold configuration is set and accounting for the
 // max postponement since the last refresh.
@@ -553,7 +570,8 @@ func (m *SnapManager) RefreshSchedule() (string, bool, error) {
 
 // EnsureAutoRefreshesAreDelayed will delay refreshes for the specified amount
 // of time, as well as return any active auto-refresh changes that are currently
-// not ready so that the client can wait for those.
+// not ready so that the client can wait for those. Auto-refreshes are then
+// held until ReleaseAutoRefreshDelay is called, for at most a day.
 func (m *SnapManager) EnsureAutoRefreshesAreDelayed(delay time.Duration) ([]*state.Change, error) {
 	// always delay for at least the specified time, this ensures that even if
 	// there are active refreshes right now, there won't be more auto-refreshes
@@ -574,6 +592,13 @@ func (m *SnapManager) EnsureAutoRefreshesAreDelayed(delay time.Duration) ([]*sta
 	return autoRefreshChgsInFlight, nil
 }
 
+// ReleaseAutoRefreshDelay releases the delay of auto-refreshes set by
+// EnsureAutoRefreshesAreDelayed and restores the previous refresh.hold,
+// unless it was changed in the meantime.
+func (m *SnapManager) ReleaseAutoRefreshDelay() error {
+	return m.autoRefresh.releaseRefreshHold()
+}
+
 // ensureForceDevmodeDropsDevmodeFromState undoes the forced devmode
 // in snapstate for forced devmode distros.
 func (m *SnapManager) ensureForceDevmodeDropsDevmodeFromState() error {
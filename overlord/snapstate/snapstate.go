@@ -32,6 +32,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/boot"
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/features"
 	"github.com/snapcore/snapd/gadget"
@@ -122,6 +123,26 @@ func optedIntoSnapdSnap(st *state.State) (bool, error) {
 	return experimentalAllowSnapd, nil
 }
 
+// retainedRevisions returns the number of revisions, including the current
+// one, kept around for a snap of the given type. The per type
+// refresh.retain-<type> setting takes precedence over the global
+// refresh.retain one.
+func retainedRevisions(st *state.State, typ snap.Type) int {
+	tr := config.NewTransaction(st)
+	var retain int
+	if err := tr.Get("core", "refresh.retain-"+string(typ), &retain); err == nil {
+		return retain
+	}
+	if err := tr.Get("core", "refresh.retain", &retain); err == nil {
+		return retain
+	}
+	// on classic we only keep 2 copies by default
+	if release.OnClassic {
+		return 2
+	}
+	return 3
+}
+
 func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int, fromChange string, inUseCheck func(snap.Type) (boot.InUseFunc, error)) (*state.TaskSet, error) {
 	// NB: we should strive not to need or propagate deviceCtx
 	// here, the resulting effects/changes were not pleasant at
@@ -309,6 +330,15 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 		prev = postRefreshHook
 	}
 
+	// only run the default-configure hook when installing the snap for
+	// the first time, so that it can prepare the configuration using the
+	// gadget defaults before the install hook and services run
+	if !snapst.IsInstalled() && flags&skipConfigure == 0 && isDefaultConfigureAllowed(snapsup) {
+		defaultConfigure := DefaultConfigure(st, snapsup.InstanceName())
+		addTask(defaultConfigure)
+		prev = defaultConfigure
+	}
+
 	var installHook *state.Task
 	// only run install hook if installing the snap for the first time
 	if !snapst.IsInstalled() {
@@ -324,15 +354,7 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 
 	// Do not do that if we are reverting to a local revision
 	if snapst.IsInstalled() && !snapsup.Flags.Revert {
-		var retain int
-		if err := config.NewTransaction(st).Get("core", "refresh.retain", &retain); err != nil {
-			// on classic we only keep 2 copies by default
-			if release.OnClassic {
-				retain = 2
-			} else {
-				retain = 3
-			}
-		}
+		retain := retainedRevisions(st, snapsup.Type)
 		retain-- //  we're adding one
 
 		seq := snapst.Sequence
@@ -399,6 +421,9 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	ts.AddAllWithEdges(installSet)
 	if checkAsserts != nil {
 		ts.MarkEdge(checkAsserts, DownloadAndChecksDoneEdge)
+	} else {
+		// local snaps are available once prepared
+		ts.MarkEdge(prepare, DownloadAndChecksDoneEdge)
 	}
 
 	if flags&skipConfigure != 0 {
@@ -429,6 +454,18 @@ func doInstall(st *state.State, snapst *SnapState, snapsup *SnapSetup, flags int
 	return ts, nil
 }
 
+// isDefaultConfigureAllowed returns whether the default-configure hook can
+// run for the snap, gadget defaults are only available for snaps with a snap
+// ID and configuration is not supported for bases and the "snapd" snap.
+func isDefaultConfigureAllowed(snapsup *SnapSetup) bool {
+	switch snapsup.Type {
+	case snap.TypeBase, snap.TypeSnapd, snap.TypeOS:
+		return false
+	}
+	hasSnapID := snapsup.SideInfo != nil && snapsup.SideInfo.SnapID != ""
+	return hasSnapID && snapsup.InstanceName() != "core"
+}
+
 // ConfigureSnap returns a set of tasks to configure snapName as done during installation/refresh.
 func ConfigureSnap(st *state.State, snapName string, confFlags int) *state.TaskSet {
 	// This is slightly ugly, ideally we would check the type instead
@@ -445,6 +482,10 @@ var Configure = func(st *state.State, snapName string, patch map[string]interfac
 	panic("internal error: snapstate.Configure is unset")
 }
 
+var DefaultConfigure = func(st *state.State, snapName string) *state.Task {
+	panic("internal error: snapstate.DefaultConfigure is unset")
+}
+
 var SetupInstallHook = func(st *state.State, snapName string) *state.Task {
 	panic("internal error: snapstate.SetupInstallHook is unset")
 }
@@ -700,6 +741,24 @@ func ensureInstallPreconditions(st *state.State, info *snap.Info, flags Flags, s
 // local revision and sideloading, or full metadata in which case it
 // the snap will appear as installed from the store.
 func InstallPath(st *state.State, si *snap.SideInfo, path, instanceName, channel string, flags Flags) (*state.TaskSet, *snap.Info, error) {
+	return installPath(st, si, path, instanceName, channel, 0, flags, nil, "")
+}
+
+// InstallPathWithDeviceContext returns a set of tasks for installing a snap
+// from a file path, using the given deviceCtx. If the snap is already
+// installed it is refreshed to the given file.
+// Note that the state must be locked by the caller.
+//
+// The returned TaskSet will contain a DownloadAndChecksDoneEdge.
+func InstallPathWithDeviceContext(st *state.State, si *snap.SideInfo, path, name string, opts *RevisionOptions, userID int, flags Flags, deviceCtx DeviceContext, fromChange string) (*state.TaskSet, error) {
+	if opts == nil {
+		opts = &RevisionOptions{}
+	}
+	ts, _, err := installPath(st, si, path, name, opts.Channel, userID, flags, deviceCtx, fromChange)
+	return ts, err
+}
+
+func installPath(st *state.State, si *snap.SideInfo, path, instanceName, channel string, userID int, flags Flags, providedDeviceCtx DeviceContext, fromChange string) (*state.TaskSet, *snap.Info, error) {
 	if si.RealName == "" {
 		return nil, nil, fmt.Errorf("internal error: snap name to install %q not provided", path)
 	}
@@ -708,7 +767,7 @@ func InstallPath(st *state.State, si *snap.SideInfo, path, instanceName, channel
 		instanceName = si.RealName
 	}
 
-	deviceCtx, err := DeviceCtxFromState(st, nil)
+	deviceCtx, err := DeviceCtxFromState(st, providedDeviceCtx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -776,9 +835,10 @@ func InstallPath(st *state.State, si *snap.SideInfo, path, instanceName, channel
 		Type:        info.Type(),
 		PlugsOnly:   len(info.Slots) == 0,
 		InstanceKey: info.InstanceKey,
+		UserID:      userID,
 	}
 
-	ts, err := doInstall(st, &snapst, snapsup, instFlags, "", inUseFor(deviceCtx))
+	ts, err := doInstall(st, &snapst, snapsup, instFlags, fromChange, inUseFor(deviceCtx))
 	return ts, info, err
 }
 
@@ -902,7 +962,11 @@ func InstallWithDeviceContext(ctx context.Context, st *state.State, name string,
 
 // InstallMany installs everything from the given list of names.
 // Note that the state must be locked by the caller.
-func InstallMany(st *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+func InstallMany(st *state.State, names []string, userID int, flags *Flags) ([]string, []*state.TaskSet, error) {
+	if flags == nil {
+		flags = &Flags{}
+	}
+
 	// need to have a model set before trying to talk the store
 	deviceCtx, err := DevicePastSeeding(st, nil)
 	if err != nil {
@@ -968,6 +1032,7 @@ func InstallMany(st *state.State, names []string, userID int) ([]string, []*stat
 		}
 	}
 
+	transactionLane := newTransactionLane(st, flags)
 	tasksets := make([]*state.TaskSet, 0, len(installs))
 	for _, sar := range installs {
 		info := sar.Info
@@ -1001,13 +1066,33 @@ func InstallMany(st *state.State, names []string, userID int) ([]string, []*stat
 		if err != nil {
 			return nil, nil, err
 		}
-		ts.JoinLane(st.NewLane())
+		joinTransactionLane(st, ts, transactionLane)
 		tasksets = append(tasksets, ts)
 	}
 
 	return toInstall, tasksets, nil
 }
 
+// newTransactionLane returns the lane shared by the tasks of all the snaps of
+// an all-snaps transaction, or 0 if each snap gets its own lane.
+func newTransactionLane(st *state.State, flags *Flags) int {
+	if flags.Transaction == client.TransactionAllSnaps {
+		return st.NewLane()
+	}
+	return 0
+}
+
+// joinTransactionLane has the tasks of a snap join the lane of the
+// transaction, or a lane of their own if transactionLane is 0, so that an
+// error undoes just the snap or all the snaps of the transaction.
+func joinTransactionLane(st *state.State, ts *state.TaskSet, transactionLane int) {
+	if transactionLane != 0 {
+		ts.JoinLane(transactionLane)
+		return
+	}
+	ts.JoinLane(st.NewLane())
+}
+
 // RefreshCandidates gets a list of candidates for update
 // Note that the state must be locked by the caller.
 func RefreshCandidates(st *state.State, user *auth.UserState) ([]*snap.Info, error) {
@@ -1131,6 +1216,7 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 	}
 
 	tasksets := make([]*state.TaskSet, 0, len(updates)+2) // 1 for auto-aliases, 1 for re-refresh
+	transactionLane := newTransactionLane(st, globalFlags)
 
 	refreshAll := len(names) == 0
 	var nameSet map[string]bool
@@ -1236,7 +1322,7 @@ func doUpdate(ctx context.Context, st *state.State, names []string, updates []*s
 			}
 			return nil, nil, err
 		}
-		ts.JoinLane(st.NewLane())
+		joinTransactionLane(st, ts, transactionLane)
 
 		// because of the sorting of updates we fill prereqs
 		// first (if branch) and only then use it to setup
@@ -1816,7 +1902,22 @@ func AutoRefresh(ctx context.Context, st *state.State) ([]string, []*state.TaskS
 		}
 	}
 
-	return UpdateMany(ctx, st, nil, userID, &Flags{IsAutoRefresh: true})
+	// snaps the user held are left alone
+	held, err := SnapRefreshHolds(st)
+	if err != nil {
+		return nil, nil, err
+	}
+	var filter updateFilter
+	if len(held) > 0 {
+		filter = func(info *snap.Info, _ *SnapState) bool {
+			if _, ok := held[info.InstanceName()]; ok {
+				logger.Noticef("Auto-refresh of snap %q is held", info.InstanceName())
+				return false
+			}
+			return true
+		}
+	}
+	return updateManyFiltered(ctx, st, nil, userID, filter, &Flags{IsAutoRefresh: true}, "")
 }
 
 // LinkNewBaseOrKernel will create prepare/link-snap tasks for a remodel
@@ -2321,6 +2422,16 @@ func RevertToRevision(st *state.State, name string, rev snap.Revision, flags Fla
 		return nil, err
 	}
 
+	// the revision we go back to must be able to read the data
+	// possibly migrated by the current one
+	curInfo, err := snapst.CurrentInfo()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Epoch.CanRead(curInfo.Epoch) {
+		return nil, fmt.Errorf("cannot revert %q to revision %s with epoch %s, because it can't read the current epoch of %s", name, rev, info.Epoch, curInfo.Epoch)
+	}
+
 	snapsup := &SnapSetup{
 		Base:        info.Base,
 		SideInfo:    snapst.Sequence[i],
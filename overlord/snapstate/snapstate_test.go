@@ -1418,6 +1418,30 @@ func (s *snapmgrTestSuite) TestRevertToRevisionAlreadyCurrent(c *C) {
 	c.Assert(ts, IsNil)
 }
 
+func (s *snapmgrTestSuite) TestRevertToRevisionCannotReadCurrentEpoch(c *C) {
+	si := snap.SideInfo{
+		RealName: "epoch-bump-snap",
+		Revision: snap.R(7),
+	}
+	si2 := snap.SideInfo{
+		RealName: "epoch-bump-snap",
+		Revision: snap.R(77),
+	}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "epoch-bump-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si, &si2},
+		Current:  snap.R(77),
+	})
+
+	ts, err := snapstate.RevertToRevision(s.state, "epoch-bump-snap", snap.R("7"), snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `cannot revert "epoch-bump-snap" to revision 7 with epoch 7, because it can't read the current epoch of 77`)
+	c.Assert(ts, IsNil)
+}
+
 func (s *snapmgrTestSuite) TestRevertRunThrough(c *C) {
 	si := snap.SideInfo{
 		RealName: "some-snap",
@@ -4186,6 +4210,25 @@ func (s *snapmgrTestSuite) TestSeqRetainConf(c *C) {
 	}
 }
 
+func (s *snapmgrTestSuite) TestSeqRetainConfPerType(c *C) {
+	revseq := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	for i := 2; i <= 10; i++ {
+		s.TearDownTest(c)
+		s.SetUpTest(c)
+		s.state.Lock()
+		tr := config.NewTransaction(s.state)
+		// the setting for the type of the snap takes precedence
+		tr.Set("core", "refresh.retain", 2)
+		tr.Set("core", "refresh.retain-app", i)
+		tr.Set("core", "refresh.retain-kernel", 10)
+		tr.Commit()
+		s.state.Unlock()
+
+		s.testUpdateSequence(c, &opSeqOpts{before: revseq[:9], current: 9, via: 10, after: revseq[10-i:]})
+	}
+}
+
 func (s *snapmgrTestSuite) TestSnapStateNoLocalRevision(c *C) {
 	si7 := snap.SideInfo{
 		RealName: "some-snap",
@@ -4374,6 +4417,7 @@ func (s *snapmgrTestSuite) TestTransitionCoreRunThrough(c *C) {
 		// the transition has no user associcated with it
 		macaroon: "",
 		target:   filepath.Join(dirs.SnapBlobDir, "core_11.snap"),
+		opts:     bootSnapDownloadOpts,
 	}})
 	expected := fakeOps{
 		{
@@ -6340,6 +6384,100 @@ func (s *snapmgrTestSuite) TestEnsureAutoRefreshesAreDelayed(c *C) {
 	c.Assert(chgs, DeepEquals, []*state.Change{chg0, chg1})
 }
 
+func (s *snapmgrTestSuite) TestReleaseAutoRefreshDelay(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// nothing to release
+	c.Assert(s.snapmgr.ReleaseAutoRefreshDelay(), IsNil)
+
+	_, err := s.snapmgr.EnsureAutoRefreshesAreDelayed(time.Minute)
+	c.Assert(err, IsNil)
+
+	var holdTime time.Time
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Get("core", "refresh.hold", &holdTime), IsNil)
+
+	c.Assert(s.snapmgr.ReleaseAutoRefreshDelay(), IsNil)
+
+	tr = config.NewTransaction(s.state)
+	err = tr.Get("core", "refresh.hold", &holdTime)
+	c.Assert(config.IsNoOption(err), Equals, true, Commentf("unexpected error: %v", err))
+}
+
+func (s *snapmgrTestSuite) TestReleaseAutoRefreshDelayKeepsChangedHold(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := s.snapmgr.EnsureAutoRefreshesAreDelayed(time.Minute)
+	c.Assert(err, IsNil)
+
+	// the hold was changed in the meantime
+	userHold := time.Now().Add(time.Hour).Truncate(time.Second)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "refresh.hold", userHold), IsNil)
+	tr.Commit()
+
+	c.Assert(s.snapmgr.ReleaseAutoRefreshDelay(), IsNil)
+
+	var holdTime time.Time
+	tr = config.NewTransaction(s.state)
+	c.Assert(tr.Get("core", "refresh.hold", &holdTime), IsNil)
+	c.Check(holdTime.Equal(userHold), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestReleaseAutoRefreshDelayRestoresPreviousHold(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("last-refresh", time.Now().Add(-12*time.Hour))
+	// the user held refreshes for a bit
+	userHold := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "refresh.hold", userHold), IsNil)
+	tr.Commit()
+
+	// the delay extends the hold, twice
+	_, err := s.snapmgr.EnsureAutoRefreshesAreDelayed(time.Hour)
+	c.Assert(err, IsNil)
+	_, err = s.snapmgr.EnsureAutoRefreshesAreDelayed(2 * time.Hour)
+	c.Assert(err, IsNil)
+
+	var holdTime time.Time
+	tr = config.NewTransaction(s.state)
+	c.Assert(tr.Get("core", "refresh.hold", &holdTime), IsNil)
+	c.Check(holdTime.After(userHold.Add(time.Hour)), Equals, true)
+
+	c.Assert(s.snapmgr.ReleaseAutoRefreshDelay(), IsNil)
+
+	// the hold of the user is back
+	tr = config.NewTransaction(s.state)
+	c.Assert(tr.Get("core", "refresh.hold", &holdTime), IsNil)
+	c.Check(holdTime.Equal(userHold), Equals, true)
+	var delay map[string]interface{}
+	c.Check(s.state.Get("refresh-delay", &delay), Equals, state.ErrNoState)
+}
+
+func (s *snapmgrTestSuite) TestReleaseAutoRefreshDelayExpiredPreviousHold(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the hold of the user expired
+	userHold := time.Now().Add(-10 * time.Minute)
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "refresh.hold", userHold), IsNil)
+	tr.Commit()
+
+	_, err := s.snapmgr.EnsureAutoRefreshesAreDelayed(time.Hour)
+	c.Assert(err, IsNil)
+	c.Assert(s.snapmgr.ReleaseAutoRefreshDelay(), IsNil)
+
+	var holdTime time.Time
+	tr = config.NewTransaction(s.state)
+	err = tr.Get("core", "refresh.hold", &holdTime)
+	c.Assert(config.IsNoOption(err), Equals, true, Commentf("unexpected error: %v", err))
+}
+
 func (s *snapmgrTestSuite) TestInstallModeDisableFreshInstall(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -115,9 +115,13 @@ type State struct {
 
 	cache map[interface{}]interface{}
 
-	restarting RestartType
-	restartLck sync.Mutex
-	bootID     string
+	restarting    RestartType
+	restartReason *RestartReason
+	restartLck    sync.Mutex
+	bootID        string
+
+	watchersMu     sync.Mutex
+	changeWatchers map[string][]chan struct{}
 }
 
 // New returns a new empty state.
@@ -252,6 +256,53 @@ func (s *State) Unlock() {
 	logger.Panicf("cannot checkpoint even after %v of retries every %v: %v", unlockCheckpointRetryMaxTime, unlockCheckpointRetryInterval, err)
 }
 
+// WatchChange returns a channel that receives a value whenever the
+// change with the given id or any of its tasks is updated (status,
+// progress or log). Updates coalesce: a pending notification is not
+// duplicated. The returned cancel function must be called once the
+// caller is no longer interested.
+func (s *State) WatchChange(id string) (updated <-chan struct{}, cancel func()) {
+	ch := make(chan struct{}, 1)
+	s.watchersMu.Lock()
+	if s.changeWatchers == nil {
+		s.changeWatchers = make(map[string][]chan struct{})
+	}
+	s.changeWatchers[id] = append(s.changeWatchers[id], ch)
+	s.watchersMu.Unlock()
+
+	cancel = func() {
+		s.watchersMu.Lock()
+		defer s.watchersMu.Unlock()
+		watchers := s.changeWatchers[id]
+		for i, w := range watchers {
+			if w == ch {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		if len(watchers) == 0 {
+			delete(s.changeWatchers, id)
+		} else {
+			s.changeWatchers[id] = watchers
+		}
+	}
+	return ch, cancel
+}
+
+func (s *State) notifyChangeUpdated(id string) {
+	if id == "" {
+		return
+	}
+	s.watchersMu.Lock()
+	defer s.watchersMu.Unlock()
+	for _, ch := range s.changeWatchers[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // EnsureBefore asks for an ensure pass to happen sooner within duration from now.
 func (s *State) EnsureBefore(d time.Duration) {
 	if s.backend != nil {
@@ -259,9 +310,25 @@ func (s *State) EnsureBefore(d time.Duration) {
 	}
 }
 
+// RestartReason describes why a restart was requested.
+type RestartReason struct {
+	// ChangeID is the ID of the change that requested the restart.
+	ChangeID string
+	// SnapName is the name of the snap whose change requires the restart.
+	SnapName string
+	// SnapType is the type of that snap, e.g. kernel or base.
+	SnapType string
+}
+
 // RequestRestart asks for a restart of the managing process.
 // The state needs to be locked to request a RestartSystem.
 func (s *State) RequestRestart(t RestartType) {
+	s.RequestRestartWithReason(t, nil)
+}
+
+// RequestRestartWithReason is like RequestRestart but also records why the
+// restart is needed, the reason can be retrieved with PendingRestartReason.
+func (s *State) RequestRestartWithReason(t RestartType, reason *RestartReason) {
 	if s.backend != nil {
 		if t == RestartSystem || t == RestartSystemNow {
 			if s.bootID == "" {
@@ -271,6 +338,7 @@ func (s *State) RequestRestart(t RestartType) {
 		}
 		s.restartLck.Lock()
 		s.restarting = t
+		s.restartReason = reason
 		s.restartLck.Unlock()
 		s.backend.RequestRestart(t)
 	}
@@ -283,6 +351,14 @@ func (s *State) Restarting() (bool, RestartType) {
 	return s.restarting != RestartUnset, s.restarting
 }
 
+// PendingRestartReason returns the reason given when the pending restart
+// was requested, or nil if none was given.
+func (s *State) PendingRestartReason() *RestartReason {
+	s.restartLck.Lock()
+	defer s.restartLck.Unlock()
+	return s.restartReason
+}
+
 var ErrExpectedReboot = errors.New("expected reboot did not happen")
 
 // VerifyReboot checks if the state remembers that a system restart was
@@ -488,6 +564,7 @@ func (s *State) Prune(startOfOperation time.Time, pruneWait, abortWait time.Dura
 			if spawnTime.Before(pruneLimit) && len(chg.Tasks()) == 0 {
 				chg.Abort()
 				delete(s.changes, chg.ID())
+				s.notifyChangeUpdated(chg.ID())
 			} else if spawnTime.Before(abortLimit) {
 				chg.Abort()
 			}
@@ -500,6 +577,7 @@ func (s *State) Prune(startOfOperation time.Time, pruneWait, abortWait time.Dura
 				delete(s.tasks, t.ID())
 			}
 			delete(s.changes, chg.ID())
+			s.notifyChangeUpdated(chg.ID())
 			readyChangesCount--
 		}
 	}
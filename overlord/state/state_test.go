@@ -820,6 +820,78 @@ func (ss *stateSuite) TestPruneEmptyChange(c *C) {
 	c.Assert(st.Change(chg.ID()), IsNil)
 }
 
+func (ss *stateSuite) TestWatchChange(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("download", "...")
+	chg.AddTask(t)
+	other := st.NewChange("remove", "...")
+
+	updated, cancel := st.WatchChange(chg.ID())
+
+	isNotified := func() bool {
+		select {
+		case <-updated:
+			return true
+		default:
+			return false
+		}
+	}
+	c.Check(isNotified(), Equals, false)
+
+	t.SetStatus(state.DoingStatus)
+	c.Check(isNotified(), Equals, true)
+
+	t.SetProgress("downloading", 1, 2)
+	c.Check(isNotified(), Equals, true)
+
+	t.Logf("halfway")
+	c.Check(isNotified(), Equals, true)
+
+	// updates coalesce
+	t.Errorf("oops")
+	chg.SetStatus(state.ErrorStatus)
+	c.Check(isNotified(), Equals, true)
+	c.Check(isNotified(), Equals, false)
+
+	// other changes do not notify
+	other.SetStatus(state.DoneStatus)
+	c.Check(isNotified(), Equals, false)
+
+	cancel()
+	t.SetStatus(state.DoneStatus)
+	c.Check(isNotified(), Equals, false)
+}
+
+func (ss *stateSuite) TestWatchChangePruned(c *C) {
+	st := state.New(&fakeStateBackend{})
+	st.Lock()
+	defer st.Unlock()
+
+	now := time.Now()
+	pruneWait := 1 * time.Hour
+	abortWait := 3 * time.Hour
+
+	chg := st.NewChange("abort", "...")
+	state.MockChangeTimes(chg, now.Add(-pruneWait), time.Time{})
+
+	updated, cancel := st.WatchChange(chg.ID())
+	defer cancel()
+
+	past := time.Now().AddDate(-1, 0, 0)
+	st.Prune(past, pruneWait, abortWait, 100)
+	c.Assert(st.Change(chg.ID()), IsNil)
+
+	select {
+	case <-updated:
+	default:
+		c.Fatal("watcher not notified of the pruned change")
+	}
+}
+
 func (ss *stateSuite) TestPruneMaxChangesHappy(c *C) {
 	st := state.New(&fakeStateBackend{})
 	st.Lock()
@@ -978,6 +1050,30 @@ func (ss *stateSuite) TestRequestRestart(c *C) {
 	ok, t = st.Restarting()
 	c.Check(ok, Equals, true)
 	c.Check(t, Equals, state.RestartDaemon)
+	c.Check(st.PendingRestartReason(), IsNil)
+}
+
+func (ss *stateSuite) TestRequestRestartWithReason(c *C) {
+	b := new(fakeStateBackend)
+	st := state.New(b)
+
+	st.Lock()
+	err := st.VerifyReboot("boot-id-1")
+	c.Assert(err, IsNil)
+	reason := &state.RestartReason{
+		ChangeID: "1",
+		SnapName: "pc-kernel",
+		SnapType: "kernel",
+	}
+	st.RequestRestartWithReason(state.RestartSystem, reason)
+	st.Unlock()
+
+	c.Check(b.restartRequested, Equals, true)
+
+	ok, t := st.Restarting()
+	c.Check(ok, Equals, true)
+	c.Check(t, Equals, state.RestartSystem)
+	c.Check(st.PendingRestartReason(), DeepEquals, reason)
 }
 
 func (ss *stateSuite) TestRequestRestartSystemAndVerifyReboot(c *C) {
@@ -208,6 +208,7 @@ func (t *Task) SetStatus(new Status) {
 	if chg != nil {
 		chg.taskStatusChanged(t, old, new)
 	}
+	t.state.notifyChangeUpdated(t.change)
 }
 
 // IsClean returns whether the task has been cleaned. See SetClean.
@@ -270,6 +271,7 @@ func (t *Task) SetProgress(label string, done, total int) {
 	} else {
 		t.progress = &progress{Label: label, Done: done, Total: total}
 	}
+	t.state.notifyChangeUpdated(t.change)
 }
 
 // SpawnTime returns the time when the change was created.
@@ -335,6 +337,7 @@ func (t *Task) addLog(kind, format string, args []interface{}) {
 	msg := fmt.Sprintf(tstr+" "+kind+" "+format, args...)
 	t.log = append(t.log, msg)
 	logger.Debugf(msg)
+	t.state.notifyChangeUpdated(t.change)
 }
 
 // Log returns the most recent messages logged into the task.
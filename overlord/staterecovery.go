@@ -0,0 +1,215 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package overlord
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	// sha3 hash for the integrity of the state backups
+	_ "golang.org/x/crypto/sha3"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var timeNow = time.Now
+
+// stateBackupPath returns the path of the last good backup of the state.
+func stateBackupPath(statePath string) string {
+	return statePath + ".bak"
+}
+
+func stateDigest(data []byte) string {
+	h := crypto.SHA3_384.New()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeStateBackup writes a backup of the state data, prefixed with a line
+// carrying the digest of the data so that the backup can be verified before
+// it gets used.
+func writeStateBackup(path string, data []byte) error {
+	var buf bytes.Buffer
+	buf.Grow(len(data) + 2*crypto.SHA3_384.Size() + 1)
+	buf.WriteString(stateDigest(data))
+	buf.WriteByte('\n')
+	buf.Write(data)
+	return osutil.AtomicWriteFile(path, buf.Bytes(), 0600, 0)
+}
+
+// readStateBackup returns the state data of a backup written by
+// writeStateBackup after verifying its integrity.
+func readStateBackup(path string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	idx := bytes.IndexByte(content, '\n')
+	if idx < 0 {
+		return nil, fmt.Errorf("state backup %q has no digest", path)
+	}
+	digest, data := string(content[:idx]), content[idx+1:]
+	if digest != stateDigest(data) {
+		return nil, fmt.Errorf("state backup %q is corrupted: digest mismatch", path)
+	}
+	return data, nil
+}
+
+// recoverState is used when the state at statePath cannot be read. It keeps
+// the unreadable state aside for inspection and falls back to the last good
+// backup of the state, the original error is returned when there is no
+// backup.
+func recoverState(backend state.Backend, statePath string, readErr error) (*state.State, error) {
+	backupPath := stateBackupPath(statePath)
+	data, err := readStateBackup(backupPath)
+	if os.IsNotExist(err) {
+		return nil, readErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v, and cannot recover from backup: %v", readErr, err)
+	}
+	s, err := state.ReadState(backend, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%v, and cannot recover from backup: %v", readErr, err)
+	}
+
+	corruptPath := fmt.Sprintf("%s.corrupt.%d", statePath, timeNow().Unix())
+	if err := os.Rename(statePath, corruptPath); err != nil {
+		return nil, fmt.Errorf("%v, and cannot move it aside: %v", readErr, err)
+	}
+	// put the recovered state in place right away, so that it is used
+	// again even if snapd stops before the next checkpoint
+	if err := osutil.AtomicWriteFile(statePath, data, 0600, 0); err != nil {
+		return nil, fmt.Errorf("%v, and cannot restore state from backup: %v", readErr, err)
+	}
+	logger.Noticef("%v, recovered state from backup, the unreadable state was moved to %q", readErr, corruptPath)
+
+	s.Lock()
+	defer s.Unlock()
+	s.Warnf("the state of snapd could not be read and was restored from a backup, recent changes to the system may have been lost")
+	if err := reconcileRecoveredState(s); err != nil {
+		return nil, fmt.Errorf("cannot reconcile state recovered from backup: %v", err)
+	}
+	return s, nil
+}
+
+// reconcileRecoveredState updates the snaps tracked by a state recovered
+// from a backup to match the snaps installed on disk, as the backup may
+// predate the last snap operations. Snaps which are installed but not tracked
+// cannot be reconstructed and are only warned about.
+func reconcileRecoveredState(s *state.State) error {
+	all, err := snapstate.All(s)
+	if err != nil {
+		return err
+	}
+	onDisk := make(map[string]string)
+	entries, err := ioutil.ReadDir(dirs.SnapMountDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		current, err := os.Readlink(filepath.Join(dirs.SnapMountDir, entry.Name(), "current"))
+		if err != nil {
+			// not a snap, or a snap which is not linked
+			continue
+		}
+		onDisk[entry.Name()] = current
+	}
+
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		snapst := all[name]
+		current, linked := onDisk[name]
+		delete(onDisk, name)
+		switch {
+		case !linked && !anyRevisionOnDisk(name, snapst):
+			snapstate.Set(s, name, nil)
+			s.Warnf("snap %q is tracked by the recovered state but is not installed, it was removed from the state", name)
+		case !linked:
+			if snapst.Active {
+				snapst.Active = false
+				snapstate.Set(s, name, snapst)
+				s.Warnf("snap %q is not active on the system, it was marked as disabled", name)
+			}
+		case current != snapst.Current.String():
+			rev, err := snap.ParseRevision(current)
+			if err != nil {
+				s.Warnf("snap %q is installed at unknown revision %q", name, current)
+				continue
+			}
+			if snapst.LastIndex(rev) < 0 {
+				si := &snap.SideInfo{
+					RealName: snap.InstanceSnap(name),
+					Revision: rev,
+				}
+				// the snap ID does not change between revisions
+				if n := len(snapst.Sequence); n > 0 {
+					si.SnapID = snapst.Sequence[n-1].SnapID
+				}
+				snapst.Sequence = append(snapst.Sequence, si)
+			}
+			s.Warnf("snap %q is installed at revision %s, the recovered state was updated from revision %s", name, rev, snapst.Current)
+			snapst.Current = rev
+			snapst.Active = true
+			snapstate.Set(s, name, snapst)
+		case !snapst.Active:
+			snapst.Active = true
+			snapstate.Set(s, name, snapst)
+			s.Warnf("snap %q is active on the system, it was marked as enabled", name)
+		}
+	}
+
+	names = names[:0]
+	for name := range onDisk {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.Warnf("snap %q is installed but is not tracked by the recovered state, it needs to be installed again", name)
+	}
+	return nil
+}
+
+// anyRevisionOnDisk returns whether any of the revisions of the snap tracked
+// by snapst is present on disk.
+func anyRevisionOnDisk(name string, snapst *snapstate.SnapState) bool {
+	for _, si := range snapst.Sequence {
+		if osutil.IsDirectory(filepath.Join(dirs.SnapMountDir, name, si.Revision.String())) {
+			return true
+		}
+	}
+	return false
+}
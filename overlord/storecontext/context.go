@@ -110,7 +110,45 @@ func (sc *storeContext) Device() (*auth.DeviceState, error) {
 	sc.state.Lock()
 	defer sc.state.Unlock()
 
-	return sc.deviceBackend.Device()
+	device, err := sc.deviceBackend.Device()
+	if err != nil {
+		return nil, err
+	}
+	if device.SessionMacaroon == "" {
+		return device, nil
+	}
+
+	// a device session is only valid with the store it was obtained
+	// from, drop it if the proxy store was switched since then so that
+	// a new one is requested
+	var sessionStoreID string
+	if err := sc.state.Get("device-session-store", &sessionStoreID); err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	proxyStoreID, err := sc.proxyStoreID()
+	if err != nil {
+		return nil, err
+	}
+	if sessionStoreID != proxyStoreID {
+		device.SessionMacaroon = ""
+		if err := sc.deviceBackend.SetDevice(device); err != nil {
+			return nil, err
+		}
+	}
+	return device, nil
+}
+
+// proxyStoreID returns the id of the proxy store if one is set, or an
+// empty string otherwise.
+func (sc *storeContext) proxyStoreID() (string, error) {
+	sto, err := sc.proxyStoreer.ProxyStore()
+	if err != nil && err != state.ErrNoState {
+		return "", err
+	}
+	if sto == nil {
+		return "", nil
+	}
+	return sto.Store(), nil
 }
 
 // UpdateDeviceAuth updates the device auth details in state.
@@ -133,10 +171,21 @@ func (sc *storeContext) UpdateDeviceAuth(device *auth.DeviceState, newSessionMac
 		return cur, nil
 	}
 
+	proxyStoreID, err := sc.proxyStoreID()
+	if err != nil {
+		return nil, err
+	}
+
 	cur.SessionMacaroon = newSessionMacaroon
 	if err := sc.deviceBackend.SetDevice(cur); err != nil {
 		return nil, fmt.Errorf("internal error: cannot update just read device state: %v", err)
 	}
+	// remember which store the session is for
+	if proxyStoreID == "" {
+		sc.state.Set("device-session-store", nil)
+	} else {
+		sc.state.Set("device-session-store", proxyStoreID)
+	}
 
 	return cur, nil
 }
@@ -182,6 +182,40 @@ func (s *storeCtxSuite) TestUpdateDeviceAuthOtherUpdate(c *C) {
 	})
 }
 
+func (s *storeCtxSuite) TestDeviceSessionKeptWithoutProxyStore(c *C) {
+	device := &auth.DeviceState{SessionMacaroon: "the-device-macaroon"}
+	storeCtx := storecontext.New(s.state, &testBackend{nothing: true, device: device})
+
+	deviceFromState, err := storeCtx.Device()
+	c.Check(err, IsNil)
+	c.Check(deviceFromState.SessionMacaroon, Equals, "the-device-macaroon")
+}
+
+func (s *storeCtxSuite) TestDeviceSessionDroppedOnProxyStoreSwitch(c *C) {
+	// the session was obtained before the proxy store was set
+	device := &auth.DeviceState{SessionMacaroon: "the-device-macaroon"}
+	b := &testBackend{device: device}
+	storeCtx := storecontext.New(s.state, b)
+
+	deviceFromState, err := storeCtx.Device()
+	c.Check(err, IsNil)
+	c.Check(deviceFromState.SessionMacaroon, Equals, "")
+	c.Check(b.device.SessionMacaroon, Equals, "")
+
+	// a session obtained from the proxy store is kept
+	_, err = storeCtx.UpdateDeviceAuth(deviceFromState, "the-proxy-device-macaroon")
+	c.Assert(err, IsNil)
+	deviceFromState, err = storeCtx.Device()
+	c.Check(err, IsNil)
+	c.Check(deviceFromState.SessionMacaroon, Equals, "the-proxy-device-macaroon")
+
+	// until the proxy store is unset
+	b.nothing = true
+	deviceFromState, err = storeCtx.Device()
+	c.Check(err, IsNil)
+	c.Check(deviceFromState.SessionMacaroon, Equals, "")
+}
+
 func (s *storeCtxSuite) TestStoreParamsFallback(c *C) {
 	storeCtx := storecontext.New(s.state, &testBackend{nothing: true})
 
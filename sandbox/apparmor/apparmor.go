@@ -125,6 +125,26 @@ func ParserFeatures() ([]string, error) {
 	return appArmorAssessment.ParserFeatures()
 }
 
+// PromptingSupported returns whether both the kernel and the apparmor
+// parser support prompt rules. If not, the reason is returned as well.
+func PromptingSupported() (bool, string) {
+	kernelFeatures, err := KernelFeatures()
+	if err != nil {
+		return false, fmt.Sprintf("cannot probe apparmor kernel features: %v", err)
+	}
+	if !strutil.ListContains(kernelFeatures, "policy:permstable32:prompt") {
+		return false, "apparmor kernel features do not support prompting"
+	}
+	parserFeatures, err := ParserFeatures()
+	if err != nil {
+		return false, fmt.Sprintf("cannot probe apparmor parser features: %v", err)
+	}
+	if !strutil.ListContains(parserFeatures, "prompt") {
+		return false, "apparmor parser does not support the prompt qualifier"
+	}
+	return true, ""
+}
+
 // ParserMtime returns the mtime of the AppArmor parser, else 0.
 func ParserMtime() int64 {
 	var mtime int64
@@ -312,6 +332,16 @@ func probeKernelFeatures() ([]string, error) {
 			features = append(features, fi.Name())
 		}
 	}
+	// the permissions supported by the policy are listed in a file
+	// rather than as separate directories, report them as
+	// policy:permstable32:<permission>
+	data, err := ioutil.ReadFile(filepath.Join(rootPath, featuresSysPath, "policy", "permstable32"))
+	if err == nil {
+		for _, perm := range strings.Fields(string(data)) {
+			features = append(features, "policy:permstable32:"+perm)
+		}
+		sort.Strings(features)
+	}
 	return features, nil
 }
 
@@ -320,10 +350,13 @@ func probeParserFeatures() ([]string, error) {
 	if err != nil {
 		return []string{}, err
 	}
-	features := make([]string, 0, 1)
+	features := make([]string, 0, 2)
 	if tryAppArmorParserFeature(parser, "change_profile unsafe /**,") {
 		features = append(features, "unsafe")
 	}
+	if tryAppArmorParserFeature(parser, "prompt /foo r,") {
+		features = append(features, "prompt")
+	}
 	sort.Strings(features)
 	return features, nil
 }
@@ -190,6 +190,13 @@ func (s *apparmorSuite) TestProbeAppArmorKernelFeatures(c *C) {
 	features, err = apparmor.ProbeKernelFeatures()
 	c.Assert(err, IsNil)
 	c.Check(features, DeepEquals, []string{"bar", "foo"})
+
+	// Pretend that the policy supports a set of permissions.
+	c.Assert(os.Mkdir(filepath.Join(d, featuresSysPath, "policy"), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(d, featuresSysPath, "policy", "permstable32"), []byte("allow deny prompt\n"), 0644), IsNil)
+	features, err = apparmor.ProbeKernelFeatures()
+	c.Assert(err, IsNil)
+	c.Check(features, DeepEquals, []string{"bar", "foo", "policy", "policy:permstable32:allow", "policy:permstable32:deny", "policy:permstable32:prompt"})
 }
 
 func (s *apparmorSuite) TestProbeAppArmorParserFeatures(c *C) {
@@ -200,22 +207,28 @@ func (s *apparmorSuite) TestProbeAppArmorParserFeatures(c *C) {
 		features []string
 	}{
 		{"exit 1", []string{}},
-		{"exit 0", []string{"unsafe"}},
+		{"exit 0", []string{"prompt", "unsafe"}},
+		{`if grep -q prompt %[1]s/stdin; then exit 1; fi`, []string{"unsafe"}},
 	}
 
 	for _, t := range testcases {
-		mockParserCmd := testutil.MockCommand(c, "apparmor_parser", fmt.Sprintf("cat > %s/stdin; %s", d, t.exit))
+		mockParserCmd := testutil.MockCommand(c, "apparmor_parser", fmt.Sprintf("cat > %[1]s/stdin; cat %[1]s/stdin >> %[1]s/stdin-all; "+t.exit, d))
 		defer mockParserCmd.Restore()
 		restore := apparmor.MockParserSearchPath(mockParserCmd.BinDir())
 		defer restore()
+		os.Remove(filepath.Join(d, "stdin-all"))
 
 		features, err := apparmor.ProbeParserFeatures()
 		c.Assert(err, IsNil)
 		c.Check(features, DeepEquals, t.features)
-		c.Check(mockParserCmd.Calls(), DeepEquals, [][]string{{"apparmor_parser", "--preprocess"}})
-		data, err := ioutil.ReadFile(filepath.Join(d, "stdin"))
+		c.Check(mockParserCmd.Calls(), DeepEquals, [][]string{
+			{"apparmor_parser", "--preprocess"},
+			{"apparmor_parser", "--preprocess"},
+		})
+		data, err := ioutil.ReadFile(filepath.Join(d, "stdin-all"))
 		c.Assert(err, IsNil)
-		c.Check(string(data), Equals, "profile snap-test {\n change_profile unsafe /**,\n}")
+		c.Check(string(data), Equals, "profile snap-test {\n change_profile unsafe /**,\n}"+
+			"profile snap-test {\n prompt /foo r,\n}")
 	}
 
 	// Pretend that we just don't have apparmor_parser at all.
@@ -247,7 +260,43 @@ func (s *apparmorSuite) TestInterfaceSystemKey(c *C) {
 	c.Check(features, DeepEquals, []string{"network", "policy"})
 	features, err = apparmor.ParserFeatures()
 	c.Assert(err, IsNil)
-	c.Check(features, DeepEquals, []string{"unsafe"})
+	c.Check(features, DeepEquals, []string{"prompt", "unsafe"})
+}
+
+func (s *apparmorSuite) TestPromptingSupported(c *C) {
+	for _, t := range []struct {
+		kernelFeatures []string
+		kernelErr      error
+		parserFeatures []string
+		parserErr      error
+		supported      bool
+		reason         string
+	}{{
+		kernelFeatures: []string{"policy", "policy:permstable32:prompt"},
+		parserFeatures: []string{"prompt", "unsafe"},
+		supported:      true,
+	}, {
+		kernelErr: fmt.Errorf("boom"),
+		reason:    "cannot probe apparmor kernel features: boom",
+	}, {
+		kernelFeatures: []string{"policy", "policy:permstable32:allow"},
+		parserFeatures: []string{"prompt", "unsafe"},
+		reason:         "apparmor kernel features do not support prompting",
+	}, {
+		kernelFeatures: []string{"policy", "policy:permstable32:prompt"},
+		parserErr:      fmt.Errorf("bang"),
+		reason:         "cannot probe apparmor parser features: bang",
+	}, {
+		kernelFeatures: []string{"policy", "policy:permstable32:prompt"},
+		parserFeatures: []string{"unsafe"},
+		reason:         "apparmor parser does not support the prompt qualifier",
+	}} {
+		restore := apparmor.MockFeatures(t.kernelFeatures, t.kernelErr, t.parserFeatures, t.parserErr)
+		supported, reason := apparmor.PromptingSupported()
+		restore()
+		c.Check(supported, Equals, t.supported)
+		c.Check(reason, Equals, t.reason)
+	}
 }
 
 func (s *apparmorSuite) TestAppArmorParserMtime(c *C) {
@@ -287,7 +336,7 @@ func (s *apparmorSuite) TestFeaturesProbedOnce(c *C) {
 	c.Check(features, DeepEquals, []string{"network", "policy"})
 	features, err = apparmor.ParserFeatures()
 	c.Assert(err, IsNil)
-	c.Check(features, DeepEquals, []string{"unsafe"})
+	c.Check(features, DeepEquals, []string{"prompt", "unsafe"})
 
 	// this makes probing fails but is not done again
 	err = os.RemoveAll(d)
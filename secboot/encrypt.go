@@ -20,12 +20,16 @@
 package secboot
 
 import (
+	"bytes"
 	"crypto/rand"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/osutil"
 )
 
@@ -99,3 +103,29 @@ func RecoveryKeyFromFile(recoveryKeyFile string) (*RecoveryKey, error) {
 	}
 	return &rkey, nil
 }
+
+// ChangeRecoveryKey replaces the recovery key oldKey of the encrypted volume on
+// the block device given by node with newKey.
+func ChangeRecoveryKey(node string, oldKey, newKey RecoveryKey) error {
+	// the new key is passed in a file only accessible by root on a tmpfs,
+	// the existing key through stdin
+	if err := os.MkdirAll(dirs.SnapRunDir, 0755); err != nil {
+		return err
+	}
+	tmpDir, err := ioutil.TempDir(dirs.SnapRunDir, "recovery-key-")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	newKeyFile := filepath.Join(tmpDir, "new-key")
+	if err := ioutil.WriteFile(newKeyFile, newKey[:], 0600); err != nil {
+		return fmt.Errorf("cannot write the new recovery key: %v", err)
+	}
+
+	cmd := exec.Command("cryptsetup", "luksChangeKey", "--key-file", "-", node, newKeyFile)
+	cmd.Stdin = bytes.NewReader(oldKey[:])
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cannot change the recovery key of %s: %v", node, osutil.OutputErr(output, err))
+	}
+	return nil
+}
@@ -21,11 +21,13 @@
 package secboot_test
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/secboot"
 	"github.com/snapcore/snapd/testutil"
 )
@@ -81,3 +83,43 @@ func (s *encryptSuite) TestEncryptionKeySave(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(di.Mode().Perm(), Equals, os.FileMode(0755))
 }
+
+func (s *encryptSuite) TestChangeRecoveryKey(c *C) {
+	dirs.SetRootDir(s.dir)
+	defer dirs.SetRootDir("/")
+
+	mockCryptsetup := testutil.MockCommand(c, "cryptsetup", `
+cat > "${0%/*}/old-key"
+cat "$5" > "${0%/*}/new-key"
+`)
+	defer mockCryptsetup.Restore()
+
+	oldKey := secboot.RecoveryKey{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	newKey := secboot.RecoveryKey{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	err := secboot.ChangeRecoveryKey("/dev/node", oldKey, newKey)
+	c.Assert(err, IsNil)
+
+	c.Assert(mockCryptsetup.Calls(), HasLen, 1)
+	call := mockCryptsetup.Calls()[0]
+	c.Check(call[:5], DeepEquals, []string{"cryptsetup", "luksChangeKey", "--key-file", "-", "/dev/node"})
+	c.Check(filepath.Dir(call[5]), testutil.FileAbsent)
+
+	binDir := mockCryptsetup.BinDir()
+	c.Check(filepath.Join(binDir, "old-key"), testutil.FileEquals, oldKey[:])
+	c.Check(filepath.Join(binDir, "new-key"), testutil.FileEquals, newKey[:])
+}
+
+func (s *encryptSuite) TestChangeRecoveryKeyError(c *C) {
+	dirs.SetRootDir(s.dir)
+	defer dirs.SetRootDir("/")
+
+	mockCryptsetup := testutil.MockCommand(c, "cryptsetup", "echo 'No key available'; exit 2")
+	defer mockCryptsetup.Restore()
+
+	err := secboot.ChangeRecoveryKey("/dev/node", secboot.RecoveryKey{}, secboot.RecoveryKey{1})
+	c.Assert(err, ErrorMatches, "cannot change the recovery key of /dev/node: No key available")
+
+	entries, err := ioutil.ReadDir(dirs.SnapRunDir)
+	c.Assert(err, IsNil)
+	c.Check(entries, HasLen, 0)
+}
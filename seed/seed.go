@@ -23,6 +23,8 @@ package seed
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -118,6 +120,12 @@ type Seed interface {
 	ModeSnaps(mode string) ([]*Snap, error)
 }
 
+// ValidateUC20SeedSystemLabel checks whether the string is a valid UC20 seed
+// system label.
+func ValidateUC20SeedSystemLabel(label string) error {
+	return internal.ValidateUC20SeedSystemLabel(label)
+}
+
 // Open returns a Seed implementation for the seed at seedDir.
 // label if not empty is used to identify a Core 20 recovery system seed.
 func Open(seedDir, label string) (Seed, error) {
@@ -130,6 +138,31 @@ func Open(seedDir, label string) (Seed, error) {
 	return &seed16{seedDir: seedDir}, nil
 }
 
+// ListSystems returns the sorted labels of the Core 20 recovery
+// system seeds found under seedDir, without loading any of them.
+// Entries under systems/ that are not directories or that do not
+// have a valid system label are skipped.
+func ListSystems(seedDir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(seedDir, "systems"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	labels := make([]string, 0, len(entries))
+	for _, fi := range entries {
+		if !fi.IsDir() {
+			continue
+		}
+		if err := internal.ValidateUC20SeedSystemLabel(fi.Name()); err != nil {
+			continue
+		}
+		labels = append(labels, fi.Name())
+	}
+	return labels, nil
+}
+
 // ReadSystemEssential retrieves in one go information about the model
 // and essential snaps of the given types for the Core 20 recovery
 // system seed specified by seedDir and label (which cannot be empty).
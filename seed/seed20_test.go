@@ -21,6 +21,7 @@ package seed_test
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -2094,3 +2095,20 @@ func (s *seed20Suite) TestOpenInvalidLabel(c *C) {
 		c.Assert(seed20, IsNil)
 	}
 }
+
+func (s *seed20Suite) TestListSystems(c *C) {
+	// no systems
+	labels, err := seed.ListSystems(s.SeedDir)
+	c.Assert(err, IsNil)
+	c.Check(labels, HasLen, 0)
+
+	for _, label := range []string{"20210302", "20191018", ":invalid:"} {
+		c.Assert(os.MkdirAll(filepath.Join(s.SeedDir, "systems", label), 0755), IsNil)
+	}
+	// not a directory
+	c.Assert(ioutil.WriteFile(filepath.Join(s.SeedDir, "systems", "20200101"), nil, 0644), IsNil)
+
+	labels, err = seed.ListSystems(s.SeedDir)
+	c.Assert(err, IsNil)
+	c.Check(labels, DeepEquals, []string{"20191018", "20210302"})
+}
@@ -511,7 +511,7 @@ func (w *Writer) InfoDerived() error {
 			// this check is here in case we relax the checks in
 			// SetOptionsSnaps
 			if err := w.policy.allowsDangerousFeatures(); err != nil {
-				return err
+				return fmt.Errorf("cannot use unasserted local snap %q from %s: %v", sn.Info.SnapName(), sn.Path, err)
 			}
 		}
 
@@ -1111,13 +1111,13 @@ func (w *Writer) checkSnapsAccessor() error {
 
 // BootSnaps returns the seed snaps involved in the boot process.
 // It can be invoked only after Downloaded returns complete ==
-// true. It returns an error for classic models as for those no snaps
-// participate in boot before user space.
+// true. It returns an error for classic models without modes as for
+// those no snaps participate in boot before user space.
 func (w *Writer) BootSnaps() ([]*SeedSnap, error) {
 	if err := w.checkSnapsAccessor(); err != nil {
 		return nil, err
 	}
-	if w.model.Classic() {
+	if w.model.Classic() && w.model.Grade() == asserts.ModelGradeUnset {
 		return nil, fmt.Errorf("no snaps participating in boot on classic")
 	}
 	var bootSnaps []*SeedSnap
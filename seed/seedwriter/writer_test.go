@@ -25,6 +25,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
@@ -1754,6 +1755,63 @@ func (s *writerSuite) TestSeedSnapsWriteMetaLocalExtraSnaps(c *C) {
 	c.Check(naming.SameSnap(unassertedSnaps[0], naming.Snap("required")), Equals, true)
 }
 
+func (s *writerSuite) TestBootSnapsClassicWithModes(c *C) {
+	model := s.Brands.Model("my-brand", "my-model", map[string]interface{}{
+		"display-name": "my model",
+		"classic":      "true",
+		"distribution": "ubuntu",
+		"architecture": "amd64",
+		"base":         "core20",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name":            "pc-kernel",
+				"id":              s.AssertedSnapID("pc-kernel"),
+				"type":            "kernel",
+				"default-channel": "20",
+			},
+			map[string]interface{}{
+				"name":            "pc",
+				"id":              s.AssertedSnapID("pc"),
+				"type":            "gadget",
+				"default-channel": "20",
+			},
+		},
+	})
+
+	s.makeSnap(c, "snapd", "")
+	s.makeSnap(c, "core20", "")
+	s.makeSnap(c, "pc-kernel=20", "")
+	s.makeSnap(c, "pc=20", "")
+
+	s.opts.Label = "20191003"
+	w, err := seedwriter.New(model, s.opts)
+	c.Assert(err, IsNil)
+
+	_, err = w.Start(s.db, s.newFetcher)
+	c.Assert(err, IsNil)
+
+	snaps, err := w.SnapsToDownload()
+	c.Assert(err, IsNil)
+	c.Check(snaps, HasLen, 4)
+	for _, sn := range snaps {
+		s.fillDownloadedSnap(c, w, sn)
+	}
+
+	complete, err := w.Downloaded()
+	c.Assert(err, IsNil)
+	c.Check(complete, Equals, true)
+
+	// unlike for classic models without modes the boot snaps are
+	// those of a UC20 system
+	bootSnaps, err := w.BootSnaps()
+	c.Assert(err, IsNil)
+	c.Assert(bootSnaps, HasLen, 4)
+	c.Check(naming.SameSnap(bootSnaps[0], naming.Snap("snapd")), Equals, true)
+	c.Check(naming.SameSnap(bootSnaps[1], naming.Snap("pc-kernel")), Equals, true)
+	c.Check(naming.SameSnap(bootSnaps[2], naming.Snap("core20")), Equals, true)
+	c.Check(naming.SameSnap(bootSnaps[3], naming.Snap("pc")), Equals, true)
+}
+
 func (s *writerSuite) TestSeedSnapsWriteMetaCore20(c *C) {
 	// add store assertion
 	storeAs, err := s.StoreSigning.Sign(asserts.StoreType, map[string]interface{}{
@@ -2370,7 +2428,7 @@ func (s *writerSuite) TestCore20NonDangerousDisallowedOptionsSnaps(c *C) {
 			}
 
 			err = w.InfoDerived()
-			c.Check(err, ErrorMatches, expectedErr)
+			c.Check(err, ErrorMatches, fmt.Sprintf(`cannot use unasserted local snap "pc" from %s: %s`, regexp.QuoteMeta(pcFn), expectedErr))
 			continue
 		}
 
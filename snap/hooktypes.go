@@ -27,6 +27,7 @@ var supportedHooks = []*HookType{
 	NewHookType(regexp.MustCompile("^prepare-device$")),
 	NewHookType(regexp.MustCompile("^install-device$")),
 	NewHookType(regexp.MustCompile("^configure$")),
+	NewHookType(regexp.MustCompile("^default-configure$")),
 	NewHookType(regexp.MustCompile("^install$")),
 	NewHookType(regexp.MustCompile("^pre-refresh$")),
 	NewHookType(regexp.MustCompile("^post-refresh$")),
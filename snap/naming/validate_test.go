@@ -87,6 +87,16 @@ func (s *ValidateSuite) TestValidateName(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateQuotaGroup(c *C) {
+	for _, name := range []string{"aa", "a-a", "iot-group", "0a", "group1"} {
+		c.Check(naming.ValidateQuotaGroup(name), IsNil)
+	}
+	for _, name := range []string{"", "a", "a--a", "a-", "-a", "A", "a a", "123", "a_b",
+		"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"} {
+		c.Check(naming.ValidateQuotaGroup(name), ErrorMatches, `invalid quota group name: ".*"`)
+	}
+}
+
 func (s *ValidateSuite) TestValidateInstanceName(c *C) {
 	validNames := []string{
 		// plain names are also valid instance names
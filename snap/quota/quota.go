@@ -0,0 +1,92 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package quota defines the quota groups which are used to limit the
+// resources used by the services of a set of snaps.
+package quota
+
+import (
+	"fmt"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/snap/naming"
+	"github.com/snapcore/snapd/systemd"
+)
+
+// MinMemoryLimit is the smallest memory limit a quota group can have.
+const MinMemoryLimit = 4 * quantity.SizeKiB
+
+// Group is a quota group, limiting the resources that the services of the
+// snaps in the group can use altogether.
+type Group struct {
+	// Name is the name of the quota group.
+	Name string `json:"name"`
+
+	// MemoryLimit is the maximum amount of memory the services of the
+	// snaps in the group can use.
+	MemoryLimit quantity.Size `json:"memory-limit"`
+
+	// Snaps is the list of snaps in the group.
+	Snaps []string `json:"snaps,omitempty"`
+}
+
+// NewGroup creates a new quota group with the given name and memory limit.
+func NewGroup(name string, memoryLimit quantity.Size) (*Group, error) {
+	grp := &Group{
+		Name:        name,
+		MemoryLimit: memoryLimit,
+	}
+	if err := grp.Validate(); err != nil {
+		return nil, err
+	}
+	return grp, nil
+}
+
+// Validate checks that the name and the memory limit of the group are valid.
+func (grp *Group) Validate() error {
+	if err := naming.ValidateQuotaGroup(grp.Name); err != nil {
+		return err
+	}
+	if grp.MemoryLimit == 0 {
+		return fmt.Errorf("quota group must have a memory limit set")
+	}
+	if grp.MemoryLimit < MinMemoryLimit {
+		min := MinMemoryLimit
+		return fmt.Errorf("memory limit %d is too small: size must be at least %s", grp.MemoryLimit, min.IECString())
+	}
+	return nil
+}
+
+// SliceFileName returns the name of the systemd slice unit of the group.
+func (grp *Group) SliceFileName() string {
+	// a dash in a slice name denotes the hierarchy of slices, so the name
+	// needs to be escaped
+	return "snap." + systemd.EscapeUnitNamePath(grp.Name) + ".slice"
+}
+
+// CurrentMemoryUsage returns the memory currently used by the services of the
+// snaps in the group.
+func (grp *Group) CurrentMemoryUsage() (quantity.Size, error) {
+	sysd := systemd.New(systemd.SystemMode, nil)
+	mem, err := sysd.CurrentMemoryUsage(grp.SliceFileName())
+	if err != nil {
+		return 0, fmt.Errorf("cannot get memory usage of quota group %q: %v", grp.Name, err)
+	}
+	return mem, nil
+}
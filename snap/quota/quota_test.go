@@ -0,0 +1,80 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package quota_test
+
+import (
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type quotaTestSuite struct{}
+
+var _ = Suite(&quotaTestSuite{})
+
+func (s *quotaTestSuite) TestNewGroup(c *C) {
+	grp, err := quota.NewGroup("iot-group", quantity.SizeMiB)
+	c.Assert(err, IsNil)
+	c.Check(grp.Name, Equals, "iot-group")
+	c.Check(grp.MemoryLimit, Equals, quantity.SizeMiB)
+	c.Check(grp.Snaps, HasLen, 0)
+	c.Check(grp.SliceFileName(), Equals, `snap.iot\x2dgroup.slice`)
+}
+
+func (s *quotaTestSuite) TestNewGroupInvalid(c *C) {
+	for _, tc := range []struct {
+		name  string
+		limit quantity.Size
+		err   string
+	}{
+		{"Bad_Name", quantity.SizeMiB, `invalid quota group name: "Bad_Name"`},
+		{"", quantity.SizeMiB, `invalid quota group name: ""`},
+		{"grp", 0, `quota group must have a memory limit set`},
+		{"grp", 1024, `memory limit 1024 is too small: size must be at least 4 KiB`},
+	} {
+		_, err := quota.NewGroup(tc.name, tc.limit)
+		c.Check(err, ErrorMatches, tc.err)
+	}
+}
+
+func (s *quotaTestSuite) TestCurrentMemoryUsage(c *C) {
+	var calls [][]string
+	restore := systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte("MemoryCurrent=2048\n"), nil
+	})
+	defer restore()
+
+	grp, err := quota.NewGroup("grp", quantity.SizeMiB)
+	c.Assert(err, IsNil)
+
+	mem, err := grp.CurrentMemoryUsage()
+	c.Assert(err, IsNil)
+	c.Check(mem, Equals, 2*quantity.SizeKiB)
+	c.Check(calls, DeepEquals, [][]string{
+		{"show", "--property", "MemoryCurrent", "snap.grp.slice"},
+	})
+}
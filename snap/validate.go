@@ -357,6 +357,10 @@ func Validate(info *Info) error {
 			return err
 		}
 	}
+	// the default-configure hook complements the configure hook
+	if info.Hooks["default-configure"] != nil && info.Hooks["configure"] == nil {
+		return fmt.Errorf(`cannot specify "default-configure" hook without "configure" hook`)
+	}
 
 	// Ensure that plugs and slots have appropriate names and interface names.
 	if err := plugsSlotsInterfacesNames(info); err != nil {
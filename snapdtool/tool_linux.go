@@ -20,6 +20,7 @@
 package snapdtool
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -34,9 +35,11 @@ import (
 )
 
 // The SNAP_REEXEC environment variable controls whether the command
-// will attempt to re-exec itself from inside an ubuntu-core snap
-// present on the system. If not present in the environ it's assumed
-// to be set to 1 (do re-exec); that is: set it to 0 to disable.
+// will attempt to re-exec itself from inside the snapd snap, or the core
+// snap when there is no suitable snapd snap, present on the system. If
+// not present in the environ it's assumed to be set to 1 (do re-exec);
+// that is: set it to 0 to disable and always use the binaries of the
+// distribution package.
 const reExecKey = "SNAP_REEXEC"
 
 var (
@@ -96,6 +99,57 @@ func coreSupportsReExec(coreOrSnapdPath string) bool {
 	return true
 }
 
+// checkReExecTarget checks that the re-exec target is a regular
+// executable file that is really shipped by the snap mounted at
+// snapRoot, i.e. not a symlink pointing somewhere outside of it.
+func checkReExecTarget(snapRoot, target string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(snapRoot)
+	if err != nil {
+		return err
+	}
+	resolved, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(resolved, resolvedRoot+"/") {
+		return fmt.Errorf("%q is outside of %q", resolved, resolvedRoot)
+	}
+	fi, err := os.Stat(resolved)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%q is not a regular file", resolved)
+	}
+	if fi.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("%q is not executable", resolved)
+	}
+	return nil
+}
+
+// reExecTarget returns the path of the given executable in the snapd
+// snap, or in the core snap if the snapd snap cannot be used, to re-exec
+// into. An empty string is returned if neither can be used.
+func reExecTarget(exe string) string {
+	for _, snapRoot := range []string{snapdSnap, coreSnap} {
+		full := filepath.Join(snapRoot, exe)
+		if !osutil.FileExists(full) {
+			continue
+		}
+		// ensure we do not use an older snapd than the distribution
+		// package, the core snap may still be recent enough
+		if !coreSupportsReExec(snapRoot) {
+			continue
+		}
+		if err := checkReExecTarget(snapRoot, full); err != nil {
+			logger.Noticef("cannot re-exec into %q: %v", full, err)
+			continue
+		}
+		return full
+	}
+	return ""
+}
+
 // InternalToolPath returns the path of an internal snapd tool. The tool
 // *must* be located inside the same tree as the current binary.
 //
@@ -150,6 +204,13 @@ func mustUnsetenv(key string) {
 
 // ExecInSnapdOrCoreSnap makes sure you're executing the binary that ships in
 // the snapd/core snap.
+//
+// It is meant to be called first thing in main() by the snapd binaries
+// that the distribution package shares with the snapd snap. It is a
+// no-op if re-exec is disabled via SNAP_REEXEC=0, outside of classic
+// systems of the distributions that support re-exec, or if there is no
+// snapd or core snap at least as recent as the running binary. It must
+// not be used by snap-bootstrap, which runs from the initramfs.
 func ExecInSnapdOrCoreSnap() {
 	// Which executable are we?
 	exe, err := os.Readlink(selfExe)
@@ -185,19 +246,10 @@ func ExecInSnapdOrCoreSnap() {
 		return
 	}
 
-	// Is this executable in the core snap too?
-	coreOrSnapdPath := snapdSnap
-	full := filepath.Join(snapdSnap, exe)
-	if !osutil.FileExists(full) {
-		coreOrSnapdPath = coreSnap
-		full = filepath.Join(coreSnap, exe)
-		if !osutil.FileExists(full) {
-			return
-		}
-	}
-
-	// If the core snap doesn't support re-exec or run-from-core then don't do it.
-	if !coreSupportsReExec(coreOrSnapdPath) {
+	// Is this executable in the snapd or core snap too, and can it be
+	// used?
+	full := reExecTarget(exe)
+	if full == "" {
 		return
 	}
 
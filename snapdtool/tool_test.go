@@ -358,6 +358,63 @@ func (s *toolSuite) TestExecInOldCoreSnap(c *C) {
 	c.Check(s.lastExecArgv, DeepEquals, os.Args)
 }
 
+func (s *toolSuite) TestExecInCoreSnapWhenSnapdSnapTooOld(c *C) {
+	defer s.mockReExecFor(c, s.snapdPath, "potato")()
+	s.fakeInternalTool(c, s.corePath, "potato")
+
+	// the snapd snap is older than the distribution package
+	s.fakeCoreVersion(c, s.snapdPath, "1")
+
+	c.Check(snapdtool.ExecInSnapdOrCoreSnap, PanicMatches, `>exec of "[^"]+/potato" in tests<`)
+	c.Check(s.execCalled, Equals, 1)
+	c.Check(s.lastExecArgv0, Equals, filepath.Join(s.corePath, "/usr/lib/snapd/potato"))
+}
+
+func (s *toolSuite) TestExecInSnapdOrCoreSnapBailsNotExecutable(c *C) {
+	defer s.mockReExecFor(c, s.snapdPath, "potato")()
+
+	c.Assert(os.Chmod(filepath.Join(s.snapdPath, "/usr/lib/snapd/potato"), 0644), IsNil)
+
+	snapdtool.ExecInSnapdOrCoreSnap()
+	c.Check(s.execCalled, Equals, 0)
+}
+
+func (s *toolSuite) TestExecInSnapdOrCoreSnapBailsNotRegular(c *C) {
+	defer s.mockReExecFor(c, s.snapdPath, "potato")()
+
+	p := filepath.Join(s.snapdPath, "/usr/lib/snapd/potato")
+	c.Assert(os.Remove(p), IsNil)
+	c.Assert(os.Mkdir(p, 0755), IsNil)
+
+	snapdtool.ExecInSnapdOrCoreSnap()
+	c.Check(s.execCalled, Equals, 0)
+}
+
+func (s *toolSuite) TestExecInSnapdOrCoreSnapBailsSymlinkOutsideOfSnap(c *C) {
+	defer s.mockReExecFor(c, s.snapdPath, "potato")()
+
+	outside := filepath.Join(c.MkDir(), "potato")
+	c.Assert(ioutil.WriteFile(outside, nil, 0755), IsNil)
+	p := filepath.Join(s.snapdPath, "/usr/lib/snapd/potato")
+	c.Assert(os.Remove(p), IsNil)
+	c.Assert(os.Symlink(outside, p), IsNil)
+
+	snapdtool.ExecInSnapdOrCoreSnap()
+	c.Check(s.execCalled, Equals, 0)
+}
+
+func (s *toolSuite) TestExecInSnapdOrCoreSnapSymlinkInsideOfSnap(c *C) {
+	defer s.mockReExecFor(c, s.snapdPath, "potato")()
+
+	p := filepath.Join(s.snapdPath, "/usr/lib/snapd/potato")
+	c.Assert(os.Rename(p, p+".real"), IsNil)
+	c.Assert(os.Symlink("potato.real", p), IsNil)
+
+	c.Check(snapdtool.ExecInSnapdOrCoreSnap, PanicMatches, `>exec of "[^"]+/potato" in tests<`)
+	c.Check(s.execCalled, Equals, 1)
+	c.Check(s.lastExecArgv0, Equals, p)
+}
+
 func (s *toolSuite) TestExecInSnapdOrCoreSnapBailsNoCoreSupport(c *C) {
 	defer s.mockReExecFor(c, s.snapdPath, "potato")()
 
@@ -139,6 +139,14 @@ func MockDownload(f func(ctx context.Context, name, sha3_384, downloadURL string
 	}
 }
 
+func MockMinParallelDownloadSize(size int64) (restore func()) {
+	old := minParallelDownloadSize
+	minParallelDownloadSize = size
+	return func() {
+		minParallelDownloadSize = old
+	}
+}
+
 func MockDoDownloadReq(f func(ctx context.Context, storeURL *url.URL, cdnHeader string, resume int64, s *Store, user *auth.UserState) (*http.Response, error)) (restore func()) {
 	orig := doDownloadReq
 	doDownloadReq = f
@@ -141,8 +141,20 @@ type DownloadOptions struct {
 	RateLimit           int64
 	IsAutoRefresh       bool
 	LeavePartialOnError bool
+	// Streams is the number of ranged requests used in parallel to
+	// download snaps of at least minParallelDownloadSize bytes, a
+	// single request is used if it is not greater than 1.
+	Streams int
 }
 
+// minParallelDownloadSize is the minimum size of a download for it to be
+// split into ranged requests carried out in parallel.
+var minParallelDownloadSize int64 = 32 * 1024 * 1024
+
+// maxDownloadStreams is the maximum number of parallel ranged requests used
+// for a single download.
+const maxDownloadStreams = 8
+
 // Download downloads the snap addressed by download info and returns its
 // filename.
 // The file is saved in temporary storage, and should be removed
@@ -157,7 +169,10 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return nil
 	}
 
-	if useDeltas() {
+	partialPath := targetPath + ".partial"
+	// a partial full download is resumed rather than replaced by the
+	// result of applying a delta, which uses the same partial file
+	if useDeltas() && !osutil.FileExists(partialPath) {
 		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
 
 		if len(downloadInfo.Deltas) == 1 {
@@ -170,7 +185,6 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		}
 	}
 
-	partialPath := targetPath + ".partial"
 	w, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
 		return err
@@ -207,7 +221,23 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		url = downloadInfo.DownloadURL
 	}
 
-	if downloadInfo.Size == 0 || resume < downloadInfo.Size {
+	parallel := dlOpts != nil && dlOpts.Streams > 1 && resume == 0 && downloadInfo.Size >= minParallelDownloadSize
+	if parallel {
+		err = downloadParallel(ctx, name, downloadInfo.Sha3_384, url, user, s, w, downloadInfo.Size, pbar, dlOpts)
+		if err == errRangesNotSupported {
+			logger.Debugf("Server does not support ranged requests, downloading %q with a single request.", partialPath)
+			if err = w.Truncate(0); err != nil {
+				return err
+			}
+			if _, err = w.Seek(0, os.SEEK_SET); err != nil {
+				return err
+			}
+			err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, 0, pbar, dlOpts)
+		}
+		if err != nil {
+			logger.Debugf("download of %q failed: %#v", url, err)
+		}
+	} else if downloadInfo.Size == 0 || resume < downloadInfo.Size {
 		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar, dlOpts)
 		if err != nil {
 			logger.Debugf("download of %q failed: %#v", url, err)
@@ -534,6 +564,212 @@ func downloadImpl(ctx context.Context, name, sha3_384, downloadURL string, user
 	return finalErr
 }
 
+var errRangesNotSupported = errors.New("server does not support ranged requests")
+
+// downloadParallel downloads size bytes into w using dlOpts.Streams
+// ranged requests in parallel. On error the part of w downloaded
+// contiguously from its start is kept so that the download can be resumed.
+func downloadParallel(ctx context.Context, name, sha3_384, downloadURL string, user *auth.UserState, s *Store, w *os.File, size int64, pbar progress.Meter, dlOpts *DownloadOptions) error {
+	storeURL, err := url.Parse(downloadURL)
+	if err != nil {
+		return err
+	}
+
+	cdnHeader, err := s.cdnHeader()
+	if err != nil {
+		return err
+	}
+
+	streams := dlOpts.Streams
+	if streams > maxDownloadStreams {
+		streams = maxDownloadStreams
+	}
+	rangeSize := (size + int64(streams) - 1) / int64(streams)
+	rangeOpts := *dlOpts
+	if dlOpts.RateLimit > 0 {
+		// the rate limit applies to the download as a whole
+		rangeOpts.RateLimit = dlOpts.RateLimit / int64(streams)
+		if rangeOpts.RateLimit == 0 {
+			rangeOpts.RateLimit = 1
+		}
+	}
+
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	pbar.Start(name, float64(size))
+	pw := &lockedWriter{w: pbar}
+
+	rangeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	startTime := time.Now()
+	written := make([]int64, streams)
+	errs := make([]error, streams)
+	var wg sync.WaitGroup
+	for i := 0; i < streams; i++ {
+		start := int64(i) * rangeSize
+		end := start + rangeSize
+		if end > size {
+			end = size
+		}
+		wg.Add(1)
+		go func(i int, start, end int64) {
+			defer wg.Done()
+			errs[i] = downloadRange(rangeCtx, name, storeURL, cdnHeader, user, s, w, start, end, &written[i], pw, &rangeOpts)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, start, end)
+	}
+	wg.Wait()
+	pbar.Finished()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if err == errRangesNotSupported {
+			return err
+		}
+		if cancelled(ctx) {
+			err = fmt.Errorf("The download has been cancelled: %s", ctx.Err())
+		} else if err == context.Canceled {
+			// cancelled because of the failure of another range
+			continue
+		}
+		// keep what was downloaded contiguously so that the
+		// download can be resumed
+		var contiguous int64
+		for j := 0; j < streams && written[j] == rangeSize; j++ {
+			contiguous += rangeSize
+		}
+		if j := contiguous / rangeSize; j < int64(streams) {
+			contiguous += written[j]
+		}
+		if terr := w.Truncate(contiguous); terr != nil {
+			return terr
+		}
+		if _, serr := w.Seek(contiguous, os.SEEK_SET); serr != nil {
+			return serr
+		}
+		logger.Debugf("Range %d of download of %q failed: %v", i, name, err)
+		return err
+	}
+
+	h := crypto.SHA3_384.New()
+	if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, w); err != nil {
+		return err
+	}
+	actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+	if sha3_384 != "" && sha3_384 != actualSha3 {
+		return HashError{name, actualSha3, sha3_384}
+	}
+
+	logger.Debugf("Download with %d streams succeeded in %.03fs.", streams, time.Since(startTime).Seconds())
+	return nil
+}
+
+// downloadRange downloads the bytes from start to end (excluded) into w,
+// written is updated with the number of bytes written so far.
+func downloadRange(ctx context.Context, name string, storeURL *url.URL, cdnHeader string, user *auth.UserState, s *Store, w io.WriterAt, start, end int64, written *int64, pbar io.Writer, dlOpts *DownloadOptions) error {
+	var finalErr error
+	startTime := time.Now()
+	for attempt := retry.Start(downloadRetryStrategy, nil); attempt.Next(); {
+		reqOptions := downloadReqOpts(storeURL, cdnHeader, dlOpts)
+		reqOptions.ExtraHeaders["Range"] = fmt.Sprintf("bytes=%d-%d", start+*written, end-1)
+
+		httputil.MaybeLogRetryAttempt(reqOptions.URL.String(), attempt, startTime)
+
+		if cancelled(ctx) {
+			return ctx.Err()
+		}
+		var resp *http.Response
+		cli := s.newHTTPClient(nil)
+		resp, finalErr = s.doRequest(ctx, cli, reqOptions, user)
+		if cancelled(ctx) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return ctx.Err()
+		}
+		if finalErr != nil {
+			if httputil.ShouldRetryAttempt(attempt, finalErr) {
+				continue
+			}
+			break
+		}
+		if httputil.ShouldRetryHttpResponse(attempt, resp) {
+			resp.Body.Close()
+			continue
+		}
+
+		switch resp.StatusCode {
+		case 206: // Partial Content
+		case 200: // OK, but the whole snap
+			resp.Body.Close()
+			return errRangesNotSupported
+		case 402: // Payment Required
+			resp.Body.Close()
+			return fmt.Errorf("please buy %s before installing it.", name)
+		default:
+			resp.Body.Close()
+			return &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
+		}
+
+		var body io.Reader = io.LimitReader(resp.Body, end-start-*written)
+		if limit := dlOpts.RateLimit; limit > 0 {
+			bucket := ratelimit.NewBucketWithRate(float64(limit), 2*limit)
+			body = ratelimitReader(body, bucket)
+		}
+		ow := &offsetWriter{w: w, off: start + *written}
+		_, finalErr = io.Copy(io.MultiWriter(ow, pbar), body)
+		resp.Body.Close()
+		*written = ow.off - start
+		if cancelled(ctx) {
+			return ctx.Err()
+		}
+		if finalErr == nil && *written < end-start {
+			finalErr = io.ErrUnexpectedEOF
+		}
+		if finalErr != nil {
+			if httputil.ShouldRetryAttempt(attempt, finalErr) {
+				continue
+			}
+		}
+		break
+	}
+	return finalErr
+}
+
+// offsetWriter writes sequentially into an io.WriterAt from the given
+// offset.
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	n, err := ow.w.WriteAt(p, ow.off)
+	ow.off += int64(n)
+	return n, err
+}
+
+// lockedWriter serializes the writes to the wrapped writer.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
 // DownloadStream will copy the snap from the request to the io.Reader
 func (s *Store) DownloadStream(ctx context.Context, name string, downloadInfo *snap.DownloadInfo, resume int64, user *auth.UserState) (io.ReadCloser, int, error) {
 	// XXX: coverage of this is rather poor
@@ -645,10 +881,15 @@ var applyDelta = func(name string, deltaPath string, deltaInfo *snap.DeltaInfo,
 		return err
 	}
 
-	if err := cmd.Run(); err != nil {
-		if err := os.Remove(partialTargetPath); err != nil {
-			logger.Noticef("failed to remove partial delta target %q: %s", partialTargetPath, err)
+	// the partial target must not be left behind, the full download
+	// falling back from a failed delta would try to resume it
+	defer func() {
+		if rerr := os.Remove(partialTargetPath); rerr != nil && !os.IsNotExist(rerr) {
+			logger.Noticef("failed to remove partial delta target %q: %s", partialTargetPath, rerr)
 		}
+	}()
+
+	if err := cmd.Run(); err != nil {
 		return err
 	}
 
@@ -662,9 +903,6 @@ var applyDelta = func(name string, deltaPath string, deltaInfo *snap.DeltaInfo,
 	}
 	sha3_384 := fmt.Sprintf("%x", bsha3_384)
 	if targetSha3_384 != "" && sha3_384 != targetSha3_384 {
-		if err := os.Remove(partialTargetPath); err != nil {
-			logger.Noticef("failed to remove partial delta target %q: %s", partialTargetPath, err)
-		}
 		return HashError{name, sha3_384, targetSha3_384}
 	}
 
@@ -31,6 +31,10 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/crypto/sha3"
@@ -410,6 +414,123 @@ func (s *storeDownloadSuite) TestDownloadRangeRequestFailOnHashError(c *C) {
 	c.Assert(n, Equals, 2)
 }
 
+func (s *storeDownloadSuite) TestDownloadParallel(c *C) {
+	restore := store.MockMinParallelDownloadSize(100)
+	defer restore()
+
+	content := make([]byte, 1000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+
+	var mu sync.Mutex
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "foo.snap", time.Time{}, bytes.NewReader(content))
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384(content))
+	snap.Size = int64(len(content))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{Streams: 3})
+	c.Assert(err, IsNil)
+	c.Check(targetFn, testutil.FileEquals, content)
+	c.Check(targetFn+".partial", testutil.FileAbsent)
+
+	sort.Strings(ranges)
+	c.Check(ranges, DeepEquals, []string{"bytes=0-333", "bytes=334-667", "bytes=668-999"})
+}
+
+func (s *storeDownloadSuite) TestDownloadParallelRangesNotSupported(c *C) {
+	restore := store.MockMinParallelDownloadSize(100)
+	defer restore()
+
+	content := bytes.Repeat([]byte("x"), 1000)
+	var n int32
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		// ranges are ignored
+		w.Write(content)
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384(content))
+	snap.Size = int64(len(content))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{Streams: 2})
+	c.Assert(err, IsNil)
+	c.Check(targetFn, testutil.FileEquals, content)
+	// the ranged requests and the single fallback one
+	c.Check(atomic.LoadInt32(&n) >= 2, Equals, true)
+}
+
+func (s *storeDownloadSuite) TestDownloadParallelSmallDownloadUsesSingleRequest(c *C) {
+	content := []byte("small content")
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		w.Write(content)
+		return nil
+	})
+	defer restore()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = "anon-url"
+	snap.DownloadURL = "AUTH-URL"
+	snap.Size = int64(len(content))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{Streams: 4})
+	c.Assert(err, IsNil)
+	c.Check(targetFn, testutil.FileEquals, content)
+}
+
+func (s *storeDownloadSuite) TestDownloadParallelFails(c *C) {
+	restore := store.MockMinParallelDownloadSize(100)
+	defer restore()
+
+	content := bytes.Repeat([]byte("x"), 1000)
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Header.Get("Range"), "bytes=0-") {
+			w.WriteHeader(404)
+			return
+		}
+		http.ServeContent(w, r, "foo.snap", time.Time{}, bytes.NewReader(content))
+	}))
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	snap := &snap.Info{}
+	snap.RealName = "foo"
+	snap.AnonDownloadURL = mockServer.URL
+	snap.DownloadURL = "AUTH-URL"
+	snap.Sha3_384 = fmt.Sprintf("%x", sha3.Sum384(content))
+	snap.Size = int64(len(content))
+
+	targetFn := filepath.Join(c.MkDir(), "foo_1.0_all.snap")
+	err := s.store.Download(s.ctx, "foo", targetFn, &snap.DownloadInfo, nil, nil, &store.DownloadOptions{Streams: 2, LeavePartialOnError: true})
+	c.Assert(err, FitsTypeOf, &store.DownloadError{})
+	c.Check(targetFn, testutil.FileAbsent)
+	// nothing was downloaded contiguously from the start, so there is
+	// nothing to resume from
+	c.Check(targetFn+".partial", testutil.FileAbsent)
+}
+
 func (s *storeDownloadSuite) TestAuthenticatedDownloadDoesNotUseAnonURL(c *C) {
 	expectedContent := []byte("I was downloaded")
 	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, _ *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
@@ -800,6 +921,94 @@ func (s *storeDownloadSuite) TestApplyDelta(c *C) {
 	}
 }
 
+func (s *storeDownloadSuite) TestDownloadFallsBackToFullDownloadWhenDeltaFails(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+
+	expectedContent := []byte("I was downloaded")
+	var urls []string
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		urls = append(urls, url)
+		if url == "anon-url" {
+			w.Write(expectedContent)
+		}
+		return nil
+	})
+	defer restore()
+	restore = store.MockApplyDelta(func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+		return fmt.Errorf("cannot apply delta")
+	})
+	defer restore()
+
+	info := &snap.DownloadInfo{
+		AnonDownloadURL: "anon-url",
+		Size:            int64(len(expectedContent)),
+		Deltas: []snap.DeltaInfo{
+			{AnonDownloadURL: "anon-delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	}
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := s.store.Download(s.ctx, "foo", path, info, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(urls, DeepEquals, []string{"anon-delta-url", "anon-url"})
+	c.Check(path, testutil.FileEquals, expectedContent)
+}
+
+func (s *storeDownloadSuite) TestDownloadResumesPartialDownloadInsteadOfDelta(c *C) {
+	origUseDeltas := os.Getenv("SNAPD_USE_DELTAS_EXPERIMENTAL")
+	defer os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", origUseDeltas)
+	c.Assert(os.Setenv("SNAPD_USE_DELTAS_EXPERIMENTAL", "1"), IsNil)
+
+	partialContent := []byte("I was ")
+	restContent := []byte("downloaded")
+	restore := store.MockDownload(func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *store.Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter, dlOpts *store.DownloadOptions) error {
+		c.Check(url, Equals, "anon-url")
+		c.Check(resume, Equals, int64(len(partialContent)))
+		w.Write(restContent)
+		return nil
+	})
+	defer restore()
+	restore = store.MockApplyDelta(func(name string, deltaPath string, deltaInfo *snap.DeltaInfo, targetPath string, targetSha3_384 string) error {
+		c.Fatalf("deltas should not be applied when resuming a download")
+		return nil
+	})
+	defer restore()
+
+	info := &snap.DownloadInfo{
+		AnonDownloadURL: "anon-url",
+		Size:            int64(len(partialContent) + len(restContent)),
+		Deltas: []snap.DeltaInfo{
+			{AnonDownloadURL: "anon-delta-url", Format: "xdelta3", FromRevision: 24, ToRevision: 26},
+		},
+	}
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(path+".partial", partialContent, 0600), IsNil)
+	err := s.store.Download(s.ctx, "foo", path, info, nil, nil, nil)
+	c.Assert(err, IsNil)
+
+	c.Check(path, testutil.FileEquals, "I was downloaded")
+}
+
+func (s *storeDownloadSuite) TestApplyDeltaHashMismatchRemovesPartial(c *C) {
+	currentSnapPath := filepath.Join(dirs.SnapBlobDir, "foo_24.snap")
+	targetSnapPath := filepath.Join(dirs.SnapBlobDir, "foo_26.snap")
+	c.Assert(os.MkdirAll(dirs.SnapBlobDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(currentSnapPath, nil, 0644), IsNil)
+	deltaPath := filepath.Join(dirs.SnapBlobDir, "the.delta")
+	c.Assert(ioutil.WriteFile(deltaPath, nil, 0644), IsNil)
+	// simulate the output of xdelta3
+	c.Assert(ioutil.WriteFile(targetSnapPath+".partial", []byte("garbage"), 0644), IsNil)
+
+	err := store.ApplyDelta("foo", deltaPath, &snap.DeltaInfo{Format: "xdelta3", FromRevision: 24, ToRevision: 26}, targetSnapPath, "expected-sha3")
+	c.Assert(err, FitsTypeOf, store.HashError{})
+	c.Check(osutil.FileExists(targetSnapPath+".partial"), Equals, false)
+	c.Check(osutil.FileExists(targetSnapPath), Equals, false)
+}
+
 type cacheObserver struct {
 	inCache map[string]bool
 
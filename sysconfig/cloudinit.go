@@ -28,6 +28,8 @@ import (
 	"path/filepath"
 	"regexp"
 
+	"gopkg.in/yaml.v2"
+
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
@@ -40,6 +42,26 @@ func HasGadgetCloudConf(gadgetDir string) bool {
 	return osutil.FileExists(filepath.Join(gadgetDir, "cloud.conf"))
 }
 
+// GadgetCloudInitDatasources returns the datasources the gadget restricts
+// cloud-init to with the datasource_list key of its cloud.conf, if any.
+func GadgetCloudInitDatasources(gadgetDir string) ([]string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(gadgetDir, "cloud.conf"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cloudConf struct {
+		DatasourceList []string `yaml:"datasource_list"`
+	}
+	if err := yaml.Unmarshal(content, &cloudConf); err != nil {
+		return nil, fmt.Errorf("cannot parse gadget cloud.conf: %v", err)
+	}
+	return cloudConf.DatasourceList, nil
+}
+
 func ubuntuDataCloudDir(rootdir string) string {
 	return filepath.Join(rootdir, "etc/cloud/")
 }
@@ -291,6 +313,11 @@ type CloudInitRestrictOptions struct {
 	// a local source, such as GCE or AWS EC2 it is merely restricted as
 	// described in the doc-comment on RestrictCloudInit.
 	DisableAfterLocalDatasourcesRun bool
+
+	// AllowedDatasources, if not empty, lists the only datasources that
+	// cloud-init may keep using after it has run. If the detected
+	// datasource is not one of them cloud-init is disabled instead.
+	AllowedDatasources []string
 }
 
 // RestrictCloudInit will limit the operations of cloud-init on subsequent boots
@@ -371,6 +398,11 @@ func RestrictCloudInit(state CloudInitState, opts *CloudInitRestrictOptions) (Cl
 	cloudInitRestrictFile := filepath.Join(dirs.GlobalRootDir, cloudInitSnapdRestrictFile)
 
 	switch {
+	case len(opts.AllowedDatasources) > 0 && !strutil.ListContains(opts.AllowedDatasources, res.DataSource):
+		// the datasource that was used is not one that the device is
+		// allowed to use, so disable cloud-init
+		res.Action = "disable"
+		err = DisableCloudInit(dirs.GlobalRootDir)
 	case opts.DisableAfterLocalDatasourcesRun && strutil.ListContains(localDatasources, res.DataSource):
 		// On UC20, DisableAfterLocalDatasourcesRun will be set, where we want
 		// to disable local sources like NoCloud and None after first-boot
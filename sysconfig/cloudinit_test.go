@@ -87,6 +87,33 @@ func (s *sysconfigSuite) TestHasGadgetCloudConf(c *C) {
 	c.Assert(sysconfig.HasGadgetCloudConf(gadgetDir), Equals, true)
 }
 
+func (s *sysconfigSuite) TestGadgetCloudInitDatasources(c *C) {
+	// no cloud.conf means no restrictions
+	gadgetDir := c.MkDir()
+	l, err := sysconfig.GadgetCloudInitDatasources(gadgetDir)
+	c.Assert(err, IsNil)
+	c.Check(l, HasLen, 0)
+
+	gadgetCloudConf := filepath.Join(gadgetDir, "cloud.conf")
+	err = ioutil.WriteFile(gadgetCloudConf, []byte("datasource_list: [Azure, GCE]\n"), 0644)
+	c.Assert(err, IsNil)
+	l, err = sysconfig.GadgetCloudInitDatasources(gadgetDir)
+	c.Assert(err, IsNil)
+	c.Check(l, DeepEquals, []string{"Azure", "GCE"})
+
+	// no datasource_list key
+	err = ioutil.WriteFile(gadgetCloudConf, []byte("manage_etc_hosts: true\n"), 0644)
+	c.Assert(err, IsNil)
+	l, err = sysconfig.GadgetCloudInitDatasources(gadgetDir)
+	c.Assert(err, IsNil)
+	c.Check(l, HasLen, 0)
+
+	err = ioutil.WriteFile(gadgetCloudConf, []byte("datasource_list: {"), 0644)
+	c.Assert(err, IsNil)
+	_, err = sysconfig.GadgetCloudInitDatasources(gadgetDir)
+	c.Assert(err, ErrorMatches, "cannot parse gadget cloud.conf: .*")
+}
+
 // this test is for initramfs calls that disable cloud-init for the ephemeral
 // writable partition that is used while running during install or recover mode
 func (s *sysconfigSuite) TestEphemeralModeInitramfsCloudInitDisables(c *C) {
@@ -531,6 +558,30 @@ func (s *sysconfigSuite) TestRestrictCloudInit(c *C) {
 			expAction:      "disable",
 			expDisableFile: true,
 		},
+		{
+			comment:             "gce done allowed by gadget",
+			state:               sysconfig.CloudInitDone,
+			cloudInitStatusJSON: gceCloudInitStatusJSON,
+			sysconfOpts: &sysconfig.CloudInitRestrictOptions{
+				DisableAfterLocalDatasourcesRun: true,
+				AllowedDatasources:              []string{"Azure", "GCE"},
+			},
+			expDatasource: "GCE",
+			expAction:     "restrict",
+			expRestrictYamlWritten: `datasource_list: [GCE]
+`,
+		},
+		{
+			comment:             "gce done not allowed by gadget",
+			state:               sysconfig.CloudInitDone,
+			cloudInitStatusJSON: gceCloudInitStatusJSON,
+			sysconfOpts: &sysconfig.CloudInitRestrictOptions{
+				AllowedDatasources: []string{"Azure"},
+			},
+			expDatasource:  "GCE",
+			expAction:      "disable",
+			expDisableFile: true,
+		},
 		{
 			comment:        "no cloud-init in $PATH",
 			state:          sysconfig.CloudInitNotFound,
@@ -0,0 +1,196 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package systemd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/godbus/dbus"
+
+	"github.com/snapcore/snapd/dbusutil"
+	"github.com/snapcore/snapd/logger"
+)
+
+const (
+	systemdBusName       = "org.freedesktop.systemd1"
+	systemdObjectPath    = "/org/freedesktop/systemd1"
+	systemdManagerIface  = "org.freedesktop.systemd1.Manager"
+	systemdJobRemovedSig = "JobRemoved"
+)
+
+var (
+	subscriptionLock sync.Mutex
+	subscriptions    = make(map[*dbus.Conn]int)
+)
+
+// useDBus controls whether the system instance of systemd is talked to
+// directly over D-Bus, instead of by executing systemctl.
+var useDBus = true
+
+// dbusManager talks to the manager of the system instance of systemd over
+// D-Bus, saving the cost of executing systemctl for each operation.
+type dbusManager struct {
+	conn *dbus.Conn
+	obj  dbus.BusObject
+}
+
+// newDBusManager returns a dbusManager using the system bus, or nil if
+// systemd cannot be reached over D-Bus, in which case systemctl should
+// be used instead.
+func newDBusManager() *dbusManager {
+	if !useDBus {
+		return nil
+	}
+	conn, err := dbusutil.SystemBus()
+	if err != nil {
+		logger.Debugf("cannot connect to the system bus, using systemctl: %v", err)
+		return nil
+	}
+	return &dbusManager{
+		conn: conn,
+		obj:  conn.Object(systemdBusName, systemdObjectPath),
+	}
+}
+
+// jobFailedError is returned when a start job queued over D-Bus did not
+// complete successfully, as opposed to a failure to talk to systemd, which
+// warrants falling back to systemctl.
+type jobFailedError struct {
+	unit   string
+	result string
+}
+
+func (e *jobFailedError) Error() string {
+	return fmt.Sprintf("cannot start %q: job finished with result %q", e.unit, e.result)
+}
+
+// startUnits queues start jobs for the given units, and if wait is set
+// waits for the jobs to complete, like systemctl start does.
+func (m *dbusManager) startUnits(units []string, wait bool) error {
+	if !wait {
+		for _, unit := range units {
+			if err := m.obj.Call(systemdManagerIface+".StartUnit", 0, unit, "replace").Err; err != nil {
+				return fmt.Errorf("cannot start %q: %v", unit, err)
+			}
+		}
+		return nil
+	}
+
+	// subscribe to the job signals before queueing any jobs, so that no
+	// job completion can be missed
+	ch := make(chan *dbus.Signal, 10)
+	m.conn.Signal(ch)
+	defer m.conn.RemoveSignal(ch)
+	matchRules := []dbus.MatchOption{
+		dbus.WithMatchSender(systemdBusName),
+		dbus.WithMatchObjectPath(systemdObjectPath),
+		dbus.WithMatchInterface(systemdManagerIface),
+		dbus.WithMatchMember(systemdJobRemovedSig),
+	}
+	if err := m.conn.AddMatchSignal(matchRules...); err != nil {
+		return err
+	}
+	defer func() {
+		if err := m.conn.RemoveMatchSignal(matchRules...); err != nil {
+			logger.Noticef("Cannot remove D-Bus signal matcher: %v", err)
+		}
+	}()
+	if err := m.subscribe(); err != nil {
+		return err
+	}
+	defer m.unsubscribe()
+
+	pending := make(map[dbus.ObjectPath]string, len(units))
+	for _, unit := range units {
+		var job dbus.ObjectPath
+		if err := m.obj.Call(systemdManagerIface+".StartUnit", 0, unit, "replace").Store(&job); err != nil {
+			return fmt.Errorf("cannot start %q: %v", unit, err)
+		}
+		pending[job] = unit
+	}
+
+	// signals of jobs that completed already are queued in ch
+	for len(pending) > 0 {
+		sig, ok := <-ch
+		if !ok {
+			return fmt.Errorf("cannot wait for start jobs: D-Bus connection closed")
+		}
+		job, result, ok := parseJobRemoved(sig)
+		if !ok {
+			continue
+		}
+		unit, ok := pending[job]
+		if !ok {
+			continue
+		}
+		if err := jobResultError(unit, result); err != nil {
+			return err
+		}
+		delete(pending, job)
+	}
+	return nil
+}
+
+// subscribe asks systemd to emit job signals, which it only does when
+// there are subscribed clients. The subscription is tracked per connection,
+// and the system bus connection is shared, so it is reference counted.
+func (m *dbusManager) subscribe() error {
+	subscriptionLock.Lock()
+	defer subscriptionLock.Unlock()
+	if subscriptions[m.conn] == 0 {
+		if err := m.obj.Call(systemdManagerIface+".Subscribe", 0).Err; err != nil {
+			return fmt.Errorf("cannot subscribe to systemd signals: %v", err)
+		}
+	}
+	subscriptions[m.conn]++
+	return nil
+}
+
+func (m *dbusManager) unsubscribe() {
+	subscriptionLock.Lock()
+	defer subscriptionLock.Unlock()
+	subscriptions[m.conn]--
+	if subscriptions[m.conn] > 0 {
+		return
+	}
+	delete(subscriptions, m.conn)
+	if err := m.obj.Call(systemdManagerIface+".Unsubscribe", 0).Err; err != nil {
+		logger.Debugf("cannot unsubscribe from systemd signals: %v", err)
+	}
+}
+
+// parseJobRemoved returns the job and its result from a JobRemoved signal,
+// with arguments: id uint32, job object path, unit string, result string.
+func parseJobRemoved(sig *dbus.Signal) (job dbus.ObjectPath, result string, ok bool) {
+	if sig.Name != systemdManagerIface+"."+systemdJobRemovedSig || len(sig.Body) != 4 {
+		return "", "", false
+	}
+	job, ok1 := sig.Body[1].(dbus.ObjectPath)
+	result, ok2 := sig.Body[3].(string)
+	return job, result, ok1 && ok2
+}
+
+func jobResultError(unit, result string) error {
+	if result == "done" {
+		return nil
+	}
+	return &jobFailedError{unit: unit, result: result}
+}
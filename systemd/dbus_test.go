@@ -0,0 +1,323 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package systemd_test
+
+import (
+	"fmt"
+
+	"github.com/godbus/dbus"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dbusutil"
+	"github.com/snapcore/snapd/dbusutil/dbustest"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+)
+
+type dbusSuite struct {
+	testutil.BaseTest
+}
+
+var _ = Suite(&dbusSuite{})
+
+func (s *dbusSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	s.AddCleanup(systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		c.Fatalf("unexpected systemctl call: %v", args)
+		return nil, nil
+	}))
+	// mocking systemctl disables the D-Bus backend
+	s.AddCleanup(systemd.MockUseDBus(true))
+}
+
+func methodReply(msg *dbus.Message, body ...interface{}) *dbus.Message {
+	reply := &dbus.Message{
+		Type: dbus.TypeMethodReply,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial()),
+			dbus.FieldSender:      dbus.MakeVariant(":1"),
+		},
+		Body: body,
+	}
+	if len(body) > 0 {
+		reply.Headers[dbus.FieldSignature] = dbus.MakeVariant(dbus.SignatureOf(body...))
+	}
+	return reply
+}
+
+func methodError(msg *dbus.Message, name, text string) *dbus.Message {
+	return &dbus.Message{
+		Type: dbus.TypeError,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial()),
+			dbus.FieldSender:      dbus.MakeVariant(":1"),
+			dbus.FieldErrorName:   dbus.MakeVariant(name),
+			dbus.FieldSignature:   dbus.MakeVariant(dbus.SignatureOf(text)),
+		},
+		Body: []interface{}{text},
+	}
+}
+
+func jobRemoved(id uint32, job dbus.ObjectPath, unit, result string) *dbus.Message {
+	body := []interface{}{id, job, unit, result}
+	return &dbus.Message{
+		Type: dbus.TypeSignal,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldPath:      dbus.MakeVariant(dbus.ObjectPath("/org/freedesktop/systemd1")),
+			dbus.FieldInterface: dbus.MakeVariant("org.freedesktop.systemd1.Manager"),
+			dbus.FieldMember:    dbus.MakeVariant("JobRemoved"),
+			dbus.FieldSender:    dbus.MakeVariant(":1"),
+			dbus.FieldSignature: dbus.MakeVariant(dbus.SignatureOf(body...)),
+		},
+		Body: body,
+	}
+}
+
+func checkManagerCall(c *C, msg *dbus.Message, member string, args ...interface{}) {
+	c.Assert(msg.Type, Equals, dbus.TypeMethodCall)
+	c.Check(msg.Headers[dbus.FieldDestination].Value(), Equals, "org.freedesktop.systemd1")
+	c.Check(msg.Headers[dbus.FieldPath].Value(), Equals, dbus.ObjectPath("/org/freedesktop/systemd1"))
+	c.Check(msg.Headers[dbus.FieldInterface].Value(), Equals, "org.freedesktop.systemd1.Manager")
+	c.Check(msg.Headers[dbus.FieldMember].Value(), Equals, member)
+	c.Check(msg.Body, DeepEquals, args)
+}
+
+func checkBusCall(c *C, msg *dbus.Message, member string) {
+	c.Assert(msg.Type, Equals, dbus.TypeMethodCall)
+	c.Check(msg.Headers[dbus.FieldDestination].Value(), Equals, "org.freedesktop.DBus")
+	c.Check(msg.Headers[dbus.FieldMember].Value(), Equals, member)
+}
+
+func (s *dbusSuite) TestStart(c *C) {
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0:
+			checkBusCall(c, msg, "AddMatch")
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 1:
+			checkManagerCall(c, msg, "Subscribe")
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 2:
+			checkManagerCall(c, msg, "StartUnit", "foo.service", "replace")
+			return []*dbus.Message{
+				methodReply(msg, dbus.ObjectPath("/org/freedesktop/systemd1/job/1")),
+				// some unrelated job
+				jobRemoved(2, "/org/freedesktop/systemd1/job/2", "other.service", "failed"),
+			}, nil
+		case 3:
+			checkManagerCall(c, msg, "StartUnit", "bar.service", "replace")
+			return []*dbus.Message{
+				methodReply(msg, dbus.ObjectPath("/org/freedesktop/systemd1/job/3")),
+				jobRemoved(3, "/org/freedesktop/systemd1/job/3", "bar.service", "done"),
+				jobRemoved(1, "/org/freedesktop/systemd1/job/1", "foo.service", "done"),
+			}, nil
+		case 4:
+			checkManagerCall(c, msg, "Unsubscribe")
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 5:
+			checkBusCall(c, msg, "RemoveMatch")
+			return []*dbus.Message{methodReply(msg)}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer dbusutil.MockOnlySystemBusAvailable(conn)()
+
+	err = systemd.New(systemd.SystemMode, nil).Start("foo.service", "bar.service")
+	c.Assert(err, IsNil)
+}
+
+func (s *dbusSuite) TestStartJobFailed(c *C) {
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0, 4:
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 1:
+			checkManagerCall(c, msg, "Subscribe")
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 2:
+			checkManagerCall(c, msg, "StartUnit", "foo.service", "replace")
+			return []*dbus.Message{
+				methodReply(msg, dbus.ObjectPath("/org/freedesktop/systemd1/job/1")),
+				jobRemoved(1, "/org/freedesktop/systemd1/job/1", "foo.service", "failed"),
+			}, nil
+		case 3:
+			checkManagerCall(c, msg, "Unsubscribe")
+			return []*dbus.Message{methodReply(msg)}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer dbusutil.MockOnlySystemBusAvailable(conn)()
+
+	err = systemd.New(systemd.SystemMode, nil).Start("foo.service")
+	c.Assert(err, ErrorMatches, `cannot start "foo.service": job finished with result "failed"`)
+}
+
+func (s *dbusSuite) TestStartCallFailsFallbackToSystemctl(c *C) {
+	var calls [][]string
+	defer systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, fmt.Errorf("Unit foo.service not found.")
+	})()
+	defer systemd.MockUseDBus(true)()
+
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0, 1, 3, 4:
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 2:
+			checkManagerCall(c, msg, "StartUnit", "foo.service", "replace")
+			return []*dbus.Message{methodError(msg, "org.freedesktop.systemd1.NoSuchUnit", "Unit foo.service not found.")}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer dbusutil.MockOnlySystemBusAvailable(conn)()
+
+	err = systemd.New(systemd.SystemMode, nil).Start("foo.service")
+	c.Assert(err, ErrorMatches, `Unit foo.service not found.`)
+	c.Check(calls, DeepEquals, [][]string{
+		{"start", "foo.service"},
+	})
+}
+
+func (s *dbusSuite) TestStartNoBlockCallFailsFallbackToSystemctl(c *C) {
+	var calls [][]string
+	defer systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})()
+	defer systemd.MockUseDBus(true)()
+
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0:
+			checkManagerCall(c, msg, "StartUnit", "foo.service", "replace")
+			return []*dbus.Message{methodError(msg, "org.freedesktop.DBus.Error.AccessDenied", "Access denied.")}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer dbusutil.MockOnlySystemBusAvailable(conn)()
+
+	err = systemd.New(systemd.SystemMode, nil).StartNoBlock("foo.service", "bar.service")
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, [][]string{
+		{"start", "--no-block", "foo.service", "bar.service"},
+	})
+}
+
+func (s *dbusSuite) TestStartWaitFailsFallbackToSystemctl(c *C) {
+	var calls [][]string
+	defer systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})()
+	defer systemd.MockUseDBus(true)()
+
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0:
+			checkBusCall(c, msg, "AddMatch")
+			return []*dbus.Message{methodReply(msg)}, nil
+		case 1:
+			checkManagerCall(c, msg, "Subscribe")
+			return []*dbus.Message{methodError(msg, "org.freedesktop.DBus.Error.AccessDenied", "Access denied.")}, nil
+		case 2:
+			checkBusCall(c, msg, "RemoveMatch")
+			return []*dbus.Message{methodReply(msg)}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer dbusutil.MockOnlySystemBusAvailable(conn)()
+
+	err = systemd.New(systemd.SystemMode, nil).Start("foo.service")
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, [][]string{
+		{"start", "foo.service"},
+	})
+}
+
+func (s *dbusSuite) TestStartNoBlock(c *C) {
+	conn, err := dbustest.Connection(func(msg *dbus.Message, n int) ([]*dbus.Message, error) {
+		switch n {
+		case 0:
+			checkManagerCall(c, msg, "StartUnit", "foo.service", "replace")
+			return []*dbus.Message{methodReply(msg, dbus.ObjectPath("/org/freedesktop/systemd1/job/1"))}, nil
+		case 1:
+			checkManagerCall(c, msg, "StartUnit", "bar.service", "replace")
+			return []*dbus.Message{methodReply(msg, dbus.ObjectPath("/org/freedesktop/systemd1/job/2"))}, nil
+		}
+		return nil, fmt.Errorf("unexpected message #%d: %s", n, msg)
+	})
+	c.Assert(err, IsNil)
+	defer conn.Close()
+	defer dbusutil.MockOnlySystemBusAvailable(conn)()
+
+	err = systemd.New(systemd.SystemMode, nil).StartNoBlock("foo.service", "bar.service")
+	c.Assert(err, IsNil)
+}
+
+func (s *dbusSuite) TestStartFallbackToSystemctl(c *C) {
+	var calls [][]string
+	defer systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})()
+	defer systemd.MockUseDBus(true)()
+	defer dbusutil.MockConnections(func() (*dbus.Conn, error) {
+		return nil, fmt.Errorf("no system bus")
+	}, nil)()
+
+	err := systemd.New(systemd.SystemMode, nil).Start("foo.service")
+	c.Assert(err, IsNil)
+	err = systemd.New(systemd.SystemMode, nil).StartNoBlock("foo.service")
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, [][]string{
+		{"start", "foo.service"},
+		{"start", "--no-block", "foo.service"},
+	})
+}
+
+func (s *dbusSuite) TestUserModeUsesSystemctl(c *C) {
+	var calls [][]string
+	defer systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})()
+	defer systemd.MockUseDBus(true)()
+	defer dbusutil.MockConnections(func() (*dbus.Conn, error) {
+		panic("unexpected use of the system bus")
+	}, nil)()
+
+	err := systemd.New(systemd.UserMode, nil).Start("foo.service")
+	c.Assert(err, IsNil)
+	c.Check(calls, DeepEquals, [][]string{
+		{"--user", "start", "foo.service"},
+	})
+}
@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/squashfs"
 )
@@ -98,6 +99,14 @@ func (s *emulation) IsActive(service string) (bool, error) {
 	return false, errNotImplemented
 }
 
+func (s *emulation) CurrentMemoryUsage(unit string) (quantity.Size, error) {
+	return 0, errNotImplemented
+}
+
+func (s *emulation) BootTimestamps() (*BootTimestamps, error) {
+	return nil, errNotImplemented
+}
+
 func (s *emulation) LogReader(services []string, n int, follow bool) (io.ReadCloser, error) {
 	return nil, errNotImplemented
 }
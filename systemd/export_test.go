@@ -78,3 +78,11 @@ func (e *Error) SetExitCode(i int) {
 func (e *Error) SetMsg(msg []byte) {
 	e.msg = msg
 }
+
+func MockUseDBus(use bool) (restore func()) {
+	old := useDBus
+	useDBus = use
+	return func() {
+		useDBus = old
+	}
+}
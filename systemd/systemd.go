@@ -26,6 +26,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -39,6 +40,8 @@ import (
 	_ "github.com/snapcore/squashfuse"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/squashfs"
 	"github.com/snapcore/snapd/sandbox/selinux"
@@ -108,9 +111,13 @@ var systemctlCmd = func(args ...string) ([]byte, error) {
 // systemctl. It's exported so it can be overridden by testing.
 func MockSystemctl(f func(args ...string) ([]byte, error)) func() {
 	oldSystemctlCmd := systemctlCmd
+	oldUseDBus := useDBus
 	systemctlCmd = f
+	// talking to systemd over D-Bus would bypass the mocked systemctl
+	useDBus = false
 	return func() {
 		systemctlCmd = oldSystemctlCmd
+		useDBus = oldUseDBus
 	}
 }
 
@@ -235,6 +242,12 @@ type Systemd interface {
 	IsEnabled(service string) (bool, error)
 	// IsActive checks whether the given service is Active
 	IsActive(service string) (bool, error)
+	// CurrentMemoryUsage returns the current memory usage of the unit,
+	// which is 0 when the unit is not running.
+	CurrentMemoryUsage(unit string) (quantity.Size, error)
+	// BootTimestamps returns the timestamps of the stages of the current
+	// boot recorded by the system manager.
+	BootTimestamps() (*BootTimestamps, error)
 	// LogReader returns a reader for the given services' log.
 	LogReader(services []string, n int, follow bool) (io.ReadCloser, error)
 	// AddMountUnitFile adds/enables/starts a mount unit.
@@ -419,10 +432,38 @@ func (s *systemd) Mask(serviceName string) error {
 	return err
 }
 
+// dbusManager returns the D-Bus backend to use for this instance, or nil if
+// systemctl must be used.
+func (s *systemd) dbusManager() *dbusManager {
+	if s.mode != SystemMode || s.rootDir != "" {
+		return nil
+	}
+	return newDBusManager()
+}
+
+// startUnitsUsingDBus starts the units over D-Bus when possible. It returns
+// false when systemctl must be used instead, either because systemd cannot be
+// reached over D-Bus or because talking to it failed.
+func (s *systemd) startUnitsUsingDBus(serviceNames []string, wait bool) (done bool, err error) {
+	m := s.dbusManager()
+	if m == nil {
+		return false, nil
+	}
+	err = m.startUnits(serviceNames, wait)
+	if _, ok := err.(*jobFailedError); err == nil || ok {
+		return true, err
+	}
+	logger.Noticef("Cannot start units over D-Bus, using systemctl: %v", err)
+	return false, nil
+}
+
 func (s *systemd) Start(serviceNames ...string) error {
 	if s.mode == GlobalUserMode {
 		panic("cannot call start with GlobalUserMode")
 	}
+	if done, err := s.startUnitsUsingDBus(serviceNames, true); done {
+		return err
+	}
 	_, err := s.systemctl(append([]string{"start"}, serviceNames...)...)
 	return err
 }
@@ -431,6 +472,9 @@ func (s *systemd) StartNoBlock(serviceNames ...string) error {
 	if s.mode == GlobalUserMode {
 		panic("cannot call start with GlobalUserMode")
 	}
+	if done, err := s.startUnitsUsingDBus(serviceNames, false); done {
+		return err
+	}
 	_, err := s.systemctl(append([]string{"start", "--no-block"}, serviceNames...)...)
 	return err
 }
@@ -668,6 +712,85 @@ func (s *systemd) IsActive(serviceName string) (bool, error) {
 	return false, err
 }
 
+func (s *systemd) CurrentMemoryUsage(unit string) (quantity.Size, error) {
+	if s.mode == GlobalUserMode {
+		panic("cannot get memory usage with GlobalUserMode")
+	}
+	out, err := s.systemctl("show", "--property", "MemoryCurrent", unit)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimPrefix(strings.TrimSpace(string(out)), "MemoryCurrent=")
+	// an inactive unit has no memory usage, depending on the version of
+	// systemd it is reported either as "[not set]" or as the maximum value
+	// of a 64bit unsigned integer
+	if value == "[not set]" || value == strconv.FormatUint(math.MaxUint64, 10) {
+		return 0, nil
+	}
+	mem, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse memory usage of unit %s: %q", unit, value)
+	}
+	return quantity.Size(mem), nil
+}
+
+// BootTimestamps are the points in time of the stages of the current boot,
+// relative to when the kernel started.
+type BootTimestamps struct {
+	// Firmware is how long before the kernel the firmware started, it is
+	// zero if unknown.
+	Firmware time.Duration
+	// Loader is how long before the kernel the boot loader started, it is
+	// zero if unknown.
+	Loader time.Duration
+	// InitRD is when the initramfs started, it is zero if there is none.
+	InitRD time.Duration
+	// Userspace is when the system manager started in the real root.
+	Userspace time.Duration
+}
+
+func (s *systemd) BootTimestamps() (*BootTimestamps, error) {
+	if s.mode == GlobalUserMode {
+		panic("cannot get boot timestamps with GlobalUserMode")
+	}
+	var ts BootTimestamps
+	props := []struct {
+		name string
+		ts   *time.Duration
+	}{
+		{"FirmwareTimestampMonotonic", &ts.Firmware},
+		{"LoaderTimestampMonotonic", &ts.Loader},
+		{"InitRDTimestampMonotonic", &ts.InitRD},
+		{"UserspaceTimestampMonotonic", &ts.Userspace},
+	}
+	names := make([]string, len(props))
+	for i, prop := range props {
+		names[i] = prop.name
+	}
+	out, err := s.systemctl("show", "--property="+strings.Join(names, ","))
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(props))
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+	for _, prop := range props {
+		value, ok := values[prop.name]
+		if !ok {
+			return nil, fmt.Errorf("cannot get boot timestamps: missing %s", prop.name)
+		}
+		usec, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %q", prop.name, value)
+		}
+		*prop.ts = time.Duration(usec) * time.Microsecond
+	}
+	return &ts, nil
+}
+
 func (s *systemd) Stop(serviceName string, timeout time.Duration) error {
 	if s.mode == GlobalUserMode {
 		panic("cannot call stop with GlobalUserMode")
@@ -34,6 +34,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/osutil/squashfs"
 	"github.com/snapcore/snapd/sandbox/selinux"
@@ -961,6 +962,68 @@ func (s *SystemdTestSuite) TestIsActiveIsInactive(c *C) {
 	c.Check(s.argses, DeepEquals, [][]string{{"is-active", "foo"}})
 }
 
+func (s *SystemdTestSuite) TestBootTimestamps(c *C) {
+	s.outs = [][]byte{
+		[]byte("FirmwareTimestampMonotonic=5000000\nLoaderTimestampMonotonic=2000000\nInitRDTimestampMonotonic=1500000\nUserspaceTimestampMonotonic=4500000\n"),
+		[]byte("FirmwareTimestampMonotonic=0\nLoaderTimestampMonotonic=0\nInitRDTimestampMonotonic=0\nUserspaceTimestampMonotonic=potato\n"),
+		[]byte("FirmwareTimestampMonotonic=0\n"),
+	}
+
+	sysd := New(SystemMode, s.rep)
+	ts, err := sysd.BootTimestamps()
+	c.Assert(err, IsNil)
+	c.Check(ts, DeepEquals, &BootTimestamps{
+		Firmware:  5 * time.Second,
+		Loader:    2 * time.Second,
+		InitRD:    1500 * time.Millisecond,
+		Userspace: 4500 * time.Millisecond,
+	})
+
+	_, err = sysd.BootTimestamps()
+	c.Assert(err, ErrorMatches, `cannot parse UserspaceTimestampMonotonic: "potato"`)
+
+	_, err = sysd.BootTimestamps()
+	c.Assert(err, ErrorMatches, `cannot get boot timestamps: missing LoaderTimestampMonotonic`)
+
+	props := "--property=FirmwareTimestampMonotonic,LoaderTimestampMonotonic,InitRDTimestampMonotonic,UserspaceTimestampMonotonic"
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"show", props},
+		{"show", props},
+		{"show", props},
+	})
+}
+
+func (s *SystemdTestSuite) TestCurrentMemoryUsage(c *C) {
+	s.outs = [][]byte{
+		[]byte("MemoryCurrent=1024\n"),
+		[]byte("MemoryCurrent=[not set]\n"),
+		[]byte("MemoryCurrent=18446744073709551615\n"),
+		[]byte("MemoryCurrent=potato\n"),
+	}
+
+	sysd := New(SystemMode, s.rep)
+	mem, err := sysd.CurrentMemoryUsage("snap.group.slice")
+	c.Assert(err, IsNil)
+	c.Check(mem, Equals, quantity.SizeKiB)
+
+	// not running
+	for i := 0; i < 2; i++ {
+		mem, err = sysd.CurrentMemoryUsage("snap.group.slice")
+		c.Assert(err, IsNil)
+		c.Check(mem, Equals, quantity.Size(0))
+	}
+
+	_, err = sysd.CurrentMemoryUsage("snap.group.slice")
+	c.Assert(err, ErrorMatches, `cannot parse memory usage of unit snap.group.slice: "potato"`)
+
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"show", "--property", "MemoryCurrent", "snap.group.slice"},
+		{"show", "--property", "MemoryCurrent", "snap.group.slice"},
+		{"show", "--property", "MemoryCurrent", "snap.group.slice"},
+		{"show", "--property", "MemoryCurrent", "snap.group.slice"},
+	})
+}
+
 func (s *SystemdTestSuite) TestIsActiveIsFailed(c *C) {
 	sysErr := &Error{}
 	// seen in the wild to be reported for a 'failed' service
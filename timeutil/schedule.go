@@ -534,9 +534,16 @@ var (
 )
 
 // Next returns the earliest event after last according to the provided
-// schedule but no later than maxDuration since last.
+// schedule but no later than maxDuration since last. A last event in the
+// future, as seen after the clock was set backwards, is taken to have
+// happened now.
 func Next(schedule []*Schedule, last time.Time, maxDuration time.Duration) time.Duration {
 	now := timeNow()
+	if last.After(now) {
+		// otherwise nothing would happen until the clock caught up
+		// with last again
+		last = now
+	}
 
 	window := ScheduleWindow{
 		Start: last.Add(maxDuration),
@@ -313,6 +313,31 @@ func (ts *timeutilSuite) TestLegacyScheduleNext(c *C) {
 
 }
 
+func (ts *timeutilSuite) TestNextLastInFuture(c *C) {
+	const shortForm = "2006-01-02 15:04"
+
+	// the clock was set backwards after the last event
+	last, err := time.ParseInLocation(shortForm, "2020-02-06 10:00", time.Local)
+	c.Assert(err, IsNil)
+	fakeNow, err := time.ParseInLocation(shortForm, "2017-02-06 20:00", time.Local)
+	c.Assert(err, IsNil)
+	restorer := timeutil.MockTimeNow(func() time.Time {
+		return fakeNow
+	})
+	defer restorer()
+
+	sched, err := timeutil.ParseSchedule("9:00-11:00")
+	c.Assert(err, IsNil)
+
+	// next day window
+	next := timeutil.Next(sched, last, maxDuration)
+	c.Check(next >= 13*time.Hour && next <= 15*time.Hour, Equals, true, Commentf("unexpected next %v", next))
+
+	// bounded by max duration since now
+	next = timeutil.Next(sched, last, 2*time.Hour)
+	c.Check(next, Equals, 2*time.Hour)
+}
+
 func (ts *timeutilSuite) TestParseSchedule(c *C) {
 	for _, t := range []struct {
 		in       string
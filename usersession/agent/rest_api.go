@@ -42,6 +42,7 @@ var restApi = []*Command{
 	rootCmd,
 	sessionInfoCmd,
 	serviceControlCmd,
+	serviceStatusCmd,
 	pendingRefreshNotificationCmd,
 }
 
@@ -61,6 +62,11 @@ var (
 		POST: postServiceControl,
 	}
 
+	serviceStatusCmd = &Command{
+		Path: "/v1/service-status",
+		GET:  serviceStatus,
+	}
+
 	pendingRefreshNotificationCmd = &Command{
 		Path: "/v1/notifications/pending-refresh",
 		POST: postPendingRefreshNotification,
@@ -211,6 +217,49 @@ func postServiceControl(c *Command, r *http.Request) Response {
 	return impl(&inst, sysd)
 }
 
+type serviceUnitStatus struct {
+	Daemon   string `json:"daemon"`
+	UnitName string `json:"unit-name"`
+	Enabled  bool   `json:"enabled"`
+	Active   bool   `json:"active"`
+}
+
+func serviceStatus(c *Command, r *http.Request) Response {
+	query := r.URL.Query()
+	var services []string
+	if s := query.Get("services"); s != "" {
+		services = strings.Split(s, ",")
+	}
+	if len(services) == 0 {
+		return BadRequest("no services specified")
+	}
+	// Refuse to report on non-snap services
+	for _, service := range services {
+		if !strings.HasPrefix(service, "snap.") {
+			return BadRequest("cannot query status of non-snap service %v", service)
+		}
+	}
+
+	// Prevent multiple systemd actions from being carried out simultaneously
+	systemdLock.Lock()
+	defer systemdLock.Unlock()
+	sysd := systemd.New(systemd.UserMode, dummyReporter{})
+	sts, err := sysd.Status(services...)
+	if err != nil {
+		return InternalError("cannot get status of services: %v", err)
+	}
+	statuses := make([]serviceUnitStatus, len(sts))
+	for i, st := range sts {
+		statuses[i] = serviceUnitStatus{
+			Daemon:   st.Daemon,
+			UnitName: st.UnitName,
+			Enabled:  st.Enabled,
+			Active:   st.Active,
+		}
+	}
+	return SyncResponse(statuses)
+}
+
 func postPendingRefreshNotification(c *Command, r *http.Request) Response {
 	contentType := r.Header.Get("Content-Type")
 	mediaType, params, err := mime.ParseMediaType(contentType)
@@ -362,6 +362,84 @@ func (s *restSuite) TestServicesStopReportsTimeout(c *C) {
 	})
 }
 
+func (s *restSuite) TestServiceStatus(c *C) {
+	// the agent.ServiceStatus end point only supports GET requests
+	c.Assert(agent.ServiceStatusCmd.GET, NotNil)
+	c.Check(agent.ServiceStatusCmd.PUT, IsNil)
+	c.Check(agent.ServiceStatusCmd.POST, IsNil)
+	c.Check(agent.ServiceStatusCmd.DELETE, IsNil)
+
+	c.Check(agent.ServiceStatusCmd.Path, Equals, "/v1/service-status")
+}
+
+func (s *restSuite) TestServicesStatus(c *C) {
+	restore := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		return []byte(`Type=simple
+Id=snap.foo.service
+ActiveState=active
+UnitFileState=enabled
+
+Type=forking
+Id=snap.bar.service
+ActiveState=inactive
+UnitFileState=disabled
+`), nil
+	})
+	defer restore()
+
+	req := httptest.NewRequest("GET", "/v1/service-status?services=snap.foo.service,snap.bar.service", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 200)
+	c.Check(rec.HeaderMap.Get("Content-Type"), Equals, "application/json")
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeSync)
+	c.Check(rsp.Result, DeepEquals, []interface{}{
+		map[string]interface{}{
+			"daemon":    "simple",
+			"unit-name": "snap.foo.service",
+			"enabled":   true,
+			"active":    true,
+		},
+		map[string]interface{}{
+			"daemon":    "forking",
+			"unit-name": "snap.bar.service",
+			"enabled":   false,
+			"active":    false,
+		},
+	})
+
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"--user", "show", "--property=Id,ActiveState,UnitFileState,Type", "snap.foo.service", "snap.bar.service"},
+	})
+}
+
+func (s *restSuite) TestServicesStatusNonSnap(c *C) {
+	req := httptest.NewRequest("GET", "/v1/service-status?services=snap.foo.service,not-snap.bar.service", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+
+	var rsp resp
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rsp), IsNil)
+	c.Check(rsp.Type, Equals, agent.ResponseTypeError)
+	c.Check(rsp.Result, DeepEquals, map[string]interface{}{
+		"message": "cannot query status of non-snap service not-snap.bar.service",
+	})
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
+func (s *restSuite) TestServicesStatusNoServices(c *C) {
+	req := httptest.NewRequest("GET", "/v1/service-status", nil)
+	rec := httptest.NewRecorder()
+	agent.ServiceStatusCmd.GET(agent.ServiceStatusCmd, req).ServeHTTP(rec, req)
+	c.Check(rec.Code, Equals, 400)
+	c.Check(s.sysdLog, HasLen, 0)
+}
+
 func (s *restSuite) TestPostPendingRefreshNotificationMalformedContentType(c *C) {
 	req := httptest.NewRequest("POST", "/v1/notifications/pending-refresh", bytes.NewBufferString(""))
 	req.Header.Set("Content-Type", "text/plain/joke")
@@ -31,6 +31,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -269,6 +270,45 @@ func (client *Client) ServicesStop(ctx context.Context, services []string) (stop
 	return stopFailures, err
 }
 
+// ServiceUnitStatus holds the status of a user service unit as
+// reported by the session agent of a given user.
+type ServiceUnitStatus struct {
+	Daemon   string `json:"daemon"`
+	UnitName string `json:"unit-name"`
+	Enabled  bool   `json:"enabled"`
+	Active   bool   `json:"active"`
+}
+
+// ServicesStatus queries the status of the given user services in all
+// active user sessions. The result is keyed by the uid of the users.
+func (client *Client) ServicesStatus(ctx context.Context, services []string) (status map[int][]ServiceUnitStatus, err error) {
+	q := make(url.Values)
+	q.Set("services", strings.Join(services, ","))
+	responses, err := client.doMany(ctx, "GET", "/v1/service-status", q, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status = make(map[int][]ServiceUnitStatus)
+	for _, resp := range responses {
+		if resp.err != nil {
+			if err == nil {
+				err = resp.err
+			}
+			continue
+		}
+		var sts []ServiceUnitStatus
+		if decodeErr := json.Unmarshal(resp.Result, &sts); decodeErr != nil {
+			if err == nil {
+				err = decodeErr
+			}
+			continue
+		}
+		status[resp.uid] = sts
+	}
+	return status, err
+}
+
 // PendingSnapRefreshInfo holds information about pending snap refresh provided to userd.
 type PendingSnapRefreshInfo struct {
 	InstanceName        string        `json:"instance-name"`
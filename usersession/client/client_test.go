@@ -190,6 +190,49 @@ func (s *clientSuite) TestSessionInfoWrongResultType(c *C) {
 	c.Check(err, ErrorMatches, `json: cannot unmarshal array into Go value of type client.SessionInfo`)
 }
 
+func (s *clientSuite) TestServicesStatus(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v1/service-status")
+		c.Check(r.URL.Query().Get("services"), Equals, "snap.foo.service,snap.bar.service")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{
+  "type": "sync",
+  "result": [
+    {"daemon": "simple", "unit-name": "snap.foo.service", "enabled": true, "active": true},
+    {"daemon": "simple", "unit-name": "snap.bar.service", "enabled": true, "active": false}
+  ]
+}`))
+	})
+	status, err := s.cli.ServicesStatus(context.Background(), []string{"snap.foo.service", "snap.bar.service"})
+	c.Assert(err, IsNil)
+	expected := []client.ServiceUnitStatus{
+		{Daemon: "simple", UnitName: "snap.foo.service", Enabled: true, Active: true},
+		{Daemon: "simple", UnitName: "snap.bar.service", Enabled: true, Active: false},
+	}
+	c.Check(status, DeepEquals, map[int][]client.ServiceUnitStatus{
+		42:   expected,
+		1000: expected,
+	})
+}
+
+func (s *clientSuite) TestServicesStatusError(c *C) {
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(500)
+		w.Write([]byte(`{
+  "type": "error",
+  "result": {
+    "message": "cannot get status of services"
+  }
+}`))
+	})
+	status, err := s.cli.ServicesStatus(context.Background(), []string{"snap.foo.service"})
+	c.Check(status, DeepEquals, map[int][]client.ServiceUnitStatus{})
+	c.Check(err, ErrorMatches, "cannot get status of services")
+}
+
 func (s *clientSuite) TestServicesDaemonReload(c *C) {
 	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,159 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/godbus/dbus"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/strutil/shlex"
+)
+
+const privilegedLauncherIntrospectionXML = `
+<interface name="org.freedesktop.DBus.Peer">
+	<method name='Ping'>
+	</method>
+	<method name='GetMachineId'>
+               <arg type='s' name='machine_uuid' direction='out'/>
+	</method>
+</interface>
+<interface name='io.snapcraft.PrivilegedDesktopLauncher'>
+	<method name='OpenDesktopEntry'>
+		<arg type='s' name='desktop_file_id' direction='in'/>
+	</method>
+</interface>`
+
+// PrivilegedDesktopLauncher implements the
+// 'io.snapcraft.PrivilegedDesktopLauncher' DBus interface. It is used by
+// snaps connected to the desktop-launch interface to start the desktop
+// applications of other snaps.
+type PrivilegedDesktopLauncher struct {
+	conn *dbus.Conn
+}
+
+// Interface returns the name of the interface this object implements
+func (s *PrivilegedDesktopLauncher) Interface() string {
+	return "io.snapcraft.PrivilegedDesktopLauncher"
+}
+
+// ObjectPath returns the path that the object is exported as
+func (s *PrivilegedDesktopLauncher) ObjectPath() dbus.ObjectPath {
+	return "/io/snapcraft/PrivilegedDesktopLauncher"
+}
+
+// IntrospectionData gives the XML formatted introspection description
+// of the DBus service.
+func (s *PrivilegedDesktopLauncher) IntrospectionData() string {
+	return privilegedLauncherIntrospectionXML
+}
+
+// desktop file ids of snap applications are of the form
+// <snap-instance>_<app>.desktop
+var validDesktopFileID = regexp.MustCompile(`^[a-z0-9-]+(_[a-z0-9]+)?_[A-Za-z0-9-]+\.desktop$`)
+
+// OpenDesktopEntry implements the 'OpenDesktopEntry' method of the
+// 'io.snapcraft.PrivilegedDesktopLauncher' DBus interface. Only the
+// desktop files installed by snapd for snap applications can be
+// launched, and only if their command is a snap application.
+func (s *PrivilegedDesktopLauncher) OpenDesktopEntry(desktopFileID string, sender dbus.Sender) *dbus.Error {
+	if !validDesktopFileID.MatchString(desktopFileID) {
+		return makeAccessDeniedError(fmt.Errorf("cannot launch desktop file %q: invalid desktop file ID", desktopFileID))
+	}
+
+	desktopFile := filepath.Join(dirs.SnapDesktopFilesDir, desktopFileID)
+	args, err := desktopFileCommand(desktopFile)
+	if err != nil {
+		return dbus.MakeFailedError(fmt.Errorf("cannot launch desktop file %q: %v", desktopFileID, err))
+	}
+
+	// run the application in its own transient unit so that it is
+	// not tied to the lifetime of userd
+	cmd := exec.Command("systemd-run", append([]string{"--user", "--"}, args...)...)
+	if err := cmd.Run(); err != nil {
+		return dbus.MakeFailedError(fmt.Errorf("cannot launch desktop file %q: %v", desktopFileID, err))
+	}
+
+	return nil
+}
+
+// desktopFileCommand returns the command line from the Exec= key of the
+// [Desktop Entry] group of the given desktop file. Field codes are
+// dropped and the command must invoke a snap application, optionally
+// with environment variables set via env.
+func desktopFileCommand(desktopFile string) ([]string, error) {
+	f, err := os.Open(desktopFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var command string
+	inDesktopEntry := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inDesktopEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if inDesktopEntry && strings.HasPrefix(line, "Exec=") {
+			command = strings.TrimPrefix(line, "Exec=")
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if command == "" {
+		return nil, fmt.Errorf("Exec not found or invalid")
+	}
+
+	args, err := shlex.Split(command)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command: %v", err)
+	}
+	// drop the field codes, there are no files or URLs to pass along
+	withoutFieldCodes := args[:0]
+	for _, arg := range args {
+		if len(arg) == 2 && arg[0] == '%' {
+			continue
+		}
+		withoutFieldCodes = append(withoutFieldCodes, arg)
+	}
+	args = withoutFieldCodes
+
+	i := 0
+	if len(args) > 0 && args[0] == "env" {
+		for i = 1; i < len(args) && strings.Contains(args[i], "="); i++ {
+		}
+	}
+	if i >= len(args) || filepath.Dir(args[i]) != dirs.SnapBinariesDir {
+		return nil, fmt.Errorf("command %q is not a snap application", command)
+	}
+	return args, nil
+}
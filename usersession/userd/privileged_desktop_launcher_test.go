@@ -0,0 +1,127 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/usersession/userd"
+)
+
+type privilegedDesktopLauncherSuite struct {
+	testutil.BaseTest
+
+	launcher       *userd.PrivilegedDesktopLauncher
+	mockSystemdRun *testutil.MockCmd
+}
+
+var _ = Suite(&privilegedDesktopLauncherSuite{})
+
+func (s *privilegedDesktopLauncherSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.launcher = &userd.PrivilegedDesktopLauncher{}
+	s.mockSystemdRun = testutil.MockCommand(c, "systemd-run", "")
+	s.AddCleanup(s.mockSystemdRun.Restore)
+}
+
+func (s *privilegedDesktopLauncherSuite) mockDesktopFile(c *C, name, content string) {
+	// snap applications are referred to by their wrappers in the
+	// (test) root
+	content = strings.Replace(content, "/snap/bin/", dirs.SnapBinariesDir+"/", -1)
+	c.Assert(os.MkdirAll(dirs.SnapDesktopFilesDir, 0755), IsNil)
+	c.Assert(ioutil.WriteFile(filepath.Join(dirs.SnapDesktopFilesDir, name), []byte(content), 0644), IsNil)
+}
+
+func (s *privilegedDesktopLauncherSuite) TestOpenDesktopEntryHappy(c *C) {
+	s.mockDesktopFile(c, "foo_bar.desktop", `[Desktop Entry]
+Name=Bar
+Exec=env BAMF_DESKTOP_FILE_HINT=/var/lib/snapd/desktop/applications/foo_bar.desktop /snap/bin/foo.bar --some-arg %U
+
+[Desktop Action Other]
+Exec=/snap/bin/foo.other
+`)
+
+	err := s.launcher.OpenDesktopEntry("foo_bar.desktop", ":some-dbus-sender")
+	c.Assert(err, IsNil)
+	c.Check(s.mockSystemdRun.Calls(), DeepEquals, [][]string{
+		{"systemd-run", "--user", "--", "env", "BAMF_DESKTOP_FILE_HINT=/var/lib/snapd/desktop/applications/foo_bar.desktop", filepath.Join(dirs.SnapBinariesDir, "foo.bar"), "--some-arg"},
+	})
+}
+
+func (s *privilegedDesktopLauncherSuite) TestOpenDesktopEntryNoEnv(c *C) {
+	s.mockDesktopFile(c, "foo_instance_bar.desktop", `[Desktop Entry]
+Exec=/snap/bin/foo_instance.bar
+`)
+
+	err := s.launcher.OpenDesktopEntry("foo_instance_bar.desktop", ":some-dbus-sender")
+	c.Assert(err, IsNil)
+	c.Check(s.mockSystemdRun.Calls(), DeepEquals, [][]string{
+		{"systemd-run", "--user", "--", filepath.Join(dirs.SnapBinariesDir, "foo_instance.bar")},
+	})
+}
+
+func (s *privilegedDesktopLauncherSuite) TestOpenDesktopEntryInvalidID(c *C) {
+	for _, id := range []string{"", "foo.desktop", "../foo_bar.desktop", "foo_bar", "foo_bar.desktop/.."} {
+		err := s.launcher.OpenDesktopEntry(id, ":some-dbus-sender")
+		c.Check(err, ErrorMatches, `cannot launch desktop file ".*": invalid desktop file ID`, Commentf(id))
+	}
+	c.Check(s.mockSystemdRun.Calls(), HasLen, 0)
+}
+
+func (s *privilegedDesktopLauncherSuite) TestOpenDesktopEntryMissing(c *C) {
+	err := s.launcher.OpenDesktopEntry("foo_bar.desktop", ":some-dbus-sender")
+	c.Check(err, ErrorMatches, `cannot launch desktop file "foo_bar.desktop": open .*/foo_bar.desktop: no such file or directory`)
+	c.Check(s.mockSystemdRun.Calls(), HasLen, 0)
+}
+
+func (s *privilegedDesktopLauncherSuite) TestOpenDesktopEntryNotSnapApp(c *C) {
+	for _, content := range []string{
+		"[Desktop Entry]\nExec=/usr/bin/gnome-terminal\n",
+		"[Desktop Entry]\nExec=env FOO=bar /bin/sh\n",
+		"[Desktop Entry]\nExec=env FOO=bar\n",
+		"[Desktop Action Other]\nExec=/snap/bin/foo.bar\n",
+		"[Desktop Entry]\nName=Bar\n",
+	} {
+		s.mockDesktopFile(c, "foo_bar.desktop", content)
+		err := s.launcher.OpenDesktopEntry("foo_bar.desktop", ":some-dbus-sender")
+		c.Check(err, ErrorMatches, `cannot launch desktop file "foo_bar.desktop": (command ".*" is not a snap application|Exec not found or invalid)`, Commentf(content))
+	}
+	c.Check(s.mockSystemdRun.Calls(), HasLen, 0)
+}
+
+func (s *privilegedDesktopLauncherSuite) TestOpenDesktopEntryFailingSystemdRun(c *C) {
+	cmd := testutil.MockCommand(c, "systemd-run", "false")
+	defer cmd.Restore()
+
+	s.mockDesktopFile(c, "foo_bar.desktop", "[Desktop Entry]\nExec=/snap/bin/foo.bar\n")
+	err := s.launcher.OpenDesktopEntry("foo_bar.desktop", ":some-dbus-sender")
+	c.Check(err, ErrorMatches, `cannot launch desktop file "foo_bar.desktop": exit status 1`)
+}
@@ -61,6 +61,7 @@ func (ud *Userd) Init() error {
 	ud.dbusIfaces = []dbusInterface{
 		&Launcher{ud.conn},
 		&Settings{ud.conn},
+		&PrivilegedDesktopLauncher{ud.conn},
 	}
 	for _, iface := range ud.dbusIfaces {
 		// export the interfaces at the godbus API level first to avoid
@@ -0,0 +1,80 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
+)
+
+func generateQuotaGroupSliceFile(grp *quota.Group) []byte {
+	// the memory accounting needs to be enabled for the limit to be
+	// enforced, MemoryLimit is the equivalent of MemoryMax for cgroup v1
+	return []byte(fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Slice for snap quota group %[1]s
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+MemoryAccounting=true
+MemoryMax=%[2]d
+MemoryLimit=%[2]d
+`, grp.Name, grp.MemoryLimit))
+}
+
+// EnsureQuotaGroupSlice writes the systemd slice unit of the given quota
+// group, systemd is reloaded if the slice was created or modified.
+func EnsureQuotaGroupSlice(grp *quota.Group, inter interacter) error {
+	path := filepath.Join(dirs.SnapServicesDir, grp.SliceFileName())
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	content := generateQuotaGroupSliceFile(grp)
+	err := osutil.EnsureFileState(path, &osutil.MemoryFileState{Content: content, Mode: 0644})
+	if err == osutil.ErrSameState {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sysd := systemd.New(systemd.SystemMode, inter)
+	return sysd.DaemonReload()
+}
+
+// RemoveQuotaGroupSlice removes the systemd slice unit of the given quota
+// group and reloads systemd.
+func RemoveQuotaGroupSlice(grp *quota.Group, inter interacter) error {
+	path := filepath.Join(dirs.SnapServicesDir, grp.SliceFileName())
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	sysd := systemd.New(systemd.SystemMode, inter)
+	return sysd.DaemonReload()
+}
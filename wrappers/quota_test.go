@@ -0,0 +1,115 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2021 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package wrappers_test
+
+import (
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/snap/quota"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/testutil"
+	"github.com/snapcore/snapd/wrappers"
+)
+
+type quotaSuite struct {
+	testutil.BaseTest
+
+	sysdLog [][]string
+}
+
+var _ = Suite(&quotaSuite{})
+
+func (s *quotaSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	dirs.SetRootDir(c.MkDir())
+	s.AddCleanup(func() { dirs.SetRootDir("") })
+
+	s.sysdLog = nil
+	s.AddCleanup(systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		return nil, nil
+	}))
+}
+
+func (s *quotaSuite) TestEnsureQuotaGroupSlice(c *C) {
+	grp, err := quota.NewGroup("foo", quantity.SizeMiB)
+	c.Assert(err, IsNil)
+
+	err = wrappers.EnsureQuotaGroupSlice(grp, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+
+	sliceFile := filepath.Join(dirs.SnapServicesDir, "snap.foo.slice")
+	c.Check(sliceFile, testutil.FileEquals, `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Slice for snap quota group foo
+Before=slices.target
+X-Snappy=yes
+
+[Slice]
+MemoryAccounting=true
+MemoryMax=1048576
+MemoryLimit=1048576
+`)
+
+	// nothing changed, no reload
+	s.sysdLog = nil
+	err = wrappers.EnsureQuotaGroupSlice(grp, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+
+	// the limit changed
+	grp.MemoryLimit = 2 * quantity.SizeMiB
+	err = wrappers.EnsureQuotaGroupSlice(grp, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+	c.Check(sliceFile, testutil.FileContains, "MemoryMax=2097152\n")
+}
+
+func (s *quotaSuite) TestRemoveQuotaGroupSlice(c *C) {
+	grp, err := quota.NewGroup("foo", quantity.SizeMiB)
+	c.Assert(err, IsNil)
+
+	err = wrappers.EnsureQuotaGroupSlice(grp, progress.Null)
+	c.Assert(err, IsNil)
+
+	s.sysdLog = nil
+	err = wrappers.RemoveQuotaGroupSlice(grp, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"daemon-reload"},
+	})
+	c.Check(filepath.Join(dirs.SnapServicesDir, "snap.foo.slice"), testutil.FileAbsent)
+
+	// removing again is a no-op
+	s.sysdLog = nil
+	err = wrappers.RemoveQuotaGroupSlice(grp, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, HasLen, 0)
+}
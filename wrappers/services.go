@@ -38,6 +38,7 @@ import (
 	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/randutil"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timeout"
@@ -381,6 +382,9 @@ type AddSnapServicesOptions struct {
 	Preseeding              bool
 	VitalityRank            int
 	RequireMountedSnapdSnap bool
+	// QuotaGroup is the quota group the snap is part of, if any, its
+	// system services are placed in the slice of the group
+	QuotaGroup *quota.Group
 }
 
 // AddSnapServices adds service units for the applications from the snap which
@@ -765,6 +769,9 @@ KillSignal={{.KillSignal}}
 {{- if .OOMAdjustScore }}
 OOMScoreAdjust={{.OOMAdjustScore}}
 {{- end}}
+{{- if .SliceUnit}}
+Slice={{.SliceUnit}}
+{{- end}}
 {{- if .InterfaceServiceSnippets}}
 {{.InterfaceServiceSnippets}}
 {{- end}}
@@ -835,6 +842,7 @@ WantedBy={{.ServicesTarget}}
 		KillMode                 string
 		KillSignal               string
 		OOMAdjustScore           int
+		SliceUnit                string
 		BusName                  string
 		Before                   []string
 		After                    []string
@@ -871,6 +879,9 @@ WantedBy={{.ServicesTarget}}
 		wrapperData.MountUnit = filepath.Base(systemd.MountUnitPath(appInfo.Snap.MountDir()))
 		wrapperData.WorkingDir = appInfo.Snap.DataDir()
 		wrapperData.After = append(wrapperData.After, "snapd.apparmor.service")
+		if opts.QuotaGroup != nil {
+			wrapperData.SliceUnit = opts.QuotaGroup.SliceFileName()
+		}
 	case snap.UserDaemon:
 		wrapperData.ServicesTarget = systemd.UserServicesTarget
 		// FIXME: ideally use UserDataDir("%h"), but then the
@@ -1272,11 +1283,14 @@ func generateOnCalendarSchedules(schedule []*timeutil.Schedule) []string {
 	return calendarEvents
 }
 
+// RestartServicesFlags carries extra flags for RestartServices.
 type RestartServicesFlags struct {
 	Reload bool
 }
 
 // Restart or reload services; if reload flag is set then "systemctl reload-or-restart" is attempted.
+// Services which are activated by sockets or timers are restarted with their
+// activation units stopped, see restartActivatedService.
 func RestartServices(svcs []*snap.AppInfo, flags *RestartServicesFlags, inter interacter, tm timings.Measurer) error {
 	sysd := systemd.New(systemd.SystemMode, inter)
 
@@ -1285,12 +1299,20 @@ func RestartServices(svcs []*snap.AppInfo, flags *RestartServicesFlags, inter in
 		if !srv.IsService() {
 			continue
 		}
+		reload := flags != nil && flags.Reload
+		var units []string
+		if srv.DaemonScope == snap.SystemDaemon {
+			units = activationUnits(srv)
+		}
 
 		var err error
 		timings.Run(tm, "restart-service", fmt.Sprintf("restart service %q", srv), func(nested timings.Measurer) {
-			if flags != nil && flags.Reload {
+			switch {
+			case len(units) != 0:
+				err = restartActivatedService(sysd, srv, units, reload)
+			case reload:
 				err = sysd.ReloadOrRestart(srv.ServiceName())
-			} else {
+			default:
 				// note: stop followed by start, not just 'restart'
 				err = sysd.Restart(srv.ServiceName(), 5*time.Second)
 			}
@@ -1303,6 +1325,61 @@ func RestartServices(svcs []*snap.AppInfo, flags *RestartServicesFlags, inter in
 	return nil
 }
 
+// activationUnits returns the names of the systemd units activating the
+// service, sockets are listed in a stable order before the timer.
+func activationUnits(app *snap.AppInfo) []string {
+	units := make([]string, 0, len(app.Sockets)+1)
+	for _, socket := range app.Sockets {
+		units = append(units, filepath.Base(socket.File()))
+	}
+	sort.Strings(units)
+	if app.Timer != nil {
+		units = append(units, filepath.Base(app.Timer.File()))
+	}
+	return units
+}
+
+// restartActivatedService restarts a service activated by sockets or a
+// timer. The activation units which are active are stopped before the service
+// so that they cannot activate it while it is being restarted and are started
+// again afterwards, even if restarting the service failed. As with any other
+// service, a service which is not running gets started.
+func restartActivatedService(sysd systemd.Systemd, app *snap.AppInfo, units []string, reload bool) (err error) {
+	serviceName := app.ServiceName()
+	sts, err := sysd.Status(units...)
+	if err != nil {
+		return fmt.Errorf("cannot get status of activators of service %q: %v", serviceName, err)
+	}
+
+	var activeUnits []string
+	for _, st := range sts {
+		if st.Active {
+			activeUnits = append(activeUnits, st.UnitName)
+		}
+	}
+	defer func() {
+		if len(activeUnits) == 0 {
+			return
+		}
+		if e := sysd.Start(activeUnits...); e != nil && err == nil {
+			err = fmt.Errorf("cannot restore activation of service %q: %v", serviceName, e)
+		}
+	}()
+
+	tout := serviceStopTimeout(app)
+	for _, unit := range activeUnits {
+		if err := sysd.Stop(unit, tout); err != nil {
+			return err
+		}
+	}
+
+	if reload {
+		return sysd.ReloadOrRestart(serviceName)
+	}
+	// note: stop followed by start, not just 'restart'
+	return sysd.Restart(serviceName, 5*time.Second)
+}
+
 // QueryDisabledServices returns a list of all currently disabled snap services
 // in the snap.
 func QueryDisabledServices(info *snap.Info, pb progress.Meter) ([]string, error) {
@@ -30,8 +30,10 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/gadget/quantity"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/quota"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/timeout"
@@ -1023,3 +1025,54 @@ OOMScoreAdjust=-899
 WantedBy=multi-user.target
 `, mountUnitPrefix, mountUnitPrefix))
 }
+
+func (s *servicesWrapperGenSuite) TestQuotaGroupSlice(c *C) {
+	service := &snap.AppInfo{
+		Snap: &snap.Info{
+			SuggestedName: "snap",
+			Version:       "0.3.4",
+			SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+		},
+		Name:         "app",
+		Command:      "bin/foo start",
+		Daemon:       "simple",
+		DaemonScope:  snap.SystemDaemon,
+		RestartDelay: timeout.Timeout(20 * time.Second),
+	}
+
+	grp, err := quota.NewGroup("iot-group", quantity.SizeMiB)
+	c.Assert(err, IsNil)
+
+	opts := &wrappers.AddSnapServicesOptions{QuotaGroup: grp}
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(service, opts)
+	c.Assert(err, IsNil)
+
+	c.Check(string(generatedWrapper), Equals, fmt.Sprintf(`[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+Requires=%s-snap-44.mount
+Wants=network.target
+After=%s-snap-44.mount network.target snapd.apparmor.service
+X-Snappy=yes
+
+[Service]
+EnvironmentFile=-/etc/environment
+ExecStart=/usr/bin/snap run snap.app
+SyslogIdentifier=snap.app
+Restart=on-failure
+RestartSec=20
+WorkingDirectory=/var/snap/snap/44
+TimeoutStopSec=30
+Type=simple
+Slice=snap.iot\x2dgroup.slice
+
+[Install]
+WantedBy=multi-user.target
+`, mountUnitPrefix, mountUnitPrefix))
+
+	// user daemons are not placed in the slice
+	service.DaemonScope = snap.UserDaemon
+	generatedWrapper, err = wrappers.GenerateSnapServiceFile(service, opts)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Not(testutil.Contains), "Slice=")
+}
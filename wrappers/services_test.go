@@ -20,6 +20,7 @@
 package wrappers_test
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -1946,6 +1947,167 @@ apps:
 	})
 }
 
+// mockActivatedServiceStatus mocks systemctl reporting the given units as
+// active, all units are enabled; starting the failStart unit fails
+func (s *servicesTestSuite) mockActivatedServiceStatus(c *C, active map[string]bool, failStart string) (restore func()) {
+	return systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		s.sysdLog = append(s.sysdLog, cmd)
+		if cmd[0] == "start" && cmd[1] == failStart {
+			return nil, fmt.Errorf("failed")
+		}
+		if cmd[0] != "show" || cmd[1] == "--property=ActiveState" {
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		var out bytes.Buffer
+		for i, unit := range cmd[2:] {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			activeState := "inactive"
+			if active[unit] {
+				activeState = "active"
+			}
+			fmt.Fprintf(&out, "Id=%s\nActiveState=%s\nUnitFileState=enabled\n", unit, activeState)
+			if strings.HasSuffix(unit, ".service") {
+				out.WriteString("Type=simple\n")
+			}
+		}
+		return out.Bytes(), nil
+	})
+}
+
+func (s *servicesTestSuite) TestRestartServicesWithActivators(c *C) {
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  sockets:
+    sock1:
+      listen-stream: $SNAP_COMMON/sock1.socket
+    sock2:
+      listen-stream: $SNAP_COMMON/sock2.socket
+  timer: 10:00-12:00
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svc2Name := "snap.hello-snap.svc2.service"
+	svc2Sock1 := "snap.hello-snap.svc2.sock1.socket"
+	svc2Sock2 := "snap.hello-snap.svc2.sock2.socket"
+	svc2Timer := "snap.hello-snap.svc2.timer"
+
+	r := s.mockActivatedServiceStatus(c, map[string]bool{
+		svc2Name:  true,
+		svc2Sock1: true,
+		svc2Timer: true,
+	}, "")
+	defer r()
+
+	err := wrappers.RestartServices([]*snap.AppInfo{info.Apps["svc2"]}, nil, progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState", svc2Sock1, svc2Sock2, svc2Timer},
+		// activators are stopped first
+		{"stop", svc2Sock1},
+		{"show", "--property=ActiveState", svc2Sock1},
+		{"stop", svc2Timer},
+		{"show", "--property=ActiveState", svc2Timer},
+		{"stop", svc2Name},
+		{"show", "--property=ActiveState", svc2Name},
+		{"start", svc2Name},
+		// and only the ones which were active are restored
+		{"start", svc2Sock1, svc2Timer},
+	})
+
+	// reload
+	s.sysdLog = nil
+	flags := &wrappers.RestartServicesFlags{Reload: true}
+	err = wrappers.RestartServices([]*snap.AppInfo{info.Apps["svc2"]}, flags, progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState", svc2Sock1, svc2Sock2, svc2Timer},
+		{"stop", svc2Sock1},
+		{"show", "--property=ActiveState", svc2Sock1},
+		{"stop", svc2Timer},
+		{"show", "--property=ActiveState", svc2Timer},
+		{"reload-or-restart", svc2Name},
+		{"start", svc2Sock1, svc2Timer},
+	})
+}
+
+func (s *servicesTestSuite) TestRestartServicesWithActivatorsServiceNotRunning(c *C) {
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  sockets:
+    sock1:
+      listen-stream: $SNAP_COMMON/sock1.socket
+ svc3:
+  command: bin/hello
+  daemon: simple
+  activates-on: [dbus-slot]
+slots:
+  dbus-slot:
+    interface: dbus
+    bus: system
+    name: org.example.Svc
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svc2Name := "snap.hello-snap.svc2.service"
+	svc2Sock1 := "snap.hello-snap.svc2.sock1.socket"
+	svc3Name := "snap.hello-snap.svc3.service"
+
+	r := s.mockActivatedServiceStatus(c, map[string]bool{
+		svc2Sock1: true,
+	}, "")
+	defer r()
+
+	apps := []*snap.AppInfo{info.Apps["svc2"], info.Apps["svc3"]}
+	err := wrappers.RestartServices(apps, nil, progress.Null, s.perfTimings)
+	c.Assert(err, IsNil)
+	// services which are not running are started, just like any other
+	// service, D-Bus activation has no unit to stop
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState", svc2Sock1},
+		{"stop", svc2Sock1},
+		{"show", "--property=ActiveState", svc2Sock1},
+		{"stop", svc2Name},
+		{"show", "--property=ActiveState", svc2Name},
+		{"start", svc2Name},
+		{"start", svc2Sock1},
+		{"stop", svc3Name},
+		{"show", "--property=ActiveState", svc3Name},
+		{"start", svc3Name},
+	})
+}
+
+func (s *servicesTestSuite) TestRestartServicesWithActivatorsRestoresOnError(c *C) {
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  timer: 10:00-12:00
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svc2Name := "snap.hello-snap.svc2.service"
+	svc2Timer := "snap.hello-snap.svc2.timer"
+
+	r := s.mockActivatedServiceStatus(c, map[string]bool{
+		svc2Name:  true,
+		svc2Timer: true,
+	}, svc2Name)
+	defer r()
+
+	err := wrappers.RestartServices([]*snap.AppInfo{info.Apps["svc2"]}, nil, progress.Null, s.perfTimings)
+	c.Assert(err, ErrorMatches, "failed")
+	c.Check(s.sysdLog, DeepEquals, [][]string{
+		{"show", "--property=Id,ActiveState,UnitFileState", svc2Timer},
+		{"stop", svc2Timer},
+		{"show", "--property=ActiveState", svc2Timer},
+		{"stop", svc2Name},
+		{"show", "--property=ActiveState", svc2Name},
+		{"start", svc2Name},
+		// the timer is started again nonetheless
+		{"start", svc2Timer},
+	})
+}
+
 func (s *servicesTestSuite) TestStopAndDisableServices(c *C) {
 	info := snaptest.MockSnap(c, packageHello+`
  svc1: